@@ -19,6 +19,9 @@
 //
 //   - a pxelinux.0, in which case we will ignore the pxelinux and try to parse
 //     pxelinux.cfg/<files>
+//
+// Without a BootFileName, or with an iscsi: one, pxeboot boots from the iSCSI
+// target of the RootPath option, as iPXE's sanboot does.
 package main
 
 import (
@@ -36,6 +39,7 @@ import (
 	"github.com/u-root/u-root/pkg/boot/netboot"
 	"github.com/u-root/u-root/pkg/curl"
 	"github.com/u-root/u-root/pkg/dhclient"
+	"github.com/u-root/u-root/pkg/mount"
 	"github.com/u-root/u-root/pkg/sh"
 	"github.com/u-root/u-root/pkg/ulog"
 
@@ -62,8 +66,9 @@ const (
 )
 
 // NetbootImages requests DHCP on every ifaceNames interface, and parses
-// netboot images from the DHCP leases. Returns bootable OSes.
-func NetbootImages(ifaceNames string) ([]boot.OSImage, error) {
+// netboot images from the DHCP leases. Returns bootable OSes. The file systems
+// of iSCSI targets booted from are mounted in mp.
+func NetbootImages(ifaceNames string, mp *mount.Pool) ([]boot.OSImage, error) {
 	filteredIfs, err := dhclient.Interfaces(ifaceNames)
 	if err != nil {
 		return nil, err
@@ -111,7 +116,7 @@ func NetbootImages(ifaceNames string) ([]boot.OSImage, error) {
 			}
 
 			// Don't use the other context, as it's for the DHCP timeout.
-			imgs, err := netboot.BootImages(context.Background(), ulog.Log, curl.DefaultSchemes, result.Lease)
+			imgs, err := netboot.BootImages(context.Background(), ulog.Log, curl.DefaultSchemes, result.Lease, mp)
 			if err != nil {
 				log.Printf("Failed to boot lease %v: %v", result.Lease, err)
 				continue
@@ -161,8 +166,9 @@ func main() {
 
 	var images []boot.OSImage
 	var err error
+	mountPool := &mount.Pool{}
 	if *bootfile == "" {
-		images, err = NetbootImages(ifName)
+		images, err = NetbootImages(ifName, mountPool)
 		if err != nil {
 			dumpNetDebugInfo()
 		}
@@ -171,7 +177,7 @@ func main() {
 		var l dhclient.Lease
 		l, err = newManualLease()
 		if err == nil {
-			images, err = netboot.BootImages(context.Background(), ulog.Log, curl.DefaultSchemes, l)
+			images, err = netboot.BootImages(context.Background(), ulog.Log, curl.DefaultSchemes, l, mountPool)
 		}
 	}
 
@@ -190,5 +196,5 @@ func main() {
 	menuEntries = append(menuEntries, menu.StartShell{})
 
 	// Boot does not return.
-	bootcmd.ShowMenuAndBoot(menuEntries, nil, *noLoad, *noExec)
+	bootcmd.ShowMenuAndBoot(menuEntries, mountPool, *noLoad, *noExec)
 }
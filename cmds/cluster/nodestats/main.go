@@ -20,6 +20,7 @@ import (
 
 	"github.com/jaypipes/ghw"
 	"github.com/u-root/u-root/pkg/cluster/health"
+	"github.com/u-root/u-root/pkg/lldp"
 )
 
 func node() *health.Stat {
@@ -59,6 +60,11 @@ func node() *health.Stat {
 		reflect.ValueOf(&k).Elem().Field(i).SetString(string(dat))
 	}
 
+	neighbors, err := lldp.ReadStateFile(lldp.DefaultStateFile)
+	if !errors.Is(err, os.ErrNotExist) {
+		errs = errors.Join(errs, err)
+	}
+
 	// ReadAll would be a bit dangerous in this context.
 	// Read a reasonable amount, and record if we did not get
 	// it all.
@@ -69,7 +75,7 @@ func node() *health.Stat {
 		errs = errors.Join(errs, fmt.Errorf("stderr read %d bytes, got %w but not io.EOF or nil", n, err))
 	}
 
-	stats := &health.Stat{Hostname: hn, Info: host, Kernel: k, Stderr: string(Stderr[:n])}
+	stats := &health.Stat{Hostname: hn, Info: host, Kernel: k, LLDP: neighbors, Stderr: string(Stderr[:n])}
 	if errs != nil {
 		stats.Err = errs.Error()
 	}
@@ -8,20 +8,46 @@
 //
 // Synopsis:
 //
-//	dhclient [OPTIONS...]
+//	dhclient [OPTIONS...] [IFACE-REGEXP]
+//
+// Description:
+//
+//	dhclient configures the matching interfaces with DHCPv4 and DHCPv6
+//	leases and exits.
+//
+//	With -d, dhclient keeps running. It renews and rebinds leases before
+//	they expire, removes leases that expire or that the server declines,
+//	extends leases when a link comes back up, and releases them when it
+//	gets SIGINT or SIGTERM. It serves the state of every lease as JSON on
+//	a unix socket, which dhclient -status prints.
 //
 // Options:
 //
-//	-timeout:  lease timeout in seconds
-//	-renewals: number of DHCP renewals before exiting
-//	-verbose:  verbose output
+//	-d:       keep leases up to date until killed
+//	-dry-run: make DHCP requests without configuring interfaces
+//	-ipv4:    use DHCPv4
+//	-ipv6:    use DHCPv6
+//	-release: release leases when a daemon exits (default true)
+//	-retry:   max number of attempts to send a request, -1 for infinity
+//	-socket:  status socket of the daemon (default /run/dhclient.sock)
+//	-status:  print the lease state of a running daemon and exit
+//	-timeout: lease timeout in seconds
+//	-v, -vv:  verbose output
 package main
 
 import (
+	"cmp"
 	"context"
+	"encoding/json"
 	"flag"
+	"fmt"
+	"io"
 	"log"
 	"net"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
 	"time"
 
 	"github.com/insomniacslk/dhcp/dhcpv4"
@@ -44,6 +70,11 @@ var (
 	v6Server = flag.String("v6-server", "ff02::1:2", "DHCPv6 server address to send to (multicast or unicast)")
 
 	v4Port = flag.Int("v4-port", dhcpv4.ServerPort, "DHCPv4 server port to send to")
+
+	daemon  = flag.Bool("d", false, "Keep leases up to date until killed")
+	release = flag.Bool("release", true, "Release leases when the daemon exits")
+	socket  = flag.String("socket", "/run/dhclient.sock", "Status socket of the daemon")
+	status  = flag.Bool("status", false, "Print the lease state of a running daemon and exit")
 )
 
 func main() {
@@ -52,6 +83,13 @@ func main() {
 		log.Fatalf("only one re")
 	}
 
+	if *status {
+		if err := printStatus(os.Stdout, *socket); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	if len(flag.Args()) > 0 {
 		ifName = flag.Args()[0]
 	}
@@ -61,10 +99,16 @@ func main() {
 		log.Fatal(err)
 	}
 
+	if *daemon {
+		if err := runDaemon(filteredIfs); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 	configureAll(filteredIfs)
 }
 
-func configureAll(ifs []netlink.Link) {
+func config() dhclient.Config {
 	packetTimeout := time.Duration(*timeout) * time.Second
 
 	c := dhclient.Config{
@@ -85,7 +129,11 @@ func configureAll(ifs []netlink.Link) {
 	if *vverbose {
 		c.LogLevel = dhclient.LogDebug
 	}
-	r := dhclient.SendRequests(context.Background(), ifs, *ipv4, *ipv6, c, 30*time.Second)
+	return c
+}
+
+func configureAll(ifs []netlink.Link) {
+	r := dhclient.SendRequests(context.Background(), ifs, *ipv4, *ipv6, config(), 30*time.Second)
 
 	for result := range r {
 		if result.Err != nil {
@@ -100,3 +148,65 @@ func configureAll(ifs []netlink.Link) {
 	}
 	log.Printf("Finished trying to configure all interfaces.")
 }
+
+// runDaemon keeps leases on ifs until SIGINT or SIGTERM.
+func runDaemon(ifs []netlink.Link) error {
+	m := &dhclient.Manager{
+		Config:        config(),
+		IPv4:          *ipv4,
+		IPv6:          *ipv6,
+		LinkUpTimeout: 30 * time.Second,
+		Release:       *release,
+		DryRun:        *dryRun,
+	}
+
+	// A socket left behind by a daemon that died is in the way.
+	if c, err := net.Dial("unix", *socket); err == nil {
+		c.Close()
+		return fmt.Errorf("a daemon is already serving %s", *socket)
+	}
+	os.Remove(*socket)
+	ln, err := net.Listen("unix", *socket)
+	if err != nil {
+		return err
+	}
+	defer ln.Close()
+	go func() {
+		if err := m.ServeStatus(ln); err != nil {
+			log.Printf("Status socket: %v", err)
+		}
+	}()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return m.Run(ctx, ifs)
+}
+
+// printStatus prints the state of the leases of the daemon serving socket.
+func printStatus(w io.Writer, socket string) error {
+	c, err := net.Dial("unix", socket)
+	if err != nil {
+		return fmt.Errorf("no daemon: %w", err)
+	}
+	defer c.Close()
+	var s []dhclient.BindingStatus
+	if err := json.NewDecoder(c).Decode(&s); err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "INTERFACE\tPROTOCOL\tSTATE\tADDRESS\tSERVER\tEXPIRES")
+	for _, b := range s {
+		expires := "never"
+		switch {
+		case b.Address == "":
+			expires = "-"
+		case !b.Expire.IsZero():
+			expires = b.Expire.Format(time.RFC3339)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", b.Interface, b.Protocol, b.State, cmp.Or(b.Address, "-"), cmp.Or(b.Server, "-"), expires)
+		if b.Error != "" {
+			fmt.Fprintf(tw, "\t\terror: %s\n", b.Error)
+		}
+	}
+	return tw.Flush()
+}
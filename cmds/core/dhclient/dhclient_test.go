@@ -6,6 +6,9 @@
 package main
 
 import (
+	"io"
+	"net"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -36,6 +39,39 @@ func TestDhclient(t *testing.T) {
 	}
 }
 
+func TestPrintStatus(t *testing.T) {
+	sock := filepath.Join(t.TempDir(), "sock")
+	if err := printStatus(io.Discard, sock); err == nil {
+		t.Errorf("printStatus without a daemon succeeded")
+	}
+	ln, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		io.WriteString(c, `[{"interface":"eth0","protocol":"IPv4","state":"bound","address":"192.0.2.10/24","server":"192.0.2.1","expire":"2026-10-18T12:00:00Z"},{"interface":"eth0","protocol":"IPv6","state":"init","error":"no response"}]`)
+	}()
+	var b strings.Builder
+	if err := printStatus(&b, sock); err != nil {
+		t.Fatalf("printStatus = %v", err)
+	}
+	for _, want := range []string{
+		"eth0       IPv4      bound  192.0.2.10/24  192.0.2.1  2026-10-18T12:00:00Z",
+		"eth0       IPv6      init   -              -          -",
+		"error: no response",
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("status\n%s\ndoes not contain %q", b.String(), want)
+		}
+	}
+}
+
 func TestMain(m *testing.M) {
 	testutil.Run(m, main)
 }
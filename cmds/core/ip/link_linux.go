@@ -57,7 +57,7 @@ const linkHelp = `Usage: ip link add  [ name ] NAME
 
 	ip link help
 
-TYPE := { bareudp | bond |bridge | dummy | ifb | vxlan }
+TYPE := { bareudp | bond |bridge | dummy | ifb | vxlan | wireguard }
 
 `
 
@@ -426,6 +426,8 @@ func (cmd *cmd) linkAdd() error {
 		return cmd.handle.LinkAdd(&netlink.IPoIB{LinkAttrs: attrs})
 	case "bareudp":
 		return cmd.handle.LinkAdd(&netlink.BareUDP{LinkAttrs: attrs})
+	case "wireguard":
+		return cmd.wireguardAdd(attrs)
 	default:
 		return fmt.Errorf("unsupported link type %s", typeName)
 	}
@@ -927,6 +929,8 @@ func (p *linkPrinter) deviceDetailsLine(t any) string {
 		line = fmt.Sprintf("    ipoib pkey %d mode %d umcast %d", dev.Pkey, dev.Mode, dev.Umcast)
 	case *netlink.BareUDP:
 		line = fmt.Sprintf("    port %d ethertype %d srcport %d min multi_proto %t", dev.Port, dev.EtherType, dev.SrcPortMin, dev.MultiProto)
+	case *netlink.Wireguard:
+		line = "    wireguard"
 	}
 
 	return line
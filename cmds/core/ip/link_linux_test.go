@@ -1427,6 +1427,11 @@ func TestDeviceDetailsLine(t *testing.T) {
 			},
 			expected: "    port 4789 ethertype 2048 srcport 1024 min multi_proto true",
 		},
+		{
+			name:     "Wireguard device",
+			device:   &netlink.Wireguard{},
+			expected: "    wireguard",
+		},
 	}
 
 	for _, tt := range tests {
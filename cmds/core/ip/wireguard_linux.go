@@ -0,0 +1,67 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//go:build !tinygo || tinygo.enable
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+)
+
+// wireguardSocketDir is where userspace WireGuard interfaces have their
+// configuration sockets, as in wg(8).
+var wireguardSocketDir = "/var/run/wireguard"
+
+// startWireguard starts the userspace WireGuard interface name, for
+// kernels without WireGuard.
+var startWireguard = func(name string) error {
+	c := exec.Command("wg", "userspace", name)
+	// It outlives ip.
+	c.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := c.Start(); err != nil {
+		return err
+	}
+	return c.Process.Release()
+}
+
+// wireguardAdd adds a WireGuard link, which wg then configures. It falls
+// back to a userspace interface when the kernel has no WireGuard.
+func (cmd *cmd) wireguardAdd(attrs netlink.LinkAttrs) error {
+	err := cmd.handle.LinkAdd(&netlink.Wireguard{LinkAttrs: attrs})
+	if !errors.Is(err, unix.EOPNOTSUPP) {
+		return err
+	}
+	if err := startWireguard(attrs.Name); err != nil {
+		return fmt.Errorf("kernel has no WireGuard, and userspace failed: %w", err)
+	}
+
+	sock := filepath.Join(wireguardSocketDir, attrs.Name+".sock")
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := os.Stat(sock); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("userspace WireGuard %s did not start", attrs.Name)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if attrs.MTU == 0 {
+		return nil
+	}
+	link, err := cmd.handle.LinkByName(attrs.Name)
+	if err != nil {
+		return err
+	}
+	return cmd.handle.LinkSetMTU(link, attrs.MTU)
+}
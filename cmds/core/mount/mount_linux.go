@@ -11,6 +11,14 @@
 // Options:
 //
 //	-r: read only
+//
+// NFS:
+//
+//	mount -t nfs HOST:/EXPORT PATH mounts an NFSv3 export without mount.nfs.
+//	The NFS options of -o are vers=3, port, mountport, rsize, wsize, timeo,
+//	retrans, acregmin, acregmax, acdirmin, acdirmax, actimeo, soft, hard,
+//	intr, lock, ac, cto, acl, rdirplus, their no forms, tcp, udp and
+//	proto. Locking is off unless lock is given, as it needs rpc.statd.
 package main
 
 import (
@@ -155,6 +163,9 @@ func (c *cmd) run(args ...string) error {
 	if c.ro {
 		flags |= unix.MS_RDONLY
 	}
+	if c.fsType == "nfs" {
+		return mountNFS(dev, path, data, flags)
+	}
 	if c.fsType == "" {
 		if _, err := mount.TryMount(dev, path, strings.Join(data, ","), flags); err != nil {
 			return err
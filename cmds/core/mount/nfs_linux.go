@@ -0,0 +1,38 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/u-root/u-root/pkg/mount"
+	"github.com/u-root/u-root/pkg/nfs"
+)
+
+// nfsTimeout bounds the MOUNT handshake.
+const nfsTimeout = time.Minute
+
+// mountNFS mounts the NFSv3 export dev, host:/export, at path. The kernel
+// takes the root file handle of the export from the MOUNT handshake, which
+// is done here, in binary mount data.
+func mountNFS(dev, path string, data []string, flags uintptr) error {
+	host, export, err := nfs.SplitSource(dev)
+	if err != nil {
+		return err
+	}
+	o, err := nfs.ParseMountOptions(data)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), nfsTimeout)
+	defer cancel()
+	b, err := (&nfs.Dialer{}).KernelMountData(ctx, host, export, o)
+	if err != nil {
+		return err
+	}
+	_, err = mount.MountData(dev, path, "nfs", b, flags)
+	return err
+}
@@ -0,0 +1,180 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !tinygo
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/pkg/sftp"
+	"github.com/u-root/u-root/pkg/sshclient"
+	"golang.org/x/crypto/ssh"
+)
+
+var (
+	errUsage   = errors.New("usage: scp [-rpv] [-P port] [-i identity] [-F config] [-o option] source... target")
+	errNotDir  = errors.New("target is not a directory")
+	errNoMatch = errors.New("no such file")
+)
+
+// location is a local path, or a path on a server if host is set.
+type location struct {
+	user, host, path string
+}
+
+// parseLocation parses [user@]host:path. Paths without a colon, or with a
+// slash before it, are local, as for OpenSSH's scp.
+func parseLocation(s string) location {
+	brackets := false
+	for i, c := range s {
+		switch {
+		case c == '[':
+			brackets = true
+		case c == ']':
+			brackets = false
+		case brackets:
+		case c == '/':
+			return location{path: s}
+		case c == ':':
+			if i == 0 {
+				return location{path: s}
+			}
+			var l location
+			l.host, l.path = s[:i], s[i+1:]
+			if at := strings.LastIndex(l.host, "@"); at >= 0 {
+				l.user, l.host = l.host[:at], l.host[at+1:]
+			}
+			l.host = strings.TrimSuffix(strings.TrimPrefix(l.host, "["), "]")
+			if l.path == "" {
+				// The login directory.
+				l.path = "."
+			}
+			return l
+		}
+	}
+	return location{path: s}
+}
+
+// remote is a connection to a server.
+type remote struct {
+	ssh  *ssh.Client
+	sftp *sftp.Client
+}
+
+type client struct {
+	dialer *sshclient.Dialer
+	port   string
+	opts   sshclient.CopyOptions
+
+	remotes map[string]*remote
+}
+
+// fs returns the file tree of l, dialing its server if need be.
+func (c *client) fs(ctx context.Context, l location) (sshclient.FS, error) {
+	if l.host == "" {
+		return sshclient.LocalFS{}, nil
+	}
+	key := l.user + "@" + l.host
+	if r, ok := c.remotes[key]; ok {
+		return sshclient.SFTPFS{Client: r.sftp}, nil
+	}
+	conn, err := c.dialer.Dial(ctx, l.user, l.host, c.port)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", l.host, err)
+	}
+	s, err := sftp.NewClient(conn)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("%s: sftp: %w", l.host, err)
+	}
+	c.remotes[key] = &remote{ssh: conn, sftp: s}
+	return sshclient.SFTPFS{Client: s}, nil
+}
+
+func (c *client) close() {
+	for _, r := range c.remotes {
+		r.sftp.Close()
+		r.ssh.Close()
+	}
+}
+
+// copy copies srcs to dst. It copies as much as it can, and returns all
+// errors.
+func (c *client) copy(ctx context.Context, srcs []string, dst string) error {
+	dl := parseLocation(dst)
+	to, err := c.fs(ctx, dl)
+	if err != nil {
+		return err
+	}
+	if len(srcs) > 1 {
+		if fi, err := to.Stat(dl.path); err != nil || !fi.IsDir() {
+			return fmt.Errorf("%s: %w", dst, errNotDir)
+		}
+	}
+
+	var errs []error
+	for _, src := range srcs {
+		sl := parseLocation(src)
+		from, err := c.fs(ctx, sl)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		paths := []string{sl.path}
+		if s, ok := from.(sshclient.SFTPFS); ok && strings.ContainsAny(sl.path, `*?[\`) {
+			// The shell expands local globs, and we remote ones.
+			if paths, err = s.Glob(sl.path); err != nil || len(paths) == 0 {
+				errs = append(errs, fmt.Errorf("%s: %w", src, errNoMatch))
+				continue
+			}
+		}
+		for _, p := range paths {
+			errs = append(errs, sshclient.Copy(to, dl.path, from, p, &c.opts))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func runClient(args []string) error {
+	if len(args) < 2 {
+		return errUsage
+	}
+	d := &sshclient.Dialer{Prompt: sshclient.TerminalPrompt}
+	cf := *configFile
+	if cf == "" {
+		cf = sshclient.DefaultConfigFile()
+	}
+	config, err := sshclient.LoadConfig(cf)
+	if err != nil {
+		return fmt.Errorf("%s: %w", cf, err)
+	}
+	d.Config = config
+	for _, o := range options {
+		if err := d.SetOption(o); err != nil {
+			return err
+		}
+	}
+	if *identity != "" {
+		d.IdentityFiles = []string{*identity}
+	}
+
+	c := &client{
+		dialer:  d,
+		port:    *port,
+		opts:    sshclient.CopyOptions{Recursive: *recursive, Preserve: *preserve},
+		remotes: map[string]*remote{},
+	}
+	if *verbose {
+		d.Logf = log.Printf
+		c.opts.Logf = log.Printf
+	}
+	defer c.close()
+	return c.copy(context.Background(), args[:len(args)-1], args[len(args)-1])
+}
@@ -0,0 +1,73 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !tinygo
+
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/u-root/u-root/pkg/sshclient"
+)
+
+func TestParseLocation(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		want location
+	}{
+		{"file", location{path: "file"}},
+		{"/abs/file", location{path: "/abs/file"}},
+		{"./a:b", location{path: "./a:b"}},
+		{":file", location{path: ":file"}},
+		{"node:", location{host: "node", path: "."}},
+		{"node:/var/log", location{host: "node", path: "/var/log"}},
+		{"root@node:log", location{user: "root", host: "node", path: "log"}},
+		{"a@b@node:x", location{user: "a@b", host: "node", path: "x"}},
+		{"root@[fe80::1]:/tmp", location{user: "root", host: "fe80::1", path: "/tmp"}},
+	} {
+		if got := parseLocation(tt.in); got != tt.want {
+			t.Errorf("parseLocation(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestClientCopy(t *testing.T) {
+	dir := t.TempDir()
+	for _, f := range []string{"a", "b", "d/c"} {
+		p := filepath.Join(dir, f)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(f), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c := &client{remotes: map[string]*remote{}}
+	ctx := context.Background()
+	a, b, d := filepath.Join(dir, "a"), filepath.Join(dir, "b"), filepath.Join(dir, "d")
+
+	if err := c.copy(ctx, []string{a, b}, a); !errors.Is(err, errNotDir) {
+		t.Errorf("copy to a file = %v, want %v", err, errNotDir)
+	}
+
+	dst := t.TempDir()
+	// The directory is skipped, but the files are copied.
+	if err := c.copy(ctx, []string{a, d, b}, dst); !errors.Is(err, sshclient.ErrIsDir) {
+		t.Errorf("copy of a directory = %v, want %v", err, sshclient.ErrIsDir)
+	}
+	c.opts.Recursive = true
+	if err := c.copy(ctx, []string{d}, dst); err != nil {
+		t.Errorf("copy -r = %v", err)
+	}
+	for _, f := range []string{"a", "b", "d/c"} {
+		if b, err := os.ReadFile(filepath.Join(dst, f)); err != nil || string(b) != f {
+			t.Errorf("%s = %q, %v, want %q", f, b, err, f)
+		}
+	}
+}
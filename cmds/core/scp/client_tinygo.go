@@ -0,0 +1,13 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build tinygo
+
+package main
+
+import "errors"
+
+func runClient(args []string) error {
+	return errors.New("only -t and -f are supported with tinygo")
+}
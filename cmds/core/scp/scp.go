@@ -6,18 +6,36 @@
 //
 // Synopsis:
 //
+//	scp [-rpv] [-P PORT] [-i IDENTITY] [-F CONFIG] [-o OPTION] SOURCE... TARGET
 //	scp [-t|-f] [FILE]
 //
 // Description:
 //
+//	Without -t or -f, scp copies the SOURCE files to TARGET, which may be
+//	local paths or [user@]host:path on SSH servers. Files are copied over
+//	SFTP, as OpenSSH's scp does by default; remote sources may be globs.
+//	With several sources, TARGET must be a directory. Copies between two
+//	servers go through this host.
+//
+//	Servers are dialed with the settings of ~/.ssh/config, the keys of
+//	the agent of SSH_AUTH_SOCK and of the identity files, and their host
+//	keys are checked against the known_hosts files.
+//
 //	If -t is given, decode SCP protocol from stdin and write to FILE.
 //	If -f is given, stream FILE over SCP protocol to stdout.
 //
 // Options:
 //
+//	-r: Copy directories recursively
+//	-p: Preserve modes and times
+//	-P: Port of the servers
+//	-i: Identity file, tried before those of the config
+//	-F: ssh_config file (default ~/.ssh/config)
+//	-o: ssh_config option as Key=Value, may be repeated
+//	-q: Quiet, ignored
+//	-v: Log what is done; ignored with -t and -f
 //	-t: Act as the target
 //	-f: Act as the source
-//	-v: Passed if SCP is verbose, ignored
 package main
 
 import (
@@ -27,6 +45,8 @@ import (
 	"log"
 	"os"
 	"path"
+
+	"github.com/u-root/u-root/pkg/uroot/unixflag"
 )
 
 const (
@@ -34,11 +54,25 @@ const (
 )
 
 var (
-	isTarget = flag.Bool("t", false, "Act as the target")
-	isSource = flag.Bool("f", false, "Act as the source")
-	_        = flag.Bool("v", false, "Ignored")
+	isTarget   = flag.Bool("t", false, "Act as the target")
+	isSource   = flag.Bool("f", false, "Act as the source")
+	verbose    = flag.Bool("v", false, "Log what is done")
+	recursive  = flag.Bool("r", false, "Copy directories recursively")
+	preserve   = flag.Bool("p", false, "Preserve modes and times")
+	port       = flag.String("P", "", "Port of the servers")
+	identity   = flag.String("i", "", "Identity file")
+	configFile = flag.String("F", "", "ssh_config file (default ~/.ssh/config)")
+	_          = flag.Bool("q", false, "Ignored")
+	options    []string
 )
 
+func init() {
+	flag.Func("o", "ssh_config option as Key=Value", func(s string) error {
+		options = append(options, s)
+		return nil
+	})
+}
+
 func scpSingleSource(w io.Writer, r io.Reader, pth string) error {
 	f, err := os.Open(pth)
 	if err != nil {
@@ -130,7 +164,14 @@ func response(in io.Reader) byte {
 }
 
 func main() {
-	flag.Parse()
+	flag.CommandLine.Parse(unixflag.ArgsWithValuesToGoArgs(os.Args[1:], "PiFo"))
+
+	if !*isSource && !*isTarget {
+		if err := runClient(flag.Args()); err != nil {
+			log.Fatalf("scp: %v", err)
+		}
+		return
+	}
 
 	if flag.NArg() == 0 {
 		log.Fatalf("no file provided")
@@ -0,0 +1,500 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !tinygo || tinygo.enable
+
+// Sftp transfers files with an SFTP server.
+//
+// Synopsis:
+//
+//	sftp [-v] [-b BATCHFILE] [-P PORT] [-i IDENTITY] [-F CONFIG] [-o OPTION] [user@]host[:dir]
+//
+// Description:
+//
+//	Sftp logs in to host and runs the commands it reads from stdin, or
+//	from BATCHFILE. In batch mode, the commands are echoed, and the first
+//	one that fails ends sftp with an error, unless it is prefixed with -.
+//
+//	Servers are dialed with the settings of ~/.ssh/config, the keys of
+//	the agent of SSH_AUTH_SOCK and of the identity files, and their host
+//	keys are checked against the known_hosts files.
+//
+// Commands:
+//
+//	cd [DIR]                     Change the remote directory, home by default
+//	lcd DIR                      Change the local directory
+//	pwd, lpwd                    Print the remote or local directory
+//	ls [-la] [PATH]              List remote files; PATH may be a glob
+//	get [-pr] REMOTE [LOCAL]     Download files; REMOTE may be a glob
+//	put [-pr] LOCAL [REMOTE]     Upload files; LOCAL may be a glob
+//	mkdir DIR, rmdir DIR         Create or remove a remote directory
+//	rm PATH                      Remove remote files; PATH may be a glob
+//	rename OLD NEW               Rename a remote file
+//	chmod MODE PATH              Change the mode of a remote file
+//	ln [-s] OLD NEW              Link a remote file
+//	help                         Print the commands
+//	exit, quit, bye              Quit
+//
+//	get and put copy directories with -r, and keep modes and times with -p.
+//
+// Options:
+//
+//	-b: Batch file, - for stdin
+//	-P: Port of the server
+//	-i: Identity file, tried before those of the config
+//	-F: ssh_config file (default ~/.ssh/config)
+//	-o: ssh_config option as Key=Value, may be repeated
+//	-v: Log what is done
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/sftp"
+	"github.com/u-root/u-root/pkg/shlex"
+	"github.com/u-root/u-root/pkg/sshclient"
+	"github.com/u-root/u-root/pkg/uroot/unixflag"
+	"golang.org/x/term"
+)
+
+var (
+	errUsage   = errors.New("usage: sftp [-v] [-b batchfile] [-P port] [-i identity] [-F config] [-o option] [user@]host[:dir]")
+	errQuit    = errors.New("quit")
+	errCommand = errors.New("unknown command, try help")
+	errArgs    = errors.New("wrong arguments, try help")
+	errNotDir  = errors.New("not a directory")
+	errNoMatch = errors.New("no such file")
+	errBatch   = errors.New("batch command failed")
+)
+
+const help = `cd [DIR]                     Change the remote directory, home by default
+lcd DIR                      Change the local directory
+pwd, lpwd                    Print the remote or local directory
+ls [-la] [PATH]              List remote files; PATH may be a glob
+get [-pr] REMOTE [LOCAL]     Download files; REMOTE may be a glob
+put [-pr] LOCAL [REMOTE]     Upload files; LOCAL may be a glob
+mkdir DIR, rmdir DIR         Create or remove a remote directory
+rm PATH                      Remove remote files; PATH may be a glob
+rename OLD NEW               Rename a remote file
+chmod MODE PATH              Change the mode of a remote file
+ln [-s] OLD NEW              Link a remote file
+help                         Print the commands
+exit, quit, bye              Quit
+`
+
+// session runs commands on an SFTP server.
+type session struct {
+	c      *sftp.Client
+	home   string
+	cwd    string
+	stdout io.Writer
+	logf   func(format string, v ...any)
+}
+
+func newSession(c *sftp.Client, stdout io.Writer) (*session, error) {
+	home, err := c.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	return &session{c: c, home: home, cwd: home, stdout: stdout}, nil
+}
+
+// remote returns the remote path of p.
+func (s *session) remote(p string) string {
+	if path.IsAbs(p) {
+		return path.Clean(p)
+	}
+	return path.Join(s.cwd, p)
+}
+
+// glob expands p on the server if it is a pattern.
+func (s *session) glob(p string) ([]string, error) {
+	p = s.remote(p)
+	if !strings.ContainsAny(p, `*?[\`) {
+		return []string{p}, nil
+	}
+	m, err := s.c.Glob(p)
+	if err == nil && len(m) == 0 {
+		err = errNoMatch
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", p, err)
+	}
+	return m, nil
+}
+
+// flags splits the leading single letter flags of args from the others.
+func flags(args []string, allowed string) (map[rune]bool, []string, error) {
+	set := map[rune]bool{}
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") && len(args[0]) > 1 {
+		if args[0] == "--" {
+			return set, args[1:], nil
+		}
+		for _, f := range args[0][1:] {
+			if !strings.ContainsRune(allowed, f) {
+				return nil, nil, fmt.Errorf("-%c: %w", f, errArgs)
+			}
+			set[f] = true
+		}
+		args = args[1:]
+	}
+	return set, args, nil
+}
+
+// run runs a command line.
+func (s *session) run(line string) error {
+	argv := shlex.Argv(line)
+	if len(argv) == 0 {
+		return nil
+	}
+	cmd, args := argv[0], argv[1:]
+	nargs := func(min, max int) error {
+		if len(args) < min || len(args) > max {
+			return fmt.Errorf("%s: %w", cmd, errArgs)
+		}
+		return nil
+	}
+
+	switch cmd {
+	case "exit", "quit", "bye":
+		return errQuit
+	case "help", "?":
+		_, err := io.WriteString(s.stdout, help)
+		return err
+	case "pwd":
+		fmt.Fprintf(s.stdout, "Remote working directory: %s\n", s.cwd)
+	case "lpwd":
+		wd, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(s.stdout, "Local working directory: %s\n", wd)
+	case "cd":
+		if err := nargs(0, 1); err != nil {
+			return err
+		}
+		dir := s.home
+		if len(args) == 1 {
+			dir = s.remote(args[0])
+		}
+		return s.cd(dir)
+	case "lcd":
+		if err := nargs(1, 1); err != nil {
+			return err
+		}
+		return os.Chdir(args[0])
+	case "ls", "dir":
+		f, args, err := flags(args, "la")
+		if err != nil {
+			return err
+		}
+		if len(args) > 1 {
+			return fmt.Errorf("%s: %w", cmd, errArgs)
+		}
+		return s.ls(args, f['l'], f['a'])
+	case "get", "put":
+		f, args, err := flags(args, "prR")
+		if err != nil {
+			return err
+		}
+		if len(args) < 1 || len(args) > 2 {
+			return fmt.Errorf("%s: %w", cmd, errArgs)
+		}
+		o := &sshclient.CopyOptions{Recursive: f['r'] || f['R'], Preserve: f['p'], Logf: s.logf}
+		if cmd == "get" {
+			return s.get(args, o)
+		}
+		return s.put(args, o)
+	case "mkdir":
+		if err := nargs(1, 1); err != nil {
+			return err
+		}
+		return s.c.Mkdir(s.remote(args[0]))
+	case "rmdir":
+		if err := nargs(1, 1); err != nil {
+			return err
+		}
+		return s.c.RemoveDirectory(s.remote(args[0]))
+	case "rm":
+		if err := nargs(1, 1); err != nil {
+			return err
+		}
+		paths, err := s.glob(args[0])
+		if err != nil {
+			return err
+		}
+		var errs []error
+		for _, p := range paths {
+			errs = append(errs, s.c.Remove(p))
+		}
+		return errors.Join(errs...)
+	case "rename":
+		if err := nargs(2, 2); err != nil {
+			return err
+		}
+		return s.c.Rename(s.remote(args[0]), s.remote(args[1]))
+	case "chmod":
+		if err := nargs(2, 2); err != nil {
+			return err
+		}
+		mode, err := strconv.ParseUint(args[0], 8, 32)
+		if err != nil {
+			return fmt.Errorf("mode %q: %w", args[0], err)
+		}
+		return s.c.Chmod(s.remote(args[1]), os.FileMode(mode))
+	case "ln", "symlink":
+		f, args, err := flags(args, "s")
+		if err != nil {
+			return err
+		}
+		if len(args) != 2 {
+			return fmt.Errorf("%s: %w", cmd, errArgs)
+		}
+		if f['s'] || cmd == "symlink" {
+			// The target is kept as given, relative to the link.
+			return s.c.Symlink(args[0], s.remote(args[1]))
+		}
+		return s.c.Link(s.remote(args[0]), s.remote(args[1]))
+	default:
+		return fmt.Errorf("%s: %w", cmd, errCommand)
+	}
+	return nil
+}
+
+func (s *session) cd(dir string) error {
+	fi, err := s.c.Stat(dir)
+	if err != nil {
+		return err
+	}
+	if !fi.IsDir() {
+		return fmt.Errorf("%s: %w", dir, errNotDir)
+	}
+	s.cwd = dir
+	return nil
+}
+
+func (s *session) ls(args []string, long, all bool) error {
+	p := "."
+	if len(args) == 1 {
+		p = args[0]
+	}
+	paths, err := s.glob(p)
+	if err != nil {
+		return err
+	}
+	var fis []fs.FileInfo
+	for _, p := range paths {
+		fi, err := s.c.Stat(p)
+		if err != nil {
+			return err
+		}
+		if !fi.IsDir() || len(paths) > 1 {
+			fis = append(fis, fi)
+			continue
+		}
+		ents, err := s.c.ReadDir(p)
+		if err != nil {
+			return err
+		}
+		for _, e := range ents {
+			if all || !strings.HasPrefix(e.Name(), ".") {
+				fis = append(fis, e)
+			}
+		}
+	}
+	slices.SortFunc(fis, func(a, b fs.FileInfo) int { return strings.Compare(a.Name(), b.Name()) })
+	for _, fi := range fis {
+		if !long {
+			fmt.Fprintln(s.stdout, fi.Name())
+			continue
+		}
+		var uid, gid uint32
+		if st, ok := fi.Sys().(*sftp.FileStat); ok {
+			uid, gid = st.UID, st.GID
+		}
+		fmt.Fprintf(s.stdout, "%v %5d %5d %10d %s %s\n", fi.Mode(), uid, gid, fi.Size(), fi.ModTime().Format("Jan _2 15:04"), fi.Name())
+	}
+	return nil
+}
+
+func (s *session) get(args []string, o *sshclient.CopyOptions) error {
+	paths, err := s.glob(args[0])
+	if err != nil {
+		return err
+	}
+	local := "."
+	if len(args) == 2 {
+		local = args[1]
+	}
+	if len(paths) > 1 {
+		if fi, err := os.Stat(local); err != nil || !fi.IsDir() {
+			return fmt.Errorf("%s: %w", local, errNotDir)
+		}
+	}
+	var errs []error
+	for _, p := range paths {
+		errs = append(errs, sshclient.Copy(sshclient.LocalFS{}, local, sshclient.SFTPFS{Client: s.c}, p, o))
+	}
+	return errors.Join(errs...)
+}
+
+func (s *session) put(args []string, o *sshclient.CopyOptions) error {
+	paths, err := filepath.Glob(args[0])
+	if err == nil && len(paths) == 0 {
+		err = errNoMatch
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", args[0], err)
+	}
+	remote := s.cwd
+	if len(args) == 2 {
+		remote = s.remote(args[1])
+	}
+	if len(paths) > 1 {
+		if fi, err := s.c.Stat(remote); err != nil || !fi.IsDir() {
+			return fmt.Errorf("%s: %w", remote, errNotDir)
+		}
+	}
+	var errs []error
+	for _, p := range paths {
+		errs = append(errs, sshclient.Copy(sshclient.SFTPFS{Client: s.c}, remote, sshclient.LocalFS{}, p, o))
+	}
+	return errors.Join(errs...)
+}
+
+// loop runs the commands of in. In batch mode, they are echoed, and errors
+// end the loop unless the command is prefixed with -. With a prompt, the
+// user is prompted for each command.
+func (s *session) loop(in io.Reader, stderr io.Writer, batch bool, prompt string) error {
+	sc := bufio.NewScanner(in)
+	for {
+		if prompt != "" {
+			fmt.Fprint(s.stdout, prompt)
+		}
+		if !sc.Scan() {
+			if prompt != "" {
+				fmt.Fprintln(s.stdout)
+			}
+			return sc.Err()
+		}
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		ignore := strings.HasPrefix(line, "-")
+		line = strings.TrimPrefix(line, "-")
+		if batch {
+			fmt.Fprintf(s.stdout, "sftp> %s\n", line)
+		}
+		err := s.run(line)
+		if errors.Is(err, errQuit) {
+			return nil
+		}
+		if err == nil {
+			continue
+		}
+		fmt.Fprintf(stderr, "%s: %v\n", strings.Fields(line)[0], err)
+		if batch && !ignore {
+			return fmt.Errorf("%q: %w", line, errBatch)
+		}
+	}
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	f := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	f.SetOutput(stderr)
+	batchFile := f.String("b", "", "Batch file, - for stdin")
+	port := f.String("P", "", "Port of the server")
+	identity := f.String("i", "", "Identity file")
+	configFile := f.String("F", "", "ssh_config file (default ~/.ssh/config)")
+	verbose := f.Bool("v", false, "Log what is done")
+	d := &sshclient.Dialer{Prompt: sshclient.TerminalPrompt}
+	f.Func("o", "ssh_config option as Key=Value", d.SetOption)
+	if err := f.Parse(unixflag.ArgsWithValuesToGoArgs(args[1:], "bPiFo")); err != nil {
+		return err
+	}
+	if f.NArg() != 1 {
+		return errUsage
+	}
+
+	cf := *configFile
+	if cf == "" {
+		cf = sshclient.DefaultConfigFile()
+	}
+	config, err := sshclient.LoadConfig(cf)
+	if err != nil {
+		return fmt.Errorf("%s: %w", cf, err)
+	}
+	d.Config = config
+	if *identity != "" {
+		d.IdentityFiles = []string{*identity}
+	}
+	if *verbose {
+		d.Logf = log.Printf
+	}
+
+	dest, dir, _ := strings.Cut(f.Arg(0), ":")
+	user, host := "", dest
+	if at := strings.LastIndex(dest, "@"); at >= 0 {
+		user, host = dest[:at], dest[at+1:]
+	}
+	conn, err := d.Dial(context.Background(), user, host, *port)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	c, err := sftp.NewClient(conn)
+	if err != nil {
+		return fmt.Errorf("sftp: %w", err)
+	}
+	defer c.Close()
+
+	s, err := newSession(c, stdout)
+	if err != nil {
+		return err
+	}
+	s.logf = d.Logf
+	if dir != "" {
+		if err := s.cd(s.remote(dir)); err != nil {
+			return err
+		}
+	}
+
+	in, batch, prompt := stdin, false, ""
+	switch *batchFile {
+	case "":
+		if f, ok := stdin.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+			prompt = "sftp> "
+		}
+	case "-":
+		batch = true
+	default:
+		bf, err := os.Open(*batchFile)
+		if err != nil {
+			return err
+		}
+		defer bf.Close()
+		in, batch = bf, true
+	}
+	return s.loop(in, stderr, batch, prompt)
+}
+
+func main() {
+	if err := run(os.Args, os.Stdin, os.Stdout, os.Stderr); err != nil {
+		log.Fatalf("sftp: %v", err)
+	}
+}
@@ -0,0 +1,148 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !tinygo || tinygo.enable
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pkg/sftp"
+)
+
+// newTestSession returns a session with an SFTP server of the local files on
+// pipes.
+func newTestSession(t *testing.T, stdout io.Writer) *session {
+	t.Helper()
+	cr, sw := io.Pipe()
+	sr, cw := io.Pipe()
+	srv, err := sftp.NewServer(struct {
+		io.Reader
+		io.WriteCloser
+	}{sr, sw})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		srv.Serve()
+		sw.Close()
+	}()
+	c, err := sftp.NewClientPipe(cr, cw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	s, err := newSession(c, stdout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestBatch(t *testing.T) {
+	remote, local := t.TempDir(), t.TempDir()
+	for _, f := range []string{"a.log", "b.log", "dir/c.log"} {
+		p := filepath.Join(local, f)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(f), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var stdout, stderr bytes.Buffer
+	s := newTestSession(t, &stdout)
+	got := t.TempDir()
+
+	batch := strings.Join([]string{
+		"cd " + remote,
+		"mkdir logs",
+		"cd logs",
+		"put " + filepath.Join(local, "*.log"),
+		"put -r " + filepath.Join(local, "dir"),
+		"# comments and empty lines are skipped",
+		"",
+		"-rm nothing",
+		"ls",
+		"rename a.log renamed.log",
+		"get -r " + filepath.Join(remote, "logs") + " " + got,
+		"rm *.log",
+		"ls",
+		"pwd",
+		"bye",
+		"ls",
+	}, "\n")
+	if err := s.loop(strings.NewReader(batch), &stderr, true, ""); err != nil {
+		t.Fatalf("loop = %v, stderr %q", err, stderr.String())
+	}
+
+	for _, want := range []string{
+		"sftp> ls\na.log\nb.log\ndir\nsftp>",
+		"sftp> ls\ndir\nsftp> pwd\nRemote working directory: " + filepath.Join(remote, "logs") + "\nsftp> bye\n",
+	} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Errorf("stdout = %q, want it to contain %q", stdout.String(), want)
+		}
+	}
+	if strings.Count(stdout.String(), "sftp> ") != 13 {
+		t.Errorf("stdout = %q, want the 13 commands up to bye", stdout.String())
+	}
+	if !strings.HasPrefix(stderr.String(), "rm: ") {
+		t.Errorf("stderr = %q, want the rm error", stderr.String())
+	}
+	for f, want := range map[string]string{
+		"logs/renamed.log": "a.log",
+		"logs/b.log":       "b.log",
+		"logs/dir/c.log":   "dir/c.log",
+	} {
+		if b, err := os.ReadFile(filepath.Join(got, f)); err != nil || string(b) != want {
+			t.Errorf("%s = %q, %v, want %q", f, b, err, want)
+		}
+	}
+}
+
+func TestBatchError(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	s := newTestSession(t, &stdout)
+	dir := t.TempDir()
+
+	err := s.loop(strings.NewReader("cd "+dir+"\nrmdir nothing\nmkdir never\n"), &stderr, true, "")
+	if !errors.Is(err, errBatch) {
+		t.Errorf("loop = %v, want %v", err, errBatch)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "never")); err == nil {
+		t.Errorf("the command after the failure ran")
+	}
+
+	// Interactively, errors do not stop sftp.
+	stdout.Reset()
+	stderr.Reset()
+	if err := s.loop(strings.NewReader("frobnicate\nget\nls -x\nchmod 999 x\nmkdir later\n"), &stderr, false, "sftp> "); err != nil {
+		t.Errorf("loop = %v, want nil", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "later")); err != nil {
+		t.Errorf("mkdir after errors: %v", err)
+	}
+	for _, want := range []error{errCommand, errArgs} {
+		if !strings.Contains(stderr.String(), want.Error()) {
+			t.Errorf("stderr = %q, want %q", stderr.String(), want)
+		}
+	}
+	if n := strings.Count(stdout.String(), "sftp> "); n != 6 {
+		t.Errorf("got %d prompts, want 6", n)
+	}
+}
+
+func TestUsage(t *testing.T) {
+	if err := run([]string{"sftp"}, nil, io.Discard, io.Discard); !errors.Is(err, errUsage) {
+		t.Errorf("run() = %v, want %v", err, errUsage)
+	}
+}
@@ -0,0 +1,217 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build (!tinygo || tinygo.enable) && !windows
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"path"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Permissions of a connection, named after the OpenSSH certificate options.
+const (
+	forceCommand         = "force-command"
+	permitPTY            = "permit-pty"
+	permitPortForwarding = "permit-port-forwarding"
+)
+
+var (
+	errNoPrincipal      = errors.New("certificate has no allowed principal")
+	errCommandConflicts = errors.New("force-command of certificate conflicts with command of authority")
+)
+
+// authorizedKey is an entry of an authorized_keys file, with the subset of
+// the OpenSSH options that sshd supports.
+type authorizedKey struct {
+	key ssh.PublicKey
+	// certAuthority trusts the user certificates signed by key, rather
+	// than key itself.
+	certAuthority bool
+	// principals replace the user name that a certificate has to name.
+	principals []string
+	command    string
+	// from is the pattern-list of the client addresses.
+	from             []string
+	noPTY            bool
+	noPortForwarding bool
+}
+
+type authorizedKeys []authorizedKey
+
+// parseAuthorizedKeys parses an authorized_keys file.
+func parseAuthorizedKeys(b []byte) (authorizedKeys, error) {
+	var keys authorizedKeys
+	for len(b) > 0 {
+		pubKey, _, options, rest, err := ssh.ParseAuthorizedKey(b)
+		if err != nil {
+			return nil, err
+		}
+		b = rest
+
+		k := authorizedKey{key: pubKey}
+		for _, o := range options {
+			name, val, _ := strings.Cut(o, "=")
+			val = strings.ReplaceAll(strings.TrimSuffix(strings.TrimPrefix(val, `"`), `"`), `\"`, `"`)
+			switch strings.ToLower(name) {
+			case "cert-authority":
+				k.certAuthority = true
+			case "principals":
+				k.principals = strings.Split(val, ",")
+			case "command":
+				k.command = val
+			case "from":
+				k.from = strings.Split(val, ",")
+			case "no-pty":
+				k.noPTY = true
+			case "no-port-forwarding":
+				k.noPortForwarding = true
+			case "restrict":
+				k.noPTY = true
+				k.noPortForwarding = true
+			case "pty":
+				k.noPTY = false
+			case "port-forwarding":
+				k.noPortForwarding = false
+			default:
+				dprintf("ignoring authorized_keys option %q", o)
+			}
+		}
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+// permissions returns the permissions of a connection authenticated with k.
+func (k *authorizedKey) permissions(pubKey ssh.PublicKey) *ssh.Permissions {
+	perms := &ssh.Permissions{
+		CriticalOptions: map[string]string{},
+		Extensions: map[string]string{
+			// Record the public key used for authentication.
+			"pubkey-fp": ssh.FingerprintSHA256(pubKey),
+		},
+	}
+	if k.command != "" {
+		perms.CriticalOptions[forceCommand] = k.command
+	}
+	if !k.noPTY {
+		perms.Extensions[permitPTY] = ""
+	}
+	if !k.noPortForwarding {
+		perms.Extensions[permitPortForwarding] = ""
+	}
+	return perms
+}
+
+// authenticate is the PublicKeyCallback of sshd. It accepts the keys of
+// keys, and the user certificates signed by their certificate authorities.
+func (keys authorizedKeys) authenticate(conn ssh.ConnMetadata, pubKey ssh.PublicKey) (*ssh.Permissions, error) {
+	if cert, ok := pubKey.(*ssh.Certificate); ok {
+		return keys.authenticateCert(conn, cert)
+	}
+
+	b := pubKey.Marshal()
+	for i, k := range keys {
+		if k.certAuthority || !bytes.Equal(k.key.Marshal(), b) || !matchAddr(k.from, conn.RemoteAddr()) {
+			continue
+		}
+		return keys[i].permissions(pubKey), nil
+	}
+	return nil, fmt.Errorf("unknown public key for %q", conn.User())
+}
+
+// authenticateCert checks a user certificate against the certificate
+// authorities of keys. The certificate has to name one of the principals of
+// the authority, or the user if there are none. Its force-command and
+// source-address options apply on top of those of the authority, and its
+// permit-pty and permit-port-forwarding extensions limit them.
+func (keys authorizedKeys) authenticateCert(conn ssh.ConnMetadata, cert *ssh.Certificate) (*ssh.Permissions, error) {
+	if cert.CertType != ssh.UserCert {
+		return nil, fmt.Errorf("certificate of %q is not a user certificate", conn.User())
+	}
+
+	checker := &ssh.CertChecker{
+		// source-address is enforced by the ssh package.
+		SupportedCriticalOptions: []string{forceCommand},
+	}
+	ca := cert.SignatureKey.Marshal()
+	for i, k := range keys {
+		if !k.certAuthority || !bytes.Equal(k.key.Marshal(), ca) || !matchAddr(k.from, conn.RemoteAddr()) {
+			continue
+		}
+
+		principals := k.principals
+		if principals == nil {
+			principals = []string{conn.User()}
+		}
+		err := errNoPrincipal
+		for _, p := range principals {
+			if err = checker.CheckCert(p, cert); err == nil {
+				break
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		perms := keys[i].permissions(cert)
+		for opt, val := range cert.CriticalOptions {
+			if opt != forceCommand {
+				perms.CriticalOptions[opt] = val
+			}
+		}
+		if cmd, ok := cert.CriticalOptions[forceCommand]; ok {
+			if k.command != "" && k.command != cmd {
+				return nil, fmt.Errorf("certificate of %q: %w", conn.User(), errCommandConflicts)
+			}
+			perms.CriticalOptions[forceCommand] = cmd
+		}
+		for _, ext := range []string{permitPTY, permitPortForwarding} {
+			if _, ok := cert.Extensions[ext]; !ok {
+				delete(perms.Extensions, ext)
+			}
+		}
+		return perms, nil
+	}
+	return nil, fmt.Errorf("certificate of %q is not signed by a trusted authority", conn.User())
+}
+
+// matchAddr matches the IP address of addr against an OpenSSH pattern-list
+// of addresses, wildcards and CIDR ranges. A match of a pattern negated with
+// `!` rejects the address. An empty list matches any address.
+func matchAddr(patterns []string, addr net.Addr) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return false
+	}
+	ip := net.ParseIP(host)
+
+	var matched bool
+	for _, p := range patterns {
+		negated := strings.HasPrefix(p, "!")
+		p = strings.TrimPrefix(p, "!")
+
+		var ok bool
+		if _, n, err := net.ParseCIDR(p); err == nil {
+			ok = ip != nil && n.Contains(ip)
+		} else {
+			ok, _ = path.Match(p, host)
+		}
+		if ok && negated {
+			return false
+		}
+		matched = matched || ok
+	}
+	return matched
+}
@@ -0,0 +1,286 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build (!tinygo || tinygo.enable) && !windows
+
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"net"
+	"slices"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+type connMetadata struct {
+	user string
+	addr net.Addr
+}
+
+func (c connMetadata) User() string          { return c.user }
+func (c connMetadata) SessionID() []byte     { return nil }
+func (c connMetadata) ClientVersion() []byte { return nil }
+func (c connMetadata) ServerVersion() []byte { return nil }
+func (c connMetadata) RemoteAddr() net.Addr  { return c.addr }
+func (c connMetadata) LocalAddr() net.Addr   { return c.addr }
+
+func newSigner(t *testing.T) ssh.Signer {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signer
+}
+
+// newCert signs a certificate of key with ca.
+func newCert(t *testing.T, ca ssh.Signer, key ssh.PublicKey, certType uint32, principals []string, validBefore uint64, perms ssh.Permissions) *ssh.Certificate {
+	t.Helper()
+	cert := &ssh.Certificate{
+		Key:             key,
+		CertType:        certType,
+		ValidPrincipals: principals,
+		ValidBefore:     validBefore,
+		Permissions:     perms,
+	}
+	if err := cert.SignCert(rand.Reader, ca); err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func TestParseAuthorizedKeys(t *testing.T) {
+	key := newSigner(t).PublicKey()
+	line := string(ssh.MarshalAuthorizedKey(key))
+
+	keys, err := parseAuthorizedKeys([]byte(
+		`cert-authority,principals="root,admin",from="10.0.0.0/8,!10.0.0.1" ` + line +
+			`command="echo \"hi\"",no-pty ` + line +
+			`restrict,port-forwarding ` + line +
+			"# comment\n\n" + line))
+	if err != nil {
+		t.Fatalf("parseAuthorizedKeys() = %v", err)
+	}
+	if len(keys) != 4 {
+		t.Fatalf("parseAuthorizedKeys() returned %d keys, want 4", len(keys))
+	}
+
+	if k := keys[0]; !k.certAuthority || !slices.Equal(k.principals, []string{"root", "admin"}) || !slices.Equal(k.from, []string{"10.0.0.0/8", "!10.0.0.1"}) {
+		t.Errorf("cert-authority entry = %+v", k)
+	}
+	if k := keys[1]; k.command != `echo "hi"` || !k.noPTY || k.noPortForwarding {
+		t.Errorf("command entry = %+v", k)
+	}
+	if k := keys[2]; !k.noPTY || k.noPortForwarding {
+		t.Errorf("restrict entry = %+v", k)
+	}
+	if k := keys[3]; k.certAuthority || k.command != "" || k.noPTY || k.noPortForwarding {
+		t.Errorf("plain entry = %+v", k)
+	}
+
+	if _, err := parseAuthorizedKeys([]byte("no key here\n")); err == nil {
+		t.Errorf("parseAuthorizedKeys() = nil, want an error")
+	}
+}
+
+func TestMatchAddr(t *testing.T) {
+	for _, tt := range []struct {
+		patterns []string
+		addr     string
+		want     bool
+	}{
+		{addr: "192.168.1.1", want: true},
+		{patterns: []string{"192.168.1.1"}, addr: "192.168.1.1", want: true},
+		{patterns: []string{"192.168.1.*"}, addr: "192.168.1.7", want: true},
+		{patterns: []string{"192.168.?.1"}, addr: "192.168.10.1", want: false},
+		{patterns: []string{"10.0.0.0/8"}, addr: "10.1.2.3", want: true},
+		{patterns: []string{"10.0.0.0/8", "!10.0.0.1"}, addr: "10.0.0.1", want: false},
+		{patterns: []string{"!10.0.0.1", "*"}, addr: "10.0.0.2", want: true},
+		{patterns: []string{"fd00::/8"}, addr: "fd00::1", want: true},
+		{patterns: []string{"10.0.0.0/8"}, addr: "fd00::1", want: false},
+	} {
+		addr := &net.TCPAddr{IP: net.ParseIP(tt.addr), Port: 22}
+		if got := matchAddr(tt.patterns, addr); got != tt.want {
+			t.Errorf("matchAddr(%q, %v) = %t, want %t", tt.patterns, addr, got, tt.want)
+		}
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	ca, user, other := newSigner(t), newSigner(t), newSigner(t)
+	caLine := string(ssh.MarshalAuthorizedKey(ca.PublicKey()))
+	userLine := string(ssh.MarshalAuthorizedKey(user.PublicKey()))
+	forever := uint64(ssh.CertTimeInfinity)
+	allowAll := ssh.Permissions{Extensions: map[string]string{permitPTY: "", permitPortForwarding: ""}}
+
+	for _, tt := range []struct {
+		name    string
+		keys    string
+		user    string
+		addr    string
+		key     ssh.PublicKey
+		ok      bool
+		forced  string
+		pty     bool
+		forward bool
+	}{
+		{
+			name:    "plain key",
+			keys:    userLine,
+			key:     user.PublicKey(),
+			ok:      true,
+			pty:     true,
+			forward: true,
+		},
+		{
+			name: "unknown key",
+			keys: userLine,
+			key:  other.PublicKey(),
+		},
+		{
+			name: "key from elsewhere",
+			keys: `from="10.0.0.0/8" ` + userLine,
+			addr: "192.168.0.1",
+			key:  user.PublicKey(),
+		},
+		{
+			name:    "key with options",
+			keys:    `from="10.0.0.0/8",command="uptime",no-port-forwarding ` + userLine,
+			addr:    "10.0.0.1",
+			key:     user.PublicKey(),
+			ok:      true,
+			forced:  "uptime",
+			pty:     true,
+			forward: false,
+		},
+		{
+			name: "key of a CA",
+			keys: "cert-authority " + userLine,
+			key:  user.PublicKey(),
+		},
+		{
+			name:    "certificate",
+			keys:    "cert-authority " + caLine,
+			user:    "root",
+			key:     newCert(t, ca, user.PublicKey(), ssh.UserCert, []string{"root"}, forever, allowAll),
+			ok:      true,
+			pty:     true,
+			forward: true,
+		},
+		{
+			name: "certificate of another user",
+			keys: "cert-authority " + caLine,
+			user: "root",
+			key:  newCert(t, ca, user.PublicKey(), ssh.UserCert, []string{"alice"}, forever, allowAll),
+		},
+		{
+			name: "certificate of an untrusted CA",
+			keys: "cert-authority " + userLine,
+			user: "root",
+			key:  newCert(t, ca, user.PublicKey(), ssh.UserCert, []string{"root"}, forever, allowAll),
+		},
+		{
+			name: "certificate without cert-authority",
+			keys: caLine,
+			user: "root",
+			key:  newCert(t, ca, user.PublicKey(), ssh.UserCert, []string{"root"}, forever, allowAll),
+		},
+		{
+			name: "expired certificate",
+			keys: "cert-authority " + caLine,
+			user: "root",
+			key:  newCert(t, ca, user.PublicKey(), ssh.UserCert, []string{"root"}, uint64(time.Now().Add(-time.Hour).Unix()), allowAll),
+		},
+		{
+			name: "host certificate",
+			keys: "cert-authority " + caLine,
+			user: "root",
+			key:  newCert(t, ca, user.PublicKey(), ssh.HostCert, []string{"root"}, forever, allowAll),
+		},
+		{
+			name: "certificate of principals",
+			keys: `cert-authority,principals="admins,ops" ` + caLine,
+			user: "root",
+			key: newCert(t, ca, user.PublicKey(), ssh.UserCert, []string{"alice", "ops"}, forever, ssh.Permissions{
+				CriticalOptions: map[string]string{forceCommand: "reboot"},
+				Extensions:      map[string]string{permitPTY: ""},
+			}),
+			ok:     true,
+			forced: "reboot",
+			pty:    true,
+		},
+		{
+			name:    "certificate under an authority with a command",
+			keys:    `cert-authority,command="uptime" ` + caLine,
+			user:    "root",
+			key:     newCert(t, ca, user.PublicKey(), ssh.UserCert, []string{"root"}, forever, allowAll),
+			ok:      true,
+			forced:  "uptime",
+			pty:     true,
+			forward: true,
+		},
+		{
+			name: "certificate with the command of its authority",
+			keys: `cert-authority,command="uptime" ` + caLine,
+			user: "root",
+			key: newCert(t, ca, user.PublicKey(), ssh.UserCert, []string{"root"}, forever, ssh.Permissions{
+				CriticalOptions: map[string]string{forceCommand: "uptime"},
+			}),
+			ok:     true,
+			forced: "uptime",
+		},
+		{
+			name: "certificate escaping the command of its authority",
+			keys: `cert-authority,command="uptime" ` + caLine,
+			user: "root",
+			key: newCert(t, ca, user.PublicKey(), ssh.UserCert, []string{"root"}, forever, ssh.Permissions{
+				CriticalOptions: map[string]string{forceCommand: "sh"},
+			}),
+		},
+		{
+			name: "certificate with unknown critical option",
+			keys: "cert-authority " + caLine,
+			user: "root",
+			key: newCert(t, ca, user.PublicKey(), ssh.UserCert, []string{"root"}, forever, ssh.Permissions{
+				CriticalOptions: map[string]string{"verify-required": ""},
+			}),
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, err := parseAuthorizedKeys([]byte(tt.keys))
+			if err != nil {
+				t.Fatalf("parseAuthorizedKeys() = %v", err)
+			}
+			if tt.addr == "" {
+				tt.addr = "127.0.0.1"
+			}
+			conn := connMetadata{user: tt.user, addr: &net.TCPAddr{IP: net.ParseIP(tt.addr), Port: 22}}
+
+			perms, err := keys.authenticate(conn, tt.key)
+			if (err == nil) != tt.ok {
+				t.Fatalf("authenticate() = %v, want success %t", err, tt.ok)
+			}
+			if err != nil {
+				return
+			}
+			if got := perms.CriticalOptions[forceCommand]; got != tt.forced {
+				t.Errorf("force-command = %q, want %q", got, tt.forced)
+			}
+			if _, got := perms.Extensions[permitPTY]; got != tt.pty {
+				t.Errorf("permit-pty = %t, want %t", got, tt.pty)
+			}
+			if _, got := perms.Extensions[permitPortForwarding]; got != tt.forward {
+				t.Errorf("permit-port-forwarding = %t, want %t", got, tt.forward)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build (!tinygo || tinygo.enable) && !windows
+
+package main
+
+import (
+	"context"
+	"log"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// callHomeMaxInterval caps the time between two calls home. The rendezvous
+// host is sent keepalives every callHomeKeepAlive by default, as
+// ServerAliveInterval of ssh does, and called again once
+// callHomeKeepAliveCount of them in a row went unanswered.
+const (
+	callHomeMaxInterval    = 5 * time.Minute
+	callHomeKeepAlive      = 30 * time.Second
+	callHomeKeepAliveCount = 3
+)
+
+// callHomeConfig returns the address of the rendezvous host of c.callhome,
+// which is [user@]host[:port], and the config to dial it with signer. The
+// rendezvous host has to have a key of the known_hosts file c.knownhosts.
+func (c *cmd) callHomeConfig(signer ssh.Signer) (string, *ssh.ClientConfig, error) {
+	hostKeyCallback, err := knownhosts.New(c.knownhosts)
+	if err != nil {
+		return "", nil, err
+	}
+
+	user, addr := "root", c.callhome
+	if i := strings.LastIndex(addr, "@"); i >= 0 {
+		user, addr = addr[:i], addr[i+1:]
+	}
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "22")
+	}
+
+	return addr, &ssh.ClientConfig{
+		User:            user,
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(signer)},
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         30 * time.Second,
+	}, nil
+}
+
+// callHome dials the rendezvous host at addr, has it forward c.callhomebind
+// back through the connection, and serves sshd with config on the forward,
+// as `ssh -R` would. It dials again with exponential backoff whenever the
+// connection fails, until ctx is done. Connections that go silent, as they
+// do when a NAT forgets them, fail after callHomeKeepAliveCount missed
+// keepalives.
+func (c *cmd) callHome(ctx context.Context, addr string, clientConfig *ssh.ClientConfig, config *ssh.ServerConfig) {
+	b := backoff.NewExponentialBackOff()
+	b.MaxInterval = callHomeMaxInterval
+	b.MaxElapsedTime = 0
+
+	backoff.RetryNotify(func() error {
+		client, err := ssh.Dial("tcp", addr, clientConfig)
+		if err != nil {
+			return err
+		}
+		defer client.Close()
+		defer context.AfterFunc(ctx, func() { client.Close() })()
+		done := make(chan struct{})
+		defer close(done)
+		go keepAlive(client, c.keepAlive, done)
+
+		listener, err := client.Listen("tcp", c.callhomebind)
+		if err != nil {
+			return err
+		}
+		log.Printf("Called home: %s forwards %s to sshd", addr, listener.Addr())
+		// The connection worked, so start over once it is gone.
+		b.Reset()
+		return serve(listener, config)
+	}, backoff.WithContext(b, ctx), func(err error, d time.Duration) {
+		log.Printf("Calling home to %s: %v, again in %v", addr, err, d.Round(time.Second))
+	})
+}
+
+// keepAlive sends keepalives to the server of client every interval until
+// done is closed, and closes client once callHomeKeepAliveCount of them in a
+// row went unanswered. Any answer will do, even a failure.
+func keepAlive(client *ssh.Client, interval time.Duration, done <-chan struct{}) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	replies := make(chan error, 1)
+	var pending bool
+	var missed int
+	for {
+		select {
+		case <-done:
+			return
+		case err := <-replies:
+			pending = false
+			if err == nil {
+				missed = 0
+			}
+		case <-t.C:
+			if pending {
+				missed++
+			}
+			if missed >= callHomeKeepAliveCount {
+				log.Printf("Calling home: %s did not answer %d keepalives", client.RemoteAddr(), missed)
+				client.Close()
+				return
+			}
+			if !pending {
+				pending = true
+				go func() {
+					_, _, err := client.SendRequest("keepalive@openssh.com", true, nil)
+					replies <- err
+				}()
+			}
+		}
+	}
+}
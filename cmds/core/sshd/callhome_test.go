@@ -0,0 +1,282 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build (!tinygo || tinygo.enable) && !windows
+
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+func TestCallHomeConfig(t *testing.T) {
+	signer := newSigner(t)
+	knownHosts := filepath.Join(t.TempDir(), "known_hosts")
+	if err := os.WriteFile(knownHosts, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		callhome string
+		addr     string
+		user     string
+	}{
+		{callhome: "rendezvous", addr: "rendezvous:22", user: "root"},
+		{callhome: "node@rendezvous:2200", addr: "rendezvous:2200", user: "node"},
+		{callhome: "node@[fd00::1]:2200", addr: "[fd00::1]:2200", user: "node"},
+		{callhome: "fd00::1", addr: "[fd00::1]:22", user: "root"},
+	} {
+		c := command(params{callhome: tt.callhome, knownhosts: knownHosts})
+		addr, config, err := c.callHomeConfig(signer)
+		if err != nil {
+			t.Fatalf("callHomeConfig(%q) = %v", tt.callhome, err)
+		}
+		if addr != tt.addr || config.User != tt.user {
+			t.Errorf("callHomeConfig(%q) = %q, %q, want %q, %q", tt.callhome, addr, config.User, tt.addr, tt.user)
+		}
+	}
+
+	c := command(params{callhome: "rendezvous", knownhosts: filepath.Join(t.TempDir(), "nothere")})
+	if _, _, err := c.callHomeConfig(signer); !os.IsNotExist(err) {
+		t.Errorf("callHomeConfig() without known_hosts = %v, want %v", err, os.ErrNotExist)
+	}
+}
+
+// freeAddr returns a local address that nothing listens on.
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	return ln.Addr().String()
+}
+
+func TestCallHome(t *testing.T) {
+	// The rendezvous host is an sshd as well, which lets the node in
+	// with its host key.
+	hostPub, err := os.ReadFile("./testdata/id_rsa.pub")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rendezvous := startServer(t, string(hostPub), "")
+	hostKey, _, _, _, err := ssh.ParseAuthorizedKey(hostPub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	knownHosts := filepath.Join(t.TempDir(), "known_hosts")
+	if err := os.WriteFile(knownHosts, []byte(knownhosts.Line([]string{rendezvous}, hostKey)+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	user := newSigner(t)
+	keys := filepath.Join(t.TempDir(), "authorized_keys")
+	if err := os.WriteFile(keys, ssh.MarshalAuthorizedKey(user.PublicKey()), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	bind := freeAddr(t)
+	c := command(params{
+		privkey:      "./testdata/id_rsa",
+		keys:         keys,
+		callhome:     "node@" + rendezvous,
+		callhomebind: bind,
+		knownhosts:   knownHosts,
+	})
+	config, private, err := c.config()
+	if err != nil {
+		t.Fatalf("config() = %v", err)
+	}
+	addr, clientConfig, err := c.callHomeConfig(private)
+	if err != nil {
+		t.Fatalf("callHomeConfig() = %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.callHome(ctx, addr, clientConfig, config)
+
+	// Reach the node through the rendezvous host, once it called.
+	var clt *ssh.Client
+	for range 50 {
+		if clt, err = ssh.Dial("tcp", bind, &ssh.ClientConfig{
+			Auth:            []ssh.AuthMethod{ssh.PublicKeys(user)},
+			HostKeyCallback: ssh.FixedHostKey(hostKey),
+			Timeout:         time.Second,
+		}); err == nil {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("ssh.Dial() through the rendezvous host = %v", err)
+	}
+	defer clt.Close()
+
+	session, err := clt.NewSession()
+	if err != nil {
+		t.Fatalf("can't create session: %v", err)
+	}
+	defer session.Close()
+	out, err := session.Output("echo hello u-root")
+	if err != nil {
+		t.Fatalf("Output() = %v", err)
+	}
+	if string(out) != "hello u-root\n" {
+		t.Errorf("Output() = %q, want %q", out, "hello u-root\n")
+	}
+}
+
+// natProxy forwards connections to addr, as a NAT does. It returns its
+// address, and a function that makes it forget the connections so far: their
+// side of addr is closed, and the other no longer hears anything.
+func natProxy(t *testing.T, addr string) (string, func()) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	var mu sync.Mutex
+	var conns []net.Conn
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			u, err := net.Dial("tcp", addr)
+			if err != nil {
+				c.Close()
+				continue
+			}
+			mu.Lock()
+			conns = append(conns, u)
+			mu.Unlock()
+			t.Cleanup(func() { c.Close() })
+			go io.Copy(u, c)
+			go io.Copy(c, u)
+		}
+	}()
+	return ln.Addr().String(), func() {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, u := range conns {
+			u.Close()
+		}
+		conns = nil
+	}
+}
+
+// dialUntil dials addr until it answers, as it does once the node called home.
+func dialUntil(t *testing.T, addr string, user ssh.Signer, hostKey ssh.PublicKey) *ssh.Client {
+	t.Helper()
+	var err error
+	for range 100 {
+		var clt *ssh.Client
+		if clt, err = ssh.Dial("tcp", addr, &ssh.ClientConfig{
+			Auth:            []ssh.AuthMethod{ssh.PublicKeys(user)},
+			HostKeyCallback: ssh.FixedHostKey(hostKey),
+			Timeout:         time.Second,
+		}); err == nil {
+			t.Cleanup(func() { clt.Close() })
+			return clt
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	t.Fatalf("ssh.Dial() through the rendezvous host = %v", err)
+	return nil
+}
+
+func TestCallHomeKeepAlive(t *testing.T) {
+	hostPub, err := os.ReadFile("./testdata/id_rsa.pub")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostKey, _, _, _, err := ssh.ParseAuthorizedKey(hostPub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rendezvous, forget := natProxy(t, startServer(t, string(hostPub), ""))
+	knownHosts := filepath.Join(t.TempDir(), "known_hosts")
+	if err := os.WriteFile(knownHosts, []byte(knownhosts.Line([]string{rendezvous}, hostKey)+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	user := newSigner(t)
+	keys := filepath.Join(t.TempDir(), "authorized_keys")
+	if err := os.WriteFile(keys, ssh.MarshalAuthorizedKey(user.PublicKey()), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	bind := freeAddr(t)
+	c := command(params{
+		privkey:      "./testdata/id_rsa",
+		keys:         keys,
+		callhome:     "node@" + rendezvous,
+		callhomebind: bind,
+		knownhosts:   knownHosts,
+	})
+	c.keepAlive = 50 * time.Millisecond
+	config, private, err := c.config()
+	if err != nil {
+		t.Fatalf("config() = %v", err)
+	}
+	addr, clientConfig, err := c.callHomeConfig(private)
+	if err != nil {
+		t.Fatalf("callHomeConfig() = %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.callHome(ctx, addr, clientConfig, config)
+	dialUntil(t, bind, user, hostKey).Close()
+
+	// The node hears nothing more on its connection, and calls again.
+	forget()
+	session, err := dialUntil(t, bind, user, hostKey).NewSession()
+	if err != nil {
+		t.Fatalf("can't create session: %v", err)
+	}
+	defer session.Close()
+	if out, err := session.Output("echo again"); err != nil || string(out) != "again\n" {
+		t.Errorf("Output() = %q, %v, want %q", out, err, "again\n")
+	}
+}
+
+func TestCallHomeUnknownHost(t *testing.T) {
+	hostPub, err := os.ReadFile("./testdata/id_rsa.pub")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rendezvous := startServer(t, string(hostPub), "")
+
+	// Pin another key for the rendezvous host.
+	knownHosts := filepath.Join(t.TempDir(), "known_hosts")
+	if err := os.WriteFile(knownHosts, []byte(knownhosts.Line([]string{rendezvous}, newSigner(t).PublicKey())+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	hostBytes, err := os.ReadFile("./testdata/id_rsa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	private, err := ssh.ParsePrivateKey(hostBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := command(params{callhome: rendezvous, knownhosts: knownHosts})
+	addr, clientConfig, err := c.callHomeConfig(private)
+	if err != nil {
+		t.Fatalf("callHomeConfig() = %v", err)
+	}
+	if _, err := ssh.Dial("tcp", addr, clientConfig); err == nil {
+		t.Errorf("ssh.Dial() of a host with another key = nil, want an error")
+	}
+}
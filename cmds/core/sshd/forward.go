@@ -0,0 +1,213 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build (!tinygo || tinygo.enable) && !windows
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strconv"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Payloads of port forwarding, RFC 4254 section 7.
+type (
+	tcpipForwardReq struct {
+		Addr string
+		Port uint32
+	}
+	tcpipForwardReply struct {
+		Port uint32
+	}
+	// directTCPIPReq is the payload of both direct-tcpip and
+	// forwarded-tcpip channels.
+	directTCPIPReq struct {
+		Addr       string
+		Port       uint32
+		OriginAddr string
+		OriginPort uint32
+	}
+)
+
+var errForwardingDenied = errors.New("port forwarding is not permitted")
+
+// directTCPIP connects a direct-tcpip channel, as opened by `ssh -L`, to
+// the address it asks for.
+func directTCPIP(newChannel ssh.NewChannel) {
+	req := &directTCPIPReq{}
+	if err := ssh.Unmarshal(newChannel.ExtraData(), req); err != nil {
+		newChannel.Reject(ssh.ConnectionFailed, "malformed direct-tcpip request")
+		return
+	}
+
+	addr := net.JoinHostPort(req.Addr, strconv.Itoa(int(req.Port)))
+	dprintf("direct-tcpip from %s:%d to %s", req.OriginAddr, req.OriginPort, addr)
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		newChannel.Reject(ssh.ConnectionFailed, err.Error())
+		return
+	}
+	channel, reqs, err := newChannel.Accept()
+	if err != nil {
+		log.Printf("Could not accept channel: %v", err)
+		conn.Close()
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+	proxy(channel, conn)
+}
+
+// proxy copies between channel and conn until both directions are done.
+func proxy(channel ssh.Channel, conn net.Conn) {
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		io.Copy(channel, conn)
+		channel.CloseWrite()
+	}()
+	go func() {
+		defer wg.Done()
+		io.Copy(conn, channel)
+		if c, ok := conn.(interface{ CloseWrite() error }); ok {
+			c.CloseWrite()
+		}
+	}()
+	wg.Wait()
+	channel.Close()
+	conn.Close()
+}
+
+// forwarder serves the tcpip-forward requests of a connection, as sent by
+// `ssh -R`.
+type forwarder struct {
+	conn    ssh.Conn
+	permit  bool
+	mu      sync.Mutex
+	forward map[string]net.Listener
+}
+
+func newForwarder(conn *ssh.ServerConn) *forwarder {
+	_, permit := conn.Permissions.Extensions[permitPortForwarding]
+	return &forwarder{
+		conn:    conn,
+		permit:  permit,
+		forward: map[string]net.Listener{},
+	}
+}
+
+// serve handles the global requests of the connection, and closes the
+// listeners once it is gone.
+func (f *forwarder) serve(reqs <-chan *ssh.Request) {
+	for req := range reqs {
+		dprintf("Global request %v", req.Type)
+		switch req.Type {
+		case "tcpip-forward":
+			port, err := f.listen(req.Payload)
+			if err != nil {
+				log.Printf("sshd: tcpip-forward: %v", err)
+				req.Reply(false, nil)
+				continue
+			}
+			req.Reply(true, port)
+		case "cancel-tcpip-forward":
+			req.Reply(f.cancel(req.Payload) == nil, nil)
+		default:
+			if req.WantReply {
+				req.Reply(false, nil)
+			}
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for addr, ln := range f.forward {
+		ln.Close()
+		delete(f.forward, addr)
+	}
+}
+
+// listen starts a forward, and returns the reply to its request: the port
+// if the client let the server pick it.
+func (f *forwarder) listen(payload []byte) ([]byte, error) {
+	if !f.permit {
+		return nil, errForwardingDenied
+	}
+	req := &tcpipForwardReq{}
+	if err := ssh.Unmarshal(payload, req); err != nil {
+		return nil, err
+	}
+
+	// "" and "*" are all addresses, as for OpenSSH with GatewayPorts.
+	bind := req.Addr
+	if bind == "*" {
+		bind = ""
+	}
+	ln, err := net.Listen("tcp", net.JoinHostPort(bind, strconv.Itoa(int(req.Port))))
+	if err != nil {
+		return nil, err
+	}
+	port := uint32(ln.Addr().(*net.TCPAddr).Port)
+
+	f.mu.Lock()
+	f.forward[net.JoinHostPort(req.Addr, strconv.Itoa(int(port)))] = ln
+	f.mu.Unlock()
+	go f.accept(ln, req.Addr, port)
+
+	if req.Port != 0 {
+		return nil, nil
+	}
+	return ssh.Marshal(tcpipForwardReply{Port: port}), nil
+}
+
+// accept opens a forwarded-tcpip channel for each connection to ln.
+func (f *forwarder) accept(ln net.Listener, addr string, port uint32) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			origin := conn.RemoteAddr().(*net.TCPAddr)
+			channel, reqs, err := f.conn.OpenChannel("forwarded-tcpip", ssh.Marshal(directTCPIPReq{
+				Addr:       addr,
+				Port:       port,
+				OriginAddr: origin.IP.String(),
+				OriginPort: uint32(origin.Port),
+			}))
+			if err != nil {
+				dprintf("forwarded-tcpip: %v", err)
+				conn.Close()
+				return
+			}
+			go ssh.DiscardRequests(reqs)
+			proxy(channel, conn)
+		}()
+	}
+}
+
+// cancel stops a forward.
+func (f *forwarder) cancel(payload []byte) error {
+	req := &tcpipForwardReq{}
+	if err := ssh.Unmarshal(payload, req); err != nil {
+		return err
+	}
+	addr := net.JoinHostPort(req.Addr, strconv.Itoa(int(req.Port)))
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	ln, ok := f.forward[addr]
+	if !ok {
+		return fmt.Errorf("no forward of %s", addr)
+	}
+	delete(f.forward, addr)
+	return ln.Close()
+}
@@ -7,6 +7,8 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
@@ -14,9 +16,12 @@ import (
 	"net"
 	"os"
 	"os/exec"
+	"slices"
+	"time"
 
 	"github.com/pkg/sftp"
 	"github.com/u-root/u-root/pkg/pty"
+	"github.com/u-root/u-root/pkg/termios"
 	"golang.org/x/crypto/ssh"
 )
 
@@ -36,26 +41,74 @@ type (
 	exitStatusReq struct {
 		ExitStatus uint32
 	}
+	windowChangeReq struct {
+		Col    uint32
+		Row    uint32
+		Xpixel uint32
+		Ypixel uint32
+	}
+	envReq struct {
+		Name  string
+		Value string
+	}
 )
 
 var (
-	debug   = flag.Bool("d", false, "Enable debug prints")
-	keys    = flag.String("keys", "authorized_keys", "Path to the authorized_keys file")
-	privkey = flag.String("privatekey", "id_rsa", "Path of private key")
-	ip      = flag.String("ip", "0.0.0.0", "ip address to listen on")
-	port    = flag.String("port", "2022", "port to listen on")
-	dprintf = func(string, ...any) {}
+	errNoPTY         = errors.New("session has no pty")
+	errPTYDenied     = errors.New("pty is not permitted")
+	errForcedCommand = errors.New("environment of a forced command can't be changed")
+	errStarted       = errors.New("session already started")
 )
 
-// start a command
+var (
+	debug          = flag.Bool("d", false, "Enable debug prints")
+	keys           = flag.String("keys", "authorized_keys", "Path to the authorized_keys file")
+	privkey        = flag.String("privatekey", "id_rsa", "Path of private key")
+	hostcert       = flag.String("hostcert", "", "Path of the OpenSSH host certificate of the private key")
+	callhome       = flag.String("callhome", "", "[user@]host[:port] of a rendezvous host to serve sshd on with a reverse tunnel")
+	callhomebind   = flag.String("callhomebind", "localhost:2022", "address the rendezvous host forwards to sshd")
+	knownHostsFile = flag.String("knownhosts", "known_hosts", "Path of the known_hosts file with the key of the rendezvous host")
+	ip             = flag.String("ip", "0.0.0.0", "ip address to listen on")
+	port           = flag.String("port", "2022", "port to listen on")
+	dprintf        = func(string, ...any) {}
+)
+
+// session is the state of a session channel.
+type session struct {
+	channel ssh.Channel
+	perms   *ssh.Permissions
+	pty     *pty.Pty
+	// env holds the variables of env requests and the TERM of the pty.
+	env []string
+	// started is whether a command runs on the channel, after which the
+	// session can't change.
+	started bool
+}
+
+// start runs command on a copy of the session, which requests that come in
+// while it runs can't change.
+func (s *session) start(reqType, command string) {
+	s.started = true
+	r := *s
+	r.env = slices.Clone(s.env)
+	go func() {
+		if err := r.exec(command); err != nil {
+			log.Printf("sshd: %s: %v", reqType, err)
+		}
+	}()
+}
+
+// run starts a command
 // TODO: use /etc/passwd, but the Go support for that is incomplete
-func runCommand(c ssh.Channel, p *pty.Pty, cmd string, args ...string) error {
+func (s *session) run(cmd string, args ...string) error {
 	var ps *os.ProcessState
+	c := s.channel
 	defer c.Close()
 
-	if p != nil {
+	if p := s.pty; p != nil {
 		log.Printf("Executing PTY command %s %v", cmd, args)
 		p.Command(cmd, args...)
+		p.C.Env = append(os.Environ(), s.env...)
 		if err := p.C.Start(); err != nil {
 			dprintf("Failed to execute: %v", err)
 			return err
@@ -66,7 +119,18 @@ func runCommand(c ssh.Channel, p *pty.Pty, cmd string, args ...string) error {
 		ps, _ = p.C.Process.Wait()
 	} else {
 		e := exec.Command(cmd, args...)
-		e.Stdin, e.Stdout, e.Stderr = c, c, c
+		e.Env = append(os.Environ(), s.env...)
+		e.Stdout, e.Stderr = c, c
+		// Don't wait for the client to close stdin once the command
+		// is done, as exec does with anything but a file.
+		stdin, err := e.StdinPipe()
+		if err != nil {
+			return err
+		}
+		go func() {
+			io.Copy(stdin, c)
+			stdin.Close()
+		}()
 		log.Printf("Executing non-PTY command %s %v", cmd, args)
 		// execute command and wait for response
 		if err := e.Run(); err != nil {
@@ -90,34 +154,88 @@ func runCommand(c ssh.Channel, p *pty.Pty, cmd string, args ...string) error {
 	return nil
 }
 
-func newPTY(b []byte) (*pty.Pty, error) {
+// exec runs command with the shell of the user, or the shell itself if it is
+// empty. This is what OpenSSH does so it's the least surprising to the user.
+// A command forced by the authorized key or certificate of the session runs
+// in its place.
+func (s *session) exec(command string) error {
+	if forced, ok := s.perms.CriticalOptions[forceCommand]; ok {
+		s.env = append(s.env, "SSH_ORIGINAL_COMMAND="+command)
+		return s.run(shell, "-c", forced)
+	}
+	if command == "" {
+		return s.run(shell)
+	}
+	return s.run(shell, "-c", command)
+}
+
+func (s *session) newPTY(b []byte) error {
+	if s.started {
+		return errStarted
+	}
+	if _, ok := s.perms.Extensions[permitPTY]; !ok {
+		return errPTYDenied
+	}
 	ptyReq := &ptyReq{}
 	err := ssh.Unmarshal(b, ptyReq)
 	dprintf("newPTY: %q", ptyReq)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	p, err := pty.New()
 	if err != nil {
-		return nil, err
+		return err
 	}
-	ws, err := p.TTY.GetWinSize()
+	if err := setWinSize(p, ptyReq.Col, ptyReq.Row, ptyReq.Xpixel, ptyReq.Ypixel); err != nil {
+		return err
+	}
+	dprintf("newPTY: set TERM to %q", ptyReq.TERM)
+	s.env = append(s.env, "TERM="+ptyReq.TERM)
+	s.pty = p
+	return nil
+}
+
+// windowChange resizes the pty of the session.
+func (s *session) windowChange(b []byte) error {
+	if s.pty == nil {
+		return errNoPTY
+	}
+	w := &windowChangeReq{}
+	if err := ssh.Unmarshal(b, w); err != nil {
+		return err
+	}
+	return setWinSize(s.pty, w.Col, w.Row, w.Xpixel, w.Ypixel)
+}
+
+func setWinSize(p *pty.Pty, col, row, xpixel, ypixel uint32) error {
+	ws, err := termios.GetWinSize(p.Ptm.Fd())
 	if err != nil {
-		return nil, err
+		return err
 	}
-	ws.Row = uint16(ptyReq.Row)
-	ws.Ypixel = uint16(ptyReq.Ypixel)
-	ws.Col = uint16(ptyReq.Col)
-	ws.Xpixel = uint16(ptyReq.Xpixel)
-	dprintf("newPTY: Set winsizes to %v", ws)
-	if err := p.TTY.SetWinSize(ws); err != nil {
-		return nil, err
+	ws.Row = uint16(row)
+	ws.Ypixel = uint16(ypixel)
+	ws.Col = uint16(col)
+	ws.Xpixel = uint16(xpixel)
+	dprintf("setWinSize: Set winsizes to %v", ws)
+	return termios.SetWinSize(p.Ptm.Fd(), ws)
+}
+
+// setEnv takes an env request. Commands forced on the session run with the
+// environment of sshd, so they can't be subverted by LD_PRELOAD and the like.
+func (s *session) setEnv(b []byte) error {
+	if s.started {
+		return errStarted
 	}
-	dprintf("newPTY: set TERM to %q", ptyReq.TERM)
-	if err := os.Setenv("TERM", ptyReq.TERM); err != nil {
-		return nil, err
+	if _, ok := s.perms.CriticalOptions[forceCommand]; ok {
+		return errForcedCommand
+	}
+	e := &envReq{}
+	if err := ssh.Unmarshal(b, e); err != nil {
+		return err
 	}
-	return p, nil
+	dprintf("setEnv: %s=%q", e.Name, e.Value)
+	s.env = append(s.env, e.Name+"="+e.Value)
+	return nil
 }
 
 func handleSftp(req *ssh.Request, channel ssh.Channel) error {
@@ -126,12 +244,10 @@ func handleSftp(req *ssh.Request, channel ssh.Channel) error {
 		req.Reply(false, nil)
 		return err
 	}
-	if err := s.Serve(); err != nil {
-		req.Reply(false, nil)
-		return err
-	}
-
+	// Clients wait for the reply before they speak SFTP, so reply
+	// before serving.
 	req.Reply(true, nil)
+	err = s.Serve()
 
 	// Need to tell the client that the operations was a success (0) and
 	// kill the connection by any means necessary.  You may see stuff like
@@ -143,18 +259,27 @@ func handleSftp(req *ssh.Request, channel ssh.Channel) error {
 	channel.SendRequest("exit-status", false, ssh.Marshal(exitStatusReq{0}))
 	channel.Close()
 
-	return nil
+	return err
 }
 
-func session(chans <-chan ssh.NewChannel) {
-	var p *pty.Pty
+func handleChannels(conn *ssh.ServerConn, chans <-chan ssh.NewChannel) {
+	_, permitForwarding := conn.Permissions.Extensions[permitPortForwarding]
 	// Service the incoming Channel channel.
 	for newChannel := range chans {
 		// Channels have a type, depending on the application level
 		// protocol intended. In the case of a shell, the type is
 		// "session" and ServerShell may be used to present a simple
-		// terminal interface.
-		if newChannel.ChannelType() != "session" {
+		// terminal interface. ssh -L opens "direct-tcpip" channels.
+		switch newChannel.ChannelType() {
+		case "session":
+		case "direct-tcpip":
+			if !permitForwarding {
+				newChannel.Reject(ssh.Prohibited, errForwardingDenied.Error())
+				continue
+			}
+			go directTCPIP(newChannel)
+			continue
+		default:
 			newChannel.Reject(ssh.UnknownChannelType, "unknown channel type")
 			continue
 		}
@@ -165,37 +290,67 @@ func session(chans <-chan ssh.NewChannel) {
 		}
 
 		// Sessions have out-of-band requests such as "shell",
-		// "pty-req" and "env".  Here we handle only the
-		// "shell" request.
+		// "pty-req" and "env".
+		s := &session{channel: channel, perms: conn.Permissions}
 		go func(in <-chan *ssh.Request) {
 			for req := range in {
 				dprintf("Request %v", req.Type)
 				switch req.Type {
-				case "shell":
-					err := runCommand(channel, p, shell)
-					req.Reply(true, fmt.Appendf(nil, "%v", err))
-				case "exec":
-					e := &execReq{}
-					if err := ssh.Unmarshal(req.Payload, e); err != nil {
-						log.Printf("sshd: %v", err)
+				case "shell", "exec":
+					if s.started {
+						log.Printf("sshd: %s: %v", req.Type, errStarted)
+						req.Reply(false, nil)
 						break
 					}
-					// Execute command using user's shell. This is what OpenSSH does
-					// so it's the least surprising to the user.
-					err := runCommand(channel, p, shell, "-c", e.Command)
-					req.Reply(true, fmt.Appendf(nil, "%v", err))
+					var command string
+					if req.Type == "exec" {
+						e := &execReq{}
+						if err := ssh.Unmarshal(req.Payload, e); err != nil {
+							log.Printf("sshd: %v", err)
+							req.Reply(false, nil)
+							break
+						}
+						command = e.Command
+					}
+					// Reply before the command closes the channel,
+					// and keep serving window-change requests.
+					req.Reply(true, nil)
+					s.start(req.Type, command)
 				case "pty-req":
-					p, err = newPTY(req.Payload)
+					err := s.newPTY(req.Payload)
+					if err != nil {
+						log.Printf("sshd: pty-req: %v", err)
+					}
+					req.Reply(err == nil, nil)
+				case "window-change":
+					if err := s.windowChange(req.Payload); err != nil {
+						log.Printf("sshd: window-change: %v", err)
+					}
+				case "env":
+					err := s.setEnv(req.Payload)
+					if err != nil {
+						dprintf("sshd: env: %v", err)
+					}
 					req.Reply(err == nil, nil)
 				case "subsystem":
 					switch {
+					case s.started:
+						log.Printf("sshd: subsystem: %v", errStarted)
+						req.Reply(false, nil)
 					case string(req.Payload[4:]) == "sftp":
-						// This handles the req.Reply
-						// and may close the channel
-						err := handleSftp(req, channel)
-						if err != nil {
-							log.Printf("sshd: sftp: %v", err)
+						if _, ok := s.perms.CriticalOptions[forceCommand]; ok {
+							req.Reply(true, nil)
+							s.start(req.Type, "internal-sftp")
+							break
 						}
+						// This handles the req.Reply and
+						// closes the channel when done.
+						s.started = true
+						go func() {
+							if err := handleSftp(req, channel); err != nil {
+								log.Printf("sshd: sftp: %v", err)
+							}
+						}()
 					default:
 						log.Printf("Not handling subsystem req %v %q",
 							req, string(req.Payload))
@@ -212,94 +367,141 @@ func session(chans <-chan ssh.NewChannel) {
 }
 
 type params struct {
-	keys    string
-	privkey string
-	ip      string
-	port    string
-	debug   bool
+	keys     string
+	privkey  string
+	hostcert string
+	// callhome is the rendezvous host to dial, and callhomebind the
+	// address it forwards to sshd.
+	callhome     string
+	callhomebind string
+	knownhosts   string
+	ip           string
+	port         string
+	debug        bool
 }
 
 func parseParams() params {
 	return params{
-		debug:   *debug,
-		keys:    *keys,
-		privkey: *privkey,
-		ip:      *ip,
-		port:    *port,
+		debug:        *debug,
+		keys:         *keys,
+		privkey:      *privkey,
+		hostcert:     *hostcert,
+		callhome:     *callhome,
+		callhomebind: *callhomebind,
+		knownhosts:   *knownHostsFile,
+		ip:           *ip,
+		port:         *port,
 	}
 }
 
 type cmd struct {
 	params
+	// keepAlive is how often the rendezvous host is sent keepalives.
+	keepAlive time.Duration
 }
 
 func command(p params) *cmd {
 	return &cmd{
-		params: p,
+		params:    p,
+		keepAlive: callHomeKeepAlive,
 	}
 }
 
 func (c *cmd) run() error {
+	config, private, err := c.config()
+	if err != nil {
+		return err
+	}
+
+	// Nodes behind NAT serve sshd on a reverse tunnel of a rendezvous
+	// host as well.
+	if c.callhome != "" {
+		addr, clientConfig, err := c.callHomeConfig(private)
+		if err != nil {
+			return err
+		}
+		go c.callHome(context.Background(), addr, clientConfig, config)
+	}
+
+	// Once a ServerConfig has been configured, connections can be
+	// accepted.
+	listener, err := net.Listen("tcp", net.JoinHostPort(c.ip, c.port))
+	if err != nil {
+		return err
+	}
+	return serve(listener, config)
+}
+
+// config returns the config of the server, and its private key.
+func (c *cmd) config() (*ssh.ServerConfig, ssh.Signer, error) {
 	if c.debug {
 		dprintf = log.Printf
 	}
 	// Public key authentication is done by comparing
 	// the public key of a received connection
-	// with the entries in the authorized_keys file.
+	// with the entries in the authorized_keys file,
+	// or checking certificates against its cert-authority entries.
 	authorizedKeysBytes, err := os.ReadFile(c.keys)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 
-	authorizedKeysMap := map[string]bool{}
-	for len(authorizedKeysBytes) > 0 {
-		pubKey, _, _, rest, err := ssh.ParseAuthorizedKey(authorizedKeysBytes)
-		if err != nil {
-			return err
-		}
-
-		authorizedKeysMap[string(pubKey.Marshal())] = true
-		authorizedKeysBytes = rest
+	authorizedKeys, err := parseAuthorizedKeys(authorizedKeysBytes)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	// An SSH server is represented by a ServerConfig, which holds
 	// certificate details and handles authentication of ServerConns.
 	config := &ssh.ServerConfig{
 		// Remove to disable public key auth.
-		PublicKeyCallback: func(c ssh.ConnMetadata, pubKey ssh.PublicKey) (*ssh.Permissions, error) {
-			if authorizedKeysMap[string(pubKey.Marshal())] {
-				return &ssh.Permissions{
-					// Record the public key used for authentication.
-					Extensions: map[string]string{
-						"pubkey-fp": ssh.FingerprintSHA256(pubKey),
-					},
-				}, nil
-			}
-			return nil, fmt.Errorf("unknown public key for %q", c.User())
-		},
+		PublicKeyCallback: authorizedKeys.authenticate,
 	}
 
 	privateBytes, err := os.ReadFile(c.privkey)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 
 	private, err := ssh.ParsePrivateKey(privateBytes)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 
 	config.AddHostKey(private)
 
-	// Once a ServerConfig has been configured, connections can be
-	// accepted.
-	listener, err := net.Listen("tcp", net.JoinHostPort(c.ip, c.port))
-	if err != nil {
-		return err
+	// Clients that trust the host CA verify the certificate instead.
+	if c.hostcert != "" {
+		certBytes, err := os.ReadFile(c.hostcert)
+		if err != nil {
+			return nil, nil, err
+		}
+		pub, _, _, _, err := ssh.ParseAuthorizedKey(certBytes)
+		if err != nil {
+			return nil, nil, err
+		}
+		cert, ok := pub.(*ssh.Certificate)
+		if !ok || cert.CertType != ssh.HostCert {
+			return nil, nil, fmt.Errorf("%s: not a host certificate", c.hostcert)
+		}
+		signer, err := ssh.NewCertSigner(cert, private)
+		if err != nil {
+			return nil, nil, err
+		}
+		config.AddHostKey(signer)
 	}
+
+	return config, private, nil
+}
+
+func serve(listener net.Listener, config *ssh.ServerConfig) error {
 	for {
 		nConn, err := listener.Accept()
 		if err != nil {
+			// Reverse tunnels end with io.EOF.
+			if errors.Is(err, net.ErrClosed) || errors.Is(err, io.EOF) {
+				return err
+			}
 			log.Printf("failed to accept incoming connection: %s", err)
 			continue
 		}
@@ -314,9 +516,10 @@ func (c *cmd) run() error {
 		log.Printf("%v logged in with key %s", conn.RemoteAddr(), conn.Permissions.Extensions["pubkey-fp"])
 
 		// The incoming Request channel must be serviced.
-		go ssh.DiscardRequests(reqs)
+		// It carries the tcpip-forward requests of ssh -R.
+		go newForwarder(conn).serve(reqs)
 
-		go session(chans)
+		go handleChannels(conn, chans)
 	}
 }
 
@@ -7,12 +7,16 @@
 package main
 
 import (
+	"bytes"
 	"errors"
+	"io"
 	"net"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/pkg/sftp"
 	"golang.org/x/crypto/ssh"
 )
 
@@ -27,6 +31,13 @@ func TestParseParmams(t *testing.T) {
 	if params.privkey != "id_rsa" {
 		t.Errorf("expected default privatekey to be id_rsa, got %q", params.privkey)
 	}
+	if params.hostcert != "" {
+		t.Errorf("expected default hostcert to be empty, got %q", params.hostcert)
+	}
+	if params.callhome != "" || params.callhomebind != "localhost:2022" || params.knownhosts != "known_hosts" {
+		t.Errorf("expected default call home to be off, to localhost:2022 and known_hosts, got %q, %q and %q",
+			params.callhome, params.callhomebind, params.knownhosts)
+	}
 	if params.ip != "0.0.0.0" {
 		t.Errorf("expected default ip to be 0.0.0.0, got %q", params.ip)
 	}
@@ -183,3 +194,257 @@ func TestSessionRun(t *testing.T) {
 		t.Errorf("expected hello u-root, got %q", string(b[:n]))
 	}
 }
+
+// startServer serves sshd with keys as authorized_keys, and returns its
+// address.
+func startServer(t *testing.T, keys string, hostcert string) string {
+	t.Helper()
+	keysFile := filepath.Join(t.TempDir(), "authorized_keys")
+	if err := os.WriteFile(keysFile, []byte(keys), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	config, _, err := command(params{
+		privkey:  "./testdata/id_rsa",
+		keys:     keysFile,
+		hostcert: hostcert,
+	}).config()
+	if err != nil {
+		t.Fatalf("config() = %v", err)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go serve(ln, config)
+	return ln.Addr().String()
+}
+
+func dial(t *testing.T, addr string, signer ssh.Signer) *ssh.Client {
+	t.Helper()
+	clt, err := ssh.Dial("tcp", addr, &ssh.ClientConfig{
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(signer)},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		Timeout:         time.Second,
+	})
+	if err != nil {
+		t.Fatalf("ssh.Dial() = %v", err)
+	}
+	t.Cleanup(func() { clt.Close() })
+	return clt
+}
+
+func TestSessionEnv(t *testing.T) {
+	signer := newSigner(t)
+	line := string(ssh.MarshalAuthorizedKey(signer.PublicKey()))
+
+	for _, tt := range []struct {
+		name string
+		keys string
+		want string
+	}{
+		{
+			name: "env",
+			keys: line,
+			want: "bar\n",
+		},
+		{
+			name: "forced command",
+			keys: `command="echo forced $SSH_ORIGINAL_COMMAND $FOO" ` + line,
+			want: "forced echo $FOO\n",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			clt := dial(t, startServer(t, tt.keys, ""), signer)
+			session, err := clt.NewSession()
+			if err != nil {
+				t.Fatalf("can't create session: %v", err)
+			}
+			defer session.Close()
+
+			// Forced commands refuse the variable, which Setenv
+			// reports as an error.
+			session.Setenv("FOO", "bar")
+			out, err := session.Output("echo $FOO")
+			if err != nil {
+				t.Fatalf("Output() = %v", err)
+			}
+			if string(out) != tt.want {
+				t.Errorf("Output() = %q, want %q", out, tt.want)
+			}
+		})
+	}
+}
+
+func TestSessionStarted(t *testing.T) {
+	signer := newSigner(t)
+	clt := dial(t, startServer(t, string(ssh.MarshalAuthorizedKey(signer.PublicKey())), ""), signer)
+	ch, reqs, err := clt.OpenChannel("session", nil)
+	if err != nil {
+		t.Fatalf("OpenChannel() = %v", err)
+	}
+	defer ch.Close()
+	go ssh.DiscardRequests(reqs)
+	go io.Copy(io.Discard, ch)
+
+	if ok, err := ch.SendRequest("exec", true, ssh.Marshal(execReq{"sleep 1"})); !ok || err != nil {
+		t.Fatalf("exec = %t, %v, want it accepted", ok, err)
+	}
+	for _, req := range []struct {
+		typ     string
+		payload []byte
+	}{
+		{"exec", ssh.Marshal(execReq{"echo again"})},
+		{"shell", nil},
+		{"env", ssh.Marshal(envReq{"FOO", "bar"})},
+		{"pty-req", ssh.Marshal(ptyReq{TERM: "vt100", Col: 80, Row: 24})},
+	} {
+		if ok, err := ch.SendRequest(req.typ, true, req.payload); ok || err != nil {
+			t.Errorf("%s after exec = %t, %v, want it rejected", req.typ, ok, err)
+		}
+	}
+}
+
+// echoServer serves a TCP echo server, and returns its listener.
+func echoServer(t *testing.T, ln net.Listener) {
+	t.Helper()
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				io.Copy(conn, conn)
+				conn.Close()
+			}()
+		}
+	}()
+}
+
+func echo(t *testing.T, conn net.Conn) {
+	t.Helper()
+	defer conn.Close()
+	if _, err := conn.Write([]byte("hello u-root")); err != nil {
+		t.Fatalf("Write() = %v", err)
+	}
+	b := make([]byte, len("hello u-root"))
+	if _, err := io.ReadFull(conn, b); err != nil {
+		t.Fatalf("ReadFull() = %v", err)
+	}
+	if string(b) != "hello u-root" {
+		t.Errorf("echo = %q, want %q", b, "hello u-root")
+	}
+}
+
+func TestPortForwarding(t *testing.T) {
+	signer := newSigner(t)
+	line := string(ssh.MarshalAuthorizedKey(signer.PublicKey()))
+
+	t.Run("direct-tcpip", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		echoServer(t, ln)
+
+		clt := dial(t, startServer(t, line, ""), signer)
+		conn, err := clt.Dial("tcp", ln.Addr().String())
+		if err != nil {
+			t.Fatalf("Dial() = %v", err)
+		}
+		echo(t, conn)
+	})
+
+	t.Run("tcpip-forward", func(t *testing.T) {
+		clt := dial(t, startServer(t, line, ""), signer)
+		ln, err := clt.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("Listen() = %v", err)
+		}
+		echoServer(t, ln)
+
+		conn, err := net.Dial("tcp", ln.Addr().String())
+		if err != nil {
+			t.Fatalf("Dial() = %v", err)
+		}
+		echo(t, conn)
+	})
+
+	t.Run("no-port-forwarding", func(t *testing.T) {
+		clt := dial(t, startServer(t, "no-port-forwarding "+line, ""), signer)
+		if _, err := clt.Dial("tcp", "127.0.0.1:22"); err == nil {
+			t.Errorf("Dial() = nil, want an error")
+		}
+		if _, err := clt.Listen("tcp", "127.0.0.1:0"); err == nil {
+			t.Errorf("Listen() = nil, want an error")
+		}
+	})
+}
+
+func TestHostCertificate(t *testing.T) {
+	ca, signer := newSigner(t), newSigner(t)
+
+	hostBytes, err := os.ReadFile("./testdata/id_rsa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	host, err := ssh.ParsePrivateKey(hostBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert := newCert(t, ca, host.PublicKey(), ssh.HostCert, []string{"127.0.0.1"}, uint64(ssh.CertTimeInfinity), ssh.Permissions{})
+	certFile := filepath.Join(t.TempDir(), "id_rsa-cert.pub")
+	if err := os.WriteFile(certFile, ssh.MarshalAuthorizedKey(cert), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	addr := startServer(t, string(ssh.MarshalAuthorizedKey(signer.PublicKey())), certFile)
+	checker := &ssh.CertChecker{
+		IsHostAuthority: func(auth ssh.PublicKey, _ string) bool {
+			return bytes.Equal(auth.Marshal(), ca.PublicKey().Marshal())
+		},
+	}
+	clt, err := ssh.Dial("tcp", addr, &ssh.ClientConfig{
+		Auth:              []ssh.AuthMethod{ssh.PublicKeys(signer)},
+		HostKeyCallback:   checker.CheckHostKey,
+		HostKeyAlgorithms: []string{ssh.CertAlgoRSASHA512v01},
+		Timeout:           time.Second,
+	})
+	if err != nil {
+		t.Fatalf("ssh.Dial() = %v", err)
+	}
+	clt.Close()
+
+	if _, _, err := command(params{privkey: "./testdata/id_rsa", keys: "./testdata/id_rsa.pub", hostcert: "./testdata/id_rsa.pub"}).config(); err == nil {
+		t.Errorf("config() with a key as host certificate = nil, want an error")
+	}
+}
+
+func TestSftp(t *testing.T) {
+	signer := newSigner(t)
+	clt := dial(t, startServer(t, string(ssh.MarshalAuthorizedKey(signer.PublicKey())), ""), signer)
+	// The client waits for the reply to the subsystem request.
+	c, err := sftp.NewClient(clt)
+	if err != nil {
+		t.Fatalf("sftp.NewClient() = %v", err)
+	}
+	defer c.Close()
+
+	p := filepath.Join(t.TempDir(), "file")
+	f, err := c.Create(p)
+	if err != nil {
+		t.Fatalf("Create(%q) = %v", p, err)
+	}
+	if _, err := f.Write([]byte("sftp")); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := os.ReadFile(p); err != nil || string(b) != "sftp" {
+		t.Errorf("file = %q, %v, want %q", b, err, "sftp")
+	}
+}
@@ -7,36 +7,67 @@
 //
 // Synopsis:
 //
-//	wget URL
+//	wget [OPTIONS] URL
 //
 // Description:
 //
 //	Returns a non-zero code on failure.
 //
+// Options:
+//
+//	-O FILE:                  output file, - for stdout
+//	-c:                       resume a partial download of the output file
+//	-t N:                     tries of the download
+//	--header "NAME: VALUE":   add a header, may be repeated
+//	--user, --password:       basic authentication
+//	--bearer TOKEN:           bearer token authentication
+//	--method METHOD:          request method
+//	--post-data DATA:         POST the string DATA
+//	--post-file FILE:         POST the contents of FILE
+//	--ca-certificate FILE:    trust the CA certificates of FILE only
+//	--certificate FILE:       client certificate
+//	--private-key FILE:       key of the client certificate, if not in it
+//	--no-check-certificate:   do not verify the server certificate
+//	--proxy URL:              proxy, rather than the one of $http_proxy
+//	--no-proxy:               do not use a proxy
+//	--sha256 HASH:            verify the SHA-256 hash of the file
+//	--show-progress:          print the progress to stderr
+//
 // Notes:
 //
 //	There are a few differences with GNU wget:
 //	- Upon error, the return value is always 1.
 //	- The protocol (http/https) is mandatory.
+//	- A file that fails --sha256 verification is removed, including the
+//	  part of it that was there before -c resumed it.
 //
 // Example:
 //
 //	wget -O google.txt http://google.com/
+//	wget -c --sha256 5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03 http://10.0.0.1/initramfs.cpio
 package main
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"log"
+	"net/http"
 	"net/url"
 	"os"
 	"path"
 	"strings"
+	"sync/atomic"
 
+	"github.com/cenkalti/backoff/v4"
 	"github.com/u-root/u-root/pkg/curl"
+	"github.com/u-root/u-root/pkg/progress"
+	"github.com/u-root/u-root/pkg/vfile"
 	"github.com/u-root/uio/uio"
 
 	// To build the dependencies of this package with TinyGo, we need to include
@@ -47,18 +78,57 @@ import (
 	_ "github.com/u-root/cpuid"
 )
 
-var errEmptyURL = errors.New("empty url")
+var (
+	errEmptyURL  = errors.New("empty url")
+	errHeader    = errors.New("header is not NAME: VALUE")
+	errPostData  = errors.New("--post-data and --post-file are exclusive")
+	errProxy     = errors.New("--proxy and --no-proxy are exclusive")
+	errTries     = errors.New("tries must be at least 1")
+	errSHA256Len = fmt.Errorf("sha256 must be %d hex digits", 2*sha256.Size)
+)
 
 type cmd struct {
 	url        string
 	outputPath string
+
+	resume   bool
+	tries    uint
+	headers  headers
+	user     string
+	password string
+	bearer   string
+	method   string
+	postData string
+	postFile string
+
+	caCert   string
+	cert     string
+	key      string
+	insecure bool
+	proxy    string
+	noProxy  bool
+
+	sha256       string
+	showProgress bool
+}
+
+// headers are the values of the repeated --header flag.
+type headers []string
+
+func (h *headers) String() string {
+	return strings.Join(*h, ", ")
+}
+
+func (h *headers) Set(s string) error {
+	*h = append(*h, s)
+	return nil
 }
 
 // flags parses wget flags
 // wget is old school, and allows flags after the URL.
 // This code does not process the -- flag specified in the
 // man page, as the command itself does not seem to either.
-func flags(args ...string) (string, string, error) {
+func flags(args ...string) (*cmd, error) {
 	// -- takes priority over everything else.
 	// flag package does not allow - as a flag.
 	// except, in spite of the docs, wget on linux seems
@@ -69,41 +139,181 @@ func flags(args ...string) (string, string, error) {
 	// the slices package is a good place to start.
 
 	if len(args) == 0 {
-		return "", "", errEmptyURL
+		return nil, errEmptyURL
 	}
 
+	c := &cmd{}
 	f := flag.NewFlagSet(args[0], flag.ContinueOnError)
-	outPath := f.String("O", "", "output file")
+	f.StringVar(&c.outputPath, "O", "", "output file")
+	f.BoolVar(&c.resume, "c", false, "resume a partial download of the output file")
+	f.BoolVar(&c.resume, "continue", false, "resume a partial download of the output file")
+	f.UintVar(&c.tries, "t", 1, "tries of the download")
+	f.UintVar(&c.tries, "tries", 1, "tries of the download")
+	f.Var(&c.headers, "header", "add a \"NAME: VALUE\" header, may be repeated")
+	f.StringVar(&c.user, "user", "", "basic authentication user")
+	f.StringVar(&c.password, "password", "", "basic authentication password")
+	f.StringVar(&c.bearer, "bearer", "", "bearer token authentication")
+	f.StringVar(&c.method, "method", "", "request method")
+	f.StringVar(&c.postData, "post-data", "", "POST the string")
+	f.StringVar(&c.postFile, "post-file", "", "POST the contents of the file")
+	f.StringVar(&c.caCert, "ca-certificate", "", "trust the CA certificates of the file only")
+	f.StringVar(&c.cert, "certificate", "", "client certificate")
+	f.StringVar(&c.key, "private-key", "", "key of the client certificate, if not in it")
+	f.BoolVar(&c.insecure, "no-check-certificate", false, "do not verify the server certificate")
+	f.StringVar(&c.proxy, "proxy", "", "proxy, rather than the one of $http_proxy")
+	f.BoolVar(&c.noProxy, "no-proxy", false, "do not use a proxy")
+	f.StringVar(&c.sha256, "sha256", "", "verify the SHA-256 hash of the file, which is removed if it does not match, even the part resumed by -c")
+	f.BoolVar(&c.showProgress, "show-progress", false, "print the progress to stderr")
 
 	if err := f.Parse(args[1:]); err != nil {
-		return "", "", err
+		return nil, err
 	}
 
 	if len(f.Args()) == 0 {
-		return "", "", errEmptyURL
+		return nil, errEmptyURL
 	}
 
-	URL := f.Args()[0]
+	c.url = f.Args()[0]
 
 	// Now, it is allowed to have switches after the URL,
 	// handle following flags
 	if err := f.Parse(f.Args()[1:]); err != nil {
-		return "", "", err
+		return nil, err
 	}
 
-	return *outPath, URL, nil
+	return c, nil
 }
 
 func command(args ...string) (*cmd, error) {
-	outPath, URL, err := flags(args...)
+	c, err := flags(args...)
 	if err != nil {
 		return nil, err
 	}
 
-	return &cmd{
-		outputPath: outPath,
-		url:        URL,
-	}, nil
+	if c.postData != "" && c.postFile != "" {
+		return nil, errPostData
+	}
+	if c.proxy != "" && c.noProxy {
+		return nil, errProxy
+	}
+	if c.tries == 0 {
+		return nil, errTries
+	}
+	return c, nil
+}
+
+// httpOpts returns the options of the HTTP client.
+func (c *cmd) httpOpts() ([]curl.HTTPClientOpt, error) {
+	var opts []curl.HTTPClientOpt
+	var contentType bool
+	for _, h := range c.headers {
+		name, value, ok := strings.Cut(h, ":")
+		if !ok {
+			return nil, fmt.Errorf("%q: %w", h, errHeader)
+		}
+		name = strings.TrimSpace(name)
+		contentType = contentType || http.CanonicalHeaderKey(name) == "Content-Type"
+		opts = append(opts, curl.WithHeader(name, strings.TrimSpace(value)))
+	}
+
+	if c.user != "" || c.password != "" {
+		opts = append(opts, curl.WithBasicAuth(c.user, c.password))
+	}
+	if c.bearer != "" {
+		opts = append(opts, curl.WithBearerToken(c.bearer))
+	}
+	if c.method != "" {
+		opts = append(opts, curl.WithMethod(strings.ToUpper(c.method)))
+	}
+
+	body := []byte(c.postData)
+	if c.postFile != "" {
+		b, err := os.ReadFile(c.postFile)
+		if err != nil {
+			return nil, err
+		}
+		body = b
+	}
+	if len(body) > 0 {
+		// As GNU wget, post forms unless told otherwise.
+		if !contentType {
+			opts = append(opts, curl.WithHeader("Content-Type", "application/x-www-form-urlencoded"))
+		}
+		opts = append(opts, curl.WithBody(body))
+	}
+
+	if c.caCert != "" || c.cert != "" || c.insecure {
+		config, err := curl.TLSConfig(c.caCert, c.cert, c.key)
+		if err != nil {
+			return nil, err
+		}
+		config.InsecureSkipVerify = c.insecure
+		opts = append(opts, curl.WithTLSConfig(config))
+	}
+
+	switch {
+	case c.noProxy:
+		opts = append(opts, curl.WithProxy(nil))
+	case c.proxy != "":
+		u, err := url.Parse(c.proxy)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, curl.WithProxy(u))
+	}
+	return opts, nil
+}
+
+// schemes returns the schemes to fetch the file from offset on with.
+func (c *cmd) schemes(offset int64) (curl.Schemes, error) {
+	opts, err := c.httpOpts()
+	if err != nil {
+		return nil, err
+	}
+	if offset > 0 {
+		opts = append(opts, curl.WithOffset(offset))
+	}
+	httpClient := curl.NewHTTPClient(http.DefaultClient, opts...)
+
+	schemes := curl.Schemes{
+		"tftp": curl.DefaultTFTPClient,
+		"http": httpClient,
+
+		// curl.DefaultSchemes doesn't support HTTPS by default.
+		"https": httpClient,
+		"nfs":   curl.DefaultNFSClient,
+		"file":  &curl.LocalFileClient{},
+	}
+	if c.tries == 1 {
+		return schemes, nil
+	}
+
+	retryHTTP := curl.RetryOr(curl.RetryConnectErrors, curl.RetryTemporaryNetworkErrors, curl.RetryHTTP)
+	for scheme, doRetry := range map[string]curl.DoRetry{
+		"tftp":  curl.RetryTFTP,
+		"http":  retryHTTP,
+		"https": retryHTTP,
+		"nfs":   curl.RetryOr(curl.RetryConnectErrors, curl.RetryTemporaryNetworkErrors),
+	} {
+		schemes[scheme] = &curl.SchemeWithRetries{
+			Scheme:  schemes[scheme],
+			DoRetry: doRetry,
+			BackOff: backoff.WithMaxRetries(backoff.NewExponentialBackOff(), uint64(c.tries-1)),
+		}
+	}
+	return schemes, nil
+}
+
+// countingReader counts the bytes read for pkg/progress.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	atomic.AddInt64(&r.n, int64(n))
+	return n, err
 }
 
 func (c *cmd) run() error {
@@ -122,32 +332,109 @@ func (c *cmd) run() error {
 		c.outputPath = defaultOutputPath(parsedURL.Path)
 	}
 
-	schemes := curl.Schemes{
-		"tftp": curl.DefaultTFTPClient,
-		"http": curl.DefaultHTTPClient,
+	var wantHash []byte
+	if c.sha256 != "" {
+		if wantHash, err = hex.DecodeString(c.sha256); err != nil {
+			return err
+		}
+		if len(wantHash) != sha256.Size {
+			return errSHA256Len
+		}
+	}
 
-		// curl.DefaultSchemes doesn't support HTTPS by default.
-		"https": curl.DefaultHTTPClient,
-		"file":  &curl.LocalFileClient{},
+	// Resume after what is there already.
+	var offset int64
+	if c.resume && c.outputPath != "-" {
+		if fi, err := os.Stat(c.outputPath); err == nil {
+			offset = fi.Size()
+		}
 	}
 
-	reader, err := schemes.FetchWithoutCache(context.Background(), parsedURL)
+	schemes, err := c.schemes(offset)
+	if err != nil {
+		return err
+	}
+
+	var reader io.Reader
+	reader, err = schemes.FetchWithoutCache(context.Background(), parsedURL)
 	if err != nil {
 		return fmt.Errorf("failed to download %v: %w", c.url, err)
 	}
 
+	// HTTP fetches from the offset on, the other schemes skip to it.
+	if offset > 0 && parsedURL.Scheme != "http" && parsedURL.Scheme != "https" {
+		if _, err := io.CopyN(io.Discard, reader, offset); err != nil {
+			return fmt.Errorf("failed to resume %v at %d: %w", c.url, offset, err)
+		}
+	}
+
+	if c.showProgress {
+		cr := &countingReader{r: reader}
+		p := progress.New(os.Stderr, "progress", &cr.n)
+		p.Begin()
+		defer p.End()
+		reader = cr
+	}
+
 	if c.outputPath == "-" {
-		_, err := io.Copy(os.Stdout, reader)
+		if wantHash == nil {
+			_, err := io.Copy(os.Stdout, reader)
+			return err
+		}
+		// Verify all of it before any of it goes out.
+		b, err := io.ReadAll(reader)
+		if err != nil {
+			return err
+		}
+		if _, err := vfile.CheckHashedContent(bytes.NewReader(b), wantHash, sha256.New()); err != nil {
+			var e vfile.ErrInvalidHash
+			if errors.As(err, &e) {
+				e.Path = c.url
+				return e
+			}
+			return err
+		}
+		_, err = os.Stdout.Write(b)
 		return err
 	}
 
-	if err := uio.ReadIntoFile(reader, c.outputPath); err != nil {
+	if offset > 0 {
+		err = appendToFile(reader, c.outputPath)
+	} else {
+		err = uio.ReadIntoFile(reader, c.outputPath)
+	}
+	if err != nil {
 		return err
 	}
 
+	if wantHash != nil {
+		f, err := vfile.OpenHashedFile256(c.outputPath, wantHash)
+		if f != nil {
+			f.Close()
+		}
+		if err != nil {
+			// Do not leave a bad file around for a script to pick up.
+			os.Remove(c.outputPath)
+			return err
+		}
+	}
 	return nil
 }
 
+// appendToFile appends the contents of r to the file at p.
+func appendToFile(r io.Reader, p string) error {
+	f, err := os.OpenFile(p, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(f, r); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
 func usage() {
 	log.Printf("Usage: %s [ARGS] URL\n", os.Args[0])
 	flag.PrintDefaults()
@@ -10,6 +10,8 @@
 package main
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
@@ -17,10 +19,14 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/u-root/u-root/pkg/curl"
+	"github.com/u-root/u-root/pkg/vfile"
 )
 
 const content = "Very simple web server"
@@ -209,10 +215,14 @@ func TestFlags(t *testing.T) {
 		{name: "url with -O last", args: []string{"wget", "a", "-O", "b"}, out: "b", url: "a", err: nil},
 	} {
 		t.Run(tt.name, func(t *testing.T) {
-			o, u, err := flags(tt.args...)
+			c, err := flags(tt.args...)
 			if !errors.Is(err, tt.err) {
 				t.Errorf("err:got %v, want %v", err, tt.err)
 			}
+			var o, u string
+			if c != nil {
+				o, u = c.outputPath, c.url
+			}
 			if o != tt.out {
 				t.Errorf("out:got %q, want %q", o, tt.out)
 			}
@@ -223,6 +233,29 @@ func TestFlags(t *testing.T) {
 	}
 }
 
+func TestCommand(t *testing.T) {
+	c, err := command("wget", "-c", "--tries", "3", "--header", "X-A: 1", "a", "--header=X-B: 2", "--no-proxy")
+	if err != nil {
+		t.Fatalf("command() = %v", err)
+	}
+	if !c.resume || c.tries != 3 || !c.noProxy || len(c.headers) != 2 || c.headers[1] != "X-B: 2" {
+		t.Errorf("command() = %+v", c)
+	}
+
+	for _, tt := range []struct {
+		args []string
+		err  error
+	}{
+		{args: []string{"wget", "--post-data", "a=b", "--post-file", "f", "a"}, err: errPostData},
+		{args: []string{"wget", "--proxy", "http://proxy", "--no-proxy", "a"}, err: errProxy},
+		{args: []string{"wget", "-t", "0", "a"}, err: errTries},
+	} {
+		if _, err := command(tt.args...); !errors.Is(err, tt.err) {
+			t.Errorf("command(%q) = %v, want %v", tt.args, err, tt.err)
+		}
+	}
+}
+
 func TestStdout(t *testing.T) {
 	srv := httptest.NewServer(handler{})
 	defer srv.Close()
@@ -257,3 +290,93 @@ func TestStdout(t *testing.T) {
 		t.Errorf("stdout = %q; want %q", string(got), content)
 	}
 }
+
+func TestOptions(t *testing.T) {
+	var failures atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/auth":
+			if user, password, ok := r.BasicAuth(); !ok || user != "root" || password != "secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+		case "/post":
+			b, _ := io.ReadAll(r.Body)
+			if r.Method != http.MethodPost || string(b) != "a=b" || r.Header.Get("Content-Type") != "application/x-www-form-urlencoded" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+		case "/flaky":
+			// Fail once, then work.
+			if failures.Add(1) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+		}
+		http.ServeContent(w, r, "", time.Time{}, strings.NewReader(content))
+	}))
+	defer srv.Close()
+
+	sum := sha256.Sum256([]byte(content))
+	goodHash := hex.EncodeToString(sum[:])
+	badHash := strings.Repeat("00", sha256.Size)
+
+	for _, tt := range []struct {
+		name    string
+		args    []string
+		partial string
+		want    string
+		err     error
+	}{
+		{name: "basic auth", args: []string{"--user", "root", "--password", "secret", srv.URL + "/auth"}, want: content},
+		{name: "no auth", args: []string{srv.URL + "/auth"}, err: curl.ErrStatusNotOk},
+		{name: "post", args: []string{"--post-data", "a=b", srv.URL + "/post"}, want: content},
+		{name: "resume", args: []string{"-c", srv.URL + "/file"}, partial: content[:5], want: content},
+		{name: "resume complete file", args: []string{"-c", srv.URL + "/file"}, partial: content, want: content},
+		{name: "no resume", args: []string{srv.URL + "/file"}, partial: "garbage", want: content},
+		{name: "sha256", args: []string{"--sha256", goodHash, srv.URL + "/file"}, want: content},
+		{name: "resume and sha256", args: []string{"-c", "--sha256", goodHash, srv.URL + "/file"}, partial: content[:5], want: content},
+		{name: "wrong sha256", args: []string{"--sha256", badHash, srv.URL + "/file"}, err: vfile.ErrHashMismatch{}},
+		{name: "short sha256", args: []string{"--sha256", "00", srv.URL + "/file"}, err: errSHA256Len},
+		{name: "bad header", args: []string{"--header", "X-A", srv.URL + "/file"}, err: errHeader},
+		{name: "retry", args: []string{"-t", "3", srv.URL + "/flaky"}, want: content},
+		{name: "progress", args: []string{"--show-progress", srv.URL + "/file"}, want: content},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			out := filepath.Join(t.TempDir(), "out")
+			if tt.partial != "" {
+				if err := os.WriteFile(out, []byte(tt.partial), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			c, err := command(append([]string{"wget", "-O", out}, tt.args...)...)
+			if err != nil {
+				t.Fatalf("command() = %v", err)
+			}
+			err = c.run()
+			var mismatch vfile.ErrHashMismatch
+			if errors.As(tt.err, &mismatch) {
+				if !errors.As(err, &mismatch) {
+					t.Fatalf("run() = %v, want %T", err, tt.err)
+				}
+				if _, err := os.Stat(out); !os.IsNotExist(err) {
+					t.Errorf("file with the wrong hash was not removed: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("run() = %v, want %v", err, tt.err)
+			}
+			if tt.want == "" {
+				return
+			}
+			got, err := os.ReadFile(out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("output = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
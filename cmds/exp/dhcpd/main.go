@@ -0,0 +1,137 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// dhcpd serves DHCPv4 and DHCPv6.
+//
+// Synopsis:
+//
+//	dhcpd [-leases FILE] CONFIG
+//	dhcpd -list [-leases FILE] [CONFIG]
+//
+// Description:
+//
+//	dhcpd hands out addresses from a pool per interface, with static
+//	reservations by MAC address or DUID, delegates IPv6 prefixes, and
+//	offers boot files selected by client architecture, as the JSON file
+//	CONFIG describes:
+//
+//	  {
+//	    "lease_file": "/var/lib/dhcpd.leases",
+//	    "interfaces": [{
+//	      "name": "eth0",
+//	      "dhcp4": {
+//	        "subnet": "192.168.0.0/24",
+//	        "start": "192.168.0.100", "end": "192.168.0.199",
+//	        "routers": ["192.168.0.1"], "dns": ["192.168.0.1"],
+//	        "lease_time": "1h",
+//	        "reservations": [{"mac": "52:54:00:12:34:56", "ip": "192.168.0.10", "hostname": "node0"}]
+//	      },
+//	      "dhcp6": {
+//	        "start": "fd00::100", "end": "fd00::1ff",
+//	        "prefixes": "fd00:100::/40", "prefix_length": 56
+//	      },
+//	      "boot": [
+//	        {"arch": [7, 9], "file": "bootx64.efi"},
+//	        {"arch": [16], "file": "http://192.168.0.1/bootx64.efi", "url": "http://[fd00::1]/bootx64.efi"},
+//	        {"file": "pxelinux.0"}
+//	      ]
+//	    }]
+//	  }
+//
+//	Leases are kept in the lease file, which is read at startup. With
+//	-list, dhcpd prints the leases in it and exits.
+//
+// Options:
+//
+//	-leases: lease file, instead of the lease_file of CONFIG
+//	-list:   print the leases and exit
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/u-root/u-root/pkg/dhcpd"
+)
+
+var errUsage = errors.New("usage: dhcpd [-leases FILE] CONFIG | dhcpd -list [-leases FILE] [CONFIG]")
+
+func run(ctx context.Context, stdout io.Writer, args []string) error {
+	f := flag.NewFlagSet("dhcpd", flag.ContinueOnError)
+	leases := f.String("leases", "", "lease file, instead of the lease_file of CONFIG")
+	list := f.Bool("list", false, "print the leases and exit")
+	if err := f.Parse(args); err != nil {
+		return errUsage
+	}
+
+	var c *dhcpd.Config
+	switch {
+	case f.NArg() == 1:
+		var err error
+		if c, err = dhcpd.Load(f.Arg(0)); err != nil {
+			return err
+		}
+		if *leases != "" {
+			c.LeaseFile = *leases
+		}
+	case f.NArg() == 0 && *list && *leases != "":
+	default:
+		return errUsage
+	}
+
+	if *list {
+		path := *leases
+		if path == "" {
+			path = c.LeaseFile
+		}
+		if path == "" {
+			return errors.New("no lease file")
+		}
+		ls, err := dhcpd.ReadLeases(path)
+		if err != nil {
+			return err
+		}
+		return printLeases(stdout, ls)
+	}
+
+	s, err := dhcpd.New(c)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ctx)
+}
+
+func printLeases(w io.Writer, ls []dhcpd.Lease) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "TYPE\tINTERFACE\tADDRESS\tMAC\tHOSTNAME\tEXPIRES")
+	for _, l := range ls {
+		addr := l.Addr.String()
+		if l.Prefix.IsValid() {
+			addr = l.Prefix.String()
+		}
+		mac := l.MAC
+		if l.Client == "" {
+			mac = "(declined)"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", l.Type, l.Interface, addr, mac, l.Hostname, l.Expires.Format(time.RFC3339))
+	}
+	return tw.Flush()
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := run(ctx, os.Stdout, os.Args[1:]); err != nil && !errors.Is(err, context.Canceled) {
+		log.Fatalf("dhcpd: %v", err)
+	}
+}
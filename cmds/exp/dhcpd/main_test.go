@@ -0,0 +1,55 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const leases = `[
+	{"type": "ipv4", "interface": "eth0", "client": "52:54:00:00:00:01", "mac": "52:54:00:00:00:01", "addr": "192.168.0.2", "hostname": "box", "expires": "2026-01-01T01:00:00Z"},
+	{"type": "ia_pd", "interface": "eth0", "client": "0003000152540000000100000001", "prefix": "fd00:100::/64", "expires": "2026-01-01T12:00:00Z"},
+	{"type": "ipv4", "interface": "eth0", "addr": "192.168.0.3", "expires": "2026-01-01T01:00:00Z"}
+]`
+
+func TestList(t *testing.T) {
+	dir := t.TempDir()
+	lf := filepath.Join(dir, "leases")
+	if err := os.WriteFile(lf, []byte(leases), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	conf := filepath.Join(dir, "dhcpd.json")
+	if err := os.WriteFile(conf, []byte(`{"lease_file": "`+lf+`", "interfaces": [{"name": "eth0", "dhcp4": {"subnet": "192.168.0.0/24"}}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{{"-list", "-leases", lf}, {"-list", conf}} {
+		var out bytes.Buffer
+		if err := run(context.Background(), &out, args); err != nil {
+			t.Fatalf("run(%q) = %v", args, err)
+		}
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		if len(lines) != 4 || !strings.Contains(lines[1], "192.168.0.2") || !strings.Contains(lines[1], "box") ||
+			!strings.Contains(lines[2], "fd00:100::/64") || !strings.Contains(lines[3], "(declined)") {
+			t.Errorf("run(%q) printed\n%s", args, out.String())
+		}
+	}
+}
+
+func TestUsage(t *testing.T) {
+	for _, args := range [][]string{{}, {"-list"}, {"a", "b"}, {"-x"}} {
+		if err := run(context.Background(), &bytes.Buffer{}, args); !errors.Is(err, errUsage) {
+			t.Errorf("run(%q) = %v, want %v", args, err, errUsage)
+		}
+	}
+	if err := run(context.Background(), &bytes.Buffer{}, []string{filepath.Join(t.TempDir(), "missing.json")}); err == nil {
+		t.Errorf("run with a missing configuration succeeded")
+	}
+}
@@ -0,0 +1,309 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// dig queries DNS servers.
+//
+// Synopsis:
+//
+//	dig [@SERVER] [-p PORT] [-t TYPE] [-x ADDR] [+OPTION...] [NAME] [TYPE]
+//
+// Description:
+//
+//	dig sends a query for NAME to SERVER, or to the first nameserver of
+//	/etc/resolv.conf, and prints the response. TYPE is A by default; with
+//	-x, dig looks up the PTR record of ADDR.
+//
+//	Answers that are truncated over UDP are retried over TCP. SERVER may
+//	be tls://ADDR[#NAME] for DNS over TLS, where NAME is the name the
+//	certificate of the server is checked against.
+//
+// Options:
+//
+//	-p:           port to query
+//	-t:           type of the query
+//	-x:           reverse lookup of ADDR
+//	+short:       print only the data of the answers
+//	+[no]tcp:     query over TCP
+//	+[no]tls:     query over TLS, on port 853 unless -p is given
+//	+[no]dnssec:  ask for DNSSEC records
+//	+[no]cdflag:  set the checking disabled flag
+//	+[no]recurse: set the recursion desired flag, which is on by default
+//	+time=N:      timeout in seconds
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/u-root/u-root/pkg/dns"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+var errUsage = errors.New("usage: dig [@SERVER] [-p PORT] [-t TYPE] [-x ADDR] [+OPTION...] [NAME] [TYPE]")
+
+// resolvConf is where the default server comes from.
+var resolvConf = "/etc/resolv.conf"
+
+type options struct {
+	server  string
+	port    int
+	name    string
+	qtype   dnsmessage.Type
+	short   bool
+	tcp     bool
+	tls     bool
+	dnssec  bool
+	cd      bool
+	norec   bool
+	timeout time.Duration
+}
+
+func parseArgs(args []string) (*options, error) {
+	o := &options{qtype: dnsmessage.TypeA, timeout: dns.DefaultTimeout}
+	var typeSet bool
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		// value returns the argument of an option, either attached or
+		// the next one.
+		value := func() (string, error) {
+			if len(arg) > 2 {
+				return arg[2:], nil
+			}
+			if i+1 == len(args) {
+				return "", errUsage
+			}
+			i++
+			return args[i], nil
+		}
+		switch {
+		case strings.HasPrefix(arg, "@"):
+			o.server = arg[1:]
+		case strings.HasPrefix(arg, "+"):
+			if err := o.plus(arg[1:]); err != nil {
+				return nil, err
+			}
+		case strings.HasPrefix(arg, "-p"):
+			v, err := value()
+			if err != nil {
+				return nil, err
+			}
+			if o.port, err = strconv.Atoi(v); err != nil || o.port <= 0 || o.port > 65535 {
+				return nil, fmt.Errorf("invalid port %q", v)
+			}
+		case strings.HasPrefix(arg, "-t"):
+			v, err := value()
+			if err != nil {
+				return nil, err
+			}
+			t, ok := dns.ParseType(v)
+			if !ok {
+				return nil, fmt.Errorf("invalid type %q", v)
+			}
+			o.qtype, typeSet = t, true
+		case strings.HasPrefix(arg, "-x"):
+			v, err := value()
+			if err != nil {
+				return nil, err
+			}
+			a, err := netip.ParseAddr(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid address %q", v)
+			}
+			o.name, o.qtype, typeSet = dns.ReverseName(a), dnsmessage.TypePTR, true
+		case strings.HasPrefix(arg, "-"):
+			return nil, errUsage
+		default:
+			// A type after the name, as in "dig example.com MX".
+			if t, ok := dns.ParseType(arg); ok && o.name != "" && !typeSet {
+				o.qtype, typeSet = t, true
+				continue
+			}
+			if o.name != "" {
+				return nil, errUsage
+			}
+			o.name = arg
+		}
+	}
+	if o.name == "" {
+		// Like dig, ask for the root servers.
+		o.name, o.qtype = ".", dnsmessage.TypeNS
+	}
+	return o, nil
+}
+
+// plus parses a +option.
+func (o *options) plus(opt string) error {
+	if v, ok := strings.CutPrefix(opt, "time="); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return fmt.Errorf("invalid timeout %q", v)
+		}
+		o.timeout = time.Duration(n) * time.Second
+		return nil
+	}
+	name, no := strings.CutPrefix(opt, "no")
+	var b *bool
+	switch name {
+	case "short":
+		b = &o.short
+	case "tcp", "vc":
+		b = &o.tcp
+	case "tls":
+		b = &o.tls
+	case "dnssec":
+		b = &o.dnssec
+	case "cdflag", "cd":
+		b = &o.cd
+	case "recurse", "rec":
+		o.norec = no
+		return nil
+	default:
+		return fmt.Errorf("invalid option +%s", opt)
+	}
+	*b = !no
+	return nil
+}
+
+// upstream returns the server to query.
+func (o *options) upstream(ctx context.Context) (dns.Upstream, error) {
+	s := o.server
+	if s == "" {
+		rc, err := dns.ReadResolvConf(resolvConf)
+		if err != nil || len(rc.Nameservers) == 0 {
+			s = "127.0.0.1"
+		} else {
+			s = rc.Nameservers[0].String()
+		}
+	}
+	u, err := dns.ParseUpstream(s)
+	if err != nil {
+		// A name, as in @dns.google.
+		addrs, lerr := net.DefaultResolver.LookupNetIP(ctx, "ip", s)
+		if lerr != nil || len(addrs) == 0 {
+			return u, err
+		}
+		u = dns.Upstream{Addr: netip.AddrPortFrom(addrs[0].Unmap(), dns.Port), ServerName: s}
+	}
+	if o.tls && !u.TLS {
+		u.TLS = true
+		u.Addr = netip.AddrPortFrom(u.Addr.Addr(), dns.TLSPort)
+	}
+	if o.port != 0 {
+		u.Addr = netip.AddrPortFrom(u.Addr.Addr(), uint16(o.port))
+	}
+	return u, nil
+}
+
+func run(ctx context.Context, stdout io.Writer, args []string) error {
+	o, err := parseArgs(args)
+	if err != nil {
+		return err
+	}
+	u, err := o.upstream(ctx)
+	if err != nil {
+		return err
+	}
+	q, err := dns.NewQuery(o.name, o.qtype, o.dnssec)
+	if err != nil {
+		return err
+	}
+	q.Header.RecursionDesired = !o.norec
+	q.Header.CheckingDisabled = o.cd
+
+	c := dns.Client{Timeout: o.timeout, TCP: o.tcp}
+	r, err := c.Exchange(ctx, q, u)
+	if err != nil {
+		return fmt.Errorf("communications error to %s: %w", u, err)
+	}
+	if o.short {
+		for _, a := range r.Answers {
+			fmt.Fprintln(stdout, dns.FormatData(a.Body))
+		}
+		return nil
+	}
+	printResponse(stdout, args, u, r)
+	return nil
+}
+
+// printResponse prints r in the format of dig.
+func printResponse(w io.Writer, args []string, u dns.Upstream, r *dns.Response) {
+	h := r.Header
+	fmt.Fprintf(w, "\n; <<>> dig <<>> %s\n", strings.Join(args, " "))
+	fmt.Fprintf(w, ";; Got answer:\n")
+	fmt.Fprintf(w, ";; ->>HEADER<<- opcode: QUERY, status: %s, id: %d\n", dns.RCodeString(h.RCode), h.ID)
+
+	var flags []string
+	for _, f := range []struct {
+		set  bool
+		name string
+	}{
+		{h.Response, "qr"},
+		{h.Authoritative, "aa"},
+		{h.Truncated, "tc"},
+		{h.RecursionDesired, "rd"},
+		{h.RecursionAvailable, "ra"},
+		{h.AuthenticData, "ad"},
+		{h.CheckingDisabled, "cd"},
+	} {
+		if f.set {
+			flags = append(flags, f.name)
+		}
+	}
+	fmt.Fprintf(w, ";; flags: %s; QUERY: %d, ANSWER: %d, AUTHORITY: %d, ADDITIONAL: %d\n",
+		strings.Join(flags, " "), len(r.Questions), len(r.Answers), len(r.Authorities), len(r.Additionals))
+
+	var additionals []dnsmessage.Resource
+	for _, a := range r.Additionals {
+		if a.Header.Type != dnsmessage.TypeOPT {
+			additionals = append(additionals, a)
+			continue
+		}
+		var eflags string
+		if a.Header.DNSSECAllowed() {
+			eflags = " do"
+		}
+		fmt.Fprintf(w, "\n;; OPT PSEUDOSECTION:\n; EDNS: version: %d, flags:%s; udp: %d\n", a.Header.TTL>>16&0xff, eflags, a.Header.Class)
+	}
+
+	fmt.Fprintf(w, "\n;; QUESTION SECTION:\n")
+	for _, q := range r.Questions {
+		fmt.Fprintf(w, ";%s\t\t%s\t%s\n", q.Name, dns.ClassString(q.Class), dns.TypeString(q.Type))
+	}
+	for _, s := range []struct {
+		name string
+		rs   []dnsmessage.Resource
+	}{
+		{"ANSWER", r.Answers},
+		{"AUTHORITY", r.Authorities},
+		{"ADDITIONAL", additionals},
+	} {
+		if len(s.rs) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "\n;; %s SECTION:\n", s.name)
+		for _, rr := range s.rs {
+			fmt.Fprintln(w, dns.FormatResource(rr))
+		}
+	}
+
+	fmt.Fprintf(w, "\n;; Query time: %d msec\n", r.RTT.Milliseconds())
+	fmt.Fprintf(w, ";; SERVER: %s#%d(%s) (%s)\n", u.Addr.Addr(), u.Addr.Port(), u.Addr.Addr(), r.Transport)
+	fmt.Fprintf(w, ";; WHEN: %s\n", time.Now().Format(time.UnixDate))
+	fmt.Fprintf(w, ";; MSG SIZE  rcvd: %d\n\n", r.Size)
+}
+
+func main() {
+	if err := run(context.Background(), os.Stdout, os.Args[1:]); err != nil {
+		log.Fatalf("dig: %v", err)
+	}
+}
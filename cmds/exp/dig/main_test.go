@@ -0,0 +1,119 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/u-root/u-root/pkg/dns"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func TestParseArgs(t *testing.T) {
+	for _, tt := range []struct {
+		args []string
+		want options
+		err  bool
+	}{
+		{args: nil, want: options{name: ".", qtype: dnsmessage.TypeNS}},
+		{args: []string{"example.com"}, want: options{name: "example.com", qtype: dnsmessage.TypeA}},
+		{args: []string{"@1.1.1.1", "example.com", "mx", "+short"}, want: options{server: "1.1.1.1", name: "example.com", qtype: dnsmessage.TypeMX, short: true}},
+		{args: []string{"-t", "SRV", "_ldap._tcp.example.com", "-p5353"}, want: options{name: "_ldap._tcp.example.com", qtype: dnsmessage.TypeSRV, port: 5353}},
+		{args: []string{"-x", "192.0.2.1", "+tcp", "+dnssec", "+cd", "+norec", "+time=2"}, want: options{name: "1.2.0.192.in-addr.arpa.", qtype: dnsmessage.TypePTR, tcp: true, dnssec: true, cd: true, norec: true, timeout: 2 * time.Second}},
+		{args: []string{"example.com", "+tls", "+notls"}, want: options{name: "example.com", qtype: dnsmessage.TypeA}},
+		// A name that looks like a type is still the name.
+		{args: []string{"txt"}, want: options{name: "txt", qtype: dnsmessage.TypeA}},
+		{args: []string{"a.example", "b.example"}, err: true},
+		{args: []string{"-t", "BOGUS", "example.com"}, err: true},
+		{args: []string{"-x", "example.com"}, err: true},
+		{args: []string{"+bogus"}, err: true},
+		{args: []string{"-p"}, err: true},
+	} {
+		got, err := parseArgs(tt.args)
+		if tt.err {
+			if err == nil {
+				t.Errorf("parseArgs(%q) succeeded, want error", tt.args)
+			}
+			continue
+		}
+		if tt.want.timeout == 0 {
+			tt.want.timeout = dns.DefaultTimeout
+		}
+		if err != nil || *got != tt.want {
+			t.Errorf("parseArgs(%q) = %+v, %v, want %+v", tt.args, got, err, tt.want)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	hosts := filepath.Join(dir, "hosts")
+	if err := os.WriteFile(hosts, []byte("192.0.2.5 router.lan\n2001:db8::5 router.lan\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pc, err := net.ListenPacket("udp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s := &dns.Server{HostsFile: hosts, Logf: t.Logf}
+		s.Serve(ctx, pc, ln)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	resolvConf = filepath.Join(dir, "resolv.conf")
+	if err := os.WriteFile(resolvConf, []byte("nameserver 127.0.0.1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	port := strconv.Itoa(ln.Addr().(*net.TCPAddr).Port)
+
+	for _, tt := range []struct {
+		args []string
+		want []string
+	}{
+		{[]string{"-p", port, "router.lan", "+short"}, []string{"192.0.2.5\n"}},
+		{[]string{"-p", port, "router.lan", "AAAA", "+short"}, []string{"2001:db8::5\n"}},
+		{[]string{"-p", port, "-x", "192.0.2.5", "+short"}, []string{"router.lan.\n"}},
+		{[]string{"@127.0.0.1", "-p", port, "router.lan", "+dnssec"}, []string{
+			"status: NOERROR",
+			";; flags: qr aa rd ra; QUERY: 1, ANSWER: 1, AUTHORITY: 0, ADDITIONAL: 1",
+			"; EDNS: version: 0, flags: do; udp: 1232",
+			";router.lan.\t\tIN\tA\n",
+			";; ANSWER SECTION:\nrouter.lan.\t0\tIN\tA\t192.0.2.5\n",
+			";; SERVER: 127.0.0.1#" + port + "(127.0.0.1) (UDP)",
+		}},
+		{[]string{"-p", port, "router.lan", "+tcp"}, []string{"(TCP)"}},
+		// Without upstream servers, other names fail.
+		{[]string{"-p", port, "example.com", "+cd"}, []string{"status: SERVFAIL", "flags: qr rd ra cd;"}},
+	} {
+		var out strings.Builder
+		if err := run(context.Background(), &out, tt.args); err != nil {
+			t.Errorf("dig %q = %v", tt.args, err)
+			continue
+		}
+		for _, w := range tt.want {
+			if !strings.Contains(out.String(), w) {
+				t.Errorf("dig %q = %q, want it to contain %q", tt.args, out.String(), w)
+			}
+		}
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// dnsd is a caching stub resolver.
+//
+// Synopsis:
+//
+//	dnsd [-listen ADDR] [-resolv FILE] [-stub] [-hosts FILE] [-upstream SERVER[,SERVER...]] [-cache N]
+//
+// Description:
+//
+//	dnsd answers DNS queries over UDP and TCP from the hosts file, from
+//	its cache, or by forwarding them to upstream servers.
+//
+//	The upstream servers are the nameservers of resolv.conf, which is
+//	reread when it changes, so dnsd follows the servers that DHCP
+//	provides. With -stub, dnsd then points resolv.conf to itself, and
+//	puts the servers back when it exits.
+//
+//	With -upstream, dnsd forwards to the given servers instead. A server
+//	of the form tls://ADDR[:PORT][#NAME] is queried over TLS, and its
+//	certificate checked against NAME.
+//
+// Options:
+//
+//	-listen:   address to listen on (default 127.0.0.53:53)
+//	-resolv:   resolv.conf file to take upstream servers from (default /etc/resolv.conf)
+//	-stub:     point resolv.conf to dnsd
+//	-hosts:    hosts file to answer from (default /etc/hosts)
+//	-upstream: comma-separated upstream servers
+//	-cache:    number of answers to cache, 0 to disable (default 4096)
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/u-root/u-root/pkg/dns"
+)
+
+var errUsage = errors.New("usage: dnsd [-listen ADDR] [-resolv FILE] [-stub] [-hosts FILE] [-upstream SERVER[,SERVER...]] [-cache N]")
+
+// newServer returns the server and listen address that args configure.
+func newServer(args []string) (*dns.Server, string, error) {
+	f := flag.NewFlagSet("dnsd", flag.ContinueOnError)
+	listen := f.String("listen", dns.StubAddr, "address to listen on")
+	resolv := f.String("resolv", "/etc/resolv.conf", "resolv.conf file to take upstream servers from")
+	stub := f.Bool("stub", false, "point resolv.conf to dnsd")
+	hosts := f.String("hosts", "/etc/hosts", "hosts file to answer from")
+	upstream := f.String("upstream", "", "comma-separated upstream servers")
+	cache := f.Int("cache", dns.DefaultCacheSize, "number of answers to cache, 0 to disable")
+	if err := f.Parse(args); err != nil || f.NArg() != 0 {
+		return nil, "", errUsage
+	}
+
+	s := &dns.Server{
+		ResolvConf:     *resolv,
+		StubResolvConf: *stub,
+		HostsFile:      *hosts,
+		CacheSize:      *cache,
+	}
+	if *cache == 0 {
+		s.CacheSize = -1
+	}
+	if *upstream != "" {
+		if *stub {
+			return nil, "", errors.New("-stub takes the upstream servers from resolv.conf")
+		}
+		for u := range strings.SplitSeq(*upstream, ",") {
+			up, err := dns.ParseUpstream(u)
+			if err != nil {
+				return nil, "", err
+			}
+			s.Upstreams = append(s.Upstreams, up)
+		}
+	}
+	return s, *listen, nil
+}
+
+func run(ctx context.Context, args []string) error {
+	s, listen, err := newServer(args)
+	if err != nil {
+		return err
+	}
+	return s.ListenAndServe(ctx, listen)
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := run(ctx, os.Args[1:]); err != nil && !errors.Is(err, context.Canceled) {
+		log.Fatalf("dnsd: %v", err)
+	}
+}
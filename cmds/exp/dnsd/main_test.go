@@ -0,0 +1,45 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"net/netip"
+	"slices"
+	"testing"
+
+	"github.com/u-root/u-root/pkg/dns"
+)
+
+func TestNewServer(t *testing.T) {
+	s, listen, err := newServer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if listen != dns.StubAddr || s.ResolvConf != "/etc/resolv.conf" || s.HostsFile != "/etc/hosts" || s.StubResolvConf || len(s.Upstreams) != 0 {
+		t.Errorf("newServer() = %+v, %q", s, listen)
+	}
+
+	s, listen, err = newServer([]string{"-listen", "[::1]:5353", "-upstream", "192.0.2.1,tls://9.9.9.9#dns.quad9.net", "-cache", "0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []dns.Upstream{
+		{Addr: netip.MustParseAddrPort("192.0.2.1:53")},
+		{Addr: netip.MustParseAddrPort("9.9.9.9:853"), TLS: true, ServerName: "dns.quad9.net"},
+	}
+	if listen != "[::1]:5353" || !slices.Equal(s.Upstreams, want) || s.CacheSize >= 0 {
+		t.Errorf("newServer() = %+v, %q", s, listen)
+	}
+
+	for _, args := range [][]string{
+		{"extra"},
+		{"-upstream", "dns.example"},
+		{"-upstream", "192.0.2.1", "-stub"},
+	} {
+		if _, _, err := newServer(args); err == nil {
+			t.Errorf("newServer(%q) succeeded, want error", args)
+		}
+	}
+}
@@ -16,15 +16,31 @@
 // Unlike the standard hdparm command, we do not allow empty
 // arguments for commands requiring a password.
 //
+// Besides the upstream verbs, --smart prints the SMART attributes in the
+// style of smartctl, and --inquiry and --read-capacity issue native SCSI
+// commands, which also work on SAS disks.
+//
+// Erase and sanitize verbs destroy all data on the disk and require
+// --yes-i-know-what-i-am-doing.
+//
 // Synopsis:
 //
-//	hdparm [--i] [--security-unlock[=password]] [--user-master|--timeout] [device ...]
+//	hdparm [--i] [--smart] [--inquiry] [--read-capacity] [device ...]
+//	hdparm [--security-unlock=password] [--security-set-pass=password] [--security-disable=password] [--user-master] [--security-mode=h|m] [device ...]
+//	hdparm [--security-erase=password] [--security-erase-enhanced=password] [--user-master] [--yes-i-know-what-i-am-doing] [device ...]
+//	hdparm [--sanitize-crypto-scramble] [--sanitize-block-erase] [--sanitize-overwrite=pattern] [--sanitize-overwrite-passes=n] [--sanitize-status] [--wait] [--yes-i-know-what-i-am-doing] [device ...]
+//	hdparm [--timeout=duration] ...
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/u-root/u-root/pkg/mount/scuzz"
@@ -35,6 +51,16 @@ import (
 // return a string and error. This simplifies other aspects of this program.
 type op func(scuzz.Disk) (string, error)
 
+// verb is an operation selected by a switch.
+type verb struct {
+	op op
+	// scsi verbs only use native SCSI commands and can be used on
+	// disks that do not speak ATA.
+	scsi bool
+	// destructive verbs destroy data and need --yes-i-know-what-i-am-doing.
+	destructive bool
+}
+
 var (
 	verbose         = flag.Bool("v", false, "verbose log")
 	debug           = func(string, ...any) {}
@@ -42,32 +68,78 @@ var (
 	identify        = flag.Bool("i", false, "Get drive identifying information")
 	admin           = flag.Bool("user-master", false, "Unlock admin (true) or user (false)")
 	timeoutDuration = flag.String("timeout", "15s", "Timeout for operations expressed as a Go duration (e.g. 15s)")
-	verbs           = []string{"security-unlock", "i"}
+
+	smart           = flag.Bool("smart", false, "Print SMART attributes and health")
+	setPass         = flag.String("security-set-pass", "", "Set the user (or, with --user-master, admin) password")
+	securityMode    = flag.String("security-mode", "h", "Security level for --security-set-pass: h (high) or m (maximum)")
+	disablePass     = flag.String("security-disable", "", "Disable security with a password")
+	erase           = flag.String("security-erase", "", "Erase the drive with SECURITY ERASE UNIT, given the password")
+	eraseEnhanced   = flag.String("security-erase-enhanced", "", "Erase the drive with enhanced SECURITY ERASE UNIT, given the password")
+	sanitizeCrypto  = flag.Bool("sanitize-crypto-scramble", false, "Sanitize the drive by changing its encryption keys")
+	sanitizeBlock   = flag.Bool("sanitize-block-erase", false, "Sanitize the drive with a block erase")
+	sanitizeOver    = flag.String("sanitize-overwrite", "", "Sanitize the drive by overwriting it with a 32-bit hex pattern")
+	sanitizePasses  = flag.Uint("sanitize-overwrite-passes", 1, "Number of passes for --sanitize-overwrite (1-16)")
+	sanitizeStatus  = flag.Bool("sanitize-status", false, "Print the sanitize status")
+	wait            = flag.Bool("wait", false, "Wait for a sanitize operation to complete")
+	inquiry         = flag.Bool("inquiry", false, "Print SCSI INQUIRY data")
+	readCapacity    = flag.Bool("read-capacity", false, "Print SCSI READ CAPACITY data")
+	iKnow           = flag.Bool("yes-i-know-what-i-am-doing", false, "Allow operations that destroy all data")
+	pollingInterval = 5 * time.Second
+
+	verbs = map[string]verb{
+		"security-unlock":          {op: unlockop},
+		"i":                        {op: identifyop},
+		"smart":                    {op: smartop},
+		"security-set-pass":        {op: setpassop},
+		"security-disable":         {op: disableop},
+		"security-erase":           {op: eraseop, destructive: true},
+		"security-erase-enhanced":  {op: eraseop, destructive: true},
+		"sanitize-crypto-scramble": {op: sanitizeop, destructive: true},
+		"sanitize-block-erase":     {op: sanitizeop, destructive: true},
+		"sanitize-overwrite":       {op: sanitizeop, destructive: true},
+		"sanitize-status":          {op: sanitizestatusop},
+		"inquiry":                  {op: inquiryop, scsi: true},
+		"read-capacity":            {op: readcapacityop, scsi: true},
+	}
+
+	errDestructive = errors.New("this operation destroys all data on the disk; add --yes-i-know-what-i-am-doing to proceed")
 )
 
 // The hdparm switches can conflict. This function returns nil if there is no conflict, and a (hopefully)
 // helpful error message otherwise. As a side effect it assigns verb.
-// TODO: use the visitor pattern to make this better. The next flag that's added will include that fix.
-func checkVerbs() (op, error) {
+func checkVerbs(fs *flag.FlagSet) (verb, error) {
 	var v []string
-	var verb op
+	var found verb
 
-	if len(*unlock) > 0 {
-		verb = unlockop
-		v = append(v, "security-unlock")
-	}
-	if *identify {
-		verb = identifyop
-		v = append(v, "i")
-	}
+	fs.Visit(func(f *flag.Flag) {
+		vb, ok := verbs[f.Name]
+		if !ok {
+			return
+		}
+		// Flags set to their zero value, e.g. -i=false or
+		// --security-unlock="", do not select a verb.
+		if f.Value.String() == f.DefValue {
+			return
+		}
+		found = vb
+		v = append(v, f.Name)
+	})
 
 	if len(v) > 1 {
-		return nil, fmt.Errorf("%v verbs were invoked and only one is allowed", v)
+		return verb{}, fmt.Errorf("%v verbs were invoked and only one is allowed", v)
 	}
 	if len(v) < 1 {
-		return nil, fmt.Errorf("no verbs were invoked and one of %v is required", verbs)
+		var names []string
+		for n := range verbs {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return verb{}, fmt.Errorf("no verbs were invoked and one of %v is required", names)
+	}
+	if found.destructive && !*iKnow {
+		return verb{}, errDestructive
 	}
-	return verb, nil
+	return found, nil
 }
 
 func unlockop(d scuzz.Disk) (string, error) {
@@ -82,10 +154,123 @@ func identifyop(d scuzz.Disk) (string, error) {
 	return i.String(), nil
 }
 
+func smartop(d scuzz.Disk) (string, error) {
+	s, err := d.SMART()
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	health := "PASSED"
+	if !s.Passed {
+		health = "FAILED"
+	}
+	fmt.Fprintf(&b, "SMART overall-health self-assessment test result: %s\n", health)
+	fmt.Fprintf(&b, "%3s %-24s %-6s %5s %5s %6s %-9s %-11s %s\n", "ID#", "ATTRIBUTE_NAME", "FLAG", "VALUE", "WORST", "THRESH", "TYPE", "WHEN_FAILED", "RAW_VALUE")
+	for _, a := range s.Attributes {
+		typ := "Old_age"
+		if a.Prefailure() {
+			typ = "Pre-fail"
+		}
+		when := "-"
+		if a.Failing() {
+			when = "FAILING_NOW"
+		}
+		fmt.Fprintf(&b, "%3d %-24s 0x%04x %5d %5d %6d %-9s %-11s %d\n", a.ID, a.Name, a.Flags, a.Current, a.Worst, a.Threshold, typ, when, a.Raw)
+	}
+	return strings.TrimSuffix(b.String(), "\n"), nil
+}
+
+func setpassop(d scuzz.Disk) (string, error) {
+	var level scuzz.SecurityLevel
+	switch *securityMode {
+	case "h":
+		level = scuzz.SecurityHigh
+	case "m":
+		level = scuzz.SecurityMaximum
+	default:
+		return "", fmt.Errorf("security mode %q: must be h or m", *securityMode)
+	}
+	return "", d.SetPassword(*setPass, *admin, level)
+}
+
+func disableop(d scuzz.Disk) (string, error) {
+	return "", d.DisablePassword(*disablePass, *admin)
+}
+
+func eraseop(d scuzz.Disk) (string, error) {
+	password, enhanced := *erase, false
+	if len(*eraseEnhanced) > 0 {
+		password, enhanced = *eraseEnhanced, true
+	}
+	if err := d.SecurityErase(password, *admin, enhanced); err != nil {
+		return "", err
+	}
+	return "security erase completed", nil
+}
+
+func sanitizeop(d scuzz.Disk) (string, error) {
+	var o scuzz.SanitizeOptions
+	switch {
+	case *sanitizeCrypto:
+		o.Action = scuzz.SanitizeCryptoScramble
+	case *sanitizeBlock:
+		o.Action = scuzz.SanitizeBlockErase
+	default:
+		p, err := strconv.ParseUint(strings.TrimPrefix(*sanitizeOver, "0x"), 16, 32)
+		if err != nil {
+			return "", fmt.Errorf("sanitize overwrite pattern %q: %w", *sanitizeOver, err)
+		}
+		if *sanitizePasses < 1 || *sanitizePasses > 16 {
+			return "", fmt.Errorf("%d sanitize overwrite passes: must be 1 to 16", *sanitizePasses)
+		}
+		o.Action = scuzz.SanitizeOverwrite
+		o.Pattern = uint32(p)
+		// 16 passes are encoded as 0.
+		o.OverwritePasses = uint8(*sanitizePasses % 16)
+	}
+	if err := d.Sanitize(o); err != nil {
+		return "", err
+	}
+	if !*wait {
+		return fmt.Sprintf("sanitize (%v) started", o.Action), nil
+	}
+	s, err := scuzz.WaitSanitize(context.Background(), d, pollingInterval, func(s *scuzz.SanitizeStatus) {
+		debug("sanitize: %v", s)
+	})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("sanitize (%v) %v", o.Action, s), nil
+}
+
+func sanitizestatusop(d scuzz.Disk) (string, error) {
+	s, err := d.SanitizeStatus()
+	if err != nil {
+		return "", err
+	}
+	return "sanitize " + s.String(), nil
+}
+
+func inquiryop(d scuzz.Disk) (string, error) {
+	i, err := d.Inquiry()
+	if err != nil {
+		return "", err
+	}
+	return i.String(), nil
+}
+
+func readcapacityop(d scuzz.Disk) (string, error) {
+	c, err := d.ReadCapacity()
+	if err != nil {
+		return "", err
+	}
+	return c.String(), nil
+}
+
 func main() {
 	flag.Parse()
 
-	verb, err := checkVerbs()
+	verb, err := checkVerbs(flag.CommandLine)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -100,13 +285,17 @@ func main() {
 		log.Fatal(err)
 	}
 
+	open := scuzz.NewSGDisk
+	if verb.scsi {
+		open = scuzz.NewSCSIDisk
+	}
 	for _, n := range flag.Args() {
-		d, err := scuzz.NewSGDisk(n, scuzz.WithTimeout(timeout))
+		d, err := open(n, scuzz.WithTimeout(timeout))
 		if err != nil {
 			log.Printf("%v: %v", n, err)
 			continue
 		}
-		s, err := verb(d)
+		s, err := verb.op(d)
 		if err != nil {
 			log.Printf("%v: %v", n, err.Error())
 		}
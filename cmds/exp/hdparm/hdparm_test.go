@@ -0,0 +1,145 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/u-root/u-root/pkg/mount/scuzz"
+)
+
+type fakeDisk struct {
+	scuzz.Disk
+	smart    *scuzz.SMARTInfo
+	sanitize scuzz.SanitizeOptions
+	polls    int
+}
+
+func (f *fakeDisk) SMART() (*scuzz.SMARTInfo, error) {
+	return f.smart, nil
+}
+
+func (f *fakeDisk) Sanitize(o scuzz.SanitizeOptions) error {
+	f.sanitize = o
+	f.polls = 2
+	return nil
+}
+
+func (f *fakeDisk) SanitizeStatus() (*scuzz.SanitizeStatus, error) {
+	if f.polls == 0 {
+		return &scuzz.SanitizeStatus{Completed: true}, nil
+	}
+	f.polls--
+	return &scuzz.SanitizeStatus{InProgress: true}, nil
+}
+
+func testFlags(t *testing.T, args ...string) *flag.FlagSet {
+	t.Helper()
+	fs := flag.NewFlagSet("hdparm", flag.ContinueOnError)
+	fs.Bool("i", false, "")
+	fs.Bool("smart", false, "")
+	fs.Bool("sanitize-block-erase", false, "")
+	fs.String("security-unlock", "", "")
+	fs.String("security-erase", "", "")
+	fs.Bool("v", false, "")
+	if err := fs.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	return fs
+}
+
+func TestCheckVerbs(t *testing.T) {
+	defer func(v bool) { *iKnow = v }(*iKnow)
+	for _, tt := range []struct {
+		args  []string
+		iKnow bool
+		err   string
+	}{
+		{args: []string{"-i"}},
+		{args: []string{"-i", "-v"}},
+		{args: []string{"-smart"}},
+		{args: []string{"-i=false", "-smart"}},
+		{args: []string{"-security-unlock=pw"}},
+		{args: []string{"-v"}, err: "no verbs were invoked"},
+		{args: []string{"-i", "-smart"}, err: "verbs were invoked and only one is allowed"},
+		{args: []string{"-security-erase=pw"}, err: "yes-i-know-what-i-am-doing"},
+		{args: []string{"-security-erase=pw"}, iKnow: true},
+		{args: []string{"-sanitize-block-erase"}, iKnow: true},
+	} {
+		*iKnow = tt.iKnow
+		_, err := checkVerbs(testFlags(t, tt.args...))
+		if tt.err == "" && err != nil {
+			t.Errorf("checkVerbs(%q): got %v, want nil", tt.args, err)
+		}
+		if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("checkVerbs(%q): got %v, want error containing %q", tt.args, err, tt.err)
+		}
+	}
+}
+
+func TestSmartop(t *testing.T) {
+	d := &fakeDisk{smart: &scuzz.SMARTInfo{
+		Passed: true,
+		Attributes: []scuzz.SMARTAttribute{
+			{ID: 5, Name: "Reallocated_Sector_Ct", Flags: 0x33, Current: 100, Worst: 100, Threshold: 10, Raw: 0},
+			{ID: 194, Name: "Temperature_Celsius", Flags: 0x22, Current: 30, Worst: 20, Threshold: 40, Raw: 30},
+		},
+	}}
+	s, err := smartop(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(s, "\n")
+	if len(lines) != 4 {
+		t.Fatalf("smartop: got %d lines, want 4:\n%s", len(lines), s)
+	}
+	if !strings.HasSuffix(lines[0], "PASSED") {
+		t.Errorf("health line: got %q", lines[0])
+	}
+	if f := strings.Fields(lines[2]); f[0] != "5" || f[6] != "Pre-fail" || f[7] != "-" {
+		t.Errorf("attribute 5: got %q", lines[2])
+	}
+	if f := strings.Fields(lines[3]); f[6] != "Old_age" || f[7] != "FAILING_NOW" || f[8] != "30" {
+		t.Errorf("attribute 194: got %q", lines[3])
+	}
+
+	d.smart.Passed = false
+	if s, _ := smartop(d); !strings.Contains(s, "FAILED") {
+		t.Errorf("failed health: got %q", s)
+	}
+}
+
+func TestSanitizeop(t *testing.T) {
+	defer func(o string, p uint, w bool) {
+		*sanitizeOver, *sanitizePasses, *wait = o, p, w
+	}(*sanitizeOver, *sanitizePasses, *wait)
+	pollingInterval = time.Millisecond
+
+	d := &fakeDisk{}
+	*sanitizeOver, *sanitizePasses, *wait = "0xdeadbeef", 16, true
+	s, err := sanitizeop(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := scuzz.SanitizeOptions{Action: scuzz.SanitizeOverwrite, Pattern: 0xdeadbeef, OverwritePasses: 0}
+	if d.sanitize != want {
+		t.Errorf("sanitize options: got %+v, want %+v", d.sanitize, want)
+	}
+	if s != "sanitize (overwrite) completed" || d.polls != 0 {
+		t.Errorf("sanitizeop: got %q with %d polls left", s, d.polls)
+	}
+
+	*sanitizeOver = "nothex"
+	if _, err := sanitizeop(d); err == nil {
+		t.Errorf("sanitizeop with bad pattern: got nil, want error")
+	}
+	*sanitizeOver, *sanitizePasses = "0", 17
+	if _, err := sanitizeop(d); err == nil {
+		t.Errorf("sanitizeop with 17 passes: got nil, want error")
+	}
+}
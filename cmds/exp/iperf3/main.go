@@ -0,0 +1,254 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// iperf3 measures network throughput, jitter and loss between two hosts,
+// and speaks the protocol of iperf3.
+//
+// Synopsis:
+//
+//	iperf3 -s [-p PORT] [-B HOST] [-4|-6] [-1] [-i SECONDS] [-J]
+//	iperf3 -c HOST [-p PORT] [-4|-6] [-u] [-b BITRATE] [-P N] [-R] [-t SECONDS|-n BYTES] [-l LENGTH] [-i SECONDS] [-J]
+//
+// Description:
+//
+//	With -s, iperf3 serves tests to clients, one at a time. With -c, it
+//	runs a test against the server HOST, which may be a u-root or an
+//	iperf3 server.
+//
+//	By default, the client sends over TCP for 10 seconds. With -R, the
+//	server sends. UDP tests send at 1 Mbit/sec unless -b is given.
+//	Sizes take K, M, G and T suffixes of powers of 1024, and bitrates
+//	suffixes of powers of 1000.
+//
+// Options:
+//
+//	-1: serve one test, then exit
+//	-4: only use IPv4
+//	-6: only use IPv6
+//	-B: address to listen on
+//	-J: print results as JSON
+//	-P: number of parallel streams (default: 1)
+//	-R: reverse mode, the server sends
+//	-b: bitrate of each stream in bits/sec (default: 1M for UDP, unlimited for TCP)
+//	-c: run a test against the server HOST
+//	-i: seconds between reports (default: 1)
+//	-l: length of the writes and datagrams (default: 128K for TCP, the MSS for UDP)
+//	-n: number of bytes to send instead of a duration
+//	-p: port of the server (default: 5201)
+//	-s: run as a server
+//	-t: seconds to run the test (default: 10)
+//	-u: use UDP
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/u-root/u-root/pkg/iperf3"
+	"github.com/u-root/u-root/pkg/netcat"
+)
+
+var errUsage = errors.New("usage: iperf3 -s [-p PORT] [-B HOST] [-4|-6] [-1] [-i SECONDS] [-J] | iperf3 -c HOST [-p PORT] [-4|-6] [-u] [-b BITRATE] [-P N] [-R] [-t SECONDS|-n BYTES] [-l LENGTH] [-i SECONDS] [-J]")
+
+type flags struct {
+	server   bool
+	client   string
+	port     uint64
+	bind     string
+	ipv4     bool
+	ipv6     bool
+	oneOff   bool
+	udp      bool
+	bitrate  string
+	parallel int
+	reverse  bool
+	time     float64
+	bytes    string
+	length   string
+	interval float64
+	json     bool
+}
+
+func run(ctx context.Context, stdout io.Writer, args []string) error {
+	var fl flags
+	f := flag.NewFlagSet("iperf3", flag.ContinueOnError)
+	f.BoolVar(&fl.oneOff, "1", false, "serve one test, then exit")
+	f.BoolVar(&fl.ipv4, "4", false, "only use IPv4")
+	f.BoolVar(&fl.ipv6, "6", false, "only use IPv6")
+	f.StringVar(&fl.bind, "B", "", "address to listen on")
+	f.BoolVar(&fl.json, "J", false, "print results as JSON")
+	f.IntVar(&fl.parallel, "P", 1, "number of parallel streams")
+	f.BoolVar(&fl.reverse, "R", false, "reverse mode, the server sends")
+	f.StringVar(&fl.bitrate, "b", "", "bitrate of each stream in bits/sec (default: 1M for UDP, unlimited for TCP)")
+	f.StringVar(&fl.client, "c", "", "run a test against the server `HOST`")
+	f.Float64Var(&fl.interval, "i", 1, "seconds between reports")
+	f.StringVar(&fl.length, "l", "", "length of the writes and datagrams (default: 128K for TCP, the MSS for UDP)")
+	f.StringVar(&fl.bytes, "n", "", "number of bytes to send instead of a duration")
+	f.Uint64Var(&fl.port, "p", iperf3.DefaultPort, "port of the server")
+	f.BoolVar(&fl.server, "s", false, "run as a server")
+	f.Float64Var(&fl.time, "t", iperf3.DefaultDuration.Seconds(), "seconds to run the test")
+	f.BoolVar(&fl.udp, "u", false, "use UDP")
+	if err := f.Parse(args); err != nil || f.NArg() != 0 {
+		return errUsage
+	}
+	if fl.server == (fl.client != "") || fl.ipv4 && fl.ipv6 || fl.port > 65535 || fl.interval <= 0 || fl.time <= 0 {
+		return errUsage
+	}
+
+	ipType := netcat.IP_NONE
+	switch {
+	case fl.ipv4:
+		ipType = netcat.IP_V4
+	case fl.ipv6:
+		ipType = netcat.IP_V6
+	}
+	if fl.server {
+		return serve(ctx, stdout, &fl, ipType)
+	}
+	return client(ctx, stdout, &fl, ipType)
+}
+
+func client(ctx context.Context, stdout io.Writer, fl *flags, ipType netcat.IPType) error {
+	socketType, err := netcat.ParseSocketType(fl.udp, false, false, false)
+	if err != nil {
+		return err
+	}
+	cfg := netcat.Config{
+		Host:            fl.client,
+		Port:            fl.port,
+		ConnectionMode:  netcat.CONNECTION_MODE_CONNECT,
+		ProtocolOptions: netcat.ProtocolOptions{IPType: ipType, SocketType: socketType},
+	}
+	network, err := cfg.ProtocolOptions.Network()
+	if err != nil {
+		return err
+	}
+	addr, err := cfg.Address()
+	if err != nil {
+		return err
+	}
+
+	c := &iperf3.Client{
+		Network:  network,
+		Addr:     addr,
+		Parallel: fl.parallel,
+		Reverse:  fl.reverse,
+		Duration: time.Duration(fl.time * float64(time.Second)),
+		Interval: time.Duration(fl.interval * float64(time.Second)),
+	}
+	if fl.bytes != "" {
+		if c.Bytes, err = iperf3.ParseSize(fl.bytes); err != nil {
+			return err
+		}
+	}
+	if fl.length != "" {
+		l, err := iperf3.ParseSize(fl.length)
+		if err != nil {
+			return err
+		}
+		if l <= 0 || l > 1<<30 {
+			return fmt.Errorf("length %q is out of range", fl.length)
+		}
+		c.BlockSize = int(l)
+	}
+	if fl.bitrate != "" {
+		if c.Bitrate, err = iperf3.ParseBitrate(fl.bitrate); err != nil {
+			return err
+		}
+	}
+	if !fl.json {
+		c.Reporter = &iperf3.TextReporter{W: stdout}
+	}
+
+	res, err := c.Run(ctx)
+	if err != nil {
+		return err
+	}
+	if fl.json {
+		return printJSON(stdout, res)
+	}
+	fmt.Fprintln(stdout, "iperf Done.")
+	return nil
+}
+
+func serve(ctx context.Context, stdout io.Writer, fl *flags, ipType netcat.IPType) error {
+	// iperf3 servers listen on all addresses of both families, unless
+	// told otherwise.
+	network, err := (&netcat.ProtocolOptions{IPType: ipType, SocketType: netcat.SOCKET_TYPE_TCP}).Network()
+	if err != nil {
+		return err
+	}
+	addr := net.JoinHostPort(fl.bind, strconv.FormatUint(fl.port, 10))
+	if fl.bind != "" || ipType != netcat.IP_NONE {
+		cfg := netcat.Config{
+			Host:            fl.bind,
+			Port:            fl.port,
+			ConnectionMode:  netcat.CONNECTION_MODE_LISTEN,
+			ProtocolOptions: netcat.ProtocolOptions{IPType: ipType, SocketType: netcat.SOCKET_TYPE_TCP},
+		}
+		if addr, err = cfg.Address(); err != nil {
+			return err
+		}
+	}
+
+	s, err := iperf3.Listen(network, addr)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	s.Interval = time.Duration(fl.interval * float64(time.Second))
+	_, port, _ := net.SplitHostPort(s.Addr().String())
+	for {
+		if !fl.json {
+			s.Reporter = &iperf3.TextReporter{W: stdout}
+			fmt.Fprintf(stdout, "-----------------------------------------------------------\n")
+			fmt.Fprintf(stdout, "Server listening on %s\n", port)
+			fmt.Fprintf(stdout, "-----------------------------------------------------------\n")
+		}
+		res, err := s.ServeOne(ctx)
+		switch {
+		case ctx.Err() != nil:
+			return ctx.Err()
+		case err != nil && fl.oneOff:
+			return err
+		case err != nil:
+			log.Printf("iperf3: %v", err)
+		case fl.json:
+			if err := printJSON(stdout, res); err != nil {
+				return err
+			}
+		}
+		if fl.oneOff {
+			return nil
+		}
+	}
+}
+
+func printJSON(stdout io.Writer, res *iperf3.Result) error {
+	b, err := json.MarshalIndent(res, "", "\t")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(stdout, "%s\n", b)
+	return err
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := run(ctx, os.Stdout, os.Args[1:]); err != nil && !errors.Is(err, context.Canceled) {
+		log.Fatalf("iperf3: %v", err)
+	}
+}
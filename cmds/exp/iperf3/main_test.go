@@ -0,0 +1,67 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/u-root/u-root/pkg/iperf3"
+)
+
+func TestClient(t *testing.T) {
+	s, err := iperf3.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	s.Logf = t.Logf
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.Serve(ctx)
+	_, port, _ := net.SplitHostPort(s.Addr().String())
+
+	var out bytes.Buffer
+	if err := run(ctx, &out, []string{"-c", "127.0.0.1", "-p", port, "-t", "0.3", "-i", "0.1", "-u", "-b", "2M", "-P", "2"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Connecting to host 127.0.0.1", "Lost/Total Datagrams", "[SUM]", "receiver", "iperf Done."} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output has no %q:\n%s", want, out.String())
+		}
+	}
+
+	out.Reset()
+	if err := run(ctx, &out, []string{"-c", "127.0.0.1", "-p", port, "-n", "1M", "-R", "-J"}); err != nil {
+		t.Fatal(err)
+	}
+	var res iperf3.Result
+	if err := json.Unmarshal(out.Bytes(), &res); err != nil {
+		t.Fatalf("%v:\n%s", err, out.String())
+	}
+	if res.Start.TestStart.Reverse != 1 || res.End.SumSent.Bytes < 1<<20 {
+		t.Errorf("result = %+v, want a reverse test of 1M", res)
+	}
+}
+
+func TestUsage(t *testing.T) {
+	for _, args := range [][]string{
+		{},
+		{"-s", "-c", "localhost"},
+		{"-c", "localhost", "-4", "-6"},
+		{"-c", "localhost", "-t", "0"},
+		{"-s", "-p", "70000"},
+		{"-s", "extra"},
+	} {
+		if err := run(context.Background(), &bytes.Buffer{}, args); !errors.Is(err, errUsage) {
+			t.Errorf("run(%q) = %v, want %v", args, err, errUsage)
+		}
+	}
+}
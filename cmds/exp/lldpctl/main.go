@@ -0,0 +1,212 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// lldpctl shows the LLDP neighbors of the links, and advertises the system
+// to them.
+//
+// Synopsis:
+//
+//	lldpctl [-json] [-state FILE]
+//	lldpctl -listen DURATION [-i REGEX] [-json]
+//	lldpctl -d [-i REGEX] [-tx] [-interval DURATION] [-name NAME] [-description TEXT] [-state FILE]
+//
+// Description:
+//
+//	With -d, lldpctl runs as a daemon that receives LLDP on the links
+//	matching REGEX, and keeps the neighbors in the state file. With -tx,
+//	it also advertises the system on them, until it is stopped.
+//
+//	Otherwise, lldpctl prints the neighbors in the state file of the
+//	daemon, or, with -listen, those it receives itself for DURATION.
+//	Switches usually advertise every 30 seconds.
+//
+// Options:
+//
+//	-d:           run as a daemon
+//	-description: system description to advertise (default: the kernel)
+//	-i:           regular expression of the links to use (default: ^e.*)
+//	-interval:    how often to advertise (default: 30s)
+//	-json:        print the neighbors as JSON
+//	-listen:      listen for neighbors for this long instead of reading the state file
+//	-name:        system name to advertise (default: the hostname)
+//	-state:       state file (default: /run/lldp/neighbors.json)
+//	-tx:          advertise the system
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/u-root/u-root/pkg/dhclient"
+	"github.com/u-root/u-root/pkg/lldp"
+	"golang.org/x/sys/unix"
+)
+
+var errUsage = errors.New("usage: lldpctl [-json] [-state FILE] | lldpctl -listen DURATION [-i REGEX] [-json] | lldpctl -d [-i REGEX] [-tx] [-interval DURATION] [-name NAME] [-description TEXT] [-state FILE]")
+
+func run(ctx context.Context, stdout io.Writer, args []string) error {
+	f := flag.NewFlagSet("lldpctl", flag.ContinueOnError)
+	daemon := f.Bool("d", false, "run as a daemon")
+	description := f.String("description", "", "system description to advertise (default: the kernel)")
+	ifName := f.String("i", "^e.*", "regular expression of the links to use")
+	interval := f.Duration("interval", lldp.DefaultInterval, "how often to advertise")
+	asJSON := f.Bool("json", false, "print the neighbors as JSON")
+	listen := f.Duration("listen", 0, "listen for neighbors for this long instead of reading the state file")
+	name := f.String("name", "", "system name to advertise (default: the hostname)")
+	state := f.String("state", lldp.DefaultStateFile, "state file")
+	tx := f.Bool("tx", false, "advertise the system")
+	if err := f.Parse(args); err != nil || f.NArg() != 0 {
+		return errUsage
+	}
+	if *daemon && *listen != 0 || !*daemon && *tx || *listen < 0 || *interval <= 0 {
+		return errUsage
+	}
+
+	var ns []lldp.Neighbor
+	switch {
+	case *daemon || *listen > 0:
+		links, err := links(*ifName)
+		if err != nil {
+			return err
+		}
+		a := lldp.NewAgent()
+		a.Interval = *interval
+		if *daemon {
+			a.StateFile = *state
+		}
+		if *tx {
+			a.Advertise = &lldp.Info{
+				SystemName:          *name,
+				SystemDescription:   *description,
+				Capabilities:        lldp.CapStation,
+				EnabledCapabilities: lldp.CapStation,
+			}
+			if a.Advertise.SystemName == "" {
+				a.Advertise.SystemName, _ = os.Hostname()
+			}
+			if a.Advertise.SystemDescription == "" {
+				a.Advertise.SystemDescription = kernel()
+			}
+		}
+		if *daemon {
+			return a.Run(ctx, links)
+		}
+		ctx, cancel := context.WithTimeout(ctx, *listen)
+		defer cancel()
+		if err := a.Run(ctx, links); !errors.Is(err, context.DeadlineExceeded) {
+			return err
+		}
+		ns = a.Neighbors()
+	default:
+		var err error
+		if ns, err = lldp.ReadStateFile(*state); err != nil {
+			return err
+		}
+	}
+
+	if *asJSON {
+		e := json.NewEncoder(stdout)
+		e.SetIndent("", "\t")
+		return e.Encode(ns)
+	}
+	printNeighbors(stdout, ns)
+	return nil
+}
+
+// links returns the Ethernet links matching the regular expression ifName.
+func links(ifName string) ([]*net.Interface, error) {
+	ls, err := dhclient.Interfaces(ifName)
+	if err != nil {
+		return nil, err
+	}
+	var ifis []*net.Interface
+	for _, l := range ls {
+		ifi, err := net.InterfaceByIndex(l.Attrs().Index)
+		if err != nil {
+			return nil, err
+		}
+		if len(ifi.HardwareAddr) == 6 {
+			ifis = append(ifis, ifi)
+		}
+	}
+	if len(ifis) == 0 {
+		return nil, fmt.Errorf("no Ethernet links match %s", ifName)
+	}
+	return ifis, nil
+}
+
+// kernel returns the kernel as uname -srvm shows it.
+func kernel() string {
+	var u unix.Utsname
+	if err := unix.Uname(&u); err != nil {
+		return ""
+	}
+	return strings.Join([]string{
+		unix.ByteSliceToString(u.Sysname[:]),
+		unix.ByteSliceToString(u.Release[:]),
+		unix.ByteSliceToString(u.Version[:]),
+		unix.ByteSliceToString(u.Machine[:]),
+	}, " ")
+}
+
+// printNeighbors prints ns as lldpctl shows neighbors.
+func printNeighbors(w io.Writer, ns []lldp.Neighbor) {
+	field := func(name string, v any) {
+		if s := fmt.Sprint(v); s != "" {
+			fmt.Fprintf(w, "  %-16s%s\n", name+":", s)
+		}
+	}
+	for i, n := range ns {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "Interface: %s, Time: %s, TTL: %ds\n", n.Interface, n.LastSeen.Format(time.RFC3339), n.TTL)
+		field("ChassisID", n.ChassisID)
+		field("SysName", n.SystemName)
+		field("SysDescr", n.SystemDescription)
+		for _, a := range n.ManagementAddresses {
+			field("MgmtIP", a)
+		}
+		if n.Capabilities != 0 {
+			field("Capabilities", fmt.Sprintf("%s (enabled: %s)", n.Capabilities, n.EnabledCapabilities))
+		}
+		field("PortID", n.PortID)
+		field("PortDescr", n.PortDescription)
+		if n.PortVLAN != 0 {
+			field("PVID", n.PortVLAN)
+		}
+		for _, v := range n.VLANs {
+			field("VLAN", fmt.Sprintf("%d %s", v.ID, v.Name))
+		}
+		if la := n.LinkAggregation; la != nil {
+			field("LAG", fmt.Sprintf("capable: %t, enabled: %t, port: %d", la.Capable, la.Enabled, la.PortID))
+		}
+		if m := n.MACPHY; m != nil {
+			field("Autoneg", fmt.Sprintf("supported: %t, enabled: %t", m.AutonegSupported, m.AutonegEnabled))
+			field("MAU", m.MAU())
+		}
+		if n.MaxFrameSize != 0 {
+			field("MFS", n.MaxFrameSize)
+		}
+	}
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := run(ctx, os.Stdout, os.Args[1:]); err != nil && !errors.Is(err, context.Canceled) {
+		log.Fatalf("lldpctl: %v", err)
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/u-root/u-root/pkg/lldp"
+)
+
+func TestShow(t *testing.T) {
+	seen := time.Now().UTC().Format(time.RFC3339)
+	state := filepath.Join(t.TempDir(), "neighbors.json")
+	if err := os.WriteFile(state, fmt.Appendf(nil, `[{
+		"interface": "eth0", "last_seen": %q,
+		"chassis_id": {"subtype": "mac", "value": "00:25:90:17:a1:c0"},
+		"port_id": {"subtype": "ifname", "value": "Gi1/0/24"},
+		"ttl": 120, "system_name": "sw1",
+		"capabilities": "bridge,router", "enabled_capabilities": "bridge",
+		"management_addresses": ["10.0.0.2"],
+		"port_vlan": 10, "vlans": [{"id": 10, "name": "servers"}],
+		"link_aggregation": {"capable": true, "enabled": true, "port_id": 5},
+		"mac_phy": {"autoneg_supported": true, "autoneg_enabled": true, "advertised": 27649, "mau_type": 30}
+	}, {
+		"interface": "eth1", "last_seen": "2000-01-01T00:00:00Z",
+		"chassis_id": {"subtype": "local", "value": "gone"},
+		"port_id": {"subtype": "local", "value": "1"},
+		"ttl": 120
+	}]`, seen), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := run(context.Background(), &out, []string{"-state", state}); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"Interface: eth0, Time: " + seen + ", TTL: 120s",
+		"ChassisID:      mac 00:25:90:17:a1:c0",
+		"SysName:        sw1",
+		"MgmtIP:         10.0.0.2",
+		"Capabilities:   bridge,router (enabled: bridge)",
+		"PortID:         ifname Gi1/0/24",
+		"PVID:           10",
+		"VLAN:           10 servers",
+		"LAG:            capable: true, enabled: true, port: 5",
+		"MAU:            1000BaseTFD",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output has no %q:\n%s", want, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "gone") {
+		t.Errorf("output has an expired neighbor:\n%s", out.String())
+	}
+
+	out.Reset()
+	if err := run(context.Background(), &out, []string{"-json", "-state", state}); err != nil {
+		t.Fatal(err)
+	}
+	var ns []lldp.Neighbor
+	if err := json.Unmarshal(out.Bytes(), &ns); err != nil {
+		t.Fatal(err)
+	}
+	if len(ns) != 1 || ns[0].SystemName != "sw1" || ns[0].LinkAggregation.PortID != 5 {
+		t.Errorf("JSON output = %+v", ns)
+	}
+}
+
+func TestUsage(t *testing.T) {
+	for _, args := range [][]string{{"a"}, {"-tx"}, {"-d", "-listen", "1s"}, {"-listen", "-1s"}, {"-d", "-interval", "0s"}, {"-x"}} {
+		if err := run(context.Background(), &bytes.Buffer{}, args); !errors.Is(err, errUsage) {
+			t.Errorf("run(%q) = %v, want %v", args, err, errUsage)
+		}
+	}
+	if err := run(context.Background(), &bytes.Buffer{}, []string{"-state", filepath.Join(t.TempDir(), "missing.json")}); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("run with a missing state file = %v, want %v", err, os.ErrNotExist)
+	}
+}
@@ -0,0 +1,201 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// mkfs.ext4 creates an ext4 file system.
+//
+// Synopsis:
+//
+//	mkfs.ext4 [-b SIZE] [-L LABEL] [-U UUID] [-N INODES] [-m PERCENT] [-O [^]has_journal] [-J size=MiB] DEVICE [FS-SIZE]
+//
+// Description:
+//
+//	mkfs.ext4 writes an empty ext4 file system to DEVICE, which is a
+//	block device or an image file. The file system fills DEVICE, or its
+//	first FS-SIZE. FS-SIZE is a number of blocks with -b and of KiB
+//	without it, unless it has a K, M, G or T suffix. Image files are
+//	created or extended to FS-SIZE.
+//
+//	The file system has a journal unless -O ^has_journal is given. It
+//	uses extents, but neither flexible block groups nor metadata
+//	checksums, and cannot be resized online.
+//
+// Options:
+//
+//	-b: block size, 1024, 2048 or 4096
+//	-J: journal options; only size=MiB is supported
+//	-L: volume label of up to 16 bytes
+//	-m: percentage of blocks reserved for root (default 5)
+//	-N: number of inodes
+//	-O: features; only has_journal and ^has_journal are supported
+//	-U: file system UUID (default random)
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/u-root/u-root/pkg/fs/ext4"
+)
+
+var errUsage = errors.New("usage: mkfs.ext4 [-b SIZE] [-L LABEL] [-U UUID] [-N INODES] [-m PERCENT] [-O [^]has_journal] [-J size=MiB] DEVICE [FS-SIZE]")
+
+// mountsPath is replaced in tests.
+var mountsPath = "/proc/mounts"
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		log.Fatalf("mkfs.ext4: %v", err)
+	}
+}
+
+// parseSize parses FS-SIZE into bytes.
+func parseSize(s string, blockSize int64) (int64, error) {
+	unit := int64(1024)
+	if blockSize != 0 {
+		unit = blockSize
+	}
+	if i := strings.IndexAny(s, "kKmMgGtT"); i >= 0 && i == len(s)-1 {
+		unit = 1 << (10 * (strings.IndexByte("kmgt", strings.ToLower(s[i:])[0]) + 1))
+		s = s[:i]
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n <= 0 || n > (1<<63-1)/unit {
+		return 0, fmt.Errorf("invalid file system size %q", s)
+	}
+	return n * unit, nil
+}
+
+func run(args []string) error {
+	fl := flag.NewFlagSet("mkfs.ext4", flag.ContinueOnError)
+	var (
+		blockSize = fl.Int("b", 0, "block size")
+		label     = fl.String("L", "", "volume label")
+		id        = fl.String("U", "", "file system UUID")
+		inodes    = fl.Uint("N", 0, "number of inodes")
+		reserved  = fl.Int("m", 5, "percentage of blocks reserved for root")
+		features  = fl.String("O", "", "features")
+		journal   = fl.String("J", "", "journal options")
+	)
+	if err := fl.Parse(args); err != nil {
+		return err
+	}
+	if fl.NArg() < 1 || fl.NArg() > 2 {
+		return errUsage
+	}
+	o := ext4.FormatOptions{
+		Label:           *label,
+		BlockSize:       *blockSize,
+		Inodes:          uint32(*inodes),
+		ReservedPercent: *reserved,
+		Journal:         true,
+	}
+	if *inodes > 1<<32-1 {
+		return fmt.Errorf("%d inodes are too many", *inodes)
+	}
+	if *id != "" {
+		u, err := uuid.Parse(*id)
+		if err != nil {
+			return fmt.Errorf("invalid UUID %q: %w", *id, err)
+		}
+		o.UUID = u
+	}
+	for _, f := range strings.Split(*features, ",") {
+		switch f {
+		case "":
+		case "has_journal":
+			o.Journal = true
+		case "^has_journal":
+			o.Journal = false
+		default:
+			return fmt.Errorf("unsupported feature %q", f)
+		}
+	}
+	for _, j := range strings.Split(*journal, ",") {
+		switch v, ok := strings.CutPrefix(j, "size="); {
+		case j == "":
+		case ok:
+			mib, err := strconv.ParseInt(v, 10, 32)
+			if err != nil || mib <= 0 {
+				return fmt.Errorf("invalid journal size %q", v)
+			}
+			o.JournalSize = mib << 20
+		default:
+			return fmt.Errorf("unsupported journal option %q", j)
+		}
+	}
+
+	path := fl.Arg(0)
+	var size int64
+	if fl.NArg() == 2 {
+		var err error
+		if size, err = parseSize(fl.Arg(1), int64(*blockSize)); err != nil {
+			return err
+		}
+	}
+	if mp, ok := mounted(path); ok {
+		return fmt.Errorf("%s is mounted on %s", path, mp)
+	}
+	flags := os.O_RDWR
+	if size != 0 {
+		flags |= os.O_CREATE
+	}
+	f, err := os.OpenFile(path, flags, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	end, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	if size == 0 {
+		size = end
+	}
+	if size > end {
+		// Image files grow to the requested size.
+		fi, err := f.Stat()
+		if err != nil {
+			return err
+		}
+		if !fi.Mode().IsRegular() {
+			return fmt.Errorf("%s has only %d bytes", path, end)
+		}
+		if err := f.Truncate(size); err != nil {
+			return err
+		}
+	}
+
+	if err := ext4.Format(f, size, o); err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// mounted returns where path is mounted, if it is.
+func mounted(path string) (string, bool) {
+	if p, err := filepath.EvalSymlinks(path); err == nil {
+		path = p
+	}
+	b, err := os.ReadFile(mountsPath)
+	if err != nil {
+		return "", false
+	}
+	for _, l := range strings.Split(string(b), "\n") {
+		if f := strings.Fields(l); len(f) >= 2 && f[0] == path {
+			return f[1], true
+		}
+	}
+	return "", false
+}
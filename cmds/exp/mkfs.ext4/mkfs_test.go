@@ -0,0 +1,101 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/u-root/u-root/pkg/fs/ext4"
+)
+
+func TestParseSize(t *testing.T) {
+	for _, tt := range []struct {
+		s         string
+		blockSize int64
+		want      int64
+	}{
+		{"1024", 0, 1 << 20},
+		{"1024", 4096, 4 << 20},
+		{"64M", 4096, 64 << 20},
+		{"2g", 0, 2 << 30},
+		{"512k", 1024, 512 << 10},
+		{"1T", 0, 1 << 40},
+	} {
+		if got, err := parseSize(tt.s, tt.blockSize); err != nil || got != tt.want {
+			t.Errorf("parseSize(%q, %d) = %d, %v, want %d", tt.s, tt.blockSize, got, err, tt.want)
+		}
+	}
+	for _, s := range []string{"", "0", "-1", "1X", "M", "1M2", "99999999999T"} {
+		if _, err := parseSize(s, 0); err == nil {
+			t.Errorf("parseSize(%q) succeeded, want an error", s)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	mountsPath = filepath.Join(dir, "mounts")
+	if err := os.WriteFile(mountsPath, []byte("/dev/sda2 / ext4 rw 0 0\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	img := filepath.Join(dir, "data.img")
+
+	for _, tt := range []struct {
+		args    []string
+		label   string
+		typ     string
+		journal bool
+	}{
+		{
+			args:    []string{"-L", "data", "-U", "8a8a3b2e-8d2c-4b3d-9d0a-2f3c6f1e7b11", img, "32M"},
+			label:   "data",
+			journal: true,
+		},
+		{
+			args: []string{"-O", "^has_journal", "-N", "1000", "-m", "0", "-U", "8a8a3b2e-8d2c-4b3d-9d0a-2f3c6f1e7b11", img},
+		},
+		{
+			args:    []string{"-b", "4096", "-J", "size=8", "-U", "8a8a3b2e-8d2c-4b3d-9d0a-2f3c6f1e7b11", img},
+			journal: true,
+		},
+	} {
+		if err := run(tt.args); err != nil {
+			t.Fatalf("run(%q) = %v", tt.args, err)
+		}
+		f, err := os.Open(img)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fsys, err := ext4.New(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fsys.Label() != tt.label || fsys.UUID() != "8a8a3b2e-8d2c-4b3d-9d0a-2f3c6f1e7b11" || fsys.Type() != "ext4" {
+			t.Errorf("run(%q): got %s %q %s", tt.args, fsys.Type(), fsys.Label(), fsys.UUID())
+		}
+		_, err = fsys.Stat("lost+found")
+		f.Close()
+		if err != nil {
+			t.Errorf("run(%q): %v", tt.args, err)
+		}
+	}
+
+	for _, args := range [][]string{
+		{},
+		{"-U", "not-a-uuid", img},
+		{"-O", "metadata_csum", img},
+		{"-J", "device=/dev/sdb", img},
+		{"-J", "size=x", img},
+		{"-b", "512", img},
+		{filepath.Join(dir, "missing.img")},
+		{"/dev/sda2"},
+	} {
+		if err := run(args); err == nil {
+			t.Errorf("run(%q) succeeded, want an error", args)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !race
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hugelgupf/vmtest/govmtest"
+	"github.com/hugelgupf/vmtest/guest"
+	"github.com/hugelgupf/vmtest/qemu"
+	"github.com/u-root/u-root/pkg/fs/ext4"
+	"github.com/u-root/u-root/pkg/mount/loop"
+)
+
+func TestIntegration(t *testing.T) {
+	qemu.SkipIfNotArch(t, qemu.ArchAMD64)
+
+	govmtest.Run(t, "vm",
+		govmtest.WithPackageToTest("github.com/u-root/u-root/cmds/exp/mkfs.ext4"),
+		govmtest.WithQEMUFn(qemu.WithVMTimeout(time.Minute)),
+	)
+}
+
+// TestKernelMount checks that the kernel mounts what mkfs.ext4 creates,
+// with and without a journal, and that files the kernel writes can be
+// read back.
+func TestKernelMount(t *testing.T) {
+	guest.SkipIfNotInVM(t)
+
+	for _, args := range [][]string{
+		{"-L", "data"},
+		{"-O", "^has_journal", "-b", "4096"},
+	} {
+		dir := t.TempDir()
+		img := filepath.Join(dir, "data.img")
+		if err := run(append(args, img, "64M")); err != nil {
+			t.Fatal(err)
+		}
+		l, err := loop.New(img, "ext4", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer l.Free() //nolint:errcheck
+
+		mnt := filepath.Join(dir, "mnt")
+		if err := os.Mkdir(mnt, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		mp, err := l.Mount(mnt, 0)
+		if err != nil {
+			t.Fatalf("mounting a file system made with %q: %v", args, err)
+		}
+		if err := os.MkdirAll(filepath.Join(mnt, "etc"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(mnt, "etc/hostname"), []byte("u-root\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := mp.Unmount(0); err != nil {
+			t.Fatal(err)
+		}
+
+		f, err := os.Open(img)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		fsys, err := ext4.New(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b, err := fsys.ReadFile("etc/hostname"); err != nil || string(b) != "u-root\n" {
+			t.Errorf("reading the file the kernel wrote: %q, %v", b, err)
+		}
+	}
+}
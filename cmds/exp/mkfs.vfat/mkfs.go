@@ -0,0 +1,153 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// mkfs.vfat creates a FAT32 file system.
+//
+// Synopsis:
+//
+//	mkfs.vfat [-F 32] [-n LABEL] [-s SECTORS] [-S SIZE] [-i VOLID] [-C] DEVICE [KBLOCKS]
+//
+// Description:
+//
+//	mkfs.vfat writes an empty FAT32 file system, such as an EFI system
+//	partition, to DEVICE, which is a block device or an image file.
+//	The file system fills DEVICE, or its first KBLOCKS KiB.
+//
+//	The cluster size defaults to what Windows uses for a file system of
+//	the same size. FAT32 needs at least 65525 clusters, so a file system
+//	with 512 byte clusters must be at least 33MiB.
+//
+// Options:
+//
+//	-C: create the image file DEVICE of KBLOCKS KiB
+//	-F: FAT size; only 32 is supported
+//	-i: volume ID as 8 hex digits (default random)
+//	-n: volume label of up to 11 characters
+//	-s: sectors per cluster
+//	-S: logical sector size (default that of the device, or 512)
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/u-root/u-root/pkg/fs/fat"
+	"golang.org/x/sys/unix"
+)
+
+var errUsage = errors.New("usage: mkfs.vfat [-F 32] [-n LABEL] [-s SECTORS] [-S SIZE] [-i VOLID] [-C] DEVICE [KBLOCKS]")
+
+// mountsPath is replaced in tests.
+var mountsPath = "/proc/mounts"
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		log.Fatalf("mkfs.vfat: %v", err)
+	}
+}
+
+func run(args []string) error {
+	fl := flag.NewFlagSet("mkfs.vfat", flag.ContinueOnError)
+	var (
+		fatSize    = fl.Int("F", 32, "FAT size; only 32 is supported")
+		label      = fl.String("n", "", "volume label")
+		spc        = fl.Int("s", 0, "sectors per cluster")
+		sectorSize = fl.Int("S", 0, "logical sector size")
+		volID      = fl.String("i", "", "volume ID as 8 hex digits")
+		create     = fl.Bool("C", false, "create the image file")
+	)
+	if err := fl.Parse(args); err != nil {
+		return err
+	}
+	if fl.NArg() < 1 || fl.NArg() > 2 || (*create && fl.NArg() != 2) {
+		return errUsage
+	}
+	if *fatSize != 32 {
+		return fmt.Errorf("FAT%d is not supported, only FAT32", *fatSize)
+	}
+	o := fat.FormatOptions{Label: *label, SectorSize: *sectorSize}
+	if *volID != "" {
+		id, err := strconv.ParseUint(*volID, 16, 32)
+		if err != nil {
+			return fmt.Errorf("invalid volume ID %q: %w", *volID, err)
+		}
+		o.VolumeID = uint32(id)
+	}
+
+	path := fl.Arg(0)
+	var size int64
+	if fl.NArg() == 2 {
+		k, err := strconv.ParseInt(fl.Arg(1), 10, 64)
+		if err != nil || k <= 0 {
+			return fmt.Errorf("invalid block count %q", fl.Arg(1))
+		}
+		size = k << 10
+	}
+	flags := os.O_RDWR
+	if *create {
+		flags |= os.O_CREATE | os.O_EXCL
+	} else if mp, ok := mounted(path); ok {
+		return fmt.Errorf("%s is mounted on %s", path, mp)
+	}
+	f, err := os.OpenFile(path, flags, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	end, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	switch {
+	case *create:
+		if err := f.Truncate(size); err != nil {
+			return err
+		}
+	case size == 0:
+		size = end
+	case size > end:
+		return fmt.Errorf("%s has only %d bytes", path, end)
+	}
+	if o.SectorSize == 0 {
+		if ss, err := unix.IoctlGetInt(int(f.Fd()), unix.BLKSSZGET); err == nil {
+			o.SectorSize = ss
+		} else {
+			o.SectorSize = 512
+		}
+	}
+	o.ClusterSize = *spc * o.SectorSize
+
+	if err := fat.Format(f, size, o); err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// mounted returns where path is mounted, if it is.
+func mounted(path string) (string, bool) {
+	if p, err := filepath.EvalSymlinks(path); err == nil {
+		path = p
+	}
+	b, err := os.ReadFile(mountsPath)
+	if err != nil {
+		return "", false
+	}
+	for _, l := range strings.Split(string(b), "\n") {
+		if f := strings.Fields(l); len(f) >= 2 && f[0] == path {
+			return f[1], true
+		}
+	}
+	return "", false
+}
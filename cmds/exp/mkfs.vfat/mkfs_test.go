@@ -0,0 +1,62 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/u-root/u-root/pkg/fs/fat"
+)
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	mountsPath = filepath.Join(dir, "mounts")
+	if err := os.WriteFile(mountsPath, []byte("/dev/sda1 /boot vfat rw 0 0\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	img := filepath.Join(dir, "esp.img")
+
+	if err := run([]string{"-C", "-n", "esp", "-i", "c0ffee01", "-s", "1", img, "65536"}); err != nil {
+		t.Fatalf("run = %v", err)
+	}
+	f, err := os.Open(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if fi, err := f.Stat(); err != nil || fi.Size() != 64<<20 {
+		t.Fatalf("image size = %v, %v, want 64MiB", fi, err)
+	}
+	fsys, err := fat.New(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fsys.Type() != fat.FAT32 || fsys.Label() != "ESP" || fsys.UUID() != "C0FF-EE01" {
+		t.Errorf("got %v %q %s, want FAT32 \"ESP\" C0FF-EE01", fsys.Type(), fsys.Label(), fsys.UUID())
+	}
+
+	// Formatting the existing image again fills it.
+	if err := run([]string{img}); err != nil {
+		t.Errorf("run(%s) = %v", img, err)
+	}
+
+	for _, args := range [][]string{
+		{},
+		{"-C", img, "1024"},
+		{"-C", filepath.Join(dir, "new.img")},
+		{"-F", "16", img},
+		{"-i", "xyz", img},
+		{"-s", "3", img},
+		{img, "1000000"},
+		{filepath.Join(dir, "missing.img")},
+		{"/dev/sda1"},
+	} {
+		if err := run(args); err == nil {
+			t.Errorf("run(%q) succeeded, want an error", args)
+		}
+	}
+}
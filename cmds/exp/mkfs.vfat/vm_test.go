@@ -0,0 +1,77 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !race
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hugelgupf/vmtest/govmtest"
+	"github.com/hugelgupf/vmtest/guest"
+	"github.com/hugelgupf/vmtest/qemu"
+	"github.com/u-root/u-root/pkg/fs/fat"
+	"github.com/u-root/u-root/pkg/mount/loop"
+)
+
+func TestIntegration(t *testing.T) {
+	qemu.SkipIfNotArch(t, qemu.ArchAMD64)
+
+	govmtest.Run(t, "vm",
+		govmtest.WithPackageToTest("github.com/u-root/u-root/cmds/exp/mkfs.vfat"),
+		govmtest.WithQEMUFn(qemu.WithVMTimeout(time.Minute)),
+	)
+}
+
+// TestKernelMount checks that the kernel mounts what mkfs.vfat creates,
+// and that files the kernel writes can be read back.
+func TestKernelMount(t *testing.T) {
+	guest.SkipIfNotInVM(t)
+
+	dir := t.TempDir()
+	img := filepath.Join(dir, "esp.img")
+	if err := run([]string{"-C", "-n", "ESP", img, "65536"}); err != nil {
+		t.Fatal(err)
+	}
+	l, err := loop.New(img, "vfat", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Free() //nolint:errcheck
+
+	mnt := filepath.Join(dir, "mnt")
+	if err := os.Mkdir(mnt, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	mp, err := l.Mount(mnt, 0)
+	if err != nil {
+		t.Fatalf("mounting a new file system: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(mnt, "EFI/BOOT"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(mnt, "EFI/BOOT/BOOTX64.EFI"), []byte("not really"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := mp.Unmount(0); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	fsys, err := fat.New(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, err := fsys.ReadFile("EFI/BOOT/BOOTX64.EFI"); err != nil || string(b) != "not really" {
+		t.Errorf("reading the file the kernel wrote: %q, %v", b, err)
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// ntpd keeps the system clock in time with NTP servers.
+//
+// Synopsis:
+//
+//	ntpd [-config FILE] [-nts] [-nocerttimecheck] [-minsources N] [-step DURATION] [-rtc DURATION] [-minpoll DURATION] [-maxpoll DURATION] [server ...]
+//	ntpd -q [-config FILE] [-nts] [server ...]
+//
+// Description:
+//
+//	ntpd polls the servers on the command line and in the config file,
+//	selects those that agree on the time, and slews the clock to it. The
+//	clock is stepped the first time, and whenever it is off by more than
+//	the step threshold. The RTC is set from the clock periodically.
+//
+//	Servers are host or host:port. With -nts, those on the command line
+//	are authenticated with Network Time Security, and their port is that
+//	of the key establishment. Config files have lines "server HOST
+//	[nts] [iburst]".
+//
+//	With -q, ntpd queries each server once, prints its offset, and exits
+//	without setting the clock.
+//
+// Options:
+//
+//	-config:          NTP config file (default: /etc/ntp.conf)
+//	-maxpoll:         longest interval between polls (default: 1024s)
+//	-minpoll:         shortest interval between polls (default: 64s)
+//	-minsources:      servers that must agree before the clock is set (default: 1)
+//	-nocerttimecheck: ignore the validity period of NTS certificates until the clock is set
+//	-nts:             use NTS with the servers on the command line
+//	-q:               query the servers and print their offsets
+//	-rtc:             interval between settings of the RTC, 0 to never set it (default: 11m)
+//	-step:            offset from which the clock is stepped (default: 128ms)
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/u-root/u-root/pkg/ntp"
+)
+
+const (
+	defaultConfig = "/etc/ntp.conf"
+	fallback      = "time.cloudflare.com"
+)
+
+var errUsage = errors.New("usage: ntpd [-config FILE] [-nts] [-nocerttimecheck] [-minsources N] [-step DURATION] [-rtc DURATION] [-minpoll DURATION] [-maxpoll DURATION] [server ...] | ntpd -q [-config FILE] [-nts] [server ...]")
+
+func run(ctx context.Context, stdout io.Writer, args []string) error {
+	f := flag.NewFlagSet("ntpd", flag.ContinueOnError)
+	config := f.String("config", defaultConfig, "NTP config file")
+	maxPoll := f.Duration("maxpoll", ntp.DefaultMaxPoll, "longest interval between polls")
+	minPoll := f.Duration("minpoll", ntp.DefaultMinPoll, "shortest interval between polls")
+	minSources := f.Int("minsources", 1, "servers that must agree before the clock is set")
+	noCertTime := f.Bool("nocerttimecheck", false, "ignore the validity period of NTS certificates until the clock is set")
+	nts := f.Bool("nts", false, "use NTS with the servers on the command line")
+	queryOnly := f.Bool("q", false, "query the servers and print their offsets")
+	rtcInterval := f.Duration("rtc", ntp.DefaultRTCInterval, "interval between settings of the RTC, 0 to never set it")
+	step := f.Duration("step", ntp.DefaultStepThreshold, "offset from which the clock is stepped")
+	if err := f.Parse(args); err != nil {
+		return errUsage
+	}
+	if *minPoll <= 0 || *maxPoll < *minPoll || *minSources < 1 || *rtcInterval < 0 || *step < 0 {
+		return errUsage
+	}
+
+	var servers []ntp.Server
+	for _, h := range f.Args() {
+		servers = append(servers, ntp.Server{Host: h, NTS: *nts, IBurst: true})
+	}
+	if *config != "" {
+		c, err := os.Open(*config)
+		switch {
+		case err == nil:
+			s, err := ntp.ParseConfig(c)
+			c.Close()
+			if err != nil {
+				return fmt.Errorf("%s: %w", *config, err)
+			}
+			servers = append(servers, s...)
+		case errors.Is(err, os.ErrNotExist) && *config == defaultConfig:
+		default:
+			return err
+		}
+	}
+	if len(servers) == 0 {
+		servers = []ntp.Server{{Host: fallback, NTS: *nts, IBurst: true}}
+	}
+
+	if *queryOnly {
+		return query(ctx, stdout, servers)
+	}
+	d := ntp.NewDaemon(servers...)
+	d.MinPoll = *minPoll
+	d.MaxPoll = *maxPoll
+	d.MinSources = *minSources
+	d.StepThreshold = *step
+	d.RTCInterval = *rtcInterval
+	d.IgnoreCertTime = *noCertTime
+	return d.Run(ctx)
+}
+
+// query prints the offsets of the servers, as ntpdate -q does.
+func query(ctx context.Context, stdout io.Writer, servers []ntp.Server) error {
+	var failed int
+	for _, s := range servers {
+		qctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		sample, err := querySample(qctx, s)
+		cancel()
+		if err != nil {
+			log.Printf("%s: %v", s.Host, err)
+			failed++
+			continue
+		}
+		fmt.Fprintf(stdout, "server %s, stratum %d, offset %+.6f, delay %.5f\n", sample.Server, sample.Stratum, sample.Offset.Seconds(), sample.Delay.Seconds())
+	}
+	if failed == len(servers) {
+		return errors.New("no server answered")
+	}
+	return nil
+}
+
+func querySample(ctx context.Context, s ntp.Server) (*ntp.Sample, error) {
+	if !s.NTS {
+		return ntp.Query(ctx, s.Host)
+	}
+	n, err := ntp.KeyExchange(ctx, s.Host, nil)
+	if err != nil {
+		return nil, err
+	}
+	return n.Query(ctx)
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := run(ctx, os.Stdout, os.Args[1:]); err != nil && !errors.Is(err, context.Canceled) {
+		log.Fatalf("ntpd: %v", err)
+	}
+}
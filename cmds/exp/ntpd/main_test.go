@@ -0,0 +1,85 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// server answers NTP queries with the time of the local clock.
+func server(t *testing.T) string {
+	t.Helper()
+	c, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	go func() {
+		b := make([]byte, 512)
+		for {
+			n, from, err := c.ReadFrom(b)
+			if err != nil {
+				return
+			}
+			if n < 48 {
+				continue
+			}
+			now := time.Now()
+			ts := uint64(now.Unix()+2208988800)<<32 | uint64(now.Nanosecond())<<32/1e9
+			resp := make([]byte, 48)
+			resp[0] = 4<<3 | 4
+			resp[1] = 1
+			copy(resp[12:], "GPS")
+			copy(resp[24:], b[40:48])
+			binary.BigEndian.PutUint64(resp[32:], ts)
+			binary.BigEndian.PutUint64(resp[40:], ts)
+			c.WriteTo(resp, from)
+		}
+	}()
+	return c.LocalAddr().String()
+}
+
+func TestQuery(t *testing.T) {
+	s1, s2 := server(t), server(t)
+	config := filepath.Join(t.TempDir(), "ntp.conf")
+	if err := os.WriteFile(config, []byte("server "+s2+" iburst\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err := run(context.Background(), &out, []string{"-q", "-config", config, s1}); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "server "+s1+", stratum 1, offset ") || !strings.HasPrefix(lines[1], "server "+s2+",") {
+		t.Errorf("output = %q", out.String())
+	}
+
+	if err := run(context.Background(), &out, []string{"-q", "-config", filepath.Join(t.TempDir(), "none"), s1}); err == nil {
+		t.Error("a missing config file is not an error")
+	}
+}
+
+func TestUsage(t *testing.T) {
+	for _, args := range [][]string{
+		{"-minpoll", "0"},
+		{"-minpoll", "1h", "-maxpoll", "1m"},
+		{"-minsources", "0"},
+		{"-step", "-1s"},
+		{"-bogus"},
+	} {
+		if err := run(context.Background(), &bytes.Buffer{}, args); !errors.Is(err, errUsage) {
+			t.Errorf("run(%q) = %v, want %v", args, err, errUsage)
+		}
+	}
+}
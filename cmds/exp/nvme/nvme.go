@@ -0,0 +1,426 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// nvme queries and administers NVMe drives.
+//
+// Synopsis:
+//
+//	nvme list [-o json]
+//	nvme id-ctrl [-o json] DEVICE
+//	nvme smart-log [-o json] DEVICE
+//	nvme format [-n NSID] [-l LBAF] [-s SES] -force DEVICE
+//	nvme sanitize -a crypto|block|overwrite|exit [-passes N] [-pattern P] [-invert] [-wait] -force DEVICE
+//
+// Description:
+//
+//	list shows every NVMe namespace in the system.
+//	id-ctrl prints the Identify Controller data structure.
+//	smart-log prints the SMART / Health Information log and exits
+//	non-zero if the controller reports a critical warning.
+//	format issues a Format NVM command, optionally with a secure erase
+//	(-s 1 erases user data, -s 2 is a cryptographic erase).
+//	sanitize starts a sanitize operation and, with -wait, follows it
+//	until it completes.
+//
+//	format and sanitize destroy all data and refuse to run without -force.
+//
+//	DEVICE is a controller (/dev/nvme0) or namespace (/dev/nvme0n1).
+//	Commands that act on a namespace default to the namespace of DEVICE,
+//	or all namespaces for a controller.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/u-root/u-root/pkg/nvme"
+)
+
+const usage = `Usage: nvme COMMAND [options] [DEVICE]
+where COMMAND := { list | id-ctrl | smart-log | format | sanitize | help }
+`
+
+var (
+	errUsage       = errors.New("usage")
+	errNoForce     = errors.New("this destroys all data on the device; use -force to proceed")
+	errCritWarning = errors.New("controller reports a critical warning")
+
+	// open and devGlob are replaced in tests.
+	open    = nvme.Open
+	devGlob = "/dev/nvme*"
+
+	controllerRE = regexp.MustCompile(`^nvme\d+$`)
+	namespaceRE  = regexp.MustCompile(`^nvme\d+n(\d+)$`)
+)
+
+func main() {
+	if err := run(os.Stdout, os.Args[1:]); err != nil {
+		if errors.Is(err, errUsage) {
+			fmt.Fprint(os.Stderr, usage)
+			os.Exit(2)
+		}
+		log.Fatalf("nvme: %v", err)
+	}
+}
+
+func run(stdout io.Writer, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+	switch args[0] {
+	case "list":
+		return list(stdout, args[1:])
+	case "id-ctrl":
+		return idCtrl(stdout, args[1:])
+	case "smart-log":
+		return smartLog(stdout, args[1:])
+	case "format":
+		return format(stdout, args[1:])
+	case "sanitize":
+		return sanitize(stdout, args[1:])
+	case "help":
+		fmt.Fprint(stdout, usage)
+		return nil
+	}
+	return fmt.Errorf("unknown command %q: %w", args[0], errUsage)
+}
+
+// defaultNSID returns the namespace a device node refers to, or
+// nvme.NSIDAll for a controller.
+func defaultNSID(dev string) uint32 {
+	m := namespaceRE.FindStringSubmatch(filepath.Base(dev))
+	if m == nil {
+		return nvme.NSIDAll
+	}
+	n, err := strconv.ParseUint(m[1], 10, 32)
+	if err != nil {
+		return nvme.NSIDAll
+	}
+	return uint32(n)
+}
+
+// parse parses args with fs and opens the single device argument.
+func parse(fs *flag.FlagSet, args []string) (*nvme.Device, string, error) {
+	fs.SetOutput(io.Discard)
+	if err := fs.Parse(args); err != nil {
+		return nil, "", fmt.Errorf("%v: %w", err, errUsage)
+	}
+	if fs.NArg() != 1 {
+		return nil, "", fmt.Errorf("%s needs exactly one device: %w", fs.Name(), errUsage)
+	}
+	dev := fs.Arg(0)
+	d, err := open(dev)
+	if err != nil {
+		return nil, "", err
+	}
+	return d, dev, nil
+}
+
+func printJSON(w io.Writer, v any) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", b)
+	return err
+}
+
+type listEntry struct {
+	Device    string
+	Serial    string
+	Model     string
+	Namespace uint32
+	UsedBytes uint64
+	SizeBytes uint64
+	BlockSize uint64
+	Firmware  string
+}
+
+func list(stdout io.Writer, args []string) error {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	output := fs.String("o", "normal", "output format: normal or json")
+	fs.SetOutput(io.Discard)
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("%v: %w", err, errUsage)
+	}
+
+	nodes, err := filepath.Glob(devGlob)
+	if err != nil {
+		return err
+	}
+	var entries []listEntry
+	for _, n := range nodes {
+		if !controllerRE.MatchString(filepath.Base(n)) {
+			continue
+		}
+		e, err := listController(n)
+		if err != nil {
+			log.Printf("%s: %v", n, err)
+			continue
+		}
+		entries = append(entries, e...)
+	}
+
+	if *output == "json" {
+		return printJSON(stdout, entries)
+	}
+	tw := tabwriter.NewWriter(stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "Node\tSN\tModel\tNamespace\tUsage\tFormat\tFW Rev")
+	for _, e := range entries {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s / %s\t%d B\t%s\n",
+			e.Device, e.Serial, e.Model, e.Namespace, humanBytes(e.UsedBytes), humanBytes(e.SizeBytes), e.BlockSize, e.Firmware)
+	}
+	return tw.Flush()
+}
+
+func listController(path string) ([]listEntry, error) {
+	d, err := open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer d.Close()
+	c, err := d.IdentifyController()
+	if err != nil {
+		return nil, err
+	}
+	ids, err := d.ActiveNamespaces()
+	if err != nil {
+		return nil, err
+	}
+	var entries []listEntry
+	for _, id := range ids {
+		ns, err := d.IdentifyNamespace(id)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, listEntry{
+			Device:    fmt.Sprintf("%sn%d", path, id),
+			Serial:    c.Serial,
+			Model:     c.Model,
+			Namespace: id,
+			UsedBytes: ns.NUSE * ns.BlockSize(),
+			SizeBytes: ns.Size(),
+			BlockSize: ns.BlockSize(),
+			Firmware:  c.Firmware,
+		})
+	}
+	return entries, nil
+}
+
+func humanBytes(n uint64) string {
+	const unit = 1000
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.2f %cB", float64(n)/float64(div), "kMGTPE"[exp])
+}
+
+func idCtrl(stdout io.Writer, args []string) error {
+	fs := flag.NewFlagSet("id-ctrl", flag.ContinueOnError)
+	output := fs.String("o", "normal", "output format: normal or json")
+	d, _, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	c, err := d.IdentifyController()
+	if err != nil {
+		return err
+	}
+	if *output == "json" {
+		return printJSON(stdout, c)
+	}
+	tw := tabwriter.NewWriter(stdout, 0, 8, 1, ' ', 0)
+	for _, f := range []struct {
+		name  string
+		value any
+	}{
+		{"vid", fmt.Sprintf("%#x", c.VID)},
+		{"ssvid", fmt.Sprintf("%#x", c.SSVID)},
+		{"sn", c.Serial},
+		{"mn", c.Model},
+		{"fr", c.Firmware},
+		{"ieee", fmt.Sprintf("%02x%02x%02x", c.IEEE[2], c.IEEE[1], c.IEEE[0])},
+		{"cntlid", fmt.Sprintf("%#x", c.CNTLID)},
+		{"ver", c.VersionString()},
+		{"oacs", fmt.Sprintf("%#x", c.OACS)},
+		{"frmw", fmt.Sprintf("%#x", c.FRMW)},
+		{"elpe", c.ELPE},
+		{"wctemp", c.WCTEMP},
+		{"cctemp", c.CCTEMP},
+		{"tnvmcap", c.TNVMCAP},
+		{"unvmcap", c.UNVMCAP},
+		{"sanicap", fmt.Sprintf("%#x", c.SANICAP)},
+		{"nn", c.NN},
+		{"oncs", fmt.Sprintf("%#x", c.ONCS)},
+		{"fna", fmt.Sprintf("%#x", c.FNA)},
+		{"vwc", fmt.Sprintf("%#x", c.VWC)},
+		{"subnqn", c.SubNQN},
+	} {
+		fmt.Fprintf(tw, "%s\t: %v\n", f.name, f.value)
+	}
+	return tw.Flush()
+}
+
+func smartLog(stdout io.Writer, args []string) error {
+	fs := flag.NewFlagSet("smart-log", flag.ContinueOnError)
+	output := fs.String("o", "normal", "output format: normal or json")
+	d, _, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	s, err := d.SMARTLog(nvme.NSIDAll)
+	if err != nil {
+		return err
+	}
+	if *output == "json" {
+		if err := printJSON(stdout, s); err != nil {
+			return err
+		}
+	} else {
+		tw := tabwriter.NewWriter(stdout, 0, 8, 1, ' ', 0)
+		fmt.Fprintf(tw, "critical_warning\t: %#x\n", s.CriticalWarning)
+		for _, w := range s.Warnings() {
+			fmt.Fprintf(tw, "\t  %s\n", w)
+		}
+		fmt.Fprintf(tw, "temperature\t: %d C\n", s.Celsius())
+		fmt.Fprintf(tw, "available_spare\t: %d%%\n", s.AvailableSpare)
+		fmt.Fprintf(tw, "available_spare_threshold\t: %d%%\n", s.AvailableSpareThreshold)
+		fmt.Fprintf(tw, "percentage_used\t: %d%%\n", s.PercentageUsed)
+		fmt.Fprintf(tw, "data_units_read\t: %v\n", s.DataUnitsRead)
+		fmt.Fprintf(tw, "data_units_written\t: %v\n", s.DataUnitsWritten)
+		fmt.Fprintf(tw, "host_read_commands\t: %v\n", s.HostReadCommands)
+		fmt.Fprintf(tw, "host_write_commands\t: %v\n", s.HostWriteCommands)
+		fmt.Fprintf(tw, "controller_busy_time\t: %v\n", s.ControllerBusyTime)
+		fmt.Fprintf(tw, "power_cycles\t: %v\n", s.PowerCycles)
+		fmt.Fprintf(tw, "power_on_hours\t: %v\n", s.PowerOnHours)
+		fmt.Fprintf(tw, "unsafe_shutdowns\t: %v\n", s.UnsafeShutdowns)
+		fmt.Fprintf(tw, "media_errors\t: %v\n", s.MediaErrors)
+		fmt.Fprintf(tw, "num_err_log_entries\t: %v\n", s.ErrorLogEntries)
+		fmt.Fprintf(tw, "warning_temp_time\t: %d\n", s.WarningTempTime)
+		fmt.Fprintf(tw, "critical_comp_time\t: %d\n", s.CriticalTempTime)
+		for i, k := range s.TemperatureSensors {
+			if k != 0 {
+				fmt.Fprintf(tw, "temperature_sensor_%d\t: %d C\n", i+1, int(k)-273)
+			}
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+	if !s.Healthy() {
+		return errCritWarning
+	}
+	return nil
+}
+
+func format(stdout io.Writer, args []string) error {
+	fs := flag.NewFlagSet("format", flag.ContinueOnError)
+	nsid := fs.Uint("n", 0, "namespace to format (default: the device's namespace, or all)")
+	lbaf := fs.Uint("l", 0, "LBA format index")
+	ses := fs.Uint("s", 0, "secure erase setting: 0 none, 1 user data erase, 2 crypto erase")
+	timeout := fs.Duration("timeout", nvme.DefaultFormatTimeout, "command timeout")
+	force := fs.Bool("force", false, "do not refuse to destroy data")
+	d, dev, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	if !*force {
+		return errNoForce
+	}
+	if *lbaf > 0x3f || *ses > 2 {
+		return fmt.Errorf("LBA format %d or secure erase setting %d out of range", *lbaf, *ses)
+	}
+
+	n := uint32(*nsid)
+	if n == 0 {
+		n = defaultNSID(dev)
+	}
+	if err := d.Format(n, nvme.FormatOptions{
+		LBAFormat:   uint8(*lbaf),
+		SecureErase: nvme.SecureErase(*ses),
+		Timeout:     *timeout,
+	}); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "Success formatting namespace %#x\n", n)
+	return nil
+}
+
+var sanitizeActions = map[string]nvme.SanitizeAction{
+	"exit":      nvme.SanitizeExitFailure,
+	"block":     nvme.SanitizeBlockErase,
+	"overwrite": nvme.SanitizeOverwrite,
+	"crypto":    nvme.SanitizeCryptoErase,
+}
+
+func sanitize(stdout io.Writer, args []string) error {
+	fs := flag.NewFlagSet("sanitize", flag.ContinueOnError)
+	action := fs.String("a", "", "sanitize action: crypto, block, overwrite or exit")
+	passes := fs.Uint("passes", 1, "overwrite passes (1-16)")
+	pattern := fs.Uint("pattern", 0, "32-bit overwrite pattern")
+	invert := fs.Bool("invert", false, "invert the overwrite pattern between passes")
+	ause := fs.Bool("ause", false, "allow unrestricted sanitize exit")
+	wait := fs.Bool("wait", false, "wait for the sanitize to complete")
+	interval := fs.Duration("interval", 5*time.Second, "status poll interval with -wait")
+	force := fs.Bool("force", false, "do not refuse to destroy data")
+	d, _, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	a, ok := sanitizeActions[*action]
+	if !ok {
+		return fmt.Errorf("unknown sanitize action %q: %w", *action, errUsage)
+	}
+	if !*force && a != nvme.SanitizeExitFailure {
+		return errNoForce
+	}
+	if *passes < 1 || *passes > 16 || *pattern > 0xffffffff {
+		return fmt.Errorf("%d passes or pattern %#x out of range", *passes, *pattern)
+	}
+
+	if err := d.Sanitize(nvme.SanitizeOptions{
+		Action:                a,
+		AllowUnrestrictedExit: *ause,
+		OverwritePasses:       uint8(*passes % 16),
+		OverwriteInvert:       *invert,
+		Pattern:               uint32(*pattern),
+	}); err != nil {
+		return err
+	}
+	if !*wait {
+		fmt.Fprintf(stdout, "Sanitize (%v) started\n", a)
+		return nil
+	}
+	s, err := d.WaitSanitize(context.Background(), *interval, func(s *nvme.SanitizeStatus) {
+		fmt.Fprintf(stdout, "Sanitize %v: %.1f%%\n", s.State, s.Percent())
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "Sanitize (%v) %v\n", a, s.State)
+	return nil
+}
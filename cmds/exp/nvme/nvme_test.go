@@ -0,0 +1,199 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/u-root/u-root/pkg/nvme"
+)
+
+type fakeController struct {
+	cmds  []nvme.Command
+	smart []byte
+}
+
+func (f *fakeController) AdminCommand(c *nvme.Command, data []byte) (uint32, error) {
+	f.cmds = append(f.cmds, *c)
+	le := binary.LittleEndian
+	switch {
+	case c.Opcode == nvme.OpIdentify && c.CDW10 == 1:
+		copy(data[4:], "SERIAL01            ")
+		copy(data[24:], "Test NVMe                               ")
+		copy(data[64:], "FW01    ")
+		le.PutUint32(data[80:], 0x10400)
+	case c.Opcode == nvme.OpIdentify && c.CDW10 == 0:
+		le.PutUint64(data[0:], 2048)
+		le.PutUint64(data[16:], 1024)
+		data[128+2] = 9
+	case c.Opcode == nvme.OpIdentify && c.CDW10 == 2:
+		le.PutUint32(data, 1)
+	case c.Opcode == nvme.OpGetLogPage && uint8(c.CDW10) == nvme.LogSMART:
+		copy(data, f.smart)
+	case c.Opcode == nvme.OpGetLogPage && uint8(c.CDW10) == nvme.LogSanitizeStatus:
+		le.PutUint16(data[2:], uint16(nvme.SanitizeCompleted))
+	}
+	return 0, nil
+}
+
+func setup(t *testing.T) *fakeController {
+	t.Helper()
+	f := &fakeController{smart: make([]byte, 512)}
+	binary.LittleEndian.PutUint16(f.smart[1:], 300)
+	oldOpen, oldGlob := open, devGlob
+	t.Cleanup(func() { open, devGlob = oldOpen, oldGlob })
+	open = func(string) (*nvme.Device, error) {
+		return nvme.NewDevice(f), nil
+	}
+	return f
+}
+
+func TestUsage(t *testing.T) {
+	for _, args := range [][]string{
+		nil,
+		{"frobnicate"},
+		{"id-ctrl"},
+		{"smart-log", "a", "b"},
+		{"list", "-x"},
+	} {
+		if err := run(&bytes.Buffer{}, args); !errors.Is(err, errUsage) {
+			t.Errorf("run(%q) = %v, want %v", args, err, errUsage)
+		}
+	}
+	var out bytes.Buffer
+	if err := run(&out, []string{"help"}); err != nil || out.String() != usage {
+		t.Errorf("help = %q, %v", out.String(), err)
+	}
+}
+
+func TestIDCtrl(t *testing.T) {
+	setup(t)
+	var out bytes.Buffer
+	if err := run(&out, []string{"id-ctrl", "/dev/nvme0"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"SERIAL01", "Test NVMe", "FW01", "1.4.0"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("id-ctrl output does not contain %q:\n%s", want, out.String())
+		}
+	}
+
+	out.Reset()
+	if err := run(&out, []string{"id-ctrl", "-o", "json", "/dev/nvme0"}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), `"Serial": "SERIAL01"`) {
+		t.Errorf("id-ctrl -o json = %s", out.String())
+	}
+}
+
+func TestSMARTLog(t *testing.T) {
+	f := setup(t)
+	var out bytes.Buffer
+	if err := run(&out, []string{"smart-log", "/dev/nvme0"}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "27 C") {
+		t.Errorf("smart-log output does not contain temperature:\n%s", out.String())
+	}
+
+	f.smart[0] = nvme.WarnReliability
+	out.Reset()
+	if err := run(&out, []string{"smart-log", "/dev/nvme0"}); !errors.Is(err, errCritWarning) {
+		t.Errorf("smart-log with critical warning = %v, want %v", err, errCritWarning)
+	}
+	if !strings.Contains(out.String(), "reliability degraded") {
+		t.Errorf("smart-log does not describe warning:\n%s", out.String())
+	}
+}
+
+func TestFormat(t *testing.T) {
+	f := setup(t)
+	if err := run(&bytes.Buffer{}, []string{"format", "/dev/nvme0n2"}); !errors.Is(err, errNoForce) {
+		t.Fatalf("format without -force = %v, want %v", err, errNoForce)
+	}
+	if len(f.cmds) != 0 {
+		t.Fatalf("format without -force sent %d commands", len(f.cmds))
+	}
+	if err := run(&bytes.Buffer{}, []string{"format", "-s", "2", "-force", "/dev/nvme0n2"}); err != nil {
+		t.Fatal(err)
+	}
+	c := f.cmds[0]
+	if c.Opcode != nvme.OpFormatNVM || c.NSID != 2 || c.CDW10 != 2<<9 {
+		t.Errorf("format sent %+v", c)
+	}
+	if err := run(&bytes.Buffer{}, []string{"format", "-s", "3", "-force", "/dev/nvme0"}); err == nil {
+		t.Errorf("format -s 3 succeeded, want error")
+	}
+}
+
+func TestSanitize(t *testing.T) {
+	f := setup(t)
+	if err := run(&bytes.Buffer{}, []string{"sanitize", "-a", "crypto", "/dev/nvme0"}); !errors.Is(err, errNoForce) {
+		t.Fatalf("sanitize without -force = %v, want %v", err, errNoForce)
+	}
+	if err := run(&bytes.Buffer{}, []string{"sanitize", "-a", "melt", "-force", "/dev/nvme0"}); !errors.Is(err, errUsage) {
+		t.Fatalf("sanitize -a melt = %v, want %v", err, errUsage)
+	}
+	var out bytes.Buffer
+	if err := run(&out, []string{"sanitize", "-a", "overwrite", "-passes", "16", "-wait", "-force", "/dev/nvme0"}); err != nil {
+		t.Fatal(err)
+	}
+	if c := f.cmds[0]; c.Opcode != nvme.OpSanitize || c.CDW10 != uint32(nvme.SanitizeOverwrite) {
+		t.Errorf("sanitize sent %+v", c)
+	}
+	if !strings.Contains(out.String(), "completed") {
+		t.Errorf("sanitize -wait output = %q", out.String())
+	}
+}
+
+func TestList(t *testing.T) {
+	setup(t)
+	dir := t.TempDir()
+	for _, n := range []string{"nvme0", "nvme0n1", "nvme-fabrics"} {
+		if err := os.WriteFile(filepath.Join(dir, n), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	devGlob = filepath.Join(dir, "nvme*")
+
+	var out bytes.Buffer
+	if err := run(&out, []string{"list", "-o", "json"}); err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(out.String(), `"Device"`); n != 1 {
+		t.Errorf("list found %d namespaces, want 1:\n%s", n, out.String())
+	}
+	if !strings.Contains(out.String(), `"SizeBytes": 1048576`) {
+		t.Errorf("list -o json = %s", out.String())
+	}
+
+	out.Reset()
+	if err := run(&out, []string{"list"}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "524.29 kB / 1.05 MB") {
+		t.Errorf("list = %s", out.String())
+	}
+}
+
+func TestDefaultNSID(t *testing.T) {
+	for dev, want := range map[string]uint32{
+		"/dev/nvme0":    nvme.NSIDAll,
+		"/dev/nvme0n1":  1,
+		"/dev/nvme12n3": 3,
+		"nvme1n1p1":     nvme.NSIDAll,
+	} {
+		if got := defaultNSID(dev); got != want {
+			t.Errorf("defaultNSID(%q) = %#x, want %#x", dev, got, want)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//go:build !tinygo || tinygo.enable
+
+package main
+
+import (
+	"encoding/json"
+	"html/template"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+// entry is a file of a directory listing.
+type entry struct {
+	Name    string    `json:"name"`
+	Dir     bool      `json:"dir"`
+	Size    int64     `json:"size"`
+	Mode    string    `json:"mode"`
+	ModTime time.Time `json:"mtime"`
+}
+
+// URL is the link to the file, relative to the directory.
+func (e entry) URL() string {
+	u := url.URL{Path: e.Name}
+	if e.Dir {
+		u.Path += "/"
+	}
+	// A colon in the first segment would make a scheme of it.
+	return "./" + u.String()
+}
+
+var listingTemplate = template.Must(template.New("listing").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Index of {{.Path}}</title></head>
+<body>
+<h1>Index of {{.Path}}</h1>
+<table>
+<tr><th>Name</th><th>Size</th><th>Modified</th></tr>
+{{- if ne .Path "/"}}
+<tr><td><a href="../">../</a></td><td></td><td></td></tr>
+{{- end}}
+{{- range .Entries}}
+<tr><td><a href="{{.URL}}">{{.Name}}{{if .Dir}}/{{end}}</a></td><td>{{if not .Dir}}{{.Size}}{{end}}</td><td>{{.ModTime.UTC.Format "2006-01-02 15:04:05"}}</td></tr>
+{{- end}}
+</table>
+</body>
+</html>
+`))
+
+// wantsJSON is whether the client asks for a JSON listing.
+func wantsJSON(r *http.Request) bool {
+	if r.URL.Query().Get("format") == "json" {
+		return true
+	}
+	for _, a := range strings.Split(r.Header.Get("Accept"), ",") {
+		if t, _, err := mime.ParseMediaType(a); err == nil && t == "application/json" {
+			return true
+		}
+	}
+	return false
+}
+
+// listing lists the directories of fs that have no index.html, as HTML or as
+// JSON, and leaves everything else to next. JSON clients get the listing of
+// directories with an index.html, too.
+func listing(fs http.FileSystem, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// next redirects directories to a name with a trailing slash,
+		// so that relative links work.
+		if !strings.HasSuffix(r.URL.Path, "/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		name := path.Clean("/" + r.URL.Path)
+		f, err := fs.Open(name)
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		defer f.Close()
+		fi, err := f.Stat()
+		if err != nil || !fi.IsDir() {
+			next.ServeHTTP(w, r)
+			return
+		}
+		asJSON := wantsJSON(r)
+		if !asJSON {
+			if index, err := fs.Open(path.Join(name, "index.html")); err == nil {
+				index.Close()
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		fis, err := f.Readdir(-1)
+		if err != nil {
+			http.Error(w, "error reading directory", http.StatusInternalServerError)
+			return
+		}
+		entries := make([]entry, 0, len(fis))
+		for _, fi := range fis {
+			entries = append(entries, entry{
+				Name:    fi.Name(),
+				Dir:     fi.IsDir(),
+				Size:    fi.Size(),
+				Mode:    fi.Mode().String(),
+				ModTime: fi.ModTime(),
+			})
+		}
+		slices.SortFunc(entries, func(a, b entry) int { return strings.Compare(a.Name, b.Name) })
+
+		w.Header().Add("Vary", "Accept")
+		if asJSON {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(entries)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		listingTemplate.Execute(w, struct {
+			Path    string
+			Entries []entry
+		}{Path: name, Entries: entries})
+	})
+}
@@ -7,20 +7,49 @@
 //
 // Synopsis:
 //
-//	srvfiles [--h=HOST] [--p=PORT] [--d=DIR]
+//	srvfiles [OPTIONS]
+//
+// Description:
+//
+//	srvfiles serves the files of a directory over HTTP or HTTPS, with Range
+//	and conditional requests. Directories without an index.html are listed
+//	as HTML, or as JSON for clients that accept application/json or ask for
+//	?format=json. Clients may PUT files into the -upload directory, e.g. to
+//	collect the logs of nodes, once they authenticate.
 //
 // Options:
 //
-//	--h: hostname (default: 127.0.0.1)
-//	--p: port number (default: 8080)
-//	--d: directory to serve (default: .)
+//	-h:           hostname (default: 127.0.0.1)
+//	-p:           port number (default: 8080)
+//	-d:           directory to serve (default: .)
+//	-tls:         serve HTTPS, with a self-signed certificate unless -cert is given
+//	-cert:        PEM certificate file, implies -tls
+//	-key:         PEM private key file (default: the -cert file)
+//	-client-ca:   PEM CA certificates that client certificates must be signed by
+//	-upload:      directory to store the files of PUT requests in
+//	-auth-file:   file of user:password lines that PUT requests authenticate with
+//	-max-upload:  largest upload in bytes, 0 for no limit
+//	-access-log:  file to log requests to, - for stdout
+//	-no-cache:    ignore conditional requests, so clients always get the whole file
+//
+// Uploads need -auth-file, -client-ca or both. -auth-file needs -tls, so that
+// passwords are not sent in the clear.
+//
+// Example:
+//
+//	srvfiles -h 0.0.0.0 -p 443 -d /srv/boot -tls -upload /srv/logs -auth-file /etc/srvfiles.auth
 package main
 
 import (
+	"errors"
 	"flag"
+	"fmt"
+	"io"
 	"log"
 	"net"
 	"net/http"
+	"os"
+	"time"
 
 	// To build the dependencies of this package with TinyGo, we need to include
 	// the cpuid package, since tinygo does not support the asm code in the
@@ -31,9 +60,10 @@ import (
 )
 
 var (
-	host = flag.String("h", "127.0.0.1", "hostname")
-	port = flag.String("p", "8080", "port number")
-	dir  = flag.String("d", ".", "directory to serve")
+	errClientCA   = errors.New("-client-ca needs -tls")
+	errUploadAuth = errors.New("-upload needs -auth-file or -client-ca")
+	errAuthFile   = errors.New("-auth-file needs -upload")
+	errAuthTLS    = errors.New("-auth-file needs -tls")
 )
 
 var cacheHeaders = []string{
@@ -56,8 +86,200 @@ func maxAgeHandler(h http.Handler) http.Handler {
 	})
 }
 
+type cmd struct {
+	host      string
+	port      string
+	dir       string
+	tls       bool
+	cert      string
+	key       string
+	clientCA  string
+	upload    string
+	authFile  string
+	maxUpload int64
+	accessLog string
+	noCache   bool
+}
+
+func command(args []string) (*cmd, error) {
+	c := &cmd{}
+	f := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	f.StringVar(&c.host, "h", "127.0.0.1", "hostname")
+	f.StringVar(&c.port, "p", "8080", "port number")
+	f.StringVar(&c.dir, "d", ".", "directory to serve")
+	f.BoolVar(&c.tls, "tls", false, "serve HTTPS, with a self-signed certificate unless -cert is given")
+	f.StringVar(&c.cert, "cert", "", "PEM certificate file, implies -tls")
+	f.StringVar(&c.key, "key", "", "PEM private key file (default: the -cert file)")
+	f.StringVar(&c.clientCA, "client-ca", "", "PEM CA certificates that client certificates must be signed by")
+	f.StringVar(&c.upload, "upload", "", "directory to store the files of PUT requests in")
+	f.StringVar(&c.authFile, "auth-file", "", "file of user:password lines that PUT requests authenticate with")
+	f.Int64Var(&c.maxUpload, "max-upload", 0, "largest upload in bytes, 0 for no limit")
+	f.StringVar(&c.accessLog, "access-log", "", "file to log requests to, - for stdout")
+	f.BoolVar(&c.noCache, "no-cache", false, "ignore conditional requests, so clients always get the whole file")
+	if err := f.Parse(args[1:]); err != nil {
+		return nil, err
+	}
+	if f.NArg() != 0 {
+		return nil, fmt.Errorf("unexpected arguments %q", f.Args())
+	}
+
+	if c.cert != "" {
+		c.tls = true
+	}
+	if c.clientCA != "" && !c.tls {
+		return nil, errClientCA
+	}
+	if c.authFile != "" && c.upload == "" {
+		return nil, errAuthFile
+	}
+	if c.authFile != "" && !c.tls {
+		return nil, errAuthTLS
+	}
+	if c.upload != "" && c.authFile == "" && c.clientCA == "" {
+		return nil, errUploadAuth
+	}
+	return c, nil
+}
+
+// handler returns the handler of c, which logs requests to access unless it
+// is nil.
+func (c *cmd) handler(access io.Writer) (http.Handler, error) {
+	var get http.Handler = listing(http.Dir(c.dir), http.FileServer(http.Dir(c.dir)))
+	if c.noCache {
+		get = maxAgeHandler(get)
+	}
+
+	var put http.Handler
+	if c.upload != "" {
+		var users map[string]string
+		if c.authFile != "" {
+			var err error
+			if users, err = readAuthFile(c.authFile); err != nil {
+				return nil, err
+			}
+		}
+		put = &uploader{dir: c.upload, users: users, maxSize: c.maxUpload}
+	}
+
+	var h http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet || r.Method == http.MethodHead:
+			get.ServeHTTP(w, r)
+		case r.Method == http.MethodPut && put != nil:
+			put.ServeHTTP(w, r)
+		default:
+			allow := "GET, HEAD"
+			if put != nil {
+				allow += ", PUT"
+			}
+			w.Header().Set("Allow", allow)
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		}
+	})
+	if access != nil {
+		h = accessLog(access, h)
+	}
+	return h, nil
+}
+
+func (c *cmd) run() error {
+	var access io.Writer
+	switch c.accessLog {
+	case "":
+	case "-":
+		access = os.Stdout
+	default:
+		f, err := os.OpenFile(c.accessLog, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		access = f
+	}
+	h, err := c.handler(access)
+	if err != nil {
+		return err
+	}
+
+	srv := &http.Server{
+		Addr:              net.JoinHostPort(c.host, c.port),
+		Handler:           h,
+		ReadHeaderTimeout: 30 * time.Second,
+	}
+	if !c.tls {
+		return srv.ListenAndServe()
+	}
+	if srv.TLSConfig, err = c.tlsConfig(); err != nil {
+		return err
+	}
+	// The certificates are in TLSConfig already.
+	return srv.ListenAndServeTLS("", "")
+}
+
 func main() {
-	flag.Parse()
-	http.Handle("/", maxAgeHandler(http.FileServer(http.Dir(*dir))))
-	log.Fatal(http.ListenAndServe(net.JoinHostPort(*host, *port), nil))
+	c, err := command(os.Args)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Fatal(c.run())
+}
+
+// statusWriter records the status and size of a response.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	n      int64
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.n += int64(n)
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// accessLog logs the requests of h to w, in the combined log format of
+// Apache and nginx, with the duration of the request appended.
+func accessLog(w io.Writer, h http.Handler) http.Handler {
+	l := log.New(w, "", 0)
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: rw}
+		h.ServeHTTP(sw, r)
+		if sw.status == 0 {
+			sw.status = http.StatusOK
+		}
+
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+		user := "-"
+		switch u, _, ok := r.BasicAuth(); {
+		case sw.status == http.StatusUnauthorized:
+			// Users who failed to authenticate are no one.
+		case ok:
+			user = u
+		case r.TLS != nil && len(r.TLS.PeerCertificates) > 0:
+			user = r.TLS.PeerCertificates[0].Subject.CommonName
+		}
+		l.Printf("%s - %s [%s] %q %d %d %q %q %v",
+			host, user, start.Format("02/Jan/2006:15:04:05 -0700"),
+			r.Method+" "+r.RequestURI+" "+r.Proto, sw.status, sw.n,
+			r.Referer(), r.UserAgent(), time.Since(start).Round(time.Microsecond))
+	})
 }
@@ -7,12 +7,26 @@ package main
 
 import (
 	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
 	"io"
+	"math/big"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestSRVFiles(t *testing.T) {
@@ -50,3 +64,398 @@ func TestSRVFiles(t *testing.T) {
 		t.Errorf("Expected %q, got %q", content, b)
 	}
 }
+
+// serve starts the handler of the srvfiles arguments args.
+func serve(t *testing.T, access io.Writer, args ...string) *httptest.Server {
+	t.Helper()
+	c, err := command(append([]string{"srvfiles"}, args...))
+	if err != nil {
+		t.Fatalf("command(%q) = %v", args, err)
+	}
+	h, err := c.handler(access)
+	if err != nil {
+		t.Fatalf("handler() = %v", err)
+	}
+	ts := httptest.NewServer(h)
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func do(t *testing.T, c *http.Client, method, url string, body io.Reader, header map[string]string) (*http.Response, []byte) {
+	t.Helper()
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	resp, err := c.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, url, err)
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, b
+}
+
+func TestCommand(t *testing.T) {
+	for _, tt := range []struct {
+		args []string
+		err  error
+	}{
+		{args: []string{"-tls", "-client-ca", "ca.pem", "-upload", "up"}},
+		{args: []string{"-cert", "c.pem", "-client-ca", "ca.pem"}},
+		{args: []string{"-tls", "-upload", "up", "-auth-file", "auth"}},
+		{args: []string{"-upload", "up", "-auth-file", "auth"}, err: errAuthTLS},
+		{args: []string{"-client-ca", "ca.pem"}, err: errClientCA},
+		{args: []string{"-upload", "up"}, err: errUploadAuth},
+		{args: []string{"-auth-file", "auth"}, err: errAuthFile},
+	} {
+		if _, err := command(append([]string{"srvfiles"}, tt.args...)); !errors.Is(err, tt.err) {
+			t.Errorf("command(%q) = %v, want %v", tt.args, err, tt.err)
+		}
+	}
+	if _, err := command([]string{"srvfiles", "extra"}); err == nil {
+		t.Errorf("command() with arguments = nil, want an error")
+	}
+}
+
+func TestRangeAndConditional(t *testing.T) {
+	dir := t.TempDir()
+	content := []byte("0123456789")
+	if err := os.WriteFile(filepath.Join(dir, "image"), content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ts := serve(t, nil, "-d", dir)
+
+	resp, b := do(t, ts.Client(), http.MethodGet, ts.URL+"/image", nil, map[string]string{"Range": "bytes=3-5"})
+	if resp.StatusCode != http.StatusPartialContent || string(b) != "345" {
+		t.Errorf("GET with Range = %d %q, want %d %q", resp.StatusCode, b, http.StatusPartialContent, "345")
+	}
+
+	resp, _ = do(t, ts.Client(), http.MethodGet, ts.URL+"/image", nil, nil)
+	lastModified := resp.Header.Get("Last-Modified")
+	resp, _ = do(t, ts.Client(), http.MethodGet, ts.URL+"/image", nil, map[string]string{"If-Modified-Since": lastModified})
+	if resp.StatusCode != http.StatusNotModified {
+		t.Errorf("GET with If-Modified-Since = %d, want %d", resp.StatusCode, http.StatusNotModified)
+	}
+
+	// -no-cache ignores the condition.
+	ts = serve(t, nil, "-d", dir, "-no-cache")
+	resp, b = do(t, ts.Client(), http.MethodGet, ts.URL+"/image", nil, map[string]string{"If-Modified-Since": lastModified})
+	if resp.StatusCode != http.StatusOK || !bytes.Equal(b, content) {
+		t.Errorf("GET with -no-cache = %d %q, want %d %q", resp.StatusCode, b, http.StatusOK, content)
+	}
+}
+
+func TestListing(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"b":                 "bb",
+		"a<&>":              "a",
+		"sub/x":             "x",
+		"site/index.html":   "index",
+		"site/other.html":   "other",
+		"sub/deeper/file.1": "1",
+	} {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ts := serve(t, nil, "-d", dir)
+
+	resp, b := do(t, ts.Client(), http.MethodGet, ts.URL+"/", nil, nil)
+	if ct := resp.Header.Get("Content-Type"); ct != "text/html; charset=utf-8" {
+		t.Errorf("Content-Type = %q, want text/html", ct)
+	}
+	for _, want := range []string{`<a href="./a%3C&amp;%3E">a&lt;&amp;&gt;</a>`, `<a href="./sub/">sub/</a>`, `<td>2</td>`} {
+		if !strings.Contains(string(b), want) {
+			t.Errorf("HTML listing %s does not contain %s", b, want)
+		}
+	}
+
+	for _, get := range []struct {
+		url    string
+		header map[string]string
+	}{
+		{url: "/sub/?format=json"},
+		{url: "/sub/", header: map[string]string{"Accept": "text/html;q=0.9, application/json"}},
+	} {
+		resp, b = do(t, ts.Client(), http.MethodGet, ts.URL+get.url, nil, get.header)
+		if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Content-Type = %q, want application/json", ct)
+		}
+		var entries []entry
+		if err := json.Unmarshal(b, &entries); err != nil {
+			t.Fatalf("JSON listing %s: %v", b, err)
+		}
+		if len(entries) != 2 || entries[0].Name != "deeper" || !entries[0].Dir || entries[1].Name != "x" || entries[1].Size != 1 {
+			t.Errorf("JSON listing = %+v, want deeper/ and x", entries)
+		}
+	}
+
+	// Directories with an index.html serve it, and redirect without a
+	// trailing slash.
+	if _, b = do(t, ts.Client(), http.MethodGet, ts.URL+"/site/", nil, nil); string(b) != "index" {
+		t.Errorf("GET /site/ = %q, want the index", b)
+	}
+	if _, b = do(t, ts.Client(), http.MethodGet, ts.URL+"/site", nil, nil); string(b) != "index" {
+		t.Errorf("GET /site = %q, want the index", b)
+	}
+	if resp, _ = do(t, ts.Client(), http.MethodGet, ts.URL+"/nothere/", nil, nil); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("GET /nothere/ = %d, want %d", resp.StatusCode, http.StatusNotFound)
+	}
+}
+
+func TestUpload(t *testing.T) {
+	up := t.TempDir()
+	auth := filepath.Join(t.TempDir(), "auth")
+	if err := os.WriteFile(auth, []byte("# nodes\nnode1:secret\n\nnode2:other:colon\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	ts := serve(t, nil, "-d", t.TempDir(), "-tls", "-upload", up, "-auth-file", auth, "-max-upload", "16")
+
+	put := func(name, user, password, body string) int {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodPut, ts.URL+name, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if user != "" {
+			req.SetBasicAuth(user, password)
+		}
+		resp, err := ts.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	for _, tt := range []struct {
+		name, user, password, body string
+		status                     int
+	}{
+		{name: "/logs/node1.log", user: "node1", password: "secret", body: "boot ok", status: http.StatusCreated},
+		{name: "/logs/node1.log", user: "node1", password: "secret", body: "boot ok 2", status: http.StatusNoContent},
+		{name: "/../../escape", user: "node2", password: "other:colon", body: "x", status: http.StatusCreated},
+		{name: "/logs/x", user: "node1", password: "wrong", body: "x", status: http.StatusUnauthorized},
+		{name: "/logs/x", user: "nobody", password: "secret", body: "x", status: http.StatusUnauthorized},
+		{name: "/logs/x", body: "x", status: http.StatusUnauthorized},
+		{name: "/logs/", user: "node1", password: "secret", body: "x", status: http.StatusBadRequest},
+		{name: "/logs", user: "node1", password: "secret", body: "x", status: http.StatusForbidden},
+		{name: "/big", user: "node1", password: "secret", body: strings.Repeat("x", 17), status: http.StatusRequestEntityTooLarge},
+	} {
+		if got := put(tt.name, tt.user, tt.password, tt.body); got != tt.status {
+			t.Errorf("PUT %s as %q = %d, want %d", tt.name, tt.user, got, tt.status)
+		}
+	}
+
+	for name, want := range map[string]string{"logs/node1.log": "boot ok 2", "escape": "x"} {
+		if b, err := os.ReadFile(filepath.Join(up, name)); err != nil || string(b) != want {
+			t.Errorf("uploaded %s = %q, %v, want %q", name, b, err, want)
+		}
+	}
+	if entries, _ := os.ReadDir(filepath.Join(up, "logs")); len(entries) != 1 {
+		t.Errorf("upload left files behind: %v", entries)
+	}
+	if _, err := os.Stat(filepath.Join(up, "big")); err == nil {
+		t.Errorf("PUT beyond -max-upload stored the file")
+	}
+
+	// Without -upload, PUT is not allowed.
+	ts = serve(t, nil, "-d", up)
+	resp, _ := do(t, ts.Client(), http.MethodPut, ts.URL+"/x", strings.NewReader("x"), nil)
+	if resp.StatusCode != http.StatusMethodNotAllowed || resp.Header.Get("Allow") != "GET, HEAD" {
+		t.Errorf("PUT without -upload = %d, Allow %q, want %d", resp.StatusCode, resp.Header.Get("Allow"), http.StatusMethodNotAllowed)
+	}
+
+	bad := filepath.Join(t.TempDir(), "bad")
+	if err := os.WriteFile(bad, []byte("nocolon\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readAuthFile(bad); !errors.Is(err, errAuthLine) {
+		t.Errorf("readAuthFile() = %v, want %v", err, errAuthLine)
+	}
+}
+
+func TestAccessLog(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "hello"), []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	auth := filepath.Join(t.TempDir(), "auth")
+	if err := os.WriteFile(auth, []byte("node1:secret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	var log bytes.Buffer
+	ts := serve(t, &log, "-d", dir, "-tls", "-upload", t.TempDir(), "-auth-file", auth)
+	do(t, ts.Client(), http.MethodGet, ts.URL+"/hello", nil, map[string]string{"User-Agent": "test-agent"})
+	do(t, ts.Client(), http.MethodGet, ts.URL+"/nothere", nil, nil)
+	for _, password := range []string{"secret", "guess"} {
+		req, err := http.NewRequest(http.MethodPut, ts.URL+"/log", strings.NewReader("x"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.SetBasicAuth("node1", password)
+		resp, err := ts.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	lines := strings.Split(strings.TrimSpace(log.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("access log = %q, want 4 lines", lines)
+	}
+	for i, want := range []string{
+		`127.0.0.1 - - [* "GET /hello HTTP/1.1" 200 5 "" "test-agent"`,
+		`127.0.0.1 - - [* "GET /nothere HTTP/1.1" 404 `,
+		`127.0.0.1 - node1 [* "PUT /log HTTP/1.1" 201 `,
+		`127.0.0.1 - - [* "PUT /log HTTP/1.1" 401 `,
+	} {
+		prefix, want, _ := strings.Cut(want, "*")
+		if !strings.HasPrefix(lines[i], prefix) || !strings.Contains(lines[i], want) {
+			t.Errorf("access log line %q does not contain %q", lines[i], prefix+"..."+want)
+		}
+	}
+}
+
+func TestTLS(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "hello"), []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// A CA that signs the server and client certificates of mutual TLS.
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caFile := filepath.Join(dir, "ca.pem")
+	if err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER}), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	issue := func(serial int64, cn string, usage x509.ExtKeyUsage) tls.Certificate {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tmpl := &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: cn},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+			IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, tmpl, ca, &key.PublicKey, caKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+
+	// The server certificate, with its key in the same file.
+	server := issue(2, "server", x509.ExtKeyUsageServerAuth)
+	keyDER, err := x509.MarshalPKCS8PrivateKey(server.PrivateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile := filepath.Join(dir, "server.pem")
+	pemBytes := append(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate[0]}), pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})...)
+	if err := os.WriteFile(certFile, pemBytes, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	start := func(args ...string) *httptest.Server {
+		c, err := command(append([]string{"srvfiles", "-d", dir}, args...))
+		if err != nil {
+			t.Fatal(err)
+		}
+		h, err := c.handler(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conf, err := c.tlsConfig()
+		if err != nil {
+			t.Fatal(err)
+		}
+		ts := httptest.NewUnstartedServer(h)
+		ts.TLS = conf
+		ts.StartTLS()
+		t.Cleanup(ts.Close)
+		return ts
+	}
+	get := func(ts *httptest.Server, conf *tls.Config) error {
+		c := &http.Client{Transport: &http.Transport{TLSClientConfig: conf}}
+		resp, err := c.Get(ts.URL + "/hello")
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if b, _ := io.ReadAll(resp.Body); string(b) != "hello" {
+			return fmt.Errorf("got %q, want %q", b, "hello")
+		}
+		return nil
+	}
+
+	// A self-signed certificate that is valid for the address.
+	ts := start("-tls")
+	leaf, err := x509.ParseCertificate(ts.TLS.Certificates[0].Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	self := x509.NewCertPool()
+	self.AddCert(leaf)
+	if err := get(ts, &tls.Config{RootCAs: self}); err != nil {
+		t.Errorf("GET with a self-signed certificate = %v", err)
+	}
+
+	ts = start("-cert", certFile, "-client-ca", caFile)
+	if err := get(ts, &tls.Config{RootCAs: pool}); err == nil {
+		t.Errorf("GET without a client certificate = nil, want an error")
+	}
+	client := issue(3, "node1", x509.ExtKeyUsageClientAuth)
+	if err := get(ts, &tls.Config{RootCAs: pool, Certificates: []tls.Certificate{client}}); err != nil {
+		t.Errorf("GET with a client certificate = %v", err)
+	}
+
+	if _, err := (&cmd{tls: true, clientCA: certFile + ".missing"}).tlsConfig(); err == nil {
+		t.Errorf("tlsConfig() with a missing CA file = nil, want an error")
+	}
+	empty := filepath.Join(dir, "empty.pem")
+	if err := os.WriteFile(empty, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := (&cmd{tls: true, clientCA: empty}).tlsConfig(); !errors.Is(err, errNoCACertificates) {
+		t.Errorf("tlsConfig() with an empty CA file = %v, want %v", err, errNoCACertificates)
+	}
+}
@@ -0,0 +1,154 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//go:build !tinygo || tinygo.enable
+
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+)
+
+var errAuthLine = errors.New("line is not user:password")
+
+// readAuthFile reads the users and passwords of file, one user:password per
+// line. Empty lines and lines that start with # are skipped.
+func readAuthFile(file string) (map[string]string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	users := map[string]string{}
+	s := bufio.NewScanner(f)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		user, password, ok := strings.Cut(line, ":")
+		if !ok || user == "" {
+			return nil, fmt.Errorf("%s:%d: %w", file, n, errAuthLine)
+		}
+		users[user] = password
+	}
+	return users, s.Err()
+}
+
+// uploader stores the files of PUT requests in dir. With users, clients
+// authenticate with one of them; without, the TLS client certificate that the
+// server requires is enough.
+type uploader struct {
+	dir     string
+	users   map[string]string
+	maxSize int64
+}
+
+func (u *uploader) authorized(r *http.Request) bool {
+	if u.users == nil {
+		return true
+	}
+	user, password, ok := r.BasicAuth()
+	if !ok {
+		return false
+	}
+	want, ok := u.users[user]
+	// Compare anyway, so that unknown users take as long.
+	return subtle.ConstantTimeCompare([]byte(password), []byte(want)) == 1 && ok
+}
+
+func (u *uploader) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !u.authorized(r) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="srvfiles", charset="UTF-8"`)
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+	if strings.HasSuffix(r.URL.Path, "/") {
+		http.Error(w, "cannot PUT a directory", http.StatusBadRequest)
+		return
+	}
+	name := path.Clean("/" + r.URL.Path)[1:]
+	if name == "" {
+		http.Error(w, "cannot PUT a directory", http.StatusBadRequest)
+		return
+	}
+	if u.maxSize > 0 {
+		if r.ContentLength > u.maxSize {
+			http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, u.maxSize)
+	}
+
+	status, err := u.store(name, r.Body)
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		switch {
+		case errors.As(err, &maxErr):
+			status = http.StatusRequestEntityTooLarge
+		case errors.Is(err, fs.ErrPermission):
+			status = http.StatusForbidden
+		default:
+			status = http.StatusInternalServerError
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	w.WriteHeader(status)
+}
+
+// store writes the file name of u.dir, which cannot lead out of it. Readers
+// see the old file or the new one, never a part. It returns
+// http.StatusCreated for new files, and http.StatusNoContent for replaced
+// ones.
+func (u *uploader) store(name string, body io.Reader) (int, error) {
+	root, err := os.OpenRoot(u.dir)
+	if err != nil {
+		return 0, err
+	}
+	defer root.Close()
+
+	if dir := path.Dir(name); dir != "." {
+		if err := root.MkdirAll(dir, 0o755); err != nil {
+			return 0, err
+		}
+	}
+	if fi, err := root.Stat(name); err == nil && fi.IsDir() {
+		return 0, fmt.Errorf("%s is a directory: %w", name, fs.ErrPermission)
+	}
+
+	tmp := fmt.Sprintf("%s.%s.upload", name, rand.Text())
+	f, err := root.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return 0, err
+	}
+	_, err = io.Copy(f, body)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		root.Remove(tmp)
+		return 0, err
+	}
+
+	status := http.StatusCreated
+	if _, err := root.Stat(name); err == nil {
+		status = http.StatusNoContent
+	}
+	if err := root.Rename(tmp, name); err != nil {
+		root.Remove(tmp)
+		return 0, err
+	}
+	return status, nil
+}
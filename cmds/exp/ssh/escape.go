@@ -0,0 +1,142 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !tinygo
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+var errEscapeChar = errors.New("escape characters are a character, ^ and a letter, or none")
+
+const escapeHelp = `Supported escape sequences:
+ %[1]c.   - terminate connection
+ %[1]cB   - send a BREAK to the remote system
+ %[1]c#   - list forwards
+ %[1]c?   - this message
+ %[1]c%[1]c   - send the escape character by typing it twice
+(Note that escapes are only recognized immediately after newline.)
+`
+
+// parseEscapeChar parses an escape character as given to -e or EscapeChar.
+// none is 0.
+func parseEscapeChar(s string) (byte, error) {
+	switch {
+	case s == "none":
+		return 0, nil
+	case len(s) == 1:
+		return s[0], nil
+	case len(s) == 2 && s[0] == '^' && s[1] >= '@' && s[1] <= '_':
+		return s[1] & 0x1f, nil
+	case len(s) == 2 && s[0] == '^' && s[1] >= 'a' && s[1] <= 'z':
+		return s[1] & 0x1f, nil
+	}
+	return 0, fmt.Errorf("%q: %w", s, errEscapeChar)
+}
+
+// escaper filters the escape sequences out of what the user types into an
+// interactive session: the escape character at the start of a line, and the
+// command after it.
+type escaper struct {
+	r   io.Reader
+	esc byte
+	// w gets the messages to the user. The terminal is in raw mode, so
+	// lines end in \r\n.
+	w io.Writer
+
+	// disconnect closes the connection, for ~.
+	disconnect func()
+	// sendBreak sends a BREAK, for ~B.
+	sendBreak func()
+	// forwards are the forwards, for ~#.
+	forwards []string
+
+	// bol is true at the beginning of a line, escaped when the escape
+	// character was just read there.
+	bol, escaped bool
+	buf          []byte
+	err          error
+}
+
+func newEscaper(r io.Reader, esc byte, w io.Writer) *escaper {
+	return &escaper{
+		r:          r,
+		esc:        esc,
+		w:          w,
+		disconnect: func() {},
+		sendBreak:  func() {},
+		bol:        true,
+	}
+}
+
+func (e *escaper) printf(format string, v ...any) {
+	fmt.Fprint(e.w, strings.ReplaceAll(fmt.Sprintf(format, v...), "\n", "\r\n"))
+}
+
+// Read implements io.Reader.
+func (e *escaper) Read(p []byte) (int, error) {
+	for len(e.buf) == 0 && e.err == nil {
+		b := make([]byte, len(p))
+		n, err := e.r.Read(b)
+		e.err = err
+		for _, c := range b[:n] {
+			if e.escape(c) {
+				// Nothing after ~. is sent.
+				e.err = io.EOF
+				break
+			}
+		}
+	}
+	if len(e.buf) > 0 {
+		n := copy(p, e.buf)
+		e.buf = e.buf[n:]
+		return n, nil
+	}
+	return 0, e.err
+}
+
+// escape handles c, and returns whether the session is over.
+func (e *escaper) escape(c byte) bool {
+	bol := c == '\r' || c == '\n'
+	defer func() { e.bol = bol }()
+
+	switch {
+	case e.escaped:
+		e.escaped = false
+		// Commands leave the line as it was, at its beginning.
+		bol = true
+		switch c {
+		case '.':
+			e.printf("%c.\nConnection closed.\n", e.esc)
+			e.disconnect()
+			return true
+		case 'B':
+			e.sendBreak()
+		case '#':
+			e.printf("%c#\nForwards:\n", e.esc)
+			for _, f := range e.forwards {
+				e.printf("  %s\n", f)
+			}
+		case '?':
+			e.printf("%c?\n"+escapeHelp, e.esc)
+		case e.esc:
+			e.buf = append(e.buf, c)
+			bol = false
+		default:
+			// Not an escape sequence, which is sent as is.
+			e.buf = append(e.buf, e.esc, c)
+			bol = c == '\r' || c == '\n'
+		}
+	case e.bol && c == e.esc:
+		e.escaped = true
+	default:
+		e.buf = append(e.buf, c)
+	}
+	return false
+}
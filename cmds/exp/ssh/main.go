@@ -7,20 +7,47 @@
 //
 // Synopsis:
 //
-//	ssh OPTIONS [DEST]
+//	ssh OPTIONS [DEST] [COMMAND...]
 //
 // Description:
 //
-//	Connects to the specified destination.
+//	Connects to the specified destination, and runs the command, or a
+//	shell if there is none.
+//
+//	The settings of the destination, such as HostName, User, Port,
+//	IdentityFile, ProxyJump, ForwardAgent, LocalForward, RemoteForward,
+//	DynamicForward and EscapeChar, are read from ~/.ssh/config.
+//
+//	In an interactive session, ~ at the beginning of a line starts an
+//	escape sequence: ~. disconnects and ~? lists the others.
 //
 // Options:
 //
+//	-A:          forward the agent of SSH_AUTH_SOCK
+//	-a:          do not forward the agent
+//	-D port:     forward the connections to [bind_address:]port, where a
+//	             SOCKS5 client asks
+//	-d, -v:      enable debug prints
+//	-e char:     escape character, or none
+//	-F file:     config file
+//	-i file:     key file
+//	-J hosts:    connect through the jump hosts, [user@]host[:port],...
+//	-L forward:  forward the connections to [bind_address:]port to
+//	             host:hostport from the server
+//	-l user:     user to log in as
+//	-N:          do not run a command, only forward ports
+//	-o option:   config option, Key=Value
+//	-p port:     port to connect to
+//	-R forward:  forward the connections to [bind_address:]port on the
+//	             server to host:hostport from here
+//
 // Destination format:
 //
 //	[user@]hostname or ssh://[user@]hostname[:port]
 package main
 
 import (
+	"context"
 	"errors"
 	"flag"
 	"fmt"
@@ -28,66 +55,51 @@ import (
 	"log"
 	"net"
 	"os"
-	guser "os/user"
-	"path/filepath"
 	"strings"
 
-	sshconfig "github.com/kevinburke/ssh_config"
+	"github.com/u-root/u-root/pkg/sshclient"
+	"github.com/u-root/u-root/pkg/uroot/unixflag"
 	"golang.org/x/crypto/ssh"
-	"golang.org/x/crypto/ssh/knownhosts"
 	"golang.org/x/term"
 )
 
 var (
-	flags = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
-
-	debug      = flags.Bool("d", false, "enable debug prints")
-	keyFile    = flags.String("i", "", "key file")
-	configFile = flags.String("F", defaultConfigFile, "config file")
-
 	v = func(string, ...any) {}
 
-	// ssh config file
-	cfg *sshconfig.Config
-
 	errInvalidArgs = errors.New("invalid command-line arguments")
+	errNoHost      = errors.New("no host specified")
 )
 
-// loadConfig loads the SSH config file
-func loadConfig(path string) (err error) {
-	var f *os.File
-	if f, err = os.Open(path); err != nil {
-		if os.IsNotExist(err) {
-			err = nil
-			cfg = &sshconfig.Config{}
-		}
-		return
-	}
-	cfg, err = sshconfig.Decode(f)
-	return
-}
-
 func main() {
 	if err := run(os.Args, os.Stdin, os.Stdout, os.Stderr); err != nil {
 		log.Fatalf("%v", err)
 	}
 }
 
-func knownHosts() (ssh.HostKeyCallback, error) {
-	etc, err := filepath.Glob("/etc/*/ssh_known_hosts")
-	if err != nil {
-		return nil, err
-	}
-	if home, ok := os.LookupEnv("HOME"); ok {
-		etc = append(etc, filepath.Join(home, ".ssh", "known_hosts"))
-	}
-	return knownhosts.New(etc...)
-}
-
 // we demand that stdin be a proper os.File because we need to be able to put it in raw mode
 func run(osArgs []string, stdin *os.File, stdout io.Writer, stderr io.Writer) error {
+	flags := flag.NewFlagSet(osArgs[0], flag.ExitOnError)
+	var (
+		debug        = flags.Bool("d", false, "enable debug prints")
+		keyFile      = flags.String("i", "", "key file")
+		configFile   = flags.String("F", defaultConfigFile, "config file")
+		port         = flags.String("p", "", "port to connect to")
+		login        = flags.String("l", "", "user to log in as")
+		jump         = flags.String("J", "", "jump hosts, [user@]host[:port],...")
+		escape       = flags.String("e", "", "escape character, or none")
+		forwardAgent = flags.Bool("A", false, "forward the agent")
+		noAgent      = flags.Bool("a", false, "do not forward the agent")
+		noCommand    = flags.Bool("N", false, "do not run a command, only forward ports")
+
+		options, local, remote, dynamic unixflag.StringArray
+	)
+	flags.BoolVar(debug, "v", false, "enable debug prints")
+	flags.Var(&options, "o", "config option, Key=Value")
+	flags.Var(&local, "L", "forward [bind_address:]port here to host:hostport from the server")
+	flags.Var(&remote, "R", "forward [bind_address:]port on the server to host:hostport from here")
+	flags.Var(&dynamic, "D", "forward [bind_address:]port here to where SOCKS5 clients ask")
 	flags.SetOutput(stderr)
-	flags.Parse(osArgs[1:])
+	flags.Parse(unixflag.ArgsWithValuesToGoArgs(osArgs[1:], "iFplJeoLRD"))
 	if *debug {
 		v = log.Printf
 	}
@@ -106,52 +118,79 @@ func run(osArgs []string, stdin *os.File, stdout io.Writer, stderr io.Writer) er
 	}
 
 	// Read the config file (if any)
-	if err := loadConfig(*configFile); err != nil {
+	cfg, err := sshclient.LoadConfig(*configFile)
+	if err != nil {
 		return fmt.Errorf("config parse failed: %w", err)
 	}
-
-	// Parse out the destination
-	user, host, port, err := parseDest(dest)
-	if err != nil {
-		return fmt.Errorf("destination parse failed: %w", err)
+	d := &sshclient.Dialer{Config: cfg, Logf: v}
+	if *jump != "" {
+		options = append(options, "ProxyJump="+*jump)
 	}
-
-	cb, err := knownHosts()
-	if err != nil {
-		return fmt.Errorf("known hosts:%w", err)
+	if *escape != "" {
+		options = append(options, "EscapeChar="+*escape)
 	}
-	// Build a client config with appropriate auth methods
-	config := &ssh.ClientConfig{
-		User:            user,
-		HostKeyCallback: cb,
+	switch {
+	case *noAgent:
+		options = append(options, "ForwardAgent=no")
+	case *forwardAgent:
+		options = append(options, "ForwardAgent=yes")
 	}
-	// Figure out if there's a keyfile or not
-	kf := getKeyFile(host, *keyFile)
-	key, err := os.ReadFile(kf)
-	if err == nil {
-		// The key exists
-		signer, err := ssh.ParsePrivateKey(key)
-		if err != nil {
-			return fmt.Errorf("ParsePrivateKey %v: %w", kf, err)
+	for _, o := range options {
+		if err := d.SetOption(o); err != nil {
+			return err
 		}
-		config.Auth = []ssh.AuthMethod{ssh.PublicKeys(signer)}
-	} else if err != nil && *keyFile != "" {
-		return fmt.Errorf("could not read user-specified keyfile %v: %w", kf, err)
 	}
-	v("Config: %+v\n", config)
+	if *keyFile != "" {
+		d.IdentityFiles = []string{*keyFile}
+	}
 	if term.IsTerminal(int(stdin.Fd())) {
-		pwReader := func() (string, error) {
-			return readPassword(stdin, stdout)
+		d.Prompt = func(prompt string, echo bool) (string, error) {
+			return readLine(stdin, stderr, prompt, echo)
 		}
-		config.Auth = append(config.Auth, ssh.PasswordCallback(pwReader))
+	}
+
+	// Parse out the destination
+	user, host, destPort := sshclient.ParseDestination(dest)
+	if host == "" {
+		return fmt.Errorf("destination parse failed: %w", errNoHost)
+	}
+	if user == "" {
+		user = *login
+	}
+	if destPort == "" {
+		destPort = *port
+	}
+	target, err := d.Resolve(user, host, destPort)
+	if err != nil {
+		return fmt.Errorf("destination parse failed: %w", err)
+	}
+	escapeChar, err := parseEscapeChar(d.Get(target.Alias, "EscapeChar"))
+	if err != nil {
+		return err
 	}
 
 	// Now connect to the server
-	conn, err := ssh.Dial("tcp", net.JoinHostPort(host, port), config)
+	conn, err := d.DialTarget(context.Background(), target)
 	if err != nil {
 		return fmt.Errorf("unable to connect: %w", err)
 	}
 	defer conn.Close()
+
+	agent := d.Get(target.Alias, "ForwardAgent") == "yes"
+	if agent {
+		if err := sshclient.ForwardAgent(conn); err != nil {
+			log.Printf("agent forwarding: %v", err)
+			agent = false
+		}
+	}
+	forwards := startForwards(conn, d, target.Alias, local, remote, dynamic)
+
+	if *noCommand {
+		v("Not running a command")
+		conn.Wait()
+		return nil
+	}
+
 	// Create a session on that connection
 	session, err := conn.NewSession()
 	if err != nil {
@@ -161,6 +200,11 @@ func run(osArgs []string, stdin *os.File, stdout io.Writer, stderr io.Writer) er
 	session.Stdout = stdout
 	session.Stderr = stderr
 	defer session.Close()
+	if agent {
+		if err := sshclient.RequestAgentForwarding(session); err != nil {
+			log.Printf("agent forwarding: %v", err)
+		}
+	}
 
 	if len(args) > 0 {
 		// run the command
@@ -188,6 +232,16 @@ func run(osArgs []string, stdin *os.File, stdout io.Writer, stderr io.Writer) er
 			if err := session.RequestPty("xterm", height, width, modes); err != nil {
 				log.Print("request for pseudo terminal failed: ", err)
 			}
+			// Escape sequences are only for people at terminals.
+			if escapeChar != 0 {
+				e := newEscaper(stdin, escapeChar, stderr)
+				e.disconnect = func() { conn.Close() }
+				e.sendBreak = func() {
+					session.SendRequest("break", false, ssh.Marshal(struct{ Ms uint32 }{1000}))
+				}
+				e.forwards = forwards
+				session.Stdin = e
+			}
 		}
 		// Start shell on remote system
 		if err := session.Shell(); err != nil {
@@ -199,59 +253,36 @@ func run(osArgs []string, stdin *os.File, stdout io.Writer, stderr io.Writer) er
 	return nil
 }
 
-// parseDest splits an ssh destination spec into separate user, host, and port fields.
-// Example specs:
-//
-//	ssh://user@host:port
-//	user@host:port
-//	user@host
-//	host
-func parseDest(input string) (user, host, port string, err error) {
-	// strip off any preceding ssh://
-	input = strings.TrimPrefix(input, "ssh://")
-	// try to find user
-	i := strings.LastIndex(input, "@")
-	if i < 0 {
-		var u *guser.User
-		u, err = guser.Current()
-		if err != nil {
-			return
-		}
-		user = u.Username
-	} else {
-		user = input[0:i]
-		input = input[i+1:]
-	}
-	if host, port, err = net.SplitHostPort(input); err != nil {
-		err = nil
-		host = input
-		port = "22"
-	}
-	if host == "" {
-		err = errors.New("no host specified")
-	}
-	return
-}
-
-// getKeyFile picks a keyfile if none has been set.
-// It will use sshconfig, else use a default.
-// The kf parameter is a user-specified key file. We pass it
-// here so it can be re-written if it contains a ~
-func getKeyFile(host, kf string) string {
-	v("getKeyFile for %q", kf)
-	if len(kf) == 0 {
-		var err error
-		kf, err = cfg.Get(host, "IdentityFile")
-		v("key file from config is %q", kf)
-		if len(kf) == 0 || err != nil {
-			kf = defaultKeyFile
+// startForwards starts the port forwards of the flags and of the config of
+// host, and returns them for ~#. As with OpenSSH, the forwards that fail
+// only get a warning.
+func startForwards(conn *ssh.Client, d *sshclient.Dialer, host string, local, remote, dynamic []string) []string {
+	var started []string
+	for _, f := range []struct {
+		specs   []string
+		parse   func(string) (*sshclient.Forward, error)
+		forward func(*ssh.Client, *sshclient.Forward, func(string, ...any)) (net.Listener, error)
+		what    string
+	}{
+		{append(d.GetAll(host, "LocalForward"), local...), sshclient.ParseForward, sshclient.ForwardLocal, "local"},
+		{append(d.GetAll(host, "RemoteForward"), remote...), sshclient.ParseForward, sshclient.ForwardRemote, "remote"},
+		{append(d.GetAll(host, "DynamicForward"), dynamic...), sshclient.ParseDynamicForward, sshclient.ForwardLocal, "dynamic"},
+	} {
+		for _, spec := range f.specs {
+			if spec == "" {
+				continue
+			}
+			fwd, err := f.parse(spec)
+			if err == nil {
+				_, err = f.forward(conn, fwd, log.Printf)
+			}
+			if err != nil {
+				log.Printf("Warning: %s forward %s: %v", f.what, spec, err)
+				continue
+			}
+			v("%s forward %v", f.what, fwd)
+			started = append(started, fmt.Sprintf("%s forward %v", f.what, fwd))
 		}
 	}
-	// The kf will always be non-zero at this point.
-	if strings.HasPrefix(kf, "~") {
-		kf = filepath.Join(os.Getenv("HOME"), kf[1:])
-	}
-	v("getKeyFile returns %q", kf)
-	// this is a tad annoying, but the config package doesn't handle ~.
-	return kf
+	return started
 }
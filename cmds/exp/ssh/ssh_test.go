@@ -6,66 +6,69 @@
 package main
 
 import (
+	"bytes"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
 	"encoding/pem"
+	"errors"
 	"io"
 	"log"
 	"os"
-	guser "os/user"
 	"path/filepath"
 	"strings"
 	"testing"
+	"testing/iotest"
 )
 
-type destTest struct {
-	input string
-	user  string
-	host  string
-	port  string
-}
-
-var destTests = []destTest{
-	{"example.org", "", "example.org", "22"},
-	{"foo@example.org", "foo", "example.org", "22"},
-	{"foo@example.org", "foo", "example.org", "22"},
-	{"ssh://192.168.0.2:23", "", "192.168.0.2", "23"},
-	{"ssh://x@example.org", "x", "example.org", "22"},
-}
-
-func TestParseDest(t *testing.T) {
-	for _, x := range destTests {
-		if x.user == "" {
-			var u *guser.User
-			u, _ = guser.Current()
-			x.user = u.Username
-		}
-		user, host, port, err := parseDest(x.input)
-		if err != nil {
-			t.Fatal(err)
+func TestParseEscapeChar(t *testing.T) {
+	for in, want := range map[string]byte{"~": '~', "none": 0, "^]": 0x1d, "^a": 1, "%": '%'} {
+		if got, err := parseEscapeChar(in); err != nil || got != want {
+			t.Errorf("parseEscapeChar(%q) = %#x, %v, want %#x", in, got, err, want)
 		}
-		if user != x.user || host != x.host || port != x.port {
-			t.Fatalf("failed on %v: got %v, %v, %v", x, user, host, port)
+	}
+	for _, in := range []string{"", "ab", "^1"} {
+		if _, err := parseEscapeChar(in); !errors.Is(err, errEscapeChar) {
+			t.Errorf("parseEscapeChar(%q) = %v, want %v", in, err, errEscapeChar)
 		}
 	}
 }
 
-// Load a config file and ask for the keyfile for a host in it
-// By populating a real file & reading it, we get to test loadConfig too
-func TestGetKeyFile(t *testing.T) {
-	dir := t.TempDir()
-	confPath := filepath.Join(dir, "sshconfig")
-	conf := []byte(`Host foo
-	IdentityFile bar_key`)
-	if err := os.WriteFile(confPath, conf, 0o600); err != nil {
-		t.Fatal(err)
-	}
-	if err := loadConfig(confPath); err != nil {
-		t.Fatal(err)
-	}
-	if kf := getKeyFile("foo", ""); kf != "bar_key" {
-		t.Fatalf(`getKeyFile("foo", "") = %v, want "bar_key"`, kf)
+func TestEscape(t *testing.T) {
+	for _, tt := range []struct {
+		name, in, sent, msgs string
+		disconnect, brk      bool
+	}{
+		{name: "no escapes", in: "ls ~/x\r", sent: "ls ~/x\r"},
+		{name: "not at the beginning of a line", in: "echo ~.\r", sent: "echo ~.\r"},
+		{name: "escape character", in: "~~x\r~~", sent: "~x\r~"},
+		{name: "not a command", in: "~x\n~\r", sent: "~x\n~\r"},
+		{name: "disconnect", in: "ls\r~.ignored", sent: "ls\r", msgs: "~.\r\nConnection closed.\r\n", disconnect: true},
+		{name: "break and help", in: "~B~?x", sent: "x", msgs: "~?\r\nSupported escape sequences:\r\n", brk: true},
+		{name: "forwards", in: "~#", msgs: "~#\r\nForwards:\r\n  local forward a -> b\r\n"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var msgs bytes.Buffer
+			var disconnect, brk bool
+			e := newEscaper(strings.NewReader(tt.in), '~', &msgs)
+			e.disconnect = func() { disconnect = true }
+			e.sendBreak = func() { brk = true }
+			e.forwards = []string{"local forward a -> b"}
+			// One byte at a time, as people type.
+			sent, err := io.ReadAll(iotest.OneByteReader(e))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(sent) != tt.sent {
+				t.Errorf("sent %q, want %q", sent, tt.sent)
+			}
+			if !strings.HasPrefix(msgs.String(), tt.msgs) {
+				t.Errorf("messages %q, want %q", msgs.String(), tt.msgs)
+			}
+			if disconnect != tt.disconnect || brk != tt.brk {
+				t.Errorf("disconnect, break = %v, %v, want %v, %v", disconnect, brk, tt.disconnect, tt.brk)
+			}
+		})
 	}
 }
 
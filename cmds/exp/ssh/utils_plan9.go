@@ -16,7 +16,6 @@ import (
 )
 
 var (
-	defaultKeyFile    = filepath.Join(os.Getenv("home"), "lib/ssh/id_rsa")
 	defaultConfigFile = filepath.Join(os.Getenv("home"), "lib/ssh/config")
 
 	consctl *os.File
@@ -49,10 +48,12 @@ func cooked() (err error) {
 	return
 }
 
-// readPassword prompts the user for a password.
-func readPassword(in *os.File, out io.Writer) (string, error) {
-	fmt.Fprintf(out, "Password: ")
-	raw(in)
+// readLine prompts the user for a line, such as a password if echo is false.
+func readLine(in *os.File, out io.Writer, prompt string, echo bool) (string, error) {
+	fmt.Fprint(out, prompt)
+	if !echo {
+		raw(in)
+	}
 	cons, err := os.OpenFile("/dev/cons", os.O_RDWR, 0o755)
 	if err != nil {
 		return "", err
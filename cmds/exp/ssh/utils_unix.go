@@ -2,7 +2,7 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-//go:build !tinygo && (!plan9 || !windows)
+//go:build !tinygo && !plan9
 
 package main
 
@@ -11,12 +11,12 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"golang.org/x/term"
 )
 
 var (
-	defaultKeyFile    = filepath.Join(os.Getenv("HOME"), ".ssh/id_rsa")
 	defaultConfigFile = filepath.Join(os.Getenv("HOME"), ".ssh/config")
 
 	oldState *term.State
@@ -35,11 +35,26 @@ func raw(in *os.File) (err error) {
 	return
 }
 
-// readPassword prompts the user for a password.
-func readPassword(in *os.File, out io.Writer) (string, error) {
-	fmt.Fprintf(out, "Password: ")
-	b, err := term.ReadPassword(int(in.Fd()))
-	return string(b), err
+// readLine prompts the user for a line, such as a password if echo is false.
+func readLine(in *os.File, out io.Writer, prompt string, echo bool) (string, error) {
+	fmt.Fprint(out, prompt)
+	if !echo {
+		b, err := term.ReadPassword(int(in.Fd()))
+		fmt.Fprintln(out)
+		return string(b), err
+	}
+	// Read byte by byte, to leave the rest for the session.
+	var line []byte
+	for {
+		var b [1]byte
+		if _, err := in.Read(b[:]); err != nil {
+			return "", err
+		}
+		if b[0] == '\n' {
+			return strings.TrimSuffix(string(line), "\r"), nil
+		}
+		line = append(line, b[0])
+	}
 }
 
 // getSize reads the size of the terminal.
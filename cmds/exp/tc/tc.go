@@ -24,11 +24,32 @@ import (
 	_ "github.com/u-root/cpuid"
 )
 
-var cmdHelp = `Usage:	tc OBJECT { COMMAND | help }
+var cmdHelp = `Usage:	tc [ OPTIONS ] OBJECT { COMMAND | help }
 where  OBJECT := { qdisc | class | filter }
+       OPTIONS := { -s[tatistics] }
 `
 
+// parseOptions strips the leading global options off args.
+func parseOptions(args []string) (stats bool, rest []string, err error) {
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") {
+		opt := strings.TrimLeft(args[0], "-")
+		switch {
+		case opt != "" && strings.HasPrefix("statistics", opt):
+			stats = true
+		default:
+			return false, nil, fmt.Errorf("%w: option %q is unknown", trafficctl.ErrInvalidArg, args[0])
+		}
+		args = args[1:]
+	}
+	return stats, args, nil
+}
+
 func main() {
+	stats, args, err := parseOptions(os.Args[1:])
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	rtnl, err := tc.Open(&tc.Config{})
 	if err != nil {
 		log.Fatal(err)
@@ -39,8 +60,8 @@ func main() {
 		}
 	}()
 
-	tctl := &trafficctl.Trafficctl{Tc: rtnl}
-	if err := run(os.Stdout, tctl, os.Args[1:]); err != nil {
+	tctl := &trafficctl.Trafficctl{Tc: rtnl, Stats: stats}
+	if err := run(os.Stdout, tctl, args); err != nil {
 		log.Fatal(err)
 	}
 }
@@ -110,7 +131,7 @@ func runQdisc(stdout io.Writer, tctl trafficctl.Tctl, args []string) error {
 	qArgs := &trafficctl.Args{}
 	var err error
 	if len(args[1:]) > 1 {
-		qArgs, err = trafficctl.ParseQdiscArgs(os.Stdout, args[1:])
+		qArgs, err = trafficctl.ParseQdiscArgs(stdout, args[1:])
 		if err != nil {
 			return err
 		}
@@ -9,6 +9,7 @@ import (
 	"bytes"
 	"errors"
 	"io"
+	"slices"
 	"testing"
 
 	trafficctl "github.com/u-root/u-root/pkg/tc"
@@ -263,3 +264,49 @@ func TestRun(t *testing.T) {
 		})
 	}
 }
+
+func TestParseOptions(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		args  []string
+		stats bool
+		rest  []string
+		err   error
+	}{
+		{
+			name: "no options",
+			args: []string{"qdisc", "show"},
+			rest: []string{"qdisc", "show"},
+		},
+		{
+			name:  "short stats",
+			args:  []string{"-s", "qdisc", "show"},
+			stats: true,
+			rest:  []string{"qdisc", "show"},
+		},
+		{
+			name:  "long stats",
+			args:  []string{"-statistics", "filter", "show"},
+			stats: true,
+			rest:  []string{"filter", "show"},
+		},
+		{
+			name: "unknown option",
+			args: []string{"-x", "qdisc"},
+			err:  trafficctl.ErrInvalidArg,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			stats, rest, err := parseOptions(tt.args)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("parseOptions(%q) = %v, want %v", tt.args, err, tt.err)
+			}
+			if err != nil {
+				return
+			}
+			if stats != tt.stats || !slices.Equal(rest, tt.rest) {
+				t.Errorf("parseOptions(%q) = %t, %q, want %t, %q", tt.args, stats, rest, tt.stats, tt.rest)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//go:build !tinygo || tinygo.enable
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+
+	"github.com/gopacket/gopacket/layers"
+)
+
+// arpData returns a string representation of the ARP layer.
+func arpData(layer *layers.ARP) string {
+	srcIP, dstIP := net.IP(layer.SourceProtAddress), net.IP(layer.DstProtAddress)
+	srcMAC, dstMAC := net.HardwareAddr(layer.SourceHwAddress), net.HardwareAddr(layer.DstHwAddress)
+	length := len(layer.Contents)
+
+	switch layer.Operation {
+	case layers.ARPRequest:
+		if len(dstMAC) > 0 && !bytes.Equal(dstMAC, make([]byte, len(dstMAC))) {
+			return fmt.Sprintf("Request who-has %s (%s) tell %s, length %d", dstIP, dstMAC, srcIP, length)
+		}
+		return fmt.Sprintf("Request who-has %s tell %s, length %d", dstIP, srcIP, length)
+	case layers.ARPReply:
+		return fmt.Sprintf("Reply %s is-at %s, length %d", srcIP, srcMAC, length)
+	case 3:
+		return fmt.Sprintf("Reverse Request who-is %s tell %s, length %d", dstMAC, srcMAC, length)
+	case 4:
+		return fmt.Sprintf("Reverse Reply %s at %s, length %d", dstMAC, dstIP, length)
+	default:
+		return fmt.Sprintf("unknown operation %d, length %d", layer.Operation, length)
+	}
+}
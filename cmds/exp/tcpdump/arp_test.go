@@ -0,0 +1,38 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//go:build !tinygo || tinygo.enable
+
+package main
+
+import (
+	"testing"
+
+	"github.com/gopacket/gopacket/layers"
+)
+
+func TestARPData(t *testing.T) {
+	base := layers.ARP{
+		BaseLayer:         layers.BaseLayer{Contents: make([]byte, 28)},
+		SourceHwAddress:   []byte{0x52, 0x54, 0x00, 0x12, 0x34, 0x56},
+		SourceProtAddress: []byte{10, 0, 0, 1},
+		DstHwAddress:      []byte{0x52, 0x54, 0x00, 0x12, 0x34, 0x57},
+		DstProtAddress:    []byte{10, 0, 0, 2},
+	}
+	for _, tt := range []struct {
+		op   uint16
+		want string
+	}{
+		{layers.ARPRequest, "Request who-has 10.0.0.2 (52:54:00:12:34:57) tell 10.0.0.1, length 28"},
+		{layers.ARPReply, "Reply 10.0.0.1 is-at 52:54:00:12:34:56, length 28"},
+		{3, "Reverse Request who-is 52:54:00:12:34:57 tell 52:54:00:12:34:56, length 28"},
+		{4, "Reverse Reply 52:54:00:12:34:57 at 10.0.0.2, length 28"},
+		{9, "unknown operation 9, length 28"},
+	} {
+		layer := base
+		layer.Operation = tt.op
+		if got := arpData(&layer); got != tt.want {
+			t.Errorf("arpData(operation %d) = %q, want %q", tt.op, got, tt.want)
+		}
+	}
+}
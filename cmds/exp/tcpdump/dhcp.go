@@ -0,0 +1,123 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//go:build !tinygo || tinygo.enable
+
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/gopacket/gopacket/layers"
+)
+
+// dhcpv4Data returns a string representation of the DHCPv4 layer.
+func dhcpv4Data(layer *layers.DHCPv4, verbose bool) string {
+	length := len(layer.Contents) + len(layer.Payload)
+	if !verbose {
+		return fmt.Sprintf("BOOTP/DHCP, %s from %s, length %d", layer.Operation, layer.ClientHWAddr, length)
+	}
+
+	var data strings.Builder
+	fmt.Fprintf(&data, "BOOTP/DHCP, %s from %s, xid 0x%x", layer.Operation, layer.ClientHWAddr, layer.Xid)
+	if layer.Flags&0x8000 != 0 {
+		data.WriteString(", Flags [Broadcast]")
+	}
+	for _, ip := range []struct {
+		name string
+		ip   net.IP
+	}{
+		{"Client-IP", layer.ClientIP},
+		{"Your-IP", layer.YourClientIP},
+		{"Server-IP", layer.NextServerIP},
+		{"Gateway-IP", layer.RelayAgentIP},
+	} {
+		if ip.ip != nil && !ip.ip.IsUnspecified() {
+			fmt.Fprintf(&data, ", %s %s", ip.name, ip.ip)
+		}
+	}
+	if file := strings.TrimRight(string(layer.File), "\x00"); file != "" {
+		fmt.Fprintf(&data, ", file %q", file)
+	}
+
+	for _, o := range layer.Options {
+		switch o.Type {
+		case layers.DHCPOptMessageType:
+			if len(o.Data) == 1 {
+				fmt.Fprintf(&data, ", DHCP-Message %s", layers.DHCPMsgType(o.Data[0]))
+			}
+		case layers.DHCPOptHostname:
+			fmt.Fprintf(&data, ", Hostname %q", o.Data)
+		case layers.DHCPOptClassID:
+			fmt.Fprintf(&data, ", Vendor-Class %q", o.Data)
+		case layers.DHCPOptRequestIP:
+			fmt.Fprintf(&data, ", Requested-IP %s", net.IP(o.Data))
+		case layers.DHCPOptServerID:
+			fmt.Fprintf(&data, ", Server-ID %s", net.IP(o.Data))
+		case layers.DHCPOptSubnetMask:
+			fmt.Fprintf(&data, ", Subnet-Mask %s", net.IP(o.Data))
+		case layers.DHCPOptRouter:
+			fmt.Fprintf(&data, ", Default-Gateway %s", ipList(o.Data, net.IPv4len))
+		case layers.DHCPOptDNS:
+			fmt.Fprintf(&data, ", Domain-Name-Server %s", ipList(o.Data, net.IPv4len))
+		case layers.DHCPOptLeaseTime:
+			if len(o.Data) == 4 {
+				fmt.Fprintf(&data, ", Lease-Time %ds", binary.BigEndian.Uint32(o.Data))
+			}
+		}
+	}
+	fmt.Fprintf(&data, ", length %d", length)
+	return data.String()
+}
+
+// dhcpv6MsgTypes are tcpdump's names for DHCPv6 messages.
+var dhcpv6MsgTypes = map[layers.DHCPv6MsgType]string{
+	layers.DHCPv6MsgTypeSolicit:            "solicit",
+	layers.DHCPv6MsgTypeAdvertise:          "advertise",
+	layers.DHCPv6MsgTypeRequest:            "request",
+	layers.DHCPv6MsgTypeConfirm:            "confirm",
+	layers.DHCPv6MsgTypeRenew:              "renew",
+	layers.DHCPv6MsgTypeRebind:             "rebind",
+	layers.DHCPv6MsgTypeReply:              "reply",
+	layers.DHCPv6MsgTypeRelease:            "release",
+	layers.DHCPv6MsgTypeDecline:            "decline",
+	layers.DHCPv6MsgTypeReconfigure:        "reconfigure",
+	layers.DHCPv6MsgTypeInformationRequest: "inf-req",
+	layers.DHCPv6MsgTypeRelayForward:       "relay-fwd",
+	layers.DHCPv6MsgTypeRelayReply:         "relay-reply",
+}
+
+// dhcpv6Data returns a string representation of the DHCPv6 layer.
+func dhcpv6Data(layer *layers.DHCPv6, verbose bool) string {
+	name, ok := dhcpv6MsgTypes[layer.MsgType]
+	if !ok {
+		name = fmt.Sprintf("type %d", layer.MsgType)
+	}
+	data := "dhcp6 " + name
+	if !verbose {
+		return data
+	}
+
+	if layer.MsgType == layers.DHCPv6MsgTypeRelayForward || layer.MsgType == layers.DHCPv6MsgTypeRelayReply {
+		data += fmt.Sprintf(" (linkaddr=%s peeraddr=%s", layer.LinkAddr, layer.PeerAddr)
+	} else {
+		data += fmt.Sprintf(" (xid=%x", layer.TransactionID)
+	}
+	var opts []string
+	for _, o := range layer.Options {
+		opts = append(opts, o.Code.String())
+	}
+	return data + fmt.Sprintf(" options [%s])", strings.Join(opts, ","))
+}
+
+// ipList formats the addresses of size bytes each in b.
+func ipList(b []byte, size int) string {
+	var ips []string
+	for ; len(b) >= size; b = b[size:] {
+		ips = append(ips, net.IP(b[:size]).String())
+	}
+	return strings.Join(ips, ",")
+}
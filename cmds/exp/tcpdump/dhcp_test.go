@@ -0,0 +1,98 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//go:build !tinygo || tinygo.enable
+
+package main
+
+import (
+	"net"
+	"testing"
+
+	"github.com/gopacket/gopacket/layers"
+)
+
+func TestDHCPv4Data(t *testing.T) {
+	ack := &layers.DHCPv4{
+		BaseLayer:    layers.BaseLayer{Contents: make([]byte, 300)},
+		Operation:    layers.DHCPOpReply,
+		Xid:          0xabcd,
+		ClientIP:     net.IPv4zero,
+		YourClientIP: net.IP{192, 168, 0, 10},
+		NextServerIP: net.IP{192, 168, 0, 1},
+		RelayAgentIP: net.IP{192, 168, 1, 1},
+		ClientHWAddr: net.HardwareAddr{0x52, 0x54, 0x00, 0x12, 0x34, 0x56},
+		File:         append([]byte("pxelinux.0"), make([]byte, 118)...),
+		Options: layers.DHCPOptions{
+			layers.NewDHCPOption(layers.DHCPOptMessageType, []byte{byte(layers.DHCPMsgTypeAck)}),
+			layers.NewDHCPOption(layers.DHCPOptServerID, []byte{192, 168, 0, 1}),
+			layers.NewDHCPOption(layers.DHCPOptLeaseTime, []byte{0, 0, 0x0e, 0x10}),
+			layers.NewDHCPOption(layers.DHCPOptSubnetMask, []byte{255, 255, 255, 0}),
+			layers.NewDHCPOption(layers.DHCPOptRouter, []byte{192, 168, 0, 1}),
+			layers.NewDHCPOption(layers.DHCPOptDNS, []byte{192, 168, 0, 53, 192, 168, 0, 54}),
+			layers.NewDHCPOption(layers.DHCPOptClassID, []byte("PXEClient")),
+			layers.NewDHCPOption(layers.DHCPOptRequestIP, []byte{192, 168, 0, 10}),
+		},
+	}
+
+	want := "BOOTP/DHCP, Reply from 52:54:00:12:34:56, length 300"
+	if got := dhcpv4Data(ack, false); got != want {
+		t.Errorf("dhcpv4Data() = %q, want %q", got, want)
+	}
+	want = `BOOTP/DHCP, Reply from 52:54:00:12:34:56, xid 0xabcd, Your-IP 192.168.0.10, Server-IP 192.168.0.1, Gateway-IP 192.168.1.1, file "pxelinux.0", ` +
+		`DHCP-Message Ack, Server-ID 192.168.0.1, Lease-Time 3600s, Subnet-Mask 255.255.255.0, Default-Gateway 192.168.0.1, ` +
+		`Domain-Name-Server 192.168.0.53,192.168.0.54, Vendor-Class "PXEClient", Requested-IP 192.168.0.10, length 300`
+	if got := dhcpv4Data(ack, true); got != want {
+		t.Errorf("dhcpv4Data(verbose) = %q, want %q", got, want)
+	}
+}
+
+func TestDHCPv6Data(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		layer   *layers.DHCPv6
+		verbose bool
+		want    string
+	}{
+		{
+			name:  "solicit",
+			layer: &layers.DHCPv6{MsgType: layers.DHCPv6MsgTypeSolicit},
+			want:  "dhcp6 solicit",
+		},
+		{
+			name: "solicit verbose",
+			layer: &layers.DHCPv6{
+				MsgType:       layers.DHCPv6MsgTypeSolicit,
+				TransactionID: []byte{0x12, 0x34, 0x56},
+				Options: layers.DHCPv6Options{
+					layers.NewDHCPv6Option(layers.DHCPv6OptClientID, []byte{0, 3, 0, 1}),
+					layers.NewDHCPv6Option(layers.DHCPv6OptIANA, make([]byte, 12)),
+				},
+			},
+			verbose: true,
+			want:    "dhcp6 solicit (xid=123456 options [ClientID,IA_NA])",
+		},
+		{
+			name: "relay verbose",
+			layer: &layers.DHCPv6{
+				MsgType:  layers.DHCPv6MsgTypeRelayForward,
+				LinkAddr: net.ParseIP("fd00::1"),
+				PeerAddr: net.ParseIP("fe80::1"),
+				Options:  layers.DHCPv6Options{layers.NewDHCPv6Option(layers.DHCPv6OptRelayMessage, nil)},
+			},
+			verbose: true,
+			want:    "dhcp6 relay-fwd (linkaddr=fd00::1 peeraddr=fe80::1 options [RelayMessage])",
+		},
+		{
+			name:  "unknown",
+			layer: &layers.DHCPv6{MsgType: 99},
+			want:  "dhcp6 type 99",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := dhcpv6Data(tt.layer, tt.verbose); got != tt.want {
+				t.Errorf("dhcpv6Data() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
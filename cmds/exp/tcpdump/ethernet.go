@@ -7,13 +7,24 @@ package main
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/layers"
 )
 
-func (cmd cmd) ethernetInfo(ethernetLayer gopacket.LinkLayer, networkLayer gopacket.NetworkLayer) string {
+func (cmd cmd) ethernetInfo(ethernetLayer gopacket.LinkLayer, networkLayer gopacket.NetworkLayer, vlans ...*layers.Dot1Q) string {
+	return cmd.linkInfo(ethernetLayer, vlans, networkLayer.NetworkFlow().EndpointType().String())
+}
+
+// linkInfo returns the link-level header for a packet of protocol proto,
+// carried in the given VLAN tags.
+func (cmd cmd) linkInfo(ethernetLayer gopacket.LinkLayer, vlans []*layers.Dot1Q, proto string) string {
 	if !cmd.Opts.Ether {
-		return fmt.Sprintf("%s %s", networkLayer.NetworkFlow().EndpointType(), cmd.Opts.Device)
+		if cmd.Opts.Device == "" {
+			return proto
+		}
+		return fmt.Sprintf("%s %s", proto, cmd.Opts.Device)
 	}
 
 	src, dst := ethernetLayer.LinkFlow().Endpoints()
@@ -25,5 +36,24 @@ func (cmd cmd) ethernetInfo(ethernetLayer gopacket.LinkLayer, networkLayer gopac
 
 	length := len(ethernetLayer.LayerContents()) + len(ethernetLayer.LayerPayload())
 
-	return fmt.Sprintf("%s > %s, ethertype %s, length %d:", src, dstHost, networkLayer.NetworkFlow().EndpointType(), length)
+	if len(vlans) == 0 {
+		return fmt.Sprintf("%s > %s, ethertype %s, length %d:", src, dstHost, proto, length)
+	}
+
+	var tags strings.Builder
+	for _, vlan := range vlans {
+		fmt.Fprintf(&tags, "vlan %d, p %d, ", vlan.VLANIdentifier, vlan.Priority)
+	}
+	return fmt.Sprintf("%s > %s, ethertype 802.1Q, length %d: %sethertype %s:", src, dstHost, length, tags.String(), proto)
+}
+
+// vlanTags returns the 802.1Q tags of the packet, outermost first.
+func vlanTags(packet gopacket.Packet) []*layers.Dot1Q {
+	var vlans []*layers.Dot1Q
+	for _, layer := range packet.Layers() {
+		if vlan, ok := layer.(*layers.Dot1Q); ok {
+			vlans = append(vlans, vlan)
+		}
+	}
+	return vlans
 }
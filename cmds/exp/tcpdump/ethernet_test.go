@@ -6,6 +6,7 @@
 package main
 
 import (
+	"net"
 	"testing"
 
 	"github.com/gopacket/gopacket"
@@ -18,6 +19,7 @@ func TestEthernetInfo(t *testing.T) {
 		cmd            cmd
 		ethernetLayer  gopacket.LinkLayer
 		networkLayer   gopacket.NetworkLayer
+		vlans          []*layers.Dot1Q
 		expectedOutput string
 	}{
 		{
@@ -88,11 +90,49 @@ func TestEthernetInfo(t *testing.T) {
 			},
 			expectedOutput: "00:11:22:33:44:55 > 66:77:88:99:aa:bb, ethertype IPv4, length 14:",
 		},
+		{
+			name: "Ether option enabled with VLAN tags",
+			cmd: cmd{
+				Opts: flags{
+					Ether:  true,
+					Device: "eth0",
+				},
+			},
+			ethernetLayer: &layers.Ethernet{
+				SrcMAC:       []byte{0x00, 0x11, 0x22, 0x33, 0x44, 0x55},
+				DstMAC:       []byte{0x66, 0x77, 0x88, 0x99, 0xAA, 0xBB},
+				EthernetType: layers.EthernetTypeQinQ,
+				BaseLayer: layers.BaseLayer{
+					Contents: make([]byte, 14),
+					Payload:  make([]byte, 8),
+				},
+			},
+			networkLayer: &layers.IPv4{
+				SrcIP:    []byte{192, 168, 0, 1},
+				DstIP:    []byte{192, 168, 0, 2},
+				Protocol: layers.IPProtocolTCP,
+			},
+			vlans:          []*layers.Dot1Q{{VLANIdentifier: 100}, {VLANIdentifier: 10, Priority: 3}},
+			expectedOutput: "00:11:22:33:44:55 > 66:77:88:99:aa:bb, ethertype 802.1Q, length 22: vlan 100, p 0, vlan 10, p 3, ethertype IPv4:",
+		},
+		{
+			name: "Ether option disabled without device",
+			ethernetLayer: &layers.Ethernet{
+				SrcMAC:       []byte{0x00, 0x11, 0x22, 0x33, 0x44, 0x55},
+				DstMAC:       []byte{0x66, 0x77, 0x88, 0x99, 0xAA, 0xBB},
+				EthernetType: layers.EthernetTypeIPv6,
+			},
+			networkLayer: &layers.IPv6{
+				SrcIP: net.ParseIP("fe80::1"),
+				DstIP: net.ParseIP("fe80::2"),
+			},
+			expectedOutput: "IPv6",
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := tt.cmd.ethernetInfo(tt.ethernetLayer, tt.networkLayer)
+			result := tt.cmd.ethernetInfo(tt.ethernetLayer, tt.networkLayer, tt.vlans...)
 			if result != tt.expectedOutput {
 				t.Errorf("ethernetInfo() = %v, want %v", result, tt.expectedOutput)
 			}
@@ -0,0 +1,52 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//go:build !tinygo || tinygo.enable
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gopacket/gopacket"
+	"github.com/packetcap/go-pcap/filter"
+	"golang.org/x/net/bpf"
+)
+
+// filterSource drops the packets of src that the BPF program vm rejects.
+// Live captures leave filtering to the kernel, this is for savefiles.
+type filterSource struct {
+	src gopacket.PacketDataSource
+	vm  *bpf.VM
+}
+
+// newFilterSource compiles the filter expression expr, which may be empty.
+func newFilterSource(src gopacket.PacketDataSource, expr string) (gopacket.PacketDataSource, error) {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return src, nil
+	}
+	insns, err := filter.NewExpression(expr).Compile().Compile()
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile filter into instructions: %w", err)
+	}
+	vm, err := bpf.NewVM(insns)
+	if err != nil {
+		return nil, fmt.Errorf("bpf assembly failed: %w", err)
+	}
+	return &filterSource{src: src, vm: vm}, nil
+}
+
+// ReadPacketData implements gopacket.PacketDataSource.
+func (f *filterSource) ReadPacketData() ([]byte, gopacket.CaptureInfo, error) {
+	for {
+		data, ci, err := f.src.ReadPacketData()
+		if err != nil {
+			return data, ci, err
+		}
+		if n, err := f.vm.Run(data); err == nil && n > 0 {
+			return data, ci, nil
+		}
+	}
+}
@@ -6,7 +6,10 @@
 package main
 
 import (
+	"encoding/binary"
 	"fmt"
+	"net"
+	"strings"
 
 	"github.com/gopacket/gopacket"
 
@@ -31,3 +34,95 @@ func parseICMP(packet gopacket.Packet) string {
 
 	return ""
 }
+
+// ndpData returns a string representation of ICMPv6 neighbor discovery
+// messages, or "" for other packets. Options are only shown if verbose is set.
+func ndpData(packet gopacket.Packet, verbose bool) string {
+	icmpv6, ok := packet.Layer(layers.LayerTypeICMPv6).(*layers.ICMPv6)
+	if !ok {
+		return ""
+	}
+	var (
+		data string
+		opts layers.ICMPv6Options
+	)
+	switch layer := packet.Layer(icmpv6.NextLayerType()).(type) {
+	case *layers.ICMPv6RouterSolicitation:
+		opts = layer.Options
+	case *layers.ICMPv6RouterAdvertisement:
+		opts = layer.Options
+		if verbose {
+			var flags []string
+			if layer.ManagedAddressConfig() {
+				flags = append(flags, "managed")
+			}
+			if layer.OtherConfig() {
+				flags = append(flags, "other stateful")
+			}
+			data = fmt.Sprintf(", hop limit %d, Flags [%s], router lifetime %ds, reachable time %dms, retrans timer %dms",
+				layer.HopLimit, strings.Join(flags, ", "), layer.RouterLifetime, layer.ReachableTime, layer.RetransTimer)
+		}
+	case *layers.ICMPv6NeighborSolicitation:
+		opts = layer.Options
+		data = fmt.Sprintf(", who has %s", layer.TargetAddress)
+	case *layers.ICMPv6NeighborAdvertisement:
+		opts = layer.Options
+		var flags []string
+		if layer.Router() {
+			flags = append(flags, "router")
+		}
+		if layer.Solicited() {
+			flags = append(flags, "solicited")
+		}
+		if layer.Override() {
+			flags = append(flags, "override")
+		}
+		data = fmt.Sprintf(", tgt is %s, Flags [%s]", layer.TargetAddress, strings.Join(flags, ", "))
+	case *layers.ICMPv6Redirect:
+		opts = layer.Options
+		data = fmt.Sprintf(", %s to %s", layer.DestinationAddress, layer.TargetAddress)
+	default:
+		return ""
+	}
+
+	if verbose && len(opts) > 0 {
+		var o []string
+		for _, opt := range opts {
+			o = append(o, ndpOption(opt))
+		}
+		data += fmt.Sprintf(", options [%s]", strings.Join(o, ", "))
+	}
+
+	return fmt.Sprintf("ICMP6 %s%s, length %d", icmpv6.TypeCode, data, len(icmpv6.Contents)+len(icmpv6.Payload))
+}
+
+// ndpRDNSS is the recursive DNS server option of RFC 8106, which gopacket
+// does not name.
+const ndpRDNSS = 25
+
+// ndpOption returns a string representation of a neighbor discovery option.
+func ndpOption(opt layers.ICMPv6Option) string {
+	switch {
+	case (opt.Type == layers.ICMPv6OptSourceAddress || opt.Type == layers.ICMPv6OptTargetAddress) && len(opt.Data) >= 6:
+		if opt.Type == layers.ICMPv6OptSourceAddress {
+			return fmt.Sprintf("source link-address %s", net.HardwareAddr(opt.Data[:6]))
+		}
+		return fmt.Sprintf("target link-address %s", net.HardwareAddr(opt.Data[:6]))
+	case opt.Type == layers.ICMPv6OptPrefixInfo && len(opt.Data) == 30:
+		var flags []string
+		if opt.Data[1]&0x80 != 0 {
+			flags = append(flags, "onlink")
+		}
+		if opt.Data[1]&0x40 != 0 {
+			flags = append(flags, "auto")
+		}
+		return fmt.Sprintf("prefix %s/%d Flags [%s] valid %ds pref %ds", net.IP(opt.Data[14:]), opt.Data[0], strings.Join(flags, ", "),
+			binary.BigEndian.Uint32(opt.Data[2:]), binary.BigEndian.Uint32(opt.Data[6:]))
+	case opt.Type == layers.ICMPv6OptMTU && len(opt.Data) == 6:
+		return fmt.Sprintf("mtu %d", binary.BigEndian.Uint32(opt.Data[2:]))
+	case opt.Type == ndpRDNSS && len(opt.Data) >= 6:
+		return fmt.Sprintf("rdnss %s lifetime %ds", ipList(opt.Data[6:], net.IPv6len), binary.BigEndian.Uint32(opt.Data[2:]))
+	default:
+		return fmt.Sprintf("option %d", opt.Type)
+	}
+}
@@ -6,6 +6,7 @@
 package main
 
 import (
+	"net"
 	"testing"
 
 	"github.com/gopacket/gopacket"
@@ -63,3 +64,69 @@ func TestParseICMP(t *testing.T) {
 		})
 	}
 }
+
+func TestNDPData(t *testing.T) {
+	// gopacket serializes the options in reverse.
+	ra := serialize(
+		&layers.ICMPv6{TypeCode: layers.CreateICMPv6TypeCode(layers.ICMPv6TypeRouterAdvertisement, 0)},
+		&layers.ICMPv6RouterAdvertisement{
+			HopLimit:       64,
+			Flags:          0xc0,
+			RouterLifetime: 1800,
+			Options: layers.ICMPv6Options{
+				{Type: layers.ICMPv6OptMTU, Data: []byte{0, 0, 0, 0, 0x05, 0xdc}},
+				{Type: layers.ICMPv6OptPrefixInfo, Data: append([]byte{64, 0xc0, 0, 0, 0x0e, 0x10, 0, 0, 0x07, 0x08, 0, 0, 0, 0}, net.ParseIP("fd00::")...)},
+				{Type: ndpRDNSS, Data: append([]byte{0, 0, 0, 0, 0, 60}, net.ParseIP("fd00::53")...)},
+				{Type: 31, Data: make([]byte, 6)},
+			},
+		})
+	na := serialize(
+		&layers.ICMPv6{TypeCode: layers.CreateICMPv6TypeCode(layers.ICMPv6TypeNeighborAdvertisement, 0)},
+		&layers.ICMPv6NeighborAdvertisement{
+			Flags:         0xe0,
+			TargetAddress: net.ParseIP("fe80::1"),
+			Options:       layers.ICMPv6Options{{Type: layers.ICMPv6OptTargetAddress, Data: []byte{0x52, 0x54, 0x00, 0x12, 0x34, 0x56}}},
+		})
+
+	for _, tt := range []struct {
+		name    string
+		data    []byte
+		verbose bool
+		want    string
+	}{
+		{
+			name: "router advertisement",
+			data: ra,
+			want: "ICMP6 RouterAdvertisement, length 88",
+		},
+		{
+			name:    "router advertisement verbose",
+			data:    ra,
+			verbose: true,
+			want: "ICMP6 RouterAdvertisement, hop limit 64, Flags [managed, other stateful], router lifetime 1800s, reachable time 0ms, retrans timer 0ms, " +
+				"options [option 31, rdnss fd00::53 lifetime 60s, prefix fd00::/64 Flags [onlink, auto] valid 3600s pref 1800s, mtu 1500], length 88",
+		},
+		{
+			name: "neighbor advertisement",
+			data: na,
+			want: "ICMP6 NeighborAdvertisement, tgt is fe80::1, Flags [router, solicited, override], length 32",
+		},
+		{
+			name:    "neighbor advertisement verbose",
+			data:    na,
+			verbose: true,
+			want:    "ICMP6 NeighborAdvertisement, tgt is fe80::1, Flags [router, solicited, override], options [target link-address 52:54:00:12:34:56], length 32",
+		},
+		{
+			name: "echo request",
+			data: []byte{0x80, 0x00, 0x4d, 0x3d, 0x1c, 0x46, 0x00, 0x01},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			packet := gopacket.NewPacket(tt.data, layers.LayerTypeICMPv6, gopacket.Default)
+			if got := ndpData(packet, tt.verbose); got != tt.want {
+				t.Errorf("ndpData() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
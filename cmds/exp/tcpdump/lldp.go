@@ -0,0 +1,110 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//go:build !tinygo || tinygo.enable
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"unicode"
+
+	"github.com/gopacket/gopacket/layers"
+)
+
+// lldpData returns a string representation of the LLDP layers. info may be
+// nil.
+func lldpData(layer *layers.LinkLayerDiscovery, info *layers.LinkLayerDiscoveryInfo, verbose bool) string {
+	var chassis, port string
+	switch layer.ChassisID.Subtype {
+	case layers.LLDPChassisIDSubTypeMACAddr:
+		chassis = net.HardwareAddr(layer.ChassisID.ID).String()
+	case layers.LLDPChassisIDSubTypeNetworkAddr:
+		chassis = lldpAddr(layer.ChassisID.ID)
+	default:
+		chassis = lldpString(layer.ChassisID.ID)
+	}
+	switch layer.PortID.Subtype {
+	case layers.LLDPPortIDSubtypeMACAddr:
+		port = net.HardwareAddr(layer.PortID.ID).String()
+	case layers.LLDPPortIDSubtypeNetworkAddr:
+		port = lldpAddr(layer.PortID.ID)
+	default:
+		port = lldpString(layer.PortID.ID)
+	}
+
+	data := fmt.Sprintf("chassis-id %s, port-id %s, ttl %ds", chassis, port, layer.TTL)
+	if info != nil {
+		if info.SysName != "" {
+			data += ", system-name " + lldpString([]byte(info.SysName))
+		}
+		if verbose {
+			if info.PortDescription != "" {
+				data += ", port-descr " + lldpString([]byte(info.PortDescription))
+			}
+			if info.SysDescription != "" {
+				data += ", system-descr " + lldpString([]byte(info.SysDescription))
+			}
+			if len(info.MgmtAddress.Address) > 0 {
+				data += ", mgmt-addr " + lldpAddr(append([]byte{byte(info.MgmtAddress.Subtype)}, info.MgmtAddress.Address...))
+			}
+			if caps := lldpCapabilities(info.SysCapabilities.EnabledCap); caps != "" {
+				data += fmt.Sprintf(", caps [%s]", caps)
+			}
+		}
+	}
+	return fmt.Sprintf("%s, length %d", data, len(layer.Contents))
+}
+
+// lldpAddr formats an address prefixed by its IANA address family.
+func lldpAddr(b []byte) string {
+	if len(b) == 0 {
+		return ""
+	}
+	switch layers.IANAAddressFamily(b[0]) {
+	case layers.IANAAddressFamilyIPV4, layers.IANAAddressFamilyIPV6:
+		if ip := net.IP(b[1:]); len(ip) == net.IPv4len || len(ip) == net.IPv6len {
+			return ip.String()
+		}
+	case layers.IANAAddressFamily802:
+		return net.HardwareAddr(b[1:]).String()
+	}
+	return fmt.Sprintf("%x", b)
+}
+
+// lldpString returns b as a string if it is printable, else in hex.
+func lldpString(b []byte) string {
+	s := string(b)
+	if strings.IndexFunc(s, func(r rune) bool { return !unicode.IsPrint(r) }) >= 0 {
+		return fmt.Sprintf("%x", b)
+	}
+	return s
+}
+
+// lldpCapabilities returns the names of the set capabilities.
+func lldpCapabilities(c layers.LLDPCapabilities) string {
+	var caps []string
+	for _, capability := range []struct {
+		set  bool
+		name string
+	}{
+		{c.Other, "Other"},
+		{c.Repeater, "Repeater"},
+		{c.Bridge, "Bridge"},
+		{c.WLANAP, "WLAN AP"},
+		{c.Router, "Router"},
+		{c.Phone, "Telephone"},
+		{c.DocSis, "Docsis"},
+		{c.StationOnly, "Station Only"},
+		{c.CVLAN, "C-VLAN"},
+		{c.SVLAN, "S-VLAN"},
+		{c.TMPR, "TPMR"},
+	} {
+		if capability.set {
+			caps = append(caps, capability.name)
+		}
+	}
+	return strings.Join(caps, ", ")
+}
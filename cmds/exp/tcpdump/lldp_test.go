@@ -0,0 +1,57 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//go:build !tinygo || tinygo.enable
+
+package main
+
+import (
+	"testing"
+
+	"github.com/gopacket/gopacket/layers"
+)
+
+func TestLLDPData(t *testing.T) {
+	layer := &layers.LinkLayerDiscovery{
+		BaseLayer: layers.BaseLayer{Contents: make([]byte, 64)},
+		ChassisID: layers.LLDPChassisID{Subtype: layers.LLDPChassisIDSubTypeNetworkAddr, ID: []byte{1, 192, 168, 0, 1}},
+		PortID:    layers.LLDPPortID{Subtype: layers.LLDPPortIDSubtypeLocal, ID: []byte{0, 7}},
+		TTL:       30,
+	}
+	info := &layers.LinkLayerDiscoveryInfo{
+		PortDescription: "uplink",
+		SysName:         "sw1",
+		SysDescription:  "Switch OS 1.0",
+		SysCapabilities: layers.LLDPSysCapabilities{EnabledCap: layers.LLDPCapabilities{Bridge: true, Router: true}},
+		MgmtAddress:     layers.LLDPMgmtAddress{Subtype: layers.IANAAddressFamilyIPV6, Address: []byte{0xfd, 0, 14: 0, 15: 1}},
+	}
+
+	for _, tt := range []struct {
+		name    string
+		info    *layers.LinkLayerDiscoveryInfo
+		verbose bool
+		want    string
+	}{
+		{
+			name: "no info",
+			want: "chassis-id 192.168.0.1, port-id 0007, ttl 30s, length 64",
+		},
+		{
+			name: "info",
+			info: info,
+			want: "chassis-id 192.168.0.1, port-id 0007, ttl 30s, system-name sw1, length 64",
+		},
+		{
+			name:    "verbose",
+			info:    info,
+			verbose: true,
+			want:    "chassis-id 192.168.0.1, port-id 0007, ttl 30s, system-name sw1, port-descr uplink, system-descr Switch OS 1.0, mgmt-addr fd00::1, caps [Bridge, Router], length 64",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lldpData(layer, tt.info, tt.verbose); got != tt.want {
+				t.Errorf("lldpData() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
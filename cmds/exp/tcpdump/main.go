@@ -53,10 +53,15 @@ type flags struct {
 	FilterFile             string
 	TimeStampInNanoSeconds bool
 	IcmpOnly               bool
+	ReadFile               string
+	WriteFile              string
+	FileSize               int
+	RotateSeconds          int
 }
 
 const tcpdumpHelp = `       tcpdump [ -ADehnpqtvx# ] [ -icmp ]
-                [ -c count ] [ --count ] [ -F file ][ -i interface ]
+                [ -c count ] [ --count ] [ -C file_size ] [ -F file ]
+			    [ -G rotate_seconds ] [ -i interface ] [ -r file ] [ -w file ]
 			    [ --number ] [ --print ] [ -s snaplen ] [ --nano ] 
 				[ EXPRESSION ]
 	EXPRESSION := [ EXPRESSION ] [ and ] [ or ] [ not ] 
@@ -98,6 +103,10 @@ func parseFlags(args []string, out io.Writer) (cmd, error) {
 	fs.BoolVar(&opts.ASCII, "A", false, "Print each packet (minus its link level header) in ASCII.  Handy for capturing web pages")
 	fs.BoolVar(&opts.Quiet, "q", false, "Quiet output. Print less protocol information so output lines are shorter")
 	fs.BoolVar(&opts.Verbose, "v", false, "When parsing and printing, produce (slightly more) verbose output.  For example, the time to live, identification, total length and options in an IP packet are printed.  Also enables additional packet integrity checks such as verifying the IP and ICMP header checksum")
+	fs.StringVar(&opts.ReadFile, "r", "", "Read packets from file, in pcap or pcapng format, rather than from a network interface")
+	fs.StringVar(&opts.WriteFile, "w", "", "Write the raw packets to file rather than parsing and printing them out. A name ending in .pcapng selects the pcapng format, pcap is written otherwise")
+	fs.IntVar(&opts.FileSize, "C", 0, "Before writing a raw packet to a savefile, check whether the file is currently larger than file_size (in millions of bytes) and, if so, close the current savefile and open a new one, named with a count appended")
+	fs.IntVar(&opts.RotateSeconds, "G", 0, "Rotate the savefile specified with -w every rotate_seconds seconds. The file name may contain strftime(3) conversions such as %Y%m%d-%H%M%S")
 	fs.BoolVar(&opts.Verbose, "verbose", false, "When parsing and printing, produce (slightly more) verbose output.  For example, the time to live, identification, total length and options in an IP packet are printed.  Also enables additional packet integrity checks such as verifying the IP and ICMP header checksum")
 
 	fs.Usage = func() {
@@ -113,6 +122,14 @@ func parseFlags(args []string, out io.Writer) (cmd, error) {
 		return cmd{}, fmt.Errorf("cannot use both -v and -q flags")
 	}
 
+	if opts.FileSize < 0 || opts.RotateSeconds < 0 {
+		return cmd{}, fmt.Errorf("file size and rotation interval cannot be negative")
+	}
+
+	if (opts.FileSize > 0 || opts.RotateSeconds > 0) && opts.WriteFile == "" {
+		return cmd{}, fmt.Errorf("-C and -G require -w")
+	}
+
 	var filter strings.Builder
 	if fs.NArg() > 0 {
 		for _, arg := range fs.Args() {
@@ -141,11 +158,6 @@ type cmd struct {
 }
 
 func (cmd *cmd) run() error {
-	var (
-		src *pcap.Handle
-		err error
-	)
-
 	if cmd.Opts.Help {
 		cmd.usage()
 
@@ -156,7 +168,7 @@ func (cmd *cmd) run() error {
 		return listDevices()
 	}
 
-	if cmd.Opts.Device == "" {
+	if cmd.Opts.Device == "" && cmd.Opts.ReadFile == "" {
 		return fmt.Errorf("no device specified")
 	}
 
@@ -172,24 +184,73 @@ func (cmd *cmd) run() error {
 		cancel()
 	}()
 
-	if src, err = pcap.OpenLive(cmd.Opts.Device, int32(cmd.Opts.SnapshotLength), !cmd.Opts.NoPromisc, 0, false); err != nil {
-		if strings.Contains(err.Error(), "operation not permitted") {
-			return fmt.Errorf("you don't have permission to capture on that/these device(s)")
+	var (
+		src      gopacket.PacketDataSource
+		linkType layers.LinkType
+	)
+
+	if cmd.Opts.ReadFile != "" {
+		f, err := os.Open(cmd.Opts.ReadFile)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		r, err := newSavefileReader(f)
+		if err != nil {
+			return fmt.Errorf("%s: %w", cmd.Opts.ReadFile, err)
+		}
+
+		if src, err = newFilterSource(r, cmd.Opts.Filter); err != nil {
+			return err
+		}
+		linkType = r.linkType
+
+		fmt.Fprintf(cmd.Out, "reading from file %s, link-type %s, snapshot length %d\n", cmd.Opts.ReadFile, linkType, r.snaplen)
+	} else {
+		handle, err := pcap.OpenLive(cmd.Opts.Device, int32(cmd.Opts.SnapshotLength), !cmd.Opts.NoPromisc, 0, false)
+		if err != nil {
+			if strings.Contains(err.Error(), "operation not permitted") {
+				return fmt.Errorf("you don't have permission to capture on that/these device(s)")
+			}
+
+			return err
+
 		}
+		defer handle.Close()
 
-		return err
+		if err := handle.SetBPFFilter(cmd.Opts.Filter); err != nil {
+			return err
+		}
+		src, linkType = handle, layers.LinkTypeEthernet
 
+		fmt.Fprintf(cmd.Out, "tcpdump: verbose output suppressed, use -v for full protocol decode\nlistening on %s, link-type %d, snapshot length %d bytes\n", cmd.Opts.Device, handle.LinkType(), cmd.Opts.SnapshotLength)
 	}
-	defer src.Close()
 
-	if err := src.SetBPFFilter(cmd.Opts.Filter); err != nil {
-		return err
+	var w *rotator
+	if cmd.Opts.WriteFile != "" {
+		var err error
+		if w, err = cmd.newRotator(linkType); err != nil {
+			return err
+		}
 	}
 
-	packetSource := gopacket.NewPacketSource(src, layers.LinkTypeEthernet)
-	packetSource.NoCopy = true
+	err := cmd.capture(ctx, src, linkType, w)
+	if w != nil {
+		if cerr := w.close(); err == nil {
+			err = cerr
+		}
+	}
 
-	fmt.Fprintf(cmd.Out, "tcpdump: verbose output suppressed, use -v for full protocol decode\nlistening on %s, link-type %d, snapshot length %d bytes\n", cmd.Opts.Device, src.LinkType(), cmd.Opts.SnapshotLength)
+	return err
+}
+
+// capture reads packets from src and prints them, or writes them to w if it
+// is not nil. It returns when src is exhausted, the packet count is reached
+// or ctx is done.
+func (cmd *cmd) capture(ctx context.Context, src gopacket.PacketDataSource, linkType layers.LinkType, w *rotator) error {
+	packetSource := gopacket.NewPacketSource(src, linkType)
+	packetSource.NoCopy = true
 
 	var (
 		capturedPackets int
@@ -199,26 +260,62 @@ func (cmd *cmd) run() error {
 		timeStamp = time.Now()
 	}
 
+	// handle reports whether the packet count has been reached.
+	handle := func(packet gopacket.Packet) (bool, error) {
+		capturedPackets++
+
+		switch {
+		case w != nil:
+			if err := w.writePacket(packet.Metadata().CaptureInfo, packet.Data()); err != nil {
+				return true, err
+			}
+		case !cmd.Opts.Count:
+			pkgTime := cmd.processPacket(packet, capturedPackets, timeStamp)
+
+			if cmd.Opts.TTT {
+				timeStamp = pkgTime
+			}
+		}
+
+		return cmd.Opts.CountPkg > 0 && capturedPackets >= cmd.Opts.CountPkg, nil
+	}
+
+	// Savefiles are read until they end, without waiting for packets.
+	if cmd.Opts.ReadFile != "" {
+		for ctx.Err() == nil {
+			packet, err := packetSource.NextPacket()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return fmt.Errorf("%s: %w", cmd.Opts.ReadFile, err)
+			}
+			if done, err := handle(packet); done || err != nil {
+				return err
+			}
+		}
+
+		if cmd.Opts.Count {
+			fmt.Fprintf(cmd.Out, "%d packets\n", capturedPackets)
+		}
+
+		return nil
+	}
+
 	for {
 		select {
 		case <-ctx.Done():
 			fmt.Fprintf(cmd.Out, "\n%d packets captured\n", capturedPackets)
 
 			return nil
-		case packet := <-packetSource.PacketsCtx(ctx):
-			capturedPackets++
-			if cmd.Opts.CountPkg > 0 && capturedPackets >= cmd.Opts.CountPkg {
+		case packet, ok := <-packetSource.PacketsCtx(ctx):
+			if !ok {
 				return nil
 			}
 
-			if !cmd.Opts.Count {
-				pkgTime := cmd.processPacket(packet, capturedPackets, timeStamp)
-
-				if cmd.Opts.TTT {
-					timeStamp = pkgTime
-				}
+			if done, err := handle(packet); done || err != nil {
+				return err
 			}
-
 		}
 	}
 }
@@ -257,10 +354,10 @@ func (cmd *cmd) processPacket(packet gopacket.Packet, num int, lastPkgTimeStamp
 	networkLayer := packet.NetworkLayer()
 
 	if networkLayer == nil {
-		return lastPkgTimeStamp
+		return cmd.processLinkPacket(packet, ethernetLayer, no, lastPkgTimeStamp)
 	}
 
-	etherInfo := cmd.ethernetInfo(ethernetLayer, networkLayer)
+	etherInfo := cmd.ethernetInfo(ethernetLayer, networkLayer, vlanTags(packet)...)
 
 	if cmd.Opts.Verbose {
 		switch layer := networkLayer.(type) {
@@ -292,6 +389,9 @@ func (cmd *cmd) processPacket(packet gopacket.Packet, num int, lastPkgTimeStamp
 	}
 
 	data := parseICMP(packet)
+	if ndp := ndpData(packet, cmd.Opts.Verbose); ndp != "" {
+		data = ndp
+	}
 
 	if cmd.Opts.IcmpOnly && data == "" {
 		return lastPkgTimeStamp
@@ -327,7 +427,7 @@ func (cmd *cmd) processPacket(packet gopacket.Packet, num int, lastPkgTimeStamp
 		case *layers.TCP:
 			data = tcpData(layer, length, cmd.Opts.Verbose, cmd.Opts.Quiet)
 		case *layers.UDP:
-			data = fmt.Sprintf("UDP, length %d", length)
+			data = udpData(packet, layer, cmd.Opts.Verbose, cmd.Opts.Quiet)
 		case *layers.UDPLite:
 			data = fmt.Sprintf("UDPLite, length %d", length)
 		case nil:
@@ -351,6 +451,46 @@ func (cmd *cmd) processPacket(packet gopacket.Packet, num int, lastPkgTimeStamp
 		dstPort,
 		data)
 
+	cmd.printContents(packet, applicationLayer)
+
+	return pkgTimeStamp
+}
+
+// processLinkPacket prints packets without a network layer, such as ARP and
+// LLDP. A timestamp of the packet is returned.
+func (cmd *cmd) processLinkPacket(packet gopacket.Packet, ethernetLayer gopacket.LinkLayer, no string, lastPkgTimeStamp time.Time) time.Time {
+	var proto, data string
+
+	switch {
+	case packet.Layer(layers.LayerTypeARP) != nil:
+		proto, data = "ARP", arpData(packet.Layer(layers.LayerTypeARP).(*layers.ARP))
+	case packet.Layer(layers.LayerTypeLinkLayerDiscovery) != nil:
+		info, _ := packet.Layer(layers.LayerTypeLinkLayerDiscoveryInfo).(*layers.LinkLayerDiscoveryInfo)
+		proto, data = "LLDP", lldpData(packet.Layer(layers.LayerTypeLinkLayerDiscovery).(*layers.LinkLayerDiscovery), info, cmd.Opts.Verbose)
+	default:
+		return lastPkgTimeStamp
+	}
+
+	if cmd.Opts.IcmpOnly {
+		return lastPkgTimeStamp
+	}
+
+	pkgTimeStamp := packet.Metadata().Timestamp
+
+	fmt.Fprintf(cmd.Out, "%s%s %s %s\n",
+		no,
+		cmd.parseTimeStamp(pkgTimeStamp, lastPkgTimeStamp),
+		cmd.linkInfo(ethernetLayer, vlanTags(packet), proto),
+		data)
+
+	cmd.printContents(packet, nil)
+
+	return pkgTimeStamp
+}
+
+// printContents prints the packet contents as requested by the -A, -x and -xx
+// flags.
+func (cmd *cmd) printContents(packet gopacket.Packet, applicationLayer gopacket.ApplicationLayer) {
 	switch {
 	case cmd.Opts.ASCII:
 		content := []byte("")
@@ -363,8 +503,6 @@ func (cmd *cmd) processPacket(packet gopacket.Packet, num int, lastPkgTimeStamp
 	case cmd.Opts.DataWithHeader:
 		fmt.Fprintf(cmd.Out, "%s\n", formatPacketData(packet.Data()))
 	}
-
-	return pkgTimeStamp
 }
 
 func main() {
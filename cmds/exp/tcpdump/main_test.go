@@ -7,7 +7,10 @@ package main
 
 import (
 	"bytes"
+	"net"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -75,6 +78,30 @@ func TestParseFlags(t *testing.T) {
 			args:        []string{"cmd", "-F", "xyz"},
 			expectedErr: true,
 		},
+		{
+			name: "write with rotation",
+			args: []string{"cmd", "-i", "eth0", "-w", "dump-%H%M.pcap", "-C", "10", "-G", "60", "port", "67"},
+			expectedCmd: cmd{
+				Opts: flags{
+					SnapshotLength: 262144,
+					Device:         "eth0",
+					WriteFile:      "dump-%H%M.pcap",
+					FileSize:       10,
+					RotateSeconds:  60,
+					Filter:         "port 67 ",
+				},
+			},
+		},
+		{
+			name:        "rotation without write",
+			args:        []string{"cmd", "-r", "in.pcap", "-C", "10"},
+			expectedErr: true,
+		},
+		{
+			name:        "negative rotation",
+			args:        []string{"cmd", "-w", "out.pcap", "-G", "-1"},
+			expectedErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -94,6 +121,68 @@ func TestParseFlags(t *testing.T) {
 	}
 }
 
+// serialize returns the bytes of a packet made of ls.
+func serialize(ls ...gopacket.SerializableLayer) []byte {
+	buf := gopacket.NewSerializeBuffer()
+	if err := gopacket.SerializeLayers(buf, gopacket.SerializeOptions{FixLengths: true}, ls...); err != nil {
+		panic(err)
+	}
+	return buf.Bytes()
+}
+
+var (
+	mac0 = net.HardwareAddr{0x52, 0x54, 0x00, 0x12, 0x34, 0x56}
+	mac1 = net.HardwareAddr{0x52, 0x54, 0x00, 0x12, 0x34, 0x57}
+
+	arpRequest = serialize(
+		&layers.Ethernet{SrcMAC: mac0, DstMAC: layers.EthernetBroadcast, EthernetType: layers.EthernetTypeARP},
+		&layers.ARP{
+			AddrType: layers.LinkTypeEthernet, Protocol: layers.EthernetTypeIPv4, HwAddressSize: 6, ProtAddressSize: 4,
+			Operation: layers.ARPRequest, SourceHwAddress: mac0, SourceProtAddress: []byte{192, 168, 0, 2},
+			DstHwAddress: make([]byte, 6), DstProtAddress: []byte{192, 168, 0, 1},
+		})
+
+	lldpFrame = serialize(
+		&layers.Ethernet{SrcMAC: mac1, DstMAC: net.HardwareAddr{0x01, 0x80, 0xc2, 0x00, 0x00, 0x0e}, EthernetType: layers.EthernetTypeLinkLayerDiscovery},
+		&layers.LinkLayerDiscovery{
+			ChassisID: layers.LLDPChassisID{Subtype: layers.LLDPChassisIDSubTypeMACAddr, ID: mac1},
+			PortID:    layers.LLDPPortID{Subtype: layers.LLDPPortIDSubtypeIfaceName, ID: []byte("swp7")},
+			TTL:       120,
+			Values: []layers.LinkLayerDiscoveryValue{
+				{Type: layers.LLDPTLVSysName, Length: 7, Value: []byte("switch1")},
+			},
+		})
+
+	dhcpDiscover = serialize(
+		&layers.Ethernet{SrcMAC: mac0, DstMAC: layers.EthernetBroadcast, EthernetType: layers.EthernetTypeIPv4},
+		&layers.IPv4{Version: 4, IHL: 5, TTL: 64, Protocol: layers.IPProtocolUDP, SrcIP: net.IPv4zero, DstIP: net.IPv4bcast},
+		&layers.UDP{SrcPort: 68, DstPort: 67},
+		&layers.DHCPv4{
+			Operation: layers.DHCPOpRequest, HardwareType: layers.LinkTypeEthernet, HardwareLen: 6, Xid: 0x1234, Flags: 0x8000,
+			ClientHWAddr: mac0,
+			Options: layers.DHCPOptions{
+				layers.NewDHCPOption(layers.DHCPOptMessageType, []byte{byte(layers.DHCPMsgTypeDiscover)}),
+				layers.NewDHCPOption(layers.DHCPOptHostname, []byte("box")),
+			},
+		})
+
+	neighborSolicitation = serialize(
+		&layers.Ethernet{SrcMAC: mac0, DstMAC: net.HardwareAddr{0x33, 0x33, 0xff, 0x00, 0x00, 0x01}, EthernetType: layers.EthernetTypeIPv6},
+		&layers.IPv6{Version: 6, HopLimit: 255, NextHeader: layers.IPProtocolICMPv6, SrcIP: net.ParseIP("fe80::2"), DstIP: net.ParseIP("ff02::1:ff00:1")},
+		&layers.ICMPv6{TypeCode: layers.CreateICMPv6TypeCode(layers.ICMPv6TypeNeighborSolicitation, 0)},
+		&layers.ICMPv6NeighborSolicitation{
+			TargetAddress: net.ParseIP("fe80::1"),
+			Options:       layers.ICMPv6Options{{Type: layers.ICMPv6OptSourceAddress, Data: mac0}},
+		})
+
+	vlanUDP = serialize(
+		&layers.Ethernet{SrcMAC: mac0, DstMAC: mac1, EthernetType: layers.EthernetTypeDot1Q},
+		&layers.Dot1Q{VLANIdentifier: 10, Priority: 5, Type: layers.EthernetTypeIPv4},
+		&layers.IPv4{Version: 4, IHL: 5, TTL: 64, Protocol: layers.IPProtocolUDP, SrcIP: net.IP{10, 0, 0, 1}, DstIP: net.IP{10, 0, 0, 2}},
+		&layers.UDP{SrcPort: 1000, DstPort: 2000},
+		gopacket.Payload("hello"))
+)
+
 func TestProcessPacket(t *testing.T) {
 	tests := []struct {
 		name             string
@@ -195,6 +284,60 @@ func TestProcessPacket(t *testing.T) {
 			opts:             flags{DataWithHeader: true, Device: "eth0", Numerical: true},
 			expectedOutput:   "00:00:00.000000 IPv4 eth0 192.168.0.104.53 > 192.168.0.1.53: 4660+ A? www.google.com (32)\n0x0000:  001c 4200 0008 001c 4200 0001 0800 4500 \n0x0010:  003c 1c46 4000 4011 b1e6 c0a8 0068 c0a8 \n0x0020:  0001 0035 0035 0028 917c 1234 0100 0001 \n0x0030:  0000 0000 0000 0377 7777 0667 6f6f 676c \n0x0040:  6503 636f 6d00 0001 0001                \n\n",
 		},
+		{
+			name:           "ARP request",
+			packetData:     arpRequest,
+			opts:           flags{Device: "eth0", Numerical: true},
+			expectedOutput: "00:00:00.000000 ARP eth0 Request who-has 192.168.0.1 tell 192.168.0.2, length 28\n",
+		},
+		{
+			name:           "ARP request with link-level header",
+			packetData:     arpRequest,
+			opts:           flags{Device: "eth0", Ether: true},
+			expectedOutput: "00:00:00.000000 52:54:00:12:34:56 > Broadcast, ethertype ARP, length 60: Request who-has 192.168.0.1 tell 192.168.0.2, length 28\n",
+		},
+		{
+			name:           "LLDP",
+			packetData:     lldpFrame,
+			opts:           flags{Numerical: true},
+			expectedOutput: "00:00:00.000000 LLDP chassis-id 52:54:00:12:34:57, port-id swp7, ttl 120s, system-name switch1, length 46\n",
+		},
+		{
+			name:           "DHCP discover",
+			packetData:     dhcpDiscover,
+			opts:           flags{Device: "eth0", Numerical: true},
+			expectedOutput: "00:00:00.000000 IPv4 eth0 0.0.0.0.68 > 255.255.255.255.67: BOOTP/DHCP, Request from 52:54:00:12:34:56, length 249\n",
+		},
+		{
+			name:           "DHCP discover verbose",
+			packetData:     dhcpDiscover,
+			opts:           flags{Device: "eth0", Numerical: true, Verbose: true},
+			expectedOutput: "00:00:00.000000 IPv4 eth0 (tos 0x0, ttl 64, id 0, offset 0, flags [], proto UDP (17), length 277)\n 0.0.0.0.68 > 255.255.255.255.67: BOOTP/DHCP, Request from 52:54:00:12:34:56, xid 0x1234, Flags [Broadcast], DHCP-Message Discover, Hostname \"box\", length 249\n",
+		},
+		{
+			name:           "DHCP discover quiet",
+			packetData:     dhcpDiscover,
+			opts:           flags{Device: "eth0", Numerical: true, Quiet: true},
+			expectedOutput: "00:00:00.000000 IPv4 eth0 0.0.0.0.68 > 255.255.255.255.67: UDP, length 249\n",
+		},
+		{
+			name:           "neighbor solicitation",
+			packetData:     neighborSolicitation,
+			opts:           flags{Device: "eth0", Numerical: true},
+			expectedOutput: "00:00:00.000000 IPv6 eth0 fe80::2. > ff02::1:ff00:1.: ICMP6 NeighborSolicitation, who has fe80::1, length 32\n",
+		},
+		{
+			name:           "neighbor solicitation verbose",
+			packetData:     neighborSolicitation,
+			opts:           flags{Device: "eth0", Numerical: true, Verbose: true},
+			expectedOutput: "00:00:00.000000 IPv6 eth0 (flowlabel 0x0, hlim 255, next-header ICMPv6 (58), payload length: 32)\n fe80::2. > ff02::1:ff00:1.: ICMP6 NeighborSolicitation, who has fe80::1, options [source link-address 52:54:00:12:34:56], length 32\n",
+		},
+		{
+			name:           "VLAN tagged UDP",
+			packetData:     vlanUDP,
+			opts:           flags{Device: "eth0", Numerical: true, Ether: true},
+			expectedOutput: "00:00:00.000000 52:54:00:12:34:56 > 52:54:00:12:34:57, ethertype 802.1Q, length 60: vlan 10, p 5, ethertype IPv4: 10.0.0.1.1000 > 10.0.0.2.2000: UDP, length 5\n",
+		},
 	}
 
 	for _, tt := range tests {
@@ -215,3 +358,61 @@ func TestProcessPacket(t *testing.T) {
 		})
 	}
 }
+
+func TestReadWriteFile(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "in.pcap")
+	f, err := os.Create(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := newPcapWriter(f, 262144, layers.LinkTypeEthernet, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.Local)
+	for i, data := range [][]byte{arpRequest, dhcpDiscover, neighborSolicitation, dhcpDiscover} {
+		ci := gopacket.CaptureInfo{Timestamp: start.Add(time.Duration(i) * time.Second), CaptureLength: len(data), Length: len(data)}
+		if err := w.writePacket(ci, data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	run := func(args ...string) string {
+		t.Helper()
+		var out bytes.Buffer
+		c, err := parseFlags(append([]string{"tcpdump"}, args...), &out)
+		if err != nil {
+			t.Fatalf("parseFlags(%q) = %v", args, err)
+		}
+		if err := c.run(); err != nil {
+			t.Fatalf("run(%q) = %v", args, err)
+		}
+		return out.String()
+	}
+
+	want := "reading from file " + in + ", link-type Ethernet, snapshot length 262144\n" +
+		"12:00:00.000000 ARP Request who-has 192.168.0.1 tell 192.168.0.2, length 28\n" +
+		"12:00:01.000000 IPv4 0.0.0.0.68 > 255.255.255.255.67: BOOTP/DHCP, Request from 52:54:00:12:34:56, length 249\n" +
+		"12:00:02.000000 IPv6 fe80::2. > ff02::1:ff00:1.: ICMP6 NeighborSolicitation, who has fe80::1, length 32\n"
+	if diff := cmp.Diff(want, run("-n", "-c", "3", "-r", in)); diff != "" {
+		t.Errorf("-r mismatch (-want +got):\n%s", diff)
+	}
+
+	// Write the DHCP packets to a pcapng file, and read them back.
+	out := filepath.Join(dir, "out.pcapng")
+	run("-r", in, "-w", out, "udp", "port", "67")
+	want = "reading from file " + out + ", link-type Ethernet, snapshot length 262144\n" +
+		"12:00:01.000000 IPv4 0.0.0.0.68 > 255.255.255.255.67: UDP, length 249\n" +
+		"12:00:03.000000 IPv4 0.0.0.0.68 > 255.255.255.255.67: UDP, length 249\n"
+	if diff := cmp.Diff(want, run("-n", "-q", "-r", out)); diff != "" {
+		t.Errorf("-r of the -w output mismatch (-want +got):\n%s", diff)
+	}
+
+	if got := run("--count", "-r", in, "udp", "port", "67"); !strings.HasSuffix(got, "\n2 packets\n") {
+		t.Errorf("--count printed %q", got)
+	}
+}
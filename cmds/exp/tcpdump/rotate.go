@@ -0,0 +1,176 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//go:build !tinygo || tinygo.enable
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/layers"
+)
+
+// rotator writes packets to savefiles. Like tcpdump with -C, it starts a new
+// file once the current one has grown past size bytes, appending a count to
+// the name. Like tcpdump with -G, it starts a new file every interval, with
+// the time of day formatted into the name by strftime conversions.
+type rotator struct {
+	name     string
+	size     int64
+	interval time.Duration
+
+	// pcapng selects the pcapng format, otherwise pcap is written.
+	pcapng   bool
+	snaplen  int
+	linkType layers.LinkType
+	ifname   string
+	nano     bool
+
+	now func() time.Time
+
+	f      *os.File
+	bw     *bufio.Writer
+	cw     *countingWriter
+	w      packetWriter
+	count  int
+	opened time.Time
+}
+
+// countingWriter counts the bytes written to w.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(b []byte) (int, error) {
+	n, err := c.w.Write(b)
+	c.n += int64(n)
+	return n, err
+}
+
+// newRotator opens the first savefile for the -w, -C and -G options.
+func (cmd *cmd) newRotator(linkType layers.LinkType) (*rotator, error) {
+	r := &rotator{
+		name:     cmd.Opts.WriteFile,
+		size:     int64(cmd.Opts.FileSize) * 1000000,
+		interval: time.Duration(cmd.Opts.RotateSeconds) * time.Second,
+		pcapng:   strings.HasSuffix(cmd.Opts.WriteFile, ".pcapng"),
+		snaplen:  cmd.Opts.SnapshotLength,
+		linkType: linkType,
+		ifname:   cmd.Opts.Device,
+		nano:     cmd.Opts.TimeStampInNanoSeconds,
+		now:      time.Now,
+	}
+	if err := r.open(r.now()); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// fileName returns the name of the current savefile.
+func (r *rotator) fileName() string {
+	name := r.name
+	if r.interval > 0 {
+		name = strftime(name, r.opened)
+	}
+	if r.count > 0 {
+		name += strconv.Itoa(r.count)
+	}
+	return name
+}
+
+func (r *rotator) open(now time.Time) error {
+	r.opened = now
+	f, err := os.Create(r.fileName())
+	if err != nil {
+		return err
+	}
+	r.f = f
+	r.bw = bufio.NewWriter(f)
+	r.cw = &countingWriter{w: r.bw}
+	if r.pcapng {
+		r.w, err = newPcapngWriter(r.cw, r.snaplen, r.linkType, r.ifname, r.nano)
+	} else {
+		r.w, err = newPcapWriter(r.cw, r.snaplen, r.linkType, r.nano)
+	}
+	if err != nil {
+		f.Close()
+		r.f = nil
+	}
+	return err
+}
+
+func (r *rotator) writePacket(ci gopacket.CaptureInfo, data []byte) error {
+	now := r.now()
+	switch {
+	case r.interval > 0 && now.Sub(r.opened) >= r.interval:
+		r.count = 0
+	case r.size > 0 && r.cw.n > r.size:
+		r.count++
+	default:
+		return r.w.writePacket(ci, data)
+	}
+	if err := r.close(); err != nil {
+		return err
+	}
+	if err := r.open(now); err != nil {
+		return err
+	}
+	return r.w.writePacket(ci, data)
+}
+
+// close flushes and closes the current savefile.
+func (r *rotator) close() error {
+	if r.f == nil {
+		return nil
+	}
+	err := r.bw.Flush()
+	if cerr := r.f.Close(); err == nil {
+		err = cerr
+	}
+	r.f = nil
+	return err
+}
+
+// strftime expands the %Y, %m, %d, %H, %M, %S, %s and %% conversions in
+// format.
+func strftime(format string, t time.Time) string {
+	var b strings.Builder
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' || i+1 == len(format) {
+			b.WriteByte(format[i])
+			continue
+		}
+		i++
+		switch format[i] {
+		case 'Y':
+			fmt.Fprintf(&b, "%04d", t.Year())
+		case 'm':
+			fmt.Fprintf(&b, "%02d", t.Month())
+		case 'd':
+			fmt.Fprintf(&b, "%02d", t.Day())
+		case 'H':
+			fmt.Fprintf(&b, "%02d", t.Hour())
+		case 'M':
+			fmt.Fprintf(&b, "%02d", t.Minute())
+		case 'S':
+			fmt.Fprintf(&b, "%02d", t.Second())
+		case 's':
+			fmt.Fprintf(&b, "%d", t.Unix())
+		case '%':
+			b.WriteByte('%')
+		default:
+			b.WriteByte('%')
+			b.WriteByte(format[i])
+		}
+	}
+	return b.String()
+}
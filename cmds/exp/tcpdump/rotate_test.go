@@ -0,0 +1,99 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//go:build !tinygo || tinygo.enable
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/layers"
+)
+
+// countPackets returns the number of packets in each of the savefiles.
+func countPackets(t *testing.T, names ...string) []int {
+	t.Helper()
+	var counts []int
+	for _, name := range names {
+		f, err := os.Open(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, got := readAll(t, f)
+		f.Close()
+		counts = append(counts, len(got))
+	}
+	return counts
+}
+
+func TestRotateSize(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "out.pcapng")
+	r := &rotator{name: name, size: 200, pcapng: true, snaplen: 1500, linkType: layers.LinkTypeEthernet, now: time.Now}
+	if err := r.open(r.now()); err != nil {
+		t.Fatal(err)
+	}
+	ci := gopacket.CaptureInfo{Timestamp: time.Now()}
+	for range 5 {
+		if err := r.writePacket(ci, make([]byte, 60)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := r.close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Headers take 60 bytes and packets 92 bytes, so the first file is
+	// 244 bytes after 2 packets.
+	got := countPackets(t, name, name+"1", name+"2")
+	if got[0] != 2 || got[1] != 2 || got[2] != 1 {
+		t.Errorf("packets per file = %v, want [2 2 1]", got)
+	}
+}
+
+func TestRotateInterval(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.Local)
+	r := &rotator{
+		name:     filepath.Join(dir, "%Y%m%d-%H%M%S.pcap"),
+		interval: time.Minute,
+		snaplen:  1500,
+		linkType: layers.LinkTypeEthernet,
+		now:      func() time.Time { return now },
+	}
+	if err := r.open(r.now()); err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range []time.Duration{0, 30 * time.Second, 30 * time.Second, time.Second} {
+		now = now.Add(d)
+		if err := r.writePacket(gopacket.CaptureInfo{Timestamp: now}, []byte{1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := r.close(); err != nil {
+		t.Fatal(err)
+	}
+
+	got := countPackets(t, filepath.Join(dir, "20260102-030405.pcap"), filepath.Join(dir, "20260102-030505.pcap"))
+	if got[0] != 2 || got[1] != 2 {
+		t.Errorf("packets per file = %v, want [2 2]", got)
+	}
+}
+
+func TestStrftime(t *testing.T) {
+	tm := time.Date(2026, 10, 9, 8, 7, 6, 0, time.UTC)
+	for format, want := range map[string]string{
+		"dump":                 "dump",
+		"dump-%Y-%m-%d_%H%M%S": "dump-2026-10-09_080706",
+		"%s.pcap":              "1791533226.pcap",
+		"100%%-%x%":            "100%-%x%",
+	} {
+		if got := strftime(format, tm); got != want {
+			t.Errorf("strftime(%q) = %q, want %q", format, got, want)
+		}
+	}
+}
@@ -0,0 +1,392 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//go:build !tinygo || tinygo.enable
+
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/bits"
+	"time"
+
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/layers"
+)
+
+// Savefiles are either classic pcap files or pcapng files, see
+// https://datatracker.ietf.org/doc/draft-ietf-opsawg-pcap/ and
+// https://datatracker.ietf.org/doc/draft-ietf-opsawg-pcapng/.
+const (
+	pcapMagicMicro = 0xa1b2c3d4
+	pcapMagicNano  = 0xa1b23c4d
+
+	pcapngSectionHeader   = 0x0a0d0d0a
+	pcapngInterface       = 1
+	pcapngSimplePacket    = 3
+	pcapngEnhancedPacket  = 6
+	pcapngByteOrderMagic  = 0x1a2b3c4d
+	pcapngOptIfName       = 2
+	pcapngOptIfTSResol    = 9
+	pcapngDefaultTSResol  = 6
+	pcapngMaxBlockLength  = 1 << 24
+	maxSavefilePacketSize = pcapngMaxBlockLength
+)
+
+// packetWriter writes packets to a savefile.
+type packetWriter interface {
+	writePacket(ci gopacket.CaptureInfo, data []byte) error
+}
+
+// pcapWriter writes packets in the pcap format.
+type pcapWriter struct {
+	w    io.Writer
+	nano bool
+}
+
+// newPcapWriter writes the pcap file header to w. Timestamps are recorded in
+// microseconds, or nanoseconds if nano is set.
+func newPcapWriter(w io.Writer, snaplen int, linkType layers.LinkType, nano bool) (*pcapWriter, error) {
+	var h [24]byte
+	magic := uint32(pcapMagicMicro)
+	if nano {
+		magic = pcapMagicNano
+	}
+	binary.LittleEndian.PutUint32(h[0:], magic)
+	binary.LittleEndian.PutUint16(h[4:], 2)
+	binary.LittleEndian.PutUint16(h[6:], 4)
+	binary.LittleEndian.PutUint32(h[16:], uint32(snaplen))
+	binary.LittleEndian.PutUint32(h[20:], uint32(linkType))
+	if _, err := w.Write(h[:]); err != nil {
+		return nil, err
+	}
+	return &pcapWriter{w: w, nano: nano}, nil
+}
+
+func (p *pcapWriter) writePacket(ci gopacket.CaptureInfo, data []byte) error {
+	var h [16]byte
+	frac := ci.Timestamp.Nanosecond() / 1000
+	if p.nano {
+		frac = ci.Timestamp.Nanosecond()
+	}
+	binary.LittleEndian.PutUint32(h[0:], uint32(ci.Timestamp.Unix()))
+	binary.LittleEndian.PutUint32(h[4:], uint32(frac))
+	binary.LittleEndian.PutUint32(h[8:], uint32(len(data)))
+	binary.LittleEndian.PutUint32(h[12:], uint32(max(ci.Length, len(data))))
+	if _, err := p.w.Write(h[:]); err != nil {
+		return err
+	}
+	_, err := p.w.Write(data)
+	return err
+}
+
+// pcapngWriter writes packets in the pcapng format, as a single section with
+// a single interface.
+type pcapngWriter struct {
+	w       io.Writer
+	tsresol uint64
+}
+
+// newPcapngWriter writes the section header and the description of the
+// interface called ifname to w.
+func newPcapngWriter(w io.Writer, snaplen int, linkType layers.LinkType, ifname string, nano bool) (*pcapngWriter, error) {
+	shb := make([]byte, 16)
+	binary.LittleEndian.PutUint32(shb[0:], pcapngByteOrderMagic)
+	binary.LittleEndian.PutUint16(shb[4:], 1)
+	binary.LittleEndian.PutUint64(shb[8:], ^uint64(0))
+	if err := writeBlock(w, pcapngSectionHeader, shb); err != nil {
+		return nil, err
+	}
+
+	idb := make([]byte, 8)
+	binary.LittleEndian.PutUint16(idb[0:], uint16(linkType))
+	binary.LittleEndian.PutUint32(idb[4:], uint32(snaplen))
+	if ifname != "" {
+		idb = appendOption(idb, pcapngOptIfName, []byte(ifname))
+	}
+	tsresol := byte(pcapngDefaultTSResol)
+	if nano {
+		tsresol = 9
+		idb = appendOption(idb, pcapngOptIfTSResol, []byte{tsresol})
+	}
+	idb = appendOption(idb, 0, nil)
+	if err := writeBlock(w, pcapngInterface, idb); err != nil {
+		return nil, err
+	}
+	return &pcapngWriter{w: w, tsresol: resolution(tsresol)}, nil
+}
+
+func (p *pcapngWriter) writePacket(ci gopacket.CaptureInfo, data []byte) error {
+	body := make([]byte, 20, 20+len(data)+3)
+	ts := uint64(ci.Timestamp.Unix())*p.tsresol + uint64(ci.Timestamp.Nanosecond())*p.tsresol/1e9
+	binary.LittleEndian.PutUint32(body[4:], uint32(ts>>32))
+	binary.LittleEndian.PutUint32(body[8:], uint32(ts))
+	binary.LittleEndian.PutUint32(body[12:], uint32(len(data)))
+	binary.LittleEndian.PutUint32(body[16:], uint32(max(ci.Length, len(data))))
+	body = append(body, data...)
+	return writeBlock(p.w, pcapngEnhancedPacket, pad(body))
+}
+
+// writeBlock writes a pcapng block with the given type and body, whose length
+// must be a multiple of 4.
+func writeBlock(w io.Writer, typ uint32, body []byte) error {
+	b := make([]byte, 8, len(body)+12)
+	binary.LittleEndian.PutUint32(b[0:], typ)
+	binary.LittleEndian.PutUint32(b[4:], uint32(len(body)+12))
+	b = append(b, body...)
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(body)+12))
+	_, err := w.Write(b)
+	return err
+}
+
+// appendOption appends a pcapng option to b.
+func appendOption(b []byte, code uint16, value []byte) []byte {
+	b = binary.LittleEndian.AppendUint16(b, code)
+	b = binary.LittleEndian.AppendUint16(b, uint16(len(value)))
+	return pad(append(b, value...))
+}
+
+// pad pads b with zeroes to a multiple of 4 bytes.
+func pad(b []byte) []byte {
+	for len(b)%4 != 0 {
+		b = append(b, 0)
+	}
+	return b
+}
+
+// resolution returns the ticks per second of a pcapng if_tsresol option.
+func resolution(tsresol byte) uint64 {
+	if tsresol&0x80 != 0 {
+		return 1 << (tsresol & 0x7f)
+	}
+	r := uint64(1)
+	for range tsresol {
+		r *= 10
+	}
+	return r
+}
+
+var (
+	errBadMagic     = errors.New("unknown file format")
+	errNoInterfaces = errors.New("no interface description block")
+)
+
+// pcapngInterfaceDesc is what a pcapng interface description block says
+// about the packets captured on it.
+type pcapngInterfaceDesc struct {
+	linkType layers.LinkType
+	snaplen  uint32
+	tsresol  uint64
+}
+
+// savefileReader reads packets from a pcap or pcapng file. It implements
+// gopacket.PacketDataSource.
+type savefileReader struct {
+	r     *bufio.Reader
+	order binary.ByteOrder
+	ng    bool
+
+	// linkType and snaplen are from the pcap file header, or from the
+	// first interface of a pcapng file.
+	linkType layers.LinkType
+	snaplen  uint32
+
+	// nano is whether pcap timestamps are in nanoseconds.
+	nano bool
+
+	// ifaces are the interfaces of the current pcapng section.
+	ifaces []pcapngInterfaceDesc
+}
+
+// newSavefileReader reads the file header from r.
+func newSavefileReader(r io.Reader) (*savefileReader, error) {
+	s := &savefileReader{r: bufio.NewReader(r)}
+	magic, err := s.r.Peek(4)
+	if err != nil {
+		return nil, noEOF(err)
+	}
+	if binary.LittleEndian.Uint32(magic) == pcapngSectionHeader {
+		s.ng = true
+		// Packets refer to interfaces, so the first one comes before
+		// them.
+		for len(s.ifaces) == 0 {
+			if _, _, err := s.readBlock(); err != nil {
+				if err == io.EOF {
+					err = errNoInterfaces
+				}
+				return nil, err
+			}
+		}
+		s.linkType, s.snaplen = s.ifaces[0].linkType, s.ifaces[0].snaplen
+		return s, nil
+	}
+
+	var h [24]byte
+	if _, err := io.ReadFull(s.r, h[:]); err != nil {
+		return nil, noEOF(err)
+	}
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		switch order.Uint32(h[:]) {
+		case pcapMagicMicro:
+		case pcapMagicNano:
+			s.nano = true
+		default:
+			continue
+		}
+		s.order = order
+		s.snaplen = order.Uint32(h[16:])
+		// The upper bits hold FCS information.
+		s.linkType = layers.LinkType(order.Uint32(h[20:]) & 0xffff)
+		return s, nil
+	}
+	return nil, errBadMagic
+}
+
+// ReadPacketData implements gopacket.PacketDataSource.
+func (s *savefileReader) ReadPacketData() ([]byte, gopacket.CaptureInfo, error) {
+	if s.ng {
+		for {
+			data, ci, err := s.readBlock()
+			if err != nil || data != nil {
+				return data, ci, err
+			}
+		}
+	}
+
+	var ci gopacket.CaptureInfo
+	var h [16]byte
+	if _, err := io.ReadFull(s.r, h[:]); err != nil {
+		return nil, ci, err
+	}
+	frac := time.Duration(s.order.Uint32(h[4:]))
+	if !s.nano {
+		frac *= time.Microsecond
+	}
+	ci.Timestamp = time.Unix(int64(s.order.Uint32(h[0:])), int64(frac))
+	ci.CaptureLength = int(s.order.Uint32(h[8:]))
+	ci.Length = int(s.order.Uint32(h[12:]))
+	if ci.CaptureLength > maxSavefilePacketSize {
+		return nil, ci, fmt.Errorf("packet length %d is too large", ci.CaptureLength)
+	}
+	data := make([]byte, ci.CaptureLength)
+	if _, err := io.ReadFull(s.r, data); err != nil {
+		return nil, ci, noEOF(err)
+	}
+	return data, ci, nil
+}
+
+// readBlock reads a pcapng block. It returns the data of packet blocks, and
+// nil data for other blocks.
+func (s *savefileReader) readBlock() ([]byte, gopacket.CaptureInfo, error) {
+	var ci gopacket.CaptureInfo
+	var h [8]byte
+	if _, err := io.ReadFull(s.r, h[:]); err != nil {
+		return nil, ci, err
+	}
+	// The section header block type is a palindrome, and its byte order
+	// magic follows the length.
+	if binary.LittleEndian.Uint32(h[:]) == pcapngSectionHeader {
+		bom, err := s.r.Peek(4)
+		if err != nil {
+			return nil, ci, noEOF(err)
+		}
+		switch {
+		case binary.LittleEndian.Uint32(bom) == pcapngByteOrderMagic:
+			s.order = binary.LittleEndian
+		case binary.BigEndian.Uint32(bom) == pcapngByteOrderMagic:
+			s.order = binary.BigEndian
+		default:
+			return nil, ci, fmt.Errorf("bad pcapng byte order magic %#x", bom)
+		}
+	}
+	if s.order == nil {
+		return nil, ci, errBadMagic
+	}
+	typ, n := s.order.Uint32(h[0:]), s.order.Uint32(h[4:])
+	if n < 12 || n%4 != 0 || n > pcapngMaxBlockLength {
+		return nil, ci, fmt.Errorf("bad pcapng block length %d", n)
+	}
+	b := make([]byte, n-8)
+	if _, err := io.ReadFull(s.r, b); err != nil {
+		return nil, ci, noEOF(err)
+	}
+	b = b[:len(b)-4]
+
+	switch typ {
+	case pcapngSectionHeader:
+		if len(b) < 16 || s.order.Uint16(b[4:]) != 1 {
+			return nil, ci, fmt.Errorf("unsupported pcapng section header")
+		}
+		s.ifaces = nil
+
+	case pcapngInterface:
+		if len(b) < 8 {
+			return nil, ci, fmt.Errorf("short pcapng interface description block")
+		}
+		d := pcapngInterfaceDesc{
+			linkType: layers.LinkType(s.order.Uint16(b[0:])),
+			snaplen:  s.order.Uint32(b[4:]),
+			tsresol:  resolution(pcapngDefaultTSResol),
+		}
+		for opts := b[8:]; len(opts) >= 4; {
+			code, l := s.order.Uint16(opts[0:]), int(s.order.Uint16(opts[2:]))
+			if code == 0 || 4+l > len(opts) {
+				break
+			}
+			if code == pcapngOptIfTSResol && l == 1 {
+				d.tsresol = resolution(opts[4])
+			}
+			opts = opts[min(len(opts), 4+(l+3)&^3):]
+		}
+		s.ifaces = append(s.ifaces, d)
+
+	case pcapngEnhancedPacket:
+		if len(b) < 20 {
+			return nil, ci, fmt.Errorf("short pcapng enhanced packet block")
+		}
+		ci.InterfaceIndex = int(s.order.Uint32(b[0:]))
+		if ci.InterfaceIndex >= len(s.ifaces) {
+			return nil, ci, fmt.Errorf("packet for unknown interface %d", ci.InterfaceIndex)
+		}
+		ts := uint64(s.order.Uint32(b[4:]))<<32 | uint64(s.order.Uint32(b[8:]))
+		ci.Timestamp = timestamp(ts, s.ifaces[ci.InterfaceIndex].tsresol)
+		ci.CaptureLength = int(s.order.Uint32(b[12:]))
+		ci.Length = int(s.order.Uint32(b[16:]))
+		if ci.CaptureLength > len(b)-20 {
+			return nil, ci, fmt.Errorf("bad pcapng captured length %d", ci.CaptureLength)
+		}
+		return b[20 : 20+ci.CaptureLength], ci, nil
+
+	case pcapngSimplePacket:
+		if len(b) < 4 || len(s.ifaces) == 0 {
+			return nil, ci, fmt.Errorf("bad pcapng simple packet block")
+		}
+		ci.Length = int(s.order.Uint32(b[0:]))
+		ci.CaptureLength = min(ci.Length, len(b)-4)
+		if snaplen := int(s.ifaces[0].snaplen); snaplen > 0 {
+			ci.CaptureLength = min(ci.CaptureLength, snaplen)
+		}
+		return b[4 : 4+ci.CaptureLength], ci, nil
+	}
+	return nil, ci, nil
+}
+
+// timestamp converts a pcapng timestamp with tps ticks per second.
+func timestamp(ts, tps uint64) time.Time {
+	hi, lo := bits.Mul64(ts%tps, 1e9)
+	nsec, _ := bits.Div64(hi, lo, tps)
+	return time.Unix(int64(ts/tps), int64(nsec))
+}
+
+// noEOF turns io.EOF into io.ErrUnexpectedEOF, for reads that must not hit
+// the end of the file.
+func noEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
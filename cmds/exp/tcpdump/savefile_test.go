@@ -0,0 +1,204 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//go:build !tinygo || tinygo.enable
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/layers"
+)
+
+type savedPacket struct {
+	ci   gopacket.CaptureInfo
+	data []byte
+}
+
+var savedPackets = []savedPacket{
+	{
+		ci:   gopacket.CaptureInfo{Timestamp: time.Unix(1700000000, 123456789), CaptureLength: 5, Length: 5},
+		data: []byte{1, 2, 3, 4, 5},
+	},
+	{
+		ci:   gopacket.CaptureInfo{Timestamp: time.Unix(1700000001, 1000), CaptureLength: 4, Length: 1500},
+		data: []byte{6, 7, 8, 9},
+	},
+}
+
+func readAll(t *testing.T, r io.Reader) (*savefileReader, []savedPacket) {
+	t.Helper()
+	s, err := newSavefileReader(r)
+	if err != nil {
+		t.Fatalf("newSavefileReader() = %v", err)
+	}
+	var got []savedPacket
+	for {
+		data, ci, err := s.ReadPacketData()
+		if err == io.EOF {
+			return s, got
+		}
+		if err != nil {
+			t.Fatalf("ReadPacketData() = %v", err)
+		}
+		got = append(got, savedPacket{ci: ci, data: data})
+	}
+}
+
+func TestSavefileRoundTrip(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		new    func(io.Writer) (packetWriter, error)
+		micros bool
+	}{
+		{
+			name: "pcap",
+			new: func(w io.Writer) (packetWriter, error) {
+				return newPcapWriter(w, 1500, layers.LinkTypeEthernet, false)
+			},
+			micros: true,
+		},
+		{
+			name: "pcap nano",
+			new: func(w io.Writer) (packetWriter, error) {
+				return newPcapWriter(w, 1500, layers.LinkTypeEthernet, true)
+			},
+		},
+		{
+			name: "pcapng",
+			new: func(w io.Writer) (packetWriter, error) {
+				return newPcapngWriter(w, 1500, layers.LinkTypeEthernet, "eth0", false)
+			},
+			micros: true,
+		},
+		{
+			name: "pcapng nano",
+			new: func(w io.Writer) (packetWriter, error) {
+				return newPcapngWriter(w, 1500, layers.LinkTypeEthernet, "eth0", true)
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var b bytes.Buffer
+			w, err := tt.new(&b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, p := range savedPackets {
+				if err := w.writePacket(p.ci, p.data); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			s, got := readAll(t, &b)
+			if s.linkType != layers.LinkTypeEthernet || s.snaplen != 1500 {
+				t.Errorf("link type %v, snaplen %d, want Ethernet and 1500", s.linkType, s.snaplen)
+			}
+			var want []savedPacket
+			for _, p := range savedPackets {
+				if tt.micros {
+					p.ci.Timestamp = p.ci.Timestamp.Truncate(time.Microsecond)
+				}
+				want = append(want, p)
+			}
+			if diff := cmp.Diff(want, got, cmp.AllowUnexported(savedPacket{})); diff != "" {
+				t.Errorf("packets mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// bigEndianPcapng returns a big-endian pcapng file with an interface using
+// binary timestamp resolution, and a simple packet block.
+func bigEndianPcapng() []byte {
+	be := binary.BigEndian
+	block := func(typ uint32, body []byte) []byte {
+		b := be.AppendUint32(nil, typ)
+		b = be.AppendUint32(b, uint32(len(body)+12))
+		b = append(b, body...)
+		return be.AppendUint32(b, uint32(len(body)+12))
+	}
+	shb := be.AppendUint32(nil, pcapngByteOrderMagic)
+	shb = append(shb, 0, 1, 0, 0, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff)
+	// Link type 1, snaplen 3, if_tsresol 2^-10.
+	idb := []byte{0, 1, 0, 0, 0, 0, 0, 3, 0, 9, 0, 1, 0x8a, 0, 0, 0, 0, 0, 0, 0}
+	// Timestamp 1.5s.
+	epb := []byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0x06, 0, 0, 0, 0, 2, 0, 0, 0, 2, 0xaa, 0xbb, 0, 0}
+	spb := []byte{0, 0, 0, 5, 1, 2, 3, 4, 5, 0, 0, 0}
+	var f []byte
+	f = append(f, block(pcapngSectionHeader, shb)...)
+	f = append(f, block(pcapngInterface, idb)...)
+	f = append(f, block(5, []byte{1, 2, 3, 4})...)
+	f = append(f, block(pcapngEnhancedPacket, epb)...)
+	f = append(f, block(pcapngSimplePacket, spb)...)
+	return f
+}
+
+func TestSavefileReader(t *testing.T) {
+	_, got := readAll(t, bytes.NewReader(bigEndianPcapng()))
+	want := []savedPacket{
+		{
+			ci:   gopacket.CaptureInfo{Timestamp: time.Unix(1, 5e8), CaptureLength: 2, Length: 2},
+			data: []byte{0xaa, 0xbb},
+		},
+		{
+			ci:   gopacket.CaptureInfo{CaptureLength: 3, Length: 5},
+			data: []byte{1, 2, 3},
+		},
+	}
+	if diff := cmp.Diff(want, got, cmp.AllowUnexported(savedPacket{})); diff != "" {
+		t.Errorf("packets mismatch (-want +got):\n%s", diff)
+	}
+
+	// A big-endian pcap file.
+	be := []byte{0xa1, 0xb2, 0xc3, 0xd4, 0, 2, 0, 4, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 64, 0, 0, 0, 113}
+	be = append(be, 0, 0, 0, 2, 0, 0, 0, 3, 0, 0, 0, 1, 0, 0, 0, 1, 0xcc)
+	s, got := readAll(t, bytes.NewReader(be))
+	if s.linkType != layers.LinkTypeLinuxSLL || len(got) != 1 || !got[0].ci.Timestamp.Equal(time.Unix(2, 3000)) {
+		t.Errorf("big-endian pcap: link type %v, packets %+v", s.linkType, got)
+	}
+}
+
+func TestSavefileErrors(t *testing.T) {
+	var pcap bytes.Buffer
+	w, _ := newPcapWriter(&pcap, 100, layers.LinkTypeEthernet, false)
+	w.writePacket(savedPackets[0].ci, savedPackets[0].data)
+
+	for _, tt := range []struct {
+		name string
+		file []byte
+		want error
+	}{
+		{name: "empty", want: io.ErrUnexpectedEOF},
+		{name: "bad magic", file: make([]byte, 24), want: errBadMagic},
+		{name: "short header", file: pcap.Bytes()[:10], want: io.ErrUnexpectedEOF},
+		{name: "no interfaces", file: bigEndianPcapng()[:28], want: errNoInterfaces},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newSavefileReader(bytes.NewReader(tt.file)); !errors.Is(err, tt.want) {
+				t.Errorf("newSavefileReader() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	for _, f := range [][]byte{pcap.Bytes()[:pcap.Len()-1], bigEndianPcapng()[:len(bigEndianPcapng())-1]} {
+		s, err := newSavefileReader(bytes.NewReader(f))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for err == nil {
+			_, _, err = s.ReadPacketData()
+		}
+		if err != io.ErrUnexpectedEOF {
+			t.Errorf("reading a truncated file = %v, want %v", err, io.ErrUnexpectedEOF)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//go:build !tinygo || tinygo.enable
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/layers"
+)
+
+// udpData returns a string representation of the UDP layer, decoding DHCP
+// payloads unless quiet is set.
+func udpData(packet gopacket.Packet, layer *layers.UDP, verbose, quiet bool) string {
+	if !quiet {
+		if dhcp, ok := packet.Layer(layers.LayerTypeDHCPv4).(*layers.DHCPv4); ok {
+			return dhcpv4Data(dhcp, verbose)
+		}
+		if dhcp, ok := packet.Layer(layers.LayerTypeDHCPv6).(*layers.DHCPv6); ok {
+			return dhcpv6Data(dhcp, verbose)
+		}
+	}
+
+	return fmt.Sprintf("UDP, length %d", len(layer.Payload))
+}
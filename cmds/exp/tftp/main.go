@@ -9,6 +9,10 @@
 //
 //	Options:
 //		-m <ascii/binary>
+//		-blksize <int>
+//			- Block size to negotiate (RFC 2348), 0 for none. Default: 0.
+//		-windowsize <int>
+//			- Window size to negotiate (RFC 7440), 0 for none. Default: 16.
 //
 //	Commands:
 //		q,quit
@@ -42,6 +46,10 @@
 //			- Prints the program/client configuration
 //		timeout <int>
 //			- Sets the total transmission timeout to <int> seconds. Default: 1
+//		blksize <int>
+//			- Sets the block size to negotiate, 0 for none.
+//		windowsize <int>
+//			- Sets the window size to negotiate, 0 for none.
 
 package main
 
@@ -60,6 +68,8 @@ func main() {
 	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
 	fs.StringVar(&f.Cmd, "c", "", "Execute command as if it had been entered on the tftp prompt.  Must be specified last on the command line.")
 	fs.StringVar(&f.Mode, "m", "netascii", "Set the default transfer mode to mode.  This is usually used with -c.")
+	fs.IntVar(&f.Blksize, "blksize", 0, "Block size to negotiate (RFC 2348), 0 for none.")
+	fs.IntVar(&f.Windowsize, "windowsize", 16, "Window size to negotiate (RFC 7440), 0 for none.")
 
 	fs.Parse(os.Args[1:])
 
@@ -84,11 +94,13 @@ func run(f tftppkg.Flags, cmdline, args []string, stdin io.Reader, stdout io.Wri
 	}
 
 	clientcfg := &tftppkg.ClientCfg{
-		Host:    ip,
-		Port:    port,
-		Mode:    m,
-		Rexmt:   tftp.ClientRetransmit(10),
-		Timeout: tftp.ClientTimeout(1),
+		Host:       ip,
+		Port:       port,
+		Mode:       m,
+		Rexmt:      tftp.ClientRetransmit(10),
+		Timeout:    tftp.ClientTimeout(1),
+		Blksize:    f.Blksize,
+		Windowsize: f.Windowsize,
 	}
 
 	input := make([]string, 0)
@@ -3,223 +3,139 @@
 // license that can be found in the LICENSE file.
 //go:build !tinygo || tinygo.enable
 
-// tftpd is a basic TFTP server that serves files from the current directory
+// tftpd is a TFTP server that serves files from a directory
 //
 // Synopsis:
 //
-//	tftpd [-port PORT] [-root DIRECTORY]
+//	tftpd [OPTIONS]
 //
 // Description:
 //
-//	tftpd runs a simple TFTP server that can handle both read and write requests.
-//	It uses the same TFTP library as the client (pack.ag/tftp).
+//	tftpd serves the files of a directory over TFTP, and negotiates the
+//	blksize, timeout, tsize and windowsize options. Clients may write the
+//	files that -write allows. Files that match a -template pattern are
+//	rendered from a Go text/template per request instead, with the fields
+//	of tftpd.TemplateData: .Name, .Addr and .MAC, the hardware address of
+//	PXELINUX per-client names such as pxelinux.cfg/01-aa-bb-cc-dd-ee-ff.
 //
 // Options:
 //
-//	-port: Port to listen on (default 69)
-//	-root: Root directory to serve files from (default current directory)
-
+//	-port:          port to listen on (default 69)
+//	-root:          root directory to serve files from (default current directory)
+//	-write:         comma-separated path.Match patterns of the files that clients may write
+//	-template:      PATTERN=FILE, render the files that match PATTERN from the template FILE; may be repeated
+//	-blksize:       largest block size to negotiate (default 1468)
+//	-windowsize:    largest window size to negotiate (default 64)
+//	-timeout:       retransmission timeout (default 1s)
+//	-retries:       retransmissions before a transfer fails (default 5)
+//	-max-transfers: concurrent transfers, 0 for no limit
+//	-v:             log transfers
+//
+// Example:
+//
+//	tftpd -root /srv/tftp -template 'pxelinux.cfg/01-*=/etc/pxelinux.tmpl'
 package main
 
 import (
 	"errors"
 	"flag"
 	"fmt"
-	"io"
 	"log"
 	"os"
 	"path/filepath"
 	"strings"
+	"text/template"
 
-	"pack.ag/tftp"
+	"github.com/u-root/u-root/pkg/tftpd"
 )
 
-var (
-	port    = flag.Int("port", 69, "Port to listen on")
-	rootDir = flag.String("root", ".", "Root directory to serve files from")
-	verbose = flag.Bool("v", false, "Enable verbose logging")
-)
-
-var errInvalidPath = errors.New("invalid path")
-
-// isValidPath checks if the provided path is valid and secure
-// using the approach recommended in https://go.dev/blog/osroot
-func isValidPath(rootDir, path string) (string, error) {
-	// Clean the path to remove any ".." elements
-	cleanPath := filepath.Clean(path)
-
-	// Create an absolute path by joining with root
-	targetPath := filepath.Join(rootDir, cleanPath)
-
-	// Resolve any symlinks
-	realPath, err := filepath.EvalSymlinks(targetPath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			// For non-existent paths (e.g., for file creation),
-			// we need to check the parent directory
-			parentDir := filepath.Dir(targetPath)
-			realParent, err := filepath.EvalSymlinks(parentDir)
-			if err != nil {
-				return "", err
-			}
-
-			// Check if the parent is still under root
-			rootAbs, err := filepath.EvalSymlinks(rootDir)
-			if err != nil {
-				return "", err
-			}
-
-			// Make paths canonical for comparison
-			realParent = filepath.Clean(realParent)
-			rootAbs = filepath.Clean(rootAbs)
-
-			// Check if parent directory is under root
-			rel, err := filepath.Rel(rootAbs, realParent)
-			if err != nil || strings.HasPrefix(rel, "..") || rel == ".." {
-				return "", errInvalidPath
-			}
-
-			return targetPath, nil
-		}
-		return "", err
-	}
+var errTemplate = errors.New("template must be PATTERN=FILE")
 
-	// Get the canonical path for the root dir
-	rootAbs, err := filepath.EvalSymlinks(rootDir)
-	if err != nil {
-		return "", err
-	}
-
-	// Make paths canonical for comparison
-	realPath = filepath.Clean(realPath)
-	rootAbs = filepath.Clean(rootAbs)
-
-	// Check if the path is contained within the root
-	rel, err := filepath.Rel(rootAbs, realPath)
-	if err != nil || strings.HasPrefix(rel, "..") || rel == ".." {
-		return "", errInvalidPath
-	}
+// templates are the values of the repeated -template flag.
+type templates []string
 
-	return realPath, nil
+func (t *templates) String() string {
+	return strings.Join(*t, ",")
 }
 
-// FileReadHandler implements tftp.ReadHandler to serve files from a directory
-type FileReadHandler struct {
-	Root string
+func (t *templates) Set(s string) error {
+	*t = append(*t, s)
+	return nil
 }
 
-// ServeTFTP handles TFTP read requests by serving files from the root directory
-func (h *FileReadHandler) ServeTFTP(w tftp.ReadRequest) {
-	requestedPath := w.Name()
-
-	if *verbose {
-		log.Printf("Received read request for %s", requestedPath)
-	}
+type cmd struct {
+	port      int
+	root      string
+	write     string
+	templates templates
+	verbose   bool
+	srv       *tftpd.Server
+}
 
-	// Validate the path securely
-	realPath, err := isValidPath(h.Root, requestedPath)
-	if err != nil {
-		if errors.Is(err, errInvalidPath) {
-			w.WriteError(tftp.ErrCodeAccessViolation, "Access violation")
-		} else if os.IsNotExist(err) {
-			w.WriteError(tftp.ErrCodeFileNotFound, "File not found")
-		} else {
-			w.WriteError(tftp.ErrCodeNotDefined, "Cannot access file")
-		}
-		return
+func command(args []string) (*cmd, error) {
+	c := &cmd{srv: &tftpd.Server{}}
+	f := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	f.IntVar(&c.port, "port", 69, "Port to listen on")
+	f.StringVar(&c.root, "root", ".", "Root directory to serve files from")
+	f.StringVar(&c.write, "write", "", "Comma-separated path.Match patterns of the files that clients may write")
+	f.Var(&c.templates, "template", "PATTERN=FILE, render the files that match PATTERN from the template FILE")
+	f.IntVar(&c.srv.MaxBlockSize, "blksize", tftpd.DefaultMaxBlockSize, "Largest block size to negotiate")
+	f.IntVar(&c.srv.MaxWindowSize, "windowsize", tftpd.DefaultMaxWindowSize, "Largest window size to negotiate")
+	f.DurationVar(&c.srv.Timeout, "timeout", tftpd.DefaultTimeout, "Retransmission timeout")
+	f.IntVar(&c.srv.Retries, "retries", tftpd.DefaultRetries, "Retransmissions before a transfer fails")
+	f.IntVar(&c.srv.MaxTransfers, "max-transfers", 0, "Concurrent transfers, 0 for no limit")
+	f.BoolVar(&c.verbose, "v", false, "Log transfers")
+	if err := f.Parse(args[1:]); err != nil {
+		return nil, err
 	}
-
-	file, err := os.Open(realPath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			w.WriteError(tftp.ErrCodeFileNotFound, "File not found")
-		} else if os.IsPermission(err) {
-			w.WriteError(tftp.ErrCodeAccessViolation, "Permission denied")
-		} else {
-			w.WriteError(tftp.ErrCodeNotDefined, "Cannot open file")
-		}
-		return
+	if f.NArg() != 0 {
+		return nil, fmt.Errorf("unexpected arguments %q", f.Args())
 	}
-	defer file.Close()
 
-	_, err = io.Copy(w, file)
+	root, err := filepath.Abs(c.root)
 	if err != nil {
-		log.Printf("Error sending file: %v", err)
+		return nil, fmt.Errorf("failed to resolve root directory path: %w", err)
 	}
-}
-
-// FileWriteHandler implements tftp.WriteHandler to write files to a directory
-type FileWriteHandler struct {
-	Root string
-}
-
-// ReceiveTFTP handles TFTP write requests by storing files in the root directory
-func (h *FileWriteHandler) ReceiveTFTP(w tftp.WriteRequest) {
-	requestedPath := w.Name()
-
-	if *verbose {
-		log.Printf("Received write request for %s", requestedPath)
-	}
-
-	// Validate the path securely
-	realPath, err := isValidPath(h.Root, requestedPath)
-	if err != nil {
-		if errors.Is(err, errInvalidPath) {
-			w.WriteError(tftp.ErrCodeAccessViolation, "Access violation")
-		} else if !os.IsNotExist(err) { // IsNotExist is expected for new files
-			w.WriteError(tftp.ErrCodeNotDefined, "Cannot access path")
+	c.root = root
+
+	dir := tftpd.Dir(root)
+	mux := &tftpd.Mux{Default: dir}
+	for _, t := range c.templates {
+		pattern, file, ok := strings.Cut(t, "=")
+		if !ok {
+			return nil, fmt.Errorf("%q: %w", t, errTemplate)
+		}
+		tmpl, err := template.ParseFiles(file)
+		if err != nil {
+			return nil, err
+		}
+		if err := mux.Handle(pattern, tftpd.Template(tmpl)); err != nil {
+			return nil, err
 		}
-		return
 	}
+	c.srv.Handler = mux
 
-	// Create directory if it doesn't exist
-	err = os.MkdirAll(filepath.Dir(realPath), 0o755)
-	if err != nil {
-		w.WriteError(tftp.ErrCodeAccessViolation, "Cannot create directory")
-		return
+	if c.write != "" {
+		c.srv.WriteHandler = dir
+		c.srv.Writable = strings.Split(c.write, ",")
 	}
-
-	file, err := os.Create(realPath)
-	if err != nil {
-		if os.IsPermission(err) {
-			w.WriteError(tftp.ErrCodeAccessViolation, "Permission denied")
-		} else {
-			w.WriteError(tftp.ErrCodeNotDefined, "Cannot create file")
-		}
-		return
+	if !c.verbose {
+		c.srv.Logf = func(string, ...any) {}
 	}
-	defer file.Close()
+	return c, nil
+}
 
-	_, err = io.Copy(file, w)
-	if err != nil {
-		log.Printf("Error receiving file: %v", err)
-		// Unfortunately, we can't report errors to the client after the transfer has started
-	}
+func (c *cmd) run() error {
+	log.Printf("TFTP server listening on port %d, serving files from %s", c.port, c.root)
+	return c.srv.ListenAndServe(fmt.Sprintf(":%d", c.port))
 }
 
 func main() {
-	flag.Parse()
-
-	// Resolve the absolute path of root directory
-	absRoot, err := filepath.Abs(*rootDir)
+	c, err := command(os.Args)
 	if err != nil {
-		log.Fatalf("Failed to resolve root directory path: %v", err)
+		log.Fatal(err)
 	}
-
-	// Create the server
-	addr := fmt.Sprintf(":%d", *port)
-	server, err := tftp.NewServer(addr)
-	if err != nil {
-		log.Fatalf("Failed to create TFTP server: %v", err)
-	}
-
-	// Register handlers
-	server.ReadHandler(&FileReadHandler{Root: absRoot})
-	server.WriteHandler(&FileWriteHandler{Root: absRoot})
-
-	log.Printf("TFTP server listening on port %d, serving files from %s", *port, absRoot)
-	err = server.ListenAndServe()
-	if err != nil {
+	if err := c.run(); err != nil {
 		log.Fatalf("Server error: %v", err)
 	}
 }
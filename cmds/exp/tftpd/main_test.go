@@ -0,0 +1,54 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestCommand(t *testing.T) {
+	dir := t.TempDir()
+	tmpl := filepath.Join(dir, "pxe.tmpl")
+	if err := os.WriteFile(tmpl, []byte("{{.MAC}}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := command([]string{"tftpd", "-root", dir, "-write", "up/*,logs/*", "-template", "pxelinux.cfg/*=" + tmpl, "-blksize", "8192", "-windowsize", "8"})
+	if err != nil {
+		t.Fatalf("command() = %v", err)
+	}
+	if c.srv.WriteHandler == nil || !slices.Equal(c.srv.Writable, []string{"up/*", "logs/*"}) {
+		t.Errorf("Writable = %q, want %q", c.srv.Writable, []string{"up/*", "logs/*"})
+	}
+	if c.srv.MaxBlockSize != 8192 || c.srv.MaxWindowSize != 8 {
+		t.Errorf("MaxBlockSize, MaxWindowSize = %d, %d, want 8192, 8", c.srv.MaxBlockSize, c.srv.MaxWindowSize)
+	}
+
+	c, err = command([]string{"tftpd", "-root", dir})
+	if err != nil {
+		t.Fatalf("command() = %v", err)
+	}
+	if c.srv.WriteHandler != nil {
+		t.Errorf("server without -write is writable")
+	}
+
+	for _, args := range [][]string{
+		{"tftpd", "-template", "nofile"},
+		{"tftpd", "-template", "*=" + filepath.Join(dir, "missing")},
+		{"tftpd", "-template", "[=" + tmpl},
+		{"tftpd", "extra"},
+	} {
+		if _, err := command(args); err == nil {
+			t.Errorf("command(%q) = nil, want an error", args)
+		}
+	}
+	if _, err := command([]string{"tftpd", "-template", "nofile"}); !errors.Is(err, errTemplate) {
+		t.Errorf("command() = %v, want %v", err, errTemplate)
+	}
+}
@@ -0,0 +1,537 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// wg configures WireGuard interfaces.
+//
+// Synopsis:
+//
+//	wg genkey | genpsk
+//	wg pubkey
+//	wg show [IFACE | all | interfaces] [FIELD | dump]
+//	wg showconf IFACE
+//	wg set IFACE [listen-port PORT] [fwmark MARK] [private-key FILE]
+//	    [peer KEY [remove] [update-only] [preshared-key FILE] [endpoint HOST:PORT]
+//	    [persistent-keepalive SECONDS] [allowed-ips IP/CIDR[,IP/CIDR...]]]...
+//	wg setconf | addconf IFACE FILE
+//	wg userspace IFACE
+//
+// Description:
+//
+//	genkey writes a new private key, and genpsk a new preshared key, to
+//	stdout. pubkey reads a private key from stdin and writes its public key.
+//
+//	show shows the state of the interfaces, or only one field of it:
+//	public-key, private-key, listen-port, fwmark, peers, preshared-keys,
+//	endpoints, allowed-ips, latest-handshakes, persistent-keepalive or
+//	transfer. dump shows all fields, tab-separated.
+//
+//	showconf writes the configuration of an interface in the format of
+//	setconf. setconf replaces the configuration of an interface with a
+//	file, and addconf adds the peers of the file. set changes parts of the
+//	configuration; a FILE of "-" is stdin.
+//
+//	Interfaces of the kernel are configured over generic netlink. On
+//	kernels without WireGuard, wg userspace IFACE creates IFACE over a TUN
+//	device and runs it until the interface is deleted. It is configured
+//	over a socket in /var/run/wireguard, which the other subcommands use
+//	when it exists. ip link add IFACE type wireguard runs it as needed.
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/netip"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/u-root/u-root/pkg/wireguard"
+	"github.com/vishvananda/netlink"
+)
+
+var errUsage = errors.New(`usage: wg genkey | genpsk | pubkey
+       wg show [IFACE | all | interfaces] [FIELD | dump]
+       wg showconf IFACE
+       wg set IFACE [listen-port PORT] [fwmark MARK] [private-key FILE] [peer KEY [remove] [update-only] [preshared-key FILE] [endpoint HOST:PORT] [persistent-keepalive SECONDS] [allowed-ips IP/CIDR[,IP/CIDR...]]]...
+       wg setconf | addconf IFACE FILE
+       wg userspace IFACE`)
+
+// now is the time that latest handshakes are shown relative to.
+var now = time.Now
+
+func run(ctx context.Context, stdin io.Reader, stdout io.Writer, args []string) error {
+	if len(args) == 0 {
+		return show(stdout, []string{"all"})
+	}
+	switch cmd, args := args[0], args[1:]; cmd {
+	case "genkey", "genpsk":
+		if len(args) != 0 {
+			return errUsage
+		}
+		var (
+			k   wireguard.Key
+			err error
+		)
+		if cmd == "genkey" {
+			k, err = wireguard.GeneratePrivateKey()
+		} else {
+			k, err = wireguard.GenerateKey()
+		}
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(stdout, k)
+		return err
+	case "pubkey":
+		if len(args) != 0 {
+			return errUsage
+		}
+		k, err := readKey(stdin)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(stdout, k.PublicKey())
+		return err
+	case "show":
+		return show(stdout, args)
+	case "showconf":
+		if len(args) != 1 {
+			return errUsage
+		}
+		d, err := wireguard.DeviceByName(args[0])
+		if err != nil {
+			return fmt.Errorf("%s: %w", args[0], err)
+		}
+		return wireguard.WriteConfig(stdout, d)
+	case "set":
+		if len(args) < 1 {
+			return errUsage
+		}
+		c, err := parseSet(stdin, args[1:])
+		if err != nil {
+			return err
+		}
+		return configure(args[0], c)
+	case "setconf", "addconf":
+		if len(args) != 2 {
+			return errUsage
+		}
+		c, err := readConfig(stdin, args[1])
+		if err != nil {
+			return err
+		}
+		if cmd == "addconf" {
+			c.ReplacePeers = false
+			for i := range c.Peers {
+				c.Peers[i].ReplaceAllowedIPs = false
+			}
+		}
+		return configure(args[0], c)
+	case "userspace":
+		if len(args) != 1 {
+			return errUsage
+		}
+		return userspace(ctx, args[0])
+	default:
+		return errUsage
+	}
+}
+
+func configure(name string, c *wireguard.Config) error {
+	if err := wireguard.Configure(name, c); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+// readKey reads a base64 key from r.
+func readKey(r io.Reader) (wireguard.Key, error) {
+	b, err := io.ReadAll(io.LimitReader(r, 1024))
+	if err != nil {
+		return wireguard.Key{}, err
+	}
+	return wireguard.ParseKey(strings.TrimSpace(string(b)))
+}
+
+// open opens file, or returns stdin for "-".
+func open(stdin io.Reader, file string) (io.ReadCloser, error) {
+	if file == "-" {
+		return io.NopCloser(stdin), nil
+	}
+	return os.Open(file)
+}
+
+func readKeyFile(stdin io.Reader, file string) (*wireguard.Key, error) {
+	f, err := open(stdin, file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	k, err := readKey(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return &k, nil
+}
+
+func readConfig(stdin io.Reader, file string) (*wireguard.Config, error) {
+	f, err := open(stdin, file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	c, err := wireguard.ParseConfig(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return c, nil
+}
+
+// parseSet parses the arguments of set after the interface.
+func parseSet(stdin io.Reader, args []string) (*wireguard.Config, error) {
+	c := &wireguard.Config{}
+	var peer *wireguard.PeerConfig
+	for len(args) > 0 {
+		arg := args[0]
+		args = args[1:]
+		switch arg {
+		case "remove", "update-only":
+			if peer == nil {
+				return nil, errUsage
+			}
+			if arg == "remove" {
+				peer.Remove = true
+			} else {
+				peer.UpdateOnly = true
+			}
+			continue
+		}
+		if len(args) == 0 {
+			return nil, errUsage
+		}
+		value := args[0]
+		args = args[1:]
+		var err error
+		switch {
+		case arg == "peer":
+			c.Peers = append(c.Peers, wireguard.PeerConfig{})
+			peer = &c.Peers[len(c.Peers)-1]
+			peer.PublicKey, err = wireguard.ParseKey(value)
+		case arg == "listen-port" && peer == nil:
+			var port int
+			port, err = wireguard.ParsePort(value)
+			c.ListenPort = &port
+		case arg == "fwmark" && peer == nil:
+			var mark int
+			mark, err = wireguard.ParseFirewallMark(value)
+			c.FirewallMark = &mark
+		case arg == "private-key" && peer == nil:
+			c.PrivateKey, err = readKeyFile(stdin, value)
+		case arg == "preshared-key" && peer != nil:
+			peer.PresharedKey, err = readKeyFile(stdin, value)
+		case arg == "endpoint" && peer != nil:
+			var ep netip.AddrPort
+			ep, err = wireguard.ParseEndpoint(value)
+			peer.Endpoint = &ep
+		case arg == "persistent-keepalive" && peer != nil:
+			var d time.Duration
+			d, err = wireguard.ParseKeepalive(value)
+			peer.PersistentKeepaliveInterval = &d
+		case arg == "allowed-ips" && peer != nil:
+			peer.ReplaceAllowedIPs = true
+			if value != "" {
+				peer.AllowedIPs, err = wireguard.ParseAllowedIPs(value)
+			}
+		default:
+			return nil, errUsage
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// show implements wg show.
+func show(stdout io.Writer, args []string) error {
+	if len(args) > 2 {
+		return errUsage
+	}
+	which := "all"
+	if len(args) > 0 {
+		which = args[0]
+	}
+	field := ""
+	if len(args) > 1 {
+		field = args[1]
+	}
+
+	var names []string
+	switch which {
+	case "all", "interfaces":
+		var err error
+		if names, err = wireguard.Devices(); err != nil {
+			return err
+		}
+		if which == "interfaces" {
+			if field != "" {
+				return errUsage
+			}
+			if len(names) > 0 {
+				fmt.Fprintln(stdout, strings.Join(names, " "))
+			}
+			return nil
+		}
+	default:
+		names = []string{which}
+	}
+
+	w := bufio.NewWriter(stdout)
+	for i, name := range names {
+		d, err := wireguard.DeviceByName(name)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		d.Name = name
+		prefix := ""
+		if which == "all" {
+			prefix = name + "\t"
+		}
+		switch field {
+		case "":
+			if i > 0 {
+				fmt.Fprintln(w)
+			}
+			showDevice(w, d)
+		default:
+			if err := showField(w, d, field, prefix); err != nil {
+				return err
+			}
+		}
+	}
+	return w.Flush()
+}
+
+// showDevice writes d in the format of wg show.
+func showDevice(w io.Writer, d *wireguard.Device) {
+	fmt.Fprintf(w, "interface: %s\n", d.Name)
+	if !d.PublicKey.IsZero() {
+		fmt.Fprintf(w, "  public key: %s\n", d.PublicKey)
+	}
+	if !d.PrivateKey.IsZero() {
+		fmt.Fprintf(w, "  private key: (hidden)\n")
+	}
+	if d.ListenPort != 0 {
+		fmt.Fprintf(w, "  listening port: %d\n", d.ListenPort)
+	}
+	if d.FirewallMark != 0 {
+		fmt.Fprintf(w, "  fwmark: 0x%x\n", d.FirewallMark)
+	}
+	for _, p := range d.Peers {
+		fmt.Fprintf(w, "\npeer: %s\n", p.PublicKey)
+		if !p.PresharedKey.IsZero() {
+			fmt.Fprintf(w, "  preshared key: (hidden)\n")
+		}
+		if p.Endpoint.IsValid() {
+			fmt.Fprintf(w, "  endpoint: %s\n", p.Endpoint)
+		}
+		fmt.Fprintf(w, "  allowed ips: %s\n", allowedIPs(p.AllowedIPs, ", "))
+		if !p.LastHandshakeTime.IsZero() {
+			fmt.Fprintf(w, "  latest handshake: %s ago\n", ago(now().Sub(p.LastHandshakeTime)))
+		}
+		if p.ReceiveBytes != 0 || p.TransmitBytes != 0 {
+			fmt.Fprintf(w, "  transfer: %s received, %s sent\n", bytes(p.ReceiveBytes), bytes(p.TransmitBytes))
+		}
+		if p.PersistentKeepaliveInterval != 0 {
+			fmt.Fprintf(w, "  persistent keepalive: every %s\n", ago(p.PersistentKeepaliveInterval))
+		}
+	}
+}
+
+// showField writes one field of d, each line after prefix.
+func showField(w io.Writer, d *wireguard.Device, field, prefix string) error {
+	peers := func(f func(p wireguard.Peer) string) {
+		for _, p := range d.Peers {
+			fmt.Fprintf(w, "%s%s\t%s\n", prefix, p.PublicKey, f(p))
+		}
+	}
+	switch field {
+	case "public-key":
+		fmt.Fprintf(w, "%s%s\n", prefix, keyOrNone(d.PublicKey))
+	case "private-key":
+		fmt.Fprintf(w, "%s%s\n", prefix, keyOrNone(d.PrivateKey))
+	case "listen-port":
+		fmt.Fprintf(w, "%s%d\n", prefix, d.ListenPort)
+	case "fwmark":
+		fmt.Fprintf(w, "%s%s\n", prefix, fwmark(d.FirewallMark))
+	case "peers":
+		for _, p := range d.Peers {
+			fmt.Fprintf(w, "%s%s\n", prefix, p.PublicKey)
+		}
+	case "preshared-keys":
+		peers(func(p wireguard.Peer) string { return keyOrNone(p.PresharedKey) })
+	case "endpoints":
+		peers(func(p wireguard.Peer) string { return endpoint(p.Endpoint) })
+	case "allowed-ips":
+		peers(func(p wireguard.Peer) string { return allowedIPs(p.AllowedIPs, " ") })
+	case "latest-handshakes":
+		peers(func(p wireguard.Peer) string { return fmt.Sprint(handshake(p.LastHandshakeTime)) })
+	case "persistent-keepalive":
+		peers(func(p wireguard.Peer) string { return keepalive(p.PersistentKeepaliveInterval) })
+	case "transfer":
+		peers(func(p wireguard.Peer) string { return fmt.Sprintf("%d\t%d", p.ReceiveBytes, p.TransmitBytes) })
+	case "dump":
+		fmt.Fprintf(w, "%s%s\t%s\t%d\t%s\n", prefix, keyOrNone(d.PrivateKey), keyOrNone(d.PublicKey), d.ListenPort, fwmark(d.FirewallMark))
+		peers(func(p wireguard.Peer) string {
+			return fmt.Sprintf("%s\t%s\t%s\t%d\t%d\t%d\t%s", keyOrNone(p.PresharedKey), endpoint(p.Endpoint),
+				allowedIPs(p.AllowedIPs, ","), handshake(p.LastHandshakeTime), p.ReceiveBytes, p.TransmitBytes,
+				keepalive(p.PersistentKeepaliveInterval))
+		})
+	default:
+		return errUsage
+	}
+	return nil
+}
+
+func keyOrNone(k wireguard.Key) string {
+	if k.IsZero() {
+		return "(none)"
+	}
+	return k.String()
+}
+
+func endpoint(ep netip.AddrPort) string {
+	if !ep.IsValid() {
+		return "(none)"
+	}
+	return ep.String()
+}
+
+func allowedIPs(ips []netip.Prefix, sep string) string {
+	if len(ips) == 0 {
+		return "(none)"
+	}
+	s := make([]string, len(ips))
+	for i, ip := range ips {
+		s[i] = ip.String()
+	}
+	return strings.Join(s, sep)
+}
+
+func fwmark(mark int) string {
+	if mark == 0 {
+		return "off"
+	}
+	return fmt.Sprintf("0x%x", mark)
+}
+
+func keepalive(d time.Duration) string {
+	if d == 0 {
+		return "off"
+	}
+	return fmt.Sprint(int(d / time.Second))
+}
+
+func handshake(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
+
+// ago formats d as wg does, as in "1 minute, 5 seconds".
+func ago(d time.Duration) string {
+	if d < time.Second {
+		return "Now"
+	}
+	units := []struct {
+		name string
+		d    time.Duration
+	}{
+		{"year", 365 * 24 * time.Hour},
+		{"day", 24 * time.Hour},
+		{"hour", time.Hour},
+		{"minute", time.Minute},
+		{"second", time.Second},
+	}
+	var parts []string
+	for _, u := range units {
+		n := d / u.d
+		if n == 0 {
+			continue
+		}
+		d -= n * u.d
+		s := fmt.Sprintf("%d %s", n, u.name)
+		if n > 1 {
+			s += "s"
+		}
+		parts = append(parts, s)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// bytes formats n as wg does, as in "1.50 KiB".
+func bytes(n int64) string {
+	switch {
+	case n < 1024:
+		return fmt.Sprintf("%d B", n)
+	case n < 1024*1024:
+		return fmt.Sprintf("%.2f KiB", float64(n)/1024)
+	case n < 1024*1024*1024:
+		return fmt.Sprintf("%.2f MiB", float64(n)/(1024*1024))
+	case n < 1024*1024*1024*1024:
+		return fmt.Sprintf("%.2f GiB", float64(n)/(1024*1024*1024))
+	}
+	return fmt.Sprintf("%.2f TiB", float64(n)/(1024*1024*1024*1024))
+}
+
+// userspace runs the interface name over a TUN device.
+func userspace(ctx context.Context, name string) error {
+	tun, err := wireguard.OpenTUN(name)
+	if err != nil {
+		return err
+	}
+	if l, err := netlink.LinkByName(name); err == nil {
+		if err := netlink.LinkSetMTU(l, wireguard.DefaultMTU); err != nil {
+			tun.Close()
+			return err
+		}
+	}
+	u, err := wireguard.NewUserspace(name, tun, wireguard.DefaultMTU)
+	if err != nil {
+		tun.Close()
+		return err
+	}
+
+	if err := os.MkdirAll(wireguard.SocketDir, 0o755); err != nil {
+		return err
+	}
+	sock := filepath.Join(wireguard.SocketDir, name+".sock")
+	// A socket left by an interface that is gone.
+	os.Remove(sock)
+	ln, err := net.Listen("unix", sock)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(sock)
+	go u.ServeUAPI(ln)
+	defer ln.Close()
+	return u.Run(ctx)
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := run(ctx, os.Stdin, os.Stdout, os.Args[1:]); err != nil && !errors.Is(err, context.Canceled) {
+		log.Fatalf("wg: %v", err)
+	}
+}
@@ -0,0 +1,212 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/u-root/u-root/pkg/wireguard"
+)
+
+const (
+	privateKey = "yAnz5TF+lXXJte14tji3zlMNq+hd2rYUIgJBgB3fBmk="
+	peerKey    = "xTIBA5rboUvnH4htodjb6e697QjLERt1NAB4mZqp8Dg="
+)
+
+func runWG(t *testing.T, stdin string, args ...string) (string, error) {
+	t.Helper()
+	var out strings.Builder
+	err := run(context.Background(), strings.NewReader(stdin), &out, args)
+	return out.String(), err
+}
+
+func TestKeys(t *testing.T) {
+	priv, err := runWG(t, "", "genkey")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wireguard.ParseKey(strings.TrimSpace(priv)); err != nil {
+		t.Errorf("genkey = %q: %v", priv, err)
+	}
+	if psk, err := runWG(t, "", "genpsk"); err != nil || psk == priv {
+		t.Errorf("genpsk = %q, %v", psk, err)
+	}
+	pub, err := runWG(t, privateKey+"\n", "pubkey")
+	if err != nil {
+		t.Fatal(err)
+	}
+	k, _ := wireguard.ParseKey(privateKey)
+	if want := k.PublicKey().String() + "\n"; pub != want {
+		t.Errorf("pubkey = %q, want %q", pub, want)
+	}
+	if _, err := runWG(t, "garbage", "pubkey"); err == nil {
+		t.Errorf("pubkey of garbage = nil error")
+	}
+}
+
+// newInterface starts a userspace interface, with an API socket in a
+// temporary SocketDir.
+func newInterface(t *testing.T, name string) {
+	t.Helper()
+	wireguard.SocketDir = t.TempDir()
+	tun, other := net.Pipe()
+	t.Cleanup(func() { other.Close() })
+	u, err := wireguard.NewUserspace(name, tun, wireguard.DefaultMTU)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		u.Run(ctx)
+		close(done)
+	}()
+	ln, err := net.Listen("unix", filepath.Join(wireguard.SocketDir, name+".sock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	go u.ServeUAPI(ln)
+	t.Cleanup(func() {
+		ln.Close()
+		cancel()
+		<-done
+	})
+}
+
+func TestSetShow(t *testing.T) {
+	newInterface(t, "wg0")
+	now = func() time.Time { return time.Unix(1700000000, 0) }
+
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "key")
+	if err := os.WriteFile(keyFile, []byte(privateKey+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runWG(t, "", "set", "wg0", "listen-port", "0", "fwmark", "off", "private-key", keyFile,
+		"peer", peerKey, "endpoint", "192.0.2.1:51820", "persistent-keepalive", "25", "allowed-ips", "10.0.0.2/32,fd00::2"); err != nil {
+		t.Fatal(err)
+	}
+
+	k, _ := wireguard.ParseKey(privateKey)
+	pub := k.PublicKey().String()
+	for _, tt := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"show", "interfaces"}, "wg0\n"},
+		{[]string{"show", "wg0", "public-key"}, pub + "\n"},
+		{[]string{"show", "wg0", "private-key"}, privateKey + "\n"},
+		{[]string{"show", "wg0", "fwmark"}, "off\n"},
+		{[]string{"show", "wg0", "peers"}, peerKey + "\n"},
+		{[]string{"show", "wg0", "endpoints"}, peerKey + "\t192.0.2.1:51820\n"},
+		{[]string{"show", "wg0", "allowed-ips"}, peerKey + "\t10.0.0.2/32 fd00::2/128\n"},
+		{[]string{"show", "wg0", "preshared-keys"}, peerKey + "\t(none)\n"},
+		{[]string{"show", "wg0", "persistent-keepalive"}, peerKey + "\t25\n"},
+		{[]string{"show", "all", "latest-handshakes"}, "wg0\t" + peerKey + "\t0\n"},
+	} {
+		got, err := runWG(t, "", tt.args...)
+		if err != nil || got != tt.want {
+			t.Errorf("wg %s = %q, %v, want %q", strings.Join(tt.args, " "), got, err, tt.want)
+		}
+	}
+
+	got, err := runWG(t, "", "show", "wg0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"interface: wg0\n",
+		"  public key: " + pub + "\n",
+		"  private key: (hidden)\n",
+		"\npeer: " + peerKey + "\n",
+		"  endpoint: 192.0.2.1:51820\n",
+		"  allowed ips: 10.0.0.2/32, fd00::2/128\n",
+		"  persistent keepalive: every 25 seconds\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("wg show wg0 = %q, does not contain %q", got, want)
+		}
+	}
+
+	// The configuration round-trips through showconf and setconf.
+	conf, err := runWG(t, "", "showconf", "wg0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runWG(t, conf, "setconf", "wg0", "-"); err != nil {
+		t.Fatal(err)
+	}
+	if again, err := runWG(t, "", "showconf", "wg0"); err != nil || again != conf {
+		t.Errorf("showconf after setconf = %q, %v, want %q", again, err, conf)
+	}
+
+	// addconf keeps the existing peers.
+	psk, _ := wireguard.GenerateKey()
+	if _, err := runWG(t, "[Peer]\nPublicKey = "+psk.String()+"\nAllowedIPs = 10.0.0.3\n", "addconf", "wg0", "-"); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := runWG(t, "", "show", "wg0", "peers"); strings.Count(got, "\n") != 2 {
+		t.Errorf("peers after addconf = %q, want 2", got)
+	}
+	if _, err := runWG(t, "", "set", "wg0", "peer", psk.String(), "remove"); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := runWG(t, "", "show", "wg0", "peers"); got != peerKey+"\n" {
+		t.Errorf("peers after remove = %q, want %q", got, peerKey+"\n")
+	}
+}
+
+func TestUsage(t *testing.T) {
+	for _, args := range [][]string{
+		{"genkey", "x"},
+		{"wat"},
+		{"showconf"},
+		{"set"},
+		{"set", "wg0", "endpoint", "192.0.2.1:1"},
+		{"set", "wg0", "remove"},
+		{"set", "wg0", "peer"},
+		{"setconf", "wg0"},
+		{"show", "wg0", "dump", "x"},
+	} {
+		if _, err := runWG(t, "", args...); !errors.Is(err, errUsage) {
+			t.Errorf("wg %s = %v, want %v", strings.Join(args, " "), err, errUsage)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	for _, tt := range []struct {
+		d    time.Duration
+		want string
+	}{
+		{0, "Now"},
+		{time.Second, "1 second"},
+		{62 * time.Second, "1 minute, 2 seconds"},
+		{49*time.Hour + time.Second, "2 days, 1 hour, 1 second"},
+	} {
+		if got := ago(tt.d); got != tt.want {
+			t.Errorf("ago(%v) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+	for _, tt := range []struct {
+		n    int64
+		want string
+	}{
+		{100, "100 B"},
+		{1536, "1.50 KiB"},
+		{5 * 1024 * 1024, "5.00 MiB"},
+	} {
+		if got := bytes(tt.n); got != tt.want {
+			t.Errorf("bytes(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
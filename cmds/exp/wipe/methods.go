@@ -0,0 +1,476 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unsafe"
+
+	"github.com/u-root/u-root/pkg/mount/block"
+	"github.com/u-root/u-root/pkg/mount/scuzz"
+	"github.com/u-root/u-root/pkg/nvme"
+	"golang.org/x/sys/unix"
+)
+
+var (
+	// errUnsupported is returned by methods the device does not support.
+	// No data has been touched when it is returned.
+	errUnsupported = errors.New("not supported by the device")
+	// errSharedController is returned by nvme-sanitize when it would
+	// erase namespaces that were not selected or are mounted.
+	errSharedController = errors.New("sanitize would erase other namespaces of the controller")
+)
+
+// pollInterval is how often hardware erase progress is checked, and sysBlock
+// where block devices are found in sysfs. They are replaced in tests.
+var (
+	pollInterval = 5 * time.Second
+	sysBlock     = "/sys/class/block"
+)
+
+// nvmeNamespace matches NVMe namespace block devices, e.g. nvme0n1.
+var nvmeNamespace = regexp.MustCompile(`^(nvme[0-9]+)n([0-9]+)$`)
+
+// device is a device to wipe.
+type device struct {
+	path      string
+	name      string
+	size      uint64
+	blockSize int
+	serial    string
+	model     string
+}
+
+// probe returns the geometry and identity of path, which is either a
+// block device or, for disk images, a regular file.
+func probe(path string) (*device, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	d := &device{path: path, name: filepath.Base(path), blockSize: 512}
+	if fi.Mode().IsRegular() {
+		d.size = uint64(fi.Size())
+		return d, nil
+	}
+	if fi.Mode()&os.ModeDevice == 0 || fi.Mode()&os.ModeCharDevice != 0 {
+		return nil, fmt.Errorf("%s is not a block device", path)
+	}
+	b, err := block.Device(path)
+	if err != nil {
+		return nil, err
+	}
+	if d.size, err = b.Size(); err != nil {
+		return nil, err
+	}
+	if d.blockSize, err = b.BlockSize(); err != nil {
+		return nil, err
+	}
+	d.serial, d.model = identity(d)
+	return d, nil
+}
+
+func sysfsString(name, attr string) string {
+	b, err := os.ReadFile(filepath.Join(sysBlock, name, "device", attr))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
+
+// identity returns the serial number and model of d, asking the device
+// itself where possible.
+func identity(d *device) (string, string) {
+	if m := nvmeNamespace.FindStringSubmatch(d.name); m != nil {
+		if c, err := nvme.Open(filepath.Join("/dev", m[1])); err == nil {
+			defer c.Close()
+			if id, err := c.IdentifyController(); err == nil {
+				return id.Serial, id.Model
+			}
+		}
+	}
+	if disk, err := scuzz.NewSGDisk(d.path); err == nil {
+		defer disk.Close()
+		if i, err := disk.Identify(); err == nil {
+			return i.Serial, i.Model
+		}
+	}
+	serial := sysfsString(d.name, "serial")
+	if serial == "" {
+		serial = sysfsString(d.name, "wwid")
+	}
+	return serial, sysfsString(d.name, "model")
+}
+
+// method is a way of erasing a device.
+type method interface {
+	String() string
+	// wipe erases d, adding the bytes processed to done.
+	wipe(ctx context.Context, d *device, done *int64) error
+	// pattern returns the content of the device after wiping, or nil if
+	// it is undefined.
+	pattern() []byte
+}
+
+var methodNames = []string{"nvme-sanitize", "nvme-format", "ata-sanitize", "ata-erase", "secdiscard", "discard", "overwrite"}
+
+// selectMethods returns the methods to try on paths, strongest first.
+func selectMethods(o options, paths []string) ([]method, error) {
+	selected := map[string]bool{}
+	for _, p := range paths {
+		selected[filepath.Base(p)] = true
+	}
+	all := []method{
+		nvmeSanitize{selected: selected},
+		nvmeFormat{},
+		ataSanitize{},
+		ataErase{},
+		discard{secure: true},
+		discard{},
+		&overwrite{passes: o.passes, fill: o.pattern},
+	}
+	if o.method == "auto" {
+		return all, nil
+	}
+	for _, m := range all {
+		if m.String() == o.method {
+			return []method{m}, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown method %q, want auto or one of %v", o.method, methodNames)
+}
+
+// openNVMe opens the controller of an NVMe namespace and identifies it.
+func openNVMe(d *device) (*nvme.Device, *nvme.IdentifyController, error) {
+	m := nvmeNamespace.FindStringSubmatch(d.name)
+	if m == nil {
+		return nil, nil, errUnsupported
+	}
+	c, err := nvme.Open(filepath.Join("/dev", m[1]))
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", errUnsupported, err)
+	}
+	id, err := c.IdentifyController()
+	if err != nil {
+		c.Close()
+		return nil, nil, fmt.Errorf("%w: %v", errUnsupported, err)
+	}
+	return c, id, nil
+}
+
+// controller returns the NVMe controller of the namespace at path, whose
+// namespaces are wiped one at a time, or path itself for other devices.
+func controller(path string) string {
+	if m := nvmeNamespace.FindStringSubmatch(filepath.Base(path)); m != nil {
+		return m[1]
+	}
+	return path
+}
+
+// namespaces returns the namespace block devices of NVMe controller ctrl.
+func namespaces(ctrl string) ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(sysBlock, ctrl+"n*"))
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, m := range matches {
+		if name := filepath.Base(m); nvmeNamespace.MatchString(name) {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// nvmeSanitize sanitizes the whole NVM subsystem of the controller, which
+// covers every namespace. It refuses to unless the controller has only the
+// namespace being wiped, or all of its namespaces are selected, and none is
+// mounted.
+type nvmeSanitize struct {
+	// selected are the names of the devices being wiped.
+	selected map[string]bool
+}
+
+func (nvmeSanitize) String() string  { return "nvme-sanitize" }
+func (nvmeSanitize) pattern() []byte { return nil }
+
+func (m nvmeSanitize) wipe(ctx context.Context, d *device, done *int64) error {
+	if !nvmeNamespace.MatchString(d.name) {
+		return errUnsupported
+	}
+	ctrl := controller(d.name)
+	names, err := namespaces(ctrl)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(names, d.name) {
+		return fmt.Errorf("%w: namespaces of %s are not in %s", errSharedController, ctrl, sysBlock)
+	}
+	for _, ns := range names {
+		if mp, ok := mounted(filepath.Join("/dev", ns)); ok {
+			return fmt.Errorf("%w: %s is mounted on %s", errSharedController, ns, mp)
+		}
+		if len(names) > 1 && !m.selected[ns] {
+			return fmt.Errorf("%w: %s is not selected", errSharedController, ns)
+		}
+	}
+
+	c, id, err := openNVMe(d)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	var o nvme.SanitizeOptions
+	switch {
+	case id.SANICAP&nvme.SANICAPCryptoErase != 0:
+		o.Action = nvme.SanitizeCryptoErase
+	case id.SANICAP&nvme.SANICAPBlockErase != 0:
+		o.Action = nvme.SanitizeBlockErase
+	default:
+		return errUnsupported
+	}
+	if err := c.Sanitize(o); err != nil {
+		return err
+	}
+	var last int64
+	_, err = c.WaitSanitize(ctx, pollInterval, func(s *nvme.SanitizeStatus) {
+		n := int64(s.Percent() * float64(d.size) / 100)
+		atomic.AddInt64(done, n-last)
+		last = n
+	})
+	return err
+}
+
+type nvmeFormat struct{}
+
+func (nvmeFormat) String() string  { return "nvme-format" }
+func (nvmeFormat) pattern() []byte { return nil }
+
+func (nvmeFormat) wipe(ctx context.Context, d *device, done *int64) error {
+	c, id, err := openNVMe(d)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	if id.OACS&nvme.OACSFormat == 0 {
+		return errUnsupported
+	}
+	nsid, err := strconv.ParseUint(nvmeNamespace.FindStringSubmatch(d.name)[2], 10, 32)
+	if err != nil {
+		return err
+	}
+	n := uint32(nsid)
+	ns, err := c.IdentifyNamespace(n)
+	if err != nil {
+		return err
+	}
+	// Keep the current LBA format so the device geometry is unchanged.
+	o := nvme.FormatOptions{LBAFormat: uint8(ns.FormattedLBA())}
+	// A crypto erase may be rejected if the namespace does not
+	// support it; fall back to a user data erase.
+	for _, ses := range []nvme.SecureErase{nvme.CryptoErase, nvme.UserDataErase} {
+		o.SecureErase = ses
+		if err = c.Format(n, o); err == nil {
+			atomic.AddInt64(done, int64(d.size))
+			return nil
+		}
+	}
+	return err
+}
+
+// openATA opens d for ATA pass-through and returns its identity.
+func openATA(d *device) (*scuzz.SGDisk, *scuzz.Info, error) {
+	disk, err := scuzz.NewSGDisk(d.path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", errUnsupported, err)
+	}
+	info, err := disk.Identify()
+	if err != nil {
+		disk.Close()
+		return nil, nil, fmt.Errorf("%w: %v", errUnsupported, err)
+	}
+	return disk, info, nil
+}
+
+type ataSanitize struct{}
+
+func (ataSanitize) String() string  { return "ata-sanitize" }
+func (ataSanitize) pattern() []byte { return nil }
+
+func (ataSanitize) wipe(ctx context.Context, d *device, done *int64) error {
+	disk, info, err := openATA(d)
+	if err != nil {
+		return err
+	}
+	defer disk.Close()
+	var o scuzz.SanitizeOptions
+	switch c := info.SanitizeCapabilities; {
+	case !c.Supported():
+		return errUnsupported
+	case c.CryptoScramble():
+		o.Action = scuzz.SanitizeCryptoScramble
+	case c.BlockErase():
+		o.Action = scuzz.SanitizeBlockErase
+	default:
+		return errUnsupported
+	}
+	if err := disk.Sanitize(o); err != nil {
+		return err
+	}
+	var last int64
+	s, err := scuzz.WaitSanitize(ctx, disk, pollInterval, func(s *scuzz.SanitizeStatus) {
+		n := int64(s.Percent() * float64(d.size) / 100)
+		atomic.AddInt64(done, n-last)
+		last = n
+	})
+	if err != nil {
+		return err
+	}
+	if !s.Completed {
+		return fmt.Errorf("sanitize %v", s)
+	}
+	return nil
+}
+
+// ataPassword is the temporary user password set for a security erase.
+// The erase clears it again.
+const ataPassword = "u-root-wipe"
+
+type ataErase struct{}
+
+func (ataErase) String() string  { return "ata-erase" }
+func (ataErase) pattern() []byte { return nil }
+
+func (ataErase) wipe(ctx context.Context, d *device, done *int64) error {
+	disk, info, err := openATA(d)
+	if err != nil {
+		return err
+	}
+	defer disk.Close()
+	st := info.SecurityStatus
+	switch {
+	case !st.SecuritySupported():
+		return errUnsupported
+	case st.SecurityFrozen():
+		return fmt.Errorf("%w: security is frozen, suspend and resume the system to unfreeze it", errUnsupported)
+	case st.SecurityEnabled():
+		return fmt.Errorf("%w: a password is already set", errUnsupported)
+	}
+	if err := disk.SetPassword(ataPassword, false, scuzz.SecurityHigh); err != nil {
+		return err
+	}
+	if err := disk.SecurityErase(ataPassword, false, st.EnhancedEraseSupported()); err != nil {
+		// Do not leave the drive locked with our password.
+		if derr := disk.DisablePassword(ataPassword, false); derr != nil {
+			return fmt.Errorf("%w; disabling password %q: %v", err, ataPassword, derr)
+		}
+		return err
+	}
+	atomic.AddInt64(done, int64(d.size))
+	return nil
+}
+
+type discard struct {
+	secure bool
+}
+
+func (m discard) String() string {
+	if m.secure {
+		return "secdiscard"
+	}
+	return "discard"
+}
+
+func (discard) pattern() []byte { return nil }
+
+func (m discard) wipe(ctx context.Context, d *device, done *int64) error {
+	f, err := os.OpenFile(d.path, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	req := uintptr(unix.BLKDISCARD)
+	if m.secure {
+		req = unix.BLKSECDISCARD
+	}
+	r := [2]uint64{0, d.size}
+	if _, _, errno := unix.Syscall(unix.SYS_IOCTL, f.Fd(), req, uintptr(unsafe.Pointer(&r))); errno != 0 {
+		if errno == unix.ENOTTY || errno == unix.EOPNOTSUPP {
+			return errUnsupported
+		}
+		return os.NewSyscallError("ioctl", errno)
+	}
+	atomic.AddInt64(done, int64(d.size))
+	return nil
+}
+
+// overwrite writes random data passes times, then a final pass of fill.
+type overwrite struct {
+	passes int
+	fill   []byte
+}
+
+func (*overwrite) String() string    { return "overwrite" }
+func (m *overwrite) pattern() []byte { return m.fill }
+
+// bufSize is the write size of an overwrite.
+const bufSize = 1 << 20
+
+func (m *overwrite) wipe(ctx context.Context, d *device, done *int64) error {
+	f, err := os.OpenFile(d.path, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// Make the buffer a whole number of patterns so that the pattern
+	// repeats seamlessly across writes.
+	buf := make([]byte, bufSize-bufSize%len(m.fill))
+	var seed [32]byte
+	for p := 0; p <= m.passes; p++ {
+		random := p < m.passes
+		var rng *rand.ChaCha8
+		if random {
+			for i := range seed {
+				seed[i] = byte(rand.Uint32())
+			}
+			rng = rand.NewChaCha8(seed)
+		} else {
+			for i := range buf {
+				buf[i] = m.fill[i%len(m.fill)]
+			}
+		}
+		for off := uint64(0); off < d.size; {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			b := buf[:min(uint64(len(buf)), d.size-off)]
+			if random {
+				rng.Read(b)
+			}
+			n, err := f.WriteAt(b, int64(off))
+			atomic.AddInt64(done, int64(n))
+			if err != nil {
+				return err
+			}
+			off += uint64(n)
+		}
+		if err := f.Sync(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
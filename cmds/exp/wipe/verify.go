@@ -0,0 +1,134 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"math/rand/v2"
+	"os"
+	"slices"
+
+	"golang.org/x/sys/unix"
+)
+
+// maxFailedLBAs limits the failed blocks listed in a certificate.
+const maxFailedLBAs = 16
+
+// verification is the result of checking sampled blocks after a wipe.
+type verification struct {
+	Samples int `json:"samples"`
+	// Verified blocks were shown to be wiped.
+	Verified int `json:"verified"`
+	// Inconclusive blocks were uniform before the wipe and unchanged
+	// after it, which proves nothing either way.
+	Inconclusive int      `json:"inconclusive"`
+	Failed       int      `json:"failed"`
+	FailedLBAs   []uint64 `json:"failed_lbas,omitempty"`
+	Passed       bool     `json:"passed"`
+}
+
+type sampledBlock struct {
+	lba     uint64
+	sum     [sha256.Size]byte
+	uniform bool
+}
+
+// samples are blocks read before a wipe.
+type samples struct {
+	blocks []sampledBlock
+}
+
+func uniform(b []byte) bool {
+	return len(bytes.Trim(b, string(b[:1]))) == 0
+}
+
+// openUncached opens d for reading and drops cached pages, so reads come
+// from the device rather than from before the wipe.
+func openUncached(d *device) (*os.File, error) {
+	f, err := os.Open(d.path)
+	if err != nil {
+		return nil, err
+	}
+	fd := int(f.Fd())
+	// Neither is supported everywhere, and the page cache is only
+	// stale if the device was erased underneath it.
+	_ = unix.IoctlSetInt(fd, unix.BLKFLSBUF, 0)
+	_ = unix.Fadvise(fd, 0, 0, unix.FADV_DONTNEED)
+	return f, nil
+}
+
+// sample reads n random blocks of d, always including the first and last
+// blocks, where partition tables live.
+func sample(d *device, n int) (*samples, error) {
+	blocks := d.size / uint64(d.blockSize)
+	if blocks == 0 {
+		return &samples{}, nil
+	}
+	lbas := []uint64{0, blocks - 1}
+	for len(lbas) < n && uint64(len(lbas)) < blocks {
+		lbas = append(lbas, rand.Uint64N(blocks))
+	}
+	slices.Sort(lbas)
+	lbas = slices.Compact(lbas)
+
+	f, err := openUncached(d)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	s := &samples{}
+	b := make([]byte, d.blockSize)
+	for _, lba := range lbas {
+		if _, err := f.ReadAt(b, int64(lba)*int64(d.blockSize)); err != nil {
+			return nil, err
+		}
+		s.blocks = append(s.blocks, sampledBlock{lba: lba, sum: sha256.Sum256(b), uniform: uniform(b)})
+	}
+	return s, nil
+}
+
+// check re-reads the sampled blocks after a wipe. If pattern is not nil,
+// the device must be filled with it; otherwise each block must have
+// changed.
+func (s *samples) check(d *device, pattern []byte) (*verification, error) {
+	f, err := openUncached(d)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	v := &verification{Samples: len(s.blocks)}
+	b := make([]byte, d.blockSize)
+	want := make([]byte, d.blockSize)
+	for _, sb := range s.blocks {
+		off := int64(sb.lba) * int64(d.blockSize)
+		if _, err := f.ReadAt(b, off); err != nil {
+			return nil, err
+		}
+		var ok bool
+		switch {
+		case pattern != nil:
+			for i := range want {
+				want[i] = pattern[(off+int64(i))%int64(len(pattern))]
+			}
+			ok = bytes.Equal(b, want)
+		case sha256.Sum256(b) != sb.sum:
+			ok = true
+		case sb.uniform:
+			v.Inconclusive++
+			continue
+		}
+		if ok {
+			v.Verified++
+			continue
+		}
+		v.Failed++
+		if len(v.FailedLBAs) < maxFailedLBAs {
+			v.FailedLBAs = append(v.FailedLBAs, sb.lba)
+		}
+	}
+	v.Passed = v.Failed == 0
+	return v, nil
+}
@@ -0,0 +1,326 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// wipe securely erases block devices and verifies the result.
+//
+// Synopsis:
+//
+//	wipe [-method M] [-passes N] [-pattern HEX] [-samples N] [-status S] [-o FILE] -force DEVICE...
+//	wipe [options] -force -all
+//
+// Description:
+//
+//	For every device, wipe tries the erase methods from strongest to
+//	weakest until one succeeds and passes verification:
+//
+//	  nvme-sanitize   NVMe sanitize, crypto erase or block erase, of
+//	                  every namespace of the controller
+//	  nvme-format     NVMe Format NVM with crypto or user data erase
+//	  ata-sanitize    ATA SANITIZE, crypto scramble or block erase
+//	  ata-erase       ATA SECURITY ERASE UNIT, enhanced if supported
+//	  secdiscard      BLKSECDISCARD of the whole device
+//	  discard         BLKDISCARD of the whole device
+//	  overwrite       -passes passes of random data, then the pattern
+//
+//	-method restricts wipe to a single method. Regardless of the method,
+//	-samples random blocks are read before the wipe and again after it.
+//	After an overwrite every sampled block must hold the pattern; after
+//	any other method it must differ from what it held before. Blocks
+//	that were uniform before the wipe and still are cannot prove
+//	anything and are reported as inconclusive.
+//
+//	Devices are wiped in parallel, except the namespaces of an NVMe
+//	controller, which are wiped one at a time. A JSON certificate, keyed
+//	by device serial number, is written to stdout or to -o.
+//
+//	wipe refuses to touch mounted devices and does nothing without
+//	-force. nvme-sanitize is skipped unless every namespace of the
+//	controller is wiped, or it has just one, and none is mounted. -all
+//	selects every disk in /sys/class/block, excluding partitions and
+//	loop, ram, zram, device-mapper and optical devices.
+//
+// Options:
+//
+//	-all:     wipe all disks
+//	-force:   really destroy the data
+//	-method:  auto or one of the methods above (default auto)
+//	-passes:  random passes before the final pattern pass (default 1)
+//	-pattern: hex pattern of the final overwrite pass (default 00)
+//	-samples: number of blocks to verify (default 64)
+//	-status:  none, xfer or progress (default progress)
+//	-o:       write the certificate to a file instead of stdout
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/u-root/u-root/pkg/mount/block"
+	"github.com/u-root/u-root/pkg/progress"
+)
+
+var (
+	errNoForce   = errors.New("this destroys all data on the devices; use -force to proceed")
+	errNoDevices = errors.New("no devices given; name devices or use -all")
+	errFailed    = errors.New("wiping failed")
+)
+
+// mountsPath is replaced in tests.
+var mountsPath = "/proc/mounts"
+
+type options struct {
+	method  string
+	passes  int
+	pattern []byte
+	samples int
+}
+
+// attempt records one method tried on a device.
+type attempt struct {
+	Method string `json:"method"`
+	Error  string `json:"error,omitempty"`
+}
+
+// record is the certificate entry for one device.
+type record struct {
+	Device       string        `json:"device"`
+	Serial       string        `json:"serial,omitempty"`
+	Model        string        `json:"model,omitempty"`
+	Size         uint64        `json:"size"`
+	BlockSize    int           `json:"block_size"`
+	Method       string        `json:"method,omitempty"`
+	Passes       int           `json:"passes,omitempty"`
+	Pattern      string        `json:"pattern,omitempty"`
+	Attempts     []attempt     `json:"attempts"`
+	Verification *verification `json:"verification,omitempty"`
+	Started      time.Time     `json:"started"`
+	Finished     time.Time     `json:"finished"`
+	Success      bool          `json:"success"`
+	Error        string        `json:"error,omitempty"`
+}
+
+// certificate describes everything wipe did.
+type certificate struct {
+	Host     string             `json:"host"`
+	Started  time.Time          `json:"started"`
+	Finished time.Time          `json:"finished"`
+	Devices  map[string]*record `json:"devices"`
+}
+
+// skipDevice returns true for block devices that -all must not select.
+func skipDevice(name string) bool {
+	for _, p := range []string{"loop", "ram", "zram", "dm-", "sr", "md", "nbd"} {
+		if strings.HasPrefix(name, p) {
+			return true
+		}
+	}
+	// Partitions have a partition attribute in sysfs.
+	_, err := os.Stat(filepath.Join(sysBlock, name, "partition"))
+	return err == nil
+}
+
+func allDevices() ([]string, error) {
+	devs, err := block.GetBlockDevices()
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, d := range devs.FilterZeroSize() {
+		if !skipDevice(d.Name) {
+			paths = append(paths, d.DevicePath())
+		}
+	}
+	return paths, nil
+}
+
+// mounted returns the mount point of path or one of its partitions.
+func mounted(path string) (string, bool) {
+	b, err := os.ReadFile(mountsPath)
+	if err != nil {
+		return "", false
+	}
+	for _, l := range strings.Split(string(b), "\n") {
+		f := strings.Fields(l)
+		if len(f) < 2 {
+			continue
+		}
+		rest, ok := strings.CutPrefix(f[0], path)
+		if !ok {
+			continue
+		}
+		if rest == "" || strings.TrimLeft(strings.TrimPrefix(rest, "p"), "0123456789") == "" {
+			return f[1], true
+		}
+	}
+	return "", false
+}
+
+// wipeDevice wipes one device, trying each method in turn, and returns
+// its certificate record. done accumulates the bytes processed.
+func wipeDevice(ctx context.Context, path string, methods []method, o options, done *int64) *record {
+	r := &record{Device: path, Started: time.Now(), Attempts: []attempt{}}
+	defer func() { r.Finished = time.Now() }()
+
+	if mp, ok := mounted(path); ok {
+		r.Error = fmt.Sprintf("device is mounted on %s", mp)
+		return r
+	}
+	d, err := probe(path)
+	if err != nil {
+		r.Error = err.Error()
+		return r
+	}
+	r.Serial, r.Model, r.Size, r.BlockSize = d.serial, d.model, d.size, d.blockSize
+
+	before, err := sample(d, o.samples)
+	if err != nil {
+		r.Error = fmt.Sprintf("sampling blocks: %v", err)
+		return r
+	}
+	for _, m := range methods {
+		a := attempt{Method: m.String()}
+		err := m.wipe(ctx, d, done)
+		if err == nil {
+			r.Verification, err = before.check(d, m.pattern())
+			if err == nil && !r.Verification.Passed {
+				err = fmt.Errorf("verification failed on %d of %d blocks", r.Verification.Failed, len(before.blocks))
+			}
+		}
+		if err != nil {
+			a.Error = err.Error()
+			r.Attempts = append(r.Attempts, a)
+			continue
+		}
+		r.Attempts = append(r.Attempts, a)
+		r.Method = m.String()
+		if ow, ok := m.(*overwrite); ok {
+			r.Passes = ow.passes + 1
+			r.Pattern = hex.EncodeToString(ow.fill)
+		}
+		r.Success = true
+		return r
+	}
+	r.Verification = nil
+	r.Error = "no method succeeded"
+	return r
+}
+
+func run(ctx context.Context, stdout, stderr io.Writer, paths []string, status string, o options) (*certificate, error) {
+	if o.samples < 1 {
+		return nil, fmt.Errorf("%d samples: at least one is required", o.samples)
+	}
+	if o.passes < 0 {
+		return nil, fmt.Errorf("%d passes: must not be negative", o.passes)
+	}
+	methods, err := selectMethods(o, paths)
+	if err != nil {
+		return nil, err
+	}
+
+	host, _ := os.Hostname()
+	c := &certificate{Host: host, Started: time.Now(), Devices: map[string]*record{}}
+	records := make([]*record, len(paths))
+	var done int64
+	p := progress.New(stderr, status, &done)
+	p.Begin()
+	// Namespaces of a controller share it, and its sanitize.
+	byController := map[string][]int{}
+	for i, path := range paths {
+		byController[controller(path)] = append(byController[controller(path)], i)
+	}
+	var wg sync.WaitGroup
+	for _, idx := range byController {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, i := range idx {
+				records[i] = wipeDevice(ctx, paths[i], methods, o, &done)
+			}
+		}()
+	}
+	wg.Wait()
+	p.End()
+	c.Finished = time.Now()
+
+	failed := false
+	for _, r := range records {
+		key := r.Serial
+		if key == "" {
+			key = r.Device
+		}
+		if _, ok := c.Devices[key]; ok {
+			key = r.Device
+		}
+		c.Devices[key] = r
+		if !r.Success {
+			failed = true
+			log.Printf("%s: %s", r.Device, r.Error)
+		}
+	}
+	enc := json.NewEncoder(stdout)
+	enc.SetIndent("", "\t")
+	if err := enc.Encode(c); err != nil {
+		return c, err
+	}
+	if failed {
+		return c, errFailed
+	}
+	return c, nil
+}
+
+func main() {
+	var (
+		all     = flag.Bool("all", false, "wipe all disks")
+		force   = flag.Bool("force", false, "really destroy all data")
+		m       = flag.String("method", "auto", "auto or one of "+strings.Join(methodNames, ", "))
+		passes  = flag.Int("passes", 1, "random overwrite passes before the pattern pass")
+		pattern = flag.String("pattern", "00", "hex pattern of the final overwrite pass")
+		samples = flag.Int("samples", 64, "number of blocks to verify")
+		status  = flag.String("status", "progress", "none, xfer or progress")
+		out     = flag.String("o", "", "write the certificate to this file")
+	)
+	flag.Parse()
+
+	fill, err := hex.DecodeString(strings.TrimPrefix(*pattern, "0x"))
+	if err != nil || len(fill) == 0 {
+		log.Fatalf("pattern %q: must be a non-empty hex string", *pattern)
+	}
+	paths := flag.Args()
+	if *all {
+		if paths, err = allDevices(); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if len(paths) == 0 {
+		log.Fatal(errNoDevices)
+	}
+	if !*force {
+		log.Fatal(errNoForce)
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		w = f
+	}
+	o := options{method: *m, passes: *passes, pattern: fill, samples: *samples}
+	if _, err := run(context.Background(), w, os.Stderr, paths, *status, o); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,219 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testImage(t *testing.T, size int) string {
+	t.Helper()
+	b := make([]byte, size)
+	rand.Read(b)
+	p := filepath.Join(t.TempDir(), "disk.img")
+	if err := os.WriteFile(p, b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestOverwrite(t *testing.T) {
+	mountsPath = filepath.Join(t.TempDir(), "mounts")
+	imgs := []string{testImage(t, 3<<20+1000), testImage(t, 64<<10)}
+	var out bytes.Buffer
+	o := options{method: "overwrite", passes: 2, pattern: []byte{0xde, 0xad, 0xbe}, samples: 32}
+	c, err := run(context.Background(), &out, os.Stderr, imgs, "none", o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, img := range imgs {
+		b, err := os.ReadFile(img)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := range b {
+			if b[i] != o.pattern[i%3] {
+				t.Fatalf("%s: byte %d is %#x, want %#x", img, i, b[i], o.pattern[i%3])
+			}
+		}
+		r := c.Devices[img]
+		if r == nil {
+			t.Fatalf("no certificate record for %s: %v", img, c.Devices)
+		}
+		if !r.Success || r.Method != "overwrite" || r.Passes != 3 || r.Pattern != "deadbe" {
+			t.Errorf("record %+v", r)
+		}
+		if v := r.Verification; !v.Passed || v.Verified != v.Samples || v.Samples < 2 {
+			t.Errorf("verification %+v", v)
+		}
+	}
+
+	var got certificate
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Devices) != 2 {
+		t.Errorf("certificate has %d devices, want 2", len(got.Devices))
+	}
+}
+
+// fakeMethod pretends to wipe a device.
+type fakeMethod struct {
+	name  string
+	err   error
+	write bool
+}
+
+func (m *fakeMethod) String() string  { return m.name }
+func (m *fakeMethod) pattern() []byte { return nil }
+
+func (m *fakeMethod) wipe(ctx context.Context, d *device, done *int64) error {
+	if m.err != nil {
+		return m.err
+	}
+	if m.write {
+		b := make([]byte, d.size)
+		rand.Read(b)
+		return os.WriteFile(d.path, b, 0o644)
+	}
+	return nil
+}
+
+func TestFallback(t *testing.T) {
+	mountsPath = filepath.Join(t.TempDir(), "mounts")
+	img := testImage(t, 1<<20)
+	methods := []method{
+		&fakeMethod{name: "unsupported", err: errUnsupported},
+		&fakeMethod{name: "noop"},
+		&fakeMethod{name: "scramble", write: true},
+		&fakeMethod{name: "never", err: errors.New("should not be reached")},
+	}
+	var done int64
+	r := wipeDevice(context.Background(), img, methods, options{samples: 8}, &done)
+	if !r.Success || r.Method != "scramble" {
+		t.Fatalf("record %+v", r)
+	}
+	if len(r.Attempts) != 3 {
+		t.Fatalf("attempts %+v, want 3", r.Attempts)
+	}
+	if !strings.Contains(r.Attempts[1].Error, "verification failed") || r.Attempts[2].Error != "" {
+		t.Errorf("attempts %+v", r.Attempts)
+	}
+	if v := r.Verification; !v.Passed || v.Verified != v.Samples {
+		t.Errorf("verification %+v", v)
+	}
+}
+
+func TestUniformInconclusive(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "zero.img")
+	if err := os.WriteFile(p, make([]byte, 1<<16), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	d, err := probe(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := sample(d, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := s.check(d, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !v.Passed || v.Inconclusive != v.Samples || v.Verified != 0 {
+		t.Errorf("verification %+v", v)
+	}
+	if v, _ := s.check(d, []byte{1}); v.Passed || v.Failed != v.Samples || len(v.FailedLBAs) != v.Samples {
+		t.Errorf("pattern verification %+v", v)
+	}
+}
+
+func TestMounted(t *testing.T) {
+	mountsPath = filepath.Join(t.TempDir(), "mounts")
+	mounts := "/dev/sda2 / ext4 rw 0 0\n/dev/nvme0n1p1 /boot vfat rw 0 0\n/dev/sdaa /data xfs rw 0 0\n"
+	if err := os.WriteFile(mountsPath, []byte(mounts), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		dev string
+		mp  string
+	}{
+		{"/dev/sda", "/"},
+		{"/dev/nvme0n1", "/boot"},
+		{"/dev/sdaa", "/data"},
+		{"/dev/sdb", ""},
+		{"/dev/nvme0n2", ""},
+	} {
+		mp, ok := mounted(tt.dev)
+		if mp != tt.mp || ok != (tt.mp != "") {
+			t.Errorf("mounted(%q) = %q, %v, want %q", tt.dev, mp, ok, tt.mp)
+		}
+	}
+
+	var out bytes.Buffer
+	_, err := run(context.Background(), &out, os.Stderr, []string{"/dev/sda"}, "none", options{method: "auto", samples: 1, pattern: []byte{0}})
+	if !errors.Is(err, errFailed) || !strings.Contains(out.String(), "mounted on /") {
+		t.Errorf("wiping a mounted device: got %v and %s", err, out.String())
+	}
+}
+
+func TestOptions(t *testing.T) {
+	for _, o := range []options{
+		{method: "shred", samples: 1, pattern: []byte{0}},
+		{method: "auto", samples: 0, pattern: []byte{0}},
+		{method: "auto", samples: 1, passes: -1, pattern: []byte{0}},
+	} {
+		if _, err := run(context.Background(), &bytes.Buffer{}, os.Stderr, nil, "none", o); err == nil {
+			t.Errorf("run(%+v): got nil, want error", o)
+		}
+	}
+}
+
+func TestNVMeSanitizeShared(t *testing.T) {
+	sysBlock = t.TempDir()
+	defer func() { sysBlock = "/sys/class/block" }()
+	for _, name := range []string{"nvme0n1", "nvme0n1p1", "nvme0n2", "nvme1n1", "nvme1n2", "nvme2n1"} {
+		if err := os.Mkdir(filepath.Join(sysBlock, name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mountsPath = filepath.Join(t.TempDir(), "mounts")
+	if err := os.WriteFile(mountsPath, []byte("/dev/nvme1n2 /data ext4 rw 0 0\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		name     string
+		selected []string
+		err      error
+		want     string
+	}{
+		{"unselected namespace", []string{"/dev/nvme0n1"}, errSharedController, "nvme0n2 is not selected"},
+		{"mounted namespace", []string{"/dev/nvme1n1", "/dev/nvme1n2"}, errSharedController, "nvme1n2 is mounted on /data"},
+		// The controller is then opened, which there is none of.
+		{"only namespace", []string{"/dev/nvme2n1"}, errUnsupported, ""},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			methods, err := selectMethods(options{method: "nvme-sanitize"}, tt.selected)
+			if err != nil {
+				t.Fatal(err)
+			}
+			d := &device{path: tt.selected[0], name: filepath.Base(tt.selected[0])}
+			err = methods[0].wipe(context.Background(), d, new(int64))
+			if !errors.Is(err, tt.err) || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("wipe(%s) = %v, want %v about %q", d.name, err, tt.err, tt.want)
+			}
+		})
+	}
+}
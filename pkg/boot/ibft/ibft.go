@@ -268,7 +268,8 @@ type Target struct {
 	// Target is the server to connect to.
 	Target *net.TCPAddr
 
-	// BootLUN is the LUN to connect to.
+	// BootLUN is the LUN to connect to, in the eight bytes of SAM, first
+	// level first: LUN 1 is 0x0001000000000000.
 	BootLUN uint64
 
 	CHAPType uint8
@@ -304,7 +305,8 @@ func (t *Target) marshal(h *heapTable) {
 		writeIP6(h.Table, t.Target.IP)
 		h.Table.Write16(uint16(t.Target.Port))
 	}
-	h.Table.Write64(t.BootLUN)
+	// The table holds the bytes of the LUN in the order of SAM.
+	binary.BigEndian.PutUint64(h.Table.WriteN(8), t.BootLUN)
 	h.Table.Write8(t.CHAPType)
 	h.Table.Write8(t.NICAssociation)
 
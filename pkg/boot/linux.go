@@ -12,6 +12,7 @@ import (
 	"runtime"
 	"strings"
 
+	"github.com/u-root/u-root/pkg/boot/ibft"
 	"github.com/u-root/u-root/pkg/boot/kexec"
 	"github.com/u-root/u-root/pkg/boot/linux"
 	"github.com/u-root/u-root/pkg/boot/pez"
@@ -40,6 +41,11 @@ type LinuxImage struct {
 	// free to use this memory unless some other mechanism (such as
 	// memmap=) reserves it.
 	ReservedRanges kexec.Ranges
+
+	// IBFT, if set, is placed in memory for the kernel, which then finds
+	// the iSCSI target it was booted from. It requires the kexec_load
+	// system call, which is used regardless of LoadSyscall.
+	IBFT *ibft.IBFT
 }
 
 var _ OSImage = &LinuxImage{}
@@ -259,12 +265,15 @@ func (li *LinuxImage) Load(opts ...LoadOption) error {
 	}
 	loadOpts.logger.Printf("Command line: %s", li.Cmdline)
 	loadOpts.logger.Printf("DTB: %#v", li.DTB)
+	if li.IBFT != nil {
+		loadOpts.logger.Printf("iBFT: %s", li.IBFT)
+	}
 
 	if !loadOpts.callKexecLoad {
 		return nil
 	}
-	if li.LoadSyscall {
-		return linux.KexecLoad(k, i, li.Cmdline, li.DTB, li.ReservedRanges)
+	if li.LoadSyscall || li.IBFT != nil {
+		return linux.KexecLoad(k, i, li.Cmdline, li.DTB, li.ReservedRanges, li.IBFT)
 	}
 	return kexec.FileLoad(k, i, li.Cmdline)
 }
@@ -12,6 +12,7 @@ import (
 	"os"
 
 	"github.com/u-root/u-root/pkg/boot/bzimage"
+	"github.com/u-root/u-root/pkg/boot/ibft"
 	"github.com/u-root/u-root/pkg/boot/kexec"
 	"github.com/u-root/u-root/pkg/boot/purgatory"
 	"github.com/u-root/uio/uio"
@@ -24,8 +25,9 @@ const (
 // KexecLoad loads a bzImage-formated Linux kernel file as the to-be-kexeced
 // kernel with the given ramfs file and cmdline string.
 //
-// It uses the kexec_load system call.
-func KexecLoad(kernel, ramfs *os.File, cmdline string, dtb io.ReaderAt, reservations kexec.Ranges) error {
+// It uses the kexec_load system call. ibft, if not nil, is placed where the
+// kernel looks for an iBFT.
+func KexecLoad(kernel, ramfs *os.File, cmdline string, dtb io.ReaderAt, reservations kexec.Ranges, ibft *ibft.IBFT) error {
 	bzimage.Debug = Debug
 
 	// A collection of vars used for processing the kernel for kexec
@@ -86,6 +88,24 @@ func KexecLoad(kernel, ramfs *os.File, cmdline string, dtb io.ReaderAt, reservat
 		Phys: mm,
 	}
 
+	// Place the iBFT first, as it is the most restricted allocation.
+	if ibft != nil {
+		ibuf := ibft.Marshal()
+
+		// The iBFT may sit between 512K and 1M in physical memory.
+		// Kernels not booted with EFI find it by scanning this range.
+		allowedRange := kexec.Range{
+			Start: 0x80000,
+			Size:  0x80000,
+		}
+		r, err := kmem.ReservePhys(uint(len(ibuf)), allowedRange)
+		if err != nil {
+			return fmt.Errorf("reserving space for the iBFT in %s: %w", allowedRange, err)
+		}
+		kmem.Segments.Insert(kexec.NewSegment(ibuf, r))
+		Debug("Added %d byte iBFT at %s", len(ibuf), r)
+	}
+
 	var relocatableKernel bool
 	if bzimg.Header.Protocolversion < 0x0205 {
 		return fmt.Errorf("bzImage boot protocol earlier thatn 2.05 is not supported currently: %v", bzimg.Header.Protocolversion)
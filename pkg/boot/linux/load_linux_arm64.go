@@ -5,10 +5,12 @@
 package linux
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"os"
 
+	"github.com/u-root/u-root/pkg/boot/ibft"
 	"github.com/u-root/u-root/pkg/boot/kexec"
 )
 
@@ -17,7 +19,13 @@ import (
 // reservedRanges are additional pieces of physical memory that are not used
 // for kexec segment allocation. They are not transmitted to the next kernel to
 // be considered reserved.
-func KexecLoad(kernel, ramfs *os.File, cmdline string, dtb io.ReaderAt, reservedRanges kexec.Ranges) error {
+//
+// An iBFT is only found by arm64 kernels in the ACPI tables, so ibft must be
+// nil.
+func KexecLoad(kernel, ramfs *os.File, cmdline string, dtb io.ReaderAt, reservedRanges kexec.Ranges, ibft *ibft.IBFT) error {
+	if ibft != nil {
+		return fmt.Errorf("placing an iBFT: %w", errors.ErrUnsupported)
+	}
 	img, err := kexecLoadImage(kernel, ramfs, cmdline, dtb, reservedRanges)
 	if err != nil {
 		return err
@@ -10,11 +10,12 @@ import (
 	"io"
 	"os"
 
+	"github.com/u-root/u-root/pkg/boot/ibft"
 	"github.com/u-root/u-root/pkg/boot/kexec"
 	"golang.org/x/sys/unix"
 )
 
 // KexecLoad is not implemented for platforms other than amd64 and arm64.
-func KexecLoad(kernel, ramfs *os.File, cmdline string, dtb io.ReaderAt, reservations kexec.Ranges) error {
+func KexecLoad(kernel, ramfs *os.File, cmdline string, dtb io.ReaderAt, reservations kexec.Ranges, ibft *ibft.IBFT) error {
 	return unix.ENOSYS
 }
@@ -0,0 +1,214 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package netboot
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/u-root/u-root/pkg/boot"
+	"github.com/u-root/u-root/pkg/boot/ibft"
+	"github.com/u-root/u-root/pkg/boot/localboot"
+	"github.com/u-root/u-root/pkg/cmdline"
+	"github.com/u-root/u-root/pkg/dhclient"
+	"github.com/u-root/u-root/pkg/iscsi"
+	"github.com/u-root/u-root/pkg/mount"
+	"github.com/u-root/u-root/pkg/mount/block"
+	"github.com/u-root/u-root/pkg/ulog"
+)
+
+// loginTimeout bounds the login to iSCSI targets.
+const loginTimeout = 30 * time.Second
+
+// errNoImages is returned when the disks of the target have nothing to boot.
+var errNoImages = errors.New("no images found")
+
+// The steps of iSCSI boot that need the kernel's initiator and disks, which
+// tests replace.
+var (
+	attachISCSI     = (*iscsi.Session).Attach
+	detachISCSI     = (*iscsi.Session).Detach
+	blockDevices    = block.GetBlockDevices
+	localbootImages = localboot.Localboot
+)
+
+// ISCSIImages boots from the iSCSI target of the root path of lease, as
+// iPXE's sanboot does: it logs into the target as initiator, finds the
+// images on its disks as localboot does, and gives them an iBFT describing
+// the session, with which the kernels they boot log into the target again.
+//
+// The file systems of the disks are mounted in mp, to be unmounted before
+// kexec. If mp is nil, they stay mounted. If no images are found, they are
+// unmounted and the session is ended again.
+func ISCSIImages(ctx context.Context, l ulog.Logger, lease dhclient.Lease, initiator string, mp *mount.Pool) ([]boot.OSImage, error) {
+	addr, volume, err := lease.ISCSIBoot()
+	if err != nil {
+		return nil, err
+	}
+	lun, err := dhclient.ISCSIBootLUN(lease)
+	if err != nil {
+		return nil, err
+	}
+	l.Printf("iSCSI target: %s at %s", volume, addr)
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr.String())
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Now().Add(loginTimeout))
+	s, err := iscsi.Login(conn, &iscsi.Options{InitiatorName: initiator, TargetName: volume})
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("iSCSI target %s: %w", volume, err)
+	}
+	conn.SetDeadline(time.Time{})
+	names, err := attachISCSI(s)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("iSCSI target %s: %w", volume, err)
+	}
+	l.Printf("iSCSI target %s is %s", volume, strings.Join(names, ", "))
+
+	if mp == nil {
+		mp = &mount.Pool{}
+	}
+	n := len(mp.MountPoints)
+	imgs, err := diskImages(l, names, mp)
+	if err == nil && len(imgs) == 0 {
+		err = errNoImages
+	}
+	if err != nil {
+		// Nothing may stay mounted from the disks that go away.
+		if uerr := (&mount.Pool{MountPoints: mp.MountPoints[n:]}).UnmountAll(0); uerr != nil {
+			l.Printf("Unmounting the disks of iSCSI target %s: %v", volume, uerr)
+		}
+		mp.MountPoints = mp.MountPoints[:n]
+		if derr := detachISCSI(s); derr != nil {
+			l.Printf("Ending the session with iSCSI target %s: %v", volume, derr)
+		}
+		return nil, fmt.Errorf("iSCSI target %s: %w", volume, err)
+	}
+
+	t := newIBFT(lease, addr, lun, s)
+	for _, img := range imgs {
+		switch img := img.(type) {
+		case *boot.LinuxImage:
+			img.IBFT = t
+		case *boot.MultibootImage:
+			img.IBFT = t
+		}
+	}
+	return imgs, nil
+}
+
+// diskImages returns the images on the disks of names and their
+// partitions, whose file systems are mounted in mp.
+func diskImages(l ulog.Logger, names []string, mp *mount.Pool) ([]boot.OSImage, error) {
+	devs, err := blockDevices()
+	if err != nil {
+		return nil, err
+	}
+	return localbootImages(l, disks(devs, names), mp)
+}
+
+// disks returns the devices of devs that are one of the disks of names, or
+// one of their partitions.
+func disks(devs block.BlockDevices, names []string) block.BlockDevices {
+	var r block.BlockDevices
+	for _, d := range devs {
+		for _, n := range names {
+			part, ok := strings.CutPrefix(d.Name, n)
+			if ok && strings.Trim(strings.TrimPrefix(part, "p"), "0123456789") == "" {
+				r = append(r, d)
+				break
+			}
+		}
+	}
+	return r
+}
+
+// initiatorName is our iSCSI name: the rd.iscsi.initiator of the kernel
+// command line, as for dracut, or one made of the MAC address of the
+// interface of lease.
+func initiatorName(lease dhclient.Lease) string {
+	if name, ok := cmdline.Flag("rd.iscsi.initiator"); ok && name != "" {
+		return name
+	}
+	return "iqn.2017-02.org.u-root:" + hex.EncodeToString(lease.Link().Attrs().HardwareAddr)
+}
+
+// newIBFT describes the session s to LUN lun of target in an iBFT, with the
+// network configuration of lease.
+func newIBFT(lease dhclient.Lease, target *net.TCPAddr, lun uint64, s *iscsi.Session) *ibft.IBFT {
+	attrs := lease.Link().Attrs()
+	nic := ibft.NIC{
+		Valid:      true,
+		Boot:       true,
+		Origin:     ibft.OriginDHCP,
+		MACAddress: attrs.HardwareAddr,
+		PCIBDF:     pciBDF(attrs.Name),
+	}
+	var dns []net.IP
+	switch p := lease.(type) {
+	case *dhclient.Packet4:
+		nic.IPNet = p.Lease()
+		if r := p.P.Router(); len(r) > 0 {
+			nic.Gateway = r[0]
+		}
+		dns = p.P.DNS()
+		nic.DHCPServer = p.P.ServerIdentifier()
+		nic.HostName = p.P.HostName()
+	case *dhclient.Packet6:
+		if a := p.Lease(); a != nil {
+			nic.IPNet = &net.IPNet{IP: a.IPv6Addr, Mask: net.CIDRMask(128, 128)}
+		}
+		dns = p.DNS()
+	}
+	if len(dns) > 0 {
+		nic.PrimaryDNS = dns[0]
+	}
+	if len(dns) > 1 {
+		nic.SecondaryDNS = dns[1]
+	}
+	nic.Global = nic.IPNet != nil && !nic.IPNet.IP.IsLinkLocalUnicast()
+
+	return &ibft.IBFT{
+		Initiator: ibft.Initiator{
+			Valid: true,
+			Boot:  true,
+			Name:  s.Opts.InitiatorName,
+		},
+		NIC0: nic,
+		Target0: ibft.Target{
+			Valid:      true,
+			Boot:       true,
+			Target:     target,
+			BootLUN:    lun,
+			TargetName: s.Opts.TargetName,
+		},
+	}
+}
+
+// pciBDF returns the PCI address of network interface name, or zero if it
+// is not a PCI device.
+func pciBDF(name string) ibft.BDF {
+	dev, err := os.Readlink(filepath.Join("/sys/class/net", name, "device"))
+	if err != nil {
+		return ibft.BDF{}
+	}
+	var domain, bus, device, function uint8
+	if _, err := fmt.Sscanf(filepath.Base(dev), "%04x:%02x:%02x.%x", &domain, &bus, &device, &function); err != nil {
+		return ibft.BDF{}
+	}
+	return ibft.BDF{Bus: bus, Device: device, Function: function}
+}
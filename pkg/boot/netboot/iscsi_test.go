@@ -0,0 +1,214 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package netboot
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/insomniacslk/dhcp/dhcpv4"
+	"github.com/u-root/u-root/pkg/boot"
+	"github.com/u-root/u-root/pkg/boot/localboot"
+	"github.com/u-root/u-root/pkg/curl"
+	"github.com/u-root/u-root/pkg/dhclient"
+	"github.com/u-root/u-root/pkg/iscsi"
+	"github.com/u-root/u-root/pkg/iscsi/iscsitest"
+	"github.com/u-root/u-root/pkg/mount"
+	"github.com/u-root/u-root/pkg/mount/block"
+	"github.com/u-root/u-root/pkg/ulog"
+	"github.com/u-root/u-root/pkg/ulog/ulogtest"
+	"github.com/vishvananda/netlink"
+)
+
+func TestDisks(t *testing.T) {
+	var devs block.BlockDevices
+	for _, name := range []string{"sda", "sda1", "sdb", "sdb1", "sdb12", "sdba", "sdba1", "nvme0n1", "nvme0n1p2", "nvme0n10"} {
+		devs = append(devs, &block.BlockDev{Name: name})
+	}
+	var got []string
+	for _, d := range disks(devs, []string{"sdb", "nvme0n1"}) {
+		got = append(got, d.Name)
+	}
+	want := []string{"sdb", "sdb1", "sdb12", "nvme0n1", "nvme0n1p2", "nvme0n10"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("disks = %v, want %v", got, want)
+	}
+}
+
+func TestISCSIBoot(t *testing.T) {
+	const volume = "iqn.2026-10.org.u-root:disk"
+	tgt := &iscsitest.Target{Name: volume}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go tgt.Serve(ln)
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	var attached *iscsi.Session
+	attachISCSI = func(s *iscsi.Session) ([]string, error) {
+		attached = s
+		t.Cleanup(func() { s.Conn.Close() })
+		return []string{"sdb"}, nil
+	}
+	blockDevices = func() (block.BlockDevices, error) {
+		return block.BlockDevices{{Name: "sda"}, {Name: "sda1"}, {Name: "sdb"}, {Name: "sdb1"}}, nil
+	}
+	var searched []string
+	localbootImages = func(l ulog.Logger, devs block.BlockDevices, mp *mount.Pool) ([]boot.OSImage, error) {
+		for _, d := range devs {
+			searched = append(searched, d.Name)
+		}
+		return []boot.OSImage{&boot.LinuxImage{Name: "linux"}, &boot.MultibootImage{Name: "multiboot"}}, nil
+	}
+	defer func() {
+		attachISCSI = (*iscsi.Session).Attach
+		blockDevices = block.GetBlockDevices
+		localbootImages = localboot.Localboot
+	}()
+
+	mac := net.HardwareAddr{0x52, 0x54, 0, 0x12, 0x34, 0x56}
+	link := &netlink.Dummy{LinkAttrs: netlink.LinkAttrs{Name: "iscsitest0", HardwareAddr: mac}}
+	p, err := dhcpv4.New(
+		dhcpv4.WithYourIP(net.IPv4(192, 168, 0, 10)),
+		dhcpv4.WithNetmask(net.CIDRMask(24, 32)),
+		dhcpv4.WithOption(dhcpv4.OptRouter(net.IPv4(192, 168, 0, 1))),
+		dhcpv4.WithOption(dhcpv4.OptDNS(net.IPv4(192, 168, 0, 2), net.IPv4(192, 168, 0, 3))),
+		dhcpv4.WithOption(dhcpv4.OptServerIdentifier(net.IPv4(192, 168, 0, 4))),
+		dhcpv4.WithOption(dhcpv4.OptHostName("client")),
+		dhcpv4.WithOption(dhcpv4.OptRootPath(fmt.Sprintf("iscsi:127.0.0.1::%d:1:%s", port, volume))),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lease := dhclient.NewPacket4(link, p)
+
+	// Without a boot file, the root path is booted from.
+	imgs, err := BootImages(context.Background(), ulogtest.Logger{TB: t}, curl.DefaultSchemes, lease, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attached == nil || attached.TSIH == 0 {
+		t.Fatalf("session attached is %+v, want a logged in session", attached)
+	}
+	if want := []string{"sdb", "sdb1"}; !reflect.DeepEqual(searched, want) {
+		t.Errorf("searched %v for images, want %v", searched, want)
+	}
+
+	logins := tgt.Logins()
+	if len(logins) != 1 || logins[0].Err != nil || logins[0].Keys["InitiatorName"] != initiatorName(lease) {
+		t.Errorf("target got logins %+v, want one from %s", logins, initiatorName(lease))
+	}
+
+	if len(imgs) != 2 {
+		t.Fatalf("got %d images, want 2", len(imgs))
+	}
+	ibft := imgs[0].(*boot.LinuxImage).IBFT
+	if ibft == nil || imgs[1].(*boot.MultibootImage).IBFT != ibft {
+		t.Fatalf("images have iBFT %v and %v, want the same one", ibft, imgs[1].(*boot.MultibootImage).IBFT)
+	}
+	if ibft.Initiator.Name != initiatorName(lease) || ibft.Target0.TargetName != volume || ibft.Target0.Target.Port != port {
+		t.Errorf("iBFT has initiator %s, target %s at %s", ibft.Initiator.Name, ibft.Target0.TargetName, ibft.Target0.Target)
+	}
+	if ibft.Target0.BootLUN != 0x0001000000000000 {
+		t.Errorf("iBFT has LUN %#x, want LUN 1", ibft.Target0.BootLUN)
+	}
+	nic := ibft.NIC0
+	if nic.IPNet.String() != "192.168.0.10/24" || !nic.Gateway.Equal(net.IPv4(192, 168, 0, 1)) ||
+		!nic.PrimaryDNS.Equal(net.IPv4(192, 168, 0, 2)) || !nic.SecondaryDNS.Equal(net.IPv4(192, 168, 0, 3)) ||
+		!nic.DHCPServer.Equal(net.IPv4(192, 168, 0, 4)) || nic.HostName != "client" ||
+		!bytes.Equal(nic.MACAddress, mac) || !nic.Global || !nic.Boot {
+		t.Errorf("iBFT NIC is %+v", nic)
+	}
+	if b := ibft.Marshal(); !bytes.HasPrefix(b, []byte("iBFT")) {
+		t.Errorf("iBFT table starts with %q, want iBFT", b[:4])
+	}
+}
+
+func TestISCSIBootNoImages(t *testing.T) {
+	const volume = "iqn.2026-10.org.u-root:disk"
+	tgt := &iscsitest.Target{Name: volume}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go tgt.Serve(ln)
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	var detached int
+	attachISCSI = func(s *iscsi.Session) ([]string, error) {
+		return []string{"sdb"}, nil
+	}
+	detachISCSI = func(s *iscsi.Session) error {
+		detached++
+		return s.Conn.Close()
+	}
+	defer func() {
+		attachISCSI = (*iscsi.Session).Attach
+		detachISCSI = (*iscsi.Session).Detach
+		blockDevices = block.GetBlockDevices
+		localbootImages = localboot.Localboot
+	}()
+
+	link := &netlink.Dummy{LinkAttrs: netlink.LinkAttrs{Name: "iscsitest0", HardwareAddr: net.HardwareAddr{0x52, 0x54, 0, 0x12, 0x34, 0x56}}}
+	p, err := dhcpv4.New(
+		dhcpv4.WithYourIP(net.IPv4(192, 168, 0, 10)),
+		dhcpv4.WithOption(dhcpv4.OptRootPath(fmt.Sprintf("iscsi:127.0.0.1::%d::%s", port, volume))),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lease := dhclient.NewPacket4(link, p)
+
+	errDisk := errors.New("disk gone")
+	for _, tt := range []struct {
+		name   string
+		devs   func() (block.BlockDevices, error)
+		images func(ulog.Logger, block.BlockDevices, *mount.Pool) ([]boot.OSImage, error)
+		err    error
+	}{
+		{
+			name:   "no block devices",
+			devs:   func() (block.BlockDevices, error) { return nil, errDisk },
+			images: localboot.Localboot,
+			err:    errDisk,
+		},
+		{
+			name: "localboot fails",
+			devs: func() (block.BlockDevices, error) { return block.BlockDevices{{Name: "sdb"}}, nil },
+			images: func(ulog.Logger, block.BlockDevices, *mount.Pool) ([]boot.OSImage, error) {
+				return nil, errDisk
+			},
+			err: errDisk,
+		},
+		{
+			name: "no images",
+			devs: func() (block.BlockDevices, error) { return block.BlockDevices{{Name: "sdb"}}, nil },
+			images: func(ulog.Logger, block.BlockDevices, *mount.Pool) ([]boot.OSImage, error) {
+				return nil, nil
+			},
+			err: errNoImages,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			detached = 0
+			blockDevices, localbootImages = tt.devs, tt.images
+			imgs, err := ISCSIImages(context.Background(), ulogtest.Logger{TB: t}, lease, initiatorName(lease), nil)
+			if !errors.Is(err, tt.err) || imgs != nil {
+				t.Errorf("ISCSIImages() = %v, %v, want %v", imgs, err, tt.err)
+			}
+			if detached != 1 {
+				t.Errorf("session detached %d times, want once", detached)
+			}
+		})
+	}
+}
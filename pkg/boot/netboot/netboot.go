@@ -5,9 +5,7 @@
 // Package netboot provides a one-stop shop for netboot parsing needs.
 //
 // netboot can take a URL from a DHCP lease and try to detect iPXE scripts and
-// PXE scripts.
-//
-// TODO: detect iSCSI root paths.
+// PXE scripts, or boot from the iSCSI target of its root path.
 package netboot
 
 import (
@@ -22,6 +20,7 @@ import (
 	"github.com/u-root/u-root/pkg/boot/netboot/simple"
 	"github.com/u-root/u-root/pkg/curl"
 	"github.com/u-root/u-root/pkg/dhclient"
+	"github.com/u-root/u-root/pkg/mount"
 	"github.com/u-root/u-root/pkg/ulog"
 )
 
@@ -33,8 +32,17 @@ import (
 //
 //   - to detect a pxelinux.0, in which case we will ignore the pxelinux.0 and
 //     try to parse pxelinux.cfg/<files>.
-func BootImages(ctx context.Context, l ulog.Logger, s curl.Schemes, lease dhclient.Lease) ([]boot.OSImage, error) {
+//
+// If the lease has no boot file but an iSCSI root path, or its boot file is
+// an iscsi: URI, the images are those on the target, as with ISCSIImages,
+// whose file systems are mounted in mp.
+func BootImages(ctx context.Context, l ulog.Logger, s curl.Schemes, lease dhclient.Lease, mp *mount.Pool) ([]boot.OSImage, error) {
 	uri, err := lease.Boot()
+	if err != nil || uri.Scheme == "iscsi" {
+		if _, _, ierr := lease.ISCSIBoot(); ierr == nil {
+			return ISCSIImages(ctx, l, lease, initiatorName(lease), mp)
+		}
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -6,6 +6,7 @@ package health
 
 import (
 	"github.com/jaypipes/ghw"
+	"github.com/u-root/u-root/pkg/lldp"
 )
 
 // Kernel should hold all stats for the kernel.
@@ -22,6 +23,9 @@ type Stat struct {
 	Hostname string
 	Info     *ghw.HostInfo
 	Kernel   Kernel
+	// LLDP are the switch ports and other neighbors that the
+	// node's lldpctl daemon has seen, if it runs.
+	LLDP []lldp.Neighbor `json:",omitempty"`
 	// This should be empty, but some packages behave badly.
 	Stderr string
 	Err    string
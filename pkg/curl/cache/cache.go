@@ -0,0 +1,246 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package cache implements a persistent content-addressed cache for the files
+// of curl.FileSchemes.
+//
+// Files are stored once per SHA-256 hash, as blobs/<hash> in the cache
+// directory, and index/ maps URLs to the blob of their last validated version.
+// A Scheme fetches through a Cache: files with an expected hash are served
+// without a request once cached, and others are revalidated with conditional
+// requests. Peers shares the cache with the other nodes of a network segment,
+// which find each other with multicast queries.
+//
+// The cache is safe to share between processes: blobs and index entries are
+// written to tmp/ and renamed into place.
+package cache
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/u-root/u-root/pkg/curl"
+)
+
+// ErrHashMismatch is returned when fetched contents do not have the expected
+// SHA-256 hash.
+var ErrHashMismatch = errors.New("SHA-256 hash mismatch")
+
+// Cache is a content-addressed cache in a directory.
+type Cache struct {
+	dir string
+
+	// MaxSize bounds the total size of the blobs in bytes. The least
+	// recently used blobs are removed past it. 0 means no bound.
+	MaxSize int64
+
+	mu sync.Mutex
+}
+
+// New returns a cache in dir, which it creates if needed.
+func New(dir string) (*Cache, error) {
+	for _, d := range []string{"blobs", "index", "tmp"} {
+		if err := os.MkdirAll(filepath.Join(dir, d), 0o755); err != nil {
+			return nil, err
+		}
+	}
+	return &Cache{dir: dir}, nil
+}
+
+type sha256Key struct{}
+
+// WithSHA256 returns a context that tells Scheme the expected SHA-256 hash of
+// the file it fetches. The file is then served from the cache or peers without
+// asking the origin, and rejected with ErrHashMismatch if the origin serves
+// something else.
+func WithSHA256(ctx context.Context, sum []byte) context.Context {
+	return context.WithValue(ctx, sha256Key{}, sum)
+}
+
+func sha256From(ctx context.Context) []byte {
+	sum, _ := ctx.Value(sha256Key{}).([]byte)
+	return sum
+}
+
+func (c *Cache) blobPath(sum []byte) string {
+	return filepath.Join(c.dir, "blobs", hex.EncodeToString(sum))
+}
+
+func (c *Cache) indexPath(u string) string {
+	h := sha256.Sum256([]byte(u))
+	return filepath.Join(c.dir, "index", hex.EncodeToString(h[:])+".json")
+}
+
+// Open opens the blob with SHA-256 hash sum, and marks it as recently used.
+func (c *Cache) Open(sum []byte) (*os.File, error) {
+	if len(sum) != sha256.Size {
+		return nil, fmt.Errorf("%x: %w", sum, fs.ErrNotExist)
+	}
+	p := c.blobPath(sum)
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	// Pruning merely gets less accurate if this fails.
+	_ = os.Chtimes(p, now, now)
+	return f, nil
+}
+
+// Has returns whether the blob with SHA-256 hash sum is cached.
+func (c *Cache) Has(sum []byte) bool {
+	if len(sum) != sha256.Size {
+		return false
+	}
+	_, err := os.Stat(c.blobPath(sum))
+	return err == nil
+}
+
+// size returns the size of the blob with SHA-256 hash sum.
+func (c *Cache) size(sum []byte) (int64, error) {
+	if len(sum) != sha256.Size {
+		return 0, fmt.Errorf("%x: %w", sum, fs.ErrNotExist)
+	}
+	fi, err := os.Stat(c.blobPath(sum))
+	if err != nil {
+		return 0, err
+	}
+	return fi.Size(), nil
+}
+
+// Put stores the contents of r, and returns their SHA-256 hash. If want is
+// not nil, the contents must hash to it.
+func (c *Cache) Put(r io.Reader, want []byte) ([]byte, error) {
+	f, err := os.CreateTemp(filepath.Join(c.dir, "tmp"), "blob-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+
+	h := sha256.New()
+	_, err = io.Copy(io.MultiWriter(f, h), r)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, err
+	}
+	sum := h.Sum(nil)
+	if want != nil && !bytes.Equal(sum, want) {
+		return nil, fmt.Errorf("got %x, want %x: %w", sum, want, ErrHashMismatch)
+	}
+	if err := os.Rename(f.Name(), c.blobPath(sum)); err != nil {
+		return nil, err
+	}
+	if err := c.prune(sum); err != nil {
+		return nil, err
+	}
+	return sum, nil
+}
+
+// entry is an index entry, the blob of the last validated version of a URL.
+type entry struct {
+	URL       string         `json:"url"`
+	Validator curl.Validator `json:"validator"`
+	SHA256    string         `json:"sha256"`
+}
+
+func (e *entry) sum() []byte {
+	sum, err := hex.DecodeString(e.SHA256)
+	if err != nil {
+		return nil
+	}
+	return sum
+}
+
+// lookup returns the index entry of u, or nil if there is none or its blob is
+// gone.
+func (c *Cache) lookup(u string) *entry {
+	b, err := os.ReadFile(c.indexPath(u))
+	if err != nil {
+		return nil
+	}
+	var e entry
+	if err := json.Unmarshal(b, &e); err != nil || e.URL != u || !c.Has(e.sum()) {
+		return nil
+	}
+	return &e
+}
+
+// record stores the index entry of u.
+func (c *Cache) record(u string, v curl.Validator, sum []byte) error {
+	b, err := json.Marshal(&entry{URL: u, Validator: v, SHA256: hex.EncodeToString(sum)})
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Join(c.dir, "tmp"), "index-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.Write(b)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), c.indexPath(u))
+}
+
+// prune removes the least recently used blobs other than keep until the blobs
+// fit in MaxSize. Index entries of removed blobs are ignored by lookup.
+func (c *Cache) prune(keep []byte) error {
+	if c.MaxSize <= 0 {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	dir := filepath.Join(c.dir, "blobs")
+	ents, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	type blob struct {
+		name  string
+		size  int64
+		mtime time.Time
+	}
+	var blobs []blob
+	var total int64
+	for _, e := range ents {
+		fi, err := e.Info()
+		if err != nil || !fi.Mode().IsRegular() {
+			continue
+		}
+		total += fi.Size()
+		if e.Name() != hex.EncodeToString(keep) {
+			blobs = append(blobs, blob{e.Name(), fi.Size(), fi.ModTime()})
+		}
+	}
+	sort.Slice(blobs, func(i, j int) bool { return blobs[i].mtime.Before(blobs[j].mtime) })
+	for _, b := range blobs {
+		if total <= c.MaxSize {
+			break
+		}
+		if err := os.Remove(filepath.Join(dir, b.name)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		total -= b.size
+	}
+	return nil
+}
@@ -0,0 +1,426 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/u-root/u-root/pkg/curl"
+)
+
+// origin serves one file, and counts the requests for it.
+type origin struct {
+	mu      sync.Mutex
+	content string
+	etag    string
+	// digest is whether the hash of content is sent in Repr-Digest.
+	digest bool
+
+	gets, notModified, heads int
+}
+
+func (o *origin) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	w.Header().Set("ETag", o.etag)
+	if o.digest {
+		sum := sha256.Sum256([]byte(o.content))
+		w.Header().Set("Repr-Digest", "sha-256=:"+base64.StdEncoding.EncodeToString(sum[:])+":")
+	}
+	switch {
+	case r.Method == http.MethodHead:
+		o.heads++
+	case r.Header.Get("If-None-Match") == o.etag:
+		o.notModified++
+		w.WriteHeader(http.StatusNotModified)
+	default:
+		o.gets++
+		io.WriteString(w, o.content)
+	}
+}
+
+func (o *origin) set(content, etag string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.content, o.etag = content, etag
+}
+
+func (o *origin) counts() [3]int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return [3]int{o.gets, o.notModified, o.heads}
+}
+
+func serveOrigin(t *testing.T, content, etag string) (*origin, *url.URL) {
+	t.Helper()
+	o := &origin{content: content, etag: etag}
+	srv := httptest.NewServer(o)
+	t.Cleanup(srv.Close)
+	u, err := url.Parse(srv.URL + "/vmlinuz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return o, u
+}
+
+func newCache(t *testing.T, dir string) *Cache {
+	t.Helper()
+	c, err := New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func fetch(t *testing.T, ctx context.Context, s curl.FileScheme, u *url.URL) (string, error) {
+	t.Helper()
+	r, err := s.FetchWithoutCache(ctx, u)
+	if err != nil {
+		return "", err
+	}
+	if c, ok := r.(io.Closer); ok {
+		defer c.Close()
+	}
+	b, err := io.ReadAll(r)
+	return string(b), err
+}
+
+func TestRevalidate(t *testing.T) {
+	o, u := serveOrigin(t, "kernel 1", `"1"`)
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	for i, tt := range []struct {
+		content, etag string
+		want          [3]int
+	}{
+		{"kernel 1", `"1"`, [3]int{1, 0, 0}},
+		{"kernel 1", `"1"`, [3]int{1, 1, 0}},
+		{"kernel 2", `"2"`, [3]int{2, 1, 0}},
+		{"kernel 2", `"2"`, [3]int{2, 2, 0}},
+	} {
+		o.set(tt.content, tt.etag)
+		// A new Cache each time shows that the cache persists.
+		s := &Scheme{Scheme: curl.DefaultHTTPClient, Cache: newCache(t, dir)}
+		got, err := fetch(t, ctx, s, u)
+		if err != nil || got != tt.content {
+			t.Errorf("fetch %d = %q, %v, want %q", i, got, err, tt.content)
+		}
+		if c := o.counts(); c != tt.want {
+			t.Errorf("fetch %d: origin got (GET, 304, HEAD) %v, want %v", i, c, tt.want)
+		}
+	}
+}
+
+func TestSHA256(t *testing.T) {
+	o, u := serveOrigin(t, "initramfs", `"1"`)
+	s := &Scheme{Scheme: curl.DefaultHTTPClient, Cache: newCache(t, t.TempDir())}
+	sum := sha256.Sum256([]byte("initramfs"))
+	ctx := WithSHA256(context.Background(), sum[:])
+
+	for i := range 2 {
+		got, err := fetch(t, ctx, s, u)
+		if err != nil || got != "initramfs" {
+			t.Errorf("fetch %d = %q, %v, want %q", i, got, err, "initramfs")
+		}
+	}
+	// The second fetch does not even revalidate.
+	if c, want := o.counts(), [3]int{1, 0, 0}; c != want {
+		t.Errorf("origin got (GET, 304, HEAD) %v, want %v", c, want)
+	}
+
+	bad := sha256.Sum256([]byte("something else"))
+	if _, err := fetch(t, WithSHA256(context.Background(), bad[:]), s, u); !errors.Is(err, ErrHashMismatch) {
+		t.Errorf("fetch with wrong hash = %v, want %v", err, ErrHashMismatch)
+	}
+	if s.Cache.Has(bad[:]) {
+		t.Errorf("blob of the wrong hash is cached")
+	}
+}
+
+func TestPassThrough(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(p, []byte("local"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	s := &Scheme{Scheme: &curl.LocalFileClient{}, Cache: newCache(t, dir)}
+	ctx := context.Background()
+	u := &url.URL{Scheme: "file", Path: p}
+
+	if got, err := fetch(t, ctx, s, u); err != nil || got != "local" {
+		t.Errorf("fetch = %q, %v, want %q", got, err, "local")
+	}
+	if ents, _ := os.ReadDir(filepath.Join(dir, "blobs")); len(ents) != 0 {
+		t.Errorf("file without validator or hash was cached: %v", ents)
+	}
+
+	// With a hash, any scheme is cached.
+	sum := sha256.Sum256([]byte("local"))
+	if got, err := fetch(t, WithSHA256(ctx, sum[:]), s, u); err != nil || got != "local" {
+		t.Errorf("fetch = %q, %v, want %q", got, err, "local")
+	}
+	if !s.Cache.Has(sum[:]) {
+		t.Errorf("file with hash was not cached")
+	}
+}
+
+func TestPrune(t *testing.T) {
+	c := newCache(t, t.TempDir())
+	c.MaxSize = 10
+	var sums [][]byte
+	for i, s := range []string{"aaaa", "bbbb", "cccc"} {
+		sum, err := c.Put(strings.NewReader(s), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Order the blobs by use.
+		mtime := time.Now().Add(time.Duration(i-10) * time.Minute)
+		if err := os.Chtimes(c.blobPath(sum), mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+		sums = append(sums, sum)
+	}
+	for i, want := range []bool{false, true, true} {
+		if got := c.Has(sums[i]); got != want {
+			t.Errorf("blob %d cached = %t, want %t", i, got, want)
+		}
+	}
+}
+
+// servePeer serves the cache of p to peers on loopback, and returns the
+// address its queries go to.
+func servePeer(t *testing.T, p *Peers) *net.UDPAddr {
+	t.Helper()
+	srv := httptest.NewServer(p.Handler())
+	t.Cleanup(srv.Close)
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- p.Serve(conn, srv.Listener.Addr().(*net.TCPAddr).Port) }()
+	t.Cleanup(func() {
+		conn.Close()
+		if err := <-done; err != nil {
+			t.Errorf("Serve = %v", err)
+		}
+	})
+	return conn.LocalAddr().(*net.UDPAddr)
+}
+
+func TestPeers(t *testing.T) {
+	o, u := serveOrigin(t, "kernel", `"1"`)
+	o.digest = true
+	ctx := context.Background()
+
+	// The first node fetches from the origin, and serves the others.
+	c1 := newCache(t, t.TempDir())
+	s1 := &Scheme{Scheme: curl.DefaultHTTPClient, Cache: c1}
+	if got, err := fetch(t, ctx, s1, u); err != nil || got != "kernel" {
+		t.Fatalf("fetch = %q, %v, want %q", got, err, "kernel")
+	}
+	group := servePeer(t, &Peers{Cache: c1})
+
+	// The second node asks the origin for the version and hash only.
+	c2 := newCache(t, t.TempDir())
+	s2 := &Scheme{
+		Scheme: curl.DefaultHTTPClient,
+		Cache:  c2,
+		Peers:  &Peers{Cache: c2, Group: group, Wait: 5 * time.Second},
+	}
+	if got, err := fetch(t, ctx, s2, u); err != nil || got != "kernel" {
+		t.Errorf("fetch from peer = %q, %v, want %q", got, err, "kernel")
+	}
+	if c, want := o.counts(), [3]int{1, 0, 1}; c != want {
+		t.Errorf("origin got (GET, 304, HEAD) %v, want %v", c, want)
+	}
+	// It revalidates its copy later.
+	if got, err := fetch(t, ctx, s2, u); err != nil || got != "kernel" {
+		t.Errorf("fetch = %q, %v, want %q", got, err, "kernel")
+	}
+	if c, want := o.counts(), [3]int{1, 1, 1}; c != want {
+		t.Errorf("origin got (GET, 304, HEAD) %v, want %v", c, want)
+	}
+
+	// A node that knows the hash does not need the origin at all.
+	c3 := newCache(t, t.TempDir())
+	s3 := &Scheme{
+		Scheme: curl.DefaultHTTPClient,
+		Cache:  c3,
+		Peers:  &Peers{Cache: c3, Group: group, Wait: 5 * time.Second},
+	}
+	sum := sha256.Sum256([]byte("kernel"))
+	dead := &url.URL{Scheme: "http", Host: "127.0.0.1:1", Path: "/vmlinuz"}
+	if got, err := fetch(t, WithSHA256(ctx, sum[:]), s3, dead); err != nil || got != "kernel" {
+		t.Errorf("fetch from peer by hash = %q, %v, want %q", got, err, "kernel")
+	}
+
+	// Once the file changes, peers have no copy of the new version.
+	o.set("kernel 2", `"2"`)
+	c4 := newCache(t, t.TempDir())
+	s4 := &Scheme{
+		Scheme: curl.DefaultHTTPClient,
+		Cache:  c4,
+		Peers:  &Peers{Cache: c4, Group: group, Wait: 100 * time.Millisecond},
+	}
+	if got, err := fetch(t, ctx, s4, u); err != nil || got != "kernel 2" {
+		t.Errorf("fetch = %q, %v, want %q", got, err, "kernel 2")
+	}
+	if c, want := o.counts(), [3]int{2, 1, 2}; c != want {
+		t.Errorf("origin got (GET, 304, HEAD) %v, want %v", c, want)
+	}
+}
+
+// answerQueries answers the queries of the returned group with the answers
+// of claim, as a peer serving HTTP on port.
+func answerQueries(t *testing.T, port int, claim func(q query) []answer) *net.UDPAddr {
+	t.Helper()
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	go func() {
+		buf := make([]byte, maxPacket)
+		for {
+			n, from, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			var q query
+			if err := json.Unmarshal(buf[:n], &q); err != nil {
+				continue
+			}
+			for _, a := range claim(q) {
+				a.ID, a.Port = q.ID, port
+				b, _ := json.Marshal(&a)
+				conn.WriteTo(b, from)
+			}
+		}
+	}()
+	return conn.LocalAddr().(*net.UDPAddr)
+}
+
+// servePeerLying serves a peer that claims to have any blob it is asked for,
+// which it answers with the blob of content.
+func servePeerLying(t *testing.T, content string) *net.UDPAddr {
+	t.Helper()
+	c := newCache(t, t.TempDir())
+	sum, err := c.Put(strings.NewReader(content), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f, err := c.Open(sum)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		defer f.Close()
+		io.Copy(w, f)
+	}))
+	t.Cleanup(srv.Close)
+	// Claim the blob asked for, and the one we have too.
+	return answerQueries(t, srv.Listener.Addr().(*net.TCPAddr).Port, func(q query) []answer {
+		return []answer{
+			{SHA256: q.SHA256, Size: int64(len(content))},
+			{SHA256: hex.EncodeToString(sum), Size: int64(len(content))},
+		}
+	})
+}
+
+func TestPeersLying(t *testing.T) {
+	ctx := context.Background()
+	group := servePeerLying(t, "evil")
+
+	for _, digest := range []bool{false, true} {
+		t.Run(fmt.Sprintf("digest=%t", digest), func(t *testing.T) {
+			o, u := serveOrigin(t, "kernel", `"1"`)
+			o.digest = digest
+			c := newCache(t, t.TempDir())
+			s := &Scheme{
+				Scheme: curl.DefaultHTTPClient,
+				Cache:  c,
+				Peers:  &Peers{Cache: c, Group: group, Wait: time.Second},
+			}
+			for range 2 {
+				if got, err := fetch(t, ctx, s, u); err != nil || got != "kernel" {
+					t.Errorf("fetch = %q, %v, want %q", got, err, "kernel")
+				}
+			}
+			if c, want := o.counts(), [3]int{1, 1, 1}; c != want {
+				t.Errorf("origin got (GET, 304, HEAD) %v, want %v", c, want)
+			}
+		})
+	}
+
+	// Nor can it serve a file of a known hash.
+	c := newCache(t, t.TempDir())
+	s := &Scheme{
+		Scheme: curl.DefaultHTTPClient,
+		Cache:  c,
+		Peers:  &Peers{Cache: c, Group: group, Wait: time.Second},
+	}
+	sum := sha256.Sum256([]byte("kernel"))
+	dead := &url.URL{Scheme: "http", Host: "127.0.0.1:1", Path: "/vmlinuz"}
+	if got, err := fetch(t, WithSHA256(ctx, sum[:]), s, dead); err == nil {
+		t.Errorf("fetch from lying peer by hash = %q, want an error", got)
+	}
+}
+
+func TestPeersStalling(t *testing.T) {
+	// The peer has the blob, but stops halfway through its body.
+	stop := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ker")
+		w.(http.Flusher).Flush()
+		select {
+		case <-stop:
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(srv.Close)
+	t.Cleanup(func() { close(stop) })
+	group := answerQueries(t, srv.Listener.Addr().(*net.TCPAddr).Port, func(q query) []answer {
+		return []answer{{SHA256: q.SHA256, Size: int64(len("kernel"))}}
+	})
+
+	o, u := serveOrigin(t, "kernel", `"1"`)
+	c := newCache(t, t.TempDir())
+	s := &Scheme{
+		Scheme: curl.DefaultHTTPClient,
+		Cache:  c,
+		Peers:  &Peers{Cache: c, Group: group, Wait: 100 * time.Millisecond},
+	}
+	sum := sha256.Sum256([]byte("kernel"))
+	start := time.Now()
+	if got, err := fetch(t, WithSHA256(context.Background(), sum[:]), s, u); err != nil || got != "kernel" {
+		t.Errorf("fetch = %q, %v, want %q", got, err, "kernel")
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("fetch took %v, want the peer given up on", d)
+	}
+	if c, want := o.counts(), [3]int{1, 0, 0}; c != want {
+		t.Errorf("origin got (GET, 304, HEAD) %v, want %v", c, want)
+	}
+}
@@ -0,0 +1,257 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/u-root/u-root/pkg/curl"
+)
+
+const (
+	// DefaultGroup is the multicast group and port of peers, in the
+	// organization-local scope of RFC 2365.
+	DefaultGroup = "239.255.67.72:7367"
+
+	// DefaultWait is how long peers are waited for.
+	DefaultWait = 500 * time.Millisecond
+
+	// maxPacket bounds queries and answers.
+	maxPacket = 64 << 10
+
+	// peerRate is how many bytes of a blob a peer must serve per Wait.
+	peerRate = 1 << 20
+)
+
+var errNoPeer = errors.New("no peer has the file")
+
+// query asks peers for a blob by hash.
+type query struct {
+	ID     uint64 `json:"id"`
+	SHA256 string `json:"sha256"`
+}
+
+// answer tells that the blob, of Size bytes, is served over HTTP on Port of
+// the peer.
+type answer struct {
+	ID     uint64 `json:"id"`
+	SHA256 string `json:"sha256"`
+	Size   int64  `json:"size"`
+	Port   int    `json:"port"`
+}
+
+// Peers shares a Cache with the other nodes of a network segment.
+//
+// A node asks for a blob by hash with a UDP query to Group, usually a
+// multicast group. The peers that have the blob answer to the sender with the
+// port of an HTTP server of their blobs, from which the node fetches the blob
+// and checks its hash. The blob is fetched from the first peer that serves it.
+//
+// Any host of the segment can answer, so nodes only ask for hashes they
+// trust: those expected by the caller, or told by the origin of the file.
+// Nor can a peer hold a node up or fill its disk: a node reads no more than
+// the size a peer answered, and no larger than MaxSize of the cache, and
+// gives up on a peer that serves less than 1 MiB per Wait. The node falls
+// back to the origin when no peer serves the blob.
+type Peers struct {
+	// Cache stores and serves the blobs.
+	Cache *Cache
+
+	// Group is where queries are sent.
+	Group *net.UDPAddr
+
+	// Wait is how long answers are waited for, DefaultWait if 0.
+	Wait time.Duration
+
+	// Client fetches the blobs, http.DefaultClient if nil.
+	Client *http.Client
+}
+
+// NewPeers returns Peers of c in DefaultGroup.
+func NewPeers(c *Cache) (*Peers, error) {
+	group, err := net.ResolveUDPAddr("udp4", DefaultGroup)
+	if err != nil {
+		return nil, err
+	}
+	return &Peers{Cache: c, Group: group}, nil
+}
+
+func (p *Peers) wait() time.Duration {
+	if p.Wait > 0 {
+		return p.Wait
+	}
+	return DefaultWait
+}
+
+func (p *Peers) client() *http.Client {
+	if p.Client != nil {
+		return p.Client
+	}
+	return http.DefaultClient
+}
+
+// find asks the peers for the blob of hash want, and stores the blob of the
+// first peer that serves it.
+func (p *Peers) find(ctx context.Context, want []byte) error {
+	conn, err := net.ListenUDP("udp", nil)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	q := query{ID: rand.Uint64(), SHA256: hex.EncodeToString(want)}
+	b, err := json.Marshal(&q)
+	if err != nil {
+		return err
+	}
+	if _, err := conn.WriteTo(b, p.Group); err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(p.wait())
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := conn.SetReadDeadline(deadline); err != nil {
+		return err
+	}
+	buf := make([]byte, maxPacket)
+	for {
+		n, from, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			return errNoPeer
+		}
+		var a answer
+		if err := json.Unmarshal(buf[:n], &a); err != nil || a.ID != q.ID || a.SHA256 != q.SHA256 {
+			continue
+		}
+		if a.Size < 0 || (p.Cache.MaxSize > 0 && a.Size > p.Cache.MaxSize) {
+			continue
+		}
+		if err := p.fetch(ctx, from.IP, &a, want); err == nil {
+			return nil
+		}
+	}
+}
+
+// fetch stores the blob sum of the peer at ip, which gave answer a. The
+// peer has Wait per MiB of the blob, and Wait more, to serve it.
+func (p *Peers) fetch(ctx context.Context, ip net.IP, a *answer, sum []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, p.wait()*time.Duration(1+a.Size/peerRate))
+	defer cancel()
+
+	u := fmt.Sprintf("http://%s/blobs/%x", net.JoinHostPort(ip.String(), strconv.Itoa(a.Port)), sum)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := p.client().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return &curl.HTTPClientCodeError{Err: curl.ErrStatusNotOk, HTTPCode: resp.StatusCode}
+	}
+	// Peers are not trusted: Put checks that the blob has the hash the
+	// node asked for, which a body cut at the size fails too.
+	_, err = p.Cache.Put(io.LimitReader(resp.Body, a.Size), sum)
+	return err
+}
+
+// Serve answers the queries that arrive on conn for blobs of the cache, which
+// the caller serves with Handler on port. It returns when conn is closed.
+func (p *Peers) Serve(conn net.PacketConn, port int) error {
+	buf := make([]byte, maxPacket)
+	for {
+		n, from, err := conn.ReadFrom(buf)
+		if errors.Is(err, net.ErrClosed) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var q query
+		if err := json.Unmarshal(buf[:n], &q); err != nil {
+			continue
+		}
+		sum, err := hex.DecodeString(q.SHA256)
+		if err != nil {
+			continue
+		}
+		size, err := p.Cache.size(sum)
+		if err != nil {
+			continue
+		}
+		b, err := json.Marshal(&answer{ID: q.ID, SHA256: hex.EncodeToString(sum), Size: size, Port: port})
+		if err != nil {
+			continue
+		}
+		// An unreachable peer should not stop the others'.
+		_, _ = conn.WriteTo(b, from)
+	}
+}
+
+// Handler serves the blobs of the cache at /blobs/<hash>.
+func (p *Peers) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, ok := strings.CutPrefix(r.URL.Path, "/blobs/")
+		sum, err := hex.DecodeString(name)
+		if !ok || err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		f, err := p.Cache.Open(sum)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		defer f.Close()
+		fi, err := f.Stat()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		http.ServeContent(w, r, name, fi.ModTime(), f)
+	})
+}
+
+// ListenAndServe joins Group on ifi, or on the default interface if ifi is
+// nil, and serves the cache to peers with an HTTP server on addr until ctx is
+// done.
+func (p *Peers) ListenAndServe(ctx context.Context, ifi *net.Interface, addr string) error {
+	conn, err := net.ListenMulticastUDP("udp4", ifi, p.Group)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{Handler: p.Handler(), ReadHeaderTimeout: 10 * time.Second}
+	defer srv.Close()
+
+	errs := make(chan error, 2)
+	go func() { errs <- srv.Serve(ln) }()
+	go func() { errs <- p.Serve(conn, ln.Addr().(*net.TCPAddr).Port) }()
+	select {
+	case <-ctx.Done():
+		return nil
+	case err := <-errs:
+		return err
+	}
+}
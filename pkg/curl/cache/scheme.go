@@ -0,0 +1,131 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/url"
+	"os"
+
+	"github.com/u-root/u-root/pkg/curl"
+)
+
+// Scheme is a curl.FileScheme that fetches the files of another scheme
+// through a Cache.
+//
+// Files whose expected hash is in the context, see WithSHA256, are looked up
+// in the cache, then asked of Peers, then fetched. Other files of a
+// curl.ValidatingScheme are revalidated with the origin and served from the
+// cache while they are unmodified; they are asked of Peers only if the origin
+// tells their hash. Files of other schemes pass through uncached, since
+// nothing tells when they change.
+type Scheme struct {
+	// Scheme fetches the files from their origin.
+	Scheme curl.FileScheme
+
+	// Cache stores the files.
+	Cache *Cache
+
+	// Peers, if set, is asked for files that are not cached before the
+	// origin is.
+	Peers *Peers
+}
+
+var _ curl.FileScheme = &Scheme{}
+
+// Fetch implements curl.FileScheme.Fetch.
+func (s *Scheme) Fetch(ctx context.Context, u *url.URL) (io.ReaderAt, error) {
+	if _, ok := s.Scheme.(curl.ValidatingScheme); !ok && sha256From(ctx) == nil {
+		return s.Scheme.Fetch(ctx, u)
+	}
+	return s.fetch(ctx, u)
+}
+
+// FetchWithoutCache implements curl.FileScheme.FetchWithoutCache. The file is
+// still cached on disk, which this Scheme is for.
+func (s *Scheme) FetchWithoutCache(ctx context.Context, u *url.URL) (io.Reader, error) {
+	if _, ok := s.Scheme.(curl.ValidatingScheme); !ok && sha256From(ctx) == nil {
+		return s.Scheme.FetchWithoutCache(ctx, u)
+	}
+	return s.fetch(ctx, u)
+}
+
+func (s *Scheme) fetch(ctx context.Context, u *url.URL) (*os.File, error) {
+	if want := sha256From(ctx); want != nil {
+		return s.fetchSum(ctx, u, want)
+	}
+
+	vs := s.Scheme.(curl.ValidatingScheme)
+	var v curl.Validator
+	e := s.Cache.lookup(u.String())
+	if e != nil {
+		v = e.Validator
+	} else if s.Peers != nil {
+		// Peers are asked for the hash the origin tells, which costs
+		// the origin a HEAD request rather than the whole file. Peers
+		// are never trusted to tell the hash themselves.
+		if cur, err := vs.Validator(ctx, u); err == nil && !cur.IsZero() && cur.SHA256 != nil {
+			if err := s.Peers.find(ctx, cur.SHA256); err == nil {
+				if err := s.Cache.record(u.String(), cur, cur.SHA256); err != nil {
+					return nil, err
+				}
+				return s.Cache.Open(cur.SHA256)
+			}
+		}
+	}
+
+	r, nv, err := vs.FetchValidated(ctx, u, v)
+	if errors.Is(err, curl.ErrNotModified) && e != nil {
+		return s.Cache.Open(e.sum())
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.store(u, r, nv, nil)
+}
+
+func (s *Scheme) fetchSum(ctx context.Context, u *url.URL, want []byte) (*os.File, error) {
+	if f, err := s.Cache.Open(want); err == nil {
+		return f, nil
+	}
+	if s.Peers != nil {
+		if err := s.Peers.find(ctx, want); err == nil {
+			return s.Cache.Open(want)
+		}
+	}
+
+	if vs, ok := s.Scheme.(curl.ValidatingScheme); ok {
+		r, v, err := vs.FetchValidated(ctx, u, curl.Validator{})
+		if err != nil {
+			return nil, err
+		}
+		return s.store(u, r, v, want)
+	}
+	r, err := s.Scheme.FetchWithoutCache(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+	return s.store(u, r, curl.Validator{}, want)
+}
+
+// store caches the contents of r with validator v as the version of u, and
+// opens them.
+func (s *Scheme) store(u *url.URL, r io.Reader, v curl.Validator, want []byte) (*os.File, error) {
+	if c, ok := r.(io.Closer); ok {
+		defer c.Close()
+	}
+	sum, err := s.Cache.Put(r, want)
+	if err != nil {
+		return nil, err
+	}
+	if !v.IsZero() {
+		if err := s.Cache.record(u.String(), v, sum); err != nil {
+			return nil, err
+		}
+	}
+	return s.Cache.Open(sum)
+}
@@ -0,0 +1,132 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package curl
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+)
+
+// ErrNoCertificates is returned by TLSConfig when the CA file holds no
+// certificate.
+var ErrNoCertificates = errors.New("no certificates found")
+
+// HTTPClientOpt is an option of NewHTTPClient.
+type HTTPClientOpt func(*HTTPClient)
+
+// WithHeader adds a header to the requests.
+func WithHeader(key, value string) HTTPClientOpt {
+	return func(h *HTTPClient) {
+		h.header.Add(key, value)
+	}
+}
+
+// WithBasicAuth authenticates the requests with a user and password.
+func WithBasicAuth(user, password string) HTTPClientOpt {
+	return func(h *HTTPClient) {
+		r := &http.Request{Header: http.Header{}}
+		r.SetBasicAuth(user, password)
+		h.header.Set("Authorization", r.Header.Get("Authorization"))
+	}
+}
+
+// WithBearerToken authenticates the requests with an OAuth 2.0 bearer token.
+func WithBearerToken(token string) HTTPClientOpt {
+	return func(h *HTTPClient) {
+		h.header.Set("Authorization", "Bearer "+token)
+	}
+}
+
+// WithMethod sets the method of the requests. It defaults to GET, or to POST
+// with a body.
+func WithMethod(method string) HTTPClientOpt {
+	return func(h *HTTPClient) {
+		h.method = method
+	}
+}
+
+// WithBody sends body with the requests.
+func WithBody(body []byte) HTTPClientOpt {
+	return func(h *HTTPClient) {
+		h.body = body
+	}
+}
+
+// WithOffset fetches files from offset on, as to resume a download. It asks
+// the server for the range, and skips to offset if the server sends the whole
+// file anyway. A file that ends at offset reads as empty.
+func WithOffset(offset int64) HTTPClientOpt {
+	return func(h *HTTPClient) {
+		h.offset = offset
+	}
+}
+
+// WithTLSConfig sets the TLS configuration of HTTPS requests.
+func WithTLSConfig(config *tls.Config) HTTPClientOpt {
+	return func(h *HTTPClient) {
+		h.transport().TLSClientConfig = config
+	}
+}
+
+// WithProxy sends the requests through the proxy at proxy, rather than the
+// one of the environment. A nil proxy sends them to the server directly.
+func WithProxy(proxy *url.URL) HTTPClientOpt {
+	return func(h *HTTPClient) {
+		if proxy == nil {
+			h.transport().Proxy = nil
+			return
+		}
+		h.transport().Proxy = http.ProxyURL(proxy)
+	}
+}
+
+// transport returns the transport of the client, which it owns from then on.
+func (h *HTTPClient) transport() *http.Transport {
+	t, ok := h.c.Transport.(*http.Transport)
+	if !ok {
+		t = http.DefaultTransport.(*http.Transport)
+	}
+	// Clone the transport only once.
+	if !h.ownTransport {
+		t = t.Clone()
+		h.c.Transport = t
+		h.ownTransport = true
+	}
+	return t
+}
+
+// TLSConfig returns a TLS configuration that trusts the PEM certificates of
+// caFile rather than the system ones, if caFile is set. If certFile is set,
+// it presents the PEM certificate of certFile and the key of keyFile to the
+// servers that ask for one. The key may be in certFile, too.
+func TLSConfig(caFile, certFile, keyFile string) (*tls.Config, error) {
+	config := &tls.Config{}
+	if caFile != "" {
+		b, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(b) {
+			return nil, fmt.Errorf("%s: %w", caFile, ErrNoCertificates)
+		}
+	}
+	if certFile != "" {
+		if keyFile == "" {
+			keyFile = certFile
+		}
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
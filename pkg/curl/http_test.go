@@ -0,0 +1,307 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package curl
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const httpContent = "0123456789"
+
+func fetchString(t *testing.T, h *HTTPClient, rawURL string) (string, error) {
+	t.Helper()
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := h.FetchWithoutCache(context.Background(), u)
+	if err != nil {
+		return "", err
+	}
+	b, err := io.ReadAll(r)
+	return string(b), err
+}
+
+func TestHTTPClientOpts(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/basic":
+			if user, password, ok := r.BasicAuth(); !ok || user != "root" || password != "secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+		case "/bearer":
+			if r.Header.Get("Authorization") != "Bearer token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+		case "/header":
+			if r.Header.Get("X-Node") != "node0" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+		case "/post":
+			b, _ := io.ReadAll(r.Body)
+			if r.Method != http.MethodPost || string(b) != "hello" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+		case "/put":
+			if r.Method != http.MethodPut {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+		case "/norangesize":
+			w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+			return
+		case "/norange":
+			// Send the whole file, as servers without Range
+			// support do.
+			w.Write([]byte(httpContent))
+			return
+		}
+		http.ServeContent(w, r, "", time.Time{}, strings.NewReader(httpContent))
+	}))
+	defer srv.Close()
+
+	for _, tt := range []struct {
+		name string
+		path string
+		opts []HTTPClientOpt
+		want string
+		err  error
+	}{
+		{name: "basic auth", path: "/basic", opts: []HTTPClientOpt{WithBasicAuth("root", "secret")}, want: httpContent},
+		{name: "wrong basic auth", path: "/basic", opts: []HTTPClientOpt{WithBasicAuth("root", "guess")}, err: ErrStatusNotOk},
+		{name: "bearer token", path: "/bearer", opts: []HTTPClientOpt{WithBearerToken("token")}, want: httpContent},
+		{name: "no bearer token", path: "/bearer", err: ErrStatusNotOk},
+		{name: "header", path: "/header", opts: []HTTPClientOpt{WithHeader("X-Node", "node0")}, want: httpContent},
+		{name: "post", path: "/post", opts: []HTTPClientOpt{WithBody([]byte("hello"))}, want: httpContent},
+		{name: "put", path: "/put", opts: []HTTPClientOpt{WithMethod(http.MethodPut)}, want: httpContent},
+		{name: "offset", path: "/", opts: []HTTPClientOpt{WithOffset(4)}, want: httpContent[4:]},
+		{name: "offset without range support", path: "/norange", opts: []HTTPClientOpt{WithOffset(4)}, want: httpContent[4:]},
+		{name: "offset at end", path: "/", opts: []HTTPClientOpt{WithOffset(int64(len(httpContent)))}, want: ""},
+		{name: "offset past end", path: "/", opts: []HTTPClientOpt{WithOffset(int64(len(httpContent) + 2))}, err: ErrContentRange},
+		{name: "offset not satisfiable", path: "/norangesize", opts: []HTTPClientOpt{WithOffset(4)}, err: ErrContentRange},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := fetchString(t, NewHTTPClient(http.DefaultClient, tt.opts...), srv.URL+tt.path)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("FetchWithoutCache() = %v, want %v", err, tt.err)
+			}
+			if got != tt.want {
+				t.Errorf("FetchWithoutCache() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if http.DefaultClient.Transport != nil {
+		t.Errorf("options changed http.DefaultClient")
+	}
+}
+
+func TestHTTPClientProxy(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("direct"))
+	}))
+	defer srv.Close()
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Proxies get the absolute URL.
+		if r.URL.String() != srv.URL+"/file" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte("proxied"))
+	}))
+	defer proxy.Close()
+
+	proxyURL, err := url.Parse(proxy.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := fetchString(t, NewHTTPClient(http.DefaultClient, WithProxy(proxyURL)), srv.URL+"/file"); err != nil || got != "proxied" {
+		t.Errorf("FetchWithoutCache() through a proxy = %q, %v, want %q", got, err, "proxied")
+	}
+	if got, err := fetchString(t, NewHTTPClient(http.DefaultClient, WithProxy(nil)), srv.URL+"/file"); err != nil || got != "direct" {
+		t.Errorf("FetchWithoutCache() without a proxy = %q, %v, want %q", got, err, "direct")
+	}
+}
+
+// writeCert writes a self-signed client certificate and its key to dir.
+func writeCert(t *testing.T, dir string) (*x509.Certificate, string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "node0"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return cert, certFile, keyFile
+}
+
+func TestTLSConfig(t *testing.T) {
+	dir := t.TempDir()
+	clientCert, certFile, keyFile := writeCert(t, dir)
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(httpContent))
+	}))
+	srv.TLS = &tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  x509.NewCertPool(),
+	}
+	srv.TLS.ClientCAs.AddCert(clientCert)
+	srv.StartTLS()
+	defer srv.Close()
+
+	caFile := filepath.Join(dir, "ca.pem")
+	if err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	both := filepath.Join(dir, "both.pem")
+	certPEM, _ := os.ReadFile(certFile)
+	keyPEM, _ := os.ReadFile(keyFile)
+	if err := os.WriteFile(both, append(certPEM, keyPEM...), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		name     string
+		ca       string
+		cert     string
+		key      string
+		fetchErr bool
+	}{
+		{name: "CA and client certificate", ca: caFile, cert: certFile, key: keyFile},
+		{name: "key in the certificate file", ca: caFile, cert: both},
+		{name: "no client certificate", ca: caFile, fetchErr: true},
+		{name: "system CAs", cert: certFile, key: keyFile, fetchErr: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := TLSConfig(tt.ca, tt.cert, tt.key)
+			if err != nil {
+				t.Fatalf("TLSConfig() = %v", err)
+			}
+			got, err := fetchString(t, NewHTTPClient(&http.Client{}, WithTLSConfig(config)), srv.URL)
+			if (err != nil) != tt.fetchErr {
+				t.Fatalf("FetchWithoutCache() = %v, want error %t", err, tt.fetchErr)
+			}
+			if err == nil && got != httpContent {
+				t.Errorf("FetchWithoutCache() = %q, want %q", got, httpContent)
+			}
+		})
+	}
+
+	if _, err := TLSConfig(keyFile, "", ""); !errors.Is(err, ErrNoCertificates) {
+		t.Errorf("TLSConfig() of a key as CA = %v, want %v", err, ErrNoCertificates)
+	}
+	if _, err := TLSConfig("", filepath.Join(dir, "nothere"), ""); !os.IsNotExist(err) {
+		t.Errorf("TLSConfig() of a missing certificate = %v, want %v", err, os.ErrNotExist)
+	}
+}
+
+func TestFetchValidated(t *testing.T) {
+	httpSum := sha256.Sum256([]byte(httpContent))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Repr-Digest", "sha-512=:AAAA:, sha-256=:"+base64.StdEncoding.EncodeToString(httpSum[:])+":")
+		http.ServeContent(w, r, "file", time.Time{}, strings.NewReader(httpContent))
+	}))
+	defer srv.Close()
+	u, err := url.Parse(srv.URL + "/file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewHTTPClient(srv.Client())
+	ctx := context.Background()
+
+	v, err := h.Validator(ctx, u)
+	if err != nil || v.ETag != `"v1"` || !bytes.Equal(v.SHA256, httpSum[:]) {
+		t.Fatalf("Validator = %+v, %v, want ETag \"v1\" and SHA-256 %x", v, err, httpSum)
+	}
+	r, got, err := h.FetchValidated(ctx, u, Validator{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, err := io.ReadAll(r); err != nil || string(b) != httpContent || !got.Matches(v) {
+		t.Errorf("FetchValidated = %q, %+v, %v, want %q, %+v", b, got, err, httpContent, v)
+	}
+	if _, _, err := h.FetchValidated(ctx, u, v); !errors.Is(err, ErrNotModified) {
+		t.Errorf("FetchValidated(%+v) = %v, want %v", v, err, ErrNotModified)
+	}
+	if _, _, err := h.FetchValidated(ctx, u, Validator{ETag: `"v0"`}); err != nil {
+		t.Errorf("FetchValidated(stale) = %v, want nil", err)
+	}
+}
+
+func TestSHA256Of(t *testing.T) {
+	sum := sha256.Sum256([]byte(httpContent))
+	b64 := base64.StdEncoding.EncodeToString(sum[:])
+	for _, tt := range []struct {
+		header, value string
+		want          []byte
+	}{
+		{"Repr-Digest", "sha-256=:" + b64 + ":", sum[:]},
+		{"Digest", "MD5=AAAA, SHA-256=" + b64, sum[:]},
+		{"Digest", "sha-512=" + b64, nil},
+		{"Repr-Digest", "sha-256=:AAAA:", nil},
+		{"Content-Digest", "sha-256=:" + b64 + ":", nil},
+	} {
+		h := http.Header{}
+		h.Set(tt.header, tt.value)
+		if got := sha256Of(h); !bytes.Equal(got, tt.want) {
+			t.Errorf("sha256Of(%s: %s) = %x, want %x", tt.header, tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package curl
+
+import (
+	"context"
+	"io"
+	"net/url"
+	"strconv"
+
+	"github.com/u-root/u-root/pkg/nfs"
+	"github.com/u-root/uio/uio"
+)
+
+// NFSClient implements FileScheme for nfs://host[:port]/path URLs with a
+// userspace NFSv3 client. It mounts the longest leading directory of the path
+// that the server exports, as iPXE does. The port of the URL is the NFS port.
+type NFSClient struct {
+	// Dialer mounts the exports. A nil Dialer is a zero nfs.Dialer.
+	Dialer *nfs.Dialer
+}
+
+// NewNFSClient returns a new NFS client with d.
+func NewNFSClient(d *nfs.Dialer) FileScheme {
+	return &NFSClient{Dialer: d}
+}
+
+// nfsFile unmounts the export once the file is read.
+type nfsFile struct {
+	*nfs.File
+	c *nfs.Client
+}
+
+func (f *nfsFile) Read(p []byte) (int, error) {
+	n, err := f.File.Read(p)
+	if err != nil && f.c != nil {
+		f.c.Close()
+		f.c = nil
+	}
+	return n, err
+}
+
+func nfsFetch(ctx context.Context, n *NFSClient, u *url.URL) (io.Reader, error) {
+	var d nfs.Dialer
+	if n.Dialer != nil {
+		d = *n.Dialer
+	}
+	if p := u.Port(); p != "" {
+		port, err := strconv.ParseUint(p, 10, 16)
+		if err != nil {
+			return nil, err
+		}
+		d.Port = int(port)
+	}
+
+	c, name, err := d.DialFile(ctx, u.Hostname(), u.Path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := c.Open(name)
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+	return &nfsFile{File: f, c: c}, nil
+}
+
+// Fetch implements FileScheme.Fetch for NFS.
+func (n *NFSClient) Fetch(ctx context.Context, u *url.URL) (io.ReaderAt, error) {
+	r, err := nfsFetch(ctx, n, u)
+	if err != nil {
+		return nil, err
+	}
+	return uio.NewCachingReader(r), nil
+}
+
+// FetchWithoutCache implements FileScheme.FetchWithoutCache for NFS.
+func (n *NFSClient) FetchWithoutCache(ctx context.Context, u *url.URL) (io.Reader, error) {
+	return nfsFetch(ctx, n, u)
+}
@@ -0,0 +1,43 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package curl
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/u-root/u-root/pkg/nfs"
+)
+
+func TestNFSClient(t *testing.T) {
+	if _, ok := DefaultSchemes["nfs"]; !ok {
+		t.Errorf("DefaultSchemes has no nfs scheme")
+	}
+
+	// A port that nothing listens on.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+
+	s := Schemes{"nfs": NewNFSClient(&nfs.Dialer{PortmapPort: port, Timeout: time.Second})}
+	u, err := url.Parse("nfs://127.0.0.1/srv/boot/vmlinuz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.FetchWithoutCache(context.Background(), u); !IsURLError(err) || !RetryConnectErrors(u, err) {
+		t.Errorf("FetchWithoutCache() without a server = %v, want a connection error", err)
+	}
+
+	u.Host = "127.0.0.1:99999"
+	if _, err := s.Fetch(context.Background(), u); err == nil {
+		t.Errorf("Fetch() with a bad port = nil, want an error")
+	}
+}
@@ -4,10 +4,11 @@
 
 // Package curl implements routines to fetch files given a URL.
 //
-// curl currently supports HTTP, TFTP, and local files.
+// curl currently supports HTTP, TFTP, NFS, and local files.
 package curl
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
@@ -18,6 +19,7 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
@@ -41,6 +43,10 @@ var ErrNoSuchScheme = errors.New("no such scheme")
 // ErrStatusNotOk is a error represents http codes other than 200.
 var ErrStatusNotOk = errors.New("not status 200")
 
+// ErrContentRange is returned when the server sends another part of the file
+// than the one asked for, or the file no longer reaches the offset asked for.
+var ErrContentRange = errors.New("unexpected content range")
+
 // File is a reference to a file fetched through this library.
 type File interface {
 	fmt.Stringer
@@ -84,10 +90,14 @@ var (
 	// DefaultTFTPClient is the default TFTP FileScheme.
 	DefaultTFTPClient = NewTFTPClient(tftp.ClientMode(tftp.ModeOctet), tftp.ClientBlocksize(1450), tftp.ClientWindowsize(64))
 
+	// DefaultNFSClient is the default NFS FileScheme.
+	DefaultNFSClient = NewNFSClient(nil)
+
 	// DefaultSchemes are the schemes supported by default.
 	DefaultSchemes = Schemes{
 		"tftp": DefaultTFTPClient,
 		"http": DefaultHTTPClient,
+		"nfs":  DefaultNFSClient,
 		"file": &LocalFileClient{},
 	}
 )
@@ -410,34 +420,99 @@ func (h *HTTPClientCodeError) Unwrap() error {
 // HTTPClient implements FileScheme for HTTP files.
 type HTTPClient struct {
 	c *http.Client
+
+	method string
+	header http.Header
+	body   []byte
+	offset int64
+
+	// ownTransport is whether the options cloned c.Transport.
+	ownTransport bool
 }
 
-// NewHTTPClient returns a new HTTP FileScheme based on the given http.Client.
-func NewHTTPClient(c *http.Client) *HTTPClient {
-	return &HTTPClient{
-		c: c,
+// NewHTTPClient returns a new HTTP FileScheme based on the given http.Client
+// and options.
+func NewHTTPClient(c *http.Client, opts ...HTTPClientOpt) *HTTPClient {
+	h := &HTTPClient{
+		c:      c,
+		header: http.Header{},
 	}
+	if len(opts) > 0 {
+		// Options may change the client, which may well be
+		// http.DefaultClient.
+		cc := *c
+		h.c = &cc
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
 }
 
-func httpFetch(ctx context.Context, c *http.Client, u *url.URL) (io.Reader, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+func httpFetch(ctx context.Context, h *HTTPClient, u *url.URL) (io.Reader, error) {
+	method := h.method
+	var body io.Reader
+	if h.body != nil {
+		if method == "" {
+			method = http.MethodPost
+		}
+		// The body is read anew by every retry.
+		body = bytes.NewReader(h.body)
+	}
+	if method == "" {
+		method = http.MethodGet
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
 	if err != nil {
 		return nil, err
 	}
-	resp, err := c.Do(req)
+	for k, v := range h.header {
+		req.Header[k] = v
+	}
+	if h.offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", h.offset))
+	}
+	resp, err := h.c.Do(req)
 	if err != nil {
 		return nil, err
 	}
 
-	if resp.StatusCode != 200 {
-		return nil, &HTTPClientCodeError{ErrStatusNotOk, resp.StatusCode}
+	switch {
+	case resp.StatusCode == http.StatusOK && h.offset > 0:
+		// The server ignored the range, so skip to the offset.
+		if _, err := io.CopyN(io.Discard, resp.Body, h.offset); err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+		return resp.Body, nil
+
+	case resp.StatusCode == http.StatusOK:
+		return resp.Body, nil
+
+	case resp.StatusCode == http.StatusPartialContent && h.offset > 0:
+		if !strings.HasPrefix(resp.Header.Get("Content-Range"), fmt.Sprintf("bytes %d-", h.offset)) {
+			resp.Body.Close()
+			return nil, fmt.Errorf("%w: %q", ErrContentRange, resp.Header.Get("Content-Range"))
+		}
+		return resp.Body, nil
+
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && h.offset > 0:
+		resp.Body.Close()
+		// The file is complete only if it ends at the offset, rather
+		// than having become shorter.
+		cr := resp.Header.Get("Content-Range")
+		if size, ok := strings.CutPrefix(cr, "bytes */"); !ok || size != strconv.FormatInt(h.offset, 10) {
+			return nil, fmt.Errorf("%w: %q at offset %d", ErrContentRange, cr, h.offset)
+		}
+		return http.NoBody, nil
 	}
-	return resp.Body, nil
+	resp.Body.Close()
+	return nil, &HTTPClientCodeError{ErrStatusNotOk, resp.StatusCode}
 }
 
 // Fetch implements FileScheme.Fetch for HTTP.
 func (h HTTPClient) Fetch(ctx context.Context, u *url.URL) (io.ReaderAt, error) {
-	r, err := httpFetch(ctx, h.c, u)
+	r, err := httpFetch(ctx, &h, u)
 	if err != nil {
 		return nil, err
 	}
@@ -446,7 +521,7 @@ func (h HTTPClient) Fetch(ctx context.Context, u *url.URL) (io.ReaderAt, error)
 
 // FetchWithoutCache implements FileScheme.FetchWithoutCache for HTTP.
 func (h HTTPClient) FetchWithoutCache(ctx context.Context, u *url.URL) (io.Reader, error) {
-	return httpFetch(ctx, h.c, u)
+	return httpFetch(ctx, &h, u)
 }
 
 // RetryOr returns a DoRetry function that returns true if any one of fn return
@@ -0,0 +1,141 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package curl
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ErrNotModified is returned by ValidatingScheme.FetchValidated when the file
+// still matches the validator.
+var ErrNotModified = errors.New("not modified")
+
+// Validator identifies a version of a file, as the ETag and Last-Modified
+// headers of HTTP do.
+type Validator struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+
+	// SHA256 is the SHA-256 hash of the version, if the origin tells it
+	// with a Repr-Digest or Digest header.
+	SHA256 []byte `json:"sha256,omitempty"`
+}
+
+// IsZero returns whether v identifies no version.
+func (v Validator) IsZero() bool {
+	return v.ETag == "" && v.LastModified == ""
+}
+
+// Matches returns whether v and w identify the same version. A strong ETag
+// takes precedence over the modification time.
+func (v Validator) Matches(w Validator) bool {
+	if v.ETag != "" || w.ETag != "" {
+		return v.ETag == w.ETag
+	}
+	return v.LastModified != "" && v.LastModified == w.LastModified
+}
+
+// ValidatingScheme is a FileScheme whose files have validators, so that
+// caches can fetch them only when they change.
+type ValidatingScheme interface {
+	FileScheme
+
+	// Validator returns the validator of the current version of u.
+	Validator(ctx context.Context, u *url.URL) (Validator, error)
+
+	// FetchValidated returns the contents of u and their validator, or
+	// ErrNotModified if u still matches v. A zero v always fetches.
+	FetchValidated(ctx context.Context, u *url.URL, v Validator) (io.Reader, Validator, error)
+}
+
+func validatorOf(h http.Header) Validator {
+	return Validator{
+		ETag:         h.Get("ETag"),
+		LastModified: h.Get("Last-Modified"),
+		SHA256:       sha256Of(h),
+	}
+}
+
+// sha256Of returns the SHA-256 hash of the Repr-Digest header of RFC 9530,
+// or of the Digest header of RFC 3230 that it obsoletes, or nil. The values
+// of Repr-Digest are byte sequences between colons.
+func sha256Of(h http.Header) []byte {
+	for _, header := range []string{"Repr-Digest", "Digest"} {
+		for _, v := range h.Values(header) {
+			for _, e := range strings.Split(v, ",") {
+				alg, val, ok := strings.Cut(strings.TrimSpace(e), "=")
+				if !ok || !strings.EqualFold(alg, "sha-256") {
+					continue
+				}
+				if header == "Repr-Digest" {
+					val = strings.TrimSuffix(strings.TrimPrefix(val, ":"), ":")
+				}
+				if b, err := base64.StdEncoding.DecodeString(val); err == nil && len(b) == 32 {
+					return b
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Validator implements ValidatingScheme.Validator for HTTP with a HEAD
+// request carrying the headers of h.
+func (h HTTPClient) Validator(ctx context.Context, u *url.URL) (Validator, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, u.String(), nil)
+	if err != nil {
+		return Validator{}, err
+	}
+	for k, v := range h.header {
+		req.Header[k] = v
+	}
+	resp, err := h.c.Do(req)
+	if err != nil {
+		return Validator{}, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Validator{}, &HTTPClientCodeError{ErrStatusNotOk, resp.StatusCode}
+	}
+	return validatorOf(resp.Header), nil
+}
+
+// FetchValidated implements ValidatingScheme.FetchValidated for HTTP with a
+// conditional GET request carrying the headers of h. The method, body and
+// offset options of h do not apply.
+func (h HTTPClient) FetchValidated(ctx context.Context, u *url.URL, v Validator) (io.Reader, Validator, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, Validator{}, err
+	}
+	for k, v := range h.header {
+		req.Header[k] = v
+	}
+	if v.ETag != "" {
+		req.Header.Set("If-None-Match", v.ETag)
+	}
+	if v.LastModified != "" {
+		req.Header.Set("If-Modified-Since", v.LastModified)
+	}
+	resp, err := h.c.Do(req)
+	if err != nil {
+		return nil, Validator{}, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp.Body, validatorOf(resp.Header), nil
+	case http.StatusNotModified:
+		resp.Body.Close()
+		return nil, v, ErrNotModified
+	}
+	resp.Body.Close()
+	return nil, Validator{}, &HTTPClientCodeError{ErrStatusNotOk, resp.StatusCode}
+}
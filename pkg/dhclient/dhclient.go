@@ -197,7 +197,8 @@ type Config struct {
 	V4ClientIdentifier bool
 }
 
-func lease4(ctx context.Context, iface netlink.Link, c Config) (Lease, error) {
+// clientOpts4 returns the nclient4 options for c.
+func clientOpts4(c Config) []nclient4.ClientOpt {
 	mods := []nclient4.ClientOpt{
 		nclient4.WithTimeout(c.Timeout),
 		nclient4.WithRetry(c.Retries),
@@ -211,7 +212,19 @@ func lease4(ctx context.Context, iface netlink.Link, c Config) (Lease, error) {
 	if c.V4ServerAddr != nil {
 		mods = append(mods, nclient4.WithServerAddr(c.V4ServerAddr))
 	}
-	client, err := nclient4.New(iface.Attrs().Name, mods...)
+	return mods
+}
+
+// clientIdentifier returns the Client Identifier option for iface, which
+// is hardware type + mac per RFC 2132 9.14.
+func clientIdentifier(iface netlink.Link) dhcpv4.Modifier {
+	ident := []byte{0x01} // Type ethernet
+	ident = append(ident, iface.Attrs().HardwareAddr...)
+	return dhcpv4.WithOption(dhcpv4.OptClientIdentifier(ident))
+}
+
+func lease4(ctx context.Context, iface netlink.Link, c Config) (Lease, error) {
+	client, err := nclient4.New(iface.Attrs().Name, clientOpts4(c)...)
 	if err != nil {
 		return nil, err
 	}
@@ -227,10 +240,7 @@ func lease4(ctx context.Context, iface netlink.Link, c Config) (Lease, error) {
 		c.Modifiers4...)
 
 	if c.V4ClientIdentifier {
-		// Client Id is hardware type + mac per RFC 2132 9.14.
-		ident := []byte{0x01} // Type ethernet
-		ident = append(ident, iface.Attrs().HardwareAddr...)
-		reqmods = append(reqmods, dhcpv4.WithOption(dhcpv4.OptClientIdentifier(ident)))
+		reqmods = append(reqmods, clientIdentifier(iface))
 	}
 
 	log.Printf("Attempting to get DHCPv4 lease on %s", iface.Attrs().Name)
@@ -245,11 +255,6 @@ func lease4(ctx context.Context, iface netlink.Link, c Config) (Lease, error) {
 }
 
 func lease6(ctx context.Context, iface netlink.Link, c Config, linkUpTimeout time.Duration) (Lease, error) {
-	clientPort := dhcpv6.DefaultClientPort
-	if c.V6ClientPort != nil {
-		clientPort = *c.V6ClientPort
-	}
-
 	// For ipv6, we cannot bind to the port until Duplicate Address
 	// Detection (DAD) is complete which is indicated by the link being no
 	// longer marked as "tentative". This usually takes about a second.
@@ -294,6 +299,36 @@ func lease6(ctx context.Context, iface netlink.Link, c Config, linkUpTimeout tim
 		}
 	}
 
+	client, err := newClient6(iface, c)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	// Prepend modifiers with default options, so they can be overriden.
+	reqmods := append(
+		[]dhcpv6.Modifier{
+			dhcpv6.WithNetboot,
+		},
+		c.Modifiers6...)
+
+	log.Printf("Attempting to get DHCPv6 lease on %s", iface.Attrs().Name)
+	p, err := client.RapidSolicit(ctx, reqmods...)
+	if err != nil {
+		return nil, err
+	}
+
+	packet := NewPacket6(iface, p)
+	log.Printf("Got DHCPv6 lease on %s: %v", iface.Attrs().Name, p.Summary())
+	return packet, nil
+}
+
+// newClient6 returns a DHCPv6 client on iface.
+func newClient6(iface netlink.Link, c Config) (*nclient6.Client, error) {
+	clientPort := dhcpv6.DefaultClientPort
+	if c.V6ClientPort != nil {
+		clientPort = *c.V6ClientPort
+	}
 	mods := []nclient6.ClientOpt{
 		nclient6.WithTimeout(c.Timeout),
 		nclient6.WithRetry(c.Retries),
@@ -313,30 +348,15 @@ func lease6(ctx context.Context, iface netlink.Link, c Config, linkUpTimeout tim
 	}
 	i, err := net.InterfaceByName(iface.Attrs().Name)
 	if err != nil {
+		conn.Close()
 		return nil, err
 	}
 	client, err := nclient6.NewWithConn(conn, i.HardwareAddr, mods...)
 	if err != nil {
+		conn.Close()
 		return nil, err
 	}
-	defer client.Close()
-
-	// Prepend modifiers with default options, so they can be overriden.
-	reqmods := append(
-		[]dhcpv6.Modifier{
-			dhcpv6.WithNetboot,
-		},
-		c.Modifiers6...)
-
-	log.Printf("Attempting to get DHCPv6 lease on %s", iface.Attrs().Name)
-	p, err := client.RapidSolicit(ctx, reqmods...)
-	if err != nil {
-		return nil, err
-	}
-
-	packet := NewPacket6(iface, p)
-	log.Printf("Got DHCPv6 lease on %s: %v", iface.Attrs().Name, p.Summary())
-	return packet, nil
+	return client, nil
 }
 
 // NetworkProtocol is either IPv4 or IPv6.
@@ -118,6 +118,30 @@ func (p *Packet4) Configure() error {
 	return nil
 }
 
+// Unconfigure removes the address of this lease from the interface. Routes
+// through the address go with it.
+func (p *Packet4) Unconfigure() error {
+	l := p.Lease()
+	if l == nil {
+		return fmt.Errorf("packet has no IP lease")
+	}
+	if err := netlink.AddrDel(p.iface, &netlink.Addr{IPNet: l}); err != nil {
+		return fmt.Errorf("delete %s from %v: %w", l, p.iface, err)
+	}
+	return nil
+}
+
+// Timers returns when the lease is to be renewed and rebound and when it
+// expires. The renewal and rebinding times default to 1/2 and 7/8 of the
+// lease time, as RFC 2131 suggests.
+func (p *Packet4) Timers() Timers {
+	lease := p.P.IPAddressLeaseTime(0)
+	if lease == 0 || lease == infiniteLease {
+		return Timers{}
+	}
+	return newTimers(p.P.IPAddressRenewalTime(lease/2), p.P.IPAddressRebindingTime(lease*7/8), lease)
+}
+
 func (p *Packet4) String() string {
 	return fmt.Sprintf("IPv4 DHCP Lease IP %s", p.Lease())
 }
@@ -200,15 +224,24 @@ func (p *Packet4) Boot() (*url.URL, error) {
 // Parses the IPv4 DHCP Root Path for iSCSI target and volume as specified by
 // RFC 4173.
 func (p *Packet4) ISCSIBoot() (*net.TCPAddr, string, error) {
+	uri, err := p.iscsiURI()
+	if err != nil {
+		return nil, "", err
+	}
+	return ParseISCSIURI(uri)
+}
+
+// iscsiURI returns the iSCSI URI of the root path, or else of the boot file.
+func (p *Packet4) iscsiURI() (string, error) {
 	rp := p.P.RootPath()
 	if len(rp) > 0 {
-		return ParseISCSIURI(rp)
+		return rp, nil
 	}
 	bootfilename := p.bootfilename()
 	if len(bootfilename) > 0 && strings.HasPrefix(bootfilename, "iscsi:") {
-		return ParseISCSIURI(bootfilename)
+		return bootfilename, nil
 	}
-	return nil, "", ErrNoRootPath
+	return "", ErrNoRootPath
 }
 
 // Response returns the DHCP response
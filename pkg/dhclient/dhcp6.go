@@ -91,6 +91,35 @@ func (p *Packet6) Configure() error {
 	return nil
 }
 
+// Unconfigure removes the address of this lease from the interface.
+func (p *Packet6) Unconfigure() error {
+	l := p.Lease()
+	if l == nil {
+		return fmt.Errorf("no lease returned")
+	}
+	dst := &netlink.Addr{IPNet: &net.IPNet{IP: l.IPv6Addr, Mask: net.CIDRMask(128, 128)}}
+	if err := netlink.AddrDel(p.iface, dst); err != nil {
+		return fmt.Errorf("delete %s from %v: %w", dst, p.iface, err)
+	}
+	return nil
+}
+
+// Timers returns when the lease is to be renewed and rebound and when it
+// expires. If the server leaves T1 and T2 to the client, they are 1/2 and
+// 4/5 of the preferred lifetime, as RFC 8415 recommends.
+func (p *Packet6) Timers() Timers {
+	iana := p.p.Options.OneIANA()
+	l := p.Lease()
+	if iana == nil || l == nil || l.ValidLifetime == infiniteLease {
+		return Timers{}
+	}
+	t1, t2 := iana.T1, iana.T2
+	if t1 == 0 && t2 == 0 {
+		t1, t2 = l.PreferredLifetime/2, l.PreferredLifetime*4/5
+	}
+	return newTimers(t1, t2, l.ValidLifetime)
+}
+
 func (p *Packet6) String() string {
 	if p.Lease() != nil {
 		return fmt.Sprintf("IPv6 DHCP Lease IP %s", p.Lease().IPv6Addr)
@@ -127,9 +156,18 @@ func (p *Packet6) Boot() (*url.URL, error) {
 // Parses the DHCPv6 Boot File for iSCSI target and volume as specified by RFC
 // 4173 and RFC 5970.
 func (p *Packet6) ISCSIBoot() (*net.TCPAddr, string, error) {
+	uri, err := p.iscsiURI()
+	if err != nil {
+		return nil, "", err
+	}
+	return ParseISCSIURI(uri)
+}
+
+// iscsiURI returns the iSCSI URI of the boot file.
+func (p *Packet6) iscsiURI() (string, error) {
 	uri := p.p.Options.BootFileURL()
 	if len(uri) == 0 {
-		return nil, "", fmt.Errorf("packet does not contain boot file URL")
+		return "", fmt.Errorf("packet does not contain boot file URL")
 	}
-	return ParseISCSIURI(uri)
+	return uri, nil
 }
@@ -67,12 +67,20 @@ const (
 // "<targetname>" may contain an arbitrary string with an arbitrary number of
 // colons.
 func ParseISCSIURI(s string) (*net.TCPAddr, string, error) {
+	addr, _, volume, err := parseISCSIURI(s)
+	return addr, volume, err
+}
+
+// parseISCSIURI is ParseISCSIURI, which also returns the LUN, see parseLUN.
+func parseISCSIURI(s string) (*net.TCPAddr, uint64, string, error) {
 	var (
 		// port has a default value according to RFC 4173.
 		port   = 3260
 		ip     net.IP
+		lun    uint64
 		volume string
 		magic  string
+		err    error
 	)
 	i := &iscsiURIParser{
 		state:   "normal",
@@ -87,14 +95,18 @@ func ParseISCSIURI(s string) (*net.TCPAddr, string, error) {
 		case serverField:
 			tok = strings.TrimPrefix(tok, "@") // ignore any leading @
 			ip = net.ParseIP(tok)
-		case protField, lunField:
+		case protField:
 			// yeah whatever
 			continue
+		case lunField:
+			if lun, err = parseLUN(tok); err != nil {
+				return nil, 0, "", fmt.Errorf("iSCSI URI %q has invalid LUN: %w", s, err)
+			}
 		case portField:
 			if len(tok) > 0 {
 				pv, err := strconv.Atoi(tok)
 				if err != nil {
-					return nil, "", fmt.Errorf("iSCSI URI %q has invalid port: %w", s, err)
+					return nil, 0, "", fmt.Errorf("iSCSI URI %q has invalid port: %w", s, err)
 				}
 				port = pv
 			}
@@ -103,18 +115,57 @@ func ParseISCSIURI(s string) (*net.TCPAddr, string, error) {
 		}
 	}
 	if i.err != nil {
-		return nil, "", fmt.Errorf("iSCSI URI %q failed to parse: %w", s, i.err)
+		return nil, 0, "", fmt.Errorf("iSCSI URI %q failed to parse: %w", s, i.err)
 	}
 	if magic != "iscsi" {
-		return nil, "", fmt.Errorf("iSCSI URI %q is missing iscsi scheme prefix, have %s", s, magic)
+		return nil, 0, "", fmt.Errorf("iSCSI URI %q is missing iscsi scheme prefix, have %s", s, magic)
 	}
 	if len(volume) == 0 {
-		return nil, "", fmt.Errorf("iSCSI URI %q is missing a volume name", s)
+		return nil, 0, "", fmt.Errorf("iSCSI URI %q is missing a volume name", s)
 	}
 	return &net.TCPAddr{
 		IP:   ip,
 		Port: port,
-	}, volume, nil
+	}, lun, volume, nil
+}
+
+// parseLUN parses the LUN of an iSCSI URI as iPXE does: up to four levels of
+// up to four hex digits, separated by dashes. The LUN is returned in the
+// eight bytes of SAM, first level first, so LUN 1 is 0x0001000000000000. An
+// empty LUN is LUN 0.
+func parseLUN(s string) (uint64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	levels := strings.Split(s, "-")
+	if len(levels) > 4 {
+		return 0, fmt.Errorf("%q has more than 4 levels", s)
+	}
+	var lun uint64
+	for i, l := range levels {
+		v, err := strconv.ParseUint(l, 16, 16)
+		if err != nil {
+			return 0, err
+		}
+		lun |= v << (48 - 16*i)
+	}
+	return lun, nil
+}
+
+// ISCSIBootLUN returns the LUN of the iSCSI target of lease to boot from,
+// see parseLUN, which ISCSIBoot leaves out. Leases of other types than
+// Packet4 and Packet6 boot from LUN 0.
+func ISCSIBootLUN(lease Lease) (uint64, error) {
+	l, ok := lease.(interface{ iscsiURI() (string, error) })
+	if !ok {
+		return 0, nil
+	}
+	uri, err := l.iscsiURI()
+	if err != nil {
+		return 0, err
+	}
+	_, lun, _, err := parseISCSIURI(uri)
+	return lun, err
 }
 
 func (i *iscsiURIParser) next() (iscsiField, string) {
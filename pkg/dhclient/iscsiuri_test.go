@@ -104,3 +104,32 @@ func TestParseURI(t *testing.T) {
 		}
 	}
 }
+
+func TestParseLUN(t *testing.T) {
+	for _, tt := range []struct {
+		uri  string
+		lun  uint64
+		want string
+	}{
+		{uri: "iscsi:192.168.1.1::::iqn.com.oracle:boot", lun: 0},
+		{uri: "iscsi:192.168.1.1:::1:iqn.com.oracle:boot", lun: 0x0001000000000000},
+		{uri: "iscsi:192.168.1.1:::4000-1:iqn.com.oracle:boot", lun: 0x4000000100000000},
+		{uri: "iscsi:192.168.1.1:::1-2-3-4:iqn.com.oracle:boot", lun: 0x0001000200030004},
+		{
+			uri:  "iscsi:192.168.1.1:::1-2-3-4-5:iqn.com.oracle:boot",
+			want: "iSCSI URI \"iscsi:192.168.1.1:::1-2-3-4-5:iqn.com.oracle:boot\" has invalid LUN: \"1-2-3-4-5\" has more than 4 levels",
+		},
+		{
+			uri:  "iscsi:192.168.1.1:::10000:iqn.com.oracle:boot",
+			want: "iSCSI URI \"iscsi:192.168.1.1:::10000:iqn.com.oracle:boot\" has invalid LUN: strconv.ParseUint: parsing \"10000\": value out of range",
+		},
+	} {
+		_, lun, _, err := parseISCSIURI(tt.uri)
+		if (err != nil && err.Error() != tt.want) || (err == nil && len(tt.want) > 0) {
+			t.Errorf("parseISCSIURI(%s) = %v, want %v", tt.uri, err, tt.want)
+		}
+		if lun != tt.lun {
+			t.Errorf("parseISCSIURI(%s) = LUN %#x, want %#x", tt.uri, lun, tt.lun)
+		}
+	}
+}
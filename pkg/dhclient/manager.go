@@ -0,0 +1,537 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dhclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/vishvananda/netlink"
+)
+
+// infiniteLease is the lease time 0xffffffff, which both DHCPv4 and DHCPv6
+// use for leases that never expire.
+const infiniteLease = 0xffffffff * time.Second
+
+// DefaultMinRetry is the shortest time between two attempts to renew or
+// rebind a lease, per RFC 2131, Section 4.4.5.
+const DefaultMinRetry = 60 * time.Second
+
+// ErrNAK is returned when a DHCP server declines to extend a lease.
+var ErrNAK = errors.New("DHCP server declined the lease")
+
+// Timers are the times at which a lease is to be renewed (T1) and rebound
+// (T2) and at which it expires, relative to when it was requested. The zero
+// Timers are those of a lease that never expires.
+type Timers struct {
+	Renew  time.Duration
+	Rebind time.Duration
+	Expire time.Duration
+}
+
+// newTimers returns Timers with T1 <= T2 <= expiry, replacing server
+// values that violate the order.
+func newTimers(t1, t2, expire time.Duration) Timers {
+	if expire <= 0 {
+		return Timers{}
+	}
+	if t2 <= 0 || t2 > expire {
+		t2 = expire
+	}
+	if t1 <= 0 || t1 > t2 {
+		t1 = t2
+	}
+	return Timers{Renew: t1, Rebind: t2, Expire: expire}
+}
+
+// timedLease is a Lease that expires. Packet4 and Packet6 are timed; a
+// Manager holds other Leases forever.
+type timedLease interface {
+	Timers() Timers
+}
+
+// unconfigurer is a Lease whose configuration can be removed again.
+type unconfigurer interface {
+	Unconfigure() error
+}
+
+// State is the state of a binding in the client state machine of RFC 2131,
+// Section 4.4, which DHCPv6 (RFC 8415, Section 18.2) follows as well.
+type State int
+
+// States of a binding.
+const (
+	// StateInit is a binding without a lease, requesting one.
+	StateInit State = iota
+	// StateBound is a binding with a lease that waits for T1.
+	StateBound
+	// StateRenewing is extending the lease with the server that granted it.
+	StateRenewing
+	// StateRebinding is extending the lease with any server.
+	StateRebinding
+	// StateReleased has given its lease back when the Manager stopped.
+	StateReleased
+)
+
+var stateNames = []string{"init", "bound", "renewing", "rebinding", "released"}
+
+func (s State) String() string {
+	if s >= 0 && int(s) < len(stateNames) {
+		return stateNames[s]
+	}
+	return fmt.Sprintf("unknown state (%d)", int(s))
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s State) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *State) UnmarshalText(b []byte) error {
+	i := slices.Index(stateNames, string(b))
+	if i < 0 {
+		return fmt.Errorf("unknown state %q", b)
+	}
+	*s = State(i)
+	return nil
+}
+
+// BindingStatus is the state of the lease of one protocol on one
+// interface.
+type BindingStatus struct {
+	Interface string    `json:"interface"`
+	Protocol  string    `json:"protocol"`
+	State     State     `json:"state"`
+	Address   string    `json:"address,omitempty"`
+	Server    string    `json:"server,omitempty"`
+	Acquired  time.Time `json:"acquired,omitzero"`
+	Renew     time.Time `json:"renew,omitzero"`
+	Rebind    time.Time `json:"rebind,omitzero"`
+	Expire    time.Time `json:"expire,omitzero"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// leaseClient runs the DHCP exchanges of one protocol on one interface.
+type leaseClient interface {
+	// Request obtains a new lease.
+	Request(ctx context.Context) (Lease, error)
+	// Renew extends l with the server that granted it.
+	Renew(ctx context.Context, l Lease) (Lease, error)
+	// Rebind extends l with any server.
+	Rebind(ctx context.Context, l Lease) (Lease, error)
+	// Release gives l back to its server.
+	Release(l Lease) error
+}
+
+// Manager keeps DHCP leases on a set of interfaces. It renews and rebinds
+// them as their timers expire, reapplies the configuration of every
+// extended lease, removes expired and declined leases, and extends leases
+// as soon as a link comes back up, in case it moved to another network.
+type Manager struct {
+	// Config configures the DHCP exchanges.
+	Config Config
+
+	// IPv4 and IPv6 select the protocols to obtain leases for.
+	IPv4 bool
+	IPv6 bool
+
+	// LinkUpTimeout is how long to wait for an interface to come up.
+	LinkUpTimeout time.Duration
+
+	// MinRetry is the shortest time between two attempts to obtain,
+	// renew or rebind a lease. It defaults to DefaultMinRetry.
+	MinRetry time.Duration
+
+	// Release releases all leases when Run returns.
+	Release bool
+
+	// DryRun obtains leases without configuring interfaces.
+	DryRun bool
+
+	mu       sync.Mutex
+	bindings []*binding
+
+	// newClient and subscribe are replaced in tests.
+	newClient func(iface netlink.Link, p NetworkProtocol) leaseClient
+	subscribe func(ch chan<- netlink.LinkUpdate, done <-chan struct{}) error
+}
+
+func (m *Manager) minRetry() time.Duration {
+	if m.MinRetry > 0 {
+		return m.MinRetry
+	}
+	return DefaultMinRetry
+}
+
+// Run obtains and keeps leases on ifs until ctx is done.
+func (m *Manager) Run(ctx context.Context, ifs []netlink.Link) error {
+	newClient, subscribe := m.newClient, m.subscribe
+	if newClient == nil {
+		newClient = m.newLeaseClient
+	}
+	if subscribe == nil {
+		subscribe = netlink.LinkSubscribe
+	}
+	updates := make(chan netlink.LinkUpdate, 16)
+	done := make(chan struct{})
+	defer close(done)
+	if err := subscribe(updates, done); err != nil {
+		return fmt.Errorf("cannot watch links: %w", err)
+	}
+
+	var bs []*binding
+	for _, iface := range ifs {
+		for _, p := range []NetworkProtocol{NetIPv4, NetIPv6} {
+			if (p == NetIPv4 && !m.IPv4) || (p == NetIPv6 && !m.IPv6) {
+				continue
+			}
+			b := &binding{
+				m:      m,
+				iface:  iface,
+				proto:  p,
+				client: newClient(iface, p),
+				links:  make(chan bool, 1),
+			}
+			b.update(StateInit, nil)
+			bs = append(bs, b)
+		}
+	}
+	m.mu.Lock()
+	m.bindings = bs
+	m.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, b := range bs {
+		wg.Go(func() { b.run(ctx) })
+	}
+	finished := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(finished)
+	}()
+
+	// Links are up, or about to be brought up by the bindings.
+	up := make(map[int]bool)
+	for {
+		select {
+		case u, ok := <-updates:
+			if !ok {
+				log.Printf("Stopped watching links")
+				updates = nil
+				continue
+			}
+			a := u.Attrs()
+			isUp := a.Flags&net.FlagUp != 0 && (a.OperState == netlink.OperUp || a.OperState == netlink.OperUnknown)
+			wasUp, known := up[a.Index]
+			up[a.Index] = isUp
+			if known && wasUp == isUp || !known && isUp {
+				continue
+			}
+			for _, b := range bs {
+				if b.iface.Attrs().Index == a.Index {
+					b.notify(isUp)
+				}
+			}
+		case <-finished:
+			return nil
+		}
+	}
+}
+
+// Status returns the state of every binding.
+func (m *Manager) Status() []BindingStatus {
+	m.mu.Lock()
+	bs := m.bindings
+	m.mu.Unlock()
+	s := make([]BindingStatus, 0, len(bs))
+	for _, b := range bs {
+		b.mu.Lock()
+		s = append(s, b.status)
+		b.mu.Unlock()
+	}
+	return s
+}
+
+// ServeStatus writes Status as JSON to every connection accepted on ln,
+// until ln is closed.
+func (m *Manager) ServeStatus(ln net.Listener) error {
+	for {
+		c, err := ln.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := json.NewEncoder(c).Encode(m.Status()); err != nil {
+			log.Printf("Writing status: %v", err)
+		}
+		c.Close()
+	}
+}
+
+// waitResult is why binding.wait returned.
+type waitResult int
+
+const (
+	waitTimer waitResult = iota
+	waitLinkUp
+	waitDone
+)
+
+// binding is the lease of one protocol on one interface.
+type binding struct {
+	m      *Manager
+	iface  netlink.Link
+	proto  NetworkProtocol
+	client leaseClient
+
+	// links receives false when the link goes down and true when it
+	// comes back up.
+	links chan bool
+
+	// Only the goroutine running the binding uses these.
+	lease    Lease
+	timers   Timers
+	acquired time.Time
+
+	mu     sync.Mutex
+	status BindingStatus
+}
+
+// notify reports a link state change, replacing one not yet seen.
+func (b *binding) notify(up bool) {
+	select {
+	case <-b.links:
+		// A down followed by an up is still a reason to renew.
+		up = true
+	default:
+	}
+	b.links <- up
+}
+
+func (b *binding) name() string {
+	return fmt.Sprintf("%s on %s", b.proto, b.iface.Attrs().Name)
+}
+
+// wait waits for d, or forever if d is negative.
+func (b *binding) wait(ctx context.Context, d time.Duration) waitResult {
+	var timer <-chan time.Time
+	if d >= 0 {
+		t := time.NewTimer(d)
+		defer t.Stop()
+		timer = t.C
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return waitDone
+		case <-timer:
+			return waitTimer
+		case up := <-b.links:
+			if up {
+				log.Printf("%s: link is up", b.name())
+				return waitLinkUp
+			}
+			log.Printf("%s: link is down", b.name())
+		}
+	}
+}
+
+// deadline returns the time at which the lease reaches t, which is zero
+// for a lease that never expires.
+func (b *binding) deadline(t time.Duration) time.Time {
+	if b.timers.Expire == 0 {
+		return time.Time{}
+	}
+	return b.acquired.Add(t)
+}
+
+func (b *binding) run(ctx context.Context) {
+	state := StateInit
+	for ctx.Err() == nil {
+		switch state {
+		case StateInit:
+			b.update(state, nil)
+			start := time.Now()
+			l, err := b.client.Request(ctx)
+			if err == nil {
+				b.bind(l, start)
+				state = StateBound
+				continue
+			}
+			if ctx.Err() != nil {
+				break
+			}
+			log.Printf("%s: no lease: %v", b.name(), err)
+			b.update(state, err)
+			b.wait(ctx, b.m.minRetry())
+
+		case StateBound:
+			b.update(state, nil)
+			d := time.Duration(-1)
+			if b.timers.Expire != 0 {
+				d = time.Until(b.deadline(b.timers.Renew))
+			}
+			switch b.wait(ctx, d) {
+			case waitTimer:
+				state = StateRenewing
+			case waitLinkUp:
+				state = StateRebinding
+			}
+
+		case StateRenewing, StateRebinding:
+			b.update(state, nil)
+			start := time.Now()
+			var l Lease
+			var err error
+			if state == StateRenewing {
+				l, err = b.client.Renew(ctx, b.lease)
+			} else {
+				l, err = b.client.Rebind(ctx, b.lease)
+			}
+			if err == nil {
+				b.bind(l, start)
+				state = StateBound
+				continue
+			}
+			if ctx.Err() != nil {
+				break
+			}
+			if errors.Is(err, ErrNAK) {
+				log.Printf("%s: %v", b.name(), err)
+				b.unbind()
+				state = StateInit
+				continue
+			}
+			log.Printf("%s: %s failed: %v", b.name(), state, err)
+			b.update(state, err)
+			if b.timers.Expire == 0 {
+				// The lease stays valid; try again when the link
+				// next comes up.
+				state = StateBound
+				continue
+			}
+			next := b.deadline(b.timers.Rebind)
+			if state == StateRebinding {
+				next = b.deadline(b.timers.Expire)
+			}
+			left := time.Until(next)
+			if left <= 0 {
+				if state == StateRenewing {
+					state = StateRebinding
+				} else {
+					log.Printf("%s: lease expired", b.name())
+					b.unbind()
+					state = StateInit
+				}
+				continue
+			}
+			// RFC 2131, Section 4.4.5: wait half the remaining time,
+			// but no less than a minute.
+			if b.wait(ctx, min(max(left/2, b.m.minRetry()), left)) == waitLinkUp {
+				state = StateRebinding
+			}
+		}
+	}
+
+	if b.lease != nil && b.m.Release {
+		if err := b.client.Release(b.lease); err != nil {
+			log.Printf("%s: release: %v", b.name(), err)
+		}
+		b.unbind()
+		b.update(StateReleased, nil)
+	}
+}
+
+// bind configures the interface with l, which was requested at start.
+func (b *binding) bind(l Lease, start time.Time) {
+	if b.lease != nil {
+		if old, _ := leaseInfo(b.lease); old != "" {
+			if addr, _ := leaseInfo(l); addr != old {
+				log.Printf("%s: address changed from %s to %s", b.name(), old, addr)
+				b.unconfigure()
+			}
+		}
+	}
+	b.lease, b.acquired, b.timers = l, start, Timers{}
+	if t, ok := l.(timedLease); ok {
+		b.timers = t.Timers()
+	}
+	if b.m.DryRun {
+		log.Printf("Dry run: would have configured %s with %s", b.iface.Attrs().Name, l)
+		return
+	}
+	if err := l.Configure(); err != nil {
+		log.Printf("Could not configure %s for %s: %v", b.iface.Attrs().Name, b.proto, err)
+		return
+	}
+	log.Printf("Configured %s with %s", b.iface.Attrs().Name, l)
+}
+
+// unbind removes the lease from the interface.
+func (b *binding) unbind() {
+	b.unconfigure()
+	b.lease, b.timers = nil, Timers{}
+}
+
+func (b *binding) unconfigure() {
+	u, ok := b.lease.(unconfigurer)
+	if b.m.DryRun || !ok {
+		return
+	}
+	if err := u.Unconfigure(); err != nil {
+		log.Printf("Could not unconfigure %s for %s: %v", b.iface.Attrs().Name, b.proto, err)
+	}
+}
+
+// update records the state of the binding for Status.
+func (b *binding) update(state State, err error) {
+	s := BindingStatus{
+		Interface: b.iface.Attrs().Name,
+		Protocol:  b.proto.String(),
+		State:     state,
+	}
+	if err != nil {
+		s.Error = err.Error()
+	}
+	if b.lease != nil {
+		s.Address, s.Server = leaseInfo(b.lease)
+		s.Acquired = b.acquired
+		s.Renew = b.deadline(b.timers.Renew)
+		s.Rebind = b.deadline(b.timers.Rebind)
+		s.Expire = b.deadline(b.timers.Expire)
+	}
+	b.mu.Lock()
+	b.status = s
+	b.mu.Unlock()
+}
+
+// leaseInfo returns the address l assigns and the server that granted it.
+func leaseInfo(l Lease) (addr, server string) {
+	switch m4, m6 := l.Message(); {
+	case m4 != nil:
+		addr = NewPacket4(l.Link(), m4).Lease().String()
+		if s := m4.ServerIdentifier(); s != nil {
+			server = s.String()
+		}
+	case m6 != nil:
+		if a := NewPacket6(l.Link(), m6).Lease(); a != nil {
+			addr = a.IPv6Addr.String()
+		}
+		if id := m6.Options.ServerID(); id != nil {
+			server = id.String()
+		}
+	}
+	return addr, server
+}
@@ -0,0 +1,385 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dhclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/insomniacslk/dhcp/dhcpv4"
+	"github.com/insomniacslk/dhcp/dhcpv6"
+	"github.com/vishvananda/netlink"
+)
+
+func TestTimers(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		l    timedLease
+		want Timers
+	}{
+		{
+			name: "v4 defaults",
+			l: NewPacket4(nil, mustNew(t,
+				dhcpv4.WithLeaseTime(3600),
+			)),
+			want: Timers{Renew: 1800 * time.Second, Rebind: 3150 * time.Second, Expire: time.Hour},
+		},
+		{
+			name: "v4 server timers",
+			l: NewPacket4(nil, mustNew(t,
+				dhcpv4.WithLeaseTime(3600),
+				dhcpv4.WithOption(dhcpv4.Option{Code: dhcpv4.OptionRenewTimeValue, Value: dhcpv4.Duration(600 * time.Second)}),
+				dhcpv4.WithOption(dhcpv4.Option{Code: dhcpv4.OptionRebindingTimeValue, Value: dhcpv4.Duration(900 * time.Second)}),
+			)),
+			want: Timers{Renew: 600 * time.Second, Rebind: 900 * time.Second, Expire: time.Hour},
+		},
+		{
+			name: "v4 out of order",
+			l: NewPacket4(nil, mustNew(t,
+				dhcpv4.WithLeaseTime(100),
+				dhcpv4.WithOption(dhcpv4.Option{Code: dhcpv4.OptionRenewTimeValue, Value: dhcpv4.Duration(90 * time.Second)}),
+				dhcpv4.WithOption(dhcpv4.Option{Code: dhcpv4.OptionRebindingTimeValue, Value: dhcpv4.Duration(200 * time.Second)}),
+			)),
+			want: Timers{Renew: 90 * time.Second, Rebind: 100 * time.Second, Expire: 100 * time.Second},
+		},
+		{
+			name: "v4 infinite",
+			l:    NewPacket4(nil, mustNew(t, dhcpv4.WithLeaseTime(0xffffffff))),
+		},
+		{
+			name: "v4 no lease time",
+			l:    NewPacket4(nil, mustNew(t)),
+		},
+		{
+			name: "v6 defaults",
+			l:    NewPacket6(nil, reply6(0, 0, 1000*time.Second, 2000*time.Second)),
+			want: Timers{Renew: 500 * time.Second, Rebind: 800 * time.Second, Expire: 2000 * time.Second},
+		},
+		{
+			name: "v6 server timers",
+			l:    NewPacket6(nil, reply6(100*time.Second, 150*time.Second, 1000*time.Second, 2000*time.Second)),
+			want: Timers{Renew: 100 * time.Second, Rebind: 150 * time.Second, Expire: 2000 * time.Second},
+		},
+		{
+			name: "v6 infinite",
+			l:    NewPacket6(nil, reply6(0, 0, infiniteLease, infiniteLease)),
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.l.Timers(); got != tt.want {
+				t.Errorf("Timers() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func reply6(t1, t2, preferred, valid time.Duration) *dhcpv6.Message {
+	m := &dhcpv6.Message{MessageType: dhcpv6.MessageTypeReply}
+	m.AddOption(&dhcpv6.OptIANA{
+		T1: t1,
+		T2: t2,
+		Options: dhcpv6.IdentityOptions{Options: []dhcpv6.Option{&dhcpv6.OptIAAddress{
+			IPv6Addr:          net.ParseIP("2001:db8::10"),
+			PreferredLifetime: preferred,
+			ValidLifetime:     valid,
+		}}},
+	})
+	return m
+}
+
+func TestNewMessage6(t *testing.T) {
+	reply := reply6(100*time.Second, 150*time.Second, 1000*time.Second, 2000*time.Second)
+	reply.AddOption(dhcpv6.OptClientID(&dhcpv6.DUIDLL{HWType: 1, LinkLayerAddr: net.HardwareAddr{2, 0, 0, 0, 0, 1}}))
+	reply.AddOption(dhcpv6.OptServerID(&dhcpv6.DUIDLL{HWType: 1, LinkLayerAddr: net.HardwareAddr{2, 0, 0, 0, 0, 2}}))
+	for _, typ := range []dhcpv6.MessageType{dhcpv6.MessageTypeRenew, dhcpv6.MessageTypeRebind, dhcpv6.MessageTypeRelease} {
+		m, err := newMessage6(reply, typ)
+		if err != nil {
+			t.Fatalf("newMessage6(%s) = %v", typ, err)
+		}
+		if m.Type() != typ || m.Options.ClientID() == nil {
+			t.Errorf("%s: got %s with client ID %v", typ, m.Type(), m.Options.ClientID())
+		}
+		if got := m.Options.ServerID() != nil; got != (typ != dhcpv6.MessageTypeRebind) {
+			t.Errorf("%s: has server ID = %t", typ, got)
+		}
+		if a := m.Options.OneIANA().Options.OneAddress(); a == nil || !a.IPv6Addr.Equal(net.ParseIP("2001:db8::10")) {
+			t.Errorf("%s: IA address %v, want 2001:db8::10", typ, a)
+		}
+	}
+	if _, err := newMessage6(&dhcpv6.Message{}, dhcpv6.MessageTypeRenew); err == nil {
+		t.Errorf("newMessage6 of an empty reply succeeded")
+	}
+}
+
+// events records what the fakes did.
+type events struct {
+	mu sync.Mutex
+	e  []string
+}
+
+func (e *events) add(f string, a ...any) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.e = append(e.e, fmt.Sprintf(f, a...))
+}
+
+// waitFor waits until the events include want, in order.
+func (e *events) waitFor(t *testing.T, want ...string) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		e.mu.Lock()
+		got := slices.Clone(e.e)
+		e.mu.Unlock()
+		if isSubsequence(got, want) {
+			return
+		}
+	}
+	t.Fatalf("events %q do not include %q", e.e, want)
+}
+
+func isSubsequence(s, sub []string) bool {
+	for _, x := range s {
+		if len(sub) > 0 && x == sub[0] {
+			sub = sub[1:]
+		}
+	}
+	return len(sub) == 0
+}
+
+type fakeLease struct {
+	*Packet4
+	t Timers
+	e *events
+}
+
+func (l *fakeLease) Timers() Timers { return l.t }
+
+func (l *fakeLease) Configure() error {
+	l.e.add("configure %s", l.P.YourIPAddr)
+	return nil
+}
+
+func (l *fakeLease) Unconfigure() error {
+	l.e.add("unconfigure %s", l.P.YourIPAddr)
+	return nil
+}
+
+// fakeClient grants leases on 192.0.2.x, renewing or rebinding them while
+// renew and rebind are nil.
+type fakeClient struct {
+	iface netlink.Link
+	t     Timers
+	e     *events
+
+	mu      sync.Mutex
+	next    int
+	renew   error
+	rebind  error
+	request error
+}
+
+func (c *fakeClient) set(renew, rebind, request error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.renew, c.rebind, c.request = renew, rebind, request
+}
+
+func (c *fakeClient) lease(ip net.IP) Lease {
+	m, _ := dhcpv4.New(
+		dhcpv4.WithYourIP(ip),
+		dhcpv4.WithNetmask(net.CIDRMask(24, 32)),
+		dhcpv4.WithServerIP(net.IPv4(192, 0, 2, 1)),
+		dhcpv4.WithOption(dhcpv4.OptServerIdentifier(net.IPv4(192, 0, 2, 1))),
+	)
+	return &fakeLease{Packet4: NewPacket4(c.iface, m), t: c.t, e: c.e}
+}
+
+func (c *fakeClient) Request(ctx context.Context) (Lease, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.e.add("request")
+	if c.request != nil {
+		return nil, c.request
+	}
+	c.next++
+	return c.lease(net.IPv4(192, 0, 2, byte(9+c.next))), nil
+}
+
+func (c *fakeClient) Renew(ctx context.Context, l Lease) (Lease, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.e.add("renew")
+	if c.renew != nil {
+		return nil, c.renew
+	}
+	return c.lease(l.(*fakeLease).P.YourIPAddr), nil
+}
+
+func (c *fakeClient) Rebind(ctx context.Context, l Lease) (Lease, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.e.add("rebind")
+	if c.rebind != nil {
+		return nil, c.rebind
+	}
+	return c.lease(l.(*fakeLease).P.YourIPAddr), nil
+}
+
+func (c *fakeClient) Release(l Lease) error {
+	c.e.add("release %s", l.(*fakeLease).P.YourIPAddr)
+	return nil
+}
+
+// startManager runs a Manager for one IPv4 interface with leases of timers
+// t. Link updates sent to the returned channel reach the manager.
+func startManager(t *testing.T, tm Timers) (*Manager, *fakeClient, *events, chan<- netlink.LinkUpdate, func()) {
+	e := &events{}
+	iface := &netlink.Dummy{LinkAttrs: netlink.LinkAttrs{Name: "eth0", Index: 2}}
+	c := &fakeClient{iface: iface, t: tm, e: e}
+	updates := make(chan netlink.LinkUpdate)
+	m := &Manager{
+		IPv4:     true,
+		MinRetry: 10 * time.Millisecond,
+		Release:  true,
+		newClient: func(netlink.Link, NetworkProtocol) leaseClient {
+			return c
+		},
+		subscribe: func(ch chan<- netlink.LinkUpdate, done <-chan struct{}) error {
+			go func() {
+				for {
+					select {
+					case u := <-updates:
+						ch <- u
+					case <-done:
+						return
+					}
+				}
+			}()
+			return nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() { errc <- m.Run(ctx, []netlink.Link{iface}) }()
+	return m, c, e, updates, func() {
+		cancel()
+		if err := <-errc; err != nil {
+			t.Errorf("Run = %v", err)
+		}
+	}
+}
+
+var testTimers = Timers{Renew: 20 * time.Millisecond, Rebind: 60 * time.Millisecond, Expire: 100 * time.Millisecond}
+
+func TestManagerRenew(t *testing.T) {
+	m, _, e, _, stop := startManager(t, testTimers)
+	e.waitFor(t, "request", "configure 192.0.2.10", "renew", "configure 192.0.2.10", "renew", "configure 192.0.2.10")
+	s := m.Status()
+	if len(s) != 1 || s[0].Interface != "eth0" || s[0].Protocol != "IPv4" || s[0].Address != "192.0.2.10/24" || s[0].Server != "192.0.2.1" {
+		t.Errorf("Status() = %+v", s)
+	}
+	stop()
+	e.waitFor(t, "release 192.0.2.10", "unconfigure 192.0.2.10")
+	if s := m.Status(); s[0].State != StateReleased || s[0].Address != "" {
+		t.Errorf("Status() after Run = %+v, want released", s)
+	}
+}
+
+func TestManagerRebind(t *testing.T) {
+	_, c, e, _, stop := startManager(t, testTimers)
+	defer stop()
+	c.set(errors.New("no response"), nil, nil)
+	e.waitFor(t, "request", "configure 192.0.2.10", "renew", "rebind", "configure 192.0.2.10")
+}
+
+func TestManagerNAK(t *testing.T) {
+	_, c, e, _, stop := startManager(t, testTimers)
+	defer stop()
+	e.waitFor(t, "configure 192.0.2.10")
+	c.set(ErrNAK, nil, nil)
+	e.waitFor(t, "renew", "unconfigure 192.0.2.10", "request", "configure 192.0.2.11")
+}
+
+func TestManagerExpire(t *testing.T) {
+	_, c, e, _, stop := startManager(t, testTimers)
+	defer stop()
+	e.waitFor(t, "configure 192.0.2.10")
+	c.set(errors.New("no response"), errors.New("no response"), errors.New("no response"))
+	e.waitFor(t, "renew", "rebind", "unconfigure 192.0.2.10", "request", "request")
+	c.set(nil, nil, nil)
+	e.waitFor(t, "configure 192.0.2.11")
+}
+
+func TestManagerLinkBounce(t *testing.T) {
+	// The lease never expires, so only the link can make the manager
+	// talk to the server again.
+	_, _, e, updates, stop := startManager(t, Timers{})
+	defer stop()
+	e.waitFor(t, "request", "configure 192.0.2.10")
+	updates <- netlink.LinkUpdate{Link: &netlink.Dummy{LinkAttrs: netlink.LinkAttrs{Index: 2, OperState: netlink.OperDown}}}
+	updates <- netlink.LinkUpdate{Link: &netlink.Dummy{LinkAttrs: netlink.LinkAttrs{Index: 2, Flags: net.FlagUp, OperState: netlink.OperUp}}}
+	e.waitFor(t, "request", "configure 192.0.2.10", "rebind", "configure 192.0.2.10")
+}
+
+func TestServeStatus(t *testing.T) {
+	m, _, e, _, stop := startManager(t, Timers{})
+	defer stop()
+	e.waitFor(t, "configure 192.0.2.10")
+
+	ln, err := net.Listen("unix", filepath.Join(t.TempDir(), "sock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	errc := make(chan error, 1)
+	go func() { errc <- m.ServeStatus(ln) }()
+	c, err := net.Dial("unix", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s []map[string]any
+	if err := json.NewDecoder(c).Decode(&s); err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+	if len(s) != 1 || s[0]["state"] != "bound" || s[0]["address"] != "192.0.2.10/24" {
+		t.Errorf("status = %v", s)
+	}
+	if _, ok := s[0]["expire"]; ok {
+		t.Errorf("status of an infinite lease has an expiry: %v", s)
+	}
+	ln.Close()
+	if err := <-errc; err != nil {
+		t.Errorf("ServeStatus = %v", err)
+	}
+	if b, _ := json.Marshal(State(9)); !strings.Contains(string(b), "unknown") {
+		t.Errorf("State(9) = %s", b)
+	}
+}
+
+func TestStateText(t *testing.T) {
+	for s := StateInit; s <= StateReleased; s++ {
+		b, err := s.MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got State
+		if err := got.UnmarshalText(b); err != nil || got != s {
+			t.Errorf("UnmarshalText(%s) = %v, %v, want %v", b, got, err, s)
+		}
+	}
+	var s State
+	if err := s.UnmarshalText([]byte("asleep")); err == nil {
+		t.Errorf("UnmarshalText(asleep) succeeded")
+	}
+}
@@ -0,0 +1,249 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dhclient
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"syscall"
+	"time"
+
+	"github.com/insomniacslk/dhcp/dhcpv4"
+	"github.com/insomniacslk/dhcp/dhcpv4/nclient4"
+	"github.com/insomniacslk/dhcp/dhcpv6"
+	"github.com/insomniacslk/dhcp/iana"
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+)
+
+func (m *Manager) newLeaseClient(iface netlink.Link, p NetworkProtocol) leaseClient {
+	if p == NetIPv4 {
+		return &client4{iface: iface, c: m.Config, linkUpTimeout: m.LinkUpTimeout}
+	}
+	return &client6{iface: iface, c: m.Config, linkUpTimeout: m.LinkUpTimeout}
+}
+
+// client4 runs DHCPv4 exchanges. Once an interface has an address, renewal
+// and rebinding use an ordinary UDP socket bound to it.
+type client4 struct {
+	iface         netlink.Link
+	c             Config
+	linkUpTimeout time.Duration
+}
+
+func (c *client4) Request(ctx context.Context) (Lease, error) {
+	if _, err := IfUp(c.iface.Attrs().Name, c.linkUpTimeout); err != nil {
+		return nil, err
+	}
+	return lease4(ctx, c.iface, c.c)
+}
+
+func (c *client4) serverPort() int {
+	if c.c.V4ServerAddr != nil {
+		return c.c.V4ServerAddr.Port
+	}
+	return dhcpv4.ServerPort
+}
+
+// listen returns a UDP socket on the DHCP client port of the interface.
+func (c *client4) listen(ctx context.Context) (net.PacketConn, error) {
+	lc := net.ListenConfig{
+		Control: func(network, address string, rc syscall.RawConn) error {
+			var serr error
+			err := rc.Control(func(fd uintptr) {
+				if serr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_REUSEADDR, 1); serr != nil {
+					return
+				}
+				if serr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_BROADCAST, 1); serr != nil {
+					return
+				}
+				serr = unix.BindToDevice(int(fd), c.iface.Attrs().Name)
+			})
+			if err != nil {
+				return err
+			}
+			return serr
+		},
+	}
+	return lc.ListenPacket(ctx, "udp4", fmt.Sprintf(":%d", dhcpv4.ClientPort))
+}
+
+// extend sends a DHCPREQUEST for the address of l to dest.
+func (c *client4) extend(ctx context.Context, l Lease, dest *net.UDPAddr) (Lease, error) {
+	ack, _ := l.Message()
+	if ack == nil {
+		return nil, errors.New("not a DHCPv4 lease")
+	}
+	conn, err := c.listen(ctx)
+	if err != nil {
+		return nil, err
+	}
+	client, err := nclient4.NewWithConn(conn, c.iface.Attrs().HardwareAddr, clientOpts4(c.c)...)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	defer client.Close()
+
+	xid, err := dhcpv4.GenerateTransactionID()
+	if err != nil {
+		return nil, err
+	}
+	mods := append(slices.Clone(c.c.Modifiers4), dhcpv4.WithTransactionID(xid))
+	if c.c.V4ClientIdentifier {
+		mods = append(mods, clientIdentifier(c.iface))
+	}
+	req, err := dhcpv4.NewRenewFromAck(ack, mods...)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.SendAndRead(ctx, dest, req, nclient4.IsMessageType(dhcpv4.MessageTypeAck, dhcpv4.MessageTypeNak))
+	if err != nil {
+		return nil, err
+	}
+	if resp.MessageType() == dhcpv4.MessageTypeNak {
+		return nil, fmt.Errorf("%w: %s", ErrNAK, resp.Message())
+	}
+	if !resp.YourIPAddr.Equal(ack.YourIPAddr) {
+		return nil, fmt.Errorf("%w: server assigned %s instead of %s", ErrNAK, resp.YourIPAddr, ack.YourIPAddr)
+	}
+	return NewPacket4(c.iface, resp), nil
+}
+
+func (c *client4) Renew(ctx context.Context, l Lease) (Lease, error) {
+	ack, _ := l.Message()
+	if ack == nil || ack.ServerIdentifier() == nil {
+		return c.Rebind(ctx, l)
+	}
+	return c.extend(ctx, l, &net.UDPAddr{IP: ack.ServerIdentifier(), Port: c.serverPort()})
+}
+
+func (c *client4) Rebind(ctx context.Context, l Lease) (Lease, error) {
+	return c.extend(ctx, l, &net.UDPAddr{IP: net.IPv4bcast, Port: c.serverPort()})
+}
+
+func (c *client4) Release(l Lease) error {
+	ack, _ := l.Message()
+	if ack == nil || ack.ServerIdentifier() == nil {
+		return errors.New("lease has no server identifier")
+	}
+	xid, err := dhcpv4.GenerateTransactionID()
+	if err != nil {
+		return err
+	}
+	msg, err := dhcpv4.NewReleaseFromACK(ack, dhcpv4.WithTransactionID(xid))
+	if err != nil {
+		return err
+	}
+	conn, err := c.listen(context.Background())
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.WriteTo(msg.ToBytes(), &net.UDPAddr{IP: ack.ServerIdentifier(), Port: c.serverPort()})
+	return err
+}
+
+// client6 runs DHCPv6 exchanges.
+type client6 struct {
+	iface         netlink.Link
+	c             Config
+	linkUpTimeout time.Duration
+}
+
+func (c *client6) Request(ctx context.Context) (Lease, error) {
+	if _, err := IfUp(c.iface.Attrs().Name, c.linkUpTimeout); err != nil {
+		return nil, err
+	}
+	return lease6(ctx, c.iface, c.c, c.linkUpTimeout)
+}
+
+// newMessage6 returns a message of type typ about the lease in reply,
+// following RFC 8415, Sections 18.2.4 to 18.2.7.
+func newMessage6(reply *dhcpv6.Message, typ dhcpv6.MessageType) (*dhcpv6.Message, error) {
+	cid, sid, ia := reply.Options.ClientID(), reply.Options.ServerID(), reply.Options.OneIANA()
+	if cid == nil || sid == nil || ia == nil {
+		return nil, errors.New("reply has no client ID, server ID or IA_NA")
+	}
+	msg, err := dhcpv6.NewMessage()
+	if err != nil {
+		return nil, err
+	}
+	msg.MessageType = typ
+	msg.AddOption(dhcpv6.OptClientID(cid))
+	if typ != dhcpv6.MessageTypeRebind {
+		msg.AddOption(dhcpv6.OptServerID(sid))
+	}
+	msg.AddOption(dhcpv6.OptElapsedTime(0))
+	msg.AddOption(&dhcpv6.OptIANA{IaId: ia.IaId, Options: dhcpv6.IdentityOptions{Options: []dhcpv6.Option{ia.Options.OneAddress()}}})
+	if typ != dhcpv6.MessageTypeRelease {
+		msg.AddOption(dhcpv6.OptRequestedOption(dhcpv6.OptionDNSRecursiveNameServer, dhcpv6.OptionDomainSearchList))
+	}
+	return msg, nil
+}
+
+// exchange sends a message of type typ about l and returns the reply.
+func (c *client6) exchange(ctx context.Context, l Lease, typ dhcpv6.MessageType) (*dhcpv6.Message, error) {
+	_, reply := l.Message()
+	if reply == nil || NewPacket6(c.iface, reply).Lease() == nil {
+		return nil, errors.New("not a DHCPv6 lease")
+	}
+	msg, err := newMessage6(reply, typ)
+	if err != nil {
+		return nil, err
+	}
+	client, err := newClient6(c.iface, c.c)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+	return client.SendAndRead(ctx, client.RemoteAddr(), msg, func(m *dhcpv6.Message) bool {
+		return m.Type() == dhcpv6.MessageTypeReply
+	})
+}
+
+// extend renews or rebinds l.
+func (c *client6) extend(ctx context.Context, l Lease, typ dhcpv6.MessageType) (Lease, error) {
+	resp, err := c.exchange(ctx, l, typ)
+	if err != nil {
+		return nil, err
+	}
+	if s := resp.Options.Status(); s != nil && s.StatusCode != iana.StatusSuccess {
+		if s.StatusCode == iana.StatusNoBinding || s.StatusCode == iana.StatusNotOnLink {
+			return nil, fmt.Errorf("%w: %s", ErrNAK, s)
+		}
+		return nil, fmt.Errorf("%s: %s", typ, s)
+	}
+	ia := resp.Options.OneIANA()
+	if ia == nil || ia.Options.OneAddress() == nil {
+		return nil, fmt.Errorf("%w: reply has no address", ErrNAK)
+	}
+	if s := ia.Options.Status(); s != nil && s.StatusCode != iana.StatusSuccess {
+		return nil, fmt.Errorf("%w: %s", ErrNAK, s)
+	}
+	if ia.Options.OneAddress().ValidLifetime == 0 {
+		return nil, fmt.Errorf("%w: address is no longer valid", ErrNAK)
+	}
+	return NewPacket6(c.iface, resp), nil
+}
+
+func (c *client6) Renew(ctx context.Context, l Lease) (Lease, error) {
+	return c.extend(ctx, l, dhcpv6.MessageTypeRenew)
+}
+
+func (c *client6) Rebind(ctx context.Context, l Lease) (Lease, error) {
+	return c.extend(ctx, l, dhcpv6.MessageTypeRebind)
+}
+
+func (c *client6) Release(l Lease) error {
+	ctx, cancel := context.WithTimeout(context.Background(), cmp.Or(c.c.Timeout, 5*time.Second))
+	defer cancel()
+	_, err := c.exchange(ctx, l, dhcpv6.MessageTypeRelease)
+	return err
+}
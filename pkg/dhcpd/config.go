@@ -0,0 +1,349 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package dhcpd implements a DHCPv4 and DHCPv6 server.
+//
+// The server hands out addresses from a pool per interface, with static
+// reservations by MAC address or DUID, delegates IPv6 prefixes, selects
+// PXE and HTTP boot files by client architecture, and keeps its leases in
+// a file so that they survive restarts.
+package dhcpd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/insomniacslk/dhcp/iana"
+)
+
+// DefaultLeaseTime is the lease time of pools that do not set one.
+const DefaultLeaseTime = 12 * time.Hour
+
+// Config is the configuration of a server.
+type Config struct {
+	// Interfaces are the interfaces to serve on.
+	Interfaces []Interface `json:"interfaces"`
+
+	// LeaseFile is where leases are kept. Leases are only kept in
+	// memory if it is empty.
+	LeaseFile string `json:"lease_file,omitempty"`
+}
+
+// Interface is what is served on one interface.
+type Interface struct {
+	Name string `json:"name"`
+
+	// DHCP4 and DHCP6 are the pools of addresses. Either may be nil not
+	// to serve that protocol.
+	DHCP4 *Pool4 `json:"dhcp4,omitempty"`
+	DHCP6 *Pool6 `json:"dhcp6,omitempty"`
+
+	// Boot are the boot files offered to network booting clients. The
+	// first that matches the architecture of a client is used.
+	Boot []Boot `json:"boot,omitempty"`
+}
+
+// Pool4 is a pool of IPv4 addresses.
+type Pool4 struct {
+	// Subnet is the subnet of the pool, and gives the netmask.
+	Subnet netip.Prefix `json:"subnet"`
+
+	// Start and End are the first and last address handed out. They
+	// default to the first and last host addresses of Subnet.
+	Start netip.Addr `json:"start,omitzero"`
+	End   netip.Addr `json:"end,omitzero"`
+
+	// ServerIP identifies the server. It defaults to the address of the
+	// interface in Subnet.
+	ServerIP netip.Addr `json:"server_ip,omitzero"`
+
+	Routers   []netip.Addr `json:"routers,omitempty"`
+	DNS       []netip.Addr `json:"dns,omitempty"`
+	Domain    string       `json:"domain,omitempty"`
+	LeaseTime Duration     `json:"lease_time,omitzero"`
+
+	// Reservations give fixed addresses to clients by MAC address. The
+	// addresses may be outside of Start and End.
+	Reservations []Reservation `json:"reservations,omitempty"`
+}
+
+// Pool6 is a pool of IPv6 addresses and delegated prefixes.
+type Pool6 struct {
+	// Subnet is the prefix of the link. It defaults to the /64 of Start.
+	// Relayed requests are served from the pool whose Subnet holds the
+	// address of the relay.
+	Subnet netip.Prefix `json:"subnet,omitzero"`
+
+	// Start and End are the first and last address handed out in an
+	// IA_NA. No addresses are handed out if they are not set.
+	Start netip.Addr `json:"start,omitzero"`
+	End   netip.Addr `json:"end,omitzero"`
+
+	// Prefixes are delegated in an IA_PD, split in prefixes of
+	// PrefixLength bits.
+	Prefixes     netip.Prefix `json:"prefixes,omitzero"`
+	PrefixLength int          `json:"prefix_length,omitempty"`
+
+	DNS       []netip.Addr `json:"dns,omitempty"`
+	Domains   []string     `json:"domains,omitempty"`
+	LeaseTime Duration     `json:"lease_time,omitzero"`
+
+	// Reservations give fixed addresses and prefixes to clients by
+	// DUID, or by the MAC address in a link-layer DUID.
+	Reservations []Reservation `json:"reservations,omitempty"`
+}
+
+// Reservation is a fixed lease of a client.
+type Reservation struct {
+	// MAC or DUID identify the client. The DUID is in hex, with or
+	// without colons.
+	MAC  string `json:"mac,omitempty"`
+	DUID string `json:"duid,omitempty"`
+
+	// IP is the address of the client, and Prefix the prefix delegated
+	// to it over DHCPv6.
+	IP     netip.Addr   `json:"ip,omitzero"`
+	Prefix netip.Prefix `json:"prefix,omitzero"`
+
+	Hostname string `json:"hostname,omitempty"`
+}
+
+// Boot is a boot file for network booting clients.
+type Boot struct {
+	// Arch are the client architectures, from option 93 of DHCPv4 and
+	// option 61 of DHCPv6, that the file is for. A Boot without Arch is
+	// for any client.
+	Arch []iana.Arch `json:"arch,omitempty"`
+
+	// File is the DHCPv4 boot file name, which is a URL for HTTP boot.
+	File string `json:"file,omitempty"`
+
+	// NextServer is the DHCPv4 server that File is fetched from. It
+	// defaults to the DHCP server.
+	NextServer netip.Addr `json:"next_server,omitzero"`
+
+	// URL is the DHCPv6 boot file URL.
+	URL string `json:"url,omitempty"`
+}
+
+// Duration is a time.Duration that is a string such as "12h" in JSON.
+type Duration time.Duration
+
+// MarshalJSON implements json.Marshaler.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"12h\": %w", err)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// Parse reads a JSON Config and checks it.
+func Parse(r io.Reader) (*Config, error) {
+	d := json.NewDecoder(r)
+	d.DisallowUnknownFields()
+	var c Config
+	if err := d.Decode(&c); err != nil {
+		return nil, fmt.Errorf("parsing DHCP server configuration: %w", err)
+	}
+	if err := c.check(); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// Load reads a JSON Config from the file at path.
+func Load(path string) (*Config, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Parse(f)
+}
+
+// check checks c and fills in defaults.
+func (c *Config) check() error {
+	if len(c.Interfaces) == 0 {
+		return errors.New("no interfaces to serve on")
+	}
+	seen := make(map[string]bool)
+	for i := range c.Interfaces {
+		ifc := &c.Interfaces[i]
+		if ifc.Name == "" {
+			return errors.New("interface without a name")
+		}
+		if seen[ifc.Name] {
+			return fmt.Errorf("interface %q is declared twice", ifc.Name)
+		}
+		seen[ifc.Name] = true
+		if ifc.DHCP4 == nil && ifc.DHCP6 == nil {
+			return fmt.Errorf("interface %q: no dhcp4 or dhcp6 pool", ifc.Name)
+		}
+		if ifc.DHCP4 != nil {
+			if err := ifc.DHCP4.check(); err != nil {
+				return fmt.Errorf("interface %q: dhcp4: %w", ifc.Name, err)
+			}
+		}
+		if ifc.DHCP6 != nil {
+			if err := ifc.DHCP6.check(); err != nil {
+				return fmt.Errorf("interface %q: dhcp6: %w", ifc.Name, err)
+			}
+		}
+		for _, b := range ifc.Boot {
+			if b.NextServer.IsValid() && !b.NextServer.Is4() {
+				return fmt.Errorf("interface %q: next server %s is not IPv4", ifc.Name, b.NextServer)
+			}
+		}
+	}
+	return nil
+}
+
+func (p *Pool4) check() error {
+	if !p.Subnet.IsValid() || !p.Subnet.Addr().Is4() {
+		return errors.New("an IPv4 subnet is needed")
+	}
+	p.Subnet = p.Subnet.Masked()
+	if !p.Start.IsValid() {
+		p.Start = p.Subnet.Addr().Next()
+	}
+	if !p.End.IsValid() {
+		p.End = lastAddr(p.Subnet).Prev()
+	}
+	if !p.Subnet.Contains(p.Start) || !p.Subnet.Contains(p.End) || p.End.Less(p.Start) {
+		return fmt.Errorf("range %s-%s is not in %s", p.Start, p.End, p.Subnet)
+	}
+	if p.ServerIP.IsValid() && !p.ServerIP.Is4() {
+		return fmt.Errorf("server IP %s is not IPv4", p.ServerIP)
+	}
+	for _, a := range append(p.Routers, p.DNS...) {
+		if !a.Is4() {
+			return fmt.Errorf("%s is not IPv4", a)
+		}
+	}
+	if p.LeaseTime == 0 {
+		p.LeaseTime = Duration(DefaultLeaseTime)
+	}
+	for i := range p.Reservations {
+		r := &p.Reservations[i]
+		if err := r.check(); err != nil {
+			return err
+		}
+		if r.MAC == "" || !r.IP.Is4() || !p.Subnet.Contains(r.IP) {
+			return fmt.Errorf("reservation %d needs a MAC and an IP in %s", i, p.Subnet)
+		}
+	}
+	return nil
+}
+
+func (p *Pool6) check() error {
+	if p.Start.IsValid() != p.End.IsValid() {
+		return errors.New("a range needs a start and an end")
+	}
+	if p.Start.IsValid() {
+		if !p.Start.Is6() || !p.End.Is6() || p.End.Less(p.Start) {
+			return fmt.Errorf("invalid range %s-%s", p.Start, p.End)
+		}
+		if !p.Subnet.IsValid() {
+			p.Subnet = netip.PrefixFrom(p.Start, 64).Masked()
+		}
+	}
+	if p.Subnet.IsValid() {
+		p.Subnet = p.Subnet.Masked()
+		if !p.Subnet.Addr().Is6() {
+			return fmt.Errorf("subnet %s is not IPv6", p.Subnet)
+		}
+		if p.Start.IsValid() && (!p.Subnet.Contains(p.Start) || !p.Subnet.Contains(p.End)) {
+			return fmt.Errorf("range %s-%s is not in %s", p.Start, p.End, p.Subnet)
+		}
+	}
+	if p.Prefixes.IsValid() {
+		p.Prefixes = p.Prefixes.Masked()
+		if !p.Prefixes.Addr().Is6() || p.PrefixLength < p.Prefixes.Bits() || p.PrefixLength > 128 {
+			return fmt.Errorf("cannot delegate /%d prefixes from %s", p.PrefixLength, p.Prefixes)
+		}
+	}
+	if !p.Start.IsValid() && !p.Prefixes.IsValid() {
+		return errors.New("no range of addresses or prefixes")
+	}
+	for _, a := range p.DNS {
+		if !a.Is6() {
+			return fmt.Errorf("%s is not IPv6", a)
+		}
+	}
+	if p.LeaseTime == 0 {
+		p.LeaseTime = Duration(DefaultLeaseTime)
+	}
+	for i := range p.Reservations {
+		r := &p.Reservations[i]
+		if err := r.check(); err != nil {
+			return err
+		}
+		if r.IP.IsValid() && !r.IP.Is6() {
+			return fmt.Errorf("reservation %d: %s is not IPv6", i, r.IP)
+		}
+		if r.Prefix.IsValid() && !r.Prefix.Addr().Is6() {
+			return fmt.Errorf("reservation %d: %s is not IPv6", i, r.Prefix)
+		}
+	}
+	return nil
+}
+
+func (r *Reservation) check() error {
+	if r.MAC == "" && r.DUID == "" {
+		return errors.New("a reservation needs a MAC or a DUID")
+	}
+	if r.MAC != "" {
+		mac, err := net.ParseMAC(r.MAC)
+		if err != nil {
+			return err
+		}
+		r.MAC = mac.String()
+	}
+	if r.DUID != "" {
+		d, err := parseHex(r.DUID)
+		if err != nil {
+			return fmt.Errorf("invalid DUID %q", r.DUID)
+		}
+		r.DUID = d
+	}
+	return nil
+}
+
+// parseHex normalizes hex bytes, with or without colons, to lower case
+// without colons.
+func parseHex(s string) (string, error) {
+	s = strings.ToLower(strings.ReplaceAll(s, ":", ""))
+	if len(s) == 0 || len(s)%2 != 0 || strings.Trim(s, "0123456789abcdef") != "" {
+		return "", errors.New("invalid hex")
+	}
+	return s, nil
+}
+
+// lastAddr returns the last address of p.
+func lastAddr(p netip.Prefix) netip.Addr {
+	a := p.Masked().Addr().AsSlice()
+	for i := p.Bits(); i < len(a)*8; i++ {
+		a[i/8] |= 0x80 >> (i % 8)
+	}
+	last, _ := netip.AddrFromSlice(a)
+	return last
+}
@@ -0,0 +1,67 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dhcpd
+
+import (
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	c, err := Parse(strings.NewReader(`{
+		"interfaces": [{
+			"name": "eth0",
+			"dhcp4": {"subnet": "192.168.0.1/24", "reservations": [{"mac": "52-54-00-12-34-56", "ip": "192.168.0.10"}]},
+			"dhcp6": {"start": "fd00::100", "end": "fd00::1ff", "lease_time": "1h", "reservations": [{"duid": "00:03:00:01:52:54:00:12:34:56"}]}
+		}]
+	}`))
+	if err != nil {
+		t.Fatalf("Parse = %v", err)
+	}
+	p4, p6 := c.Interfaces[0].DHCP4, c.Interfaces[0].DHCP6
+	if p4.Subnet != netip.MustParsePrefix("192.168.0.0/24") || p4.Start != netip.MustParseAddr("192.168.0.1") ||
+		p4.End != netip.MustParseAddr("192.168.0.254") || p4.LeaseTime != Duration(DefaultLeaseTime) {
+		t.Errorf("dhcp4 = %+v", p4)
+	}
+	if p4.Reservations[0].MAC != "52:54:00:12:34:56" {
+		t.Errorf("reservation MAC = %q", p4.Reservations[0].MAC)
+	}
+	if p6.Subnet != netip.MustParsePrefix("fd00::/64") || p6.LeaseTime != Duration(time.Hour) {
+		t.Errorf("dhcp6 = %+v", p6)
+	}
+	if p6.Reservations[0].DUID != "00030001525400123456" {
+		t.Errorf("reservation DUID = %q", p6.Reservations[0].DUID)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, s := range []string{
+		`{}`,
+		`{"interfaces": [{"name": "eth0"}]}`,
+		`{"interfaces": [{"dhcp4": {"subnet": "10.0.0.0/8"}}]}`,
+		`{"interfaces": [{"name": "eth0", "dhcp4": {"subnet": "10.0.0.0/8"}}, {"name": "eth0", "dhcp4": {"subnet": "10.0.0.0/8"}}]}`,
+		`{"interfaces": [{"name": "eth0", "dhcp4": {"subnet": "fd00::/64"}}]}`,
+		`{"interfaces": [{"name": "eth0", "dhcp4": {"subnet": "10.0.0.0/24", "start": "10.0.1.1"}}]}`,
+		`{"interfaces": [{"name": "eth0", "dhcp4": {"subnet": "10.0.0.0/24", "start": "10.0.0.9", "end": "10.0.0.8"}}]}`,
+		`{"interfaces": [{"name": "eth0", "dhcp4": {"subnet": "10.0.0.0/24", "dns": ["fd00::1"]}}]}`,
+		`{"interfaces": [{"name": "eth0", "dhcp4": {"subnet": "10.0.0.0/24", "reservations": [{"ip": "10.0.0.5"}]}}]}`,
+		`{"interfaces": [{"name": "eth0", "dhcp4": {"subnet": "10.0.0.0/24", "reservations": [{"mac": "52:54", "ip": "10.0.0.5"}]}}]}`,
+		`{"interfaces": [{"name": "eth0", "dhcp4": {"subnet": "10.0.0.0/24", "reservations": [{"mac": "52:54:00:12:34:56", "ip": "10.1.0.5"}]}}]}`,
+		`{"interfaces": [{"name": "eth0", "dhcp4": {"subnet": "10.0.0.0/24", "lease_time": 3600}}]}`,
+		`{"interfaces": [{"name": "eth0", "dhcp4": {"subnet": "10.0.0.0/24", "color": "blue"}}]}`,
+		`{"interfaces": [{"name": "eth0", "dhcp6": {}}]}`,
+		`{"interfaces": [{"name": "eth0", "dhcp6": {"start": "fd00::1"}}]}`,
+		`{"interfaces": [{"name": "eth0", "dhcp6": {"start": "fd00::1", "end": "fd01::1"}}]}`,
+		`{"interfaces": [{"name": "eth0", "dhcp6": {"prefixes": "fd00::/48", "prefix_length": 40}}]}`,
+		`{"interfaces": [{"name": "eth0", "dhcp6": {"prefixes": "fd00::/48", "prefix_length": 56, "reservations": [{"duid": "xyz"}]}}]}`,
+		`{"interfaces": [{"name": "eth0", "dhcp4": {"subnet": "10.0.0.0/24"}, "boot": [{"next_server": "fd00::1"}]}]}`,
+	} {
+		if c, err := Parse(strings.NewReader(s)); err == nil {
+			t.Errorf("Parse(%s) = %+v, want an error", s, c)
+		}
+	}
+}
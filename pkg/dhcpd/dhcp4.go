@@ -0,0 +1,291 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dhcpd
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"slices"
+	"time"
+
+	"github.com/insomniacslk/dhcp/dhcpv4"
+)
+
+// handle4 answers the DHCPv4 message m received on ifc.
+func (s *Server) handle4(ifc *iface, conn net.PacketConn, peer net.Addr, m *dhcpv4.DHCPv4) {
+	if m.OpCode != dhcpv4.OpcodeBootRequest {
+		return
+	}
+	reply, err := s.reply4(ifc, m)
+	if err != nil {
+		s.Logf("dhcpd: %s: %s from %s: %v", ifc.Name, m.MessageType(), m.ClientHWAddr, err)
+		return
+	}
+	if reply == nil {
+		return
+	}
+	if _, err := conn.WriteTo(reply.ToBytes(), replyAddr4(m, reply)); err != nil {
+		s.Logf("dhcpd: %s: sending %s to %s: %v", ifc.Name, reply.MessageType(), m.ClientHWAddr, err)
+		return
+	}
+	s.Logf("dhcpd: %s: %s %s to %s", ifc.Name, reply.MessageType(), reply.YourIPAddr, m.ClientHWAddr)
+}
+
+// replyAddr4 is where reply to m goes, as RFC 2131, Section 4.1 says.
+func replyAddr4(m, reply *dhcpv4.DHCPv4) net.Addr {
+	switch {
+	case !m.GatewayIPAddr.IsUnspecified() && m.GatewayIPAddr != nil:
+		return &net.UDPAddr{IP: m.GatewayIPAddr, Port: dhcpv4.ServerPort}
+	case reply.MessageType() != dhcpv4.MessageTypeNak && !m.ClientIPAddr.IsUnspecified() && m.ClientIPAddr != nil:
+		return &net.UDPAddr{IP: m.ClientIPAddr, Port: dhcpv4.ClientPort}
+	}
+	// The client has no address yet, and answering it by unicast would
+	// take a raw socket to skip ARP.
+	return &net.UDPAddr{IP: net.IPv4bcast, Port: dhcpv4.ClientPort}
+}
+
+// pool4 returns the interface whose pool serves a client of m that came in
+// on ifc: that of ifc, or the one holding the address of the relay agent.
+func (s *Server) pool4(ifc *iface, m *dhcpv4.DHCPv4) *iface {
+	relay, ok := netip.AddrFromSlice(m.GatewayIPAddr.To4())
+	if !ok || relay.IsUnspecified() {
+		return ifc
+	}
+	for _, i := range s.ifaces {
+		if i.DHCP4 != nil && i.DHCP4.Subnet.Contains(relay) {
+			return i
+		}
+	}
+	return nil
+}
+
+// reply4 handles m and returns the reply to send, if any.
+func (s *Server) reply4(ifc *iface, m *dhcpv4.DHCPv4) (*dhcpv4.DHCPv4, error) {
+	pi := s.pool4(ifc, m)
+	if pi == nil {
+		return nil, fmt.Errorf("no pool for relay %s", m.GatewayIPAddr)
+	}
+	p := pi.DHCP4
+	mac := m.ClientHWAddr.String()
+	client := mac
+	if id := m.Options.Get(dhcpv4.OptionClientIdentifier); len(id) > 0 {
+		client = hexString(id)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	s.db.expire(now)
+	cur := s.db.find(LeaseIPv4, pi.Name, client)
+
+	switch mt := m.MessageType(); mt {
+	case dhcpv4.MessageTypeDiscover:
+		addr, hostname, err := s.lease4(pi, client, mac, toAddr(m.RequestedIPAddress()))
+		if err != nil {
+			return nil, err
+		}
+		if hostname == "" {
+			hostname = m.HostName()
+		}
+		if cur == nil || cur.Addr != addr {
+			s.db.put(Lease{
+				Type:      LeaseIPv4,
+				Interface: pi.Name,
+				Client:    client,
+				MAC:       mac,
+				Addr:      addr,
+				Hostname:  hostname,
+				Expires:   now.Add(offerHold),
+				offered:   true,
+			})
+		}
+		return ack4(ifc, pi, m, dhcpv4.MessageTypeOffer, addr, hostname)
+
+	case dhcpv4.MessageTypeRequest:
+		if sid := toAddr(m.ServerIdentifier()); sid.IsValid() && sid != ifc.serverIP {
+			// The client took the offer of another server.
+			if cur != nil && cur.offered {
+				s.db.removeLease(cur)
+			}
+			return nil, nil
+		}
+		want := toAddr(m.RequestedIPAddress())
+		if !want.IsValid() {
+			want = toAddr(m.ClientIPAddr)
+		}
+		if !want.IsValid() {
+			return nil, errors.New("no requested address")
+		}
+		addr, hostname, err := s.lease4(pi, client, mac, want)
+		if err != nil || addr != want {
+			// The client moved to another subnet, or the address
+			// went to another client.
+			return nak4(ifc, m, fmt.Sprintf("%s is not available", want))
+		}
+		if hostname == "" {
+			hostname = m.HostName()
+		}
+		if hostname == "" && cur != nil {
+			hostname = cur.Hostname
+		}
+		s.db.put(Lease{
+			Type:      LeaseIPv4,
+			Interface: pi.Name,
+			Client:    client,
+			MAC:       mac,
+			Addr:      addr,
+			Hostname:  hostname,
+			Expires:   now.Add(time.Duration(p.LeaseTime)),
+		})
+		if err := s.db.save(); err != nil {
+			s.Logf("dhcpd: saving leases: %v", err)
+		}
+		return ack4(ifc, pi, m, dhcpv4.MessageTypeAck, addr, hostname)
+
+	case dhcpv4.MessageTypeDecline:
+		addr := toAddr(m.RequestedIPAddress())
+		if cur == nil || cur.Addr != addr {
+			return nil, nil
+		}
+		s.Logf("dhcpd: %s: %s declined %s, which is in use", pi.Name, mac, addr)
+		s.db.removeLease(cur)
+		s.db.put(Lease{Type: LeaseIPv4, Interface: pi.Name, Addr: addr, Expires: now.Add(time.Duration(p.LeaseTime))})
+		if err := s.db.save(); err != nil {
+			s.Logf("dhcpd: saving leases: %v", err)
+		}
+		return nil, nil
+
+	case dhcpv4.MessageTypeRelease:
+		if cur != nil && cur.Addr == toAddr(m.ClientIPAddr) {
+			s.db.removeLease(cur)
+			if err := s.db.save(); err != nil {
+				s.Logf("dhcpd: saving leases: %v", err)
+			}
+		}
+		return nil, nil
+
+	case dhcpv4.MessageTypeInform:
+		return ack4(ifc, pi, m, dhcpv4.MessageTypeAck, netip.Addr{}, "")
+
+	default:
+		return nil, fmt.Errorf("unexpected message type %s", mt)
+	}
+}
+
+// lease4 returns the address for client with mac on pi, preferring its
+// current lease and then want, and the hostname reserved for it.
+func (s *Server) lease4(pi *iface, client, mac string, want netip.Addr) (netip.Addr, string, error) {
+	p := pi.DHCP4
+	if r := reservation(p.Reservations, mac, ""); r != nil {
+		return r.IP, r.Hostname, nil
+	}
+	var cur netip.Addr
+	if l := s.db.find(LeaseIPv4, pi.Name, client); l != nil {
+		cur = l.Addr
+	}
+	free := func(a netip.Addr) bool {
+		if a == pi.serverIP || slices.Contains(p.Routers, a) || reserved(p.Reservations, a, netip.Prefix{}, mac, "") {
+			return false
+		}
+		l := s.db.owner(a, netip.Prefix{})
+		return l == nil || (l.Interface == pi.Name && l.Client == client)
+	}
+	addr, err := pick(p.Start, p.End, free, cur, want)
+	return addr, "", err
+}
+
+// ack4 returns an offer or acknowledgement of addr to m, which came in on
+// ifc, with the options of the pool of pi. An acknowledgement of an
+// inform message has no address.
+func ack4(ifc, pi *iface, m *dhcpv4.DHCPv4, mt dhcpv4.MessageType, addr netip.Addr, hostname string) (*dhcpv4.DHCPv4, error) {
+	p := pi.DHCP4
+	mods := []dhcpv4.Modifier{
+		dhcpv4.WithMessageType(mt),
+		// RFC 2131, Section 4.3.1. Server Identifier: MUST
+		dhcpv4.WithOption(dhcpv4.OptServerIdentifier(ifc.serverIP.AsSlice())),
+		dhcpv4.WithNetmask(net.CIDRMask(p.Subnet.Bits(), 32)),
+	}
+	if addr.IsValid() {
+		lt := time.Duration(p.LeaseTime)
+		mods = append(mods,
+			dhcpv4.WithYourIP(addr.AsSlice()),
+			dhcpv4.WithOption(dhcpv4.OptIPAddressLeaseTime(lt)),
+			dhcpv4.WithOption(dhcpv4.Option{Code: dhcpv4.OptionRenewTimeValue, Value: dhcpv4.Duration(lt / 2)}),
+			dhcpv4.WithOption(dhcpv4.Option{Code: dhcpv4.OptionRebindingTimeValue, Value: dhcpv4.Duration(lt * 7 / 8)}),
+		)
+	}
+	if len(p.Routers) > 0 {
+		mods = append(mods, dhcpv4.WithRouter(toIPs(p.Routers)...))
+	}
+	if len(p.DNS) > 0 {
+		mods = append(mods, dhcpv4.WithDNS(toIPs(p.DNS)...))
+	}
+	if p.Domain != "" {
+		mods = append(mods, dhcpv4.WithOption(dhcpv4.OptDomainName(p.Domain)))
+	}
+	if hostname != "" {
+		mods = append(mods, dhcpv4.WithOption(dhcpv4.OptHostName(hostname)))
+	}
+	reply, err := dhcpv4.NewReplyFromRequest(m, mods...)
+	if err != nil {
+		return nil, err
+	}
+	// RFC 6842, MUST include Client Identifier if client specified one.
+	if id := m.Options.Get(dhcpv4.OptionClientIdentifier); len(id) > 0 {
+		reply.UpdateOption(dhcpv4.OptClientIdentifier(id))
+	}
+	if b := pi.boot(m.ClientArch()); b != nil && b.File != "" {
+		reply.BootFileName = b.File
+		next := b.NextServer
+		if !next.IsValid() {
+			next = ifc.serverIP
+		}
+		reply.ServerIPAddr = next.AsSlice()
+		if isHTTPClient(m.ClassIdentifier()) {
+			reply.UpdateOption(dhcpv4.OptClassIdentifier(httpClient))
+		}
+	}
+	return reply, nil
+}
+
+// nak4 returns a negative acknowledgement of m, which came in on ifc.
+func nak4(ifc *iface, m *dhcpv4.DHCPv4, msg string) (*dhcpv4.DHCPv4, error) {
+	reply, err := dhcpv4.NewReplyFromRequest(m,
+		dhcpv4.WithMessageType(dhcpv4.MessageTypeNak),
+		dhcpv4.WithOption(dhcpv4.OptServerIdentifier(ifc.serverIP.AsSlice())),
+		dhcpv4.WithOption(dhcpv4.OptMessage(msg)),
+	)
+	if err != nil {
+		return nil, err
+	}
+	// RFC 2131, Section 4.3.2: relay agents broadcast negative
+	// acknowledgements.
+	if !m.GatewayIPAddr.IsUnspecified() && m.GatewayIPAddr != nil {
+		reply.SetBroadcast()
+	}
+	return reply, nil
+}
+
+// toAddr returns ip, or an invalid address if it is nil or unspecified.
+func toAddr(ip net.IP) netip.Addr {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	a, ok := netip.AddrFromSlice(ip)
+	if !ok || a.IsUnspecified() {
+		return netip.Addr{}
+	}
+	return a
+}
+
+func toIPs(as []netip.Addr) []net.IP {
+	ips := make([]net.IP, 0, len(as))
+	for _, a := range as {
+		ips = append(ips, a.AsSlice())
+	}
+	return ips
+}
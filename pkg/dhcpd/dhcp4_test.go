@@ -0,0 +1,267 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dhcpd
+
+import (
+	"net"
+	"net/netip"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/insomniacslk/dhcp/dhcpv4"
+	"github.com/insomniacslk/dhcp/dhcpv6"
+	"github.com/insomniacslk/dhcp/iana"
+)
+
+var (
+	mac0 = net.HardwareAddr{0x52, 0x54, 0x00, 0x00, 0x00, 0x01}
+	mac1 = net.HardwareAddr{0x52, 0x54, 0x00, 0x00, 0x00, 0x02}
+	mac2 = net.HardwareAddr{0x52, 0x54, 0x00, 0x00, 0x00, 0x03}
+)
+
+const testConfig = `{
+	"interfaces": [{
+		"name": "eth0",
+		"dhcp4": {
+			"subnet": "192.168.0.0/24", "start": "192.168.0.1", "end": "192.168.0.3",
+			"server_ip": "192.168.0.1", "routers": ["192.168.0.254"], "dns": ["192.168.0.53"],
+			"domain": "example.com", "lease_time": "1h",
+			"reservations": [{"mac": "52:54:00:00:00:03", "ip": "192.168.0.10", "hostname": "node3"}]
+		},
+		"dhcp6": {
+			"start": "fd00::1", "end": "fd00::2",
+			"prefixes": "fd00:100::/62", "prefix_length": 64,
+			"dns": ["fd00::53"], "domains": ["example.com"],
+			"reservations": [{"duid": "00030001525400000003", "ip": "fd00::10", "prefix": "fd00:200::/56"}]
+		},
+		"boot": [
+			{"arch": [7, 9], "file": "bootx64.efi"},
+			{"arch": [16], "file": "http://192.168.0.1/bootx64.efi", "url": "http://[fd00::1]/bootx64.efi"},
+			{"file": "pxelinux.0", "next_server": "192.168.0.2"}
+		]
+	}, {
+		"name": "eth1",
+		"dhcp4": {"subnet": "10.0.0.0/24", "start": "10.0.0.100", "end": "10.0.0.100", "server_ip": "10.0.0.1"},
+		"dhcp6": {"subnet": "fd01::/64", "prefixes": "fd01:100::/48", "prefix_length": 56}
+	}]
+}`
+
+// newTestServer returns a server of testConfig, with a lease file in dir,
+// whose clock is at *now.
+func newTestServer(t *testing.T, dir string, now *time.Time) *Server {
+	t.Helper()
+	c, err := Parse(strings.NewReader(testConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.LeaseFile = filepath.Join(dir, "leases")
+	s, err := New(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.now = func() time.Time { return *now }
+	s.Logf = t.Logf
+	for i := range c.Interfaces {
+		ifc := &iface{Interface: &c.Interfaces[i], serverIP: c.Interfaces[i].DHCP4.ServerIP}
+		ifc.duid = &dhcpv6.DUIDLL{HWType: iana.HWTypeEthernet, LinkLayerAddr: net.HardwareAddr{0x02, 0, 0, 0, 0, byte(i)}}
+		s.ifaces = append(s.ifaces, ifc)
+	}
+	return s
+}
+
+func discover(t *testing.T, mac net.HardwareAddr, mods ...dhcpv4.Modifier) *dhcpv4.DHCPv4 {
+	t.Helper()
+	m, err := dhcpv4.NewDiscovery(mac, mods...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func request(t *testing.T, offer *dhcpv4.DHCPv4, mods ...dhcpv4.Modifier) *dhcpv4.DHCPv4 {
+	t.Helper()
+	offer.OpCode = dhcpv4.OpcodeBootReply
+	m, err := dhcpv4.NewRequestFromOffer(offer, mods...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func (s *Server) mustReply4(t *testing.T, ifc int, m *dhcpv4.DHCPv4, want dhcpv4.MessageType) *dhcpv4.DHCPv4 {
+	t.Helper()
+	r, err := s.reply4(s.ifaces[ifc], m)
+	if err != nil {
+		t.Fatalf("reply to %s = %v", m.MessageType(), err)
+	}
+	if r == nil || r.MessageType() != want {
+		t.Fatalf("reply to %s = %v, want a %s", m.MessageType(), r, want)
+	}
+	return r
+}
+
+func TestDHCPv4(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := newTestServer(t, dir, &now)
+
+	// DORA.
+	offer := s.mustReply4(t, 0, discover(t, mac0, dhcpv4.WithOption(dhcpv4.OptHostName("box"))), dhcpv4.MessageTypeOffer)
+	if got := offer.YourIPAddr.String(); got != "192.168.0.2" {
+		t.Errorf("offered %s, want 192.168.0.2 as the server has .1", got)
+	}
+	if sid := offer.ServerIdentifier(); !sid.Equal(net.IPv4(192, 168, 0, 1)) {
+		t.Errorf("server identifier %s", sid)
+	}
+	if offer.IPAddressLeaseTime(0) != time.Hour || offer.IPAddressRenewalTime(0) != 30*time.Minute ||
+		offer.SubnetMask().String() != "ffffff00" || !offer.Router()[0].Equal(net.IPv4(192, 168, 0, 254)) ||
+		!offer.DNS()[0].Equal(net.IPv4(192, 168, 0, 53)) || offer.DomainName() != "example.com" {
+		t.Errorf("offer options: %s", offer.Summary())
+	}
+	if offer.BootFileName != "pxelinux.0" || !offer.ServerIPAddr.Equal(net.IPv4(192, 168, 0, 2)) {
+		t.Errorf("boot file %q from %s, want pxelinux.0 from 192.168.0.2", offer.BootFileName, offer.ServerIPAddr)
+	}
+	if len(s.Leases()) != 0 {
+		t.Errorf("offers are leases: %v", s.Leases())
+	}
+
+	// Another client does not get the address offered to the first.
+	offer1 := s.mustReply4(t, 0, discover(t, mac1, dhcpv4.WithOption(dhcpv4.OptClientArch(iana.EFI_X86_64))), dhcpv4.MessageTypeOffer)
+	if got := offer1.YourIPAddr.String(); got != "192.168.0.3" {
+		t.Errorf("offered %s to the second client, want 192.168.0.3", got)
+	}
+	if offer1.BootFileName != "bootx64.efi" || !offer1.ServerIPAddr.Equal(net.IPv4(192, 168, 0, 1)) {
+		t.Errorf("boot file %q from %s, want bootx64.efi from the server", offer1.BootFileName, offer1.ServerIPAddr)
+	}
+	// The pool is exhausted.
+	if r, err := s.reply4(s.ifaces[0], discover(t, net.HardwareAddr{0x52, 0x54, 0, 0, 0, 0x99})); err == nil {
+		t.Errorf("discover on an exhausted pool = %v", r)
+	}
+
+	ack := s.mustReply4(t, 0, request(t, offer), dhcpv4.MessageTypeAck)
+	if !ack.YourIPAddr.Equal(offer.YourIPAddr) || ack.HostName() != "box" {
+		t.Errorf("ack = %s", ack.Summary())
+	}
+	ls, err := ReadLeases(filepath.Join(dir, "leases"))
+	if err != nil || len(ls) != 1 || ls[0].Addr.String() != "192.168.0.2" || ls[0].MAC != mac0.String() ||
+		ls[0].Hostname != "box" || !ls[0].Expires.Equal(now.Add(time.Hour)) {
+		t.Fatalf("lease file = %+v, %v", ls, err)
+	}
+
+	// The second client takes the offer of another server.
+	s.mustReply4(t, 0, discover(t, mac1), dhcpv4.MessageTypeOffer)
+	if r, _ := s.reply4(s.ifaces[0], request(t, offer1, dhcpv4.WithOption(dhcpv4.OptServerIdentifier(net.IPv4(192, 168, 0, 99))))); r != nil {
+		t.Errorf("reply to a request for another server = %s", r.Summary())
+	}
+	if o := s.mustReply4(t, 0, discover(t, mac2), dhcpv4.MessageTypeOffer); o.YourIPAddr.String() != "192.168.0.10" || o.HostName() != "node3" {
+		t.Errorf("reservation offer = %s", o.Summary())
+	}
+
+	// A restarted server remembers the lease, and renews it.
+	now = now.Add(40 * time.Minute)
+	s = newTestServer(t, dir, &now)
+	renew, err := dhcpv4.NewRenewFromAck(ack)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ack = s.mustReply4(t, 0, renew, dhcpv4.MessageTypeAck)
+	if ack.YourIPAddr.String() != "192.168.0.2" {
+		t.Errorf("renewal = %s", ack.Summary())
+	}
+	if to := replyAddr4(renew, ack); to.String() != "192.168.0.2:68" {
+		t.Errorf("renewal goes to %s, want 192.168.0.2:68", to)
+	}
+	// Another client asking for the address after a reboot is refused.
+	req, _ := dhcpv4.New(dhcpv4.WithHwAddr(mac1), dhcpv4.WithMessageType(dhcpv4.MessageTypeRequest),
+		dhcpv4.WithOption(dhcpv4.OptRequestedIPAddress(net.IPv4(192, 168, 0, 2))))
+	nak := s.mustReply4(t, 0, req, dhcpv4.MessageTypeNak)
+	if to := replyAddr4(req, nak); to.String() != "255.255.255.255:68" {
+		t.Errorf("NAK goes to %s", to)
+	}
+
+	// Release and decline.
+	rel, _ := dhcpv4.NewReleaseFromACK(ack)
+	if r, err := s.reply4(s.ifaces[0], rel); r != nil || err != nil {
+		t.Errorf("reply to release = %v, %v", r, err)
+	}
+	if ls := s.Leases(); len(ls) != 0 {
+		t.Errorf("leases after release = %+v", ls)
+	}
+	offer = s.mustReply4(t, 0, discover(t, mac1), dhcpv4.MessageTypeOffer)
+	ack = s.mustReply4(t, 0, request(t, offer), dhcpv4.MessageTypeAck)
+	dec, _ := dhcpv4.New(dhcpv4.WithHwAddr(mac1), dhcpv4.WithMessageType(dhcpv4.MessageTypeDecline),
+		dhcpv4.WithOption(dhcpv4.OptRequestedIPAddress(ack.YourIPAddr)))
+	s.reply4(s.ifaces[0], dec)
+	if ls := s.Leases(); len(ls) != 1 || ls[0].Client != "" || !ls[0].Addr.Is4() {
+		t.Errorf("leases after decline = %+v", ls)
+	}
+	if o := s.mustReply4(t, 0, discover(t, mac1), dhcpv4.MessageTypeOffer); o.YourIPAddr.Equal(ack.YourIPAddr) {
+		t.Errorf("declined address %s offered again", o.YourIPAddr)
+	}
+
+	// Expired leases are free again.
+	now = now.Add(2 * time.Hour)
+	if ls := s.Leases(); len(ls) != 0 {
+		t.Errorf("leases after they expired = %+v", ls)
+	}
+}
+
+func TestDHCPv4Relay(t *testing.T) {
+	now := time.Now()
+	s := newTestServer(t, t.TempDir(), &now)
+	m := discover(t, mac0, dhcpv4.WithGatewayIP(net.IPv4(10, 0, 0, 254)),
+		dhcpv4.WithOption(dhcpv4.OptClientIdentifier([]byte{1, 2, 3})),
+		dhcpv4.WithOption(dhcpv4.OptClassIdentifier("HTTPClient:Arch:00016")),
+		dhcpv4.WithOption(dhcpv4.OptClientArch(iana.EFI_X86_64_HTTP)))
+	offer := s.mustReply4(t, 0, m, dhcpv4.MessageTypeOffer)
+	if offer.YourIPAddr.String() != "10.0.0.100" || !offer.ServerIdentifier().Equal(net.IPv4(192, 168, 0, 1)) {
+		t.Errorf("relayed offer = %s", offer.Summary())
+	}
+	if to := replyAddr4(m, offer); to.String() != "10.0.0.254:67" {
+		t.Errorf("relayed offer goes to %s", to)
+	}
+	if got := offer.Options.Get(dhcpv4.OptionClientIdentifier); string(got) != "\x01\x02\x03" {
+		t.Errorf("client identifier %x not echoed", got)
+	}
+	// eth1 has no boot files.
+	if offer.BootFileName != "" {
+		t.Errorf("boot file %q", offer.BootFileName)
+	}
+	s.mustReply4(t, 0, request(t, offer, dhcpv4.WithGatewayIP(net.IPv4(10, 0, 0, 254)),
+		dhcpv4.WithOption(dhcpv4.OptClientIdentifier([]byte{1, 2, 3}))), dhcpv4.MessageTypeAck)
+	if ls := s.Leases(); len(ls) != 1 || ls[0].Interface != "eth1" || ls[0].Client != "010203" {
+		t.Errorf("leases = %+v", ls)
+	}
+
+	m = discover(t, mac0,
+		dhcpv4.WithOption(dhcpv4.OptClassIdentifier("HTTPClient:Arch:00016")),
+		dhcpv4.WithOption(dhcpv4.OptClientArch(iana.EFI_X86_64_HTTP)))
+	offer = s.mustReply4(t, 0, m, dhcpv4.MessageTypeOffer)
+	if offer.BootFileName != "http://192.168.0.1/bootx64.efi" || offer.ClassIdentifier() != "HTTPClient" {
+		t.Errorf("HTTP boot offer = %s", offer.Summary())
+	}
+
+	if _, err := s.reply4(s.ifaces[0], discover(t, mac0, dhcpv4.WithGatewayIP(net.IPv4(172, 16, 0, 1)))); err == nil {
+		t.Errorf("discover from a relay without a pool succeeded")
+	}
+}
+
+func TestNextPrefix(t *testing.T) {
+	for _, tt := range []struct{ in, want string }{
+		{"fd00::/64", "fd00:0:0:1::/64"},
+		{"fd00:0:0:ff00::/56", "fd00:0:1::/56"},
+		{"fd00:ffff:ffff:ffff::/64", "fd01::/64"},
+		{"10.0.0.252/30", "10.0.1.0/30"},
+	} {
+		if got := nextPrefix(netip.MustParsePrefix(tt.in)); got.String() != tt.want {
+			t.Errorf("nextPrefix(%s) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+	if got := nextPrefix(netip.MustParsePrefix("ffff:ffff:ffff:ffff:ffff:ffff:ffff:ff00/120")); got.IsValid() {
+		t.Errorf("nextPrefix of the last prefix = %s", got)
+	}
+}
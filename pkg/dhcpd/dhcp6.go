@@ -0,0 +1,360 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dhcpd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"slices"
+	"time"
+
+	"github.com/insomniacslk/dhcp/dhcpv6"
+	"github.com/insomniacslk/dhcp/iana"
+	"github.com/insomniacslk/dhcp/rfc1035label"
+)
+
+// handle6 answers the DHCPv6 message d received on ifc, relayed or not.
+func (s *Server) handle6(ifc *iface, conn net.PacketConn, peer net.Addr, d dhcpv6.DHCPv6) {
+	msg, err := d.GetInnerMessage()
+	if err != nil {
+		s.Logf("dhcpd: %s: %v", ifc.Name, err)
+		return
+	}
+	pi := ifc
+	if relay, ok := d.(*dhcpv6.RelayMessage); ok {
+		if pi = s.pool6(relay); pi == nil {
+			s.Logf("dhcpd: %s: %s: no pool for relay %s", ifc.Name, msg.Type(), relay.LinkAddr)
+			return
+		}
+	}
+	var mac string
+	if hw, err := dhcpv6.ExtractMAC(d); err == nil {
+		mac = hw.String()
+	}
+	reply, err := s.reply6(ifc, pi, msg, mac)
+	if err != nil {
+		s.Logf("dhcpd: %s: %s from %s: %v", ifc.Name, msg.Type(), peer, err)
+		return
+	}
+	if reply == nil {
+		return
+	}
+	out := dhcpv6.DHCPv6(reply)
+	if relay, ok := d.(*dhcpv6.RelayMessage); ok {
+		if out, err = dhcpv6.NewRelayReplFromRelayForw(relay, reply); err != nil {
+			s.Logf("dhcpd: %s: %v", ifc.Name, err)
+			return
+		}
+	}
+	if _, err := conn.WriteTo(out.ToBytes(), peer); err != nil {
+		s.Logf("dhcpd: %s: sending %s to %s: %v", ifc.Name, reply.Type(), peer, err)
+		return
+	}
+	s.Logf("dhcpd: %s: %s to %s", ifc.Name, reply.Type(), peer)
+}
+
+// pool6 returns the interface whose pool holds the link address of the
+// relay agent closest to the client.
+func (s *Server) pool6(relay *dhcpv6.RelayMessage) *iface {
+	inner, err := dhcpv6.DecapsulateRelayIndex(relay, -1)
+	if err != nil {
+		return nil
+	}
+	link, ok := netip.AddrFromSlice(inner.(*dhcpv6.RelayMessage).LinkAddr)
+	if !ok || link.IsUnspecified() {
+		return nil
+	}
+	for _, i := range s.ifaces {
+		if i.DHCP6 != nil && i.DHCP6.Subnet.IsValid() && i.DHCP6.Subnet.Contains(link) {
+			return i
+		}
+	}
+	return nil
+}
+
+// reply6 handles msg from the client with mac, and returns the reply to
+// send, if any.
+func (s *Server) reply6(ifc, pi *iface, msg *dhcpv6.Message, mac string) (*dhcpv6.Message, error) {
+	p := pi.DHCP6
+	var duid string
+	if id := msg.Options.ClientID(); id != nil {
+		duid = hexString(id.ToBytes())
+	} else if msg.Type() != dhcpv6.MessageTypeInformationRequest {
+		return nil, errors.New("no client ID")
+	}
+	// RFC 8415, Section 16: messages for other servers are discarded, as
+	// are those that must not name one and do.
+	sid := msg.Options.ServerID()
+	switch msg.Type() {
+	case dhcpv6.MessageTypeSolicit, dhcpv6.MessageTypeConfirm, dhcpv6.MessageTypeRebind:
+		if sid != nil {
+			return nil, nil
+		}
+	case dhcpv6.MessageTypeRequest, dhcpv6.MessageTypeRenew, dhcpv6.MessageTypeRelease, dhcpv6.MessageTypeDecline:
+		if sid == nil || !bytes.Equal(sid.ToBytes(), ifc.duid.ToBytes()) {
+			return nil, nil
+		}
+	case dhcpv6.MessageTypeInformationRequest:
+		if sid != nil && !bytes.Equal(sid.ToBytes(), ifc.duid.ToBytes()) {
+			return nil, nil
+		}
+	default:
+		return nil, fmt.Errorf("unexpected message type %s", msg.Type())
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	s.db.expire(now)
+
+	var reply *dhcpv6.Message
+	var err error
+	switch msg.Type() {
+	case dhcpv6.MessageTypeSolicit:
+		// RFC 8415, Section 18.3.1: a client asking for rapid commit
+		// gets its leases at once.
+		if msg.GetOneOption(dhcpv6.OptionRapidCommit) != nil {
+			reply, err = dhcpv6.NewReplyFromMessage(msg)
+		} else {
+			reply, err = dhcpv6.NewAdvertiseFromSolicit(msg)
+		}
+		if err != nil {
+			return nil, err
+		}
+		s.assign6(pi, msg, reply, duid, mac, now, reply.Type() == dhcpv6.MessageTypeReply)
+
+	case dhcpv6.MessageTypeRequest, dhcpv6.MessageTypeRenew, dhcpv6.MessageTypeRebind:
+		if reply, err = dhcpv6.NewReplyFromMessage(msg); err != nil {
+			return nil, err
+		}
+		s.assign6(pi, msg, reply, duid, mac, now, true)
+
+	case dhcpv6.MessageTypeRelease, dhcpv6.MessageTypeDecline:
+		if reply, err = dhcpv6.NewReplyFromMessage(msg); err != nil {
+			return nil, err
+		}
+		s.release6(pi, msg, duid, now, msg.Type() == dhcpv6.MessageTypeDecline)
+		reply.AddOption(&dhcpv6.OptStatusCode{StatusCode: iana.StatusSuccess})
+
+	case dhcpv6.MessageTypeConfirm:
+		// RFC 8415, Section 18.3.3: the client asks whether its
+		// addresses are still on the link.
+		var addrs []netip.Addr
+		for _, ia := range msg.Options.IANA() {
+			for _, a := range ia.Options.Addresses() {
+				addrs = append(addrs, toAddr(a.IPv6Addr))
+			}
+		}
+		if len(addrs) == 0 || !p.Subnet.IsValid() {
+			return nil, nil
+		}
+		if reply, err = dhcpv6.NewReplyFromMessage(msg); err != nil {
+			return nil, err
+		}
+		code := iana.StatusSuccess
+		if slices.ContainsFunc(addrs, func(a netip.Addr) bool { return !p.Subnet.Contains(a) }) {
+			code = iana.StatusNotOnLink
+		}
+		reply.AddOption(&dhcpv6.OptStatusCode{StatusCode: code})
+
+	case dhcpv6.MessageTypeInformationRequest:
+		if reply, err = dhcpv6.NewReplyFromMessage(msg); err != nil {
+			return nil, err
+		}
+	}
+	addOptions6(ifc, pi, msg, reply)
+	return reply, nil
+}
+
+// assign6 adds to reply an address for each IA_NA of msg and a prefix for
+// each IA_PD, and binds them to the client if bind is set or offers them
+// otherwise.
+func (s *Server) assign6(pi *iface, msg, reply *dhcpv6.Message, duid, mac string, now time.Time, bind bool) {
+	p := pi.DHCP6
+	lt := time.Duration(p.LeaseTime)
+	expires := now.Add(lt)
+	if !bind {
+		expires = now.Add(offerHold)
+	}
+	record := func(l Lease) {
+		if cur := s.db.find(l.Type, pi.Name, l.Client); !bind && cur != nil && cur.Addr == l.Addr && cur.Prefix == l.Prefix {
+			// Keep the binding the client already has.
+			return
+		}
+		l.Interface, l.MAC, l.Expires, l.offered = pi.Name, mac, expires, !bind
+		s.db.put(l)
+	}
+
+	for _, ia := range msg.Options.IANA() {
+		client := duid + "/" + hexString(ia.IaId[:])
+		var want netip.Addr
+		if a := ia.Options.OneAddress(); a != nil {
+			want = toAddr(a.IPv6Addr)
+		}
+		out := &dhcpv6.OptIANA{IaId: ia.IaId}
+		addr, err := s.leaseNA(pi, client, duid, mac, want)
+		if err != nil {
+			out.Options.Add(&dhcpv6.OptStatusCode{StatusCode: iana.StatusNoAddrsAvail, StatusMessage: err.Error()})
+		} else {
+			record(Lease{Type: LeaseNA, Client: client, Addr: addr})
+			out.T1, out.T2 = lt/2, lt*4/5
+			out.Options.Add(&dhcpv6.OptIAAddress{IPv6Addr: addr.AsSlice(), PreferredLifetime: lt, ValidLifetime: lt})
+			if want.IsValid() && want != addr {
+				// RFC 8415, Section 18.3.4: an address the client
+				// may no longer use has lifetimes of 0.
+				out.Options.Add(&dhcpv6.OptIAAddress{IPv6Addr: want.AsSlice()})
+			}
+		}
+		reply.AddOption(out)
+	}
+
+	for _, ia := range msg.Options.IAPD() {
+		client := duid + "/" + hexString(ia.IaId[:])
+		var want netip.Prefix
+		if ps := ia.Options.Prefixes(); len(ps) > 0 && ps[0].Prefix != nil {
+			want = toPrefix(ps[0].Prefix)
+		}
+		out := &dhcpv6.OptIAPD{IaId: ia.IaId}
+		prefix, err := s.leasePD(pi, client, duid, mac, want)
+		if err != nil {
+			out.Options.Add(&dhcpv6.OptStatusCode{StatusCode: iana.StatusNoPrefixAvail, StatusMessage: err.Error()})
+		} else {
+			record(Lease{Type: LeasePD, Client: client, Prefix: prefix})
+			out.T1, out.T2 = lt/2, lt*4/5
+			out.Options.Add(&dhcpv6.OptIAPrefix{Prefix: toIPNet(prefix), PreferredLifetime: lt, ValidLifetime: lt})
+			if want.IsValid() && want.Bits() > 0 && want != prefix {
+				out.Options.Add(&dhcpv6.OptIAPrefix{Prefix: toIPNet(want)})
+			}
+		}
+		reply.AddOption(out)
+	}
+
+	if bind {
+		if err := s.db.save(); err != nil {
+			s.Logf("dhcpd: saving leases: %v", err)
+		}
+	}
+}
+
+// release6 removes the leases of the IAs of msg. Declined addresses are
+// kept out of the pool for a lease time.
+func (s *Server) release6(pi *iface, msg *dhcpv6.Message, duid string, now time.Time, decline bool) {
+	for _, ia := range msg.Options.IANA() {
+		client := duid + "/" + hexString(ia.IaId[:])
+		if l := s.db.find(LeaseNA, pi.Name, client); l != nil {
+			s.db.removeLease(l)
+			if decline {
+				s.Logf("dhcpd: %s: %s declined %s, which is in use", pi.Name, client, l.Addr)
+				s.db.put(Lease{Type: LeaseNA, Interface: pi.Name, Addr: l.Addr, Expires: now.Add(time.Duration(pi.DHCP6.LeaseTime))})
+			}
+		}
+	}
+	for _, ia := range msg.Options.IAPD() {
+		s.db.remove(LeasePD, pi.Name, duid+"/"+hexString(ia.IaId[:]))
+	}
+	if err := s.db.save(); err != nil {
+		s.Logf("dhcpd: saving leases: %v", err)
+	}
+}
+
+// leaseNA returns the address for an IA_NA of client, whose DUID is duid
+// and MAC is mac, preferring its current lease and then want.
+func (s *Server) leaseNA(pi *iface, client, duid, mac string, want netip.Addr) (netip.Addr, error) {
+	p := pi.DHCP6
+	if r := reservation(p.Reservations, mac, duid); r != nil && r.IP.IsValid() {
+		return r.IP, nil
+	}
+	if !p.Start.IsValid() {
+		return netip.Addr{}, errors.New("no addresses to hand out")
+	}
+	var cur netip.Addr
+	if l := s.db.find(LeaseNA, pi.Name, client); l != nil {
+		cur = l.Addr
+	}
+	free := func(a netip.Addr) bool {
+		if reserved(p.Reservations, a, netip.Prefix{}, mac, duid) {
+			return false
+		}
+		l := s.db.owner(a, netip.Prefix{})
+		return l == nil || (l.Interface == pi.Name && l.Client == client)
+	}
+	return pick(p.Start, p.End, free, cur, want)
+}
+
+// leasePD returns the prefix for an IA_PD of client, whose DUID is duid
+// and MAC is mac, preferring its current lease and then want.
+func (s *Server) leasePD(pi *iface, client, duid, mac string, want netip.Prefix) (netip.Prefix, error) {
+	p := pi.DHCP6
+	if r := reservation(p.Reservations, mac, duid); r != nil && r.Prefix.IsValid() {
+		return r.Prefix, nil
+	}
+	if !p.Prefixes.IsValid() {
+		return netip.Prefix{}, errors.New("no prefixes to delegate")
+	}
+	var cur netip.Prefix
+	if l := s.db.find(LeasePD, pi.Name, client); l != nil {
+		cur = l.Prefix
+	}
+	inPool := func(q netip.Prefix) bool {
+		return q.IsValid() && q.Bits() == p.PrefixLength && q == q.Masked() && p.Prefixes.Contains(q.Addr())
+	}
+	free := func(q netip.Prefix) bool {
+		if reserved(p.Reservations, netip.Addr{}, q, mac, duid) {
+			return false
+		}
+		l := s.db.owner(netip.Addr{}, q)
+		return l == nil || (l.Interface == pi.Name && l.Client == client)
+	}
+	for _, q := range []netip.Prefix{cur, want} {
+		if inPool(q) && free(q) {
+			return q, nil
+		}
+	}
+	for q := netip.PrefixFrom(p.Prefixes.Addr(), p.PrefixLength); inPool(q); q = nextPrefix(q) {
+		if free(q) {
+			return q, nil
+		}
+	}
+	return netip.Prefix{}, errExhausted
+}
+
+// addOptions6 adds the server ID and the options of the pool of pi that
+// msg asks for to reply.
+func addOptions6(ifc, pi *iface, msg, reply *dhcpv6.Message) {
+	p := pi.DHCP6
+	reply.AddOption(dhcpv6.OptServerID(ifc.duid))
+	if len(p.DNS) > 0 {
+		reply.AddOption(dhcpv6.OptDNS(toIPs(p.DNS)...))
+	}
+	if len(p.Domains) > 0 {
+		reply.AddOption(dhcpv6.OptDomainSearchList(&rfc1035label.Labels{Labels: p.Domains}))
+	}
+	if b := pi.boot(msg.Options.ArchTypes()); b != nil && b.URL != "" {
+		reply.AddOption(dhcpv6.OptBootFileURL(b.URL))
+		for _, vc := range msg.Options.VendorClasses() {
+			if len(vc.Data) > 0 && isHTTPClient(string(vc.Data[0])) {
+				reply.AddOption(&dhcpv6.OptVendorClass{EnterpriseNumber: vc.EnterpriseNumber, Data: [][]byte{[]byte(httpClient)}})
+				break
+			}
+		}
+	}
+}
+
+// toPrefix returns n as a netip.Prefix, or an invalid prefix.
+func toPrefix(n *net.IPNet) netip.Prefix {
+	a, ok := netip.AddrFromSlice(n.IP)
+	if !ok {
+		return netip.Prefix{}
+	}
+	ones, _ := n.Mask.Size()
+	return netip.PrefixFrom(a.Unmap(), ones)
+}
+
+func toIPNet(p netip.Prefix) *net.IPNet {
+	return &net.IPNet{IP: p.Addr().AsSlice(), Mask: net.CIDRMask(p.Bits(), p.Addr().BitLen())}
+}
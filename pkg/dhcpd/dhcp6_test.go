@@ -0,0 +1,206 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dhcpd
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/insomniacslk/dhcp/dhcpv6"
+	"github.com/insomniacslk/dhcp/iana"
+)
+
+func solicit(t *testing.T, mac net.HardwareAddr, mods ...dhcpv6.Modifier) *dhcpv6.Message {
+	t.Helper()
+	mods = append([]dhcpv6.Modifier{
+		dhcpv6.WithClientID(&dhcpv6.DUIDLL{HWType: iana.HWTypeEthernet, LinkLayerAddr: mac}),
+		dhcpv6.WithIAPD([4]byte{0, 0, 0, 1}),
+	}, mods...)
+	m, err := dhcpv6.NewSolicit(mac, mods...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func (s *Server) mustReply6(t *testing.T, ifc int, m *dhcpv6.Message, want dhcpv6.MessageType) *dhcpv6.Message {
+	t.Helper()
+	r, err := s.reply6(s.ifaces[ifc], s.ifaces[ifc], m, "")
+	if err != nil {
+		t.Fatalf("reply to %s = %v", m.Type(), err)
+	}
+	if r == nil || r.Type() != want {
+		t.Fatalf("reply to %s = %v, want a %s", m.Type(), r, want)
+	}
+	return r
+}
+
+// leases6 returns the address and prefix in the IAs of m.
+func leases6(t *testing.T, m *dhcpv6.Message) (string, string) {
+	t.Helper()
+	na, pd := m.Options.OneIANA(), m.Options.OneIAPD()
+	if na == nil || pd == nil {
+		t.Fatalf("no IA_NA or IA_PD in %s", m.LongString(0))
+	}
+	var addr, prefix string
+	if a := na.Options.OneAddress(); a != nil {
+		addr = a.IPv6Addr.String()
+	}
+	if ps := pd.Options.Prefixes(); len(ps) > 0 {
+		prefix = ps[0].Prefix.String()
+	}
+	return addr, prefix
+}
+
+func TestDHCPv6(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := newTestServer(t, dir, &now)
+
+	adv := s.mustReply6(t, 0, solicit(t, mac0), dhcpv6.MessageTypeAdvertise)
+	if addr, prefix := leases6(t, adv); addr != "fd00::1" || prefix != "fd00:100::/64" {
+		t.Errorf("advertised %s and %s, want fd00::1 and fd00:100::/64", addr, prefix)
+	}
+	if dns := adv.Options.DNS(); len(dns) != 1 || dns[0].String() != "fd00::53" {
+		t.Errorf("DNS = %v", dns)
+	}
+	if adv.Options.ServerID() == nil {
+		t.Errorf("no server ID in %s", adv.Summary())
+	}
+	if len(s.Leases()) != 0 {
+		t.Errorf("advertisements are leases: %v", s.Leases())
+	}
+
+	// Another client gets the next address and prefix.
+	adv1 := s.mustReply6(t, 0, solicit(t, mac1), dhcpv6.MessageTypeAdvertise)
+	if addr, prefix := leases6(t, adv1); addr != "fd00::2" || prefix != "fd00:100:0:1::/64" {
+		t.Errorf("advertised %s and %s to the second client", addr, prefix)
+	}
+
+	req, err := dhcpv6.NewRequestFromAdvertise(adv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reply := s.mustReply6(t, 0, req, dhcpv6.MessageTypeReply)
+	if addr, prefix := leases6(t, reply); addr != "fd00::1" || prefix != "fd00:100::/64" {
+		t.Errorf("replied %s and %s", addr, prefix)
+	}
+	if na := reply.Options.OneIANA(); na.T1 != 6*time.Hour || na.Options.OneAddress().ValidLifetime != DefaultLeaseTime {
+		t.Errorf("IA_NA = %s", na)
+	}
+	ls, err := ReadLeases(s.c.LeaseFile)
+	if err != nil || len(ls) != 2 {
+		t.Fatalf("lease file = %+v, %v", ls, err)
+	}
+
+	// A request for another server is ignored.
+	req.UpdateOption(dhcpv6.OptServerID(&dhcpv6.DUIDLL{HWType: iana.HWTypeEthernet, LinkLayerAddr: mac2}))
+	if r, _ := s.reply6(s.ifaces[0], s.ifaces[0], req, ""); r != nil {
+		t.Errorf("reply to a request for another server = %s", r.Summary())
+	}
+
+	// Rapid commit with a reservation.
+	reply = s.mustReply6(t, 0, solicit(t, mac2, dhcpv6.WithRapidCommit), dhcpv6.MessageTypeReply)
+	if addr, prefix := leases6(t, reply); addr != "fd00::10" || prefix != "fd00:200::/56" {
+		t.Errorf("reserved %s and %s, want fd00::10 and fd00:200::/56", addr, prefix)
+	}
+	if len(s.Leases()) != 4 {
+		t.Errorf("leases = %+v", s.Leases())
+	}
+
+	// Confirm.
+	conf, _ := dhcpv6.NewMessage(dhcpv6.WithClientID(&dhcpv6.DUIDLL{HWType: iana.HWTypeEthernet, LinkLayerAddr: mac0}),
+		dhcpv6.WithIANA(dhcpv6.OptIAAddress{IPv6Addr: net.ParseIP("fd00::1")}))
+	conf.MessageType = dhcpv6.MessageTypeConfirm
+	if r := s.mustReply6(t, 0, conf, dhcpv6.MessageTypeReply); r.Options.Status().StatusCode != iana.StatusSuccess {
+		t.Errorf("confirm of an address on the link = %s", r.Options.Status())
+	}
+	conf.Options.Del(dhcpv6.OptionIANA)
+	conf.AddOption(&dhcpv6.OptIANA{Options: dhcpv6.IdentityOptions{Options: dhcpv6.Options{&dhcpv6.OptIAAddress{IPv6Addr: net.ParseIP("fd02::1")}}}})
+	if r := s.mustReply6(t, 0, conf, dhcpv6.MessageTypeReply); r.Options.Status().StatusCode != iana.StatusNotOnLink {
+		t.Errorf("confirm of an address off the link = %s", r.Options.Status())
+	}
+
+	// A restarted server renews the lease, and releases it.
+	now = now.Add(time.Hour)
+	s = newTestServer(t, dir, &now)
+	renew, _ := dhcpv6.NewMessage()
+	renew.MessageType = dhcpv6.MessageTypeRenew
+	for _, o := range []dhcpv6.OptionCode{dhcpv6.OptionClientID, dhcpv6.OptionServerID, dhcpv6.OptionIANA, dhcpv6.OptionIAPD} {
+		renew.AddOption(adv.GetOneOption(o))
+	}
+	if addr, prefix := leases6(t, s.mustReply6(t, 0, renew, dhcpv6.MessageTypeReply)); addr != "fd00::1" || prefix != "fd00:100::/64" {
+		t.Errorf("renewed %s and %s", addr, prefix)
+	}
+	rel := renew
+	rel.MessageType = dhcpv6.MessageTypeRelease
+	if r := s.mustReply6(t, 0, rel, dhcpv6.MessageTypeReply); r.Options.Status().StatusCode != iana.StatusSuccess {
+		t.Errorf("release = %s", r.Summary())
+	}
+	for _, l := range s.Leases() {
+		if l.Addr.String() == "fd00::1" || l.Prefix.String() == "fd00:100::/64" {
+			t.Errorf("lease %+v after release", l)
+		}
+	}
+}
+
+// packetConn records what is written to it.
+type packetConn struct {
+	net.PacketConn
+	b  []byte
+	to net.Addr
+}
+
+func (c *packetConn) WriteTo(b []byte, to net.Addr) (int, error) {
+	c.b, c.to = b, to
+	return len(b), nil
+}
+
+func TestDHCPv6Relay(t *testing.T) {
+	now := time.Now()
+	s := newTestServer(t, t.TempDir(), &now)
+	sol := solicit(t, mac0,
+		dhcpv6.WithArchType(iana.EFI_X86_64_HTTP),
+		dhcpv6.WithOption(&dhcpv6.OptVendorClass{EnterpriseNumber: 343, Data: [][]byte{[]byte("HTTPClient:Arch:00016")}}))
+	relayed, err := dhcpv6.EncapsulateRelay(sol, dhcpv6.MessageTypeRelayForward, net.ParseIP("fd01::1"), net.ParseIP("fe80::1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	relayed.AddOption(dhcpv6.OptInterfaceID([]byte("port7")))
+	var c packetConn
+	peer := &net.UDPAddr{IP: net.ParseIP("fd01::1"), Port: dhcpv6.DefaultServerPort}
+	s.handle6(s.ifaces[0], &c, peer, relayed)
+	if c.to != peer {
+		t.Fatalf("reply went to %v, want %v", c.to, peer)
+	}
+	d, err := dhcpv6.FromBytes(c.b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr, ok := d.(*dhcpv6.RelayMessage)
+	if !ok || rr.Type() != dhcpv6.MessageTypeRelayReply || string(rr.Options.InterfaceID()) != "port7" {
+		t.Fatalf("reply = %s", d.Summary())
+	}
+	adv, err := rr.GetInnerMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// eth1 only delegates prefixes, and has no boot files.
+	if st := adv.Options.OneIANA().Options.Status(); st == nil || st.StatusCode != iana.StatusNoAddrsAvail {
+		t.Errorf("IA_NA status = %v", st)
+	}
+	if _, prefix := leases6(t, adv); prefix != "fd01:100::/56" {
+		t.Errorf("delegated %s, want fd01:100::/56", prefix)
+	}
+	if adv.Options.BootFileURL() != "" {
+		t.Errorf("boot file URL %q", adv.Options.BootFileURL())
+	}
+
+	adv = s.mustReply6(t, 0, sol, dhcpv6.MessageTypeAdvertise)
+	if adv.Options.BootFileURL() != "http://[fd00::1]/bootx64.efi" || len(adv.Options.VendorClass(343)) != 1 {
+		t.Errorf("HTTP boot advertisement = %s", adv.LongString(0))
+	}
+}
@@ -0,0 +1,193 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dhcpd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// LeaseType is what a lease is of.
+type LeaseType string
+
+// Lease types.
+const (
+	LeaseIPv4 LeaseType = "ipv4"
+	LeaseNA   LeaseType = "ia_na"
+	LeasePD   LeaseType = "ia_pd"
+)
+
+// Lease is an address or prefix bound to a client.
+type Lease struct {
+	Type      LeaseType `json:"type"`
+	Interface string    `json:"interface"`
+
+	// Client identifies the client: the hex of the client identifier
+	// option or the MAC address for DHCPv4, the hex of the DUID and
+	// IAID for DHCPv6. A lease without a Client is of an address that
+	// a client declined because it is in use.
+	Client string `json:"client,omitempty"`
+
+	// MAC is the MAC address of the client, if known.
+	MAC string `json:"mac,omitempty"`
+
+	// Addr is the address of an IPv4 or IA_NA lease, Prefix the prefix
+	// of an IA_PD lease.
+	Addr   netip.Addr   `json:"addr,omitzero"`
+	Prefix netip.Prefix `json:"prefix,omitzero"`
+
+	Hostname string    `json:"hostname,omitempty"`
+	Expires  time.Time `json:"expires"`
+
+	// offered is set until the client requests the lease; offers are
+	// not saved.
+	offered bool
+}
+
+// ReadLeases reads the leases in the lease file at path.
+func ReadLeases(path string) ([]Lease, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var ls []Lease
+	if err := json.Unmarshal(b, &ls); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return ls, nil
+}
+
+// leaseDB holds the leases of a server. Its users hold Server.mu.
+type leaseDB struct {
+	path   string
+	leases []*Lease
+}
+
+// openLeases reads the unexpired leases of the lease file at path. A
+// missing file has no leases.
+func openLeases(path string, now time.Time) (*leaseDB, error) {
+	db := &leaseDB{path: path}
+	if path == "" {
+		return db, nil
+	}
+	ls, err := ReadLeases(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return db, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, l := range ls {
+		if l.Expires.After(now) {
+			db.leases = append(db.leases, &l)
+		}
+	}
+	return db, nil
+}
+
+// save writes the leases that are not offers to the lease file. The file
+// is replaced atomically so a crash leaves either the old or new leases.
+func (db *leaseDB) save() error {
+	if db.path == "" {
+		return nil
+	}
+	ls := []Lease{}
+	for _, l := range db.leases {
+		if !l.offered {
+			ls = append(ls, *l)
+		}
+	}
+	b, err := json.MarshalIndent(ls, "", "\t")
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(db.path), filepath.Base(db.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), db.path)
+}
+
+// expire drops the leases that expired before now.
+func (db *leaseDB) expire(now time.Time) {
+	ls := db.leases[:0]
+	for _, l := range db.leases {
+		if l.Expires.After(now) {
+			ls = append(ls, l)
+		}
+	}
+	clear(db.leases[len(ls):])
+	db.leases = ls
+}
+
+// find returns the lease of type t of client on ifname, or nil.
+func (db *leaseDB) find(t LeaseType, ifname, client string) *Lease {
+	for _, l := range db.leases {
+		if l.Type == t && l.Interface == ifname && l.Client == client {
+			return l
+		}
+	}
+	return nil
+}
+
+// owner returns the lease of addr, or of a prefix overlapping p, or nil.
+func (db *leaseDB) owner(addr netip.Addr, p netip.Prefix) *Lease {
+	for _, l := range db.leases {
+		if addr.IsValid() && l.Addr == addr {
+			return l
+		}
+		if p.IsValid() && l.Prefix.IsValid() && l.Prefix.Overlaps(p) {
+			return l
+		}
+	}
+	return nil
+}
+
+// put adds l, replacing the lease of the same client and any other lease
+// of its address or prefix, and returns it.
+func (db *leaseDB) put(l Lease) *Lease {
+	if l.Client != "" {
+		db.remove(l.Type, l.Interface, l.Client)
+	}
+	if o := db.owner(l.Addr, l.Prefix); o != nil {
+		db.removeLease(o)
+	}
+	db.leases = append(db.leases, &l)
+	return &l
+}
+
+// remove removes the lease of type t of client on ifname.
+func (db *leaseDB) remove(t LeaseType, ifname, client string) {
+	if l := db.find(t, ifname, client); l != nil {
+		db.removeLease(l)
+	}
+}
+
+func (db *leaseDB) removeLease(l *Lease) {
+	for i, o := range db.leases {
+		if o == l {
+			db.leases = append(db.leases[:i], db.leases[i+1:]...)
+			return
+		}
+	}
+}
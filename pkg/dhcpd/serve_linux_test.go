@@ -0,0 +1,100 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dhcpd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hugelgupf/vmtest/guest"
+	"github.com/u-root/u-root/pkg/dhclient"
+	"github.com/vishvananda/netlink"
+)
+
+// TestServe serves leases to pkg/dhclient over a veth pair.
+func TestServe(t *testing.T) {
+	guest.SkipIfNotInVM(t)
+
+	la := netlink.NewLinkAttrs()
+	la.Name = "dhcpd0"
+	if err := netlink.LinkAdd(&netlink.Veth{LinkAttrs: la, PeerName: "dhcpd1"}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if l, err := netlink.LinkByName("dhcpd0"); err == nil {
+			netlink.LinkDel(l)
+		}
+	})
+	var links []netlink.Link
+	for _, name := range []string{"dhcpd0", "dhcpd1"} {
+		// Link-local addresses are usable at once without DAD.
+		if err := os.WriteFile(fmt.Sprintf("/proc/sys/net/ipv6/conf/%s/accept_dad", name), []byte("0"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		l, err := netlink.LinkByName(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := netlink.LinkSetUp(l); err != nil {
+			t.Fatal(err)
+		}
+		links = append(links, l)
+	}
+	addr, _ := netlink.ParseAddr("192.168.77.1/24")
+	if err := netlink.AddrAdd(links[0], addr); err != nil {
+		t.Fatal(err)
+	}
+
+	c := &Config{
+		Interfaces: []Interface{{
+			Name:  "dhcpd0",
+			DHCP4: &Pool4{Subnet: netip.MustParsePrefix("192.168.77.0/24"), Start: netip.MustParseAddr("192.168.77.50"), End: netip.MustParseAddr("192.168.77.59")},
+			DHCP6: &Pool6{Start: netip.MustParseAddr("fd77::50"), End: netip.MustParseAddr("fd77::59")},
+		}},
+		LeaseFile: filepath.Join(t.TempDir(), "leases"),
+	}
+	s, err := New(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() { served <- s.Serve(ctx) }()
+
+	conf := dhclient.Config{Timeout: 2 * time.Second, Retries: 5}
+	for r := range dhclient.SendRequests(ctx, links[1:], true, true, conf, 10*time.Second) {
+		if r.Err != nil {
+			t.Errorf("%s lease: %v", r.Protocol, r.Err)
+			continue
+		}
+		t.Logf("%s lease: %s", r.Protocol, r.Lease)
+	}
+
+	ls, err := ReadLeases(c.LeaseFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []LeaseType
+	for _, l := range ls {
+		got = append(got, l.Type)
+		if l.Interface != "dhcpd0" || (!c.Interfaces[0].DHCP4.Subnet.Contains(l.Addr) && !c.Interfaces[0].DHCP6.Subnet.Contains(l.Addr)) {
+			t.Errorf("lease %+v", l)
+		}
+	}
+	if len(got) != 2 {
+		t.Errorf("lease types = %v, want ipv4 and ia_na", got)
+	}
+
+	cancel()
+	if err := <-served; !errors.Is(err, context.Canceled) {
+		t.Errorf("Serve = %v, want %v", err, context.Canceled)
+	}
+}
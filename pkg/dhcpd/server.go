@@ -0,0 +1,245 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dhcpd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/netip"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/insomniacslk/dhcp/dhcpv4"
+	"github.com/insomniacslk/dhcp/dhcpv4/server4"
+	"github.com/insomniacslk/dhcp/dhcpv6"
+	"github.com/insomniacslk/dhcp/dhcpv6/server6"
+	"github.com/insomniacslk/dhcp/iana"
+)
+
+// offerHold is how long an offered address is kept for the client that it
+// was offered to.
+const offerHold = time.Minute
+
+// httpClient is the vendor class of UEFI HTTP boot clients, which they
+// expect back in replies.
+const httpClient = "HTTPClient"
+
+// Server is a DHCPv4 and DHCPv6 server.
+type Server struct {
+	// Logf logs requests and errors. It defaults to log.Printf.
+	Logf func(format string, v ...any)
+
+	c      *Config
+	ifaces []*iface
+
+	// now is replaced in tests.
+	now func() time.Time
+
+	mu sync.Mutex
+	db *leaseDB
+}
+
+// iface is an interface served on.
+type iface struct {
+	*Interface
+
+	// serverIP identifies the server over DHCPv4, and duid over
+	// DHCPv6.
+	serverIP netip.Addr
+	duid     dhcpv6.DUID
+}
+
+// New returns a server for c, with the leases of its lease file.
+func New(c *Config) (*Server, error) {
+	if err := c.check(); err != nil {
+		return nil, err
+	}
+	db, err := openLeases(c.LeaseFile, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return &Server{Logf: log.Printf, c: c, now: time.Now, db: db}, nil
+}
+
+// Leases returns the current leases, without offers.
+func (s *Server) Leases() []Lease {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.db.expire(s.now())
+	var ls []Lease
+	for _, l := range s.db.leases {
+		if !l.offered {
+			ls = append(ls, *l)
+		}
+	}
+	return ls
+}
+
+// Serve serves on the interfaces of the configuration until ctx is done or
+// one of them fails.
+func (s *Server) Serve(ctx context.Context) error {
+	var closers []func() error
+	serve := make(chan error, 2*len(s.c.Interfaces))
+	defer func() {
+		for _, c := range closers {
+			c()
+		}
+	}()
+	for i := range s.c.Interfaces {
+		ifc, err := newIface(&s.c.Interfaces[i])
+		if err != nil {
+			return err
+		}
+		s.ifaces = append(s.ifaces, ifc)
+		if ifc.DHCP4 != nil {
+			srv, err := server4.NewServer(ifc.Name, &net.UDPAddr{Port: dhcpv4.ServerPort}, func(conn net.PacketConn, peer net.Addr, m *dhcpv4.DHCPv4) {
+				s.handle4(ifc, conn, peer, m)
+			})
+			if err != nil {
+				return fmt.Errorf("%s: %w", ifc.Name, err)
+			}
+			closers = append(closers, srv.Close)
+			go func() { serve <- srv.Serve() }()
+		}
+		if ifc.DHCP6 != nil {
+			srv, err := server6.NewServer(ifc.Name, nil, func(conn net.PacketConn, peer net.Addr, m dhcpv6.DHCPv6) {
+				s.handle6(ifc, conn, peer, m)
+			})
+			if err != nil {
+				return fmt.Errorf("%s: %w", ifc.Name, err)
+			}
+			closers = append(closers, srv.Close)
+			go func() { serve <- srv.Serve() }()
+		}
+		s.Logf("dhcpd: serving on %s", ifc.Name)
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-serve:
+		return err
+	}
+}
+
+// newIface finds the server IP and DUID of ifc.
+func newIface(ifc *Interface) (*iface, error) {
+	netif, err := net.InterfaceByName(ifc.Name)
+	if err != nil {
+		return nil, err
+	}
+	i := &iface{Interface: ifc}
+	if p := ifc.DHCP4; p != nil {
+		i.serverIP = p.ServerIP
+		if !i.serverIP.IsValid() {
+			addrs, err := netif.Addrs()
+			if err != nil {
+				return nil, err
+			}
+			for _, a := range addrs {
+				if n, ok := a.(*net.IPNet); ok {
+					if ip, ok := netip.AddrFromSlice(n.IP.To4()); ok && p.Subnet.Contains(ip) {
+						i.serverIP = ip
+						break
+					}
+				}
+			}
+		}
+		if !i.serverIP.IsValid() {
+			return nil, fmt.Errorf("%s has no address in %s to serve DHCPv4 from", ifc.Name, p.Subnet)
+		}
+	}
+	if ifc.DHCP6 != nil {
+		if len(netif.HardwareAddr) == 0 {
+			return nil, fmt.Errorf("%s has no MAC address for a DHCPv6 server DUID", ifc.Name)
+		}
+		i.duid = &dhcpv6.DUIDLL{HWType: iana.HWTypeEthernet, LinkLayerAddr: netif.HardwareAddr}
+	}
+	return i, nil
+}
+
+// boot returns the boot file for a client of one of archs, or nil.
+func (ifc *Interface) boot(archs []iana.Arch) *Boot {
+	for i, b := range ifc.Boot {
+		if len(b.Arch) == 0 || slices.ContainsFunc(archs, func(a iana.Arch) bool { return slices.Contains(b.Arch, a) }) {
+			return &ifc.Boot[i]
+		}
+	}
+	return nil
+}
+
+// reservation returns the reservation for a client with mac or duid, or nil.
+func reservation(rs []Reservation, mac, duid string) *Reservation {
+	for i, r := range rs {
+		if (r.MAC != "" && r.MAC == mac) || (r.DUID != "" && r.DUID == duid) {
+			return &rs[i]
+		}
+	}
+	return nil
+}
+
+// reserved reports whether addr or prefix p are reserved for a client other
+// than the one with mac or duid.
+func reserved(rs []Reservation, addr netip.Addr, p netip.Prefix, mac, duid string) bool {
+	for _, r := range rs {
+		if (r.MAC != "" && r.MAC == mac) || (r.DUID != "" && r.DUID == duid) {
+			continue
+		}
+		if (addr.IsValid() && r.IP == addr) || (p.IsValid() && r.Prefix.IsValid() && r.Prefix.Overlaps(p)) {
+			return true
+		}
+	}
+	return false
+}
+
+// errExhausted is returned when a pool has nothing left for a client.
+var errExhausted = errors.New("pool exhausted")
+
+// pick returns the first of prefer, then of the addresses from start to
+// end, that is in that range and that free accepts.
+func pick(start, end netip.Addr, free func(netip.Addr) bool, prefer ...netip.Addr) (netip.Addr, error) {
+	inRange := func(a netip.Addr) bool { return a.IsValid() && !a.Less(start) && !end.Less(a) }
+	for _, a := range prefer {
+		if inRange(a) && free(a) {
+			return a, nil
+		}
+	}
+	for a := start; inRange(a); a = a.Next() {
+		if free(a) {
+			return a, nil
+		}
+	}
+	return netip.Addr{}, errExhausted
+}
+
+// nextPrefix returns the prefix of the same length after p, or an invalid
+// prefix if there is none.
+func nextPrefix(p netip.Prefix) netip.Prefix {
+	a := p.Addr().AsSlice()
+	for i := p.Bits() - 1; i >= 0; i-- {
+		bit := byte(0x80 >> (i % 8))
+		a[i/8] ^= bit
+		if a[i/8]&bit != 0 {
+			next, _ := netip.AddrFromSlice(a)
+			return netip.PrefixFrom(next, p.Bits())
+		}
+	}
+	return netip.Prefix{}
+}
+
+// hexString returns b in lower case hex, as reservations have DUIDs.
+func hexString(b []byte) string {
+	return fmt.Sprintf("%x", b)
+}
+
+// isHTTPClient reports whether a vendor class is that of an HTTP boot
+// client.
+func isHTTPClient(class string) bool {
+	return strings.HasPrefix(class, httpClient)
+}
@@ -0,0 +1,152 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dns
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// TTL bounds of the cache. Negative answers without an SOA record are kept
+// for negativeTTL.
+const (
+	maxTTL      = 24 * time.Hour
+	negativeTTL = 30 * time.Second
+)
+
+// cacheKey identifies a question. Answers to DNSSEC queries carry more
+// records, so they are cached apart.
+type cacheKey struct {
+	name   string
+	qtype  dnsmessage.Type
+	class  dnsmessage.Class
+	dnssec bool
+	cd     bool
+}
+
+func newCacheKey(q dnsmessage.Question, dnssec, cd bool) cacheKey {
+	return cacheKey{name: strings.ToLower(q.Name.String()), qtype: q.Type, class: q.Class, dnssec: dnssec, cd: cd}
+}
+
+type cacheEntry struct {
+	m       *dnsmessage.Message
+	stored  time.Time
+	expires time.Time
+}
+
+// cache holds answers for as long as their records live.
+type cache struct {
+	size int
+	now  func() time.Time
+
+	mu      sync.Mutex
+	entries map[cacheKey]cacheEntry
+}
+
+func newCache(size int) *cache {
+	return &cache{size: size, now: time.Now, entries: map[cacheKey]cacheEntry{}}
+}
+
+// get returns a copy of the answer for k, with TTLs reduced by the time it
+// has been cached.
+func (c *cache) get(k cacheKey) (*dnsmessage.Message, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[k]
+	if !ok {
+		return nil, false
+	}
+	now := c.now()
+	if !now.Before(e.expires) {
+		delete(c.entries, k)
+		return nil, false
+	}
+	age := uint32(now.Sub(e.stored) / time.Second)
+	m := &dnsmessage.Message{Header: e.m.Header}
+	for _, s := range []struct {
+		dst *[]dnsmessage.Resource
+		src []dnsmessage.Resource
+	}{
+		{&m.Answers, e.m.Answers},
+		{&m.Authorities, e.m.Authorities},
+		{&m.Additionals, e.m.Additionals},
+	} {
+		for _, r := range s.src {
+			r.Header.TTL -= min(age, r.Header.TTL)
+			*s.dst = append(*s.dst, r)
+		}
+	}
+	return m, true
+}
+
+// put caches m for k. Only successful and NXDOMAIN answers are cached.
+func (c *cache) put(k cacheKey, m *dnsmessage.Message) {
+	if c.size <= 0 || m.Header.Truncated {
+		return
+	}
+	ttl, ok := cacheTTL(m)
+	if !ok || ttl <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	if len(c.entries) >= c.size {
+		for k, e := range c.entries {
+			if !now.Before(e.expires) {
+				delete(c.entries, k)
+			}
+		}
+	}
+	if len(c.entries) >= c.size {
+		// Map order is random enough for an eviction policy.
+		for k := range c.entries {
+			delete(c.entries, k)
+			break
+		}
+	}
+	// Packing a message writes to its records, so the cache keeps its own.
+	m = &dnsmessage.Message{
+		Header:      m.Header,
+		Answers:     slices.Clone(m.Answers),
+		Authorities: slices.Clone(m.Authorities),
+		Additionals: slices.Clone(m.Additionals),
+	}
+	c.entries[k] = cacheEntry{m: m, stored: now, expires: now.Add(ttl)}
+}
+
+// cacheTTL returns how long m may be cached: the lowest TTL of its
+// records, or for negative answers the SOA minimum of RFC 2308.
+func cacheTTL(m *dnsmessage.Message) (time.Duration, bool) {
+	if m.Header.RCode != dnsmessage.RCodeSuccess && m.Header.RCode != dnsmessage.RCodeNameError {
+		return 0, false
+	}
+	if len(m.Answers) == 0 {
+		for _, r := range m.Authorities {
+			if soa, ok := r.Body.(*dnsmessage.SOAResource); ok {
+				return min(time.Duration(min(r.Header.TTL, soa.MinTTL))*time.Second, maxTTL), true
+			}
+		}
+		return negativeTTL, true
+	}
+	ttl := maxTTL
+	for _, rs := range [][]dnsmessage.Resource{m.Answers, m.Authorities, m.Additionals} {
+		for _, r := range rs {
+			ttl = min(ttl, time.Duration(r.Header.TTL)*time.Second)
+		}
+	}
+	return ttl, true
+}
+
+// clear drops all answers.
+func (c *cache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clear(c.entries)
+}
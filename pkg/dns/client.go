@@ -0,0 +1,305 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package dns implements a DNS client and a caching stub resolver.
+//
+// The client speaks DNS over UDP, falling back to TCP for truncated answers,
+// and DNS over TLS (RFC 7858). The resolver answers from a hosts file and a
+// cache, and forwards other queries to upstream servers, such as those that
+// DHCP put in resolv.conf.
+package dns
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// Ports of DNS and DNS over TLS.
+const (
+	Port    = 53
+	TLSPort = 853
+)
+
+// DefaultTimeout bounds an exchange with a server.
+const DefaultTimeout = 5 * time.Second
+
+// maxUDPSize is the EDNS0 UDP payload size queries advertise.
+const maxUDPSize = 1232
+
+// Upstream is a DNS server.
+type Upstream struct {
+	// Addr is the address and port of the server.
+	Addr netip.AddrPort
+
+	// TLS selects DNS over TLS.
+	TLS bool
+
+	// ServerName is the name the TLS certificate of the server is
+	// verified against. If empty, the certificate must be valid for the
+	// address.
+	ServerName string
+}
+
+// ParseUpstream parses an upstream server of the form
+// [tls://]ADDRESS[:PORT][#SERVERNAME], where IPv6 addresses with a port are
+// in brackets. The port defaults to 53, or 853 for TLS.
+func ParseUpstream(s string) (Upstream, error) {
+	var u Upstream
+	rest, ok := strings.CutPrefix(s, "tls://")
+	u.TLS = ok
+	rest, u.ServerName, _ = strings.Cut(rest, "#")
+	if ap, err := netip.ParseAddrPort(rest); err == nil {
+		u.Addr = ap
+		return u, nil
+	}
+	a, err := netip.ParseAddr(strings.Trim(rest, "[]"))
+	if err != nil {
+		return u, fmt.Errorf("invalid DNS server %q", s)
+	}
+	port := uint16(Port)
+	if u.TLS {
+		port = TLSPort
+	}
+	u.Addr = netip.AddrPortFrom(a, port)
+	return u, nil
+}
+
+// String returns u in the form parsed by ParseUpstream.
+func (u Upstream) String() string {
+	s := u.Addr.String()
+	if u.TLS {
+		s = "tls://" + s
+	}
+	if u.ServerName != "" {
+		s += "#" + u.ServerName
+	}
+	return s
+}
+
+// Response is the answer of a server.
+type Response struct {
+	dnsmessage.Message
+
+	// Size is the length of the response on the wire.
+	Size int
+
+	// Transport is "UDP", "TCP" or "TLS".
+	Transport string
+
+	// RTT is how long the exchange took.
+	RTT time.Duration
+}
+
+// Client sends queries to DNS servers.
+type Client struct {
+	// Timeout bounds each exchange. DefaultTimeout is used if zero.
+	Timeout time.Duration
+
+	// TCP sends queries over TCP rather than UDP.
+	TCP bool
+
+	// TLSConfig is the configuration for DNS over TLS. If nil, the
+	// system roots are used.
+	TLSConfig *tls.Config
+}
+
+var (
+	errMismatch  = errors.New("response does not match the query")
+	errTruncated = errors.New("truncated response")
+)
+
+// Exchange sends q to the server u and returns its response. Truncated UDP
+// responses are retried over TCP.
+func (c *Client) Exchange(ctx context.Context, q *dnsmessage.Message, u Upstream) (*Response, error) {
+	b, err := q.Pack()
+	if err != nil {
+		return nil, err
+	}
+	timeout := c.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	transport := "UDP"
+	switch {
+	case u.TLS:
+		transport = "TLS"
+	case c.TCP:
+		transport = "TCP"
+	}
+	r, err := c.exchange(ctx, b, u, transport)
+	if errors.Is(err, errTruncated) {
+		transport = "TCP"
+		r, err = c.exchange(ctx, b, u, transport)
+	}
+	if err != nil {
+		return nil, err
+	}
+	resp := &Response{Size: len(r), Transport: transport, RTT: time.Since(start)}
+	if err := resp.Unpack(r); err != nil {
+		return nil, err
+	}
+	if !resp.Header.Response || resp.Header.ID != q.Header.ID || !sameQuestions(resp.Questions, q.Questions) {
+		return nil, errMismatch
+	}
+	return resp, nil
+}
+
+// exchange sends the packed query q to u over transport.
+func (c *Client) exchange(ctx context.Context, q []byte, u Upstream, transport string) ([]byte, error) {
+	var (
+		d    net.Dialer
+		conn net.Conn
+		err  error
+	)
+	switch transport {
+	case "TLS":
+		conf := &tls.Config{}
+		if c.TLSConfig != nil {
+			conf = c.TLSConfig.Clone()
+		}
+		conf.ServerName = u.ServerName
+		if conf.ServerName == "" {
+			conf.ServerName = u.Addr.Addr().String()
+		}
+		td := tls.Dialer{NetDialer: &d, Config: conf}
+		conn, err = td.DialContext(ctx, "tcp", u.Addr.String())
+	case "TCP":
+		conn, err = d.DialContext(ctx, "tcp", u.Addr.String())
+	default:
+		conn, err = d.DialContext(ctx, "udp", u.Addr.String())
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if dl, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(dl)
+	}
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	if transport != "UDP" {
+		if err := writeMsg(conn, q); err != nil {
+			return nil, err
+		}
+		return readMsg(conn)
+	}
+
+	if _, err := conn.Write(q); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 65535)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		var p dnsmessage.Parser
+		h, err := p.Start(buf[:n])
+		// Ignore stray datagrams, as a spoofing defense.
+		if err != nil || !h.Response || h.ID != binary.BigEndian.Uint16(q) {
+			continue
+		}
+		if h.Truncated {
+			return nil, errTruncated
+		}
+		return buf[:n:n], nil
+	}
+}
+
+// writeMsg writes a length-prefixed message to a stream.
+func writeMsg(w io.Writer, m []byte) error {
+	if len(m) > 65535 {
+		return fmt.Errorf("message of %d bytes is too long", len(m))
+	}
+	_, err := w.Write(append(binary.BigEndian.AppendUint16(nil, uint16(len(m))), m...))
+	return err
+}
+
+// readMsg reads a length-prefixed message from a stream.
+func readMsg(r io.Reader) ([]byte, error) {
+	var l [2]byte
+	if _, err := io.ReadFull(r, l[:]); err != nil {
+		return nil, err
+	}
+	m := make([]byte, binary.BigEndian.Uint16(l[:]))
+	if _, err := io.ReadFull(r, m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// sameQuestions compares questions, with names case-insensitively.
+func sameQuestions(a, b []dnsmessage.Question) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Type != b[i].Type || a[i].Class != b[i].Class || !strings.EqualFold(a[i].Name.String(), b[i].Name.String()) {
+			return false
+		}
+	}
+	return true
+}
+
+// NewQuery returns a recursive query for name of type t, advertising EDNS0
+// support. With dnssec set, the query asks for DNSSEC records.
+func NewQuery(name string, t dnsmessage.Type, dnssec bool) (*dnsmessage.Message, error) {
+	if !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+	n, err := dnsmessage.NewName(name)
+	if err != nil {
+		return nil, err
+	}
+	var opt dnsmessage.ResourceHeader
+	if err := opt.SetEDNS0(maxUDPSize, dnsmessage.RCodeSuccess, dnssec); err != nil {
+		return nil, err
+	}
+	return &dnsmessage.Message{
+		Header:      dnsmessage.Header{ID: newID(), RecursionDesired: true},
+		Questions:   []dnsmessage.Question{{Name: n, Type: t, Class: dnsmessage.ClassINET}},
+		Additionals: []dnsmessage.Resource{{Header: opt, Body: &dnsmessage.OPTResource{}}},
+	}, nil
+}
+
+// ReverseName returns the name of PTR records for a.
+func ReverseName(a netip.Addr) string {
+	var b strings.Builder
+	if a.Is4() || a.Is4In6() {
+		ip := a.Unmap().As4()
+		for i := 3; i >= 0; i-- {
+			b.WriteString(strconv.Itoa(int(ip[i])) + ".")
+		}
+		return b.String() + "in-addr.arpa."
+	}
+	ip := a.As16()
+	for i := 15; i >= 0; i-- {
+		fmt.Fprintf(&b, "%x.%x.", ip[i]&0xf, ip[i]>>4)
+	}
+	return b.String() + "ip6.arpa."
+}
+
+// newID returns a random query ID.
+func newID() uint16 {
+	var b [2]byte
+	rand.Read(b[:])
+	return binary.BigEndian.Uint16(b[:])
+}
@@ -0,0 +1,239 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dns
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// testServer is an upstream server that answers with handler, over UDP and
+// TCP on the same port.
+type testServer struct {
+	addr    netip.AddrPort
+	queries atomic.Int32
+}
+
+func newTestServer(t *testing.T, handler func(q *dnsmessage.Message, tcp bool) *dnsmessage.Message) *testServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pc, err := net.ListenPacket("udp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		ln.Close()
+		pc.Close()
+	})
+	ts := &testServer{addr: netip.MustParseAddrPort(ln.Addr().String())}
+	answer := func(b []byte, tcp bool) []byte {
+		ts.queries.Add(1)
+		var q dnsmessage.Message
+		if err := q.Unpack(b); err != nil {
+			return nil
+		}
+		r := handler(&q, tcp)
+		r.Header.ID = q.Header.ID
+		r.Header.Response = true
+		r.Questions = q.Questions
+		rb, err := r.Pack()
+		if err != nil {
+			t.Error(err)
+		}
+		return rb
+	}
+	go func() {
+		buf := make([]byte, 65535)
+		for {
+			n, peer, err := pc.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if r := answer(buf[:n], false); r != nil {
+				pc.WriteTo(r, peer)
+			}
+		}
+	}()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				for {
+					q, err := readMsg(conn)
+					if err != nil {
+						return
+					}
+					if writeMsg(conn, answer(q, true)) != nil {
+						return
+					}
+				}
+			}()
+		}
+	}()
+	return ts
+}
+
+func aRecord(name string, ttl uint32, addr string) dnsmessage.Resource {
+	return dnsmessage.Resource{
+		Header: dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName(name), Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: ttl},
+		Body:   &dnsmessage.AResource{A: netip.MustParseAddr(addr).As4()},
+	}
+}
+
+func TestParseUpstream(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		want Upstream
+		err  bool
+	}{
+		{in: "192.0.2.1", want: Upstream{Addr: netip.MustParseAddrPort("192.0.2.1:53")}},
+		{in: "192.0.2.1:5353", want: Upstream{Addr: netip.MustParseAddrPort("192.0.2.1:5353")}},
+		{in: "2001:db8::1", want: Upstream{Addr: netip.MustParseAddrPort("[2001:db8::1]:53")}},
+		{in: "[2001:db8::1]:5353", want: Upstream{Addr: netip.MustParseAddrPort("[2001:db8::1]:5353")}},
+		{in: "tls://1.1.1.1#cloudflare-dns.com", want: Upstream{Addr: netip.MustParseAddrPort("1.1.1.1:853"), TLS: true, ServerName: "cloudflare-dns.com"}},
+		{in: "tls://[2001:db8::1]:8853", want: Upstream{Addr: netip.MustParseAddrPort("[2001:db8::1]:8853"), TLS: true}},
+		{in: "dns.example", err: true},
+	} {
+		got, err := ParseUpstream(tt.in)
+		if (err != nil) != tt.err || (err == nil && got != tt.want) {
+			t.Errorf("ParseUpstream(%q) = %v, %v, want %v, error %t", tt.in, got, err, tt.want, tt.err)
+		}
+		if err == nil {
+			if again, _ := ParseUpstream(got.String()); again != got {
+				t.Errorf("ParseUpstream(%q) = %v, want %v", got.String(), again, got)
+			}
+		}
+	}
+}
+
+func TestReverseName(t *testing.T) {
+	for _, tt := range []struct {
+		addr string
+		want string
+	}{
+		{"192.0.2.10", "10.2.0.192.in-addr.arpa."},
+		{"::ffff:192.0.2.10", "10.2.0.192.in-addr.arpa."},
+		{"2001:db8::567:89ab", "b.a.9.8.7.6.5.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa."},
+	} {
+		a := netip.MustParseAddr(tt.addr)
+		got := ReverseName(a)
+		if got != tt.want {
+			t.Errorf("ReverseName(%s) = %q, want %q", a, got, tt.want)
+		}
+		if back, ok := reverseAddr(got); !ok || back != a.Unmap() {
+			t.Errorf("reverseAddr(%q) = %v, %t, want %v", got, back, ok, a.Unmap())
+		}
+	}
+	for _, name := range []string{"example.com.", "1.2.3.in-addr.arpa.", "300.2.0.192.in-addr.arpa.", "b.a.ip6.arpa."} {
+		if a, ok := reverseAddr(name); ok {
+			t.Errorf("reverseAddr(%q) = %v, want none", name, a)
+		}
+	}
+}
+
+func TestExchange(t *testing.T) {
+	ts := newTestServer(t, func(q *dnsmessage.Message, tcp bool) *dnsmessage.Message {
+		if !tcp {
+			return &dnsmessage.Message{Header: dnsmessage.Header{Truncated: true}}
+		}
+		return &dnsmessage.Message{Answers: []dnsmessage.Resource{aRecord("host.example.", 60, "192.0.2.1")}}
+	})
+	q, err := NewQuery("host.example", dnsmessage.TypeA, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !q.Additionals[0].Header.DNSSECAllowed() {
+		t.Errorf("NewQuery did not set the DO bit")
+	}
+
+	var c Client
+	r, err := c.Exchange(context.Background(), q, Upstream{Addr: ts.addr})
+	if err != nil {
+		t.Fatalf("Exchange = %v", err)
+	}
+	if r.Transport != "TCP" || len(r.Answers) != 1 {
+		t.Errorf("Exchange = %s with %d answers, want TCP with 1", r.Transport, len(r.Answers))
+	}
+	if got := ts.queries.Load(); got != 2 {
+		t.Errorf("server got %d queries, want 2", got)
+	}
+
+	c.TCP = true
+	if r, err = c.Exchange(context.Background(), q, Upstream{Addr: ts.addr}); err != nil || r.Transport != "TCP" {
+		t.Errorf("Exchange over TCP = %v", err)
+	}
+}
+
+func TestExchangeMismatch(t *testing.T) {
+	ts := newTestServer(t, func(q *dnsmessage.Message, tcp bool) *dnsmessage.Message {
+		q.Questions[0].Name = dnsmessage.MustNewName("other.example.")
+		return &dnsmessage.Message{}
+	})
+	q, err := NewQuery("host.example", dnsmessage.TypeA, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := Client{TCP: true}
+	if _, err := c.Exchange(context.Background(), q, Upstream{Addr: ts.addr}); err != errMismatch {
+		t.Errorf("Exchange = %v, want %v", err, errMismatch)
+	}
+}
+
+func TestFormatResource(t *testing.T) {
+	name := dnsmessage.MustNewName("example.com.")
+	hdr := func(typ dnsmessage.Type) dnsmessage.ResourceHeader {
+		return dnsmessage.ResourceHeader{Name: name, Type: typ, Class: dnsmessage.ClassINET, TTL: 300}
+	}
+	rrsig := []byte{
+		0, 1, 13, 2, 0, 0, 0x0e, 0x10, // A, algorithm 13, 2 labels, TTL 3600
+		0x65, 0x53, 0xf1, 0x00, // 20231114221320 expiration
+		0x65, 0x41, 0x7c, 0x00, // 20231031221320 inception
+		0x30, 0x39, // key tag 12345
+		7, 'e', 'x', 'a', 'm', 'p', 'l', 'e', 3, 'c', 'o', 'm', 0,
+		1, 2, 3,
+	}
+	for _, tt := range []struct {
+		r    dnsmessage.Resource
+		want string
+	}{
+		{aRecord("example.com.", 300, "192.0.2.1"), "example.com.\t300\tIN\tA\t192.0.2.1"},
+		{dnsmessage.Resource{Header: hdr(dnsmessage.TypeMX), Body: &dnsmessage.MXResource{Pref: 10, MX: dnsmessage.MustNewName("mx.example.com.")}}, "example.com.\t300\tIN\tMX\t10 mx.example.com."},
+		{dnsmessage.Resource{Header: hdr(dnsmessage.TypeSRV), Body: &dnsmessage.SRVResource{Priority: 1, Weight: 2, Port: 443, Target: name}}, "example.com.\t300\tIN\tSRV\t1 2 443 example.com."},
+		{dnsmessage.Resource{Header: hdr(dnsmessage.TypeTXT), Body: &dnsmessage.TXTResource{TXT: []string{"v=spf1 -all", `a"b`}}}, "example.com.\t300\tIN\tTXT\t\"v=spf1 -all\" \"a\\\"b\""},
+		{dnsmessage.Resource{Header: hdr(TypeDS), Body: &dnsmessage.UnknownResource{Type: TypeDS, Data: []byte{0x30, 0x39, 13, 2, 0xab, 0xcd}}}, "example.com.\t300\tIN\tDS\t12345 13 2 ABCD"},
+		{dnsmessage.Resource{Header: hdr(TypeDNSKEY), Body: &dnsmessage.UnknownResource{Type: TypeDNSKEY, Data: []byte{1, 1, 3, 13, 1, 2, 3}}}, "example.com.\t300\tIN\tDNSKEY\t257 3 13 AQID"},
+		{dnsmessage.Resource{Header: hdr(TypeRRSIG), Body: &dnsmessage.UnknownResource{Type: TypeRRSIG, Data: rrsig}}, "example.com.\t300\tIN\tRRSIG\tA 13 2 3600 20231114221320 20231031221320 12345 example.com. AQID"},
+		{dnsmessage.Resource{Header: hdr(999), Body: &dnsmessage.UnknownResource{Type: 999, Data: []byte{0xde, 0xad}}}, "example.com.\t300\tIN\tTYPE999\t\\# 2 dead"},
+	} {
+		if got := FormatResource(tt.r); got != tt.want {
+			t.Errorf("FormatResource = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestParseType(t *testing.T) {
+	for _, s := range []string{"A", "aaaa", "SRV", "txt", "PTR", "MX", "DNSKEY", "TYPE999"} {
+		typ, ok := ParseType(s)
+		if !ok || !strings.EqualFold(TypeString(typ), s) {
+			t.Errorf("ParseType(%q) = %v, %t", s, typ, ok)
+		}
+	}
+	if _, ok := ParseType("BOGUS"); ok {
+		t.Errorf("ParseType(BOGUS) succeeded")
+	}
+}
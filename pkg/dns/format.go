@@ -0,0 +1,198 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dns
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// DNSSEC record types, which dnsmessage does not name.
+const (
+	TypeDS     dnsmessage.Type = 43
+	TypeRRSIG  dnsmessage.Type = 46
+	TypeNSEC   dnsmessage.Type = 47
+	TypeDNSKEY dnsmessage.Type = 48
+	TypeNSEC3  dnsmessage.Type = 50
+)
+
+var typeNames = map[dnsmessage.Type]string{
+	dnsmessage.TypeA:     "A",
+	dnsmessage.TypeNS:    "NS",
+	dnsmessage.TypeCNAME: "CNAME",
+	dnsmessage.TypeSOA:   "SOA",
+	dnsmessage.TypePTR:   "PTR",
+	dnsmessage.TypeMX:    "MX",
+	dnsmessage.TypeTXT:   "TXT",
+	dnsmessage.TypeAAAA:  "AAAA",
+	dnsmessage.TypeSRV:   "SRV",
+	dnsmessage.TypeOPT:   "OPT",
+	dnsmessage.TypeSVCB:  "SVCB",
+	dnsmessage.TypeHTTPS: "HTTPS",
+	dnsmessage.TypeALL:   "ANY",
+	dnsmessage.TypeAXFR:  "AXFR",
+	TypeDS:               "DS",
+	TypeRRSIG:            "RRSIG",
+	TypeNSEC:             "NSEC",
+	TypeDNSKEY:           "DNSKEY",
+	TypeNSEC3:            "NSEC3",
+}
+
+// TypeString returns the mnemonic of t, or TYPEn for unknown types.
+func TypeString(t dnsmessage.Type) string {
+	if s, ok := typeNames[t]; ok {
+		return s
+	}
+	return "TYPE" + strconv.Itoa(int(t))
+}
+
+// ParseType parses a type mnemonic such as AAAA, or TYPEn.
+func ParseType(s string) (dnsmessage.Type, bool) {
+	s = strings.ToUpper(s)
+	for t, name := range typeNames {
+		if name == s {
+			return t, true
+		}
+	}
+	if n, ok := strings.CutPrefix(s, "TYPE"); ok {
+		if v, err := strconv.ParseUint(n, 10, 16); err == nil {
+			return dnsmessage.Type(v), true
+		}
+	}
+	return 0, false
+}
+
+// ClassString returns the mnemonic of c.
+func ClassString(c dnsmessage.Class) string {
+	switch c {
+	case dnsmessage.ClassINET:
+		return "IN"
+	case dnsmessage.ClassCHAOS:
+		return "CH"
+	case dnsmessage.ClassANY:
+		return "ANY"
+	}
+	return "CLASS" + strconv.Itoa(int(c))
+}
+
+// RCodeString returns the mnemonic of the response code rc.
+func RCodeString(rc dnsmessage.RCode) string {
+	switch rc {
+	case dnsmessage.RCodeSuccess:
+		return "NOERROR"
+	case dnsmessage.RCodeFormatError:
+		return "FORMERR"
+	case dnsmessage.RCodeServerFailure:
+		return "SERVFAIL"
+	case dnsmessage.RCodeNameError:
+		return "NXDOMAIN"
+	case dnsmessage.RCodeNotImplemented:
+		return "NOTIMP"
+	case dnsmessage.RCodeRefused:
+		return "REFUSED"
+	}
+	return "RCODE" + strconv.Itoa(int(rc))
+}
+
+// FormatResource returns r in zone file format.
+func FormatResource(r dnsmessage.Resource) string {
+	return fmt.Sprintf("%s\t%d\t%s\t%s\t%s", r.Header.Name, r.Header.TTL, ClassString(r.Header.Class), TypeString(r.Header.Type), FormatData(r.Body))
+}
+
+// FormatData returns the data of a resource in zone file format. DNSSEC
+// records are decoded; other unknown records are in the generic format of
+// RFC 3597.
+func FormatData(b dnsmessage.ResourceBody) string {
+	switch b := b.(type) {
+	case *dnsmessage.AResource:
+		return netip.AddrFrom4(b.A).String()
+	case *dnsmessage.AAAAResource:
+		return netip.AddrFrom16(b.AAAA).String()
+	case *dnsmessage.NSResource:
+		return b.NS.String()
+	case *dnsmessage.CNAMEResource:
+		return b.CNAME.String()
+	case *dnsmessage.PTRResource:
+		return b.PTR.String()
+	case *dnsmessage.MXResource:
+		return fmt.Sprintf("%d %s", b.Pref, b.MX)
+	case *dnsmessage.SRVResource:
+		return fmt.Sprintf("%d %d %d %s", b.Priority, b.Weight, b.Port, b.Target)
+	case *dnsmessage.SOAResource:
+		return fmt.Sprintf("%s %s %d %d %d %d %d", b.NS, b.MBox, b.Serial, b.Refresh, b.Retry, b.Expire, b.MinTTL)
+	case *dnsmessage.TXTResource:
+		var txt []string
+		for _, t := range b.TXT {
+			txt = append(txt, strconv.Quote(t))
+		}
+		return strings.Join(txt, " ")
+	case *dnsmessage.UnknownResource:
+		if s, ok := formatDNSSEC(b.Type, b.Data); ok {
+			return s
+		}
+		return fmt.Sprintf("\\# %d %x", len(b.Data), b.Data)
+	case *dnsmessage.SVCBResource:
+		return fmt.Sprintf("%d %s", b.Priority, b.Target)
+	case *dnsmessage.HTTPSResource:
+		return fmt.Sprintf("%d %s", b.Priority, b.Target)
+	}
+	return b.GoString()
+}
+
+// formatDNSSEC formats DS, DNSKEY and RRSIG records of RFC 4034.
+func formatDNSSEC(t dnsmessage.Type, d []byte) (string, bool) {
+	switch t {
+	case TypeDS:
+		if len(d) < 4 {
+			return "", false
+		}
+		return fmt.Sprintf("%d %d %d %X", binary.BigEndian.Uint16(d), d[2], d[3], d[4:]), true
+	case TypeDNSKEY:
+		if len(d) < 4 {
+			return "", false
+		}
+		return fmt.Sprintf("%d %d %d %s", binary.BigEndian.Uint16(d), d[2], d[3], base64.StdEncoding.EncodeToString(d[4:])), true
+	case TypeRRSIG:
+		if len(d) < 18 {
+			return "", false
+		}
+		signer, n, ok := wireName(d[18:])
+		if !ok {
+			return "", false
+		}
+		stamp := func(b []byte) string {
+			return time.Unix(int64(binary.BigEndian.Uint32(b)), 0).UTC().Format("20060102150405")
+		}
+		return fmt.Sprintf("%s %d %d %d %s %s %d %s %s",
+			TypeString(dnsmessage.Type(binary.BigEndian.Uint16(d))), d[2], d[3], binary.BigEndian.Uint32(d[4:]),
+			stamp(d[8:]), stamp(d[12:]), binary.BigEndian.Uint16(d[16:]), signer,
+			base64.StdEncoding.EncodeToString(d[18+n:])), true
+	}
+	return "", false
+}
+
+// wireName decodes an uncompressed name, returning its length.
+func wireName(b []byte) (string, int, bool) {
+	var labels []string
+	for i := 0; i < len(b); {
+		l := int(b[i])
+		if l == 0 {
+			return strings.Join(labels, ".") + ".", i + 1, true
+		}
+		if l > 63 || i+1+l > len(b) {
+			break
+		}
+		labels = append(labels, string(b[i+1:i+1+l]))
+		i += 1 + l
+	}
+	return "", 0, false
+}
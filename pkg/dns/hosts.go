@@ -0,0 +1,75 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dns
+
+import (
+	"bufio"
+	"io"
+	"net/netip"
+	"os"
+	"strings"
+)
+
+// Hosts is a hosts file.
+type Hosts struct {
+	addrs map[string][]netip.Addr
+	names map[netip.Addr][]string
+}
+
+// ParseHosts parses a hosts file. Lines with an invalid address are
+// ignored.
+func ParseHosts(r io.Reader) (*Hosts, error) {
+	h := &Hosts{addrs: map[string][]netip.Addr{}, names: map[netip.Addr][]string{}}
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line, _, _ := strings.Cut(s.Text(), "#")
+		f := strings.Fields(line)
+		if len(f) < 2 {
+			continue
+		}
+		a, err := netip.ParseAddr(f[0])
+		if err != nil {
+			continue
+		}
+		a = a.Unmap().WithZone("")
+		for _, name := range f[1:] {
+			name = canonical(name)
+			h.addrs[name] = append(h.addrs[name], a)
+			h.names[a] = append(h.names[a], name)
+		}
+	}
+	return h, s.Err()
+}
+
+// ReadHosts reads the hosts file at path.
+func ReadHosts(path string) (*Hosts, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseHosts(f)
+}
+
+// Lookup returns the addresses of name, of both families. ok reports
+// whether name is in the file.
+func (h *Hosts) Lookup(name string) (addrs []netip.Addr, ok bool) {
+	addrs, ok = h.addrs[canonical(name)]
+	return addrs, ok
+}
+
+// LookupAddr returns the names of a, the first being its canonical name.
+func (h *Hosts) LookupAddr(a netip.Addr) []string {
+	return h.names[a.Unmap().WithZone("")]
+}
+
+// canonical returns name in lower case, fully qualified.
+func canonical(name string) string {
+	name = strings.ToLower(name)
+	if !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+	return name
+}
@@ -0,0 +1,74 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dns
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"strings"
+)
+
+// ResolvConf is the part of resolv.conf that the resolver uses.
+type ResolvConf struct {
+	Nameservers []netip.Addr
+	Search      []string
+	Domain      string
+}
+
+// ParseResolvConf parses resolv.conf. Invalid nameserver lines and unknown
+// keywords are ignored, as the C library does.
+func ParseResolvConf(r io.Reader) (*ResolvConf, error) {
+	rc := &ResolvConf{}
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line, _, _ := strings.Cut(s.Text(), "#")
+		line, _, _ = strings.Cut(line, ";")
+		f := strings.Fields(line)
+		if len(f) < 2 {
+			continue
+		}
+		switch f[0] {
+		case "nameserver":
+			// Scoped addresses are written as fe80::1%eth0.
+			if a, err := netip.ParseAddr(f[1]); err == nil {
+				rc.Nameservers = append(rc.Nameservers, a)
+			}
+		case "search":
+			rc.Search = f[1:]
+		case "domain":
+			rc.Domain = f[1]
+		}
+	}
+	return rc, s.Err()
+}
+
+// ReadResolvConf reads the resolv.conf file at path.
+func ReadResolvConf(path string) (*ResolvConf, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseResolvConf(f)
+}
+
+// Write writes rc to path.
+func (rc *ResolvConf) Write(path string) error {
+	var b bytes.Buffer
+	if rc.Domain != "" {
+		fmt.Fprintf(&b, "domain %s\n", rc.Domain)
+	}
+	for _, a := range rc.Nameservers {
+		fmt.Fprintf(&b, "nameserver %s\n", a)
+	}
+	if len(rc.Search) > 0 {
+		fmt.Fprintf(&b, "search %s\n", strings.Join(rc.Search, " "))
+	}
+	return os.WriteFile(path, b.Bytes(), 0o644)
+}
@@ -0,0 +1,504 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dns
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"net/netip"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// StubAddr is the address stub resolvers conventionally listen on.
+const StubAddr = "127.0.0.53:53"
+
+// DefaultCacheSize is the number of answers a server caches by default.
+const DefaultCacheSize = 4096
+
+// reloadInterval is how often a server checks its files for changes.
+const reloadInterval = time.Second
+
+// tcpIdle is how long a server keeps an idle TCP connection.
+const tcpIdle = 10 * time.Second
+
+// Server is a caching stub resolver. It answers from a hosts file and
+// forwards other queries to upstream servers.
+type Server struct {
+	// Upstreams are the servers queries are forwarded to, in order of
+	// preference. If empty, the nameservers of ResolvConf are used.
+	Upstreams []Upstream
+
+	// ResolvConf is the resolv.conf file that upstream servers are taken
+	// from, which is reread when it changes, e.g. when DHCP writes it.
+	ResolvConf string
+
+	// StubResolvConf rewrites ResolvConf to point to the server, after
+	// taking its nameservers as upstream servers. The nameservers are put
+	// back when the server stops.
+	StubResolvConf bool
+
+	// HostsFile is a hosts file that the server answers from, which is
+	// reread when it changes.
+	HostsFile string
+
+	// Client forwards queries.
+	Client Client
+
+	// CacheSize is the number of answers cached. DefaultCacheSize is used
+	// if zero; negative disables the cache.
+	CacheSize int
+
+	// Logf logs changes of configuration and upstream failures. It
+	// defaults to log.Printf.
+	Logf func(format string, v ...any)
+
+	once  sync.Once
+	cache *cache
+
+	mu        sync.Mutex
+	addr      netip.Addr
+	upstreams []Upstream
+	hosts     *Hosts
+	stamps    map[string]stamp
+}
+
+// stamp identifies a version of a file.
+type stamp struct {
+	mod  time.Time
+	size int64
+}
+
+func (s *Server) init() {
+	s.once.Do(func() {
+		size := s.CacheSize
+		if size == 0 {
+			size = DefaultCacheSize
+		}
+		s.cache = newCache(size)
+		s.stamps = map[string]stamp{}
+		if s.Logf == nil {
+			s.Logf = log.Printf
+		}
+	})
+}
+
+// ListenAndServe serves DNS over UDP and TCP on addr until ctx is done.
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	pc, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return err
+	}
+	defer pc.Close()
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer ln.Close()
+	return s.Serve(ctx, pc, ln)
+}
+
+// Serve serves DNS on pc and ln until ctx is done or either fails.
+func (s *Server) Serve(ctx context.Context, pc net.PacketConn, ln net.Listener) error {
+	s.init()
+	if ap, err := netip.ParseAddrPort(pc.LocalAddr().String()); err == nil {
+		s.mu.Lock()
+		s.addr = ap.Addr().Unmap()
+		s.mu.Unlock()
+	}
+	s.reload()
+	defer s.restoreResolvConf()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		<-ctx.Done()
+		pc.Close()
+		ln.Close()
+	}()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 2)
+	wg.Go(func() {
+		t := time.NewTicker(reloadInterval)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+				s.reload()
+			}
+		}
+	})
+	wg.Go(func() { errs <- s.serveUDP(ctx, pc) })
+	wg.Go(func() { errs <- s.serveTCP(ctx, ln) })
+
+	var err error
+	select {
+	case <-ctx.Done():
+	case err = <-errs:
+	}
+	if err == nil {
+		err = ctx.Err()
+	}
+	cancel()
+	wg.Wait()
+	return err
+}
+
+func (s *Server) serveUDP(ctx context.Context, pc net.PacketConn) error {
+	for {
+		buf := make([]byte, 65535)
+		n, peer, err := pc.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() {
+				continue
+			}
+			return err
+		}
+		go func() {
+			if r := s.resolve(ctx, buf[:n], true); r != nil {
+				pc.WriteTo(r, peer)
+			}
+		}()
+	}
+}
+
+func (s *Server) serveTCP(ctx context.Context, ln net.Listener) error {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		go func() {
+			defer conn.Close()
+			stop := context.AfterFunc(ctx, func() { conn.Close() })
+			defer stop()
+			for {
+				conn.SetDeadline(time.Now().Add(tcpIdle))
+				q, err := readMsg(conn)
+				if err != nil {
+					return
+				}
+				r := s.resolve(ctx, q, false)
+				if r == nil || writeMsg(conn, r) != nil {
+					return
+				}
+			}
+		}()
+	}
+}
+
+// resolve answers the packed query q. It returns nil if q is not worth an
+// answer. UDP answers that are too large for the client are truncated.
+func (s *Server) resolve(ctx context.Context, b []byte, udp bool) []byte {
+	var q dnsmessage.Message
+	if err := q.Unpack(b); err != nil {
+		var p dnsmessage.Parser
+		h, err := p.Start(b)
+		if err != nil || h.Response {
+			return nil
+		}
+		r := dnsmessage.Message{Header: dnsmessage.Header{ID: h.ID, Response: true, OpCode: h.OpCode, RCode: dnsmessage.RCodeFormatError}}
+		rb, _ := r.Pack()
+		return rb
+	}
+	if q.Header.Response {
+		return nil
+	}
+
+	r := dnsmessage.Message{
+		Header: dnsmessage.Header{
+			ID:                 q.Header.ID,
+			Response:           true,
+			OpCode:             q.Header.OpCode,
+			RecursionDesired:   q.Header.RecursionDesired,
+			RecursionAvailable: true,
+			CheckingDisabled:   q.Header.CheckingDisabled,
+		},
+		Questions: q.Questions,
+	}
+	var (
+		edns   bool
+		dnssec bool
+		limit  = 512
+	)
+	for _, a := range q.Additionals {
+		if a.Header.Type == dnsmessage.TypeOPT {
+			edns = true
+			dnssec = a.Header.DNSSECAllowed()
+			limit = max(limit, int(a.Header.Class))
+		}
+	}
+
+	switch {
+	case q.Header.OpCode != 0:
+		r.Header.RCode = dnsmessage.RCodeNotImplemented
+	case len(q.Questions) != 1:
+		r.Header.RCode = dnsmessage.RCodeFormatError
+	default:
+		a := s.answer(ctx, q.Questions[0], dnssec, q.Header.CheckingDisabled)
+		r.Header.RCode = a.Header.RCode
+		r.Header.Authoritative = a.Header.Authoritative
+		// RFC 6840, Section 5.8: only clients that ask get the AD bit.
+		r.Header.AuthenticData = a.Header.AuthenticData && (dnssec || q.Header.AuthenticData)
+		r.Answers = a.Answers
+		r.Authorities = a.Authorities
+		// The answer may be cached, so it must not be appended to.
+		r.Additionals = slices.Clone(a.Additionals)
+	}
+	if edns {
+		var opt dnsmessage.ResourceHeader
+		opt.SetEDNS0(maxUDPSize, dnsmessage.RCodeSuccess, dnssec)
+		r.Additionals = append(r.Additionals, dnsmessage.Resource{Header: opt, Body: &dnsmessage.OPTResource{}})
+	}
+
+	rb, err := r.Pack()
+	if err == nil && (!udp || len(rb) <= limit) {
+		return rb
+	}
+	r.Header.Truncated = err == nil
+	if err != nil {
+		r.Header.RCode = dnsmessage.RCodeServerFailure
+	}
+	r.Answers, r.Authorities = nil, nil
+	r.Additionals = slices.DeleteFunc(r.Additionals, func(a dnsmessage.Resource) bool { return a.Header.Type != dnsmessage.TypeOPT })
+	rb, _ = r.Pack()
+	return rb
+}
+
+// answer answers q from the hosts file, the cache or an upstream server.
+func (s *Server) answer(ctx context.Context, q dnsmessage.Question, dnssec, cd bool) *dnsmessage.Message {
+	if m := s.fromHosts(q); m != nil {
+		return m
+	}
+	key := newCacheKey(q, dnssec, cd)
+	if m, ok := s.cache.get(key); ok {
+		return m
+	}
+
+	var opt dnsmessage.ResourceHeader
+	opt.SetEDNS0(maxUDPSize, dnsmessage.RCodeSuccess, dnssec)
+	fq := &dnsmessage.Message{
+		Header:      dnsmessage.Header{ID: newID(), RecursionDesired: true, CheckingDisabled: cd},
+		Questions:   []dnsmessage.Question{q},
+		Additionals: []dnsmessage.Resource{{Header: opt, Body: &dnsmessage.OPTResource{}}},
+	}
+	fail := &dnsmessage.Message{Header: dnsmessage.Header{RCode: dnsmessage.RCodeServerFailure}}
+	for _, u := range s.servers() {
+		fq.Header.ID = newID()
+		resp, err := s.Client.Exchange(ctx, fq, u)
+		if err != nil {
+			if ctx.Err() == nil {
+				s.Logf("dns: %s %s: %s: %v", q.Name, TypeString(q.Type), u, err)
+			}
+			continue
+		}
+		m := &resp.Message
+		if rc := m.Header.RCode; rc == dnsmessage.RCodeServerFailure || rc == dnsmessage.RCodeRefused {
+			fail.Header.RCode = rc
+			continue
+		}
+		m.Additionals = slices.DeleteFunc(m.Additionals, func(a dnsmessage.Resource) bool { return a.Header.Type == dnsmessage.TypeOPT })
+		s.cache.put(key, m)
+		return m
+	}
+	return fail
+}
+
+// fromHosts answers address and PTR queries for names and addresses in the
+// hosts file. A name with addresses of the other family only has no data.
+func (s *Server) fromHosts(q dnsmessage.Question) *dnsmessage.Message {
+	s.mu.Lock()
+	h := s.hosts
+	s.mu.Unlock()
+	if h == nil || q.Class != dnsmessage.ClassINET {
+		return nil
+	}
+	m := &dnsmessage.Message{Header: dnsmessage.Header{Authoritative: true}}
+	hdr := dnsmessage.ResourceHeader{Name: q.Name, Type: q.Type, Class: q.Class}
+	switch q.Type {
+	case dnsmessage.TypeA, dnsmessage.TypeAAAA:
+		addrs, ok := h.Lookup(q.Name.String())
+		if !ok {
+			return nil
+		}
+		for _, a := range addrs {
+			switch {
+			case q.Type == dnsmessage.TypeA && a.Is4():
+				m.Answers = append(m.Answers, dnsmessage.Resource{Header: hdr, Body: &dnsmessage.AResource{A: a.As4()}})
+			case q.Type == dnsmessage.TypeAAAA && a.Is6():
+				m.Answers = append(m.Answers, dnsmessage.Resource{Header: hdr, Body: &dnsmessage.AAAAResource{AAAA: a.As16()}})
+			}
+		}
+	case dnsmessage.TypePTR:
+		a, ok := reverseAddr(q.Name.String())
+		if !ok {
+			return nil
+		}
+		names := h.LookupAddr(a)
+		if len(names) == 0 {
+			return nil
+		}
+		for _, name := range names {
+			n, err := dnsmessage.NewName(name)
+			if err != nil {
+				continue
+			}
+			m.Answers = append(m.Answers, dnsmessage.Resource{Header: hdr, Body: &dnsmessage.PTRResource{PTR: n}})
+		}
+	default:
+		return nil
+	}
+	return m
+}
+
+// reverseAddr returns the address of a PTR name made by ReverseName.
+func reverseAddr(name string) (netip.Addr, bool) {
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+	if rest, ok := strings.CutSuffix(name, ".in-addr.arpa"); ok {
+		labels := strings.Split(rest, ".")
+		if len(labels) != 4 {
+			return netip.Addr{}, false
+		}
+		var ip [4]byte
+		for i, l := range labels {
+			v, err := strconv.ParseUint(l, 10, 8)
+			if err != nil {
+				return netip.Addr{}, false
+			}
+			ip[3-i] = byte(v)
+		}
+		return netip.AddrFrom4(ip), true
+	}
+	if rest, ok := strings.CutSuffix(name, ".ip6.arpa"); ok {
+		labels := strings.Split(rest, ".")
+		if len(labels) != 32 {
+			return netip.Addr{}, false
+		}
+		var ip [16]byte
+		for i, l := range labels {
+			v, err := strconv.ParseUint(l, 16, 4)
+			if err != nil || len(l) != 1 {
+				return netip.Addr{}, false
+			}
+			// The first label is the low nibble of the last byte.
+			ip[15-i/2] |= byte(v) << (4 * (i % 2))
+		}
+		return netip.AddrFrom16(ip), true
+	}
+	return netip.Addr{}, false
+}
+
+// servers returns the upstream servers to forward to.
+func (s *Server) servers() []Upstream {
+	if len(s.Upstreams) > 0 {
+		return s.Upstreams
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.upstreams
+}
+
+// changed reports whether the file at path changed since the last call.
+func (s *Server) changed(path string) bool {
+	var st stamp
+	if fi, err := os.Stat(path); err == nil {
+		st = stamp{mod: fi.ModTime(), size: fi.Size()}
+	}
+	old, ok := s.stamps[path]
+	s.stamps[path] = st
+	return !ok || old != st
+}
+
+// reload rereads the hosts and resolv.conf files if they changed.
+func (s *Server) reload() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.HostsFile != "" && s.changed(s.HostsFile) {
+		h, err := ReadHosts(s.HostsFile)
+		if err != nil && !os.IsNotExist(err) {
+			s.Logf("dns: %v", err)
+		}
+		s.hosts = h
+	}
+
+	if len(s.Upstreams) > 0 || s.ResolvConf == "" || !s.changed(s.ResolvConf) {
+		return
+	}
+	rc, err := ReadResolvConf(s.ResolvConf)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			s.Logf("dns: %v", err)
+		}
+		return
+	}
+	var ups []Upstream
+	for _, ns := range rc.Nameservers {
+		// Our own address is there after StubResolvConf rewrote the
+		// file.
+		if ns.Unmap() == s.addr {
+			continue
+		}
+		ups = append(ups, Upstream{Addr: netip.AddrPortFrom(ns, Port)})
+	}
+	if len(ups) == 0 {
+		return
+	}
+	if !slices.Equal(ups, s.upstreams) {
+		s.Logf("dns: upstream servers %v", ups)
+		s.upstreams = ups
+		s.cache.clear()
+	}
+	if s.StubResolvConf && s.addr.IsValid() {
+		rc.Nameservers = []netip.Addr{s.addr}
+		if err := rc.Write(s.ResolvConf); err != nil {
+			s.Logf("dns: %v", err)
+		}
+		s.changed(s.ResolvConf)
+	}
+}
+
+// restoreResolvConf puts the upstream servers back into resolv.conf.
+func (s *Server) restoreResolvConf() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.StubResolvConf || len(s.upstreams) == 0 {
+		return
+	}
+	rc, err := ReadResolvConf(s.ResolvConf)
+	if err != nil {
+		s.Logf("dns: %v", err)
+		return
+	}
+	if !slices.Equal(rc.Nameservers, []netip.Addr{s.addr}) {
+		// Someone else wrote it since.
+		return
+	}
+	rc.Nameservers = nil
+	for _, u := range s.upstreams {
+		rc.Nameservers = append(rc.Nameservers, u.Addr.Addr())
+	}
+	if err := rc.Write(s.ResolvConf); err != nil {
+		s.Logf("dns: %v", err)
+	}
+}
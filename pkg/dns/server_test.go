@@ -0,0 +1,267 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dns
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const testHosts = `# comment
+127.0.0.1	localhost
+::1		localhost ip6-localhost
+192.0.2.5	Router.lan router # trailing
+bogus		ignored
+`
+
+func TestParseHosts(t *testing.T) {
+	h, err := ParseHosts(strings.NewReader(testHosts))
+	if err != nil {
+		t.Fatal(err)
+	}
+	addrs, ok := h.Lookup("LOCALHOST")
+	if want := []netip.Addr{netip.MustParseAddr("127.0.0.1"), netip.MustParseAddr("::1")}; !ok || !slices.Equal(addrs, want) {
+		t.Errorf("Lookup(localhost) = %v, %t, want %v", addrs, ok, want)
+	}
+	if _, ok := h.Lookup("ignored"); ok {
+		t.Errorf("Lookup(ignored) found the name of an invalid line")
+	}
+	if got, want := h.LookupAddr(netip.MustParseAddr("::ffff:192.0.2.5")), []string{"router.lan.", "router."}; !slices.Equal(got, want) {
+		t.Errorf("LookupAddr = %v, want %v", got, want)
+	}
+}
+
+func TestParseResolvConf(t *testing.T) {
+	rc, err := ParseResolvConf(strings.NewReader("# dhclient\ndomain lan\nnameserver 192.0.2.1\nnameserver fe80::1%eth0\nnameserver bogus\nsearch lan example.com ; old\noptions ndots:2\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &ResolvConf{
+		Nameservers: []netip.Addr{netip.MustParseAddr("192.0.2.1"), netip.MustParseAddr("fe80::1%eth0")},
+		Search:      []string{"lan", "example.com"},
+		Domain:      "lan",
+	}
+	if !slices.Equal(rc.Nameservers, want.Nameservers) || !slices.Equal(rc.Search, want.Search) || rc.Domain != want.Domain {
+		t.Errorf("ParseResolvConf = %+v, want %+v", rc, want)
+	}
+}
+
+func TestCache(t *testing.T) {
+	c := newCache(2)
+	now := time.Unix(1000, 0)
+	c.now = func() time.Time { return now }
+
+	q := func(name string) cacheKey {
+		return newCacheKey(dnsmessage.Question{Name: dnsmessage.MustNewName(name), Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET}, false, false)
+	}
+	c.put(q("a.example."), &dnsmessage.Message{Answers: []dnsmessage.Resource{aRecord("a.example.", 60, "192.0.2.1"), aRecord("a.example.", 30, "192.0.2.2")}})
+	soa := dnsmessage.Resource{
+		Header: dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName("example."), Type: dnsmessage.TypeSOA, Class: dnsmessage.ClassINET, TTL: 3600},
+		Body:   &dnsmessage.SOAResource{NS: dnsmessage.MustNewName("ns.example."), MBox: dnsmessage.MustNewName("root.example."), MinTTL: 10},
+	}
+	c.put(q("nx.example."), &dnsmessage.Message{Header: dnsmessage.Header{RCode: dnsmessage.RCodeNameError}, Authorities: []dnsmessage.Resource{soa}})
+	c.put(q("fail.example."), &dnsmessage.Message{Header: dnsmessage.Header{RCode: dnsmessage.RCodeServerFailure}})
+	if _, ok := c.get(q("fail.example.")); ok {
+		t.Errorf("SERVFAIL was cached")
+	}
+
+	now = now.Add(20 * time.Second)
+	m, ok := c.get(q("A.Example."))
+	if !ok || m.Answers[0].Header.TTL != 40 || m.Answers[1].Header.TTL != 10 {
+		t.Errorf("get after 20s = %v, %t, want TTLs 40 and 10", m, ok)
+	}
+	if _, ok := c.get(q("nx.example.")); ok {
+		t.Errorf("negative answer outlived the SOA minimum")
+	}
+	now = now.Add(10 * time.Second)
+	if _, ok := c.get(q("a.example.")); ok {
+		t.Errorf("answer outlived its lowest TTL")
+	}
+
+	for _, name := range []string{"1.example.", "2.example.", "3.example."} {
+		c.put(q(name), &dnsmessage.Message{Answers: []dnsmessage.Resource{aRecord(name, 60, "192.0.2.1")}})
+	}
+	if len(c.entries) != 2 {
+		t.Errorf("cache has %d entries, want 2", len(c.entries))
+	}
+}
+
+// startServer serves s on a local port and returns the upstream to query it.
+func startServer(t *testing.T, s *Server) Upstream {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pc, err := net.ListenPacket("udp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Serve(ctx, pc, ln)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return Upstream{Addr: netip.MustParseAddrPort(ln.Addr().String())}
+}
+
+func TestServer(t *testing.T) {
+	ts := newTestServer(t, func(q *dnsmessage.Message, tcp bool) *dnsmessage.Message {
+		switch q.Questions[0].Name.String() {
+		case "www.example.":
+			return &dnsmessage.Message{Header: dnsmessage.Header{AuthenticData: true}, Answers: []dnsmessage.Resource{aRecord("www.example.", 300, "192.0.2.80")}}
+		case "big.example.":
+			m := &dnsmessage.Message{}
+			for i := range 100 {
+				m.Answers = append(m.Answers, aRecord("big.example.", 300, netip.AddrFrom4([4]byte{192, 0, 2, byte(i)}).String()))
+			}
+			return m
+		}
+		return &dnsmessage.Message{Header: dnsmessage.Header{RCode: dnsmessage.RCodeNameError}}
+	})
+	hosts := filepath.Join(t.TempDir(), "hosts")
+	if err := os.WriteFile(hosts, []byte(testHosts), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	dead, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dead.Close()
+
+	s := &Server{
+		// The first upstream does not answer, so queries go to the
+		// second.
+		Upstreams: []Upstream{{Addr: netip.MustParseAddrPort(dead.LocalAddr().String())}, {Addr: ts.addr}},
+		HostsFile: hosts,
+		Client:    Client{Timeout: time.Second},
+		Logf:      t.Logf,
+	}
+	stub := startServer(t, s)
+
+	query := func(name string, typ dnsmessage.Type, dnssec, tcp bool) *Response {
+		t.Helper()
+		q, err := NewQuery(name, typ, dnssec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c := Client{TCP: tcp}
+		r, err := c.Exchange(context.Background(), q, stub)
+		if err != nil {
+			t.Fatalf("%s %s: %v", name, TypeString(typ), err)
+		}
+		return r
+	}
+
+	for _, tt := range []struct {
+		name    string
+		typ     dnsmessage.Type
+		dnssec  bool
+		rcode   dnsmessage.RCode
+		answers []string
+		ad      bool
+	}{
+		{name: "router", typ: dnsmessage.TypeA, answers: []string{"192.0.2.5"}},
+		{name: "localhost", typ: dnsmessage.TypeAAAA, answers: []string{"::1"}},
+		{name: "router.lan", typ: dnsmessage.TypeAAAA},
+		{name: "5.2.0.192.in-addr.arpa", typ: dnsmessage.TypePTR, answers: []string{"router.lan.", "router."}},
+		{name: "www.example", typ: dnsmessage.TypeA, answers: []string{"192.0.2.80"}},
+		{name: "www.example", typ: dnsmessage.TypeA, dnssec: true, answers: []string{"192.0.2.80"}, ad: true},
+		{name: "nx.example", typ: dnsmessage.TypeA, rcode: dnsmessage.RCodeNameError},
+	} {
+		r := query(tt.name, tt.typ, tt.dnssec, false)
+		var answers []string
+		for _, a := range r.Answers {
+			answers = append(answers, FormatData(a.Body))
+		}
+		if r.Header.RCode != tt.rcode || !slices.Equal(answers, tt.answers) || r.Header.AuthenticData != tt.ad {
+			t.Errorf("%s %s = %s %v ad %t, want %s %v ad %t", tt.name, TypeString(tt.typ), RCodeString(r.Header.RCode), answers, r.Header.AuthenticData, RCodeString(tt.rcode), tt.answers, tt.ad)
+		}
+	}
+
+	n := ts.queries.Load()
+	query("www.example", dnsmessage.TypeA, false, false)
+	if got := ts.queries.Load(); got != n {
+		t.Errorf("cached answer was forwarded")
+	}
+
+	// 100 records do not fit in the advertised 1232 bytes.
+	if r := query("big.example", dnsmessage.TypeA, false, false); r.Transport != "TCP" || len(r.Answers) != 100 {
+		t.Errorf("big answer came over %s with %d records, want TCP with 100", r.Transport, len(r.Answers))
+	}
+}
+
+func TestServerServerFailure(t *testing.T) {
+	ts := newTestServer(t, func(q *dnsmessage.Message, tcp bool) *dnsmessage.Message {
+		return &dnsmessage.Message{Header: dnsmessage.Header{RCode: dnsmessage.RCodeRefused}}
+	})
+	stub := startServer(t, &Server{Upstreams: []Upstream{{Addr: ts.addr}}, Logf: t.Logf})
+	q, err := NewQuery("www.example", dnsmessage.TypeA, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var c Client
+	r, err := c.Exchange(context.Background(), q, stub)
+	if err != nil || r.Header.RCode != dnsmessage.RCodeRefused {
+		t.Errorf("Exchange = %v, %v, want REFUSED", r, err)
+	}
+}
+
+func TestStubResolvConf(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "resolv.conf")
+	if err := os.WriteFile(path, []byte("domain lan\nnameserver 192.0.2.1\nnameserver 192.0.2.2\nsearch lan\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{ResolvConf: path, StubResolvConf: true, Logf: t.Logf}
+	s.init()
+	s.addr = netip.MustParseAddr("127.0.0.53")
+
+	want := []Upstream{{Addr: netip.MustParseAddrPort("192.0.2.1:53")}, {Addr: netip.MustParseAddrPort("192.0.2.2:53")}}
+	s.reload()
+	if got := s.servers(); !slices.Equal(got, want) {
+		t.Errorf("upstreams = %v, want %v", got, want)
+	}
+	stubbed := "domain lan\nnameserver 127.0.0.53\nsearch lan\n"
+	if b, err := os.ReadFile(path); err != nil || string(b) != stubbed {
+		t.Errorf("resolv.conf = %q, %v, want %q", b, err, stubbed)
+	}
+
+	// Rereading our own file keeps the upstreams.
+	s.stamps = map[string]stamp{}
+	s.reload()
+	if got := s.servers(); !slices.Equal(got, want) {
+		t.Errorf("upstreams after reload = %v, want %v", got, want)
+	}
+
+	// DHCP writes new servers.
+	if err := os.WriteFile(path, []byte("nameserver 198.51.100.1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s.stamps = map[string]stamp{}
+	s.reload()
+	want = []Upstream{{Addr: netip.MustParseAddrPort("198.51.100.1:53")}}
+	if got := s.servers(); !slices.Equal(got, want) {
+		t.Errorf("upstreams after DHCP = %v, want %v", got, want)
+	}
+
+	s.restoreResolvConf()
+	if b, err := os.ReadFile(path); err != nil || string(b) != "nameserver 198.51.100.1\n" {
+		t.Errorf("restored resolv.conf = %q, %v", b, err)
+	}
+}
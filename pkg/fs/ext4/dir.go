@@ -0,0 +1,175 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ext4
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+	"syscall"
+)
+
+// Directory entry file types.
+var direntTypes = map[uint8]fs.FileMode{
+	1: 0,
+	2: fs.ModeDir,
+	3: fs.ModeDevice | fs.ModeCharDevice,
+	4: fs.ModeDevice,
+	5: fs.ModeNamedPipe,
+	6: fs.ModeSocket,
+	7: fs.ModeSymlink,
+}
+
+// maxSymlinks is the number of symlinks followed while resolving a path,
+// as in Linux.
+const maxSymlinks = 40
+
+type dirent struct {
+	name string
+	ino  uint32
+	// typ is the file type, if the file system records it.
+	typ   fs.FileMode
+	typOK bool
+}
+
+// parseDirents decodes the linear directory entries in b. The entries of
+// hash tree index blocks and checksum tails have inode 0 and are skipped,
+// which makes every directory readable as a linear directory. If dots is
+// set, b starts with the . and .. entries, which are skipped as well.
+// Names with a slash, and . or .. anywhere else, make the directory
+// corrupt.
+func (fsys *FS) parseDirents(b []byte, ents []dirent, dots bool) ([]dirent, error) {
+	for i, off := 0, 0; off+8 <= len(b); i++ {
+		ino := le.Uint32(b[off:])
+		recLen := int(le.Uint16(b[off+4:]))
+		// 64KiB blocks encode a record spanning the block as 0 or
+		// 65535.
+		if fsys.blockSize == 65536 && (recLen == 0 || recLen == 65535) {
+			recLen = 65536
+		}
+		nameLen := int(b[off+6])
+		if recLen < 8 || off+recLen > len(b) || 8+nameLen > recLen {
+			return nil, fmt.Errorf("corrupt directory entry at offset %d", off)
+		}
+		if ino != 0 && nameLen > 0 {
+			name := string(b[off+8 : off+8+nameLen])
+			switch {
+			case dots && (i == 0 && name == "." || i == 1 && name == ".."):
+			case name == "." || name == ".." || strings.ContainsAny(name, "/\x00"):
+				return nil, fmt.Errorf("corrupt directory entry %q at offset %d", name, off)
+			default:
+				d := dirent{name: name, ino: ino}
+				if fsys.sb.featureIncompat&incompatFiletype != 0 {
+					d.typ, d.typOK = direntTypes[b[off+7]]
+				}
+				ents = append(ents, d)
+			}
+		}
+		off += recLen
+	}
+	return ents, nil
+}
+
+// readDir returns the entries of directory in, except . and ..
+func (fsys *FS) readDir(in *inode) ([]dirent, error) {
+	if in.flags&flagEncrypt != 0 {
+		return nil, fmt.Errorf("%w: encrypted directory", ErrUnsupported)
+	}
+	if in.flags&flagInlineData != 0 {
+		// Inline directories start with the parent inode number
+		// rather than . and .. entries.
+		b, err := in.inlineData()
+		if err != nil {
+			return nil, err
+		}
+		if len(b) < 4 {
+			return nil, fmt.Errorf("inode %d: inline directory too short", in.ino)
+		}
+		ents, err := fsys.parseDirents(b[4:min(len(b), iBlockSize)], nil, false)
+		if err != nil || len(b) <= iBlockSize {
+			return ents, err
+		}
+		return fsys.parseDirents(b[iBlockSize:], ents, false)
+	}
+	b, err := fsys.readAll(in)
+	if err != nil {
+		return nil, err
+	}
+	return fsys.parseDirents(b, nil, true)
+}
+
+// lookup finds name in directory in.
+func (fsys *FS) lookup(in *inode, name string) (*inode, error) {
+	ents, err := fsys.readDir(in)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range ents {
+		if e.name == name {
+			return fsys.inode(e.ino)
+		}
+	}
+	return nil, fs.ErrNotExist
+}
+
+// walk resolves name to an inode. Symlinks are resolved within the file
+// system, with absolute targets relative to its root. A symlink as the
+// last element is only followed if follow is set.
+func (fsys *FS) walk(op, name string, follow bool) (*inode, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	root, err := fsys.inode(rootIno)
+	if err != nil {
+		return nil, &fs.PathError{Op: op, Path: name, Err: err}
+	}
+	// stack holds the inodes of the directories walked so far, so
+	// that .. in symlink targets can be resolved.
+	stack := []*inode{root}
+	var elems []string
+	if name != "." {
+		elems = strings.Split(name, "/")
+	}
+	links := 0
+	for len(elems) > 0 {
+		e := elems[0]
+		elems = elems[1:]
+		switch e {
+		case "", ".":
+			continue
+		case "..":
+			if len(stack) > 1 {
+				stack = stack[:len(stack)-1]
+			}
+			continue
+		}
+		dir := stack[len(stack)-1]
+		if !dir.isDir() {
+			return nil, &fs.PathError{Op: op, Path: name, Err: syscall.ENOTDIR}
+		}
+		in, err := fsys.lookup(dir, e)
+		if err != nil {
+			return nil, &fs.PathError{Op: op, Path: name, Err: err}
+		}
+		if in.isSymlink() && (len(elems) > 0 || follow) {
+			links++
+			if links > maxSymlinks {
+				return nil, &fs.PathError{Op: op, Path: name, Err: syscall.ELOOP}
+			}
+			target, err := fsys.readlink(in)
+			if err != nil {
+				return nil, &fs.PathError{Op: op, Path: name, Err: err}
+			}
+			if path.IsAbs(target) {
+				stack = stack[:1]
+			}
+			elems = append(strings.Split(target, "/"), elems...)
+			continue
+		}
+		stack = append(stack, in)
+	}
+	return stack[len(stack)-1], nil
+}
@@ -0,0 +1,285 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package ext4 reads ext2, ext3 and ext4 file systems, and creates ext4
+// file systems, without kernel support.
+//
+// FS implements io/fs.FS, as well as fs.ReadDirFS, fs.ReadFileFS,
+// fs.StatFS and fs.ReadLinkFS, on top of an io.ReaderAt such as a block
+// device or an image file. It supports extents, block maps, 64-bit block
+// numbers, flexible and meta block groups, inline data and hash indexed
+// (htree) directories, which are read through their linear leaf blocks.
+//
+// The journal is not replayed, so a file system that was not cleanly
+// unmounted may appear as it was before the last transactions.
+// Checksums are not verified.
+//
+// Format creates a new, empty file system.
+package ext4
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	superblockOffset = 1024
+	superblockSize   = 1024
+	magic            = 0xef53
+
+	rootIno = 2
+)
+
+// Compatible feature flags.
+const (
+	compatHasJournal = 0x4
+)
+
+// Read-only compatible feature flags.
+const (
+	roCompatSparseSuper = 0x1
+)
+
+// Incompatible feature flags.
+const (
+	incompatFiletype   = 0x2
+	incompatRecover    = 0x4
+	incompatJournalDev = 0x8
+	incompatMetaBG     = 0x10
+	incompatExtents    = 0x40
+	incompat64Bit      = 0x80
+	incompatMMP        = 0x100
+	incompatFlexBG     = 0x200
+	incompatEAInode    = 0x400
+	incompatDirData    = 0x1000
+	incompatCsumSeed   = 0x2000
+	incompatLargeDir   = 0x4000
+	incompatInlineData = 0x8000
+	incompatEncrypt    = 0x10000
+	incompatCasefold   = 0x20000
+
+	// supportedIncompat are the incompatible features FS can read.
+	// Encrypted and casefolded directories are refused individually.
+	supportedIncompat = incompatFiletype | incompatRecover | incompatMetaBG |
+		incompatExtents | incompat64Bit | incompatMMP | incompatFlexBG |
+		incompatEAInode | incompatCsumSeed | incompatLargeDir |
+		incompatInlineData | incompatEncrypt | incompatCasefold
+)
+
+var (
+	// ErrBadMagic is returned by New if there is no ext2/3/4 file system.
+	ErrBadMagic = errors.New("no ext2/3/4 superblock found")
+	// ErrUnsupported is returned for features FS cannot read.
+	ErrUnsupported = errors.New("unsupported ext4 feature")
+)
+
+var le = binary.LittleEndian
+
+// superblock holds the superblock fields FS uses.
+type superblock struct {
+	inodesCount     uint32
+	blocksCount     uint64
+	firstDataBlock  uint32
+	logBlockSize    uint32
+	blocksPerGroup  uint32
+	inodesPerGroup  uint32
+	inodeSize       uint16
+	featureCompat   uint32
+	featureIncompat uint32
+	featureROCompat uint32
+	uuid            [16]byte
+	volumeName      string
+	descSize        uint16
+	firstMetaBG     uint32
+}
+
+func parseSuperblock(b []byte) (*superblock, error) {
+	if le.Uint16(b[0x38:]) != magic {
+		return nil, ErrBadMagic
+	}
+	sb := &superblock{
+		inodesCount:     le.Uint32(b[0x0:]),
+		blocksCount:     uint64(le.Uint32(b[0x4:])),
+		firstDataBlock:  le.Uint32(b[0x14:]),
+		logBlockSize:    le.Uint32(b[0x18:]),
+		blocksPerGroup:  le.Uint32(b[0x20:]),
+		inodesPerGroup:  le.Uint32(b[0x28:]),
+		inodeSize:       128,
+		featureCompat:   le.Uint32(b[0x5c:]),
+		featureIncompat: le.Uint32(b[0x60:]),
+		featureROCompat: le.Uint32(b[0x64:]),
+		volumeName:      strings.TrimRight(string(b[0x78:0x88]), "\x00"),
+		descSize:        32,
+		firstMetaBG:     le.Uint32(b[0x104:]),
+	}
+	copy(sb.uuid[:], b[0x68:0x78])
+	// Revision 0 file systems have fixed 128 byte inodes.
+	if le.Uint32(b[0x4c:]) > 0 {
+		sb.inodeSize = le.Uint16(b[0x58:])
+	}
+	if sb.featureIncompat&incompat64Bit != 0 {
+		sb.blocksCount |= uint64(le.Uint32(b[0x150:])) << 32
+		sb.descSize = le.Uint16(b[0xfe:])
+	}
+
+	if f := sb.featureIncompat &^ supportedIncompat; f != 0 {
+		return nil, fmt.Errorf("%w: incompatible features %#x", ErrUnsupported, f)
+	}
+	if sb.logBlockSize > 6 {
+		return nil, fmt.Errorf("block size 1024<<%d is too large", sb.logBlockSize)
+	}
+	bs := uint32(1024) << sb.logBlockSize
+	if sb.inodeSize < 128 || sb.inodeSize&(sb.inodeSize-1) != 0 || uint32(sb.inodeSize) > bs {
+		return nil, fmt.Errorf("invalid inode size %d", sb.inodeSize)
+	}
+	if sb.descSize < 32 || sb.descSize&(sb.descSize-1) != 0 || uint32(sb.descSize) > bs {
+		return nil, fmt.Errorf("invalid group descriptor size %d", sb.descSize)
+	}
+	if sb.blocksPerGroup == 0 || sb.inodesPerGroup == 0 {
+		return nil, fmt.Errorf("invalid group geometry: %d blocks and %d inodes per group", sb.blocksPerGroup, sb.inodesPerGroup)
+	}
+	if uint64(sb.firstDataBlock) >= sb.blocksCount {
+		return nil, fmt.Errorf("first data block %d beyond %d blocks", sb.firstDataBlock, sb.blocksCount)
+	}
+	return sb, nil
+}
+
+// FS is a read-only ext2, ext3 or ext4 file system.
+type FS struct {
+	r          io.ReaderAt
+	sb         *superblock
+	blockSize  uint64
+	groups     uint32
+	descPerBlk uint32
+}
+
+// New reads the file system from r.
+func New(r io.ReaderAt) (*FS, error) {
+	b := make([]byte, superblockSize)
+	if _, err := r.ReadAt(b, superblockOffset); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, ErrBadMagic
+		}
+		return nil, err
+	}
+	sb, err := parseSuperblock(b)
+	if err != nil {
+		return nil, err
+	}
+	bs := uint64(1024) << sb.logBlockSize
+	fsys := &FS{
+		r:          r,
+		sb:         sb,
+		blockSize:  bs,
+		groups:     uint32((sb.blocksCount - uint64(sb.firstDataBlock) + uint64(sb.blocksPerGroup) - 1) / uint64(sb.blocksPerGroup)),
+		descPerBlk: uint32(bs / uint64(sb.descSize)),
+	}
+	root, err := fsys.inode(rootIno)
+	if err != nil {
+		return nil, fmt.Errorf("reading root directory: %w", err)
+	}
+	if !root.isDir() {
+		return nil, fmt.Errorf("root inode is not a directory (mode %#o)", root.mode)
+	}
+	return fsys, nil
+}
+
+// Label returns the volume label.
+func (fsys *FS) Label() string {
+	return fsys.sb.volumeName
+}
+
+// UUID returns the file system UUID in its canonical text form.
+func (fsys *FS) UUID() string {
+	u := fsys.sb.uuid
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16])
+}
+
+// Type returns ext2, ext3 or ext4, following the features in use.
+func (fsys *FS) Type() string {
+	switch {
+	case fsys.sb.featureIncompat&^(incompatFiletype|incompatRecover|incompatMetaBG) != 0:
+		return "ext4"
+	case fsys.sb.featureCompat&compatHasJournal != 0:
+		return "ext3"
+	}
+	return "ext2"
+}
+
+// BlockSize returns the file system block size.
+func (fsys *FS) BlockSize() int {
+	return int(fsys.blockSize)
+}
+
+func (fsys *FS) readBlock(blk uint64) ([]byte, error) {
+	if blk >= fsys.sb.blocksCount {
+		return nil, fmt.Errorf("block %d beyond end of file system (%d blocks)", blk, fsys.sb.blocksCount)
+	}
+	b := make([]byte, fsys.blockSize)
+	if _, err := fsys.r.ReadAt(b, int64(blk*fsys.blockSize)); err != nil {
+		return nil, fmt.Errorf("reading block %d: %w", blk, err)
+	}
+	return b, nil
+}
+
+// hasSuper returns true if group g holds a superblock backup, which is
+// always the case without the sparse_super feature.
+func (fsys *FS) hasSuper(g uint32) bool {
+	return fsys.sb.featureROCompat&roCompatSparseSuper == 0 || sparseGroup(g)
+}
+
+// sparseGroup returns true if group g holds a superblock backup with the
+// sparse_super feature: groups 0 and 1 and powers of 3, 5 and 7.
+func sparseGroup(g uint32) bool {
+	if g <= 1 {
+		return true
+	}
+	for _, p := range []uint32{3, 5, 7} {
+		n := p
+		for n < g {
+			n *= p
+		}
+		if n == g {
+			return true
+		}
+	}
+	return false
+}
+
+// descBlock returns the block holding the group descriptor of group g.
+func (fsys *FS) descBlock(g uint32) uint64 {
+	i := g / fsys.descPerBlk
+	first := uint64(fsys.sb.firstDataBlock)
+	if fsys.sb.featureIncompat&incompatMetaBG == 0 || i < fsys.sb.firstMetaBG {
+		return first + 1 + uint64(i)
+	}
+	// With meta_bg, each group of descPerBlk groups keeps its
+	// descriptors in its first group.
+	mg := i * fsys.descPerBlk
+	b := first + uint64(mg)*uint64(fsys.sb.blocksPerGroup)
+	if fsys.hasSuper(mg) {
+		b++
+	}
+	return b
+}
+
+// inodeTable returns the first block of the inode table of group g.
+func (fsys *FS) inodeTable(g uint32) (uint64, error) {
+	if g >= fsys.groups {
+		return 0, fmt.Errorf("group %d beyond %d groups", g, fsys.groups)
+	}
+	b, err := fsys.readBlock(fsys.descBlock(g))
+	if err != nil {
+		return 0, err
+	}
+	d := b[uint64(g%fsys.descPerBlk)*uint64(fsys.sb.descSize):]
+	t := uint64(le.Uint32(d[0x8:]))
+	if fsys.sb.descSize >= 64 {
+		t |= uint64(le.Uint32(d[0x28:])) << 32
+	}
+	return t, nil
+}
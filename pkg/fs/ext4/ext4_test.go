@@ -0,0 +1,215 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ext4
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// The images are generated by testdata/mkimages.sh.
+var images = []struct {
+	name string
+	typ  string
+}{
+	{"ext4", "ext4"},
+	{"ext2", "ext2"},
+	{"inline", "ext4"},
+	{"metabg", "ext4"},
+}
+
+func openImage(t *testing.T, name string) *FS {
+	t.Helper()
+	f, err := os.Open(filepath.Join("testdata", name+".img.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	z, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := io.ReadAll(z)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsys, err := New(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("New(%s): %v", name, err)
+	}
+	return fsys
+}
+
+func TestImages(t *testing.T) {
+	var lines strings.Builder
+	for i := range 3000 {
+		fmt.Fprintf(&lines, "line %d\n", i)
+	}
+	for _, img := range images {
+		t.Run(img.name, func(t *testing.T) {
+			fsys := openImage(t, img.name)
+			if fsys.Type() != img.typ || fsys.Label() != img.name || fsys.UUID() != "8a8a3b2e-8d2c-4b3d-9d0a-2f3c6f1e7b11" {
+				t.Errorf("got type %q, label %q, uuid %q", fsys.Type(), fsys.Label(), fsys.UUID())
+			}
+
+			for name, want := range map[string]string{
+				"hello.txt":                      "hello, world\n",
+				"dir/sub/nested.txt":             "nested\n",
+				"lines.txt":                      lines.String(),
+				"many/file-with-a-long-name-123": "123\n",
+				"link":                           "hello.txt",
+				"abs/nested.txt":                 "nested\n",
+				"dir/sub/up":                     "hello, world\n",
+				"long":                           "nested\n",
+			} {
+				if name == "link" {
+					got, err := fsys.ReadLink(name)
+					if err != nil || got != want {
+						t.Errorf("ReadLink(%q) = %q, %v, want %q", name, got, err, want)
+					}
+					continue
+				}
+				got, err := fsys.ReadFile(name)
+				if err != nil || string(got) != want {
+					t.Errorf("ReadFile(%q) = %.40q, %v, want %.40q", name, got, err, want)
+				}
+			}
+
+			fi, err := fsys.Stat("link")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fi.Mode() != fs.ModeSetuid|0o755 || !fi.ModTime().Equal(time.Unix(1700000000, 0)) || fi.Size() != 13 {
+				t.Errorf("Stat(link): mode %v, mtime %v, size %d", fi.Mode(), fi.ModTime(), fi.Size())
+			}
+			if fi, err := fsys.Lstat("link"); err != nil || fi.Mode().Type() != fs.ModeSymlink {
+				t.Errorf("Lstat(link) = %v, %v, want a symlink", fi, err)
+			}
+			if _, err := fsys.Open("loop"); !errors.Is(err, syscall.ELOOP) {
+				t.Errorf("Open(loop): got %v, want ELOOP", err)
+			}
+			if _, err := fsys.Open("hello.txt/x"); !errors.Is(err, syscall.ENOTDIR) {
+				t.Errorf("Open(hello.txt/x): got %v, want ENOTDIR", err)
+			}
+			if _, err := fsys.Open("missing"); !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("Open(missing): got %v, want ErrNotExist", err)
+			}
+
+			ents, err := fsys.ReadDir("many")
+			if err != nil || len(ents) != 400 {
+				t.Fatalf("ReadDir(many): %d entries, %v, want 400", len(ents), err)
+			}
+
+			sparse, err := fsys.ReadFile("sparse")
+			if err != nil || len(sparse) != 3000000 {
+				t.Fatalf("ReadFile(sparse): %d bytes, %v", len(sparse), err)
+			}
+			want := make([]byte, 3000000)
+			copy(want[100000:], "start")
+			copy(want[2000000:], "middle")
+			copy(want[2999997:], "end")
+			if !bytes.Equal(sparse, want) {
+				t.Errorf("sparse file contents differ")
+			}
+
+			islands, err := fsys.ReadFile("islands")
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := range 12 {
+				w := fmt.Sprintf("island %d", i)
+				if got := string(islands[i*65536 : i*65536+len(w)]); got != w {
+					t.Errorf("islands at %d: got %q, want %q", i*65536, got, w)
+				}
+			}
+
+			// The root holds a symlink loop, which fstest cannot walk.
+			for dir, want := range map[string]string{"dir": "sub/nested.txt", "many": "file-with-a-long-name-7"} {
+				sub, err := fs.Sub(fsys, dir)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := fstest.TestFS(sub, want); err != nil {
+					t.Error(err)
+				}
+			}
+		})
+	}
+}
+
+func TestSeek(t *testing.T) {
+	fsys := openImage(t, "ext4")
+	f, err := fsys.Open("lines.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	s := f.(io.ReadSeeker)
+	if _, err := s.Seek(-10, io.SeekEnd); err != nil {
+		t.Fatal(err)
+	}
+	b, err := io.ReadAll(s)
+	if err != nil || string(b) != "line 2999\n" {
+		t.Errorf("reading the end: got %q, %v", b, err)
+	}
+}
+
+func TestBadImages(t *testing.T) {
+	if _, err := New(bytes.NewReader(make([]byte, 4096))); !errors.Is(err, ErrBadMagic) {
+		t.Errorf("New(zeros): got %v, want ErrBadMagic", err)
+	}
+	if _, err := New(bytes.NewReader(nil)); !errors.Is(err, ErrBadMagic) {
+		t.Errorf("New(empty): got %v, want ErrBadMagic", err)
+	}
+	sb := make([]byte, 4096)
+	le.PutUint16(sb[superblockOffset+0x38:], magic)
+	le.PutUint32(sb[superblockOffset+0x60:], incompatJournalDev)
+	if _, err := New(bytes.NewReader(sb)); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("New(journal device): got %v, want ErrUnsupported", err)
+	}
+}
+
+func TestBadDirents(t *testing.T) {
+	dirent := func(ino uint32, name string) []byte {
+		b := make([]byte, 8+(len(name)+3)&^3)
+		le.PutUint32(b, ino)
+		le.PutUint16(b[4:], uint16(len(b)))
+		b[6] = byte(len(name))
+		copy(b[8:], name)
+		return b
+	}
+	dots := slices.Concat(dirent(2, "."), dirent(2, ".."))
+	fsys := &FS{blockSize: 4096, sb: &superblock{}}
+	ents, err := fsys.parseDirents(slices.Concat(dots, dirent(12, "boot")), nil, true)
+	if err != nil || len(ents) != 1 || ents[0].name != "boot" {
+		t.Errorf("parseDirents(., .., boot) = %v, %v, want boot", ents, err)
+	}
+	for _, b := range [][]byte{
+		slices.Concat(dots, dirent(12, "../etc")),
+		slices.Concat(dots, dirent(12, "a/b")),
+		slices.Concat(dots, dirent(12, "..")),
+		slices.Concat(dots, dirent(12, ".")),
+		slices.Concat(dirent(2, ".."), dirent(2, ".")),
+	} {
+		if ents, err := fsys.parseDirents(b, nil, true); err == nil {
+			t.Errorf("parseDirents(%q) = %v, want an error", b, ents)
+		}
+	}
+	if ents, err := fsys.parseDirents(dots, nil, false); err == nil {
+		t.Errorf("parseDirents(., ..) of an inline directory = %v, want an error", ents)
+	}
+}
@@ -0,0 +1,249 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ext4
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+)
+
+var (
+	_ fs.ReadDirFS   = (*FS)(nil)
+	_ fs.ReadFileFS  = (*FS)(nil)
+	_ fs.StatFS      = (*FS)(nil)
+	_ fs.ReadLinkFS  = (*FS)(nil)
+	_ io.ReaderAt    = (*file)(nil)
+	_ io.Seeker      = (*file)(nil)
+	_ fs.ReadDirFile = (*dir)(nil)
+)
+
+// Open implements fs.FS. Symlinks are followed.
+func (fsys *FS) Open(name string) (fs.File, error) {
+	in, err := fsys.walk("open", name, true)
+	if err != nil {
+		return nil, err
+	}
+	fi := &fileInfo{name: path.Base(name), in: in}
+	if in.isDir() {
+		ents, err := fsys.readDir(in)
+		if err != nil {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+		}
+		return &dir{fsys: fsys, fi: fi, ents: ents}, nil
+	}
+	return &file{fsys: fsys, fi: fi}, nil
+}
+
+// ReadDir implements fs.ReadDirFS. The entries are sorted by name.
+func (fsys *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	in, err := fsys.walk("readdir", name, true)
+	if err != nil {
+		return nil, err
+	}
+	if !in.isDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: syscall.ENOTDIR}
+	}
+	ents, err := fsys.readDir(in)
+	if err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+	}
+	return fsys.dirEntries(ents), nil
+}
+
+// ReadFile implements fs.ReadFileFS.
+func (fsys *FS) ReadFile(name string) ([]byte, error) {
+	in, err := fsys.walk("read", name, true)
+	if err != nil {
+		return nil, err
+	}
+	if in.isDir() {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: syscall.EISDIR}
+	}
+	b, err := fsys.readAll(in)
+	if err != nil {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: err}
+	}
+	return b, nil
+}
+
+// Stat implements fs.StatFS. Symlinks are followed.
+func (fsys *FS) Stat(name string) (fs.FileInfo, error) {
+	in, err := fsys.walk("stat", name, true)
+	if err != nil {
+		return nil, err
+	}
+	return &fileInfo{name: path.Base(name), in: in}, nil
+}
+
+// Lstat implements fs.ReadLinkFS. A final symlink is not followed.
+func (fsys *FS) Lstat(name string) (fs.FileInfo, error) {
+	in, err := fsys.walk("lstat", name, false)
+	if err != nil {
+		return nil, err
+	}
+	return &fileInfo{name: path.Base(name), in: in}, nil
+}
+
+// ReadLink implements fs.ReadLinkFS.
+func (fsys *FS) ReadLink(name string) (string, error) {
+	in, err := fsys.walk("readlink", name, false)
+	if err != nil {
+		return "", err
+	}
+	if !in.isSymlink() {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: fs.ErrInvalid}
+	}
+	target, err := fsys.readlink(in)
+	if err != nil {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: err}
+	}
+	return target, nil
+}
+
+func (fsys *FS) dirEntries(ents []dirent) []fs.DirEntry {
+	des := make([]fs.DirEntry, 0, len(ents))
+	for _, e := range ents {
+		des = append(des, &dirEntry{fsys: fsys, d: e})
+	}
+	slices.SortFunc(des, func(a, b fs.DirEntry) int { return strings.Compare(a.Name(), b.Name()) })
+	return des
+}
+
+// fileInfo implements fs.FileInfo.
+type fileInfo struct {
+	name string
+	in   *inode
+}
+
+func (fi *fileInfo) Name() string       { return fi.name }
+func (fi *fileInfo) Size() int64        { return int64(fi.in.size) }
+func (fi *fileInfo) Mode() fs.FileMode  { return fi.in.fileMode() }
+func (fi *fileInfo) ModTime() time.Time { return fi.in.mtime }
+func (fi *fileInfo) IsDir() bool        { return fi.in.isDir() }
+
+// Sys returns the *Stat of the file.
+func (fi *fileInfo) Sys() any {
+	return &Stat{Ino: fi.in.ino, UID: fi.in.uid, GID: fi.in.gid, Nlink: fi.in.links}
+}
+
+// Stat is the underlying data source of a FileInfo returned by FS.
+type Stat struct {
+	Ino   uint32
+	UID   uint32
+	GID   uint32
+	Nlink uint16
+}
+
+// dirEntry implements fs.DirEntry.
+type dirEntry struct {
+	fsys *FS
+	d    dirent
+}
+
+func (de *dirEntry) Name() string { return de.d.name }
+
+func (de *dirEntry) IsDir() bool { return de.Type().IsDir() }
+
+func (de *dirEntry) Type() fs.FileMode {
+	if de.d.typOK {
+		return de.d.typ
+	}
+	// Without the filetype feature the type is in the inode.
+	fi, err := de.Info()
+	if err != nil {
+		return 0
+	}
+	return fi.Mode().Type()
+}
+
+func (de *dirEntry) Info() (fs.FileInfo, error) {
+	in, err := de.fsys.inode(de.d.ino)
+	if err != nil {
+		return nil, err
+	}
+	return &fileInfo{name: de.d.name, in: in}, nil
+}
+
+// file is an open file that is not a directory.
+type file struct {
+	fsys *FS
+	fi   *fileInfo
+	off  int64
+}
+
+func (f *file) Stat() (fs.FileInfo, error) { return f.fi, nil }
+func (f *file) Close() error               { return nil }
+
+func (f *file) Read(p []byte) (int, error) {
+	n, err := f.ReadAt(p, f.off)
+	f.off += int64(n)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+// ReadAt implements io.ReaderAt.
+func (f *file) ReadAt(p []byte, off int64) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if !f.fi.Mode().IsRegular() {
+		return 0, &fs.PathError{Op: "read", Path: f.fi.name, Err: errors.New("not a regular file")}
+	}
+	return f.fsys.readAt(f.fi.in, p, off)
+}
+
+// Seek implements io.Seeker.
+func (f *file) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.off
+	case io.SeekEnd:
+		offset += int64(f.fi.in.size)
+	default:
+		return 0, &fs.PathError{Op: "seek", Path: f.fi.name, Err: fs.ErrInvalid}
+	}
+	if offset < 0 {
+		return 0, &fs.PathError{Op: "seek", Path: f.fi.name, Err: fs.ErrInvalid}
+	}
+	f.off = offset
+	return offset, nil
+}
+
+// dir is an open directory.
+type dir struct {
+	fsys *FS
+	fi   *fileInfo
+	ents []dirent
+	off  int
+}
+
+func (d *dir) Stat() (fs.FileInfo, error) { return d.fi, nil }
+func (d *dir) Close() error               { return nil }
+
+func (d *dir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.fi.name, Err: syscall.EISDIR}
+}
+
+// ReadDir implements fs.ReadDirFile.
+func (d *dir) ReadDir(n int) ([]fs.DirEntry, error) {
+	rest := d.ents[d.off:]
+	if n > 0 {
+		if len(rest) == 0 {
+			return nil, io.EOF
+		}
+		rest = rest[:min(n, len(rest))]
+	}
+	d.off += len(rest)
+	return d.fsys.dirEntries(rest), nil
+}
@@ -0,0 +1,377 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ext4
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"time"
+)
+
+// Inode mode type bits.
+const (
+	modeTypeMask = 0xf000
+	modeFIFO     = 0x1000
+	modeChar     = 0x2000
+	modeDir      = 0x4000
+	modeBlock    = 0x6000
+	modeRegular  = 0x8000
+	modeSymlink  = 0xa000
+	modeSocket   = 0xc000
+)
+
+// Inode flags.
+const (
+	flagEncrypt    = 0x800
+	flagIndex      = 0x1000
+	flagExtents    = 0x80000
+	flagCasefold   = 0x40000000
+	flagInlineData = 0x10000000
+)
+
+const (
+	// iBlockSize is the size of the i_block array, which holds the
+	// block map, the extent tree root, inline data or a fast symlink.
+	iBlockSize = 60
+
+	extentMagic    = 0xf30a
+	maxExtentDepth = 5
+	// extentInitMax is the longest initialized extent; longer
+	// lengths mark uninitialized extents, which read as zeros.
+	extentInitMax = 32768
+
+	// xattrMagic starts the extended attributes stored in an inode.
+	xattrMagic       = 0xea020000
+	xattrIndexSystem = 7
+
+	// maxSymlinkLen is the longest symlink target Linux allows.
+	maxSymlinkLen = 4096
+)
+
+type inode struct {
+	ino   uint32
+	mode  uint16
+	uid   uint32
+	gid   uint32
+	links uint16
+	size  uint64
+	flags uint32
+	mtime time.Time
+	block []byte
+	// extra is the inode beyond the base 128 bytes, holding extended
+	// attributes.
+	extra []byte
+}
+
+func (in *inode) isDir() bool {
+	return in.mode&modeTypeMask == modeDir
+}
+
+func (in *inode) isSymlink() bool {
+	return in.mode&modeTypeMask == modeSymlink
+}
+
+// fileMode converts the inode mode to an fs.FileMode.
+func (in *inode) fileMode() fs.FileMode {
+	m := fs.FileMode(in.mode & 0o777)
+	if in.mode&0o4000 != 0 {
+		m |= fs.ModeSetuid
+	}
+	if in.mode&0o2000 != 0 {
+		m |= fs.ModeSetgid
+	}
+	if in.mode&0o1000 != 0 {
+		m |= fs.ModeSticky
+	}
+	switch in.mode & modeTypeMask {
+	case modeDir:
+		m |= fs.ModeDir
+	case modeSymlink:
+		m |= fs.ModeSymlink
+	case modeChar:
+		m |= fs.ModeDevice | fs.ModeCharDevice
+	case modeBlock:
+		m |= fs.ModeDevice
+	case modeFIFO:
+		m |= fs.ModeNamedPipe
+	case modeSocket:
+		m |= fs.ModeSocket
+	}
+	return m
+}
+
+// decodeTime decodes a 32-bit timestamp and its extra field, which holds
+// nanoseconds and two more bits of seconds.
+func decodeTime(sec, extra uint32) time.Time {
+	s := int64(int32(sec)) + int64(extra&3)<<32
+	return time.Unix(s, int64(extra>>2)).UTC()
+}
+
+func (fsys *FS) inode(ino uint32) (*inode, error) {
+	if ino == 0 || ino > fsys.sb.inodesCount {
+		return nil, fmt.Errorf("inode %d out of range", ino)
+	}
+	g := (ino - 1) / fsys.sb.inodesPerGroup
+	t, err := fsys.inodeTable(g)
+	if err != nil {
+		return nil, err
+	}
+	size := uint64(fsys.sb.inodeSize)
+	b := make([]byte, size)
+	off := t*fsys.blockSize + uint64((ino-1)%fsys.sb.inodesPerGroup)*size
+	if _, err := fsys.r.ReadAt(b, int64(off)); err != nil {
+		return nil, fmt.Errorf("reading inode %d: %w", ino, err)
+	}
+	in := &inode{
+		ino:   ino,
+		mode:  le.Uint16(b[0x0:]),
+		uid:   uint32(le.Uint16(b[0x2:])) | uint32(le.Uint16(b[0x78:]))<<16,
+		gid:   uint32(le.Uint16(b[0x18:])) | uint32(le.Uint16(b[0x7a:]))<<16,
+		links: le.Uint16(b[0x1a:]),
+		size:  uint64(le.Uint32(b[0x4:])) | uint64(le.Uint32(b[0x6c:]))<<32,
+		flags: le.Uint32(b[0x20:]),
+		block: b[0x28 : 0x28+iBlockSize],
+	}
+	var mtimeExtra uint32
+	if size > 128 {
+		extra := uint64(le.Uint16(b[0x80:]))
+		if 128+extra <= size {
+			in.extra = b[128+extra:]
+			if extra >= 0x8c-128 {
+				mtimeExtra = le.Uint32(b[0x88:])
+			}
+		}
+	}
+	in.mtime = decodeTime(le.Uint32(b[0x10:]), mtimeExtra)
+	return in, nil
+}
+
+// xattr returns the value of an extended attribute stored in the inode.
+func (in *inode) xattr(index uint8, name string) ([]byte, bool) {
+	b := in.extra
+	if len(b) < 4 || le.Uint32(b) != xattrMagic {
+		return nil, false
+	}
+	entries := b[4:]
+	for off := 0; off+16 <= len(entries) && le.Uint32(entries[off:]) != 0; {
+		e := entries[off:]
+		nameLen := int(e[0])
+		if 16+nameLen > len(e) {
+			break
+		}
+		if e[1] == index && string(e[16:16+nameLen]) == name {
+			voff, vsize := int(le.Uint16(e[2:])), int(le.Uint32(e[8:]))
+			if voff+vsize > len(entries) {
+				return nil, false
+			}
+			return entries[voff : voff+vsize], true
+		}
+		off += (16 + nameLen + 3) &^ 3
+	}
+	return nil, false
+}
+
+// inlineData returns the contents of an inode with inline data.
+func (in *inode) inlineData() ([]byte, error) {
+	data := append([]byte(nil), in.block...)
+	if v, ok := in.xattr(xattrIndexSystem, "data"); ok {
+		data = append(data, v...)
+	}
+	if uint64(len(data)) < in.size {
+		return nil, fmt.Errorf("inode %d: %d bytes of inline data, want %d", in.ino, len(data), in.size)
+	}
+	return data[:in.size], nil
+}
+
+// run is a range of contiguous blocks of a file.
+type run struct {
+	// phys is the first physical block, or zero for a hole.
+	phys uint64
+	// n is the number of blocks in the run.
+	n uint64
+}
+
+// mapBlock returns the run of blocks starting at logical block lblk.
+func (fsys *FS) mapBlock(in *inode, lblk uint64) (run, error) {
+	if in.flags&flagExtents != 0 {
+		return fsys.extentRun(in.block, lblk, maxExtentDepth)
+	}
+	return fsys.indirectRun(in, lblk)
+}
+
+// extentRun looks up lblk in the extent tree node.
+func (fsys *FS) extentRun(node []byte, lblk uint64, depth int) (run, error) {
+	if le.Uint16(node) != extentMagic {
+		return run{}, fmt.Errorf("bad extent header magic %#x", le.Uint16(node))
+	}
+	entries := int(le.Uint16(node[2:]))
+	if 12+12*entries > len(node) {
+		return run{}, fmt.Errorf("%d extents do not fit in a node of %d bytes", entries, len(node))
+	}
+	hole := run{n: ^uint64(0) - lblk}
+	if le.Uint16(node[6:]) == 0 {
+		for i := range entries {
+			e := node[12+12*i:]
+			first := uint64(le.Uint32(e[0:]))
+			n := uint64(le.Uint16(e[4:]))
+			uninit := n > extentInitMax
+			if uninit {
+				n -= extentInitMax
+			}
+			if lblk < first {
+				return run{n: first - lblk}, nil
+			}
+			if lblk >= first+n {
+				continue
+			}
+			if uninit {
+				return run{n: first + n - lblk}, nil
+			}
+			start := uint64(le.Uint16(e[6:]))<<32 | uint64(le.Uint32(e[8:]))
+			return run{phys: start + lblk - first, n: first + n - lblk}, nil
+		}
+		return hole, nil
+	}
+
+	if depth == 0 {
+		return run{}, fmt.Errorf("extent tree deeper than %d levels", maxExtentDepth)
+	}
+	idx := -1
+	for i := range entries {
+		if uint64(le.Uint32(node[12+12*i:])) > lblk {
+			break
+		}
+		idx = i
+	}
+	if idx < 0 {
+		if entries == 0 {
+			return hole, nil
+		}
+		return run{n: uint64(le.Uint32(node[12:])) - lblk}, nil
+	}
+	e := node[12+12*idx:]
+	child, err := fsys.readBlock(uint64(le.Uint16(e[8:]))<<32 | uint64(le.Uint32(e[4:])))
+	if err != nil {
+		return run{}, err
+	}
+	r, err := fsys.extentRun(child, lblk, depth-1)
+	if err != nil {
+		return run{}, err
+	}
+	// A hole at the end of the child ends where the next child begins.
+	if idx+1 < entries {
+		r.n = min(r.n, uint64(le.Uint32(node[12+12*(idx+1):]))-lblk)
+	}
+	return r, nil
+}
+
+// indirectRun maps lblk through the classic ext2/3 block map: 12 direct
+// blocks followed by single, double and triple indirect blocks.
+func (fsys *FS) indirectRun(in *inode, lblk uint64) (run, error) {
+	const direct = 12
+	if lblk < direct {
+		return run{phys: uint64(le.Uint32(in.block[4*lblk:])), n: 1}, nil
+	}
+	ptrs := fsys.blockSize / 4
+	rel := lblk - direct
+	level, span := 1, ptrs
+	for rel >= span {
+		rel -= span
+		level++
+		if level > 3 {
+			return run{}, fmt.Errorf("block %d beyond the block map", lblk)
+		}
+		span *= ptrs
+	}
+	blk := uint64(le.Uint32(in.block[4*(direct+level-1):]))
+	for ; level > 0; level-- {
+		if blk == 0 {
+			return run{n: 1}, nil
+		}
+		span /= ptrs
+		b, err := fsys.readBlock(blk)
+		if err != nil {
+			return run{}, err
+		}
+		blk = uint64(le.Uint32(b[4*(rel/span):]))
+		rel %= span
+	}
+	return run{phys: blk, n: 1}, nil
+}
+
+// readAt reads the contents of in at off.
+func (fsys *FS) readAt(in *inode, p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, fmt.Errorf("negative offset %d", off)
+	}
+	if uint64(off) >= in.size {
+		return 0, io.EOF
+	}
+	if in.flags&flagInlineData != 0 {
+		data, err := in.inlineData()
+		if err != nil {
+			return 0, err
+		}
+		n := copy(p, data[off:])
+		if n < len(p) {
+			return n, io.EOF
+		}
+		return n, nil
+	}
+
+	var n int
+	for n < len(p) {
+		pos := uint64(off) + uint64(n)
+		if pos >= in.size {
+			return n, io.EOF
+		}
+		r, err := fsys.mapBlock(in, pos/fsys.blockSize)
+		if err != nil {
+			return n, err
+		}
+		inBlock := pos % fsys.blockSize
+		want := uint64(len(p) - n)
+		want = min(want, in.size-pos)
+		if r.n < (want+inBlock+fsys.blockSize-1)/fsys.blockSize {
+			want = r.n*fsys.blockSize - inBlock
+		}
+		buf := p[n : n+int(want)]
+		if r.phys == 0 {
+			clear(buf)
+		} else {
+			if r.phys+(inBlock+want-1)/fsys.blockSize >= fsys.sb.blocksCount {
+				return n, fmt.Errorf("inode %d: block %d beyond end of file system", in.ino, r.phys)
+			}
+			if _, err := fsys.r.ReadAt(buf, int64(r.phys*fsys.blockSize+inBlock)); err != nil {
+				return n, err
+			}
+		}
+		n += len(buf)
+	}
+	return n, nil
+}
+
+// readAll reads the whole contents of in.
+func (fsys *FS) readAll(in *inode) ([]byte, error) {
+	b := make([]byte, in.size)
+	if _, err := fsys.readAt(in, b, 0); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return b, nil
+}
+
+// readlink returns the target of a symbolic link.
+func (fsys *FS) readlink(in *inode) (string, error) {
+	// Fast symlinks keep short targets in i_block.
+	if in.size < iBlockSize && in.flags&(flagExtents|flagInlineData) == 0 {
+		return string(in.block[:in.size]), nil
+	}
+	if in.size > maxSymlinkLen {
+		return "", fmt.Errorf("inode %d: symlink of %d bytes is too long", in.ino, in.size)
+	}
+	b, err := fsys.readAll(in)
+	return string(b), err
+}
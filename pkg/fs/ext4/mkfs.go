@@ -0,0 +1,732 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ext4
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Features of file systems created by Format.
+const (
+	compatExtAttr  = 0x8
+	compatDirIndex = 0x20
+
+	roCompatLargeFile  = 0x2
+	roCompatHugeFile   = 0x8
+	roCompatGDTCsum    = 0x10
+	roCompatDirNlink   = 0x20
+	roCompatExtraIsize = 0x40
+
+	formatCompat   = compatExtAttr | compatDirIndex
+	formatIncompat = incompatFiletype | incompatExtents
+	formatROCompat = roCompatSparseSuper | roCompatLargeFile | roCompatHugeFile |
+		roCompatGDTCsum | roCompatDirNlink | roCompatExtraIsize
+)
+
+// Group descriptor flags.
+const (
+	bgInodeUninit  = 0x1
+	bgItableZeroed = 0x4
+)
+
+const (
+	formatInodeSize  = 256
+	formatExtraIsize = 32
+	firstIno         = 11
+	journalIno       = 8
+	lostFoundIno     = 11
+	lostFoundSize    = 16384
+
+	// maxExtentLen is the longest initialized extent.
+	maxExtentLen = extentInitMax
+
+	// minJournalBlocks is the smallest journal JBD2 accepts.
+	minJournalBlocks = 1024
+	jbd2Magic        = 0xc03b3998
+	jbd2SuperblockV2 = 4
+
+	flagsUnsignedHash  = 0x2
+	defmXattrUser      = 0x4
+	defmACL            = 0x8
+	jnlBackupBlocks    = 1
+	hashHalfMD4        = 1
+	superblockStateOK  = 1
+	errorsContinue     = 1
+	maxMountCountUnset = 0xffff
+)
+
+// FormatOptions configure Format.
+type FormatOptions struct {
+	// Label is the volume label of at most 16 bytes.
+	Label string
+
+	// UUID is the file system UUID. If zero, a random one is used.
+	UUID [16]byte
+
+	// BlockSize is 1024, 2048 or 4096. If zero, it is 1024 for file
+	// systems smaller than 512MiB and 4096 otherwise, as mke2fs does.
+	BlockSize int
+
+	// Inodes is the number of inodes. If zero, there is one inode for
+	// every 16KiB, or every 4KiB on file systems smaller than 512MiB.
+	// The number is rounded up to fill the inode tables.
+	Inodes uint32
+
+	// ReservedPercent is the percentage of blocks reserved for root.
+	ReservedPercent int
+
+	// Journal adds an ext3/4 journal.
+	Journal bool
+
+	// JournalSize is the journal size in bytes. If zero, it is chosen
+	// from the size of the file system as mke2fs does.
+	JournalSize int64
+}
+
+// ErrTooSmall is returned by Format if the file system does not fit.
+var ErrTooSmall = errors.New("device too small")
+
+// formatter lays out a new file system.
+type formatter struct {
+	w          io.WriterAt
+	o          *FormatOptions
+	now        time.Time
+	uuid       [16]byte
+	bs         uint64
+	blocks     uint64
+	first      uint64
+	bpg        uint64
+	groups     uint32
+	ipg        uint32
+	descSize   uint64
+	gdtBlocks  uint64
+	itBlocks   uint64
+	incompat   uint32
+	compat     uint32
+	journal    uint64
+	next       uint64
+	used       []run
+	inodeTable []byte
+}
+
+// Format creates an empty ext4 file system of size bytes on w. It holds
+// a root directory and lost+found, and a journal if requested.
+//
+// The file system uses extents, but neither flexible block groups nor
+// metadata checksums, and has no room reserved for online resizing. The
+// inode tables of all groups but the first are left uninitialized and
+// are zeroed by the kernel on the first read-write mount.
+func Format(w io.WriterAt, size int64, o FormatOptions) error {
+	if len(o.Label) > 16 {
+		return fmt.Errorf("label %q is longer than 16 bytes", o.Label)
+	}
+	if o.ReservedPercent < 0 || o.ReservedPercent > 50 {
+		return fmt.Errorf("reserved blocks percentage %d is not between 0 and 50", o.ReservedPercent)
+	}
+	f := &formatter{w: w, o: &o, now: time.Now(), uuid: o.UUID}
+	if f.uuid == [16]byte{} {
+		if _, err := rand.Read(f.uuid[:]); err != nil {
+			return err
+		}
+		// A version 4, variant 1 UUID.
+		f.uuid[6] = f.uuid[6]&0x0f | 0x40
+		f.uuid[8] = f.uuid[8]&0x3f | 0x80
+	}
+	if err := f.layout(size); err != nil {
+		return err
+	}
+	if err := f.allocate(); err != nil {
+		return err
+	}
+	return f.write()
+}
+
+// layout computes the geometry of the file system.
+func (f *formatter) layout(size int64) error {
+	small := size < 512<<20
+	switch f.o.BlockSize {
+	case 0:
+		f.bs = 4096
+		if small {
+			f.bs = 1024
+		}
+	case 1024, 2048, 4096:
+		f.bs = uint64(f.o.BlockSize)
+	default:
+		return fmt.Errorf("invalid block size %d", f.o.BlockSize)
+	}
+	if size < 0 {
+		return fmt.Errorf("%w: %d bytes", ErrTooSmall, size)
+	}
+	f.blocks = uint64(size) / f.bs
+	if f.bs == 1024 {
+		f.first = 1
+	}
+	f.next = f.first
+	f.bpg = 8 * f.bs
+	f.compat = formatCompat
+	f.incompat = formatIncompat
+	f.descSize = 32
+	if f.blocks > 1<<32-1 {
+		f.incompat |= incompat64Bit
+		f.descSize = 64
+	}
+
+	perBlock := uint32(f.bs / formatInodeSize)
+	inodes := uint64(f.o.Inodes)
+	if inodes == 0 {
+		ratio := uint64(16384)
+		if small {
+			ratio = 4096
+		}
+		inodes = uint64(size) / ratio
+	}
+	// The last group is dropped if it cannot hold its metadata and a
+	// few data blocks.
+	for {
+		if f.blocks <= f.first {
+			return fmt.Errorf("%w: %d bytes", ErrTooSmall, size)
+		}
+		f.groups = uint32((f.blocks - f.first + f.bpg - 1) / f.bpg)
+		f.gdtBlocks = (uint64(f.groups)*f.descSize + f.bs - 1) / f.bs
+		ipg := (inodes + uint64(f.groups) - 1) / uint64(f.groups)
+		ipg = max(ipg, firstIno+1)
+		ipg = (ipg + uint64(perBlock) - 1) / uint64(perBlock) * uint64(perBlock)
+		ipg = (ipg + 7) &^ 7
+		if ipg > f.bs*8 {
+			return fmt.Errorf("%d inodes do not fit in %d groups of at most %d inodes", inodes, f.groups, f.bs*8)
+		}
+		f.ipg = uint32(ipg)
+		f.itBlocks = ipg / uint64(perBlock)
+
+		last := f.groups - 1
+		lastSize := f.groupEnd(last) - f.groupStart(last)
+		if lastSize >= f.metaBlocks(last)+50 || f.groups == 1 {
+			break
+		}
+		f.blocks -= lastSize
+	}
+	if uint64(f.ipg)*uint64(f.groups) > 1<<32-1 {
+		return fmt.Errorf("%d inodes are too many", uint64(f.ipg)*uint64(f.groups))
+	}
+	if f.metaBlocks(0) >= f.bpg {
+		return fmt.Errorf("%d group descriptors do not fit in a group", f.groups)
+	}
+	if f.metaBlocks(0) >= f.groupEnd(0) {
+		return fmt.Errorf("%w: %d bytes", ErrTooSmall, size)
+	}
+
+	if f.o.Journal {
+		f.compat |= compatHasJournal
+		f.journal = uint64(f.o.JournalSize) / f.bs
+		if f.o.JournalSize == 0 {
+			f.journal = defaultJournalBlocks(f.blocks)
+		}
+		switch {
+		case f.o.JournalSize == 0 && f.journal == 0:
+			return fmt.Errorf("%w for a journal: %d blocks", ErrTooSmall, f.blocks)
+		case f.journal < minJournalBlocks:
+			return fmt.Errorf("journal of %d blocks is smaller than %d blocks", f.journal, minJournalBlocks)
+		}
+		if f.journal > f.blocks/2 {
+			return fmt.Errorf("journal of %d blocks is more than half of %d blocks", f.journal, f.blocks)
+		}
+	}
+	return nil
+}
+
+// defaultJournalBlocks returns the journal size mke2fs picks for a file
+// system of n blocks.
+func defaultJournalBlocks(n uint64) uint64 {
+	switch {
+	case n < 2048:
+		return 0
+	case n < 32768:
+		return 1024
+	case n < 256*1024:
+		return 4096
+	case n < 512*1024:
+		return 8192
+	case n < 4096*1024:
+		return 16384
+	case n < 8192*1024:
+		return 32768
+	case n < 16384*1024:
+		return 65536
+	case n < 32768*1024:
+		return 131072
+	}
+	return 262144
+}
+
+func (f *formatter) groupStart(g uint32) uint64 {
+	return f.first + uint64(g)*f.bpg
+}
+
+func (f *formatter) groupEnd(g uint32) uint64 {
+	return min(f.groupStart(g)+f.bpg, f.blocks)
+}
+
+// superBlocks returns the number of blocks taken by the superblock and
+// group descriptor copies of group g.
+func (f *formatter) superBlocks(g uint32) uint64 {
+	if !sparseGroup(g) {
+		return 0
+	}
+	return 1 + f.gdtBlocks
+}
+
+// metaBlocks returns the number of metadata blocks at the start of
+// group g: superblock and descriptors, bitmaps and the inode table.
+func (f *formatter) metaBlocks(g uint32) uint64 {
+	return f.superBlocks(g) + 2 + f.itBlocks
+}
+
+func (f *formatter) blockBitmap(g uint32) uint64 { return f.groupStart(g) + f.superBlocks(g) }
+func (f *formatter) inodeBitmap(g uint32) uint64 { return f.blockBitmap(g) + 1 }
+func (f *formatter) inodeTableAt(g uint32) uint64 {
+	return f.blockBitmap(g) + 2
+}
+
+// alloc allocates n data blocks, returning runs of contiguous blocks.
+func (f *formatter) alloc(n uint64) ([]run, error) {
+	var runs []run
+	for n > 0 {
+		if f.next >= f.blocks {
+			return nil, fmt.Errorf("%w: out of space", ErrTooSmall)
+		}
+		g := uint32((f.next - f.first) / f.bpg)
+		f.next = max(f.next, f.groupStart(g)+f.metaBlocks(g))
+		if f.next >= f.groupEnd(g) {
+			f.next = f.groupEnd(g)
+			continue
+		}
+		take := min(n, f.groupEnd(g)-f.next)
+		if l := len(runs); l > 0 && runs[l-1].phys+runs[l-1].n == f.next {
+			runs[l-1].n += take
+		} else {
+			runs = append(runs, run{phys: f.next, n: take})
+		}
+		f.used = append(f.used, run{phys: f.next, n: take})
+		f.next += take
+		n -= take
+	}
+	return runs, nil
+}
+
+// pending are blocks to write, by block number.
+type pending map[uint64][]byte
+
+// fileBlocks holds the blocks of a file and its extent tree.
+type fileBlocks struct {
+	data []run
+	// iblock is the root of the extent tree.
+	iblock [iBlockSize]byte
+	// leaves are the extent tree leaf blocks, if the extents do not
+	// fit in the inode.
+	leaves []uint64
+}
+
+// count returns the number of blocks of the file, including the extent
+// tree.
+func (fb *fileBlocks) count() uint64 {
+	n := uint64(len(fb.leaves))
+	for _, r := range fb.data {
+		n += r.n
+	}
+	return n
+}
+
+func putExtentHeader(b []byte, entries, maxEntries, depth int) {
+	le.PutUint16(b[0:], extentMagic)
+	le.PutUint16(b[2:], uint16(entries))
+	le.PutUint16(b[4:], uint16(maxEntries))
+	le.PutUint16(b[6:], uint16(depth))
+}
+
+func putExtent(b []byte, lblk uint64, r run) {
+	le.PutUint32(b[0:], uint32(lblk))
+	le.PutUint16(b[4:], uint16(r.n))
+	le.PutUint16(b[6:], uint16(r.phys>>32))
+	le.PutUint32(b[8:], uint32(r.phys))
+}
+
+// allocFile allocates n blocks and builds their extent tree. It returns
+// the extent tree leaf blocks to write.
+func (f *formatter) allocFile(n uint64) (*fileBlocks, pending, error) {
+	data, err := f.alloc(n)
+	if err != nil {
+		return nil, nil, err
+	}
+	fb := &fileBlocks{data: data}
+	type extent struct {
+		lblk uint64
+		r    run
+	}
+	var exts []extent
+	var lblk uint64
+	for _, r := range data {
+		for r.n > 0 {
+			e := run{phys: r.phys, n: min(r.n, maxExtentLen)}
+			exts = append(exts, extent{lblk, e})
+			lblk += e.n
+			r.phys += e.n
+			r.n -= e.n
+		}
+	}
+
+	const inInode = (iBlockSize - 12) / 12
+	if len(exts) <= inInode {
+		putExtentHeader(fb.iblock[:], len(exts), inInode, 0)
+		for i, e := range exts {
+			putExtent(fb.iblock[12+12*i:], e.lblk, e.r)
+		}
+		return fb, nil, nil
+	}
+
+	perLeaf := int(f.bs-12) / 12
+	nleaves := (len(exts) + perLeaf - 1) / perLeaf
+	if nleaves > inInode {
+		return nil, nil, fmt.Errorf("%d extents need a deeper extent tree", len(exts))
+	}
+	leaves := pending{}
+	putExtentHeader(fb.iblock[:], nleaves, inInode, 1)
+	for i := range nleaves {
+		rs, err := f.alloc(1)
+		if err != nil {
+			return nil, nil, err
+		}
+		blk := rs[0].phys
+		fb.leaves = append(fb.leaves, blk)
+		chunk := exts[i*perLeaf : min((i+1)*perLeaf, len(exts))]
+		b := make([]byte, f.bs)
+		putExtentHeader(b, len(chunk), perLeaf, 0)
+		for j, e := range chunk {
+			putExtent(b[12+12*j:], e.lblk, e.r)
+		}
+		leaves[blk] = b
+
+		idx := fb.iblock[12+12*i:]
+		le.PutUint32(idx[0:], uint32(chunk[0].lblk))
+		le.PutUint32(idx[4:], uint32(blk))
+		le.PutUint16(idx[8:], uint16(blk>>32))
+	}
+	return fb, leaves, nil
+}
+
+// allocate allocates the root directory, lost+found and the journal, and
+// prepares their blocks and inodes.
+func (f *formatter) allocate() error {
+	f.inodeTable = make([]byte, f.itBlocks*f.bs)
+	blocks := pending{}
+
+	root, _, err := f.allocFile(1)
+	if err != nil {
+		return err
+	}
+	b := make([]byte, f.bs)
+	off := putDirent(b, 0, rootIno, ".", 12, 2)
+	off = putDirent(b, off, rootIno, "..", 12, 2)
+	putDirent(b, off, lostFoundIno, "lost+found", int(f.bs)-off, 2)
+	blocks[root.data[0].phys] = b
+	f.putInode(rootIno, modeDir|0o755, 3, f.bs, root)
+
+	lfBlocks := max(lostFoundSize/f.bs, 1)
+	lf, _, err := f.allocFile(lfBlocks)
+	if err != nil {
+		return err
+	}
+	b = make([]byte, f.bs)
+	off = putDirent(b, 0, lostFoundIno, ".", 12, 2)
+	putDirent(b, off, rootIno, "..", int(f.bs)-off, 2)
+	blocks[lf.data[0].phys] = b
+	for i := uint64(1); i < lfBlocks; i++ {
+		b := make([]byte, f.bs)
+		le.PutUint16(b[4:], uint16(f.bs))
+		blocks[lf.data[0].phys+i] = b
+	}
+	f.putInode(lostFoundIno, modeDir|0o700, 2, lfBlocks*f.bs, lf)
+
+	// The bad blocks inode is empty.
+	f.putInode(1, 0, 0, 0, nil)
+
+	if err := f.writeBlocks(blocks); err != nil {
+		return err
+	}
+	if f.journal == 0 {
+		return nil
+	}
+	j, leaves, err := f.allocFile(f.journal)
+	if err != nil {
+		return fmt.Errorf("allocating a journal of %d blocks: %w", f.journal, err)
+	}
+	f.putInode(journalIno, modeRegular|0o600, 1, f.journal*f.bs, j)
+	if err := f.writeBlocks(leaves); err != nil {
+		return err
+	}
+	return f.writeJournal(j)
+}
+
+// putDirent writes a directory entry at off and returns the offset of
+// the next entry.
+func putDirent(b []byte, off int, ino uint32, name string, recLen int, typ uint8) int {
+	le.PutUint32(b[off:], ino)
+	le.PutUint16(b[off+4:], uint16(recLen))
+	b[off+6] = uint8(len(name))
+	b[off+7] = typ
+	copy(b[off+8:], name)
+	return off + recLen
+}
+
+// encodeTime encodes t as seconds and the extra field of decodeTime.
+func encodeTime(t time.Time) (uint32, uint32) {
+	s := t.Unix()
+	return uint32(s), uint32((s-int64(int32(s)))>>32)&3 | uint32(t.Nanosecond())<<2
+}
+
+// putInode writes an inode of the first group into the inode table.
+func (f *formatter) putInode(ino uint32, mode uint16, links uint16, size uint64, fb *fileBlocks) {
+	b := f.inodeTable[uint64(ino-1)*formatInodeSize:][:formatInodeSize]
+	sec, extra := encodeTime(f.now)
+	le.PutUint16(b[0x0:], mode)
+	le.PutUint32(b[0x4:], uint32(size))
+	le.PutUint32(b[0x6c:], uint32(size>>32))
+	for _, o := range []int{0x8, 0xc, 0x10, 0x90} {
+		le.PutUint32(b[o:], sec)
+	}
+	le.PutUint16(b[0x1a:], links)
+	le.PutUint16(b[0x80:], formatExtraIsize)
+	for _, o := range []int{0x84, 0x88, 0x8c, 0x94} {
+		le.PutUint32(b[o:], extra)
+	}
+	if fb == nil {
+		return
+	}
+	sectors := fb.count() * f.bs / 512
+	le.PutUint32(b[0x1c:], uint32(sectors))
+	le.PutUint16(b[0x74:], uint16(sectors>>32))
+	le.PutUint32(b[0x20:], flagExtents)
+	copy(b[0x28:], fb.iblock[:])
+}
+
+func (f *formatter) writeBlocks(blocks pending) error {
+	for blk, b := range blocks {
+		if _, err := f.w.WriteAt(b, int64(blk*f.bs)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeJournal writes the journal superblock and zeroes the rest of the
+// journal.
+func (f *formatter) writeJournal(j *fileBlocks) error {
+	zero := make([]byte, 256*f.bs)
+	for _, r := range j.data {
+		for blk := r.phys; blk < r.phys+r.n; {
+			n := min(r.phys+r.n-blk, 256)
+			if _, err := f.w.WriteAt(zero[:n*f.bs], int64(blk*f.bs)); err != nil {
+				return err
+			}
+			blk += n
+		}
+	}
+	be := binary.BigEndian
+	b := make([]byte, f.bs)
+	be.PutUint32(b[0x0:], jbd2Magic)
+	be.PutUint32(b[0x4:], jbd2SuperblockV2)
+	be.PutUint32(b[0xc:], uint32(f.bs))
+	be.PutUint32(b[0x10:], uint32(f.journal))
+	be.PutUint32(b[0x14:], 1) // first log block
+	be.PutUint32(b[0x18:], 1) // first expected sequence
+	if f.incompat&incompat64Bit != 0 {
+		be.PutUint32(b[0x28:], 0x2) // JBD2_FEATURE_INCOMPAT_64BIT
+	}
+	copy(b[0x30:], f.uuid[:])
+	be.PutUint32(b[0x40:], 1) // number of file systems sharing the journal
+	_, err := f.w.WriteAt(b, int64(j.data[0].phys*f.bs))
+	return err
+}
+
+// crc16 is the CRC-16 used for group descriptor checksums.
+func crc16(crc uint16, b []byte) uint16 {
+	for _, c := range b {
+		crc ^= uint16(c)
+		for range 8 {
+			if crc&1 != 0 {
+				crc = crc>>1 ^ 0xa001
+			} else {
+				crc >>= 1
+			}
+		}
+	}
+	return crc
+}
+
+// setBits sets bits [from, to) of b.
+func setBits(b []byte, from, to uint64) {
+	for i := from; i < to; i++ {
+		b[i/8] |= 1 << (i % 8)
+	}
+}
+
+// write writes the group metadata, the first inode table and, last,
+// the superblocks.
+func (f *formatter) write() error {
+	if _, err := f.w.WriteAt(f.inodeTable, int64(f.inodeTableAt(0)*f.bs)); err != nil {
+		return err
+	}
+
+	gdt := make([]byte, f.gdtBlocks*f.bs)
+	var freeBlocks, freeInodes uint64
+	for g := range f.groups {
+		start, end := f.groupStart(g), f.groupEnd(g)
+		bb := make([]byte, f.bs)
+		setBits(bb, 0, f.metaBlocks(g))
+		for _, r := range f.used {
+			if from, to := max(r.phys, start), min(r.phys+r.n, end); from < to {
+				setBits(bb, from-start, to-start)
+			}
+		}
+		setBits(bb, end-start, f.bs*8)
+		var used uint64
+		for _, c := range bb {
+			for ; c != 0; c &= c - 1 {
+				used++
+			}
+		}
+		free := f.bs*8 - used
+		freeBlocks += free
+
+		ib := make([]byte, f.bs)
+		setBits(ib, uint64(f.ipg), f.bs*8)
+		freeIno, unused, dirs, flags := f.ipg, f.ipg, 0, uint16(bgInodeUninit)
+		if g == 0 {
+			setBits(ib, 0, firstIno)
+			freeIno, unused, dirs, flags = f.ipg-firstIno, f.ipg-firstIno, 2, bgItableZeroed
+		}
+		freeInodes += uint64(freeIno)
+		if _, err := f.w.WriteAt(append(bb, ib...), int64(f.blockBitmap(g)*f.bs)); err != nil {
+			return err
+		}
+
+		d := gdt[uint64(g)*f.descSize:][:f.descSize]
+		bbm, ibm, it := f.blockBitmap(g), f.inodeBitmap(g), f.inodeTableAt(g)
+		le.PutUint32(d[0x0:], uint32(bbm))
+		le.PutUint32(d[0x4:], uint32(ibm))
+		le.PutUint32(d[0x8:], uint32(it))
+		le.PutUint16(d[0xc:], uint16(free))
+		le.PutUint16(d[0xe:], uint16(freeIno))
+		le.PutUint16(d[0x10:], uint16(dirs))
+		le.PutUint16(d[0x12:], flags)
+		le.PutUint16(d[0x1c:], uint16(unused))
+		if f.descSize >= 64 {
+			le.PutUint32(d[0x20:], uint32(bbm>>32))
+			le.PutUint32(d[0x24:], uint32(ibm>>32))
+			le.PutUint32(d[0x28:], uint32(it>>32))
+		}
+		var gb [4]byte
+		le.PutUint32(gb[:], g)
+		crc := crc16(crc16(crc16(0xffff, f.uuid[:]), gb[:]), d[:0x1e])
+		if f.descSize > 0x20 {
+			crc = crc16(crc, d[0x20:])
+		}
+		le.PutUint16(d[0x1e:], crc)
+	}
+
+	sb := f.superblock(freeBlocks, freeInodes)
+	for g := range f.groups {
+		if !sparseGroup(g) {
+			continue
+		}
+		start := f.groupStart(g)
+		if _, err := f.w.WriteAt(gdt, int64((start+1)*f.bs)); err != nil {
+			return err
+		}
+		le.PutUint16(sb[0x5a:], uint16(g))
+		if g == 0 {
+			// Clear the boot sectors along with the first
+			// block.
+			b := make([]byte, superblockOffset+f.bs)
+			copy(b[superblockOffset:], sb)
+			if _, err := f.w.WriteAt(b[:max(f.bs, 2*superblockOffset)], 0); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, err := f.w.WriteAt(sb, int64(start*f.bs)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// superblock returns the superblock of the file system.
+func (f *formatter) superblock(freeBlocks, freeInodes uint64) []byte {
+	b := make([]byte, superblockSize)
+	now, _ := encodeTime(f.now)
+	reserved := f.blocks * uint64(f.o.ReservedPercent) / 100
+	le.PutUint32(b[0x0:], f.ipg*f.groups)
+	le.PutUint32(b[0x4:], uint32(f.blocks))
+	le.PutUint32(b[0x8:], uint32(reserved))
+	le.PutUint32(b[0xc:], uint32(freeBlocks))
+	le.PutUint32(b[0x10:], uint32(freeInodes))
+	le.PutUint32(b[0x14:], uint32(f.first))
+	logBS := uint32(0)
+	for 1024<<logBS < f.bs {
+		logBS++
+	}
+	le.PutUint32(b[0x18:], logBS)
+	le.PutUint32(b[0x1c:], logBS)
+	le.PutUint32(b[0x20:], uint32(f.bpg))
+	le.PutUint32(b[0x24:], uint32(f.bpg))
+	le.PutUint32(b[0x28:], f.ipg)
+	le.PutUint32(b[0x30:], now)
+	le.PutUint16(b[0x36:], maxMountCountUnset)
+	le.PutUint16(b[0x38:], magic)
+	le.PutUint16(b[0x3a:], superblockStateOK)
+	le.PutUint16(b[0x3c:], errorsContinue)
+	le.PutUint32(b[0x40:], now)
+	le.PutUint32(b[0x4c:], 1) // dynamic revision
+	le.PutUint32(b[0x54:], firstIno)
+	le.PutUint16(b[0x58:], formatInodeSize)
+	le.PutUint32(b[0x5c:], f.compat)
+	le.PutUint32(b[0x60:], f.incompat)
+	le.PutUint32(b[0x64:], formatROCompat)
+	copy(b[0x68:], f.uuid[:])
+	copy(b[0x78:0x88], f.o.Label)
+	// The directory hash seed only has to be unique; derive it from
+	// the UUID.
+	for i := range 16 {
+		b[0xec+i] = f.uuid[15-i] ^ 0x5a
+	}
+	b[0xfc] = hashHalfMD4
+	le.PutUint32(b[0x100:], defmXattrUser|defmACL)
+	le.PutUint32(b[0x108:], now)
+	if f.journal != 0 {
+		le.PutUint32(b[0xe0:], journalIno)
+		b[0xfd] = jnlBackupBlocks
+		// Back up the journal inode's block map and size.
+		j := f.inodeTable[(journalIno-1)*formatInodeSize:]
+		copy(b[0x10c:], j[0x28:0x28+iBlockSize])
+		le.PutUint32(b[0x10c+iBlockSize:], le.Uint32(j[0x6c:]))
+		le.PutUint32(b[0x10c+iBlockSize+4:], le.Uint32(j[0x4:]))
+	}
+	if f.incompat&incompat64Bit != 0 {
+		le.PutUint16(b[0xfe:], uint16(f.descSize))
+		le.PutUint32(b[0x150:], uint32(f.blocks>>32))
+		le.PutUint32(b[0x154:], uint32(reserved>>32))
+		le.PutUint32(b[0x158:], uint32(freeBlocks>>32))
+	}
+	le.PutUint16(b[0x15c:], formatExtraIsize)
+	le.PutUint16(b[0x15e:], formatExtraIsize)
+	le.PutUint32(b[0x160:], flagsUnsignedHash)
+	return b
+}
@@ -0,0 +1,135 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ext4
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+var testUUID = [16]byte{0x8a, 0x8a, 0x3b, 0x2e, 0x8d, 0x2c, 0x4b, 0x3d, 0x9d, 0x0a, 0x2f, 0x3c, 0x6f, 0x1e, 0x7b, 0x11}
+
+func TestFormat(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		size      int64
+		o         FormatOptions
+		blockSize int
+		inodes    uint32
+		journal   bool
+	}{
+		{
+			name:      "small",
+			size:      8 << 20,
+			o:         FormatOptions{Label: "small", UUID: testUUID},
+			blockSize: 1024,
+			inodes:    2048,
+		},
+		{
+			name:      "journal",
+			size:      600 << 20,
+			o:         FormatOptions{Label: "data", UUID: testUUID, Journal: true, ReservedPercent: 5},
+			blockSize: 4096,
+			inodes:    38400,
+			journal:   true,
+		},
+		{
+			name:      "inodes",
+			size:      100 << 20,
+			o:         FormatOptions{UUID: testUUID, BlockSize: 2048, Inodes: 1000},
+			blockSize: 2048,
+			// Rounded up to fill 32 inode table blocks in
+			// each of 4 groups.
+			inodes: 4 * 256,
+		},
+		{
+			// The journal spans many groups and needs an
+			// extent tree leaf.
+			name:      "large journal",
+			size:      200 << 20,
+			o:         FormatOptions{UUID: testUUID, Journal: true, JournalSize: 64 << 20},
+			blockSize: 1024,
+			inodes:    51200,
+			journal:   true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "img")
+			f, err := os.Create(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			if err := f.Truncate(tt.size); err != nil {
+				t.Fatal(err)
+			}
+			if err := Format(f, tt.size, tt.o); err != nil {
+				t.Fatalf("Format = %v", err)
+			}
+
+			fsys, err := New(f)
+			if err != nil {
+				t.Fatalf("New = %v", err)
+			}
+			if fsys.Type() != "ext4" || fsys.BlockSize() != tt.blockSize {
+				t.Errorf("got %s with %d-byte blocks, want ext4 with %d-byte blocks", fsys.Type(), fsys.BlockSize(), tt.blockSize)
+			}
+			if fsys.Label() != tt.o.Label || fsys.UUID() != "8a8a3b2e-8d2c-4b3d-9d0a-2f3c6f1e7b11" {
+				t.Errorf("label %q, UUID %s, want %q, 8a8a3b2e-8d2c-4b3d-9d0a-2f3c6f1e7b11", fsys.Label(), fsys.UUID(), tt.o.Label)
+			}
+			if fsys.sb.inodesCount != tt.inodes {
+				t.Errorf("%d inodes, want %d", fsys.sb.inodesCount, tt.inodes)
+			}
+			if got := fsys.sb.featureCompat&compatHasJournal != 0; got != tt.journal {
+				t.Errorf("journal = %t, want %t", got, tt.journal)
+			}
+			ents, err := fsys.ReadDir(".")
+			if err != nil || len(ents) != 1 || ents[0].Name() != "lost+found" || !ents[0].IsDir() {
+				t.Errorf("ReadDir(.) = %v, %v, want lost+found", ents, err)
+			}
+			if ents, err := fsys.ReadDir("lost+found"); err != nil || len(ents) != 0 {
+				t.Errorf("ReadDir(lost+found) = %v, %v, want no entries", ents, err)
+			}
+
+			// The kernel is the final judge, but e2fsck comes
+			// close.
+			e2fsck, err := exec.LookPath("e2fsck")
+			if err != nil {
+				t.Skip("e2fsck not found")
+			}
+			if out, err := exec.Command(e2fsck, "-fn", path).CombinedOutput(); err != nil {
+				t.Errorf("e2fsck: %v\n%s", err, out)
+			}
+		})
+	}
+}
+
+func TestFormatErrors(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "img"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := Format(f, 8<<10, FormatOptions{}); !errors.Is(err, ErrTooSmall) {
+		t.Errorf("Format(8KiB) = %v, want %v", err, ErrTooSmall)
+	}
+	if err := Format(f, 1<<20, FormatOptions{Journal: true}); !errors.Is(err, ErrTooSmall) {
+		t.Errorf("Format(1MiB) with a journal = %v, want %v", err, ErrTooSmall)
+	}
+	for _, o := range []FormatOptions{
+		{Label: "this label is too long"},
+		{BlockSize: 8192},
+		{ReservedPercent: 60},
+		{Journal: true, JournalSize: 512 << 10},
+		{Inodes: 1 << 20},
+	} {
+		if err := Format(f, 16<<20, o); err == nil {
+			t.Errorf("Format(%+v) succeeded, want an error", o)
+		}
+	}
+}
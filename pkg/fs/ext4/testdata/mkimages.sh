@@ -0,0 +1,55 @@
+#!/bin/sh
+# Generates the test images. Requires mke2fs and e2fsck from e2fsprogs.
+set -e
+
+dir=$(mktemp -d)
+trap 'rm -rf "$dir"' EXIT
+
+src="$dir/src"
+mkdir -p "$src/dir/sub" "$src/many" "$src/empty"
+printf 'hello, world\n' > "$src/hello.txt"
+printf 'nested\n' > "$src/dir/sub/nested.txt"
+# A sparse file with holes. mke2fs loses trailing holes with inline_data,
+# so the file ends in data.
+printf 'start' | dd of="$src/sparse" bs=1 seek=100000 conv=notrunc 2>/dev/null
+printf 'middle' | dd of="$src/sparse" bs=1 seek=2000000 conv=notrunc 2>/dev/null
+printf 'end' | dd of="$src/sparse" bs=1 seek=2999997 conv=notrunc 2>/dev/null
+# Many small extents, which need an extent tree deeper than the inode.
+i=0
+while [ $i -lt 12 ]; do
+	printf 'island %d' $i | dd of="$src/islands" bs=1 seek=$((i * 65536)) conv=notrunc 2>/dev/null
+	i=$((i + 1))
+done
+# A file large enough to need indirect blocks on ext2.
+i=0
+while [ $i -lt 3000 ]; do
+	echo "line $i"
+	i=$((i + 1))
+done > "$src/lines.txt"
+i=0
+while [ $i -lt 400 ]; do
+	printf '%d\n' $i > "$src/many/file-with-a-long-name-$i"
+	i=$((i + 1))
+done
+ln -s hello.txt "$src/link"
+ln -s /dir/sub "$src/abs"
+ln -s ../../hello.txt "$src/dir/sub/up"
+ln -s dir/sub/this-is-a-rather-long-symlink-target-that-does-not-fit-in-the-inode/../../sub/nested.txt "$src/long"
+ln -s loop "$src/loop"
+chmod 4755 "$src/hello.txt"
+touch -d @1700000000 "$src/hello.txt"
+mkdir -p "$src/dir/sub/this-is-a-rather-long-symlink-target-that-does-not-fit-in-the-inode"
+
+mkimg() {
+	out=$1
+	shift
+	rm -f "$dir/img"
+	mke2fs -q -F -L "$out" -U 8a8a3b2e-8d2c-4b3d-9d0a-2f3c6f1e7b11 -E root_owner=0:0 -d "$src" "$@" "$dir/img" 8M
+	e2fsck -fyD "$dir/img" >/dev/null 2>&1 || [ $? -le 1 ]
+	gzip -9n < "$dir/img" > "$out.img.gz"
+}
+
+mkimg ext4 -t ext4 -b 4096 -O 64bit,metadata_csum
+mkimg ext2 -t ext2 -b 1024
+mkimg inline -t ext4 -O inline_data,^has_journal -I 256
+mkimg metabg -t ext4 -b 1024 -g 256 -O meta_bg,64bit,^resize_inode,^has_journal
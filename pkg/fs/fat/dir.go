@@ -0,0 +1,240 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fat
+
+import (
+	"io/fs"
+	"strings"
+	"syscall"
+	"time"
+	"unicode/utf16"
+)
+
+// Directory entry attributes.
+const (
+	attrReadOnly = 0x01
+	attrVolumeID = 0x08
+	attrDir      = 0x10
+	attrLFN      = 0x0f
+	attrLFNMask  = 0x3f
+
+	// Case flags of the reserved byte, used by Windows NT for short
+	// names that are all lower case.
+	caseLowerBase = 0x08
+	caseLowerExt  = 0x10
+
+	lfnLast     = 0x40
+	lfnSeqMask  = 0x1f
+	lfnChars    = 13
+	deletedMark = 0xe5
+)
+
+// entry is a decoded directory entry.
+type entry struct {
+	name    string
+	short   string
+	attr    uint8
+	cluster uint32
+	size    uint32
+	mtime   time.Time
+}
+
+func (e *entry) isDir() bool {
+	return e.attr&attrDir != 0
+}
+
+func (e *entry) mode() fs.FileMode {
+	m := fs.FileMode(0o644)
+	if e.isDir() {
+		m = fs.ModeDir | 0o755
+	}
+	if e.attr&attrReadOnly != 0 {
+		m &^= 0o222
+	}
+	return m
+}
+
+// directory is the decoded contents of a directory.
+type directory struct {
+	entries []*entry
+	// label is the volume label, which is an entry of the root
+	// directory.
+	label string
+}
+
+// root returns the entry of the root directory.
+func (fsys *FS) root() *entry {
+	return &entry{name: ".", attr: attrDir, cluster: fsys.rootCluster}
+}
+
+// shortName decodes an 8.3 name.
+func shortName(b []byte, lower uint8) string {
+	decode := func(b []byte, lower bool) string {
+		r := make([]rune, 0, len(b))
+		for _, c := range b {
+			r = append(r, rune(c))
+		}
+		s := strings.TrimRight(string(r), " ")
+		if lower {
+			s = strings.ToLower(s)
+		}
+		return s
+	}
+	base := append([]byte(nil), b[:8]...)
+	// 0x05 stands for a first character of 0xe5, the deleted mark.
+	if base[0] == 0x05 {
+		base[0] = deletedMark
+	}
+	name := decode(base, lower&caseLowerBase != 0)
+	if ext := decode(b[8:11], lower&caseLowerExt != 0); ext != "" {
+		name += "." + ext
+	}
+	return name
+}
+
+// shortChecksum is the checksum of a short name stored in the long name
+// entries that belong to it.
+func shortChecksum(b []byte) uint8 {
+	var sum uint8
+	for _, c := range b[:11] {
+		sum = (sum&1)<<7 + sum>>1 + c
+	}
+	return sum
+}
+
+// dosTime decodes a FAT date and time.
+func dosTime(d, t uint16) time.Time {
+	return time.Date(1980+int(d>>9), time.Month(d>>5&0xf), int(d&0x1f),
+		int(t>>11), int(t>>5&0x3f), int(t&0x1f)*2, 0, time.UTC)
+}
+
+// parseDir decodes the directory entries in b.
+func (fsys *FS) parseDir(b []byte) *directory {
+	d := &directory{}
+	var (
+		lfn  [][]uint16
+		want int // sequence number of the next long name entry
+		sum  uint8
+	)
+	for off := 0; off+dirEntrySize <= len(b); off += dirEntrySize {
+		e := b[off : off+dirEntrySize]
+		if e[0] == 0 {
+			break
+		}
+		if e[0] == deletedMark {
+			lfn = nil
+			continue
+		}
+		attr := e[11]
+		if attr&attrLFNMask == attrLFN {
+			seq := int(e[0] & lfnSeqMask)
+			switch {
+			case e[0]&lfnLast != 0 && seq > 0:
+				lfn, want, sum = make([][]uint16, seq), seq, e[13]
+			case lfn == nil || seq != want || e[13] != sum:
+				lfn = nil
+				continue
+			}
+			part := make([]uint16, 0, lfnChars)
+			for _, o := range []int{1, 3, 5, 7, 9, 14, 16, 18, 20, 22, 24, 28, 30} {
+				part = append(part, le.Uint16(e[o:]))
+			}
+			lfn[seq-1] = part
+			want--
+			continue
+		}
+		if attr&attrVolumeID != 0 {
+			if attr&attrDir == 0 && d.label == "" {
+				d.label = strings.TrimRight(string(e[:11]), " ")
+			}
+			lfn = nil
+			continue
+		}
+
+		ent := &entry{
+			short:   shortName(e[:11], e[12]),
+			attr:    attr,
+			cluster: uint32(le.Uint16(e[26:])),
+			size:    le.Uint32(e[28:]),
+			mtime:   dosTime(le.Uint16(e[24:]), le.Uint16(e[22:])),
+		}
+		if fsys.typ == FAT32 {
+			ent.cluster |= uint32(le.Uint16(e[20:])) << 16
+		}
+		ent.name = ent.short
+		if lfn != nil && want == 0 && sum == shortChecksum(e) {
+			var u []uint16
+			for _, p := range lfn {
+				u = append(u, p...)
+			}
+			for i, c := range u {
+				if c == 0 {
+					u = u[:i]
+					break
+				}
+			}
+			ent.name = string(utf16.Decode(u))
+		}
+		lfn = nil
+		if ent.name == "." || ent.name == ".." {
+			continue
+		}
+		d.entries = append(d.entries, ent)
+	}
+	return d
+}
+
+// readDir reads the directory of e.
+func (fsys *FS) readDir(e *entry) (*directory, error) {
+	if e.cluster == 0 && fsys.typ != FAT32 {
+		b := make([]byte, fsys.rootSize)
+		if _, err := fsys.r.ReadAt(b, fsys.rootStart); err != nil {
+			return nil, err
+		}
+		return fsys.parseDir(b), nil
+	}
+	cs, err := fsys.chain(e.cluster)
+	if err != nil {
+		return nil, err
+	}
+	size := int64(len(cs)) * fsys.clusterSize
+	b := make([]byte, size)
+	if _, err := fsys.readChain(cs, size, b, 0); err != nil {
+		return nil, err
+	}
+	return fsys.parseDir(b), nil
+}
+
+// walk resolves name, looking up each element case-insensitively.
+func (fsys *FS) walk(op, name string) (*entry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	e := fsys.root()
+	if name == "." {
+		return e, nil
+	}
+	for _, elem := range strings.Split(name, "/") {
+		if !e.isDir() {
+			return nil, &fs.PathError{Op: op, Path: name, Err: syscall.ENOTDIR}
+		}
+		d, err := fsys.readDir(e)
+		if err != nil {
+			return nil, &fs.PathError{Op: op, Path: name, Err: err}
+		}
+		var found *entry
+		for _, c := range d.entries {
+			if strings.EqualFold(c.name, elem) || strings.EqualFold(c.short, elem) {
+				found = c
+				break
+			}
+		}
+		if found == nil {
+			return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+		}
+		e = found
+	}
+	return e, nil
+}
@@ -0,0 +1,267 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package fat reads FAT12, FAT16 and FAT32 file systems, and creates FAT32
+// file systems, without kernel support.
+//
+// FS implements io/fs.FS, as well as fs.ReadDirFS, fs.ReadFileFS and
+// fs.StatFS, on top of an io.ReaderAt such as a block device or an image
+// file. VFAT long file names are supported. As on Windows, and with the
+// Linux vfat driver, names are looked up case-insensitively.
+//
+// Short names are decoded as Latin-1 rather than with an OEM code page.
+// Timestamps are interpreted as UTC.
+package fat
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Type is the FAT variant, named after the size of FAT entries.
+type Type int
+
+// FAT variants.
+const (
+	FAT12 Type = 12
+	FAT16 Type = 16
+	FAT32 Type = 32
+)
+
+func (t Type) String() string {
+	return fmt.Sprintf("FAT%d", int(t))
+}
+
+const (
+	// Cluster count limits that determine the FAT type.
+	maxFAT12Clusters = 4084
+	maxFAT16Clusters = 65524
+
+	dirEntrySize = 32
+)
+
+// ErrNotFAT is returned by New if there is no FAT file system.
+var ErrNotFAT = errors.New("no FAT boot sector found")
+
+var le = binary.LittleEndian
+
+// FS is a read-only FAT file system.
+type FS struct {
+	r           io.ReaderAt
+	typ         Type
+	clusterSize int64
+	fatStart    int64
+	// rootStart and rootSize locate the fixed root directory of FAT12
+	// and FAT16.
+	rootStart   int64
+	rootSize    int64
+	rootCluster uint32
+	dataStart   int64
+	clusters    uint32
+	label       string
+	serial      uint32
+}
+
+func isPowerOf2(n uint32) bool {
+	return n != 0 && n&(n-1) == 0
+}
+
+// New reads the file system from r.
+func New(r io.ReaderAt) (*FS, error) {
+	b := make([]byte, 512)
+	if _, err := r.ReadAt(b, 0); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, ErrNotFAT
+		}
+		return nil, err
+	}
+	if b[0] != 0xeb && b[0] != 0xe9 {
+		return nil, ErrNotFAT
+	}
+	var (
+		bps         = uint32(le.Uint16(b[0x0b:]))
+		spc         = uint32(b[0x0d])
+		reserved    = uint32(le.Uint16(b[0x0e:]))
+		fats        = uint32(b[0x10])
+		rootEntries = uint32(le.Uint16(b[0x11:]))
+		total       = uint32(le.Uint16(b[0x13:]))
+		fatSize     = uint32(le.Uint16(b[0x16:]))
+	)
+	if bps < 512 || bps > 4096 || !isPowerOf2(bps) || !isPowerOf2(spc) || reserved == 0 || fats == 0 {
+		return nil, ErrNotFAT
+	}
+	if total == 0 {
+		total = le.Uint32(b[0x20:])
+	}
+	// The extended boot record starts later on FAT32.
+	ebr := b[0x24:]
+	if fatSize == 0 {
+		fatSize = le.Uint32(b[0x24:])
+		ebr = b[0x40:]
+	}
+	rootSectors := (rootEntries*dirEntrySize + bps - 1) / bps
+	meta := uint64(reserved) + uint64(fats)*uint64(fatSize) + uint64(rootSectors)
+	if fatSize == 0 || uint64(total) <= meta {
+		return nil, ErrNotFAT
+	}
+
+	fsys := &FS{
+		r:           r,
+		clusterSize: int64(bps * spc),
+		fatStart:    int64(reserved) * int64(bps),
+		rootStart:   int64(uint64(reserved)+uint64(fats)*uint64(fatSize)) * int64(bps),
+		rootSize:    int64(rootSectors) * int64(bps),
+		dataStart:   int64(meta) * int64(bps),
+		clusters:    uint32((uint64(total) - meta) / uint64(spc)),
+	}
+	switch {
+	case fsys.clusters <= maxFAT12Clusters:
+		fsys.typ = FAT12
+	case fsys.clusters <= maxFAT16Clusters:
+		fsys.typ = FAT16
+	default:
+		fsys.typ = FAT32
+		if rootEntries != 0 {
+			return nil, fmt.Errorf("FAT32 with %d fixed root directory entries", rootEntries)
+		}
+		fsys.rootCluster = le.Uint32(b[0x2c:])
+	}
+	// The FAT must have room for an entry for every cluster.
+	if uint64(fatSize)*uint64(bps)*8 < uint64(fsys.clusters+2)*uint64(fsys.typ) {
+		return nil, fmt.Errorf("%v FAT of %d sectors is too small for %d clusters", fsys.typ, fatSize, fsys.clusters)
+	}
+	// Extended boot signatures 0x28 and 0x29 carry a serial number,
+	// and 0x29 also a label.
+	if ebr[2] == 0x28 || ebr[2] == 0x29 {
+		fsys.serial = le.Uint32(ebr[3:])
+	}
+	if ebr[2] == 0x29 {
+		fsys.label = strings.TrimRight(string(ebr[7:18]), " ")
+		if fsys.label == "NO NAME" {
+			fsys.label = ""
+		}
+	}
+	root, err := fsys.readDir(fsys.root())
+	if err != nil {
+		return nil, fmt.Errorf("reading root directory: %w", err)
+	}
+	if root.label != "" {
+		fsys.label = root.label
+	}
+	return fsys, nil
+}
+
+// Type returns the FAT variant.
+func (fsys *FS) Type() Type {
+	return fsys.typ
+}
+
+// Label returns the volume label.
+func (fsys *FS) Label() string {
+	return fsys.label
+}
+
+// UUID returns the volume serial number in the XXXX-XXXX form Linux uses
+// as the file system UUID.
+func (fsys *FS) UUID() string {
+	return fmt.Sprintf("%04X-%04X", fsys.serial>>16, fsys.serial&0xffff)
+}
+
+// next returns the FAT entry of cluster c.
+func (fsys *FS) next(c uint32) (uint32, error) {
+	var b [4]byte
+	switch fsys.typ {
+	case FAT12:
+		if _, err := fsys.r.ReadAt(b[:2], fsys.fatStart+int64(c)*3/2); err != nil {
+			return 0, err
+		}
+		v := uint32(le.Uint16(b[:]))
+		if c&1 != 0 {
+			return v >> 4, nil
+		}
+		return v & 0xfff, nil
+	case FAT16:
+		if _, err := fsys.r.ReadAt(b[:2], fsys.fatStart+int64(c)*2); err != nil {
+			return 0, err
+		}
+		return uint32(le.Uint16(b[:])), nil
+	}
+	if _, err := fsys.r.ReadAt(b[:], fsys.fatStart+int64(c)*4); err != nil {
+		return 0, err
+	}
+	return le.Uint32(b[:]) & 0x0fffffff, nil
+}
+
+// endOfChain returns the lowest FAT entry value ending a chain. The value
+// just below it marks a bad cluster.
+func (fsys *FS) endOfChain() uint32 {
+	switch fsys.typ {
+	case FAT12:
+		return 0xff8
+	case FAT16:
+		return 0xfff8
+	}
+	return 0x0ffffff8
+}
+
+// chain returns the clusters of the chain starting at cluster start.
+func (fsys *FS) chain(start uint32) ([]uint32, error) {
+	if start == 0 {
+		return nil, nil
+	}
+	eoc := fsys.endOfChain()
+	var cs []uint32
+	for c := start; ; {
+		if c < 2 || c > fsys.clusters+1 {
+			return nil, fmt.Errorf("cluster %d out of range in chain starting at %d", c, start)
+		}
+		if uint32(len(cs)) >= fsys.clusters {
+			return nil, fmt.Errorf("cluster chain starting at %d loops", start)
+		}
+		cs = append(cs, c)
+		n, err := fsys.next(c)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case n >= eoc:
+			return cs, nil
+		case n == eoc-1:
+			return nil, fmt.Errorf("bad cluster %d in chain starting at %d", n, start)
+		}
+		c = n
+	}
+}
+
+func (fsys *FS) clusterOffset(c uint32) int64 {
+	return fsys.dataStart + int64(c-2)*fsys.clusterSize
+}
+
+// readChain reads size bytes from clusters at off.
+func (fsys *FS) readChain(cs []uint32, size int64, p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, fmt.Errorf("negative offset %d", off)
+	}
+	var n int
+	for n < len(p) {
+		pos := off + int64(n)
+		if pos >= size {
+			return n, io.EOF
+		}
+		i := pos / fsys.clusterSize
+		if i >= int64(len(cs)) {
+			return n, fmt.Errorf("file of %d bytes has only %d clusters", size, len(cs))
+		}
+		in := pos % fsys.clusterSize
+		want := min(int64(len(p)-n), fsys.clusterSize-in, size-pos)
+		if _, err := fsys.r.ReadAt(p[n:n+int(want)], fsys.clusterOffset(cs[i])+in); err != nil {
+			return n, err
+		}
+		n += int(want)
+	}
+	return n, nil
+}
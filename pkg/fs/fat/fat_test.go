@@ -0,0 +1,430 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fat
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+	"unicode/utf16"
+)
+
+// node is a file or directory of a test image.
+type node struct {
+	name  string
+	data  []byte
+	dir   []*node
+	isDir bool
+}
+
+// builder writes minimal FAT images for tests.
+type builder struct {
+	b           []byte
+	typ         Type
+	bps         uint32
+	spc         uint32
+	fatStart    uint32
+	rootStart   uint32
+	rootEntries uint32
+	dataStart   uint32
+	next        uint32
+	short       int
+	// stride spaces the clusters of a chain to fragment files.
+	stride uint32
+}
+
+var testTime = time.Date(2024, 2, 29, 13, 37, 42, 0, time.UTC)
+
+func newImage(typ Type, sectors, spc uint32, tree []*node) []byte {
+	bld := &builder{typ: typ, bps: 512, spc: spc, next: 2, stride: 2}
+	reserved, fats := uint32(1), uint32(2)
+	if typ == FAT32 {
+		reserved = 32
+	} else {
+		bld.rootEntries = 512
+	}
+	rootSectors := bld.rootEntries * dirEntrySize / bld.bps
+	clusters := (sectors - reserved - rootSectors) / spc
+	fatSize := ((clusters+2)*uint32(typ)/8 + bld.bps) / bld.bps
+	bld.fatStart = reserved * bld.bps
+	bld.rootStart = (reserved + fats*fatSize) * bld.bps
+	bld.dataStart = bld.rootStart + rootSectors*bld.bps
+	bld.b = make([]byte, sectors*bld.bps)
+
+	b := bld.b
+	copy(b, []byte{0xeb, 0x3c, 0x90})
+	copy(b[3:], "MSWIN4.1")
+	le.PutUint16(b[0x0b:], uint16(bld.bps))
+	b[0x0d] = uint8(spc)
+	le.PutUint16(b[0x0e:], uint16(reserved))
+	b[0x10] = uint8(fats)
+	le.PutUint16(b[0x11:], uint16(bld.rootEntries))
+	b[0x15] = 0xf8
+	le.PutUint32(b[0x20:], sectors)
+	ebr := b[0x24:]
+	if typ == FAT32 {
+		le.PutUint32(b[0x24:], fatSize)
+		ebr = b[0x40:]
+	} else {
+		le.PutUint16(b[0x16:], uint16(fatSize))
+	}
+	ebr[2] = 0x29
+	le.PutUint32(ebr[3:], 0x1234abcd)
+	copy(ebr[7:], "NO NAME    ")
+	copy(ebr[18:], "FAT     ")
+	b[510], b[511] = 0x55, 0xaa
+
+	bld.setFAT(0, 0x0ffffff8)
+	bld.setFAT(1, 0x0fffffff)
+	root := &node{isDir: true, dir: tree}
+	if typ == FAT32 {
+		c := bld.writeDir(root, 0, true)
+		le.PutUint32(b[0x2c:], c)
+	} else {
+		bld.fillDir(b[bld.rootStart:bld.dataStart], root, 0, true)
+	}
+	return bld.b
+}
+
+func (bld *builder) setFAT(c, v uint32) {
+	b := bld.b[bld.fatStart:]
+	switch bld.typ {
+	case FAT12:
+		v &= 0xfff
+		off := c * 3 / 2
+		old := le.Uint16(b[off:])
+		if c&1 != 0 {
+			le.PutUint16(b[off:], old&0x000f|uint16(v)<<4)
+		} else {
+			le.PutUint16(b[off:], old&0xf000|uint16(v))
+		}
+	case FAT16:
+		le.PutUint16(b[c*2:], uint16(v))
+	default:
+		le.PutUint32(b[c*4:], v&0x0fffffff)
+	}
+}
+
+// alloc allocates a chain holding size bytes and returns its clusters.
+func (bld *builder) alloc(size int) []uint32 {
+	csize := int(bld.bps * bld.spc)
+	n := (size + csize - 1) / csize
+	var cs []uint32
+	for range n {
+		cs = append(cs, bld.next)
+		bld.next += bld.stride
+	}
+	for i, c := range cs {
+		if i+1 < len(cs) {
+			bld.setFAT(c, cs[i+1])
+		} else {
+			bld.setFAT(c, 0x0fffffff)
+		}
+	}
+	return cs
+}
+
+func (bld *builder) write(cs []uint32, data []byte) {
+	csize := int(bld.bps * bld.spc)
+	for i, c := range cs {
+		off := int(bld.dataStart) + int(c-2)*csize
+		copy(bld.b[off:off+csize], data[min(i*csize, len(data)):])
+	}
+}
+
+// shortEntry returns the 8.3 name and case flags of name, and whether
+// name needs a long name.
+func (bld *builder) shortEntry(name string) ([11]byte, uint8, bool) {
+	var s [11]byte
+	for i := range s {
+		s[i] = ' '
+	}
+	base, ext, _ := strings.Cut(name, ".")
+	valid := len(base) > 0 && len(base) <= 8 && len(ext) <= 3 && strings.Count(name, ".") <= 1
+	for _, r := range name {
+		if r > 0x7e || strings.ContainsRune(" +,;=[]", r) {
+			valid = false
+		}
+	}
+	var flags uint8
+	if valid {
+		switch {
+		case base == strings.ToUpper(base) && ext == strings.ToUpper(ext):
+		case base == strings.ToLower(base) && ext == strings.ToLower(ext):
+			flags = caseLowerBase | caseLowerExt
+		default:
+			valid = false
+		}
+	}
+	if valid {
+		copy(s[:], strings.ToUpper(base))
+		copy(s[8:], strings.ToUpper(ext))
+		return s, flags, false
+	}
+	bld.short++
+	alias := fmt.Sprintf("LONG~%d", bld.short)
+	copy(s[:], alias)
+	return s, 0, true
+}
+
+func lfnEntries(name string, sum uint8) [][]byte {
+	u := utf16.Encode([]rune(name))
+	u = append(u, 0)
+	for len(u)%lfnChars != 0 {
+		u = append(u, 0xffff)
+	}
+	n := len(u) / lfnChars
+	var ents [][]byte
+	for seq := n; seq >= 1; seq-- {
+		e := make([]byte, dirEntrySize)
+		e[0] = uint8(seq)
+		if seq == n {
+			e[0] |= lfnLast
+		}
+		e[11] = attrLFN
+		e[13] = sum
+		part := u[(seq-1)*lfnChars:]
+		for i, o := range []int{1, 3, 5, 7, 9, 14, 16, 18, 20, 22, 24, 28, 30} {
+			le.PutUint16(e[o:], part[i])
+		}
+		ents = append(ents, e)
+	}
+	return ents
+}
+
+func (bld *builder) entry(name [11]byte, attr, flags uint8, cluster uint32, size int) []byte {
+	e := make([]byte, dirEntrySize)
+	copy(e, name[:])
+	e[11] = attr
+	e[12] = flags
+	le.PutUint16(e[20:], uint16(cluster>>16))
+	le.PutUint16(e[26:], uint16(cluster))
+	le.PutUint32(e[28:], uint32(size))
+	t := testTime
+	le.PutUint16(e[22:], uint16(t.Hour()<<11|t.Minute()<<5|t.Second()/2))
+	le.PutUint16(e[24:], uint16((t.Year()-1980)<<9|int(t.Month())<<5|t.Day()))
+	return e
+}
+
+// dirEntries returns the encoded entries of dir n, writing its children.
+func (bld *builder) dirEntries(n *node, self, parent uint32, root bool) [][]byte {
+	var ents [][]byte
+	dot := func(s string) [11]byte {
+		var b [11]byte
+		copy(b[:], s+"           ")
+		return b
+	}
+	if root {
+		var label [11]byte
+		copy(label[:], "TESTVOL    ")
+		ents = append(ents, bld.entry(label, attrVolumeID, 0, 0, 0))
+	} else {
+		ents = append(ents, bld.entry(dot("."), attrDir, 0, self, 0))
+		ents = append(ents, bld.entry(dot(".."), attrDir, 0, parent, 0))
+	}
+	// A deleted entry with a long name, which must be skipped.
+	for _, e := range lfnEntries("deleted file name", 0) {
+		e[0] = deletedMark
+		ents = append(ents, e)
+	}
+	ents = append(ents, bld.entry(dot("\xe5ELETED"), 0, 0, 0, 0))
+	for _, c := range n.dir {
+		short, flags, long := bld.shortEntry(c.name)
+		if long {
+			ents = append(ents, lfnEntries(c.name, shortChecksum(short[:]))...)
+		}
+		var cluster uint32
+		attr := uint8(0)
+		size := len(c.data)
+		if c.isDir {
+			attr = attrDir
+			size = 0
+			cluster = bld.writeDir(c, self, false)
+		} else if len(c.data) > 0 {
+			cs := bld.alloc(len(c.data))
+			bld.write(cs, c.data)
+			cluster = cs[0]
+		}
+		ents = append(ents, bld.entry(short, attr, flags, cluster, size))
+	}
+	return ents
+}
+
+func (bld *builder) fillDir(b []byte, n *node, self uint32, root bool) {
+	for i, e := range bld.dirEntries(n, self, 0, root) {
+		copy(b[i*dirEntrySize:], e)
+	}
+}
+
+// writeDir allocates and writes directory n and returns its cluster.
+func (bld *builder) writeDir(n *node, parent uint32, root bool) uint32 {
+	// Allocate before writing children, whose entries point back.
+	size := (len(n.dir)*4 + 40) * dirEntrySize
+	cs := bld.alloc(size)
+	var buf []byte
+	for _, e := range bld.dirEntries(n, cs[0], parent, root) {
+		buf = append(buf, e...)
+	}
+	buf = append(buf, make([]byte, len(cs)*int(bld.bps*bld.spc)-len(buf))...)
+	bld.write(cs, buf)
+	return cs[0]
+}
+
+func testTree() ([]*node, map[string]string) {
+	big := make([]byte, 100000)
+	for i := range big {
+		big[i] = byte(i * 7)
+	}
+	many := &node{name: "many", isDir: true}
+	files := map[string]string{
+		"README.TXT":           "upper case 8.3\n",
+		"readme2.txt":          "lower case 8.3\n",
+		"Mixed Case Name.conf": "long name\n",
+		"Grüße – unicode.txt":  "unicode\n",
+		"EFI/BOOT/grub.cfg":    "menuentry linux {}\n",
+		"EFI/BOOT/a very long file name that needs many long name entries.txt": "long\n",
+		"empty":   "",
+		"big.bin": string(big),
+	}
+	for i := range 100 {
+		name := fmt.Sprintf("file number %d", i)
+		many.dir = append(many.dir, &node{name: name, data: []byte(name)})
+		files["many/"+name] = name
+	}
+	tree := []*node{
+		{name: "README.TXT", data: []byte(files["README.TXT"])},
+		{name: "readme2.txt", data: []byte(files["readme2.txt"])},
+		{name: "Mixed Case Name.conf", data: []byte(files["Mixed Case Name.conf"])},
+		{name: "Grüße – unicode.txt", data: []byte(files["Grüße – unicode.txt"])},
+		{name: "EFI", isDir: true, dir: []*node{
+			{name: "BOOT", isDir: true, dir: []*node{
+				{name: "grub.cfg", data: []byte(files["EFI/BOOT/grub.cfg"])},
+				{name: "a very long file name that needs many long name entries.txt", data: []byte("long\n")},
+			}},
+		}},
+		{name: "empty", data: nil},
+		{name: "big.bin", data: big},
+		{name: "emptydir", isDir: true},
+		many,
+	}
+	return tree, files
+}
+
+func TestImages(t *testing.T) {
+	for _, tt := range []struct {
+		typ     Type
+		sectors uint32
+		spc     uint32
+	}{
+		{FAT12, 2880, 1},
+		{FAT16, 40000, 1},
+		{FAT32, 140000, 1},
+		{FAT32, 600000, 8},
+	} {
+		t.Run(fmt.Sprintf("%v-%d", tt.typ, tt.spc), func(t *testing.T) {
+			tree, files := testTree()
+			img := newImage(tt.typ, tt.sectors, tt.spc, tree)
+			fsys, err := New(bytes.NewReader(img))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fsys.Type() != tt.typ || fsys.Label() != "TESTVOL" || fsys.UUID() != "1234-ABCD" {
+				t.Errorf("got type %v, label %q, uuid %q", fsys.Type(), fsys.Label(), fsys.UUID())
+			}
+			for name, want := range files {
+				got, err := fsys.ReadFile(name)
+				if err != nil || string(got) != want {
+					t.Errorf("ReadFile(%q) = %.40q, %v, want %.40q", name, got, err, want)
+				}
+			}
+			var names []string
+			for n := range files {
+				names = append(names, n)
+			}
+			names = append(names, "emptydir")
+			if err := fstest.TestFS(fsys, names...); err != nil {
+				t.Error(err)
+			}
+
+			// Lookups ignore case, and short aliases work too.
+			for _, name := range []string{"efi/boot/GRUB.CFG", "readme.txt", "Readme2.TXT", "EFI/BOOT/long~3"} {
+				if _, err := fsys.Stat(name); err != nil {
+					t.Errorf("Stat(%q): %v", name, err)
+				}
+			}
+			fi, err := fsys.Stat("README.TXT")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !fi.ModTime().Equal(testTime) || fi.Mode() != 0o644 || fi.Size() != 15 {
+				t.Errorf("Stat(README.TXT): mtime %v, mode %v, size %d", fi.ModTime(), fi.Mode(), fi.Size())
+			}
+			ents, err := fsys.ReadDir(".")
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, e := range ents {
+				got = append(got, e.Name())
+			}
+			want := "EFI,Grüße – unicode.txt,Mixed Case Name.conf,README.TXT,big.bin,empty,emptydir,many,readme2.txt"
+			if strings.Join(got, ",") != want {
+				t.Errorf("ReadDir(.) = %v, want %v", got, want)
+			}
+			if _, err := fsys.Open("README.TXT/x"); err == nil {
+				t.Errorf("Open(README.TXT/x): got nil, want error")
+			}
+			if _, err := fsys.Open("missing"); !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("Open(missing): got %v, want ErrNotExist", err)
+			}
+
+			f, err := fsys.Open("big.bin")
+			if err != nil {
+				t.Fatal(err)
+			}
+			b := make([]byte, 1000)
+			if _, err := f.(io.ReaderAt).ReadAt(b, 99500); err != io.EOF {
+				t.Errorf("ReadAt past the end: got %v, want EOF", err)
+			}
+		})
+	}
+}
+
+func TestBadChains(t *testing.T) {
+	tree := []*node{{name: "A", data: bytes.Repeat([]byte{1}, 2048)}}
+	img := newImage(FAT16, 40000, 1, tree)
+	fsys, err := New(bytes.NewReader(img))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A's chain is 2, 4, 6, 8. Make it loop, then point it out of range.
+	bld := &builder{b: img, typ: FAT16, fatStart: 512}
+	bld.setFAT(8, 2)
+	if _, err := fsys.Open("A"); err == nil || !strings.Contains(err.Error(), "loops") {
+		t.Errorf("Open with looping chain: got %v", err)
+	}
+	bld.setFAT(8, 0xfff0)
+	if _, err := fsys.Open("A"); err == nil || !strings.Contains(err.Error(), "out of range") {
+		t.Errorf("Open with chain out of range: got %v", err)
+	}
+	bld.setFAT(8, 0xfff7)
+	if _, err := fsys.Open("A"); err == nil || !strings.Contains(err.Error(), "bad cluster") {
+		t.Errorf("Open with bad cluster: got %v", err)
+	}
+}
+
+func TestNotFAT(t *testing.T) {
+	for _, b := range [][]byte{nil, make([]byte, 512), append([]byte{0xeb, 0x76, 0x90}, "EXFAT   "+string(make([]byte, 501))...)} {
+		if _, err := New(bytes.NewReader(b)); !errors.Is(err, ErrNotFAT) {
+			t.Errorf("New(%.12q): got %v, want ErrNotFAT", b, err)
+		}
+	}
+}
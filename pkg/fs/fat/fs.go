@@ -0,0 +1,188 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fat
+
+import (
+	"io"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+)
+
+var (
+	_ fs.ReadDirFS   = (*FS)(nil)
+	_ fs.ReadFileFS  = (*FS)(nil)
+	_ fs.StatFS      = (*FS)(nil)
+	_ io.ReaderAt    = (*file)(nil)
+	_ io.Seeker      = (*file)(nil)
+	_ fs.ReadDirFile = (*dir)(nil)
+)
+
+// Open implements fs.FS.
+func (fsys *FS) Open(name string) (fs.File, error) {
+	e, err := fsys.walk("open", name)
+	if err != nil {
+		return nil, err
+	}
+	fi := &fileInfo{name: path.Base(name), e: e}
+	if e.isDir() {
+		d, err := fsys.readDir(e)
+		if err != nil {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+		}
+		return &dir{fi: fi, ents: d.entries}, nil
+	}
+	cs, err := fsys.chain(e.cluster)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return &file{fsys: fsys, fi: fi, clusters: cs}, nil
+}
+
+// ReadDir implements fs.ReadDirFS. The entries are sorted by name.
+func (fsys *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	e, err := fsys.walk("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	if !e.isDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: syscall.ENOTDIR}
+	}
+	d, err := fsys.readDir(e)
+	if err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+	}
+	return dirEntries(d.entries), nil
+}
+
+// ReadFile implements fs.ReadFileFS.
+func (fsys *FS) ReadFile(name string) ([]byte, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := f.(*dir); ok {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: syscall.EISDIR}
+	}
+	fl := f.(*file)
+	b := make([]byte, fl.fi.e.size)
+	if _, err := fl.ReadAt(b, 0); err != nil && err != io.EOF {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: err}
+	}
+	return b, nil
+}
+
+// Stat implements fs.StatFS.
+func (fsys *FS) Stat(name string) (fs.FileInfo, error) {
+	e, err := fsys.walk("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return &fileInfo{name: path.Base(name), e: e}, nil
+}
+
+func dirEntries(ents []*entry) []fs.DirEntry {
+	des := make([]fs.DirEntry, 0, len(ents))
+	for _, e := range ents {
+		des = append(des, fs.FileInfoToDirEntry(&fileInfo{name: e.name, e: e}))
+	}
+	slices.SortFunc(des, func(a, b fs.DirEntry) int { return strings.Compare(a.Name(), b.Name()) })
+	return des
+}
+
+// fileInfo implements fs.FileInfo.
+type fileInfo struct {
+	name string
+	e    *entry
+}
+
+func (fi *fileInfo) Name() string       { return fi.name }
+func (fi *fileInfo) Mode() fs.FileMode  { return fi.e.mode() }
+func (fi *fileInfo) ModTime() time.Time { return fi.e.mtime }
+func (fi *fileInfo) IsDir() bool        { return fi.e.isDir() }
+func (fi *fileInfo) Sys() any           { return nil }
+
+func (fi *fileInfo) Size() int64 {
+	if fi.e.isDir() {
+		return 0
+	}
+	return int64(fi.e.size)
+}
+
+// file is an open regular file.
+type file struct {
+	fsys     *FS
+	fi       *fileInfo
+	clusters []uint32
+	off      int64
+}
+
+func (f *file) Stat() (fs.FileInfo, error) { return f.fi, nil }
+func (f *file) Close() error               { return nil }
+
+func (f *file) Read(p []byte) (int, error) {
+	n, err := f.ReadAt(p, f.off)
+	f.off += int64(n)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+// ReadAt implements io.ReaderAt.
+func (f *file) ReadAt(p []byte, off int64) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	return f.fsys.readChain(f.clusters, int64(f.fi.e.size), p, off)
+}
+
+// Seek implements io.Seeker.
+func (f *file) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.off
+	case io.SeekEnd:
+		offset += int64(f.fi.e.size)
+	default:
+		return 0, &fs.PathError{Op: "seek", Path: f.fi.name, Err: fs.ErrInvalid}
+	}
+	if offset < 0 {
+		return 0, &fs.PathError{Op: "seek", Path: f.fi.name, Err: fs.ErrInvalid}
+	}
+	f.off = offset
+	return offset, nil
+}
+
+// dir is an open directory.
+type dir struct {
+	fi   *fileInfo
+	ents []*entry
+	off  int
+}
+
+func (d *dir) Stat() (fs.FileInfo, error) { return d.fi, nil }
+func (d *dir) Close() error               { return nil }
+
+func (d *dir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.fi.name, Err: syscall.EISDIR}
+}
+
+// ReadDir implements fs.ReadDirFile.
+func (d *dir) ReadDir(n int) ([]fs.DirEntry, error) {
+	rest := d.ents[d.off:]
+	if n > 0 {
+		if len(rest) == 0 {
+			return nil, io.EOF
+		}
+		rest = rest[:min(n, len(rest))]
+	}
+	d.off += len(rest)
+	return dirEntries(rest), nil
+}
@@ -0,0 +1,223 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fat
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"strings"
+	"time"
+)
+
+const (
+	// Layout of file systems created by Format.
+	reservedSectors = 32
+	numFATs         = 2
+	fsInfoSector    = 1
+	backupSector    = 6
+	mediaFixed      = 0xf8
+
+	// maxFAT32Clusters is the highest cluster count a FAT32 FAT can
+	// address.
+	maxFAT32Clusters = 0x0ffffff5 - 2
+)
+
+// FormatOptions configure Format.
+type FormatOptions struct {
+	// Label is the volume label of at most 11 characters. It is
+	// stored in upper case.
+	Label string
+
+	// SectorSize is the logical sector size of the device, 512 if
+	// zero.
+	SectorSize int
+
+	// ClusterSize is the cluster size in bytes. If zero, it is chosen
+	// from the size of the file system as Windows does.
+	ClusterSize int
+
+	// VolumeID is the volume serial number. If zero, a random one is
+	// used.
+	VolumeID uint32
+}
+
+// ErrTooSmall is returned by Format if the file system would not have
+// enough clusters to be FAT32.
+var ErrTooSmall = errors.New("too small for FAT32")
+
+// defaultClusterSize returns the cluster size Windows uses for a FAT32
+// file system of size bytes.
+func defaultClusterSize(size int64) int {
+	switch {
+	case size <= 260<<20:
+		return 512
+	case size <= 8<<30:
+		return 4096
+	case size <= 16<<30:
+		return 8192
+	case size <= 32<<30:
+		return 16384
+	}
+	return 32768
+}
+
+// labelChars are the characters, besides letters and digits, that may
+// appear in a short name or a volume label.
+const labelChars = " !#$%&'()-@^_`{}~"
+
+func validLabel(label string) error {
+	if len(label) > 11 {
+		return fmt.Errorf("label %q is longer than 11 characters", label)
+	}
+	for _, c := range label {
+		if !('A' <= c && c <= 'Z' || '0' <= c && c <= '9' || strings.ContainsRune(labelChars, c)) {
+			return fmt.Errorf("label %q contains %q", label, c)
+		}
+	}
+	return nil
+}
+
+// Format creates an empty FAT32 file system of size bytes on w, e.g. an
+// EFI system partition.
+//
+// Only the boot sectors, the FATs and the root directory are written.
+// The file system carries no boot code.
+func Format(w io.WriterAt, size int64, o FormatOptions) error {
+	label := strings.ToUpper(o.Label)
+	if err := validLabel(label); err != nil {
+		return err
+	}
+	bps := o.SectorSize
+	if bps == 0 {
+		bps = 512
+	}
+	if bps < 512 || bps > 4096 || !isPowerOf2(uint32(bps)) {
+		return fmt.Errorf("invalid sector size %d", bps)
+	}
+	cs := o.ClusterSize
+	if cs == 0 {
+		cs = max(defaultClusterSize(size), bps)
+	}
+	if cs < bps || cs > 64*1024 || !isPowerOf2(uint32(cs)) {
+		return fmt.Errorf("invalid cluster size %d for %d-byte sectors", cs, bps)
+	}
+	spc := int64(cs / bps)
+	total := size / int64(bps)
+	if total > 0xffffffff {
+		return fmt.Errorf("%d sectors are too many for FAT32", total)
+	}
+
+	// Every cluster, plus the two reserved entries, needs four bytes
+	// in each FAT. Start with an estimate that ignores the space the
+	// FATs take and shrink them until the clusters fit exactly.
+	avail := total - reservedSectors
+	if avail <= 0 {
+		return fmt.Errorf("%w: %d bytes", ErrTooSmall, size)
+	}
+	fatSize := ((avail/spc+2)*4 + int64(bps) - 1) / int64(bps)
+	for {
+		clusters := (avail - numFATs*fatSize) / spc
+		need := ((clusters+2)*4 + int64(bps) - 1) / int64(bps)
+		if need >= fatSize {
+			fatSize = need
+			break
+		}
+		fatSize = need
+	}
+	clusters := (avail - numFATs*fatSize) / spc
+	if clusters <= maxFAT16Clusters {
+		return fmt.Errorf("%w: %d bytes give %d clusters of %d bytes, need at least %d",
+			ErrTooSmall, size, max(clusters, 0), cs, maxFAT16Clusters+1)
+	}
+	if clusters > maxFAT32Clusters {
+		return fmt.Errorf("%d clusters are too many for FAT32; use larger clusters", clusters)
+	}
+
+	serial := o.VolumeID
+	if serial == 0 {
+		serial = rand.Uint32()
+	}
+	bsLabel := label
+	if bsLabel == "" {
+		bsLabel = "NO NAME"
+	}
+
+	// Boot sector.
+	bs := make([]byte, bps)
+	copy(bs, []byte{0xeb, 0x58, 0x90})
+	copy(bs[0x03:], "u-root  ")
+	le.PutUint16(bs[0x0b:], uint16(bps))
+	bs[0x0d] = uint8(spc)
+	le.PutUint16(bs[0x0e:], reservedSectors)
+	bs[0x10] = numFATs
+	bs[0x15] = mediaFixed
+	le.PutUint16(bs[0x18:], 32) // sectors per track
+	le.PutUint16(bs[0x1a:], 64) // heads
+	le.PutUint32(bs[0x20:], uint32(total))
+	le.PutUint32(bs[0x24:], uint32(fatSize))
+	le.PutUint32(bs[0x2c:], 2) // root directory cluster
+	le.PutUint16(bs[0x30:], fsInfoSector)
+	le.PutUint16(bs[0x32:], backupSector)
+	bs[0x40] = 0x80 // drive number
+	bs[0x42] = 0x29
+	le.PutUint32(bs[0x43:], serial)
+	copy(bs[0x47:], fmt.Sprintf("%-11s", bsLabel))
+	copy(bs[0x52:], "FAT32   ")
+	bs[510], bs[511] = 0x55, 0xaa
+
+	// FSInfo sector. The root directory takes the first cluster.
+	info := make([]byte, bps)
+	le.PutUint32(info[0:], 0x41615252)
+	le.PutUint32(info[484:], 0x61417272)
+	le.PutUint32(info[488:], uint32(clusters-1))
+	le.PutUint32(info[492:], 3)
+	le.PutUint32(info[508:], 0xaa550000)
+
+	reserved := make([]byte, reservedSectors*bps)
+	for _, s := range []int{0, backupSector} {
+		copy(reserved[s*bps:], bs)
+		copy(reserved[(s+fsInfoSector)*bps:], info)
+	}
+	// The third sector of each boot record only carries a signature.
+	for _, s := range []int{2, backupSector + 2} {
+		reserved[(s+1)*bps-2], reserved[(s+1)*bps-1] = 0x55, 0xaa
+	}
+	if _, err := w.WriteAt(reserved, 0); err != nil {
+		return err
+	}
+
+	fat := make([]byte, fatSize*int64(bps))
+	le.PutUint32(fat[0:], 0x0fffff00|mediaFixed)
+	le.PutUint32(fat[4:], 0x0fffffff)
+	le.PutUint32(fat[8:], 0x0fffffff)
+	for i := range int64(numFATs) {
+		if _, err := w.WriteAt(fat, (reservedSectors+i*fatSize)*int64(bps)); err != nil {
+			return err
+		}
+	}
+
+	root := make([]byte, cs)
+	if label != "" {
+		copy(root, fmt.Sprintf("%-11s", label))
+		root[11] = attrVolumeID
+		d, t := dosDateTime(time.Now())
+		le.PutUint16(root[22:], t)
+		le.PutUint16(root[24:], d)
+	}
+	_, err := w.WriteAt(root, (reservedSectors+numFATs*fatSize)*int64(bps))
+	return err
+}
+
+// dosDateTime encodes t as a FAT date and time.
+func dosDateTime(t time.Time) (uint16, uint16) {
+	t = t.UTC()
+	if t.Year() < 1980 {
+		t = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	d := uint16(t.Year()-1980)<<9 | uint16(t.Month())<<5 | uint16(t.Day())
+	return d, uint16(t.Hour())<<11 | uint16(t.Minute())<<5 | uint16(t.Second()/2)
+}
@@ -0,0 +1,116 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fat
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	for _, tt := range []struct {
+		name        string
+		size        int64
+		o           FormatOptions
+		clusterSize int64
+		label       string
+		uuid        string
+	}{
+		{
+			name:        "defaults",
+			size:        64 << 20,
+			o:           FormatOptions{VolumeID: 0xdeadbeef},
+			clusterSize: 512,
+			uuid:        "DEAD-BEEF",
+		},
+		{
+			name:        "esp",
+			size:        300 << 20,
+			o:           FormatOptions{Label: "efi system", VolumeID: 0x1234abcd},
+			clusterSize: 4096,
+			label:       "EFI SYSTEM",
+			uuid:        "1234-ABCD",
+		},
+		{
+			name:        "4k sectors",
+			size:        600 << 20,
+			o:           FormatOptions{Label: "DATA", SectorSize: 4096, ClusterSize: 8192, VolumeID: 1},
+			clusterSize: 8192,
+			label:       "DATA",
+			uuid:        "0000-0001",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := os.Create(filepath.Join(t.TempDir(), "img"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			if err := f.Truncate(tt.size); err != nil {
+				t.Fatal(err)
+			}
+			if err := Format(f, tt.size, tt.o); err != nil {
+				t.Fatalf("Format = %v", err)
+			}
+
+			fsys, err := New(f)
+			if err != nil {
+				t.Fatalf("New = %v", err)
+			}
+			if fsys.Type() != FAT32 || fsys.clusterSize != tt.clusterSize {
+				t.Errorf("got %v with %d-byte clusters, want FAT32 with %d-byte clusters", fsys.Type(), fsys.clusterSize, tt.clusterSize)
+			}
+			if fsys.Label() != tt.label || fsys.UUID() != tt.uuid {
+				t.Errorf("label %q, UUID %s, want %q, %s", fsys.Label(), fsys.UUID(), tt.label, tt.uuid)
+			}
+			if ents, err := fsys.ReadDir("."); err != nil || len(ents) != 0 {
+				t.Errorf("ReadDir(.) = %v, %v, want an empty root directory", ents, err)
+			}
+			// The data area must end within the file system.
+			if end := fsys.clusterOffset(fsys.clusters + 2); end > tt.size {
+				t.Errorf("%d clusters end at %d, beyond %d", fsys.clusters, end, tt.size)
+			}
+			// Every cluster is free, except the root directory's.
+			for _, c := range []uint32{3, fsys.clusters + 1} {
+				if n, err := fsys.next(c); err != nil || n != 0 {
+					t.Errorf("FAT entry of cluster %d = %#x, %v, want 0", c, n, err)
+				}
+			}
+			b := make([]byte, 512)
+			if _, err := f.ReadAt(b, backupSector*fsys.fatStart/reservedSectors); err != nil {
+				t.Fatal(err)
+			}
+			if b[510] != 0x55 || b[511] != 0xaa || string(b[0x52:0x5a]) != "FAT32   " {
+				t.Errorf("no backup boot sector")
+			}
+		})
+	}
+}
+
+func TestFormatErrors(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "img"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := Format(f, 16<<20, FormatOptions{}); !errors.Is(err, ErrTooSmall) {
+		t.Errorf("Format(16MiB) = %v, want %v", err, ErrTooSmall)
+	}
+	if err := Format(f, 256<<20, FormatOptions{ClusterSize: 4096}); !errors.Is(err, ErrTooSmall) {
+		t.Errorf("Format(256MiB, 4KiB clusters) = %v, want %v", err, ErrTooSmall)
+	}
+	for _, o := range []FormatOptions{
+		{Label: "TOO LONG LABEL"},
+		{Label: "A/B"},
+		{ClusterSize: 1000},
+		{SectorSize: 8192},
+	} {
+		if err := Format(f, 64<<20, o); err == nil {
+			t.Errorf("Format(%+v) succeeded, want an error", o)
+		}
+	}
+}
@@ -0,0 +1,286 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package iperf3
+
+import (
+	"cmp"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"strings"
+	"time"
+)
+
+// Client runs a test against a server.
+type Client struct {
+	// Network is tcp, tcp4 or tcp6 for TCP tests, and udp, udp4 or
+	// udp6 for UDP tests.
+	Network string
+
+	// Addr is the address of the server, as host:port.
+	Addr string
+
+	// Parallel is the number of streams. It defaults to 1.
+	Parallel int
+
+	// Reverse makes the server send, and the client receive.
+	Reverse bool
+
+	// Duration is how long the test runs, unless Bytes is set. It
+	// defaults to DefaultDuration.
+	Duration time.Duration
+
+	// Bytes, if set, is how much to send over all streams instead.
+	Bytes int64
+
+	// BlockSize is the size of writes, and of UDP datagrams. It defaults
+	// to DefaultTCPBlockSize for TCP, and to the MSS of the control
+	// connection for UDP.
+	BlockSize int
+
+	// Bitrate is the bitrate of each stream in bits per second. It
+	// defaults to DefaultUDPBitrate for UDP, and to unlimited for TCP.
+	Bitrate uint64
+
+	// Interval is how often to report. It defaults to DefaultInterval.
+	Interval time.Duration
+
+	// Reporter, if not nil, is told about the test as it runs.
+	Reporter Reporter
+}
+
+// controlNetwork returns the TCP network of the control connection, and
+// whether the test is UDP.
+func controlNetwork(network string) (string, bool, error) {
+	switch network {
+	case "", "tcp", "tcp4", "tcp6":
+		return cmp.Or(network, "tcp"), false, nil
+	case "udp", "udp4", "udp6":
+		return "tcp" + strings.TrimPrefix(network, "udp"), true, nil
+	}
+	return "", false, fmt.Errorf("unsupported network %q", network)
+}
+
+// Run runs the test, and returns its result once the server has sent its
+// side of it.
+func (c *Client) Run(ctx context.Context) (*Result, error) {
+	network, udp, err := controlNetwork(c.Network)
+	if err != nil {
+		return nil, err
+	}
+	p := &params{
+		TCP:           !udp,
+		UDP:           udp,
+		Time:          int(math.Ceil(cmp.Or(c.Duration, DefaultDuration).Seconds())),
+		Num:           float64(c.Bytes),
+		Parallel:      max(c.Parallel, 1),
+		Reverse:       c.Reverse,
+		Len:           c.BlockSize,
+		Bandwidth:     float64(c.Bitrate),
+		PacingTimer:   1000,
+		ClientVersion: clientVersion,
+	}
+	if c.Bytes > 0 {
+		p.Time = 0
+	}
+	if p.Parallel > maxStreams {
+		return nil, fmt.Errorf("%d streams are more than %d", p.Parallel, maxStreams)
+	}
+	if udp {
+		p.UDPCounters64Bit = 1
+		if p.Bandwidth == 0 {
+			p.Bandwidth = DefaultUDPBitrate
+		}
+	}
+
+	var d net.Dialer
+	ctrl, err := d.DialContext(ctx, network, c.Addr)
+	if err != nil {
+		return nil, err
+	}
+	defer ctrl.Close()
+	// Cancelling tells the server, and unblocks reads.
+	defer context.AfterFunc(ctx, func() {
+		writeState(ctrl, clientTerminate)
+		ctrl.Close()
+	})()
+
+	if p.Len == 0 {
+		p.Len = DefaultTCPBlockSize
+		if udp {
+			p.Len = DefaultUDPBlockSize
+			if mss := tcpMSS(ctrl); mss > 0 {
+				p.Len = min(mss, MaxUDPBlockSize)
+			}
+		}
+	}
+	if udp && (p.Len < udpHeaderLen(true) || p.Len > MaxUDPBlockSize) {
+		return nil, fmt.Errorf("UDP block size %d is not between %d and %d", p.Len, udpHeaderLen(true), MaxUDPBlockSize)
+	}
+
+	cookie := newCookie()
+	if _, err := ctrl.Write(cookie); err != nil {
+		return nil, err
+	}
+	t := newTest(p, !p.Reverse, cmp.Or(c.Interval, DefaultInterval), c.Reporter)
+	t.result.Start.Cookie = string(cookie[:cookieLen-1])
+	server := addrPort(ctrl.RemoteAddr())
+	t.result.Start.ConnectingTo = &Host{Host: server.Addr().String(), Port: int(server.Port())}
+	defer t.close()
+
+	res, err := c.run(ctx, ctrl, cookie, p, t)
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return res, err
+}
+
+// run runs the test on the control connection ctrl.
+func (c *Client) run(ctx context.Context, ctrl net.Conn, cookie []byte, p *params, t *test) (*Result, error) {
+	if err := expect(ctrl, paramExchange); err != nil {
+		return nil, err
+	}
+	if err := writeJSON(ctrl, p); err != nil {
+		return nil, err
+	}
+	if err := expect(ctrl, createStreams); err != nil {
+		return nil, err
+	}
+	for range p.Parallel {
+		if err := c.connect(ctx, ctrl, cookie, t); err != nil {
+			return nil, fmt.Errorf("connecting stream: %w", err)
+		}
+	}
+	if err := expect(ctrl, testStart); err != nil {
+		return nil, err
+	}
+	t.run()
+	if err := expect(ctrl, testRunning); err != nil {
+		return nil, err
+	}
+
+	// The server only speaks once the test ends.
+	next := make(chan error, 1)
+	go func() { next <- expect(ctrl, exchangeResults) }()
+	var timeout <-chan time.Time
+	if p.Time > 0 {
+		timer := time.NewTimer(cmp.Or(c.Duration, DefaultDuration))
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case <-timeout:
+	case <-t.full:
+	case err := <-next:
+		if err == nil {
+			err = errors.New("the server ended the test early")
+		}
+		return nil, err
+	}
+	t.finish()
+	if err := writeState(ctrl, testEnd); err != nil {
+		return nil, err
+	}
+
+	if err := <-next; err != nil {
+		return nil, err
+	}
+	if err := writeJSON(ctrl, t.results()); err != nil {
+		return nil, err
+	}
+	var peer results
+	if err := readJSON(ctrl, &peer); err != nil {
+		return nil, fmt.Errorf("reading results: %w", err)
+	}
+	if err := expect(ctrl, displayResults); err != nil {
+		return nil, err
+	}
+	if err := writeState(ctrl, iperfDone); err != nil {
+		return nil, err
+	}
+	return t.summarize(&peer)
+}
+
+// connect connects a stream of the test to the server.
+func (c *Client) connect(ctx context.Context, ctrl net.Conn, cookie []byte, t *test) error {
+	// Streams go to the address the control connection went to.
+	server := ctrl.RemoteAddr().String()
+	var d net.Dialer
+	if !t.udp {
+		conn, err := d.DialContext(ctx, ctrl.RemoteAddr().Network(), server)
+		if err != nil {
+			return err
+		}
+		if _, err := conn.Write(cookie); err != nil {
+			conn.Close()
+			return err
+		}
+		t.addStream(conn, conn.LocalAddr(), conn.RemoteAddr(), conn.Write)
+		return nil
+	}
+
+	network := "udp4"
+	if addrPort(ctrl.RemoteAddr()).Addr().Is6() {
+		network = "udp6"
+	}
+	conn, err := d.DialContext(ctx, network, server)
+	if err != nil {
+		return err
+	}
+	if uc, ok := conn.(*net.UDPConn); ok {
+		// Best effort, for high bitrates.
+		uc.SetReadBuffer(4 << 20)
+		uc.SetWriteBuffer(4 << 20)
+	}
+	// The server learns the address of the stream from its first
+	// datagram.
+	if _, err := conn.Write(binary.NativeEndian.AppendUint32(nil, udpConnectMsg)); err != nil {
+		conn.Close()
+		return err
+	}
+	conn.SetReadDeadline(time.Now().Add(30 * time.Second))
+	var b [4]byte
+	if _, err := io.ReadFull(conn, b[:]); err != nil {
+		conn.Close()
+		return err
+	}
+	conn.SetReadDeadline(time.Time{})
+	if r := binary.NativeEndian.Uint32(b[:]); r != udpConnectReply && r != legacyUDPConnectReply {
+		conn.Close()
+		return fmt.Errorf("unexpected reply %#x to UDP connect", r)
+	}
+	t.addStream(conn, conn.LocalAddr(), conn.RemoteAddr(), conn.Write)
+	return nil
+}
+
+// expect reads the next state of the server, which should be want.
+func expect(ctrl net.Conn, want state) error {
+	s, err := readState(ctrl)
+	if errors.Is(err, io.EOF) {
+		return errors.New("the server closed the control connection")
+	}
+	if err != nil {
+		return err
+	}
+	switch s {
+	case want:
+		return nil
+	case accessDenied:
+		return ErrBusy
+	case serverTerminate:
+		return errors.New("the server has terminated")
+	case serverError:
+		var e ServerError
+		if err := binary.Read(ctrl, binary.BigEndian, &e); err != nil {
+			return fmt.Errorf("the server failed: %w", err)
+		}
+		return &e
+	}
+	return fmt.Errorf("unexpected state %d from the server, want %d", s, want)
+}
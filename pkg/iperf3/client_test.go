@@ -0,0 +1,136 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package iperf3
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// serve starts a server on the loopback, serving until the test ends.
+func serve(t *testing.T) *Server {
+	t.Helper()
+	s, err := Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Logf = t.Logf
+	s.Interval = 100 * time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.Serve(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		s.Close()
+		<-done
+	})
+	return s
+}
+
+func TestLoopback(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		c    Client
+	}{
+		{name: "tcp", c: Client{}},
+		{name: "tcp reverse", c: Client{Reverse: true}},
+		{name: "tcp parallel", c: Client{Parallel: 3}},
+		{name: "tcp bytes", c: Client{Bytes: 4 << 20, Duration: time.Minute}},
+		{name: "tcp bitrate", c: Client{Bitrate: 8 << 20}},
+		{name: "udp", c: Client{Network: "udp", Bitrate: 10 << 20}},
+		{name: "udp reverse parallel", c: Client{Network: "udp", Reverse: true, Parallel: 2, BlockSize: 1200}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			s := serve(t)
+			c := tt.c
+			c.Addr = s.Addr().String()
+			c.Duration = cmpDuration(c.Duration, 500*time.Millisecond)
+			c.Interval = 100 * time.Millisecond
+			var out bytes.Buffer
+			c.Reporter = &TextReporter{W: &out}
+			res, err := c.Run(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Logf("\n%s", out.String())
+
+			n := max(c.Parallel, 1)
+			if len(res.Start.Connected) != n || len(res.End.Streams) != n {
+				t.Fatalf("%d streams connected and %d ended, want %d", len(res.Start.Connected), len(res.End.Streams), n)
+			}
+			for i, s := range res.End.Streams {
+				if s.Sender.Socket != streamID(i) || s.Receiver.Socket != streamID(i) {
+					t.Errorf("stream %d has IDs %d and %d, want %d", i, s.Sender.Socket, s.Receiver.Socket, streamID(i))
+				}
+			}
+			sent, recv := res.End.SumSent, res.End.SumReceived
+			if sent.Bytes == 0 || recv.Bytes == 0 || recv.Bytes > sent.Bytes {
+				t.Errorf("sent %d bytes and received %d", sent.Bytes, recv.Bytes)
+			}
+			if c.Bytes > 0 && sent.Bytes < c.Bytes {
+				t.Errorf("sent %d bytes, want at least %d", sent.Bytes, c.Bytes)
+			}
+			if c.Bitrate > 0 && sent.BitsPerSecond > 1.5*float64(c.Bitrate)*float64(n) {
+				t.Errorf("sent %.0f bits/sec, want at most %d", sent.BitsPerSecond, c.Bitrate)
+			}
+			if c.Bytes == 0 && len(res.Intervals) == 0 {
+				t.Errorf("no intervals")
+			}
+			if !strings.Contains(out.String(), "receiver") {
+				t.Errorf("no summary in %q", out.String())
+			}
+		})
+	}
+}
+
+func cmpDuration(d, def time.Duration) time.Duration {
+	if d == 0 {
+		return def
+	}
+	return d
+}
+
+func TestBusy(t *testing.T) {
+	s := serve(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	first := make(chan error, 1)
+	go func() {
+		_, err := (&Client{Addr: s.Addr().String(), Duration: 2 * time.Second}).Run(ctx)
+		first <- err
+	}()
+
+	// Wait for the first test to run.
+	var err error
+	for range 50 {
+		time.Sleep(50 * time.Millisecond)
+		if _, err = (&Client{Addr: s.Addr().String(), Duration: 100 * time.Millisecond}).Run(ctx); errors.Is(err, ErrBusy) {
+			break
+		}
+	}
+	if !errors.Is(err, ErrBusy) {
+		t.Errorf("second test = %v, want %v", err, ErrBusy)
+	}
+	cancel()
+	<-first
+
+	// The server serves the next test, once it is done with the first.
+	for range 50 {
+		if _, err = (&Client{Addr: s.Addr().String(), Duration: 200 * time.Millisecond}).Run(context.Background()); !errors.Is(err, ErrBusy) {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if err != nil {
+		t.Errorf("third test: %v", err)
+	}
+}
@@ -0,0 +1,275 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package iperf3 implements network throughput tests that speak the
+// protocol of iperf3, so that a Client can test against iperf3 servers and
+// iperf3 clients against a Server.
+//
+// A test has a TCP control connection, over which the client sends the
+// parameters as JSON and the server drives the test with one-byte states,
+// and one or more TCP or UDP data streams. At the end, both sides exchange
+// their counters, so that each reports what the other saw.
+package iperf3
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultPort is the port of iperf3 servers.
+const DefaultPort = 5201
+
+// Defaults of tests.
+const (
+	DefaultDuration     = 10 * time.Second
+	DefaultInterval     = time.Second
+	DefaultTCPBlockSize = 128 << 10
+	DefaultUDPBlockSize = 1460
+	DefaultUDPBitrate   = 1 << 20
+)
+
+// MaxUDPBlockSize is the largest UDP payload.
+const MaxUDPBlockSize = 65507
+
+// maxStreams is the most parallel streams of a test, as in iperf3.
+const maxStreams = 128
+
+// clientVersion is sent to servers, which only log it.
+const clientVersion = "3.17 (u-root)"
+
+// state is a step of a test, which the server sends on the control
+// connection.
+type state int8
+
+const (
+	testStart       state = 1
+	testRunning     state = 2
+	testEnd         state = 4
+	paramExchange   state = 9
+	createStreams   state = 10
+	serverTerminate state = 11
+	clientTerminate state = 12
+	exchangeResults state = 13
+	displayResults  state = 14
+	iperfDone       state = 16
+	accessDenied    state = -1
+	serverError     state = -2
+)
+
+// errUnimplemented is the iperf3 error number of unimplemented features.
+const errUnimplemented = 13
+
+// The first datagram of a UDP stream, and the server's reply, in the byte
+// order of the host as iperf3 sends them. Old versions send other values.
+const (
+	udpConnectMsg         = 0x36373839
+	udpConnectReply       = 0x39383736
+	legacyUDPConnectReply = 987654321
+)
+
+// cookieLen is the length of the cookie that identifies a test on its
+// connections, with its NUL.
+const cookieLen = 37
+
+// newCookie returns a random cookie, as iperf3 makes them.
+func newCookie() []byte {
+	const chars = "abcdefghijklmnopqrstuvwxyz234567"
+	c := make([]byte, cookieLen)
+	rand.Read(c[:cookieLen-1])
+	for i := range cookieLen - 1 {
+		c[i] = chars[int(c[i])%len(chars)]
+	}
+	c[cookieLen-1] = 0
+	return c
+}
+
+// streamID returns the ID of the ith stream of a test. iperf3 skips 2.
+func streamID(i int) int {
+	if i == 0 {
+		return 1
+	}
+	return i + 2
+}
+
+// params are the parameters of a test, which the client sends to the
+// server. Numbers that may be large are floats, as iperf3 uses doubles.
+type params struct {
+	TCP              bool    `json:"tcp,omitempty"`
+	UDP              bool    `json:"udp,omitempty"`
+	SCTP             bool    `json:"sctp,omitempty"`
+	Omit             int     `json:"omit"`
+	Time             int     `json:"time"`
+	Num              float64 `json:"num"`
+	BlockCount       float64 `json:"blockcount"`
+	MSS              int     `json:"MSS,omitempty"`
+	NoDelay          bool    `json:"nodelay,omitempty"`
+	Parallel         int     `json:"parallel"`
+	Reverse          bool    `json:"reverse,omitempty"`
+	Bidirectional    bool    `json:"bidirectional,omitempty"`
+	Window           int     `json:"window,omitempty"`
+	Len              int     `json:"len,omitempty"`
+	Bandwidth        float64 `json:"bandwidth,omitempty"`
+	PacingTimer      int     `json:"pacing_timer,omitempty"`
+	UDPCounters64Bit int     `json:"udp_counters_64bit,omitempty"`
+	ClientVersion    string  `json:"client_version,omitempty"`
+}
+
+// results are the counters of one side of a test, which the sides
+// exchange at its end.
+type results struct {
+	CPUUtilTotal         float64         `json:"cpu_util_total"`
+	CPUUtilUser          float64         `json:"cpu_util_user"`
+	CPUUtilSystem        float64         `json:"cpu_util_system"`
+	SenderHasRetransmits int             `json:"sender_has_retransmits"`
+	Streams              []streamResults `json:"streams"`
+}
+
+type streamResults struct {
+	ID             int     `json:"id"`
+	Bytes          float64 `json:"bytes"`
+	Retransmits    float64 `json:"retransmits"`
+	Jitter         float64 `json:"jitter"`
+	Errors         float64 `json:"errors"`
+	OmittedErrors  float64 `json:"omitted_errors"`
+	Packets        float64 `json:"packets"`
+	OmittedPackets float64 `json:"omitted_packets"`
+	StartTime      float64 `json:"start_time"`
+	EndTime        float64 `json:"end_time"`
+}
+
+// maxJSONLen bounds the JSON messages of the control connection.
+const maxJSONLen = 1 << 20
+
+// writeJSON writes v as iperf3 does: its length as 4 bytes, then the JSON.
+func writeJSON(w io.Writer, v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(binary.BigEndian.AppendUint32(nil, uint32(len(b))), b...))
+	return err
+}
+
+func readJSON(r io.Reader, v any) error {
+	var l [4]byte
+	if _, err := io.ReadFull(r, l[:]); err != nil {
+		return err
+	}
+	n := binary.BigEndian.Uint32(l[:])
+	if n > maxJSONLen {
+		return fmt.Errorf("JSON message of %d bytes is too long", n)
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+func writeState(w io.Writer, s state) error {
+	_, err := w.Write([]byte{byte(s)})
+	return err
+}
+
+func readState(r io.Reader) (state, error) {
+	var b [1]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return 0, err
+	}
+	return state(int8(b[0])), nil
+}
+
+// ServerError is an error that the server reports with iperf3 error
+// numbers.
+type ServerError struct {
+	Code  int32
+	Errno int32
+}
+
+func (e *ServerError) Error() string {
+	if e.Code == errUnimplemented {
+		return "the server does not implement the test"
+	}
+	return fmt.Sprintf("server error %d (errno %d)", e.Code, e.Errno)
+}
+
+// ErrBusy is returned when the server is running another test.
+var ErrBusy = errors.New("the server is busy running a test")
+
+// ParseSize parses a size in bytes, with an optional K, M, G or T suffix of
+// powers of 1024, as iperf3 does for lengths.
+func ParseSize(s string) (int64, error) {
+	v, err := parseUnits(s, 1024)
+	if err != nil {
+		return 0, err
+	}
+	if v > math.MaxInt64 {
+		return 0, fmt.Errorf("size %q is too large", s)
+	}
+	return int64(v), nil
+}
+
+// ParseBitrate parses a bitrate in bits per second, with an optional K, M,
+// G or T suffix of powers of 1000, as iperf3 does.
+func ParseBitrate(s string) (uint64, error) {
+	v, err := parseUnits(s, 1000)
+	if err != nil {
+		return 0, err
+	}
+	if v > math.MaxUint64 {
+		return 0, fmt.Errorf("bitrate %q is too large", s)
+	}
+	return uint64(v), nil
+}
+
+func parseUnits(s string, base float64) (float64, error) {
+	mult := 1.0
+	if i := strings.IndexAny(s, "kKmMgGtT"); i >= 0 && i == len(s)-1 {
+		mult = math.Pow(base, float64(strings.IndexByte("kmgt", strings.ToLower(s)[i])+1))
+		s = s[:i]
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || v < 0 || math.IsInf(v, 0) || math.IsNaN(v) {
+		return 0, fmt.Errorf("invalid number %q", s)
+	}
+	return v * mult, nil
+}
+
+// FormatBytes formats n bytes as iperf3 shows transfers, as in
+// "1.10 GBytes".
+func FormatBytes(n float64) string {
+	return formatUnits(n, 1024, "Bytes")
+}
+
+// FormatBitrate formats bits per second as iperf3 does, as in
+// "941 Mbits/sec".
+func FormatBitrate(bps float64) string {
+	return formatUnits(bps, 1000, "bits/sec")
+}
+
+func formatUnits(v, base float64, unit string) string {
+	prefix := ""
+	for _, p := range []string{"K", "M", "G", "T"} {
+		if v < base {
+			break
+		}
+		v /= base
+		prefix = p
+	}
+	switch {
+	case v < 9.995:
+		return fmt.Sprintf("%4.2f %s%s", v, prefix, unit)
+	case v < 99.95:
+		return fmt.Sprintf("%4.1f %s%s", v, prefix, unit)
+	}
+	return fmt.Sprintf("%4.0f %s%s", v, prefix, unit)
+}
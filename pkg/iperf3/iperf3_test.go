@@ -0,0 +1,100 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package iperf3
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestParseUnits(t *testing.T) {
+	for _, tt := range []struct {
+		in      string
+		size    int64
+		bitrate uint64
+	}{
+		{in: "0", size: 0, bitrate: 0},
+		{in: "1460", size: 1460, bitrate: 1460},
+		{in: "128K", size: 128 << 10, bitrate: 128000},
+		{in: "1.5m", size: 3 << 19, bitrate: 1500000},
+		{in: "10G", size: 10 << 30, bitrate: 10000000000},
+		{in: "1t", size: 1 << 40, bitrate: 1000000000000},
+	} {
+		if got, err := ParseSize(tt.in); err != nil || got != tt.size {
+			t.Errorf("ParseSize(%q) = %d, %v, want %d", tt.in, got, err, tt.size)
+		}
+		if got, err := ParseBitrate(tt.in); err != nil || got != tt.bitrate {
+			t.Errorf("ParseBitrate(%q) = %d, %v, want %d", tt.in, got, err, tt.bitrate)
+		}
+	}
+	for _, in := range []string{"", "K", "-1", "1X", "1KK", "NaN", "Inf"} {
+		if _, err := ParseSize(in); err == nil {
+			t.Errorf("ParseSize(%q) succeeded", in)
+		}
+	}
+}
+
+func TestFormatUnits(t *testing.T) {
+	for _, tt := range []struct {
+		got, want string
+	}{
+		{FormatBytes(0), "0.00 Bytes"},
+		{FormatBytes(512), " 512 Bytes"},
+		{FormatBytes(112 << 20), " 112 MBytes"},
+		{FormatBytes(1.1 * (1 << 30)), "1.10 GBytes"},
+		{FormatBitrate(941e6), " 941 Mbits/sec"},
+		{FormatBitrate(1.05e6), "1.05 Mbits/sec"},
+		{FormatBitrate(25.5e9), "25.5 Gbits/sec"},
+	} {
+		if tt.got != tt.want {
+			t.Errorf("got %q, want %q", tt.got, tt.want)
+		}
+	}
+}
+
+func TestCookie(t *testing.T) {
+	c := newCookie()
+	if len(c) != cookieLen || c[cookieLen-1] != 0 {
+		t.Fatalf("cookie %q is not %d bytes with a NUL", c, cookieLen)
+	}
+	for _, b := range c[:cookieLen-1] {
+		if !bytes.ContainsRune([]byte("abcdefghijklmnopqrstuvwxyz234567"), rune(b)) {
+			t.Errorf("cookie %q has %q", c, b)
+		}
+	}
+}
+
+func TestStreamID(t *testing.T) {
+	for i, want := range []int{1, 3, 4, 5} {
+		if got := streamID(i); got != want {
+			t.Errorf("streamID(%d) = %d, want %d", i, got, want)
+		}
+	}
+}
+
+func TestJSONFraming(t *testing.T) {
+	var b bytes.Buffer
+	if err := writeJSON(&b, &params{TCP: true, Time: 10, Parallel: 1, Len: 131072}); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"tcp":true,"omit":0,"time":10,"num":0,"blockcount":0,"parallel":1,"len":131072}`
+	if got := b.Bytes(); int(got[3]) != len(want) || string(got[4:]) != want {
+		t.Errorf("writeJSON = %q, want %q with its length", got, want)
+	}
+
+	// iperf3 writes whole numbers of doubles in exponent form when they
+	// are large.
+	var p params
+	msg := `{"udp":true,"time":5,"parallel":2,"reverse":true,"len":1448,"bandwidth":1e+10,"num":0}`
+	if err := readJSON(bytes.NewReader(append([]byte{0, 0, 0, byte(len(msg))}, msg...)), &p); err != nil {
+		t.Fatal(err)
+	}
+	if !p.UDP || !p.Reverse || p.Parallel != 2 || p.Len != 1448 || p.Bandwidth != 1e10 {
+		t.Errorf("readJSON = %+v", p)
+	}
+	if err := readJSON(bytes.NewReader([]byte{0xff, 0, 0, 0}), &p); err == nil {
+		t.Error("readJSON of a huge message succeeded")
+	}
+}
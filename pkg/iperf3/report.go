@@ -0,0 +1,214 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package iperf3
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Result is the result of a test, in the layout of iperf3 --json.
+type Result struct {
+	Start     Start      `json:"start"`
+	Intervals []Interval `json:"intervals"`
+	End       End        `json:"end"`
+}
+
+// Start describes a test as it starts.
+type Start struct {
+	Connected          []Connection `json:"connected"`
+	Version            string       `json:"version"`
+	Timestamp          Timestamp    `json:"timestamp"`
+	ConnectingTo       *Host        `json:"connecting_to,omitempty"`
+	AcceptedConnection *Host        `json:"accepted_connection,omitempty"`
+	Cookie             string       `json:"cookie"`
+	TestStart          TestStart    `json:"test_start"`
+}
+
+// Connection is a data stream. Socket is the stream ID, where iperf3 has
+// the file descriptor.
+type Connection struct {
+	Socket     int    `json:"socket"`
+	LocalHost  string `json:"local_host"`
+	LocalPort  int    `json:"local_port"`
+	RemoteHost string `json:"remote_host"`
+	RemotePort int    `json:"remote_port"`
+}
+
+// Timestamp is when a test started.
+type Timestamp struct {
+	Time     string `json:"time"`
+	TimeSecs int64  `json:"timesecs"`
+}
+
+// Host is the other end of the control connection.
+type Host struct {
+	Host string `json:"host"`
+	Port int    `json:"port"`
+}
+
+// TestStart are the parameters of a test.
+type TestStart struct {
+	Protocol   string `json:"protocol"`
+	NumStreams int    `json:"num_streams"`
+	BlkSize    int    `json:"blksize"`
+	Omit       int    `json:"omit"`
+	Duration   int    `json:"duration"`
+	Bytes      int64  `json:"bytes"`
+	Blocks     int64  `json:"blocks"`
+	Reverse    int    `json:"reverse"`
+}
+
+// Interval are the counters of the streams over a reporting interval.
+type Interval struct {
+	Streams []Stats `json:"streams"`
+	Sum     Stats   `json:"sum"`
+}
+
+// Stats are the counters of one stream, or of all streams, over a time.
+// Retransmits are only known to TCP senders, and JitterMS and LostPackets
+// to UDP receivers.
+type Stats struct {
+	Socket        int     `json:"socket,omitempty"`
+	Start         float64 `json:"start"`
+	End           float64 `json:"end"`
+	Seconds       float64 `json:"seconds"`
+	Bytes         int64   `json:"bytes"`
+	BitsPerSecond float64 `json:"bits_per_second"`
+	Retransmits   int64   `json:"retransmits"`
+	JitterMS      float64 `json:"jitter_ms"`
+	LostPackets   int64   `json:"lost_packets"`
+	Packets       int64   `json:"packets"`
+	LostPercent   float64 `json:"lost_percent"`
+	OutOfOrder    int64   `json:"out_of_order"`
+	Omitted       bool    `json:"omitted"`
+	Sender        bool    `json:"sender"`
+}
+
+// End is the summary of a test, as both sides saw it.
+type End struct {
+	Streams               []EndStream `json:"streams"`
+	SumSent               Stats       `json:"sum_sent"`
+	SumReceived           Stats       `json:"sum_received"`
+	CPUUtilizationPercent CPU         `json:"cpu_utilization_percent"`
+}
+
+// EndStream is the summary of a stream.
+type EndStream struct {
+	Sender   Stats `json:"sender"`
+	Receiver Stats `json:"receiver"`
+}
+
+// CPU is the CPU use of both sides, in percent of the wall time.
+type CPU struct {
+	HostTotal    float64 `json:"host_total"`
+	HostUser     float64 `json:"host_user"`
+	HostSystem   float64 `json:"host_system"`
+	RemoteTotal  float64 `json:"remote_total"`
+	RemoteUser   float64 `json:"remote_user"`
+	RemoteSystem float64 `json:"remote_system"`
+}
+
+// Reporter is told about a test as it runs.
+type Reporter interface {
+	// Start is called when the streams are connected, with the Start of
+	// the result.
+	Start(*Result)
+	// Interval is called at the end of each reporting interval.
+	Interval(*Interval)
+	// End is called with the complete result.
+	End(*Result)
+}
+
+// TextReporter prints tests as iperf3 does.
+type TextReporter struct {
+	W io.Writer
+
+	udp    bool
+	sender bool
+}
+
+const separator = "- - - - - - - - - - - - - - - - - - - - - - - - -"
+
+// Start implements Reporter.
+func (r *TextReporter) Start(res *Result) {
+	s := &res.Start
+	r.udp = s.TestStart.Protocol == "UDP"
+	switch {
+	case s.ConnectingTo != nil:
+		fmt.Fprintf(r.W, "Connecting to host %s, port %d\n", s.ConnectingTo.Host, s.ConnectingTo.Port)
+		if s.TestStart.Reverse != 0 {
+			fmt.Fprintf(r.W, "Reverse mode, remote host %s is sending\n", s.ConnectingTo.Host)
+		}
+		r.sender = s.TestStart.Reverse == 0
+	case s.AcceptedConnection != nil:
+		fmt.Fprintf(r.W, "Accepted connection from %s, port %d\n", s.AcceptedConnection.Host, s.AcceptedConnection.Port)
+		r.sender = s.TestStart.Reverse != 0
+	}
+	for _, c := range s.Connected {
+		fmt.Fprintf(r.W, "[%3d] local %s port %d connected to %s port %d\n", c.Socket, c.LocalHost, c.LocalPort, c.RemoteHost, c.RemotePort)
+	}
+	r.header(r.sender)
+}
+
+func (r *TextReporter) header(sender bool) {
+	h := "[ ID] Interval           Transfer     Bitrate"
+	switch {
+	case !r.udp && sender:
+		h += "         Retr"
+	case r.udp && sender:
+		h += "         Total Datagrams"
+	case r.udp:
+		h += "         Jitter    Lost/Total Datagrams"
+	}
+	fmt.Fprintln(r.W, h)
+}
+
+// Interval implements Reporter.
+func (r *TextReporter) Interval(iv *Interval) {
+	for _, s := range iv.Streams {
+		r.line(fmt.Sprintf("[%3d]", s.Socket), &s, "")
+	}
+	if len(iv.Streams) > 1 {
+		r.line("[SUM]", &iv.Sum, "")
+		fmt.Fprintln(r.W, separator)
+	}
+}
+
+func (r *TextReporter) line(id string, s *Stats, role string) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %6.2f-%-6.2f sec  %s  %s", id, s.Start, s.End, FormatBytes(float64(s.Bytes)), FormatBitrate(s.BitsPerSecond))
+	switch {
+	case !r.udp && s.Sender:
+		fmt.Fprintf(&b, "  %4d", s.Retransmits)
+	case !r.udp && role != "":
+		b.WriteString("      ")
+	case r.udp && s.Sender && role == "":
+		fmt.Fprintf(&b, "  %d", s.Packets)
+	case r.udp:
+		fmt.Fprintf(&b, "  %.3f ms  %d/%d (%.2g%%)", s.JitterMS, s.LostPackets, s.Packets, s.LostPercent)
+	}
+	if role != "" {
+		b.WriteString("  " + role)
+	}
+	fmt.Fprintln(r.W, b.String())
+}
+
+// End implements Reporter.
+func (r *TextReporter) End(res *Result) {
+	fmt.Fprintln(r.W, separator)
+	// UDP summaries have the loss of both sides.
+	r.header(!r.udp)
+	for _, s := range res.End.Streams {
+		r.line(fmt.Sprintf("[%3d]", s.Sender.Socket), &s.Sender, "sender")
+		r.line(fmt.Sprintf("[%3d]", s.Receiver.Socket), &s.Receiver, "receiver")
+	}
+	if len(res.End.Streams) > 1 {
+		r.line("[SUM]", &res.End.SumSent, "sender")
+		r.line("[SUM]", &res.End.SumReceived, "receiver")
+	}
+	fmt.Fprintln(r.W)
+}
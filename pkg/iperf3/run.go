@@ -0,0 +1,546 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package iperf3
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/netip"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// stream is a data stream of a test.
+type stream struct {
+	id     int
+	udp    bool
+	sender bool
+
+	// conn is the connection of TCP streams, and of UDP streams on the
+	// client. write sends on it, or on the shared socket of UDP streams
+	// on the server.
+	conn          net.Conn
+	write         func([]byte) (int, error)
+	local, remote netip.AddrPort
+
+	mu          sync.Mutex
+	cur, last   counters
+	prevTransit float64
+	transits    bool
+}
+
+// counters are the counters of a stream. Packets are the packets sent by
+// senders, and the highest sequence number received by receivers.
+type counters struct {
+	bytes       int64
+	packets     int64
+	lost        int64
+	outOfOrder  int64
+	retransmits int64
+	jitter      float64
+}
+
+func (st *stream) snapshot() counters {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	c := st.cur
+	if st.sender && !st.udp {
+		if r, ok := tcpRetransmits(st.conn); ok {
+			c.retransmits = r
+		}
+	}
+	return c
+}
+
+// stats returns the stats of c, over the seconds from start to end.
+func (st *stream) stats(c counters, start, end float64, sender bool) Stats {
+	s := Stats{
+		Socket:      st.id,
+		Start:       start,
+		End:         end,
+		Seconds:     end - start,
+		Bytes:       c.bytes,
+		Retransmits: c.retransmits,
+		JitterMS:    c.jitter * 1000,
+		LostPackets: c.lost,
+		Packets:     c.packets,
+		OutOfOrder:  c.outOfOrder,
+		Sender:      sender,
+	}
+	s.finish()
+	return s
+}
+
+// finish fills in the rates of s.
+func (s *Stats) finish() {
+	if s.Seconds > 0 {
+		s.BitsPerSecond = float64(s.Bytes) * 8 / s.Seconds
+	}
+	if s.Packets > 0 {
+		s.LostPercent = 100 * float64(s.LostPackets) / float64(s.Packets)
+	}
+}
+
+// add adds s to the sum of all streams.
+func (s *Stats) add(o *Stats) {
+	s.Start = o.Start
+	s.End = max(s.End, o.End)
+	s.Seconds = max(s.Seconds, o.Seconds)
+	s.Bytes += o.Bytes
+	s.Retransmits += o.Retransmits
+	s.LostPackets += o.LostPackets
+	s.Packets += o.Packets
+	s.OutOfOrder += o.OutOfOrder
+	s.Sender = o.Sender
+}
+
+// udpHeaderLen returns the length of the header of UDP datagrams.
+func udpHeaderLen(counters64 bool) int {
+	if counters64 {
+		return 16
+	}
+	return 12
+}
+
+// test is a running test, on either side.
+type test struct {
+	udp        bool
+	sender     bool
+	counters64 bool
+	blksize    int
+	rate       uint64
+	limit      int64
+	interval   time.Duration
+	reporter   Reporter
+	streams    []*stream
+	result     Result
+
+	// closers are closed with the streams.
+	closers []io.Closer
+
+	start     time.Time
+	end       time.Time
+	cpuStart  [2]time.Duration
+	cpuUtil   [3]float64
+	total     atomic.Int64
+	stopped   atomic.Bool
+	stop      chan struct{}
+	full      chan struct{}
+	fullOnce  sync.Once
+	wg        sync.WaitGroup
+	reporting sync.WaitGroup
+	lastEnd   float64
+}
+
+func newTest(p *params, sender bool, interval time.Duration, r Reporter) *test {
+	t := &test{
+		udp:        p.UDP,
+		sender:     sender,
+		counters64: p.UDPCounters64Bit != 0,
+		blksize:    p.Len,
+		rate:       uint64(p.Bandwidth),
+		limit:      int64(p.Num),
+		interval:   interval,
+		reporter:   r,
+		stop:       make(chan struct{}),
+		full:       make(chan struct{}),
+	}
+	if t.limit == 0 && p.BlockCount > 0 {
+		t.limit = int64(p.BlockCount) * int64(p.Len)
+	}
+	protocol := "TCP"
+	if t.udp {
+		protocol = "UDP"
+	}
+	t.result.Start.Version = "iperf " + clientVersion
+	t.result.Start.TestStart = TestStart{
+		Protocol:   protocol,
+		NumStreams: p.Parallel,
+		BlkSize:    p.Len,
+		Duration:   p.Time,
+		Bytes:      int64(p.Num),
+		Blocks:     int64(p.BlockCount),
+	}
+	if p.Reverse {
+		t.result.Start.TestStart.Reverse = 1
+	}
+	return t
+}
+
+// addStream adds a stream on conn, which send writes to.
+func (t *test) addStream(conn net.Conn, local, remote net.Addr, send func([]byte) (int, error)) *stream {
+	st := &stream{
+		id:     streamID(len(t.streams)),
+		udp:    t.udp,
+		sender: t.sender,
+		conn:   conn,
+		write:  send,
+		local:  addrPort(local),
+		remote: addrPort(remote),
+	}
+	t.streams = append(t.streams, st)
+	t.result.Start.Connected = append(t.result.Start.Connected, Connection{
+		Socket:     st.id,
+		LocalHost:  st.local.Addr().String(),
+		LocalPort:  int(st.local.Port()),
+		RemoteHost: st.remote.Addr().String(),
+		RemotePort: int(st.remote.Port()),
+	})
+	return st
+}
+
+func addrPort(a net.Addr) netip.AddrPort {
+	switch a := a.(type) {
+	case *net.TCPAddr:
+		ap := a.AddrPort()
+		return netip.AddrPortFrom(ap.Addr().Unmap(), ap.Port())
+	case *net.UDPAddr:
+		ap := a.AddrPort()
+		return netip.AddrPortFrom(ap.Addr().Unmap(), ap.Port())
+	}
+	return netip.AddrPort{}
+}
+
+// run starts the streams. Receivers of UDP on the server get their
+// datagrams from the server.
+func (t *test) run() {
+	t.start = time.Now()
+	t.cpuStart[0], t.cpuStart[1] = cpuTimes()
+	now := t.start.UTC()
+	t.result.Start.Timestamp = Timestamp{Time: now.Format(time.RFC1123), TimeSecs: now.Unix()}
+	if t.reporter != nil {
+		t.reporter.Start(&t.result)
+	}
+	for _, st := range t.streams {
+		switch {
+		case st.sender:
+			t.wg.Go(func() { t.send(st) })
+		case st.conn != nil:
+			t.wg.Go(func() { t.receive(st) })
+		}
+	}
+	t.reporting.Go(t.report)
+}
+
+// count counts n bytes towards the limit of the test.
+func (t *test) count(n int) {
+	if t.limit > 0 && t.total.Add(int64(n)) >= t.limit {
+		t.fullOnce.Do(func() { close(t.full) })
+	}
+}
+
+// send sends on st until the test stops or its limit is reached, at the
+// bitrate of the test.
+func (t *test) send(st *stream) {
+	b := make([]byte, t.blksize)
+	rand.Read(b)
+	pace := time.NewTimer(0)
+	defer pace.Stop()
+	var sent int64
+	for !t.stopped.Load() {
+		if t.limit > 0 && t.total.Load() >= t.limit {
+			return
+		}
+		if t.rate > 0 {
+			due := t.start.Add(time.Duration(float64(sent) * 8 / float64(t.rate) * float64(time.Second)))
+			if d := time.Until(due); d > 0 {
+				pace.Reset(d)
+				select {
+				case <-t.stop:
+					return
+				case <-pace.C:
+				}
+			}
+		}
+		if t.udp {
+			st.mu.Lock()
+			seq := st.cur.packets + 1
+			st.mu.Unlock()
+			now := time.Now()
+			binary.BigEndian.PutUint32(b, uint32(now.Unix()))
+			binary.BigEndian.PutUint32(b[4:], uint32(now.Nanosecond()/1000))
+			if t.counters64 {
+				binary.BigEndian.PutUint64(b[8:], uint64(seq))
+			} else {
+				binary.BigEndian.PutUint32(b[8:], uint32(seq))
+			}
+		}
+		n, err := st.write(b)
+		if err != nil {
+			// UDP senders may outrun the interface, which iperf3
+			// does not count as a failure either.
+			if t.udp && !t.stopped.Load() {
+				continue
+			}
+			return
+		}
+		sent += int64(n)
+		st.mu.Lock()
+		st.cur.bytes += int64(n)
+		st.cur.packets++
+		st.mu.Unlock()
+		t.count(n)
+	}
+}
+
+// receive receives on the connection of st until it is closed.
+func (t *test) receive(st *stream) {
+	b := make([]byte, max(t.blksize, MaxUDPBlockSize))
+	for {
+		n, err := st.conn.Read(b)
+		if err != nil {
+			return
+		}
+		if t.udp {
+			t.receiveUDP(st, b[:n], time.Now())
+			continue
+		}
+		if t.stopped.Load() {
+			continue
+		}
+		st.mu.Lock()
+		st.cur.bytes += int64(n)
+		st.mu.Unlock()
+		t.count(n)
+	}
+}
+
+// receiveUDP counts a UDP datagram of st that arrived at arrival, as iperf3
+// does: gaps in the sequence numbers are lost datagrams, until the missing
+// ones arrive out of order. The jitter is that of RFC 1889.
+func (t *test) receiveUDP(st *stream, b []byte, arrival time.Time) {
+	if len(b) < udpHeaderLen(t.counters64) || t.stopped.Load() {
+		return
+	}
+	sent := time.Unix(int64(binary.BigEndian.Uint32(b)), int64(binary.BigEndian.Uint32(b[4:]))*1000)
+	var seq int64
+	if t.counters64 {
+		seq = int64(binary.BigEndian.Uint64(b[8:]))
+	} else {
+		seq = int64(binary.BigEndian.Uint32(b[8:]))
+	}
+
+	st.mu.Lock()
+	c := &st.cur
+	c.bytes += int64(len(b))
+	if seq > c.packets {
+		c.lost += seq - 1 - c.packets
+		c.packets = seq
+	} else {
+		c.outOfOrder++
+		if c.lost > 0 {
+			c.lost--
+		}
+	}
+	transit := arrival.Sub(sent).Seconds()
+	if st.transits {
+		c.jitter += (math.Abs(transit-st.prevTransit) - c.jitter) / 16
+	}
+	st.prevTransit, st.transits = transit, true
+	st.mu.Unlock()
+	t.count(len(b))
+}
+
+// report reports an interval on every tick until the test stops.
+func (t *test) report() {
+	if t.interval <= 0 {
+		return
+	}
+	tick := time.NewTicker(t.interval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-t.stop:
+			return
+		case now := <-tick.C:
+			t.addInterval(now)
+		}
+	}
+}
+
+// addInterval adds the interval that ends at end.
+func (t *test) addInterval(end time.Time) {
+	start, secs := t.lastEnd, end.Sub(t.start).Seconds()
+	if secs <= start {
+		return
+	}
+	t.lastEnd = secs
+	iv := Interval{Sum: Stats{Start: start, End: secs, Seconds: secs - start}}
+	for _, st := range t.streams {
+		c := st.snapshot()
+		st.mu.Lock()
+		d := counters{
+			bytes:       c.bytes - st.last.bytes,
+			packets:     c.packets - st.last.packets,
+			lost:        c.lost - st.last.lost,
+			outOfOrder:  c.outOfOrder - st.last.outOfOrder,
+			retransmits: c.retransmits - st.last.retransmits,
+			jitter:      c.jitter,
+		}
+		st.last = c
+		st.mu.Unlock()
+		s := st.stats(d, start, secs, t.sender)
+		iv.Streams = append(iv.Streams, s)
+		iv.Sum.add(&s)
+		iv.Sum.JitterMS += s.JitterMS / float64(len(t.streams))
+	}
+	iv.Sum.Sender = t.sender
+	iv.Sum.finish()
+	t.result.Intervals = append(t.result.Intervals, iv)
+	if t.reporter != nil {
+		t.reporter.Interval(&iv)
+	}
+}
+
+// finish stops the test, and reports its last, partial interval.
+func (t *test) finish() {
+	if t.stopped.Swap(true) {
+		return
+	}
+	close(t.stop)
+	if t.start.IsZero() {
+		return
+	}
+	t.end = time.Now()
+	t.reporting.Wait()
+	// Short leftovers, as of a test that stops on time, are not worth
+	// a line.
+	if t.end.Sub(t.start).Seconds()-t.lastEnd > t.interval.Seconds()/10 {
+		t.addInterval(t.end)
+	}
+	user, system := cpuTimes()
+	if wall := t.end.Sub(t.start); wall > 0 {
+		t.cpuUtil[1] = 100 * float64(user-t.cpuStart[0]) / float64(wall)
+		t.cpuUtil[2] = 100 * float64(system-t.cpuStart[1]) / float64(wall)
+		t.cpuUtil[0] = t.cpuUtil[1] + t.cpuUtil[2]
+	}
+}
+
+// close closes the streams and waits for them.
+func (t *test) close() {
+	t.finish()
+	for _, st := range t.streams {
+		if st.conn != nil {
+			st.conn.Close()
+		}
+	}
+	for _, c := range t.closers {
+		c.Close()
+	}
+	t.wg.Wait()
+}
+
+// results returns the counters to send to the other side.
+func (t *test) results() *results {
+	r := &results{
+		CPUUtilTotal:         t.cpuUtil[0],
+		CPUUtilUser:          t.cpuUtil[1],
+		CPUUtilSystem:        t.cpuUtil[2],
+		SenderHasRetransmits: -1,
+		Streams:              []streamResults{},
+	}
+	if t.sender && !t.udp {
+		r.SenderHasRetransmits = 0
+	}
+	secs := t.end.Sub(t.start).Seconds()
+	for _, st := range t.streams {
+		c := st.snapshot()
+		if st.sender && !st.udp {
+			if _, ok := tcpRetransmits(st.conn); ok {
+				r.SenderHasRetransmits = 1
+			}
+		}
+		r.Streams = append(r.Streams, streamResults{
+			ID:          st.id,
+			Bytes:       float64(c.bytes),
+			Retransmits: float64(c.retransmits),
+			Jitter:      c.jitter,
+			Errors:      float64(c.lost),
+			Packets:     float64(c.packets),
+			EndTime:     secs,
+		})
+	}
+	if r.SenderHasRetransmits != 1 {
+		for i := range r.Streams {
+			r.Streams[i].Retransmits = -1
+		}
+	}
+	return r
+}
+
+// summarize completes the result with the counters of the other side.
+func (t *test) summarize(peer *results) (*Result, error) {
+	res := &t.result
+	res.End = End{
+		SumSent:     Stats{Sender: true},
+		SumReceived: Stats{},
+		CPUUtilizationPercent: CPU{
+			HostTotal:    t.cpuUtil[0],
+			HostUser:     t.cpuUtil[1],
+			HostSystem:   t.cpuUtil[2],
+			RemoteTotal:  peer.CPUUtilTotal,
+			RemoteUser:   peer.CPUUtilUser,
+			RemoteSystem: peer.CPUUtilSystem,
+		},
+	}
+	secs := t.end.Sub(t.start).Seconds()
+	var jitter float64
+	for _, st := range t.streams {
+		i := -1
+		for j, ps := range peer.Streams {
+			if ps.ID == st.id {
+				i = j
+			}
+		}
+		if i < 0 {
+			return nil, fmt.Errorf("the results of the peer have no stream %d", st.id)
+		}
+		ps := &peer.Streams[i]
+		local := st.stats(st.snapshot(), 0, secs, t.sender)
+		remote := Stats{
+			Socket:      st.id,
+			Start:       0,
+			End:         ps.EndTime,
+			Seconds:     ps.EndTime - ps.StartTime,
+			Bytes:       int64(ps.Bytes),
+			Retransmits: max(int64(ps.Retransmits), 0),
+			JitterMS:    ps.Jitter * 1000,
+			LostPackets: int64(ps.Errors),
+			Packets:     int64(ps.Packets),
+			Sender:      !t.sender,
+		}
+		remote.finish()
+		es := EndStream{Sender: local, Receiver: remote}
+		if !t.sender {
+			es = EndStream{Sender: remote, Receiver: local}
+		}
+		if t.udp {
+			// The sender knows how many datagrams there were, and
+			// the receiver how many were lost.
+			es.Sender.JitterMS, es.Sender.LostPackets = es.Receiver.JitterMS, es.Receiver.LostPackets
+			es.Receiver.Packets = es.Sender.Packets
+			es.Sender.finish()
+			es.Receiver.finish()
+			jitter += es.Receiver.JitterMS / float64(len(t.streams))
+		}
+		res.End.Streams = append(res.End.Streams, es)
+		res.End.SumSent.add(&es.Sender)
+		res.End.SumReceived.add(&es.Receiver)
+	}
+	res.End.SumSent.Sender = true
+	res.End.SumReceived.Sender = false
+	res.End.SumSent.JitterMS, res.End.SumReceived.JitterMS = jitter, jitter
+	res.End.SumSent.finish()
+	res.End.SumReceived.finish()
+	if t.reporter != nil {
+		t.reporter.End(res)
+	}
+	return res, nil
+}
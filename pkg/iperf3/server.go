@@ -0,0 +1,363 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package iperf3
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+)
+
+// setupTimeout bounds how long a client may take to send the parameters
+// and connect the streams of a test.
+const setupTimeout = 30 * time.Second
+
+// Server serves tests to clients, one at a time as iperf3 does.
+type Server struct {
+	// Logf logs the errors of tests. It defaults to log.Printf.
+	Logf func(format string, v ...any)
+
+	// Interval is how often to report. It defaults to DefaultInterval.
+	Interval time.Duration
+
+	// Reporter, if not nil, is told about each test as it runs.
+	Reporter Reporter
+
+	ln      net.Listener
+	network string
+	conns   chan *pending
+	done    chan struct{}
+
+	mu        sync.Mutex
+	acceptErr error
+}
+
+// pending is a connection that has sent its cookie.
+type pending struct {
+	conn   net.Conn
+	cookie string
+}
+
+// Listen returns a server listening on the TCP network and address, as
+// in net.Listen. UDP tests use the same port.
+func Listen(network, addr string) (*Server, error) {
+	if network == "" {
+		network = "tcp"
+	}
+	if !strings.HasPrefix(network, "tcp") {
+		return nil, fmt.Errorf("unsupported network %q", network)
+	}
+	ln, err := net.Listen(network, addr)
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{
+		Logf:     log.Printf,
+		Interval: DefaultInterval,
+		ln:       ln,
+		network:  network,
+		conns:    make(chan *pending),
+		done:     make(chan struct{}),
+	}
+	go s.accept()
+	return s, nil
+}
+
+// Addr returns the address the server listens on.
+func (s *Server) Addr() net.Addr {
+	return s.ln.Addr()
+}
+
+// Close stops listening.
+func (s *Server) Close() error {
+	return s.ln.Close()
+}
+
+// accept accepts connections, and reads their cookies.
+func (s *Server) accept() {
+	defer close(s.done)
+	for {
+		c, err := s.ln.Accept()
+		if err != nil {
+			s.mu.Lock()
+			s.acceptErr = err
+			s.mu.Unlock()
+			return
+		}
+		go func() {
+			c.SetReadDeadline(time.Now().Add(setupTimeout))
+			cookie := make([]byte, cookieLen)
+			if _, err := io.ReadFull(c, cookie); err != nil {
+				c.Close()
+				return
+			}
+			c.SetReadDeadline(time.Time{})
+			select {
+			case s.conns <- &pending{conn: c, cookie: string(cookie)}:
+			case <-s.done:
+				c.Close()
+			}
+		}()
+	}
+}
+
+// Serve serves tests until ctx is done or the server is closed.
+func (s *Server) Serve(ctx context.Context) error {
+	for {
+		if _, err := s.ServeOne(ctx); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			select {
+			case <-s.done:
+				return err
+			default:
+			}
+			s.Logf("iperf3: %v", err)
+		}
+	}
+}
+
+// ServeOne serves one test, and returns its result.
+func (s *Server) ServeOne(ctx context.Context) (*Result, error) {
+	var p *pending
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-s.done:
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return nil, s.acceptErr
+	case p = <-s.conns:
+	}
+	defer p.conn.Close()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	defer context.AfterFunc(ctx, func() { p.conn.Close() })()
+
+	// Everyone else is turned away, except the streams of the test,
+	// until release is called. Clients that come later wait for the next
+	// test.
+	streams := make(chan net.Conn, maxStreams)
+	denyCtx, stopDeny := context.WithCancel(ctx)
+	var deny sync.WaitGroup
+	release := func() {
+		stopDeny()
+		deny.Wait()
+	}
+	defer func() {
+		release()
+		close(streams)
+		for c := range streams {
+			c.Close()
+		}
+	}()
+	deny.Go(func() {
+		for {
+			var o *pending
+			select {
+			case <-denyCtx.Done():
+				return
+			case o = <-s.conns:
+			}
+			if o.cookie == p.cookie {
+				select {
+				case streams <- o.conn:
+					continue
+				default:
+				}
+			}
+			writeState(o.conn, accessDenied)
+			o.conn.Close()
+		}
+	})
+	return s.serve(ctx, p.conn, p.cookie, streams, release)
+}
+
+// serveError tells the client of an error, and returns it.
+func serveError(ctrl net.Conn, code int32, err error) error {
+	writeState(ctrl, serverError)
+	binary.Write(ctrl, binary.BigEndian, &ServerError{Code: code})
+	return err
+}
+
+// serve runs the test of ctrl. It calls release once the client has the
+// results, and the next client may come.
+func (s *Server) serve(ctx context.Context, ctrl net.Conn, cookie string, streams <-chan net.Conn, release func()) (*Result, error) {
+	ctrl.SetReadDeadline(time.Now().Add(setupTimeout))
+	if err := writeState(ctrl, paramExchange); err != nil {
+		return nil, err
+	}
+	var p params
+	if err := readJSON(ctrl, &p); err != nil {
+		return nil, fmt.Errorf("reading parameters: %w", err)
+	}
+	switch {
+	case p.SCTP, p.Bidirectional:
+		return nil, serveError(ctrl, errUnimplemented, errors.New("SCTP and bidirectional tests are not supported"))
+	case p.Parallel > maxStreams:
+		return nil, serveError(ctrl, errUnimplemented, fmt.Errorf("%d streams are more than %d", p.Parallel, maxStreams))
+	case p.UDP && p.Len != 0 && (p.Len < udpHeaderLen(p.UDPCounters64Bit != 0) || p.Len > MaxUDPBlockSize):
+		return nil, serveError(ctrl, errUnimplemented, fmt.Errorf("UDP block size %d is out of range", p.Len))
+	case p.Len < 0 || p.Len > 1<<30:
+		return nil, serveError(ctrl, errUnimplemented, fmt.Errorf("block size %d is out of range", p.Len))
+	}
+	p.Parallel = max(p.Parallel, 1)
+	if p.Len == 0 {
+		p.Len = DefaultTCPBlockSize
+		if p.UDP {
+			p.Len = DefaultUDPBlockSize
+		}
+	}
+	if p.UDP && p.Bandwidth == 0 {
+		p.Bandwidth = DefaultUDPBitrate
+	}
+
+	t := newTest(&p, p.Reverse, s.Interval, s.Reporter)
+	t.result.Start.Cookie = strings.TrimRight(cookie, "\x00")
+	client := addrPort(ctrl.RemoteAddr())
+	t.result.Start.AcceptedConnection = &Host{Host: client.Addr().String(), Port: int(client.Port())}
+	defer t.close()
+
+	var udp *net.UDPConn
+	if p.UDP {
+		var err error
+		if udp, err = s.listenUDP(); err != nil {
+			return nil, serveError(ctrl, errUnimplemented, err)
+		}
+		t.closers = append(t.closers, udp)
+	}
+	if err := writeState(ctrl, createStreams); err != nil {
+		return nil, err
+	}
+	if err := s.acceptStreams(ctx, t, p.Parallel, streams, udp); err != nil {
+		return nil, err
+	}
+	if err := writeState(ctrl, testStart); err != nil {
+		return nil, err
+	}
+	t.run()
+	if err := writeState(ctrl, testRunning); err != nil {
+		return nil, err
+	}
+
+	// The client ends the test, in time if it has one.
+	ctrl.SetReadDeadline(time.Time{})
+	if p.Time > 0 {
+		ctrl.SetReadDeadline(time.Now().Add(time.Duration(p.Time+p.Omit)*time.Second + setupTimeout))
+	}
+	st, err := readState(ctrl)
+	if err != nil {
+		return nil, fmt.Errorf("client went away: %w", err)
+	}
+	switch st {
+	case testEnd:
+	case clientTerminate:
+		return nil, errors.New("the client terminated the test")
+	default:
+		return nil, fmt.Errorf("unexpected state %d from the client", st)
+	}
+	t.close()
+
+	if err := writeState(ctrl, exchangeResults); err != nil {
+		return nil, err
+	}
+	ctrl.SetReadDeadline(time.Now().Add(setupTimeout))
+	var peer results
+	if err := readJSON(ctrl, &peer); err != nil {
+		return nil, fmt.Errorf("reading results: %w", err)
+	}
+	if err := writeJSON(ctrl, t.results()); err != nil {
+		return nil, err
+	}
+	// The client is done once it has this state, so make way for the
+	// next before sending it.
+	release()
+	if err := writeState(ctrl, displayResults); err != nil {
+		return nil, err
+	}
+	// The client says it is done, or just goes away.
+	readState(ctrl)
+	return t.summarize(&peer)
+}
+
+// listenUDP listens for UDP streams on the port of the server.
+func (s *Server) listenUDP() (*net.UDPConn, error) {
+	network := "udp" + strings.TrimPrefix(s.network, "tcp")
+	udp, err := net.ListenUDP(network, net.UDPAddrFromAddrPort(addrPort(s.ln.Addr())))
+	if err != nil {
+		return nil, err
+	}
+	// Best effort, for high bitrates.
+	udp.SetReadBuffer(4 << 20)
+	udp.SetWriteBuffer(4 << 20)
+	return udp, nil
+}
+
+// acceptStreams accepts n streams, from streams for TCP, or as the first
+// datagrams from new addresses on udp.
+func (s *Server) acceptStreams(ctx context.Context, t *test, n int, streams <-chan net.Conn, udp *net.UDPConn) error {
+	timeout := time.NewTimer(setupTimeout)
+	defer timeout.Stop()
+	if udp == nil {
+		for range n {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-timeout.C:
+				return errors.New("timed out waiting for streams")
+			case c := <-streams:
+				t.addStream(c, c.LocalAddr(), c.RemoteAddr(), c.Write)
+			}
+		}
+		return nil
+	}
+
+	udp.SetReadDeadline(time.Now().Add(setupTimeout))
+	peers := map[netip.AddrPort]*stream{}
+	b := make([]byte, MaxUDPBlockSize)
+	for len(peers) < n {
+		_, from, err := udp.ReadFromUDPAddrPort(b)
+		if err != nil {
+			return fmt.Errorf("waiting for UDP streams: %w", err)
+		}
+		from = netip.AddrPortFrom(from.Addr().Unmap(), from.Port())
+		if _, ok := peers[from]; ok {
+			continue
+		}
+		st := t.addStream(nil, udp.LocalAddr(), net.UDPAddrFromAddrPort(from), func(b []byte) (int, error) {
+			return udp.WriteToUDPAddrPort(b, from)
+		})
+		peers[from] = st
+		if _, err := udp.WriteToUDPAddrPort(binary.NativeEndian.AppendUint32(nil, udpConnectReply), from); err != nil {
+			return err
+		}
+	}
+	udp.SetReadDeadline(time.Time{})
+
+	// Datagrams of all streams arrive on the one socket.
+	t.wg.Go(func() {
+		for {
+			n, from, err := udp.ReadFromUDPAddrPort(b)
+			if err != nil {
+				return
+			}
+			from = netip.AddrPortFrom(from.Addr().Unmap(), from.Port())
+			if st, ok := peers[from]; ok && !st.sender {
+				t.receiveUDP(st, b[:n], time.Now())
+			}
+		}
+	})
+	return nil
+}
@@ -0,0 +1,54 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package iperf3
+
+import (
+	"net"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// control runs fn on the socket of c, if it is a TCP connection.
+func control(c net.Conn, fn func(fd int)) bool {
+	sc, ok := c.(syscall.Conn)
+	if !ok {
+		return false
+	}
+	rc, err := sc.SyscallConn()
+	if err != nil {
+		return false
+	}
+	return rc.Control(func(fd uintptr) { fn(int(fd)) }) == nil
+}
+
+// tcpRetransmits returns the retransmitted segments of a TCP connection.
+func tcpRetransmits(c net.Conn) (int64, bool) {
+	var (
+		info *unix.TCPInfo
+		err  error
+	)
+	if !control(c, func(fd int) { info, err = unix.GetsockoptTCPInfo(fd, unix.IPPROTO_TCP, unix.TCP_INFO) }) || err != nil {
+		return 0, false
+	}
+	return int64(info.Total_retrans), true
+}
+
+// tcpMSS returns the maximum segment size of a TCP connection, or 0.
+func tcpMSS(c net.Conn) int {
+	var mss int
+	control(c, func(fd int) { mss, _ = unix.GetsockoptInt(fd, unix.IPPROTO_TCP, unix.TCP_MAXSEG) })
+	return mss
+}
+
+// cpuTimes returns the user and system CPU time of the process.
+func cpuTimes() (user, system time.Duration) {
+	var ru unix.Rusage
+	if err := unix.Getrusage(unix.RUSAGE_SELF, &ru); err != nil {
+		return 0, 0
+	}
+	return time.Duration(ru.Utime.Nano()), time.Duration(ru.Stime.Nano())
+}
@@ -0,0 +1,24 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !linux
+
+package iperf3
+
+import (
+	"net"
+	"time"
+)
+
+func tcpRetransmits(net.Conn) (int64, bool) {
+	return 0, false
+}
+
+func tcpMSS(net.Conn) int {
+	return 0
+}
+
+func cpuTimes() (user, system time.Duration) {
+	return 0, 0
+}
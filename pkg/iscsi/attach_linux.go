@@ -0,0 +1,220 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package iscsi
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/u-root/iscsinl"
+)
+
+var (
+	errNotTCP    = errors.New("the kernel only takes TCP connections")
+	errNoDevices = errors.New("no block devices appeared")
+)
+
+// Attach hands s to the kernel's initiator, which takes the connection over,
+// and returns the names of the block devices of the LUNs of the target,
+// e.g. sda. Their partition tables have been read.
+//
+// The kernel sessions of iscsinl start at CmdSN 0, which is the one Login
+// uses. If Attach fails, the kernel session is gone again, but s.Conn is
+// left to the caller to close.
+func (s *Session) Attach() (_ []string, err error) {
+	conn, ok := s.Conn.(interface{ File() (*os.File, error) })
+	if !ok {
+		return nil, fmt.Errorf("%T: %w", s.Conn, errNotTCP)
+	}
+	nl, err := iscsinl.ConnectNetlink()
+	if err != nil {
+		return nil, err
+	}
+	defer nl.Conn.Close()
+
+	o := s.Opts
+	if o.CmdsMax == 0 {
+		o.CmdsMax = 128
+	}
+	if o.QueueDepth == 0 {
+		o.QueueDepth = 16
+	}
+	sid, host, err := nl.CreateSession(o.CmdsMax, o.QueueDepth)
+	if err != nil {
+		return nil, fmt.Errorf("creating session: %w", err)
+	}
+	s.attached, s.sid = true, sid
+	defer func() {
+		if err != nil {
+			s.destroy(nl)
+		}
+	}()
+	cid, err := nl.CreateConnection(sid)
+	if err != nil {
+		return nil, fmt.Errorf("creating connection: %w", err)
+	}
+	s.cid = cid
+	f, err := conn.File()
+	if err != nil {
+		return nil, err
+	}
+	// The kernel holds its own reference to the socket.
+	defer f.Close()
+	if err := nl.BindConnection(sid, cid, int(f.Fd())); err != nil {
+		return nil, fmt.Errorf("binding connection: %w", err)
+	}
+	for _, p := range s.kernelParams() {
+		if err := nl.SetParam(sid, cid, p.param, p.value); err != nil {
+			return nil, fmt.Errorf("setting %v: %w", p.param, err)
+		}
+	}
+	if err := nl.StartConnection(sid, cid); err != nil {
+		return nil, fmt.Errorf("starting connection: %w", err)
+	}
+
+	devs, err := scan(sid, host, o.ScanTimeout)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range devs {
+		if err := iscsinl.ReReadPartitionTable("/dev/" + d); err != nil {
+			return nil, fmt.Errorf("reading partition table of %s: %w", d, err)
+		}
+	}
+	return devs, nil
+}
+
+// Detach ends the kernel session that Attach started, which drops the
+// connection to the target, and closes s.Conn.
+func (s *Session) Detach() error {
+	var err error
+	if s.attached {
+		var nl *iscsinl.IscsiIpcConn
+		if nl, err = iscsinl.ConnectNetlink(); err == nil {
+			err = s.destroy(nl)
+			nl.Conn.Close()
+		}
+	}
+	return errors.Join(err, s.Conn.Close())
+}
+
+// destroy stops and destroys the kernel session of s. Sessions that were
+// only partly set up make some of the steps fail, so all are tried.
+func (s *Session) destroy(nl *iscsinl.IscsiIpcConn) error {
+	stopErr := nl.StopConnection(s.sid, s.cid)
+	connErr := nl.DestroyConnection(s.sid, s.cid)
+	if err := nl.DestroySession(s.sid); err != nil {
+		return errors.Join(err, connErr, stopErr)
+	}
+	s.attached = false
+	return nil
+}
+
+type kernelParam struct {
+	param iscsinl.IscsiParam
+	value string
+}
+
+// kernelParams are the parameters of the kernel session and connection.
+func (s *Session) kernelParams() []kernelParam {
+	b := func(key string) string {
+		if s.Param(key) == "Yes" {
+			return "1"
+		}
+		return "0"
+	}
+	digest := func(key string) string {
+		if s.Param(key) == "CRC32C" {
+			return "1"
+		}
+		return "0"
+	}
+	seconds := func(d time.Duration) string {
+		if d == 0 {
+			d = time.Minute
+		}
+		return strconv.Itoa(int(d / time.Second))
+	}
+	xmit := s.TargetParams["MaxRecvDataSegmentLength"]
+	if xmit == "" {
+		xmit = defaults["MaxRecvDataSegmentLength"]
+	}
+	p := []kernelParam{
+		{iscsinl.ISCSI_PARAM_TARGET_NAME, s.Opts.TargetName},
+		{iscsinl.ISCSI_PARAM_INITIATOR_NAME, s.Opts.InitiatorName},
+		{iscsinl.ISCSI_PARAM_MAX_RECV_DLENGTH, s.Param("MaxRecvDataSegmentLength")},
+		{iscsinl.ISCSI_PARAM_MAX_XMIT_DLENGTH, xmit},
+		{iscsinl.ISCSI_PARAM_FIRST_BURST, s.Param("FirstBurstLength")},
+		{iscsinl.ISCSI_PARAM_MAX_BURST, s.Param("MaxBurstLength")},
+		{iscsinl.ISCSI_PARAM_MAX_R2T, s.Param("MaxOutstandingR2T")},
+		{iscsinl.ISCSI_PARAM_ERL, s.Param("ErrorRecoveryLevel")},
+		{iscsinl.ISCSI_PARAM_PDU_INORDER_EN, b("DataPDUInOrder")},
+		{iscsinl.ISCSI_PARAM_DATASEQ_INORDER_EN, b("DataSequenceInOrder")},
+		{iscsinl.ISCSI_PARAM_INITIAL_R2T_EN, b("InitialR2T")},
+		{iscsinl.ISCSI_PARAM_IMM_DATA_EN, b("ImmediateData")},
+		{iscsinl.ISCSI_PARAM_HDRDGST_EN, digest("HeaderDigest")},
+		{iscsinl.ISCSI_PARAM_DATADGST_EN, digest("DataDigest")},
+		{iscsinl.ISCSI_PARAM_EXP_STATSN, strconv.FormatUint(uint64(s.ExpStatSN), 10)},
+		{iscsinl.ISCSI_PARAM_PING_TMO, seconds(s.Opts.PingTimeout)},
+		{iscsinl.ISCSI_PARAM_RECV_TMO, seconds(s.Opts.RecvTimeout)},
+	}
+	if tpgt, ok := s.TargetParams["TargetPortalGroupTag"]; ok {
+		p = append(p, kernelParam{iscsinl.ISCSI_PARAM_TPGT, tpgt})
+	}
+	return p
+}
+
+// scan asks the kernel to scan the SCSI host of session sid, and waits for
+// its block devices.
+func scan(sid, host uint32, timeout time.Duration) ([]string, error) {
+	if timeout == 0 {
+		timeout = 3 * time.Second
+	}
+	// The three wildcards are the channel, SCSI target ID and LUN.
+	if err := os.WriteFile(fmt.Sprintf("/sys/class/scsi_host/host%d/scan", host), []byte("- - -"), 0); err != nil {
+		return nil, err
+	}
+
+	// The devices of a scan don't appear at once: wait until there is at
+	// least one, and no more appeared in the last 100ms.
+	pattern := fmt.Sprintf("/sys/class/iscsi_session/session%d/device/target*/*/block/*/uevent", sid)
+	var uevents []string
+	for deadline := time.Now().Add(timeout); time.Now().Before(deadline); {
+		time.Sleep(100 * time.Millisecond)
+		m, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		if len(m) > 0 && len(m) == len(uevents) {
+			break
+		}
+		uevents = m
+	}
+
+	var devs []string
+	for _, u := range uevents {
+		f, err := os.Open(u)
+		if err != nil {
+			continue
+		}
+		s := bufio.NewScanner(f)
+		for s.Scan() {
+			if name, ok := strings.CutPrefix(s.Text(), "DEVNAME="); ok {
+				devs = append(devs, name)
+			}
+		}
+		f.Close()
+	}
+	if len(devs) == 0 {
+		return nil, fmt.Errorf("session %d: %w", sid, errNoDevices)
+	}
+	return devs, nil
+}
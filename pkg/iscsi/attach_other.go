@@ -0,0 +1,19 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !linux
+
+package iscsi
+
+import "errors"
+
+// Attach is only supported on Linux.
+func (s *Session) Attach() ([]string, error) {
+	return nil, errors.ErrUnsupported
+}
+
+// Detach closes s.Conn, as there is no kernel session on other systems.
+func (s *Session) Detach() error {
+	return s.Conn.Close()
+}
@@ -0,0 +1,266 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package iscsitest is an iSCSI target for tests, which does just enough of
+// login for initiators to log into it, in place of a real target set up
+// with targetcli.
+package iscsitest
+
+import (
+	"errors"
+	"maps"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/u-root/u-root/pkg/iscsi"
+)
+
+// Status classes and details of login responses, RFC 7143 Section 11.13.5.
+const (
+	classSuccess        = 0x00
+	classInitiatorError = 0x02
+
+	detailAuthFailure  = 0x01
+	detailNotFound     = 0x03
+	detailMissingParam = 0x07
+)
+
+// Target is a target. After login, it reads the PDUs of the initiator, and
+// ignores them.
+type Target struct {
+	// Name is the name initiators log into.
+	Name string
+	// Params are the values of the keys the target wants, which are
+	// negotiated with the offers of the initiator. Keys missing are
+	// accepted as offered.
+	Params map[string]string
+	// Offers are keys the target offers itself, after the offers of the
+	// initiator, which makes login take another round trip.
+	Offers map[string]string
+	// Declare are the keys the target declares, such as
+	// MaxRecvDataSegmentLength and TargetAlias.
+	Declare map[string]string
+
+	mu     sync.Mutex
+	logins []Login
+	tsih   uint16
+}
+
+// Login is a login to a target.
+type Login struct {
+	ISID [6]byte
+	TSIH uint16
+	// Keys are the keys the initiator sent, with the answers of the
+	// initiator to Offers.
+	Keys map[string]string
+	// Results are the results of the negotiation of the keys.
+	Results map[string]string
+	// Err is why the login failed.
+	Err error
+}
+
+// Logins returns the logins to t.
+func (t *Target) Logins() []Login {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return slices.Clone(t.logins)
+}
+
+// Serve serves the connections of ln until it is closed.
+func (t *Target) Serve(ln net.Listener) error {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return err
+		}
+		go t.serve(conn)
+	}
+}
+
+var (
+	errStage  = errors.New("bad login stage")
+	errRefuse = errors.New("login refused")
+)
+
+// session is the state of a login.
+type session struct {
+	t      *Target
+	conn   net.Conn
+	req    iscsi.LoginRequest
+	statSN uint32
+	login  Login
+	// tpgt is whether the TargetPortalGroupTag was declared, and
+	// offered whether the Offers were.
+	tpgt, offered bool
+}
+
+func (t *Target) serve(conn net.Conn) {
+	defer conn.Close()
+	s := &session{t: t, conn: conn, statSN: 0x1000}
+	s.login.Keys = map[string]string{}
+	s.login.Results = map[string]string{}
+	if err := t.login(s); err != nil {
+		return
+	}
+	for {
+		if _, err := iscsi.ReadPDU(conn); err != nil {
+			return
+		}
+	}
+}
+
+// login runs the login of s until the full feature phase.
+func (t *Target) login(s *session) error {
+	for {
+		p, err := iscsi.ReadPDU(s.conn)
+		if err != nil {
+			return err
+		}
+		if p.Opcode() != iscsi.OpLogin {
+			return s.refuse(0, errStage)
+		}
+		if err := p.Decode(&s.req); err != nil {
+			return err
+		}
+		kv, err := iscsi.ParseText(p.Data)
+		if err != nil {
+			return s.refuse(0, err)
+		}
+		maps.Copy(s.login.Keys, kv)
+		s.login.ISID = s.req.ISID
+
+		csg, nsg := iscsi.Stages(s.req.Flags)
+		transit := s.req.Flags&iscsi.LoginTransit != 0
+		var keys []string
+		switch csg {
+		case iscsi.SecurityNegotiation:
+			if s.login.Keys["InitiatorName"] == "" {
+				return s.refuse(detailMissingParam, errRefuse)
+			}
+			if s.login.Keys["TargetName"] != t.Name {
+				return s.refuse(detailNotFound, errRefuse)
+			}
+			if m, ok := kv["AuthMethod"]; ok {
+				if !slices.Contains(strings.Split(m, ","), "None") {
+					return s.refuse(detailAuthFailure, errRefuse)
+				}
+				keys = append(keys, "AuthMethod=None")
+			}
+			if !s.tpgt {
+				s.tpgt = true
+				keys = append(keys, "TargetPortalGroupTag=1")
+			}
+		case iscsi.OperationalNegotiation:
+			keys, err = t.negotiate(s, kv)
+			if err != nil {
+				return s.refuse(0, err)
+			}
+			if !s.offered && len(t.Offers) > 0 {
+				// Offer our keys, and stay in this stage to get
+				// the answers.
+				s.offered = true
+				for _, k := range slices.Sorted(maps.Keys(t.Offers)) {
+					keys = append(keys, k+"="+t.Offers[k])
+				}
+				transit = false
+			}
+			if transit && nsg == iscsi.FullFeaturePhase {
+				for _, k := range slices.Sorted(maps.Keys(t.Declare)) {
+					keys = append(keys, k+"="+t.Declare[k])
+				}
+				t.mu.Lock()
+				t.tsih++
+				s.login.TSIH = t.tsih
+				t.mu.Unlock()
+				s.record(nil)
+			}
+		default:
+			return s.refuse(0, errStage)
+		}
+		if err := s.respond(classSuccess, 0, transit, keys); err != nil {
+			return err
+		}
+		if transit && nsg == iscsi.FullFeaturePhase {
+			return nil
+		}
+	}
+}
+
+// negotiate answers the operational keys of kv, and records the results.
+func (t *Target) negotiate(s *session, kv map[string]string) ([]string, error) {
+	var keys []string
+	for _, k := range slices.Sorted(maps.Keys(kv)) {
+		v := kv[k]
+		if _, ok := t.Offers[k]; ok && s.offered {
+			// An answer to our offer.
+			s.login.Results[k] = v
+			continue
+		}
+		switch k {
+		case "InitiatorName", "TargetName", "SessionType", "InitiatorAlias":
+			continue
+		case "MaxRecvDataSegmentLength":
+			// Declarative.
+			s.login.Results[k] = v
+			continue
+		}
+		ours, ok := t.Params[k]
+		if !ok {
+			ours = v
+		}
+		result, err := iscsi.Negotiate(k, v, ours)
+		if err != nil {
+			return nil, err
+		}
+		s.login.Results[k] = result
+		keys = append(keys, k+"="+result)
+	}
+	return keys, nil
+}
+
+// record records the login, before the initiator gets the last response.
+func (s *session) record(err error) {
+	s.login.Err = err
+	s.t.mu.Lock()
+	defer s.t.mu.Unlock()
+	s.t.logins = append(s.t.logins, s.login)
+}
+
+// refuse ends the login with an initiator error.
+func (s *session) refuse(detail uint8, err error) error {
+	s.record(err)
+	s.respond(classInitiatorError, detail, false, nil)
+	return err
+}
+
+// respond sends a login response.
+func (s *session) respond(class, detail uint8, transit bool, keys []string) error {
+	csg, nsg := iscsi.Stages(s.req.Flags)
+	if !transit {
+		nsg = 0
+	}
+	rsp := &iscsi.LoginResponse{
+		Opcode:       iscsi.OpLoginRsp,
+		Flags:        iscsi.LoginFlags(transit, csg, nsg),
+		ISID:         s.req.ISID,
+		ITT:          s.req.ITT,
+		StatSN:       s.statSN,
+		ExpCmdSN:     s.req.CmdSN,
+		MaxCmdSN:     s.req.CmdSN + 31,
+		StatusClass:  class,
+		StatusDetail: detail,
+	}
+	if transit && nsg == iscsi.FullFeaturePhase {
+		rsp.TSIH = s.login.TSIH
+	}
+	s.statSN++
+	p, err := iscsi.NewPDU(rsp, iscsi.EncodeText(keys...))
+	if err != nil {
+		return err
+	}
+	_, err = p.WriteTo(s.conn)
+	return err
+}
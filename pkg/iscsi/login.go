@@ -0,0 +1,247 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package iscsi logs into iSCSI targets, RFC 7143, and hands the sessions to
+// the kernel's initiator, which makes block devices of their LUNs.
+//
+// Unlike github.com/u-root/iscsinl, which sends the login PDUs through the
+// kernel, Login negotiates on a plain connection in user space, so it works
+// with any net.Conn. Only the session's connection and parameters are given
+// to the kernel, by Attach.
+package iscsi
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"slices"
+	"time"
+)
+
+var (
+	errAuth     = errors.New("targets requiring authentication are not supported")
+	errTooLong  = errors.New("login took too many round trips")
+	errResponse = errors.New("bad login response")
+)
+
+// maxRounds bounds the login requests.
+const maxRounds = 16
+
+// Options are the options of a session.
+type Options struct {
+	// InitiatorName is our iSCSI name, usually an IQN.
+	InitiatorName string
+	// TargetName is the name of the target to log into.
+	TargetName string
+	// ISID is the initiator part of the session ID. The default is a
+	// random one.
+	ISID [6]byte
+	// Params change the operational keys offered during login, which are
+	// DefaultParams otherwise.
+	Params map[string]string
+
+	// CmdsMax and QueueDepth are the numbers of commands and I/Os the
+	// kernel may have outstanding, 128 and 16 by default.
+	CmdsMax, QueueDepth uint16
+	// PingTimeout is how long the kernel waits for a NOP-In, and
+	// RecvTimeout how long a connection may be idle before it sends a
+	// NOP-Out. Both are 1 minute by default.
+	PingTimeout, RecvTimeout time.Duration
+	// ScanTimeout is how long Attach waits for block devices, 3 seconds
+	// by default.
+	ScanTimeout time.Duration
+}
+
+// Session is a session in the full feature phase, after login.
+type Session struct {
+	Conn net.Conn
+	Opts Options
+
+	TSIH uint16
+	// CmdSN is the CmdSN of the first command. It is the one of the
+	// login, which, being immediate, did not advance it.
+	CmdSN uint32
+	// ExpStatSN is the StatSN expected from the target next.
+	ExpStatSN uint32
+	// Params are the results of the negotiation of the operational keys.
+	Params map[string]string
+	// TargetParams are the keys declared by the target, such as
+	// MaxRecvDataSegmentLength, TargetAlias and TargetPortalGroupTag.
+	TargetParams map[string]string
+
+	// attached is set once Attach created the kernel session sid, with
+	// connection cid.
+	attached bool
+	sid, cid uint32
+}
+
+// Param returns the value of a negotiated key, or its default if it was
+// not negotiated.
+func (s *Session) Param(key string) string {
+	if v, ok := s.Params[key]; ok && v != "Irrelevant" && v != "NotUnderstood" {
+		return v
+	}
+	return defaults[key]
+}
+
+// LoginError is a login refused by the target, RFC 7143 Section 11.13.5.
+type LoginError struct {
+	Class, Detail uint8
+	// TargetAddress is where the target moved, for redirections.
+	TargetAddress string
+}
+
+func (e *LoginError) Error() string {
+	var s string
+	switch {
+	case e.Class == 1:
+		s = "target moved to " + e.TargetAddress
+	case e.Class == 2 && e.Detail == 1:
+		s = "authentication failure"
+	case e.Class == 2 && e.Detail == 2:
+		s = "authorization failure"
+	case e.Class == 2 && e.Detail == 3:
+		s = "target not found"
+	case e.Class == 2 && e.Detail == 4:
+		s = "target removed"
+	case e.Class == 2:
+		s = "initiator error"
+	case e.Class == 3:
+		s = "target error"
+	default:
+		s = "unknown status"
+	}
+	return fmt.Sprintf("login failed: %s (status %02x%02x)", s, e.Class, e.Detail)
+}
+
+// Login logs into the target of o on conn, without authentication, and
+// returns the session in the full feature phase.
+func Login(conn net.Conn, o *Options) (*Session, error) {
+	s := &Session{
+		Conn:         conn,
+		Opts:         *o,
+		Params:       map[string]string{},
+		TargetParams: map[string]string{},
+	}
+	if s.Opts.ISID == [6]byte{} {
+		// The random format of ISIDs, RFC 7143 Section 10.12.5.
+		rand.Read(s.Opts.ISID[1:4])
+		s.Opts.ISID[0] = 0x80
+	}
+	offers := maps.Clone(DefaultParams)
+	maps.Copy(offers, o.Params)
+
+	keys := []string{
+		"InitiatorName=" + o.InitiatorName,
+		"TargetName=" + o.TargetName,
+		"SessionType=Normal",
+		"AuthMethod=None",
+	}
+	stage := SecurityNegotiation
+	for round := 0; ; round++ {
+		if round == maxRounds {
+			return nil, errTooLong
+		}
+		next := OperationalNegotiation
+		if stage == OperationalNegotiation {
+			next = FullFeaturePhase
+		}
+		rsp, kv, err := s.exchange(stage, next, keys)
+		if err != nil {
+			return nil, err
+		}
+		// Answers to our keys, and the keys the target offers in turn.
+		keys = nil
+		for _, k := range slices.Sorted(maps.Keys(kv)) {
+			v := kv[k]
+			r, known := rules[k]
+			switch {
+			case k == "AuthMethod":
+				if v != "None" {
+					return nil, fmt.Errorf("AuthMethod=%s: %w", v, errAuth)
+				}
+			case known && r == declarative:
+				s.TargetParams[k] = v
+			case offers[k] != "":
+				if err := checkResult(k, offers[k], v); err != nil {
+					return nil, err
+				}
+				s.Params[k] = v
+				delete(offers, k)
+			default:
+				ours, ok := defaults[k]
+				if !ok {
+					ours = v
+				}
+				result, err := Negotiate(k, v, ours)
+				if err != nil {
+					return nil, err
+				}
+				s.Params[k] = result
+				keys = append(keys, k+"="+result)
+			}
+		}
+
+		csg, nsg := Stages(rsp.Flags)
+		if csg != stage {
+			return nil, fmt.Errorf("target answered stage %d in stage %d: %w", csg, stage, errResponse)
+		}
+		if rsp.Flags&LoginTransit == 0 {
+			continue
+		}
+		if nsg == FullFeaturePhase {
+			s.TSIH = rsp.TSIH
+			return s, nil
+		}
+		if nsg != OperationalNegotiation || stage != SecurityNegotiation {
+			return nil, fmt.Errorf("target went from stage %d to %d: %w", stage, nsg, errResponse)
+		}
+		stage = nsg
+		for _, k := range slices.Sorted(maps.Keys(offers)) {
+			s.Params[k] = offers[k]
+			keys = append(keys, k+"="+offers[k])
+		}
+	}
+}
+
+// exchange sends a login request of keys in stage csg, asking for nsg, and
+// returns the response and its keys.
+func (s *Session) exchange(csg, nsg Stage, keys []string) (*LoginResponse, map[string]string, error) {
+	req := &LoginRequest{
+		Opcode:    OpLogin | OpImmediate,
+		Flags:     LoginFlags(true, csg, nsg),
+		ISID:      s.Opts.ISID,
+		CmdSN:     s.CmdSN,
+		ExpStatSN: s.ExpStatSN,
+	}
+	p, err := NewPDU(req, EncodeText(keys...))
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := p.WriteTo(s.Conn); err != nil {
+		return nil, nil, err
+	}
+	p, err = ReadPDU(s.Conn)
+	if err != nil {
+		return nil, nil, err
+	}
+	if p.Opcode() != OpLoginRsp {
+		return nil, nil, fmt.Errorf("opcode %#x: %w", p.Opcode(), errResponse)
+	}
+	rsp := &LoginResponse{}
+	if err := p.Decode(rsp); err != nil {
+		return nil, nil, err
+	}
+	kv, err := ParseText(p.Data)
+	if err != nil {
+		return nil, nil, err
+	}
+	if rsp.StatusClass != 0 {
+		return nil, nil, &LoginError{Class: rsp.StatusClass, Detail: rsp.StatusDetail, TargetAddress: kv["TargetAddress"]}
+	}
+	s.ExpStatSN = rsp.StatSN + 1
+	return rsp, kv, nil
+}
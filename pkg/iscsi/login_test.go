@@ -0,0 +1,178 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package iscsi_test
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/u-root/u-root/pkg/iscsi"
+	"github.com/u-root/u-root/pkg/iscsi/iscsitest"
+)
+
+const (
+	initiatorName = "iqn.2026-10.org.u-root:test"
+	targetName    = "iqn.2026-10.org.u-root:disk"
+)
+
+// serve serves tgt on a loopback port, and returns a connection to it.
+func serve(t *testing.T, tgt *iscsitest.Target) net.Conn {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go tgt.Serve(ln)
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestPDU(t *testing.T) {
+	req := &iscsi.LoginRequest{
+		Opcode:    iscsi.OpLogin | iscsi.OpImmediate,
+		Flags:     iscsi.LoginFlags(true, iscsi.OperationalNegotiation, iscsi.FullFeaturePhase),
+		ISID:      [6]byte{0x80, 1, 2, 3, 0, 0},
+		CmdSN:     7,
+		ExpStatSN: 0x1001,
+	}
+	p, err := iscsi.NewPDU(req, iscsi.EncodeText("a=b", "HeaderDigest=None"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	if _, err := p.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	// "a=b\0HeaderDigest=None\0" is 22 bytes, padded to 24.
+	if b.Len() != 48+24 {
+		t.Errorf("PDU is %d bytes, want %d", b.Len(), 48+24)
+	}
+	got, err := iscsi.ReadPDU(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var h iscsi.LoginRequest
+	if err := got.Decode(&h); err != nil {
+		t.Fatal(err)
+	}
+	h.DataSegmentLength = [3]byte{}
+	if h != *req || got.Opcode() != iscsi.OpLogin {
+		t.Errorf("read %+v, opcode %#x, want %+v", h, got.Opcode(), req)
+	}
+	if csg, nsg := iscsi.Stages(h.Flags); csg != iscsi.OperationalNegotiation || nsg != iscsi.FullFeaturePhase {
+		t.Errorf("stages %d, %d, want 1, 3", csg, nsg)
+	}
+	kv, err := iscsi.ParseText(got.Data)
+	if err != nil || !reflect.DeepEqual(kv, map[string]string{"a": "b", "HeaderDigest": "None"}) {
+		t.Errorf("ParseText = %v, %v", kv, err)
+	}
+
+	for _, bad := range []string{"a\x00", "a=b\x00a=c\x00", "=b\x00"} {
+		if _, err := iscsi.ParseText([]byte(bad)); err == nil {
+			t.Errorf("ParseText(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestLogin(t *testing.T) {
+	tgt := &iscsitest.Target{
+		Name: targetName,
+		Params: map[string]string{
+			"MaxBurstLength": "65536",
+			"ImmediateData":  "No",
+		},
+		Offers: map[string]string{
+			"DefaultTime2Wait":  "5",
+			"X-org.example.Key": "1",
+		},
+		Declare: map[string]string{
+			"MaxRecvDataSegmentLength": "65536",
+			"TargetAlias":              "disk",
+		},
+	}
+	conn := serve(t, tgt)
+	s, err := iscsi.Login(conn, &iscsi.Options{
+		InitiatorName: initiatorName,
+		TargetName:    targetName,
+		Params:        map[string]string{"HeaderDigest": "CRC32C,None"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for key, want := range map[string]string{
+		"HeaderDigest":             "CRC32C",
+		"DataDigest":               "None",
+		"MaxBurstLength":           "65536",
+		"ImmediateData":            "No",
+		"InitialR2T":               "No",
+		"MaxRecvDataSegmentLength": "262144",
+		"DefaultTime2Wait":         "5",
+		"DefaultTime2Retain":       "20",
+	} {
+		if got := s.Param(key); got != want {
+			t.Errorf("Param(%s) = %q, want %q", key, got, want)
+		}
+	}
+	if got := s.Params["X-org.example.Key"]; got != "NotUnderstood" {
+		t.Errorf("X-org.example.Key = %q, want NotUnderstood", got)
+	}
+	want := map[string]string{
+		"MaxRecvDataSegmentLength": "65536",
+		"TargetAlias":              "disk",
+		"TargetPortalGroupTag":     "1",
+	}
+	if !reflect.DeepEqual(s.TargetParams, want) {
+		t.Errorf("TargetParams = %v, want %v", s.TargetParams, want)
+	}
+	// The target answered the security stage, its offers and the end of
+	// the operational stage.
+	if s.ExpStatSN != 0x1003 || s.CmdSN != 0 || s.TSIH == 0 {
+		t.Errorf("ExpStatSN %#x, CmdSN %d, TSIH %d, want 0x1003, 0 and a TSIH", s.ExpStatSN, s.CmdSN, s.TSIH)
+	}
+
+	logins := tgt.Logins()
+	if len(logins) != 1 {
+		t.Fatalf("target got %d logins, want 1", len(logins))
+	}
+	l := logins[0]
+	if l.Err != nil || l.ISID != s.Opts.ISID || l.ISID[0] != 0x80 || l.TSIH != s.TSIH {
+		t.Errorf("target got ISID %x, TSIH %d, %v, want ISID %x and TSIH %d", l.ISID, l.TSIH, l.Err, s.Opts.ISID, s.TSIH)
+	}
+	if l.Keys["InitiatorName"] != initiatorName || l.Keys["SessionType"] != "Normal" || l.Keys["AuthMethod"] != "None" {
+		t.Errorf("target got keys %v", l.Keys)
+	}
+	if l.Results["DefaultTime2Wait"] != "5" || l.Results["X-org.example.Key"] != "NotUnderstood" || l.Results["HeaderDigest"] != "CRC32C" {
+		t.Errorf("target got results %v", l.Results)
+	}
+}
+
+func TestLoginFailure(t *testing.T) {
+	t.Run("not found", func(t *testing.T) {
+		conn := serve(t, &iscsitest.Target{Name: targetName})
+		_, err := iscsi.Login(conn, &iscsi.Options{InitiatorName: initiatorName, TargetName: "iqn.2026-10.org.u-root:nope"})
+		var le *iscsi.LoginError
+		if !errors.As(err, &le) || le.Class != 2 || le.Detail != 3 {
+			t.Errorf("Login = %v, want target not found", err)
+		}
+	})
+
+	t.Run("rejected", func(t *testing.T) {
+		tgt := &iscsitest.Target{Name: targetName, Params: map[string]string{"DataDigest": "CRC32C"}}
+		conn := serve(t, tgt)
+		_, err := iscsi.Login(conn, &iscsi.Options{InitiatorName: initiatorName, TargetName: targetName})
+		if err == nil {
+			t.Errorf("Login with digests the target rejects succeeded")
+		}
+	})
+}
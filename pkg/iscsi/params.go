@@ -0,0 +1,182 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package iscsi
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// rule is how the result of the negotiation of a key is picked from the
+// values of both sides, RFC 7143 Section 6.2.
+type rule int
+
+const (
+	// declarative keys are not negotiated: each side says its value.
+	declarative rule = iota
+	// choice picks the first value of the offered list the responder
+	// supports.
+	choice
+	minimum
+	maximum
+	// and and or are the boolean functions of Yes and No.
+	and
+	or
+)
+
+// defaults are the default values of the keys of login, RFC 7143
+// Section 13, and rules are how they are negotiated.
+var (
+	defaults = map[string]string{
+		"HeaderDigest":             "None",
+		"DataDigest":               "None",
+		"MaxConnections":           "1",
+		"InitialR2T":               "Yes",
+		"ImmediateData":            "Yes",
+		"MaxRecvDataSegmentLength": "8192",
+		"MaxBurstLength":           "262144",
+		"FirstBurstLength":         "65536",
+		"DefaultTime2Wait":         "2",
+		"DefaultTime2Retain":       "20",
+		"MaxOutstandingR2T":        "1",
+		"DataPDUInOrder":           "Yes",
+		"DataSequenceInOrder":      "Yes",
+		"ErrorRecoveryLevel":       "0",
+		"IFMarker":                 "No",
+		"OFMarker":                 "No",
+	}
+
+	rules = map[string]rule{
+		"HeaderDigest":             choice,
+		"DataDigest":               choice,
+		"MaxConnections":           minimum,
+		"InitialR2T":               or,
+		"ImmediateData":            and,
+		"MaxRecvDataSegmentLength": declarative,
+		"MaxBurstLength":           minimum,
+		"FirstBurstLength":         minimum,
+		"DefaultTime2Wait":         maximum,
+		"DefaultTime2Retain":       minimum,
+		"MaxOutstandingR2T":        minimum,
+		"DataPDUInOrder":           or,
+		"DataSequenceInOrder":      or,
+		"ErrorRecoveryLevel":       minimum,
+		"IFMarker":                 and,
+		"OFMarker":                 and,
+		"TargetAlias":              declarative,
+		"TargetAddress":            declarative,
+		"TargetPortalGroupTag":     declarative,
+	}
+)
+
+// DefaultParams are the operational keys offered by Login, unless
+// Options.Params changes them. The values are those of open-iscsi.
+var DefaultParams = map[string]string{
+	"HeaderDigest":             "None",
+	"DataDigest":               "None",
+	"MaxConnections":           "1",
+	"InitialR2T":               "No",
+	"ImmediateData":            "Yes",
+	"MaxRecvDataSegmentLength": "262144",
+	"MaxBurstLength":           "16776192",
+	"FirstBurstLength":         "262144",
+	"MaxOutstandingR2T":        "1",
+	"DataPDUInOrder":           "Yes",
+	"DataSequenceInOrder":      "Yes",
+	"ErrorRecoveryLevel":       "0",
+}
+
+var errNegotiation = errors.New("bad negotiation")
+
+// Negotiate returns the result of the negotiation of key between the value
+// offered by one side and the one the other side wants, as the responder
+// picks it. For lists, ours is the list of supported values.
+func Negotiate(key, offered, ours string) (string, error) {
+	r, ok := rules[key]
+	if !ok {
+		return "NotUnderstood", nil
+	}
+	switch r {
+	case choice:
+		for _, v := range strings.Split(offered, ",") {
+			if slices.Contains(strings.Split(ours, ","), v) {
+				return v, nil
+			}
+		}
+		return "Reject", nil
+	case minimum, maximum:
+		a, err := strconv.ParseUint(offered, 10, 32)
+		if err != nil {
+			return "", fmt.Errorf("%s=%s: %w", key, offered, errNegotiation)
+		}
+		b, err := strconv.ParseUint(ours, 10, 32)
+		if err != nil {
+			return "", fmt.Errorf("%s=%s: %w", key, ours, errNegotiation)
+		}
+		if (r == minimum) == (a < b) {
+			return offered, nil
+		}
+		return ours, nil
+	case and, or:
+		a, err := parseBool(key, offered)
+		if err != nil {
+			return "", err
+		}
+		b, err := parseBool(key, ours)
+		if err != nil {
+			return "", err
+		}
+		if r == and {
+			return boolString(a && b), nil
+		}
+		return boolString(a || b), nil
+	}
+	return "", fmt.Errorf("%s is declarative: %w", key, errNegotiation)
+}
+
+// checkResult checks that result is a result the responder may have picked
+// for our offer of key, as Negotiate would.
+func checkResult(key, ours, result string) error {
+	if result == "Reject" {
+		return fmt.Errorf("target rejected %s=%s: %w", key, ours, errNegotiation)
+	}
+	if result == "Irrelevant" || result == "NotUnderstood" {
+		return nil
+	}
+	var ok bool
+	switch rules[key] {
+	case choice:
+		ok = slices.Contains(strings.Split(ours, ","), result)
+	case minimum, maximum, and, or:
+		want, err := Negotiate(key, ours, result)
+		ok = err == nil && want == result
+	default:
+		ok = true
+	}
+	if !ok {
+		return fmt.Errorf("target answered %s=%s to %s: %w", key, result, ours, errNegotiation)
+	}
+	return nil
+}
+
+func parseBool(key, v string) (bool, error) {
+	switch v {
+	case "Yes":
+		return true, nil
+	case "No":
+		return false, nil
+	}
+	return false, fmt.Errorf("%s=%s is not Yes or No: %w", key, v, errNegotiation)
+}
+
+func boolString(b bool) string {
+	if b {
+		return "Yes"
+	}
+	return "No"
+}
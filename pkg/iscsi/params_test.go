@@ -0,0 +1,59 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package iscsi
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	for _, tt := range []struct {
+		key, offered, ours string
+		want               string
+		err                error
+	}{
+		{key: "HeaderDigest", offered: "CRC32C,None", ours: "None,CRC32C", want: "CRC32C"},
+		{key: "HeaderDigest", offered: "CRC32C", ours: "None", want: "Reject"},
+		{key: "MaxBurstLength", offered: "262144", ours: "65536", want: "65536"},
+		{key: "MaxBurstLength", offered: "512", ours: "65536", want: "512"},
+		{key: "DefaultTime2Wait", offered: "2", ours: "5", want: "5"},
+		{key: "ImmediateData", offered: "Yes", ours: "No", want: "No"},
+		{key: "InitialR2T", offered: "No", ours: "Yes", want: "Yes"},
+		{key: "InitialR2T", offered: "No", ours: "No", want: "No"},
+		{key: "X-org.example.Key", offered: "1", ours: "1", want: "NotUnderstood"},
+		{key: "MaxBurstLength", offered: "lots", ours: "65536", err: errNegotiation},
+		{key: "ImmediateData", offered: "Maybe", ours: "No", err: errNegotiation},
+		{key: "TargetAlias", offered: "a", ours: "b", err: errNegotiation},
+	} {
+		got, err := Negotiate(tt.key, tt.offered, tt.ours)
+		if got != tt.want || !errors.Is(err, tt.err) {
+			t.Errorf("Negotiate(%s, %s, %s) = %q, %v, want %q, %v", tt.key, tt.offered, tt.ours, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestCheckResult(t *testing.T) {
+	for _, tt := range []struct {
+		key, ours, result string
+		ok                bool
+	}{
+		{"HeaderDigest", "CRC32C,None", "None", true},
+		{"HeaderDigest", "None", "CRC32C", false},
+		{"MaxBurstLength", "262144", "65536", true},
+		{"MaxBurstLength", "65536", "262144", false},
+		{"ImmediateData", "Yes", "No", true},
+		{"ImmediateData", "No", "Yes", false},
+		{"InitialR2T", "Yes", "No", false},
+		{"InitialR2T", "No", "Yes", true},
+		{"ErrorRecoveryLevel", "0", "Irrelevant", true},
+		{"ErrorRecoveryLevel", "0", "Reject", false},
+	} {
+		err := checkResult(tt.key, tt.ours, tt.result)
+		if (err == nil) != tt.ok || (err != nil && !errors.Is(err, errNegotiation)) {
+			t.Errorf("checkResult(%s, %s, %s) = %v, want ok %t", tt.key, tt.ours, tt.result, err, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package iscsi
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Opcodes, RFC 7143 Section 11.1.1.
+const (
+	OpNOPOut    = 0x00
+	OpLogin     = 0x03
+	OpText      = 0x04
+	OpLogout    = 0x06
+	OpNOPIn     = 0x20
+	OpLoginRsp  = 0x23
+	OpTextRsp   = 0x24
+	OpLogoutRsp = 0x26
+	OpReject    = 0x3f
+
+	// OpImmediate is the I bit of requests that are delivered
+	// immediately, such as logins.
+	OpImmediate = 0x40
+)
+
+// Flags of login PDUs, RFC 7143 Section 11.12.
+const (
+	LoginTransit  = 0x80
+	LoginContinue = 0x40
+)
+
+// Stage is a stage of login, RFC 7143 Section 11.12.3.
+type Stage uint8
+
+// Stages.
+const (
+	SecurityNegotiation    Stage = 0
+	OperationalNegotiation Stage = 1
+	FullFeaturePhase       Stage = 3
+)
+
+// LoginFlags returns the flags of a login PDU in stage csg that goes to
+// stage nsg if transit is set.
+func LoginFlags(transit bool, csg, nsg Stage) uint8 {
+	f := uint8(csg)<<2 | uint8(nsg)
+	if transit {
+		f |= LoginTransit
+	}
+	return f
+}
+
+// Stages returns the current and next stage of the flags of a login PDU.
+func Stages(flags uint8) (csg, nsg Stage) {
+	return Stage(flags>>2) & 3, Stage(flags) & 3
+}
+
+// bhsLen is the length of the basic header segment of all PDUs.
+const bhsLen = 48
+
+var errPDU = errors.New("bad PDU")
+
+// LoginRequest is the header of login requests, RFC 7143 Section 11.12.
+type LoginRequest struct {
+	Opcode            uint8
+	Flags             uint8
+	VersionMax        uint8
+	VersionMin        uint8
+	TotalAHSLength    uint8
+	DataSegmentLength [3]uint8
+	ISID              [6]uint8
+	TSIH              uint16
+	ITT               uint32
+	CID               uint16
+	_                 uint16
+	CmdSN             uint32
+	ExpStatSN         uint32
+	_                 [16]uint8
+}
+
+// LoginResponse is the header of login responses, RFC 7143 Section 11.13.
+type LoginResponse struct {
+	Opcode            uint8
+	Flags             uint8
+	VersionMax        uint8
+	VersionActive     uint8
+	TotalAHSLength    uint8
+	DataSegmentLength [3]uint8
+	ISID              [6]uint8
+	TSIH              uint16
+	ITT               uint32
+	_                 uint32
+	StatSN            uint32
+	ExpCmdSN          uint32
+	MaxCmdSN          uint32
+	StatusClass       uint8
+	StatusDetail      uint8
+	_                 [10]uint8
+}
+
+// PDU is an iSCSI protocol data unit. Additional header segments and
+// digests, which are not used during login, are not supported.
+type PDU struct {
+	// BHS is the basic header segment. Its DataSegmentLength is set by
+	// WriteTo.
+	BHS [bhsLen]byte
+	// Data is the data segment, without padding.
+	Data []byte
+}
+
+// NewPDU returns a PDU of header h, one of the header structs of this
+// package, and data.
+func NewPDU(h any, data []byte) (*PDU, error) {
+	var b bytes.Buffer
+	if err := binary.Write(&b, binary.BigEndian, h); err != nil {
+		return nil, err
+	}
+	if b.Len() != bhsLen {
+		return nil, fmt.Errorf("%T is %d bytes: %w", h, b.Len(), errPDU)
+	}
+	p := &PDU{Data: data}
+	copy(p.BHS[:], b.Bytes())
+	return p, nil
+}
+
+// Opcode returns the opcode, without the I bit.
+func (p *PDU) Opcode() uint8 {
+	return p.BHS[0] &^ OpImmediate
+}
+
+// Decode decodes the header into h, one of the header structs of this
+// package.
+func (p *PDU) Decode(h any) error {
+	return binary.Read(bytes.NewReader(p.BHS[:]), binary.BigEndian, h)
+}
+
+// ReadPDU reads a PDU from r.
+func ReadPDU(r io.Reader) (*PDU, error) {
+	p := &PDU{}
+	if _, err := io.ReadFull(r, p.BHS[:]); err != nil {
+		return nil, err
+	}
+	if ahs := p.BHS[4]; ahs != 0 {
+		return nil, fmt.Errorf("additional header segments of %d words: %w", ahs, errPDU)
+	}
+	n := int(p.BHS[5])<<16 | int(p.BHS[6])<<8 | int(p.BHS[7])
+	// The data segment is padded to 4 bytes.
+	data := make([]byte, (n+3)&^3)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+	p.Data = data[:n]
+	return p, nil
+}
+
+// WriteTo writes p to w.
+func (p *PDU) WriteTo(w io.Writer) (int64, error) {
+	n := len(p.Data)
+	if n >= 1<<24 {
+		return 0, fmt.Errorf("data segment of %d bytes: %w", n, errPDU)
+	}
+	p.BHS[5], p.BHS[6], p.BHS[7] = byte(n>>16), byte(n>>8), byte(n)
+	b := make([]byte, 0, bhsLen+(n+3)&^3)
+	b = append(b, p.BHS[:]...)
+	b = append(b, p.Data...)
+	b = append(b, make([]byte, (4-n%4)%4)...)
+	m, err := w.Write(b)
+	return int64(m), err
+}
+
+// EncodeText encodes the key=value pairs of the data segment of login and
+// text PDUs.
+func EncodeText(pairs ...string) []byte {
+	var b []byte
+	for _, p := range pairs {
+		b = append(b, p...)
+		b = append(b, 0)
+	}
+	return b
+}
+
+// ParseText parses the key=value pairs of the data segment of login and
+// text PDUs.
+func ParseText(b []byte) (map[string]string, error) {
+	kv := map[string]string{}
+	for _, p := range strings.Split(strings.TrimRight(string(b), "\x00"), "\x00") {
+		if p == "" && len(kv) == 0 {
+			continue
+		}
+		k, v, ok := strings.Cut(p, "=")
+		if !ok || k == "" {
+			return nil, fmt.Errorf("text %q is not key=value: %w", p, errPDU)
+		}
+		if _, ok := kv[k]; ok {
+			return nil, fmt.Errorf("text key %s is repeated: %w", k, errPDU)
+		}
+		kv[k] = v
+	}
+	return kv, nil
+}
@@ -7,17 +7,61 @@
 package libinit
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/u-root/u-root/pkg/cmdline"
+	"github.com/u-root/u-root/pkg/netconf"
+	"github.com/u-root/u-root/pkg/shlex"
 	"github.com/u-root/u-root/pkg/ulog"
 	"github.com/vishvananda/netlink"
 )
 
+// NetConfigPath is a network configuration in the initramfs, in the JSON
+// format of package netconf.
+var NetConfigPath = "/etc/netconf.json"
+
+// netInitTimeout bounds the network configuration of NetInit, so that a
+// missing cable or DHCP server cannot hold up the boot for longer.
+const netInitTimeout = time.Minute
+
 // NetInit is u-root network initialization.
+//
+// It brings up loopback. If NetConfigPath exists or the kernel command line
+// has uroot.netconf, it then applies the network configuration in
+// NetConfigPath, that of the network parameters on the kernel command line
+// such as ip=, and that at the URL in uroot.netconf=, in this order.
+// Otherwise the network parameters are left to the kernel.
 func linuxNetInit() {
 	if err := loopbackUp(); err != nil {
 		ulog.KernelLog.Printf("Failed to initialize loopback: %v", err)
 	}
+	args := shlex.Argv(cmdline.FullCmdLine())
+	if !wantNetwork(NetConfigPath, args) {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), netInitTimeout)
+	defer cancel()
+	if err := configureNetwork(ctx, NetConfigPath, args); err != nil {
+		ulog.KernelLog.Printf("Failed to configure network: %v", err)
+	}
+}
+
+// wantNetwork returns whether the network is to be configured: whether
+// there is a configuration at path, or the kernel command line args ask for
+// it with uroot.netconf, which may be the URL of a configuration.
+func wantNetwork(path string, args []string) bool {
+	if _, err := os.Stat(path); err == nil {
+		return true
+	}
+	return slices.ContainsFunc(args, func(a string) bool {
+		return a == "uroot.netconf" || strings.HasPrefix(a, "uroot.netconf=")
+	})
 }
 
 func loopbackUp() error {
@@ -32,6 +76,44 @@ func loopbackUp() error {
 	return nil
 }
 
+// configureNetwork applies the configuration in path and in the kernel
+// command line args.
+func configureNetwork(ctx context.Context, path string, args []string) error {
+	c, err := netconf.Load(path)
+	if errors.Is(err, os.ErrNotExist) {
+		c, err = &netconf.Config{}, nil
+	}
+	if err != nil {
+		return err
+	}
+	switch cl, err := netconf.ParseCmdline(args); {
+	case errors.Is(err, netconf.ErrOff):
+		ulog.KernelLog.Printf("Network configuration from the kernel command line is off")
+	case err != nil:
+		return err
+	case cl != nil:
+		c.Merge(cl)
+	}
+	var errs []error
+	if len(c.Links) > 0 || len(c.Routes) > 0 || len(c.DNS.Nameservers) > 0 || c.Hostname != "" {
+		errs = append(errs, netconf.Apply(ctx, c))
+	}
+
+	// The network may only now be up enough to fetch the rest.
+	for _, a := range args {
+		u, ok := strings.CutPrefix(a, "uroot.netconf=")
+		if !ok {
+			continue
+		}
+		c, err := netconf.Fetch(ctx, u)
+		if err != nil {
+			return errors.Join(append(errs, err)...)
+		}
+		errs = append(errs, netconf.Apply(ctx, c))
+	}
+	return errors.Join(errs...)
+}
+
 func init() {
 	osNetInit = linuxNetInit
 }
@@ -0,0 +1,65 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build linux && !tinygo
+
+package libinit
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfigureNetwork(t *testing.T) {
+	dir := t.TempDir()
+	bad := filepath.Join(dir, "bad.json")
+	if err := os.WriteFile(bad, []byte(`{"links": [{"name": "eth0", "kind": "token-ring"}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	missing := filepath.Join(dir, "missing.json")
+	for _, tt := range []struct {
+		name    string
+		path    string
+		args    []string
+		wantErr bool
+	}{
+		{name: "nothing", path: missing, args: []string{"console=ttyS0"}},
+		{name: "off", path: missing, args: []string{"ip=off"}},
+		{name: "bad file", path: bad, wantErr: true},
+		{name: "bad cmdline", path: missing, args: []string{"ip=eth0:carrier-pigeon"}, wantErr: true},
+		{name: "bad URL", path: missing, args: []string{"uroot.netconf=file://" + missing}, wantErr: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := configureNetwork(context.Background(), tt.path, tt.args); (err != nil) != tt.wantErr {
+				t.Errorf("configureNetwork = %v, want error %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWantNetwork(t *testing.T) {
+	dir := t.TempDir()
+	conf := filepath.Join(dir, "netconf.json")
+	if err := os.WriteFile(conf, []byte(`{}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	missing := filepath.Join(dir, "missing.json")
+	for _, tt := range []struct {
+		path string
+		args []string
+		want bool
+	}{
+		{path: missing, args: []string{"ip=dhcp"}, want: false},
+		{path: missing, args: []string{"uroot.netconfig"}, want: false},
+		{path: conf, args: []string{"ip=dhcp"}, want: true},
+		{path: missing, args: []string{"ip=dhcp", "uroot.netconf"}, want: true},
+		{path: missing, args: []string{"uroot.netconf=http://10.0.0.1/netconf.json"}, want: true},
+	} {
+		if got := wantNetwork(tt.path, tt.args); got != tt.want {
+			t.Errorf("wantNetwork(%s, %q) = %t, want %t", filepath.Base(tt.path), tt.args, got, tt.want)
+		}
+	}
+}
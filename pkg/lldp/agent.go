@@ -0,0 +1,366 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lldp
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// DefaultStateFile is where lldpctl keeps the neighbors that it has seen.
+const DefaultStateFile = "/run/lldp/neighbors.json"
+
+// DefaultInterval is how often an Agent advertises by default, as in
+// 802.1AB.
+const DefaultInterval = 30 * time.Second
+
+// conn is a link to receive and send LLDP frames on.
+type conn interface {
+	readFrame(b []byte) (int, error)
+	writeFrame(b []byte) error
+	Close() error
+}
+
+// neighborKey identifies a neighbor: the same system may be seen on several
+// links, and one link may have several systems.
+type neighborKey struct {
+	ifname  string
+	chassis ChassisID
+	port    PortID
+}
+
+// Agent receives LLDP frames, and optionally advertises the system, on a
+// set of links.
+type Agent struct {
+	// Logf logs neighbors and errors. It defaults to log.Printf.
+	Logf func(format string, v ...any)
+
+	// Advertise is advertised on every link if it is not nil. The chassis
+	// ID defaults to the first MAC address of the links, and the port
+	// ID, TTL, management addresses and maximum frame size to those of
+	// each link.
+	Advertise *Info
+
+	// Interval is how often to advertise. It defaults to
+	// DefaultInterval.
+	Interval time.Duration
+
+	// StateFile, if set, is rewritten with the neighbors whenever they
+	// change.
+	StateFile string
+
+	// now is replaced in tests.
+	now func() time.Time
+
+	mu        sync.Mutex
+	neighbors map[neighborKey]*Neighbor
+}
+
+// NewAgent returns an agent that only receives.
+func NewAgent() *Agent {
+	return &Agent{
+		Logf:      log.Printf,
+		Interval:  DefaultInterval,
+		now:       time.Now,
+		neighbors: map[neighborKey]*Neighbor{},
+	}
+}
+
+// Neighbors returns the neighbors that have not expired, by interface,
+// chassis and port.
+func (a *Agent) Neighbors() []Neighbor {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.expire(a.now())
+	return a.list()
+}
+
+func (a *Agent) list() []Neighbor {
+	ns := []Neighbor{}
+	for _, n := range a.neighbors {
+		ns = append(ns, *n)
+	}
+	slices.SortFunc(ns, func(x, y Neighbor) int {
+		return cmp.Or(
+			cmp.Compare(x.Interface, y.Interface),
+			cmp.Compare(x.ChassisID.String(), y.ChassisID.String()),
+			cmp.Compare(x.PortID.String(), y.PortID.String()),
+		)
+	})
+	return ns
+}
+
+// Run receives on links, and advertises on them if a.Advertise is set,
+// until ctx is done or a link fails. When advertising, it tells the
+// neighbors that it is leaving before it returns.
+func (a *Agent) Run(ctx context.Context, links []*net.Interface) error {
+	if len(links) == 0 {
+		return errors.New("no links to run LLDP on")
+	}
+	conns := make([]conn, len(links))
+	for i, ifi := range links {
+		c, err := listen(ifi)
+		if err != nil {
+			for _, c := range conns[:i] {
+				c.Close()
+			}
+			return err
+		}
+		conns[i] = c
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	errs := make(chan error, len(links))
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for i, ifi := range links {
+		wg.Go(func() {
+			b := make([]byte, 1518)
+			for {
+				n, err := conns[i].readFrame(b)
+				if err != nil {
+					if ctx.Err() == nil {
+						errs <- err
+					}
+					return
+				}
+				a.receive(ifi.Name, ifi.HardwareAddr, b[:n])
+			}
+		})
+	}
+	// Closing the links stops the receivers.
+	defer func() {
+		cancel()
+		for _, c := range conns {
+			c.Close()
+		}
+	}()
+
+	var infos []*Info
+	if a.Advertise != nil {
+		infos = a.infos(links)
+	}
+	advertise := func() {
+		for i, ifi := range links {
+			if err := a.send(conns[i], ifi, infos[i]); err != nil {
+				a.Logf("lldp: %s: %v", ifi.Name, err)
+			}
+		}
+	}
+	if infos != nil {
+		advertise()
+		defer func() {
+			for i := range infos {
+				shutdown := *infos[i]
+				shutdown.TTL = 0
+				infos[i] = &shutdown
+			}
+			advertise()
+		}()
+	}
+
+	expire := time.NewTicker(time.Second)
+	defer expire.Stop()
+	next := a.now().Add(a.interval())
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-errs:
+			return err
+		case <-expire.C:
+			a.mu.Lock()
+			a.expire(a.now())
+			a.mu.Unlock()
+			if now := a.now(); infos != nil && !now.Before(next) {
+				advertise()
+				next = now.Add(a.interval())
+			}
+		}
+	}
+}
+
+func (a *Agent) interval() time.Duration {
+	if a.Interval <= 0 {
+		return DefaultInterval
+	}
+	return a.Interval
+}
+
+// infos returns what to advertise on each of links.
+func (a *Agent) infos(links []*net.Interface) []*Info {
+	var chassis ChassisID
+	for _, ifi := range links {
+		if len(ifi.HardwareAddr) == 6 {
+			chassis = ChassisID{Subtype: ChassisMAC, Value: ifi.HardwareAddr.String()}
+			break
+		}
+	}
+	infos := make([]*Info, len(links))
+	for i, ifi := range links {
+		info := *a.Advertise
+		if info.ChassisID.Value == "" {
+			info.ChassisID = chassis
+		}
+		if info.PortID.Value == "" {
+			info.PortID = PortID{Subtype: PortInterfaceName, Value: ifi.Name}
+		}
+		if info.TTL == 0 {
+			// Neighbors keep the information for 4 intervals.
+			info.TTL = min(max(int(4*a.interval()/time.Second), 1), 0xffff)
+		}
+		if info.ManagementAddresses == nil {
+			info.ManagementAddresses = globalAddrs(ifi)
+		}
+		if info.MaxFrameSize == 0 && ifi.MTU > 0 {
+			// The Ethernet header, a VLAN tag and the FCS.
+			info.MaxFrameSize = ifi.MTU + 18
+		}
+		infos[i] = &info
+	}
+	return infos
+}
+
+// globalAddrs returns the global unicast addresses of ifi.
+func globalAddrs(ifi *net.Interface) []netip.Addr {
+	addrs, err := ifi.Addrs()
+	if err != nil {
+		return nil
+	}
+	var as []netip.Addr
+	for _, addr := range addrs {
+		n, ok := addr.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		if a, ok := netip.AddrFromSlice(n.IP); ok && a.IsGlobalUnicast() {
+			as = append(as, a.Unmap())
+		}
+	}
+	return as
+}
+
+func (a *Agent) send(c conn, ifi *net.Interface, info *Info) error {
+	if len(ifi.HardwareAddr) != 6 {
+		return errors.New("no Ethernet address to send from")
+	}
+	frame, err := Frame(ifi.HardwareAddr, info)
+	if err != nil {
+		return err
+	}
+	return c.writeFrame(frame)
+}
+
+// receive handles an LLDP frame received on the link ifname with the
+// address local.
+func (a *Agent) receive(ifname string, local net.HardwareAddr, frame []byte) {
+	src, info, err := ParseFrame(frame)
+	if err != nil {
+		a.Logf("lldp: %s: %v", ifname, err)
+		return
+	}
+	if bytes.Equal(src, local) {
+		return
+	}
+	key := neighborKey{ifname: ifname, chassis: info.ChassisID, port: info.PortID}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	now := a.now()
+	a.expire(now)
+	_, known := a.neighbors[key]
+	if info.TTL == 0 {
+		// The neighbor is shutting down.
+		if known {
+			delete(a.neighbors, key)
+			a.Logf("lldp: %s: %s port %s left", ifname, info.ChassisID, info.PortID)
+			a.save()
+		}
+		return
+	}
+	if !known {
+		a.Logf("lldp: %s: new neighbor %s port %s", ifname, info.ChassisID, info.PortID)
+	}
+	a.neighbors[key] = &Neighbor{Interface: ifname, LastSeen: now, Info: *info}
+	a.save()
+}
+
+// expire drops the neighbors that expired at now.
+func (a *Agent) expire(now time.Time) {
+	var changed bool
+	for key, n := range a.neighbors {
+		if n.Expired(now) {
+			delete(a.neighbors, key)
+			changed = true
+		}
+	}
+	if changed {
+		a.save()
+	}
+}
+
+// save writes the neighbors to the state file, replacing it atomically.
+func (a *Agent) save() {
+	if a.StateFile == "" {
+		return
+	}
+	if err := writeStateFile(a.StateFile, a.list()); err != nil {
+		a.Logf("lldp: %v", err)
+	}
+}
+
+func writeStateFile(path string, ns []Neighbor) error {
+	b, err := json.MarshalIndent(ns, "", "\t")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Chmod(0o644); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// ReadStateFile returns the neighbors in the state file of an Agent that
+// have not expired.
+func ReadStateFile(path string) ([]Neighbor, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var ns []Neighbor
+	if err := json.Unmarshal(b, &ns); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	return slices.DeleteFunc(ns, func(n Neighbor) bool { return n.Expired(now) }), nil
+}
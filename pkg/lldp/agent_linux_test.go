@@ -0,0 +1,96 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lldp
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/hugelgupf/vmtest/guest"
+	"github.com/vishvananda/netlink"
+)
+
+// TestAgentVeth advertises from one end of a veth pair to an agent on the
+// other end.
+func TestAgentVeth(t *testing.T) {
+	guest.SkipIfNotInVM(t)
+
+	la := netlink.NewLinkAttrs()
+	la.Name = "lldp0"
+	if err := netlink.LinkAdd(&netlink.Veth{LinkAttrs: la, PeerName: "lldp1"}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if l, err := netlink.LinkByName("lldp0"); err == nil {
+			netlink.LinkDel(l)
+		}
+	})
+	var ifis []*net.Interface
+	for _, name := range []string{"lldp0", "lldp1"} {
+		l, err := netlink.LinkByName(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := netlink.LinkSetUp(l); err != nil {
+			t.Fatal(err)
+		}
+		ifi, err := net.InterfaceByName(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ifis = append(ifis, ifi)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	rx := NewAgent()
+	rx.Logf = t.Logf
+	received := make(chan error, 1)
+	go func() { received <- rx.Run(ctx, ifis[1:]) }()
+
+	tx := NewAgent()
+	tx.Logf = t.Logf
+	tx.Interval = time.Second
+	tx.Advertise = &Info{SystemName: "node1", Capabilities: CapStation, EnabledCapabilities: CapStation}
+	txCtx, txCancel := context.WithCancel(ctx)
+	sent := make(chan error, 1)
+	go func() { sent <- tx.Run(txCtx, ifis[:1]) }()
+
+	var ns []Neighbor
+	for deadline := time.Now().Add(10 * time.Second); len(ns) == 0; time.Sleep(100 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("no neighbor seen")
+		}
+		ns = rx.Neighbors()
+	}
+	n := ns[0]
+	if n.Interface != "lldp1" || n.SystemName != "node1" || n.ChassisID.Value != ifis[0].HardwareAddr.String() ||
+		n.PortID != (PortID{Subtype: PortInterfaceName, Value: "lldp0"}) || n.TTL != 4 {
+		t.Errorf("neighbor = %+v", n)
+	}
+	// The transmitter does not see its own frames.
+	if ns := tx.Neighbors(); len(ns) != 0 {
+		t.Errorf("transmitter neighbors = %v, want none", ns)
+	}
+
+	// Stopping the transmitter removes it from the receiver.
+	txCancel()
+	if err := <-sent; !errors.Is(err, context.Canceled) {
+		t.Errorf("transmitter Run = %v", err)
+	}
+	for deadline := time.Now().Add(5 * time.Second); len(rx.Neighbors()) != 0; time.Sleep(100 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("neighbor not removed on shutdown")
+		}
+	}
+
+	cancel()
+	if err := <-received; !errors.Is(err, context.Canceled) {
+		t.Errorf("receiver Run = %v", err)
+	}
+}
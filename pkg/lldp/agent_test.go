@@ -0,0 +1,164 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lldp
+
+import (
+	"encoding/json"
+	"net"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func testAgent(t *testing.T, now *time.Time) *Agent {
+	a := NewAgent()
+	a.Logf = t.Logf
+	a.now = func() time.Time { return *now }
+	a.StateFile = filepath.Join(t.TempDir(), "lldp", "neighbors.json")
+	return a
+}
+
+func mustFrame(t *testing.T, src net.HardwareAddr, info *Info) []byte {
+	t.Helper()
+	frame, err := Frame(src, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return frame
+}
+
+func TestAgentReceive(t *testing.T) {
+	// ReadStateFile expires neighbors by the wall clock.
+	now := time.Now().Round(0)
+	a := testAgent(t, &now)
+	local := net.HardwareAddr{2, 0, 0, 0, 0, 1}
+	sw := net.HardwareAddr{2, 0, 0, 0, 0, 2}
+	sw1 := &Info{
+		ChassisID:  ChassisID{Subtype: ChassisMAC, Value: sw.String()},
+		PortID:     PortID{Subtype: PortInterfaceName, Value: "Gi1/0/1"},
+		TTL:        120,
+		SystemName: "sw1",
+	}
+	sw2 := &Info{
+		ChassisID: ChassisID{Subtype: ChassisLocal, Value: "sw2"},
+		PortID:    PortID{Subtype: PortLocal, Value: "7"},
+		TTL:       10,
+	}
+
+	a.receive("eth0", local, mustFrame(t, sw, sw1))
+	a.receive("eth1", local, mustFrame(t, sw, sw2))
+	// Our own frames, and garbage, are ignored.
+	a.receive("eth0", local, mustFrame(t, local, sw2))
+	a.receive("eth0", local, []byte("garbage"))
+
+	want := []Neighbor{
+		{Interface: "eth0", LastSeen: now, Info: *sw1},
+		{Interface: "eth1", LastSeen: now, Info: *sw2},
+	}
+	if diff := cmp.Diff(want, a.Neighbors(), cmpNetip); diff != "" {
+		t.Errorf("Neighbors (-want +got):\n%s", diff)
+	}
+	ns, err := ReadStateFile(a.StateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ns) != 2 {
+		t.Errorf("state file has %d neighbors, want 2", len(ns))
+	}
+
+	// sw2 expires, and sw1 is refreshed.
+	now = now.Add(10 * time.Second)
+	a.receive("eth0", local, mustFrame(t, sw, sw1))
+	want = []Neighbor{{Interface: "eth0", LastSeen: now, Info: *sw1}}
+	if diff := cmp.Diff(want, a.Neighbors(), cmpNetip); diff != "" {
+		t.Errorf("Neighbors after expiry (-want +got):\n%s", diff)
+	}
+
+	// A TTL of 0 removes sw1.
+	shutdown := *sw1
+	shutdown.TTL = 0
+	a.receive("eth0", local, mustFrame(t, sw, &shutdown))
+	if ns := a.Neighbors(); len(ns) != 0 {
+		t.Errorf("Neighbors after shutdown = %v, want none", ns)
+	}
+	b, err := os.ReadFile(a.StateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(b); got != "[]\n" {
+		t.Errorf("state file = %q, want an empty list", got)
+	}
+}
+
+func TestReadStateFileExpired(t *testing.T) {
+	now := time.Now()
+	ns := []Neighbor{
+		{Interface: "eth0", LastSeen: now, Info: Info{ChassisID: ChassisID{Subtype: ChassisLocal, Value: "a"}, TTL: 120}},
+		{Interface: "eth0", LastSeen: now.Add(-time.Hour), Info: Info{ChassisID: ChassisID{Subtype: ChassisLocal, Value: "b"}, TTL: 120}},
+	}
+	path := filepath.Join(t.TempDir(), "neighbors.json")
+	if err := writeStateFile(path, ns); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadStateFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].ChassisID.Value != "a" {
+		t.Errorf("ReadStateFile = %v, want only a", got)
+	}
+}
+
+func TestNeighborJSON(t *testing.T) {
+	n := Neighbor{
+		Interface: "eth0",
+		LastSeen:  time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		Info: Info{
+			ChassisID:           ChassisID{Subtype: ChassisMAC, Value: "02:00:00:00:00:02"},
+			PortID:              PortID{Subtype: 9, Value: "x"},
+			TTL:                 120,
+			Capabilities:        CapBridge | CapRouter,
+			ManagementAddresses: []netip.Addr{netip.MustParseAddr("10.0.0.2")},
+		},
+	}
+	b, err := json.Marshal(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Neighbor
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatalf("%s: %v", b, err)
+	}
+	if diff := cmp.Diff(n, got, cmpNetip); diff != "" {
+		t.Errorf("JSON round trip (-want +got):\n%s", diff)
+	}
+}
+
+func TestAgentInfos(t *testing.T) {
+	a := NewAgent()
+	a.Interval = 20 * time.Second
+	a.Advertise = &Info{SystemName: "node1", ManagementAddresses: []netip.Addr{}}
+	links := []*net.Interface{
+		{Name: "lo", MTU: 65536},
+		{Name: "eth0", MTU: 1500, HardwareAddr: net.HardwareAddr{2, 0, 0, 0, 0, 1}},
+		{Name: "eth1", MTU: 9000, HardwareAddr: net.HardwareAddr{2, 0, 0, 0, 0, 2}},
+	}
+	infos := a.infos(links)
+	want := &Info{
+		ChassisID:           ChassisID{Subtype: ChassisMAC, Value: "02:00:00:00:00:01"},
+		PortID:              PortID{Subtype: PortInterfaceName, Value: "eth1"},
+		TTL:                 80,
+		SystemName:          "node1",
+		ManagementAddresses: []netip.Addr{},
+		MaxFrameSize:        9018,
+	}
+	if diff := cmp.Diff(want, infos[2], cmpNetip); diff != "" {
+		t.Errorf("infos (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lldp
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/mdlayher/packet"
+	"golang.org/x/sys/unix"
+)
+
+type packetConn struct {
+	*packet.Conn
+}
+
+// listen opens an AF_PACKET socket for LLDP frames on ifi, which receives
+// frames sent to any of the LLDP addresses.
+func listen(ifi *net.Interface) (conn, error) {
+	c, err := packet.Listen(ifi, packet.Raw, EtherType, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", ifi.Name, err)
+	}
+	rc, err := c.SyscallConn()
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+	var serr error
+	if err := rc.Control(func(fd uintptr) {
+		for _, addr := range []net.HardwareAddr{NearestBridge, NearestNonTPMRBridge, NearestCustomerBridge} {
+			mreq := &unix.PacketMreq{
+				Ifindex: int32(ifi.Index),
+				Type:    unix.PACKET_MR_MULTICAST,
+				Alen:    uint16(len(addr)),
+			}
+			copy(mreq.Address[:], addr)
+			if serr = unix.SetsockoptPacketMreq(int(fd), unix.SOL_PACKET, unix.PACKET_ADD_MEMBERSHIP, mreq); serr != nil {
+				return
+			}
+		}
+	}); err != nil {
+		serr = err
+	}
+	if serr != nil {
+		c.Close()
+		return nil, fmt.Errorf("%s: joining LLDP multicast groups: %w", ifi.Name, serr)
+	}
+	return &packetConn{c}, nil
+}
+
+func (c *packetConn) readFrame(b []byte) (int, error) {
+	n, _, err := c.ReadFrom(b)
+	return n, err
+}
+
+func (c *packetConn) writeFrame(b []byte) error {
+	_, err := c.WriteTo(b, &packet.Addr{HardwareAddr: NearestBridge})
+	return err
+}
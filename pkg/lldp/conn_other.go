@@ -0,0 +1,16 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !linux
+
+package lldp
+
+import (
+	"errors"
+	"net"
+)
+
+func listen(*net.Interface) (conn, error) {
+	return nil, errors.ErrUnsupported
+}
@@ -0,0 +1,417 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package lldp implements the Link Layer Discovery Protocol of IEEE
+// 802.1AB, which switches use to advertise themselves and the port that a
+// link is patched into.
+//
+// An Agent receives LLDP frames on a set of links, keeps the neighbors they
+// describe until their TTL runs out, and optionally advertises the system
+// on the links itself. Parse and Info.MarshalBinary decode and encode
+// LLDPDUs, including the 802.1 VLAN and link aggregation, and the 802.3
+// MAC/PHY TLVs.
+package lldp
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/netip"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// EtherType is the EtherType of LLDP frames.
+const EtherType = 0x88cc
+
+// The destination addresses of LLDP frames. Switches advertise to
+// NearestBridge, which no bridge forwards.
+var (
+	NearestBridge         = net.HardwareAddr{0x01, 0x80, 0xc2, 0x00, 0x00, 0x0e}
+	NearestNonTPMRBridge  = net.HardwareAddr{0x01, 0x80, 0xc2, 0x00, 0x00, 0x03}
+	NearestCustomerBridge = net.HardwareAddr{0x01, 0x80, 0xc2, 0x00, 0x00, 0x00}
+)
+
+// Info is the information of an LLDPDU.
+type Info struct {
+	ChassisID ChassisID `json:"chassis_id"`
+	PortID    PortID    `json:"port_id"`
+
+	// TTL is how long the information is valid, in seconds. A TTL of 0
+	// says the sender shuts down.
+	TTL int `json:"ttl"`
+
+	PortDescription     string       `json:"port_description,omitempty"`
+	SystemName          string       `json:"system_name,omitempty"`
+	SystemDescription   string       `json:"system_description,omitempty"`
+	Capabilities        Capabilities `json:"capabilities,omitempty"`
+	EnabledCapabilities Capabilities `json:"enabled_capabilities,omitempty"`
+	ManagementAddresses []netip.Addr `json:"management_addresses,omitempty"`
+
+	// PortVLAN is the port VLAN ID, the VLAN of untagged frames.
+	PortVLAN int `json:"port_vlan,omitempty"`
+
+	// VLANs are the VLANs of the port that have names.
+	VLANs []VLAN `json:"vlans,omitempty"`
+
+	LinkAggregation *LinkAggregation `json:"link_aggregation,omitempty"`
+	MACPHY          *MACPHY          `json:"mac_phy,omitempty"`
+
+	// MaxFrameSize is the largest frame the port takes, with the
+	// Ethernet header and FCS.
+	MaxFrameSize int `json:"max_frame_size,omitempty"`
+}
+
+// ChassisIDSubtype says what a chassis ID is.
+type ChassisIDSubtype byte
+
+// Chassis ID subtypes.
+const (
+	ChassisComponent      ChassisIDSubtype = 1
+	ChassisInterfaceAlias ChassisIDSubtype = 2
+	ChassisPortComponent  ChassisIDSubtype = 3
+	ChassisMAC            ChassisIDSubtype = 4
+	ChassisNetworkAddress ChassisIDSubtype = 5
+	ChassisInterfaceName  ChassisIDSubtype = 6
+	ChassisLocal          ChassisIDSubtype = 7
+)
+
+var chassisIDSubtypes = map[ChassisIDSubtype]string{
+	ChassisComponent:      "chassis",
+	ChassisInterfaceAlias: "ifalias",
+	ChassisPortComponent:  "port",
+	ChassisMAC:            "mac",
+	ChassisNetworkAddress: "ip",
+	ChassisInterfaceName:  "ifname",
+	ChassisLocal:          "local",
+}
+
+// PortIDSubtype says what a port ID is.
+type PortIDSubtype byte
+
+// Port ID subtypes.
+const (
+	PortInterfaceAlias PortIDSubtype = 1
+	PortComponent      PortIDSubtype = 2
+	PortMAC            PortIDSubtype = 3
+	PortNetworkAddress PortIDSubtype = 4
+	PortInterfaceName  PortIDSubtype = 5
+	PortAgentCircuitID PortIDSubtype = 6
+	PortLocal          PortIDSubtype = 7
+)
+
+var portIDSubtypes = map[PortIDSubtype]string{
+	PortInterfaceAlias: "ifalias",
+	PortComponent:      "port",
+	PortMAC:            "mac",
+	PortNetworkAddress: "ip",
+	PortInterfaceName:  "ifname",
+	PortAgentCircuitID: "circuit",
+	PortLocal:          "local",
+}
+
+// String returns the name of s, as lldpctl shows it.
+func (s ChassisIDSubtype) String() string {
+	if n, ok := chassisIDSubtypes[s]; ok {
+		return n
+	}
+	return fmt.Sprintf("subtype%d", byte(s))
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s ChassisIDSubtype) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *ChassisIDSubtype) UnmarshalText(b []byte) error {
+	for st, n := range chassisIDSubtypes {
+		if n == string(b) {
+			*s = st
+			return nil
+		}
+	}
+	var n byte
+	if _, err := fmt.Sscanf(string(b), "subtype%d", &n); err != nil {
+		return fmt.Errorf("unknown chassis ID subtype %q", b)
+	}
+	*s = ChassisIDSubtype(n)
+	return nil
+}
+
+// String returns the name of s, as lldpctl shows it.
+func (s PortIDSubtype) String() string {
+	if n, ok := portIDSubtypes[s]; ok {
+		return n
+	}
+	return fmt.Sprintf("subtype%d", byte(s))
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s PortIDSubtype) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *PortIDSubtype) UnmarshalText(b []byte) error {
+	for st, n := range portIDSubtypes {
+		if n == string(b) {
+			*s = st
+			return nil
+		}
+	}
+	var n byte
+	if _, err := fmt.Sscanf(string(b), "subtype%d", &n); err != nil {
+		return fmt.Errorf("unknown port ID subtype %q", b)
+	}
+	*s = PortIDSubtype(n)
+	return nil
+}
+
+// ChassisID identifies the system that sent an LLDPDU. Value is a MAC
+// address for ChassisMAC, an IP address for ChassisNetworkAddress, and a
+// string otherwise, or hex if it is not printable.
+type ChassisID struct {
+	Subtype ChassisIDSubtype `json:"subtype"`
+	Value   string           `json:"value"`
+}
+
+func (id ChassisID) String() string {
+	return id.Subtype.String() + " " + id.Value
+}
+
+// PortID identifies the port that sent an LLDPDU. Value is a MAC address
+// for PortMAC, an IP address for PortNetworkAddress, and a string
+// otherwise, or hex if it is not printable.
+type PortID struct {
+	Subtype PortIDSubtype `json:"subtype"`
+	Value   string        `json:"value"`
+}
+
+func (id PortID) String() string {
+	return id.Subtype.String() + " " + id.Value
+}
+
+// Capabilities are the capabilities of a system, as a bit set.
+type Capabilities uint16
+
+// Capabilities.
+const (
+	CapOther Capabilities = 1 << iota
+	CapRepeater
+	CapBridge
+	CapWLANAccessPoint
+	CapRouter
+	CapTelephone
+	CapDOCSIS
+	CapStation
+	CapCVLAN
+	CapSVLAN
+	CapTPMR
+)
+
+var capabilityNames = []string{"other", "repeater", "bridge", "wlan", "router", "telephone", "docsis", "station", "cvlan", "svlan", "tpmr"}
+
+// String returns the names of the capabilities, comma-separated.
+func (c Capabilities) String() string {
+	var names []string
+	for i, n := range capabilityNames {
+		if c&(1<<i) != 0 {
+			names = append(names, n)
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (c Capabilities) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (c *Capabilities) UnmarshalText(b []byte) error {
+	*c = 0
+	for n := range strings.SplitSeq(string(b), ",") {
+		if n == "" {
+			continue
+		}
+		i := slices.Index(capabilityNames, n)
+		if i < 0 {
+			return fmt.Errorf("unknown capability %q", n)
+		}
+		*c |= 1 << i
+	}
+	return nil
+}
+
+// VLAN is a VLAN of a port.
+type VLAN struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// LinkAggregation says whether the port can be and is aggregated.
+type LinkAggregation struct {
+	Capable bool `json:"capable"`
+	Enabled bool `json:"enabled"`
+
+	// PortID identifies the aggregation the port is in.
+	PortID int `json:"port_id"`
+}
+
+// MACPHY is the auto-negotiation state and operational MAU type of a port.
+type MACPHY struct {
+	AutonegSupported bool `json:"autoneg_supported"`
+	AutonegEnabled   bool `json:"autoneg_enabled"`
+
+	// Advertised are the auto-negotiation capabilities advertised, as the
+	// bits of ifMauAutoNegCapAdvertisedBits.
+	Advertised uint16 `json:"advertised"`
+
+	// MAUType is the operational MAU type, as in RFC 4836.
+	MAUType int `json:"mau_type"`
+}
+
+// MAU returns the name of the MAU type, as in "1000BaseTFD".
+func (m *MACPHY) MAU() string {
+	if n, ok := mauTypes[m.MAUType]; ok {
+		return n
+	}
+	return fmt.Sprintf("unknown (%d)", m.MAUType)
+}
+
+// mauTypes are the names of dot3MauType of RFC 4836.
+var mauTypes = map[int]string{
+	1:  "AUI",
+	2:  "10Base5",
+	3:  "FOIRL",
+	4:  "10Base2",
+	5:  "10BaseT",
+	6:  "10BaseFP",
+	7:  "10BaseFB",
+	8:  "10BaseFL",
+	9:  "10Broad36",
+	10: "10BaseTHD",
+	11: "10BaseTFD",
+	12: "10BaseFLHD",
+	13: "10BaseFLFD",
+	14: "100BaseT4",
+	15: "100BaseTXHD",
+	16: "100BaseTXFD",
+	17: "100BaseFXHD",
+	18: "100BaseFXFD",
+	19: "100BaseT2HD",
+	20: "100BaseT2FD",
+	21: "1000BaseXHD",
+	22: "1000BaseXFD",
+	23: "1000BaseLXHD",
+	24: "1000BaseLXFD",
+	25: "1000BaseSXHD",
+	26: "1000BaseSXFD",
+	27: "1000BaseCXHD",
+	28: "1000BaseCXFD",
+	29: "1000BaseTHD",
+	30: "1000BaseTFD",
+	31: "10GigBaseX",
+	32: "10GigBaseLX4",
+	33: "10GigBaseR",
+	34: "10GigBaseER",
+	35: "10GigBaseLR",
+	36: "10GigBaseSR",
+	37: "10GigBaseW",
+	38: "10GigBaseEW",
+	39: "10GigBaseLW",
+	40: "10GigBaseSW",
+	41: "10GigBaseCX4",
+	54: "10GigBaseT",
+	55: "10GigBaseLRM",
+	56: "1000BaseKX",
+	57: "10GigBaseKX4",
+	58: "10GigBaseKR",
+}
+
+// Neighbor is a system seen on a link.
+type Neighbor struct {
+	// Interface is the local link that the neighbor is on.
+	Interface string    `json:"interface"`
+	LastSeen  time.Time `json:"last_seen"`
+	Info
+}
+
+// Expired reports whether the information of n ran out at now.
+func (n *Neighbor) Expired(now time.Time) bool {
+	return !now.Before(n.LastSeen.Add(time.Duration(n.TTL) * time.Second))
+}
+
+// formatID returns an ID of the given kind in the form of Value.
+func formatID(b []byte, mac, addr bool) string {
+	switch {
+	case mac && len(b) == 6:
+		return net.HardwareAddr(b).String()
+	case addr:
+		if a, ok := parseAddr(b); ok {
+			return a.String()
+		}
+	}
+	return printable(b)
+}
+
+// printable returns b as a string if it is printable, else in hex.
+func printable(b []byte) string {
+	s := string(b)
+	if !utf8.ValidString(s) || strings.IndexFunc(s, func(r rune) bool { return !unicode.IsPrint(r) }) >= 0 {
+		return hex.EncodeToString(b)
+	}
+	return s
+}
+
+// IANA address families, which prefix network addresses.
+const (
+	familyIPv4 = 1
+	familyIPv6 = 2
+)
+
+// parseAddr parses an IP address prefixed by its address family.
+func parseAddr(b []byte) (netip.Addr, bool) {
+	if len(b) == 0 {
+		return netip.Addr{}, false
+	}
+	switch {
+	case b[0] == familyIPv4 && len(b) == 5, b[0] == familyIPv6 && len(b) == 17:
+		return netip.AddrFromSlice(b[1:])
+	}
+	return netip.Addr{}, false
+}
+
+// appendAddr appends a prefixed by its address family.
+func appendAddr(b []byte, a netip.Addr) []byte {
+	if a.Is4() {
+		b = append(b, familyIPv4)
+	} else {
+		b = append(b, familyIPv6)
+	}
+	return append(b, a.AsSlice()...)
+}
+
+// encodeID returns the bytes of an ID of the given kind.
+func encodeID(value string, mac, addr bool) ([]byte, error) {
+	switch {
+	case mac:
+		hw, err := net.ParseMAC(value)
+		if err != nil {
+			return nil, err
+		}
+		return hw, nil
+	case addr:
+		a, err := netip.ParseAddr(value)
+		if err != nil {
+			return nil, err
+		}
+		return appendAddr(nil, a.Unmap()), nil
+	}
+	return []byte(value), nil
+}
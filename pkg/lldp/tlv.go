@@ -0,0 +1,304 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lldp
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// TLV types.
+const (
+	tlvEnd                = 0
+	tlvChassisID          = 1
+	tlvPortID             = 2
+	tlvTTL                = 3
+	tlvPortDescription    = 4
+	tlvSystemName         = 5
+	tlvSystemDescription  = 6
+	tlvSystemCapabilities = 7
+	tlvManagementAddress  = 8
+	tlvOrgSpecific        = 127
+)
+
+// Organizationally specific TLVs of IEEE 802.1 and 802.3.
+var (
+	oui8021 = [3]byte{0x00, 0x80, 0xc2}
+	oui8023 = [3]byte{0x00, 0x12, 0x0f}
+)
+
+const (
+	dot1PortVLAN        = 1
+	dot1VLANName        = 3
+	dot1LinkAggregation = 7
+
+	dot3MACPHY          = 1
+	dot3LinkAggregation = 3
+	dot3MaxFrameSize    = 4
+)
+
+// maxTLVLen is the largest value of a TLV.
+const maxTLVLen = 511
+
+// headerLen is the length of an Ethernet header.
+const headerLen = 14
+
+// minFrameLen is the length of the shortest Ethernet frame, without FCS.
+const minFrameLen = 60
+
+var errMalformed = errors.New("malformed LLDPDU")
+
+// Parse parses an LLDPDU, the payload of an LLDP frame. TLVs it does not
+// know are skipped.
+func Parse(b []byte) (*Info, error) {
+	info := &Info{}
+	n := 0
+	for ; len(b) > 0; n++ {
+		if len(b) < 2 {
+			return nil, errMalformed
+		}
+		typ, l := b[0]>>1, int(binary.BigEndian.Uint16(b)&maxTLVLen)
+		if len(b) < 2+l {
+			return nil, fmt.Errorf("%w: TLV %d is truncated", errMalformed, typ)
+		}
+		v := b[2 : 2+l]
+		b = b[2+l:]
+		// The LLDPDU starts with the chassis ID, port ID and TTL.
+		if n < 3 && typ != []byte{tlvChassisID, tlvPortID, tlvTTL}[n] {
+			break
+		}
+		if typ == tlvEnd {
+			break
+		}
+		if err := info.parseTLV(typ, v); err != nil {
+			return nil, fmt.Errorf("%w: TLV %d: %w", errMalformed, typ, err)
+		}
+	}
+	if n < 3 {
+		return nil, fmt.Errorf("%w: mandatory TLVs are missing", errMalformed)
+	}
+	return info, nil
+}
+
+var errShort = errors.New("too short")
+
+func (info *Info) parseTLV(typ byte, v []byte) error {
+	switch typ {
+	case tlvChassisID:
+		if len(v) < 2 {
+			return errShort
+		}
+		st := ChassisIDSubtype(v[0])
+		info.ChassisID = ChassisID{Subtype: st, Value: formatID(v[1:], st == ChassisMAC, st == ChassisNetworkAddress)}
+	case tlvPortID:
+		if len(v) < 2 {
+			return errShort
+		}
+		st := PortIDSubtype(v[0])
+		info.PortID = PortID{Subtype: st, Value: formatID(v[1:], st == PortMAC, st == PortNetworkAddress)}
+	case tlvTTL:
+		if len(v) < 2 {
+			return errShort
+		}
+		info.TTL = int(binary.BigEndian.Uint16(v))
+	case tlvPortDescription:
+		info.PortDescription = string(v)
+	case tlvSystemName:
+		info.SystemName = string(v)
+	case tlvSystemDescription:
+		info.SystemDescription = string(v)
+	case tlvSystemCapabilities:
+		if len(v) < 4 {
+			return errShort
+		}
+		info.Capabilities = Capabilities(binary.BigEndian.Uint16(v))
+		info.EnabledCapabilities = Capabilities(binary.BigEndian.Uint16(v[2:]))
+	case tlvManagementAddress:
+		// The address string length counts the address family.
+		if len(v) < 1 || len(v) < 1+int(v[0]) {
+			return errShort
+		}
+		if a, ok := parseAddr(v[1 : 1+v[0]]); ok {
+			info.ManagementAddresses = append(info.ManagementAddresses, a)
+		}
+	case tlvOrgSpecific:
+		if len(v) < 4 {
+			return errShort
+		}
+		return info.parseOrgTLV([3]byte(v), v[3], v[4:])
+	}
+	return nil
+}
+
+func (info *Info) parseOrgTLV(oui [3]byte, subtype byte, v []byte) error {
+	switch {
+	case oui == oui8021 && subtype == dot1PortVLAN:
+		if len(v) < 2 {
+			return errShort
+		}
+		info.PortVLAN = int(binary.BigEndian.Uint16(v))
+	case oui == oui8021 && subtype == dot1VLANName:
+		if len(v) < 3 || len(v) < 3+int(v[2]) {
+			return errShort
+		}
+		info.VLANs = append(info.VLANs, VLAN{ID: int(binary.BigEndian.Uint16(v)), Name: string(v[3 : 3+v[2]])})
+	case oui == oui8021 && subtype == dot1LinkAggregation, oui == oui8023 && subtype == dot3LinkAggregation:
+		if len(v) < 5 {
+			return errShort
+		}
+		info.LinkAggregation = &LinkAggregation{
+			Capable: v[0]&1 != 0,
+			Enabled: v[0]&2 != 0,
+			PortID:  int(binary.BigEndian.Uint32(v[1:])),
+		}
+	case oui == oui8023 && subtype == dot3MACPHY:
+		if len(v) < 5 {
+			return errShort
+		}
+		info.MACPHY = &MACPHY{
+			AutonegSupported: v[0]&1 != 0,
+			AutonegEnabled:   v[0]&2 != 0,
+			Advertised:       binary.BigEndian.Uint16(v[1:]),
+			MAUType:          int(binary.BigEndian.Uint16(v[3:])),
+		}
+	case oui == oui8023 && subtype == dot3MaxFrameSize:
+		if len(v) < 2 {
+			return errShort
+		}
+		info.MaxFrameSize = int(binary.BigEndian.Uint16(v))
+	}
+	return nil
+}
+
+// appendTLV appends a TLV of type typ with value v.
+func appendTLV(b []byte, typ byte, v ...[]byte) ([]byte, error) {
+	var l int
+	for _, p := range v {
+		l += len(p)
+	}
+	if l > maxTLVLen {
+		return nil, fmt.Errorf("TLV %d is %d bytes, longer than %d", typ, l, maxTLVLen)
+	}
+	b = binary.BigEndian.AppendUint16(b, uint16(typ)<<9|uint16(l))
+	for _, p := range v {
+		b = append(b, p...)
+	}
+	return b, nil
+}
+
+func u16(v int) []byte {
+	return binary.BigEndian.AppendUint16(nil, uint16(v))
+}
+
+func flags(bits ...bool) byte {
+	var f byte
+	for i, b := range bits {
+		if b {
+			f |= 1 << i
+		}
+	}
+	return f
+}
+
+// MarshalBinary returns info as an LLDPDU.
+func (info *Info) MarshalBinary() ([]byte, error) {
+	chassis, err := encodeID(info.ChassisID.Value, info.ChassisID.Subtype == ChassisMAC, info.ChassisID.Subtype == ChassisNetworkAddress)
+	if err != nil {
+		return nil, fmt.Errorf("chassis ID: %w", err)
+	}
+	port, err := encodeID(info.PortID.Value, info.PortID.Subtype == PortMAC, info.PortID.Subtype == PortNetworkAddress)
+	if err != nil {
+		return nil, fmt.Errorf("port ID: %w", err)
+	}
+	if len(chassis) == 0 || len(chassis) > 255 || len(port) == 0 || len(port) > 255 {
+		return nil, errors.New("chassis and port IDs must be 1 to 255 bytes")
+	}
+	if info.TTL < 0 || info.TTL > 0xffff {
+		return nil, fmt.Errorf("TTL %d out of range", info.TTL)
+	}
+
+	var b []byte
+	add := func(typ byte, v ...[]byte) {
+		if err == nil {
+			b, err = appendTLV(b, typ, v...)
+		}
+	}
+	add(tlvChassisID, []byte{byte(info.ChassisID.Subtype)}, chassis)
+	add(tlvPortID, []byte{byte(info.PortID.Subtype)}, port)
+	add(tlvTTL, u16(info.TTL))
+	org := func(oui [3]byte, subtype byte, v ...[]byte) {
+		add(tlvOrgSpecific, append([][]byte{oui[:], {subtype}}, v...)...)
+	}
+	if info.PortDescription != "" {
+		add(tlvPortDescription, []byte(info.PortDescription))
+	}
+	if info.SystemName != "" {
+		add(tlvSystemName, []byte(info.SystemName))
+	}
+	if info.SystemDescription != "" {
+		add(tlvSystemDescription, []byte(info.SystemDescription))
+	}
+	if info.Capabilities != 0 || info.EnabledCapabilities != 0 {
+		add(tlvSystemCapabilities, u16(int(info.Capabilities)), u16(int(info.EnabledCapabilities)))
+	}
+	for _, a := range info.ManagementAddresses {
+		addr := appendAddr(nil, a.Unmap())
+		// The interface is unknown, and there is no OID.
+		add(tlvManagementAddress, []byte{byte(len(addr))}, addr, []byte{1, 0, 0, 0, 0, 0})
+	}
+	if info.PortVLAN != 0 {
+		org(oui8021, dot1PortVLAN, u16(info.PortVLAN))
+	}
+	for _, v := range info.VLANs {
+		if len(v.Name) > 32 {
+			return nil, fmt.Errorf("VLAN name %q is longer than 32 bytes", v.Name)
+		}
+		org(oui8021, dot1VLANName, u16(v.ID), []byte{byte(len(v.Name))}, []byte(v.Name))
+	}
+	if la := info.LinkAggregation; la != nil {
+		org(oui8021, dot1LinkAggregation, []byte{flags(la.Capable, la.Enabled)}, binary.BigEndian.AppendUint32(nil, uint32(la.PortID)))
+	}
+	if m := info.MACPHY; m != nil {
+		org(oui8023, dot3MACPHY, []byte{flags(m.AutonegSupported, m.AutonegEnabled)}, u16(int(m.Advertised)), u16(m.MAUType))
+	}
+	if info.MaxFrameSize != 0 {
+		org(oui8023, dot3MaxFrameSize, u16(info.MaxFrameSize))
+	}
+	add(tlvEnd)
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// ParseFrame parses an Ethernet frame with an LLDPDU, returning its source
+// address.
+func ParseFrame(frame []byte) (net.HardwareAddr, *Info, error) {
+	if len(frame) < headerLen || binary.BigEndian.Uint16(frame[12:]) != EtherType {
+		return nil, nil, errors.New("not an LLDP frame")
+	}
+	info, err := Parse(frame[headerLen:])
+	if err != nil {
+		return nil, nil, err
+	}
+	return net.HardwareAddr(frame[6:12]), info, nil
+}
+
+// Frame returns info in an Ethernet frame from src to NearestBridge.
+func Frame(src net.HardwareAddr, info *Info) ([]byte, error) {
+	pdu, err := info.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	frame := make([]byte, 0, max(headerLen+len(pdu), minFrameLen))
+	frame = append(frame, NearestBridge...)
+	frame = append(frame, src...)
+	frame = binary.BigEndian.AppendUint16(frame, EtherType)
+	frame = append(frame, pdu...)
+	// Pad after the End TLV, where it is ignored.
+	return append(frame, make([]byte, cap(frame)-len(frame))...), nil
+}
@@ -0,0 +1,222 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lldp
+
+import (
+	"encoding/hex"
+	"errors"
+	"net"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+var cmpNetip = cmpopts.EquateComparable(netip.Addr{})
+
+// switchFrame is an LLDP frame as a switch sends it.
+var switchFrame = strings.Join([]string{
+	// Ethernet header.
+	"0180c200000e", "00259017a1c4", "88cc",
+	// Chassis ID: MAC 00:25:90:17:a1:c0.
+	"0207", "04", "00259017a1c0",
+	// Port ID: interface name Gi1/0/24.
+	"0409", "05", "4769312f302f3234",
+	// TTL: 120.
+	"0602", "0078",
+	// Port description: uplink.
+	"0806", "75706c696e6b",
+	// System name: sw1.
+	"0a03", "737731",
+	// System capabilities: bridge and router, bridge enabled.
+	"0e04", "0014", "0004",
+	// Management address: 10.0.0.2 on ifIndex 1.
+	"100c", "05", "010a000002", "02", "00000001", "00",
+	// 802.1 port VLAN ID: 10.
+	"fe06", "0080c2", "01", "000a",
+	// 802.1 VLAN name: 10 servers.
+	"fe0e", "0080c2", "03", "000a", "07", "73657276657273",
+	// 802.1 link aggregation: capable, enabled, port 5.
+	"fe09", "0080c2", "07", "03", "00000005",
+	// 802.3 MAC/PHY: autonegotiation supported and enabled, 1000BASE-T FD.
+	"fe09", "00120f", "01", "03", "6c01", "001e",
+	// 802.3 maximum frame size: 9216.
+	"fe06", "00120f", "04", "2400",
+	// An unknown organization.
+	"fe05", "aabbcc", "01", "ff",
+	// End.
+	"0000",
+}, "")
+
+func TestParseFrame(t *testing.T) {
+	frame, err := hex.DecodeString(switchFrame)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, info, err := ParseFrame(frame)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := src.String(), "00:25:90:17:a1:c4"; got != want {
+		t.Errorf("source = %s, want %s", got, want)
+	}
+	want := &Info{
+		ChassisID:           ChassisID{Subtype: ChassisMAC, Value: "00:25:90:17:a1:c0"},
+		PortID:              PortID{Subtype: PortInterfaceName, Value: "Gi1/0/24"},
+		TTL:                 120,
+		PortDescription:     "uplink",
+		SystemName:          "sw1",
+		Capabilities:        CapBridge | CapRouter,
+		EnabledCapabilities: CapBridge,
+		ManagementAddresses: []netip.Addr{netip.MustParseAddr("10.0.0.2")},
+		PortVLAN:            10,
+		VLANs:               []VLAN{{ID: 10, Name: "servers"}},
+		LinkAggregation:     &LinkAggregation{Capable: true, Enabled: true, PortID: 5},
+		MACPHY:              &MACPHY{AutonegSupported: true, AutonegEnabled: true, Advertised: 0x6c01, MAUType: 30},
+		MaxFrameSize:        9216,
+	}
+	if diff := cmp.Diff(want, info, cmpNetip); diff != "" {
+		t.Errorf("ParseFrame (-want +got):\n%s", diff)
+	}
+	if got, want := info.MACPHY.MAU(), "1000BaseTFD"; got != want {
+		t.Errorf("MAU() = %q, want %q", got, want)
+	}
+}
+
+func TestMarshalRoundTrip(t *testing.T) {
+	for _, info := range []*Info{
+		{
+			ChassisID: ChassisID{Subtype: ChassisLocal, Value: "host"},
+			PortID:    PortID{Subtype: PortMAC, Value: "02:00:00:00:00:01"},
+			TTL:       0,
+		},
+		{
+			ChassisID:           ChassisID{Subtype: ChassisNetworkAddress, Value: "2001:db8::1"},
+			PortID:              PortID{Subtype: PortNetworkAddress, Value: "192.0.2.1"},
+			TTL:                 65535,
+			PortDescription:     "eth0",
+			SystemName:          "node1",
+			SystemDescription:   "u-root",
+			Capabilities:        CapStation | CapRouter,
+			EnabledCapabilities: CapStation,
+			ManagementAddresses: []netip.Addr{netip.MustParseAddr("192.0.2.1"), netip.MustParseAddr("2001:db8::1")},
+			PortVLAN:            4094,
+			VLANs:               []VLAN{{ID: 1, Name: "default"}, {ID: 2, Name: ""}},
+			LinkAggregation:     &LinkAggregation{Capable: true, PortID: 1 << 31},
+			MACPHY:              &MACPHY{AutonegSupported: true, MAUType: 58},
+			MaxFrameSize:        1518,
+		},
+	} {
+		src := net.HardwareAddr{2, 0, 0, 0, 0, 1}
+		frame, err := Frame(src, info)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(frame) < minFrameLen {
+			t.Errorf("frame is %d bytes, shorter than %d", len(frame), minFrameLen)
+		}
+		gotSrc, got, err := ParseFrame(frame)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if gotSrc.String() != src.String() {
+			t.Errorf("source = %s, want %s", gotSrc, src)
+		}
+		if diff := cmp.Diff(info, got, cmpNetip); diff != "" {
+			t.Errorf("round trip (-want +got):\n%s", diff)
+		}
+	}
+}
+
+func TestMarshalErrors(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		info Info
+	}{
+		{name: "no chassis", info: Info{PortID: PortID{Subtype: PortLocal, Value: "1"}}},
+		{name: "bad MAC", info: Info{ChassisID: ChassisID{Subtype: ChassisMAC, Value: "x"}, PortID: PortID{Subtype: PortLocal, Value: "1"}}},
+		{name: "TTL", info: Info{ChassisID: ChassisID{Subtype: ChassisLocal, Value: "a"}, PortID: PortID{Subtype: PortLocal, Value: "1"}, TTL: 1 << 16}},
+		{name: "long name", info: Info{ChassisID: ChassisID{Subtype: ChassisLocal, Value: "a"}, PortID: PortID{Subtype: PortLocal, Value: "1"}, SystemName: strings.Repeat("x", 512)}},
+		{name: "long VLAN name", info: Info{ChassisID: ChassisID{Subtype: ChassisLocal, Value: "a"}, PortID: PortID{Subtype: PortLocal, Value: "1"}, VLANs: []VLAN{{ID: 1, Name: strings.Repeat("x", 33)}}}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.info.MarshalBinary(); err == nil {
+				t.Error("MarshalBinary succeeded, want error")
+			}
+		})
+	}
+}
+
+func TestParseMalformed(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		pdu  string
+	}{
+		{name: "empty", pdu: ""},
+		{name: "odd byte", pdu: "02"},
+		{name: "truncated", pdu: "0207040025"},
+		{name: "no port", pdu: "020704002590" + "17a1c0" + "0602" + "0078"},
+		{name: "no TTL", pdu: "0202" + "0761" + "0402" + "0731" + "0000"},
+		{name: "end first", pdu: "0000"},
+		{name: "short chassis", pdu: "0201" + "04" + "0402" + "0731" + "0602" + "0078"},
+		{name: "short TTL", pdu: "0202" + "0761" + "0402" + "0731" + "0601" + "00"},
+		{name: "short capabilities", pdu: "0202" + "0761" + "0402" + "0731" + "0602" + "0078" + "0e02" + "0014"},
+		{name: "management address length", pdu: "0202" + "0761" + "0402" + "0731" + "0602" + "0078" + "1002" + "0901"},
+		{name: "short org", pdu: "0202" + "0761" + "0402" + "0731" + "0602" + "0078" + "fe03" + "0080c2"},
+		{name: "short VLAN name", pdu: "0202" + "0761" + "0402" + "0731" + "0602" + "0078" + "fe08" + "0080c2" + "03" + "000a" + "0961"},
+		{name: "short link aggregation", pdu: "0202" + "0761" + "0402" + "0731" + "0602" + "0078" + "fe05" + "0080c2" + "07" + "03"},
+		{name: "short MAC/PHY", pdu: "0202" + "0761" + "0402" + "0731" + "0602" + "0078" + "fe06" + "00120f" + "01" + "03" + "6c"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := hex.DecodeString(tt.pdu)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := Parse(b); !errors.Is(err, errMalformed) {
+				t.Errorf("Parse = %v, want %v", err, errMalformed)
+			}
+		})
+	}
+}
+
+func TestParseFrameNotLLDP(t *testing.T) {
+	frame, err := hex.DecodeString(switchFrame)
+	if err != nil {
+		t.Fatal(err)
+	}
+	frame[12] = 0x08
+	if _, _, err := ParseFrame(frame); err == nil {
+		t.Error("ParseFrame of an IPv4 frame succeeded")
+	}
+	if _, _, err := ParseFrame(frame[:10]); err == nil {
+		t.Error("ParseFrame of a short frame succeeded")
+	}
+}
+
+func TestIDs(t *testing.T) {
+	for _, tt := range []struct {
+		id   string
+		want string
+	}{
+		{id: ChassisID{Subtype: ChassisMAC, Value: "00:25:90:17:a1:c0"}.String(), want: "mac 00:25:90:17:a1:c0"},
+		{id: PortID{Subtype: PortInterfaceName, Value: "eth0"}.String(), want: "ifname eth0"},
+		{id: PortID{Subtype: 42, Value: "x"}.String(), want: "subtype42 x"},
+	} {
+		if tt.id != tt.want {
+			t.Errorf("ID = %q, want %q", tt.id, tt.want)
+		}
+	}
+
+	// Binary IDs are shown in hex.
+	info, err := Parse([]byte{2, 4, byte(ChassisLocal), 0, 1, 0xff, 4, 2, byte(PortLocal), '1', 6, 2, 0, 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := info.ChassisID.Value, "0001ff"; got != want {
+		t.Errorf("chassis ID = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,24 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !race
+
+package lldp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hugelgupf/vmtest/govmtest"
+	"github.com/hugelgupf/vmtest/qemu"
+)
+
+func TestVM(t *testing.T) {
+	qemu.SkipIfNotArch(t, qemu.ArchAMD64)
+
+	govmtest.Run(t, "vm",
+		govmtest.WithPackageToTest("github.com/u-root/u-root/pkg/lldp"),
+		govmtest.WithQEMUFn(qemu.WithVMTimeout(2*time.Minute)),
+	)
+}
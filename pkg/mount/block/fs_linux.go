@@ -0,0 +1,58 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package block
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+
+	"github.com/u-root/u-root/pkg/fs/ext4"
+	"github.com/u-root/u-root/pkg/fs/fat"
+	"github.com/u-root/u-root/pkg/mount"
+)
+
+var _ mount.FSOpener = &BlockDev{}
+
+// ErrNoUserspaceFS is returned by OpenFS if r holds no file system that
+// can be read in userspace.
+var ErrNoUserspaceFS = errors.New("no ext2/3/4 or FAT file system")
+
+// OpenFS returns a read-only view of the ext2/3/4 or FAT file system on r,
+// read in userspace, and its type as used by mount(2).
+func OpenFS(r io.ReaderAt) (fs.FS, string, error) {
+	e, err := ext4.New(r)
+	if err == nil {
+		return e, e.Type(), nil
+	}
+	if !errors.Is(err, ext4.ErrBadMagic) {
+		return nil, "", err
+	}
+	f, err := fat.New(r)
+	if err == nil {
+		return f, "vfat", nil
+	}
+	if !errors.Is(err, fat.ErrNotFAT) {
+		return nil, "", err
+	}
+	return nil, "", ErrNoUserspaceFS
+}
+
+// WithFS implements mount.FSOpener. It reads the file system on the device
+// in userspace.
+func (b *BlockDev) WithFS(fn func(fsys fs.FS, fsType string) error) error {
+	f, err := os.Open(b.DevicePath())
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fsys, fsType, err := OpenFS(f)
+	if err != nil {
+		return fmt.Errorf("%s: %w", b.DevicePath(), err)
+	}
+	return fn(fsys, fsType)
+}
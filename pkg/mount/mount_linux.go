@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"unsafe"
 
 	"golang.org/x/sys/unix"
 )
@@ -50,6 +51,9 @@ type MountPoint struct {
 	FSType string
 	Flags  uintptr
 	Data   string
+	// Userspace is set if the file system was not mounted, but read
+	// in userspace and partially copied to Path. See Pool.Mount.
+	Userspace bool
 }
 
 // String implements fmt.Stringer.
@@ -57,8 +61,12 @@ func (mp *MountPoint) String() string {
 	return fmt.Sprintf("MountPoint(path=%s, device=%s, fs=%s, flags=%#x, data=%s)", mp.Path, mp.Device, mp.FSType, mp.Flags, mp.Data)
 }
 
-// Unmount unmounts a file system that was previously mounted.
+// Unmount unmounts a file system that was previously mounted. For file
+// systems read in userspace, the copy is removed.
 func (mp *MountPoint) Unmount(flags uintptr) error {
+	if mp.Userspace {
+		return os.RemoveAll(mp.Path)
+	}
 	if err := unix.Unmount(mp.Path, int(flags)); err != nil {
 		return &os.PathError{
 			Op:   "unmount",
@@ -103,6 +111,48 @@ func Mount(dev, path, fsType, data string, flags uintptr, opts ...func() error)
 	}, nil
 }
 
+// MountData is Mount with binary data, such as the struct nfs_mount_data
+// of NFS, which the data string of Mount cannot hold.
+func MountData(dev, path, fsType string, data []byte, flags uintptr) (*MountPoint, error) {
+	err := mountData(dev, path, fsType, data, flags)
+	if err != nil {
+		return nil, &os.PathError{
+			Op:   "mount",
+			Path: path,
+			Err:  fmt.Errorf("from device %q (fs type %s, flags %#x): %w", dev, fsType, flags, err),
+		}
+	}
+	return &MountPoint{
+		Path:   path,
+		Device: dev,
+		FSType: fsType,
+		Flags:  flags,
+	}, nil
+}
+
+func mountData(dev, path, fsType string, data []byte, flags uintptr) error {
+	d, err := unix.BytePtrFromString(dev)
+	if err != nil {
+		return err
+	}
+	p, err := unix.BytePtrFromString(path)
+	if err != nil {
+		return err
+	}
+	t, err := unix.BytePtrFromString(fsType)
+	if err != nil {
+		return err
+	}
+	var b *byte
+	if len(data) > 0 {
+		b = &data[0]
+	}
+	if _, _, errno := unix.Syscall6(unix.SYS_MOUNT, uintptr(unsafe.Pointer(d)), uintptr(unsafe.Pointer(p)), uintptr(unsafe.Pointer(t)), flags, uintptr(unsafe.Pointer(b)), 0); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
 // TryMount tries to mount a device on the given mountpoint, trying in order
 // the supported block device file systems on the system.
 func TryMount(device, path, data string, flags uintptr, opts ...func() error) (*MountPoint, error) {
@@ -161,6 +211,11 @@ type Pool struct {
 // Mount mounts a file system using Mounter and returns the MountPoint. If the
 // device has already been mounted, it is not mounted again.
 //
+// If the kernel cannot mount a read-only file system, e.g. because the
+// driver is not built in, and mounter is an FSOpener, the file system is
+// read in userspace instead and the files needed to boot, see
+// UserspaceDirs, are copied to the mount point, up to UserspaceMaxSize.
+//
 // Note the pool is keyed on Mounter.DevName() alone meaning DevName is used to
 // determine whether it has already been mounted.
 func (p *Pool) Mount(mounter Mounter, flags uintptr) (*MountPoint, error) {
@@ -182,6 +237,17 @@ func (p *Pool) Mount(mounter Mounter, flags uintptr) (*MountPoint, error) {
 	path := filepath.Join(p.tmpDir, mounter.DevName())
 	os.MkdirAll(path, 0o777)
 	m, err := mounter.Mount(path, flags)
+	if o, ok := mounter.(FSOpener); ok && err != nil && flags&MS_RDONLY != 0 {
+		um, uerr := mountUserspace(o, path)
+		if uerr == nil {
+			p.MountPoints = append(p.MountPoints, um)
+			return um, nil
+		}
+		// Nothing was mounted on path, so it only holds what was
+		// copied so far.
+		os.RemoveAll(path)
+		return nil, fmt.Errorf("%w; %w", err, uerr)
+	}
 	if err != nil {
 		// unix.Rmdir is used (instead of os.RemoveAll) because it
 		// fails when the directory is non-empty. It would be a bit
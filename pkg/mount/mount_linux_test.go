@@ -7,11 +7,13 @@ package mount_test
 import (
 	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
+	"testing/fstest"
 
 	"github.com/hugelgupf/vmtest/guest"
 	"github.com/u-root/u-root/pkg/mount"
@@ -143,6 +145,104 @@ func TestMountPool(t *testing.T) {
 	}
 }
 
+// fakeFSOpener cannot be mounted, but can be read in userspace.
+type fakeFSOpener struct {
+	fakeMounter
+	fsys fs.FS
+}
+
+func (m *fakeFSOpener) Mount(path string, flags uintptr, opts ...func() error) (*mount.MountPoint, error) {
+	return nil, errors.New("unknown filesystem type")
+}
+
+func (m *fakeFSOpener) WithFS(fn func(fs.FS, string) error) error {
+	return fn(m.fsys, "vfat")
+}
+
+func TestMountPoolUserspace(t *testing.T) {
+	big := make([]byte, 100)
+	defer func(n int64) { mount.UserspaceMaxFileSize = n }(mount.UserspaceMaxFileSize)
+	mount.UserspaceMaxFileSize = int64(len(big) - 1)
+	dev := &fakeFSOpener{
+		fakeMounter: fakeMounter{name: "sdz1"},
+		fsys: fstest.MapFS{
+			"vmlinuz":              {Data: []byte("kernel"), Mode: 0o644},
+			"vmlinuz.old":          {Data: []byte("boot/vmlinuz-1"), Mode: fs.ModeSymlink},
+			"EFI/BOOT/grub.cfg":    {Data: []byte("menuentry"), Mode: 0o644},
+			"boot/vmlinuz-1":       {Data: []byte("kernel 1"), Mode: 0o755},
+			"boot/disk.iso":        {Data: big, Mode: 0o644},
+			"home/user/secret.txt": {Data: []byte("not needed to boot")},
+		},
+	}
+	mp := &mount.Pool{}
+	if _, err := mp.Mount(dev, 0); err == nil {
+		t.Fatalf("read-write mount of %s succeeded, want an error", dev.name)
+	}
+	m, err := mp.Mount(dev, mount.ReadOnly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !m.Userspace || m.FSType != "vfat" || m.Device != "/dev/sdz1" {
+		t.Errorf("mount point %v, want a userspace vfat mount of /dev/sdz1", m)
+	}
+	for name, want := range map[string]string{
+		"vmlinuz":           "kernel",
+		"vmlinuz.old":       "kernel 1",
+		"EFI/BOOT/grub.cfg": "menuentry",
+		"boot/vmlinuz-1":    "kernel 1",
+	} {
+		if got, err := os.ReadFile(filepath.Join(m.Path, name)); err != nil || string(got) != want {
+			t.Errorf("%s: got %q, %v, want %q", name, got, err, want)
+		}
+	}
+	for _, name := range []string{"boot/disk.iso", "home"} {
+		if _, err := os.Stat(filepath.Join(m.Path, name)); !os.IsNotExist(err) {
+			t.Errorf("%s was copied", name)
+		}
+	}
+	if fi, err := os.Stat(filepath.Join(m.Path, "boot/vmlinuz-1")); err != nil || fi.Mode().Perm() != 0o755 {
+		t.Errorf("boot/vmlinuz-1: %v, %v, want mode 0755", fi, err)
+	}
+	if m2, err := mp.Mount(dev, mount.ReadOnly); err != nil || m2 != m {
+		t.Errorf("remounting: got %v, %v, want %v", m2, err, m)
+	}
+
+	if err := mp.UnmountAll(0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(m.Path); !os.IsNotExist(err) {
+		t.Errorf("%s still exists after UnmountAll: %v", m.Path, err)
+	}
+}
+
+func TestMountPoolUserspaceMaxSize(t *testing.T) {
+	defer func(n int64) { mount.UserspaceMaxSize = n }(mount.UserspaceMaxSize)
+	mount.UserspaceMaxSize = 15
+	dev := &fakeFSOpener{
+		fakeMounter: fakeMounter{name: "sdz1"},
+		fsys: fstest.MapFS{
+			"boot/grub.cfg":  {Data: []byte("menu"), Mode: 0o644},
+			"boot/vmlinuz-1": {Data: []byte("kernel 1"), Mode: 0o644},
+			"boot/vmlinuz-2": {Data: []byte("kernel 2"), Mode: 0o644},
+		},
+	}
+	mp := &mount.Pool{}
+	defer mp.UnmountAll(0)
+	m, err := mp.Mount(dev, mount.ReadOnly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]bool{
+		"boot/grub.cfg":  true,
+		"boot/vmlinuz-1": true,
+		"boot/vmlinuz-2": false,
+	} {
+		if _, err := os.Stat(filepath.Join(m.Path, name)); (err == nil) != want {
+			t.Errorf("%s: copied is %v, want %v", name, err == nil, want)
+		}
+	}
+}
+
 func TestIsTmpRamfs(t *testing.T) {
 	guest.SkipIfNotInVM(t)
 
@@ -9,6 +9,7 @@ import (
 	"encoding/binary"
 	"fmt"
 	"strings"
+	"time"
 )
 
 // direction is the transfer direction.
@@ -86,6 +87,56 @@ type (
 	statusBlock [maxStatusBlockLen]byte
 )
 
+// ataRegisters are the ATA output registers, returned by the SCSI/ATA
+// Translation layer in an ATA Status Return sense descriptor when a command
+// is issued with a check condition.
+type ataRegisters struct {
+	err    uint8
+	count  uint16
+	lba    uint64
+	device uint8
+	status uint8
+}
+
+const (
+	ataStatusErr uint8 = 1 << 0
+	ataStatusDF  uint8 = 1 << 5
+
+	senseDescriptorFormat = 0x72
+	ataReturnDescriptor   = 0x09
+)
+
+// failed returns true if the drive reported an error or device fault.
+func (r *ataRegisters) failed() bool {
+	return r.status&(ataStatusErr|ataStatusDF) != 0
+}
+
+// ataReturn finds the ATA Status Return descriptor in descriptor format
+// sense data.
+func (sb statusBlock) ataReturn() (*ataRegisters, bool) {
+	if sb[0]&0x7f != senseDescriptorFormat {
+		return nil, false
+	}
+	end := min(8+int(sb[7]), len(sb))
+	for i := 8; i+1 < end; i += 2 + int(sb[i+1]) {
+		d := sb[i:]
+		if d[0] != ataReturnDescriptor {
+			continue
+		}
+		if i+14 > len(sb) {
+			return nil, false
+		}
+		return &ataRegisters{
+			err:    d[3],
+			count:  uint16(d[4])<<8 | uint16(d[5]),
+			lba:    uint64(d[10])<<40 | uint64(d[8])<<32 | uint64(d[6])<<24 | uint64(d[11])<<16 | uint64(d[9])<<8 | uint64(d[7]),
+			device: d[12],
+			status: d[13],
+		}, true
+	}
+	return nil, false
+}
+
 func (b dataBlock) toWordBlock() (wordBlock, error) {
 	var w wordBlock
 	err := binary.Read(bytes.NewBuffer(b[:]), binary.BigEndian, &w)
@@ -152,5 +203,24 @@ func unpackIdentify(s statusBlock, d dataBlock, w wordBlock) *Info {
 	info.SecurityStatus = DiskSecurityStatus(binary.LittleEndian.Uint16(d[256:258]))
 
 	info.TrustedComputingSupport = w[48]
+
+	word := func(n int) uint16 {
+		return binary.LittleEndian.Uint16(d[2*n:])
+	}
+	info.SMARTSupported = word(82)&1 != 0
+	info.SMARTEnabled = word(85)&1 != 0
+	info.SanitizeCapabilities = SanitizeCapabilities(word(59) & 0xf000)
+	info.SecurityEraseTime = eraseTime(word(89))
+	info.EnhancedSecurityEraseTime = eraseTime(word(90))
 	return &info
 }
+
+// eraseTime decodes the time required by a SECURITY ERASE UNIT command
+// from IDENTIFY word 89 or 90.
+func eraseTime(w uint16) time.Duration {
+	if w&0x8000 != 0 {
+		// Extended format: bits 14:0 are units of two minutes.
+		return time.Duration(w&0x7fff) * 2 * time.Minute
+	}
+	return time.Duration(w&0xff) * 2 * time.Minute
+}
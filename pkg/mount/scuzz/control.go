@@ -15,21 +15,23 @@ import (
 const DefaultTimeout time.Duration = 15 * time.Second
 
 const (
-	securitySupported    DiskSecurityStatus = 0x1
-	securityEnabled      DiskSecurityStatus = 0x2
-	securityLocked       DiskSecurityStatus = 0x4
-	securityFrozen       DiskSecurityStatus = 0x8
-	securityCountExpired DiskSecurityStatus = 0x10
-	securityLevelMax     DiskSecurityStatus = 0x100
+	securitySupported     DiskSecurityStatus = 0x1
+	securityEnabled       DiskSecurityStatus = 0x2
+	securityLocked        DiskSecurityStatus = 0x4
+	securityFrozen        DiskSecurityStatus = 0x8
+	securityCountExpired  DiskSecurityStatus = 0x10
+	securityEnhancedErase DiskSecurityStatus = 0x20
+	securityLevelMax      DiskSecurityStatus = 0x100
 )
 
 var securityStatusStrings = map[DiskSecurityStatus]string{
-	securitySupported:    "SUPPORTED",
-	securityEnabled:      "ENABLED",
-	securityLocked:       "LOCKED",
-	securityFrozen:       "FROZEN",
-	securityCountExpired: "COUNT EXPIRED",
-	securityLevelMax:     "LEVEL MAX",
+	securitySupported:     "SUPPORTED",
+	securityEnabled:       "ENABLED",
+	securityLocked:        "LOCKED",
+	securityFrozen:        "FROZEN",
+	securityCountExpired:  "COUNT EXPIRED",
+	securityEnhancedErase: "ENHANCED ERASE",
+	securityLevelMax:      "LEVEL MAX",
 }
 
 // Info is information about a SCSI disk device.
@@ -40,6 +42,16 @@ type Info struct {
 	SecurityStatus          DiskSecurityStatus
 	TrustedComputingSupport uint16
 
+	SMARTSupported bool
+	SMARTEnabled   bool
+
+	SanitizeCapabilities SanitizeCapabilities
+
+	// SecurityEraseTime and EnhancedSecurityEraseTime are the drive's
+	// estimates for SECURITY ERASE UNIT, or zero if not given.
+	SecurityEraseTime         time.Duration
+	EnhancedSecurityEraseTime time.Duration
+
 	Serial           string
 	Model            string
 	FirmwareRevision string
@@ -61,6 +73,33 @@ type Disk interface {
 
 	// Identify returns drive identity information
 	Identify() (*Info, error)
+
+	// SMART returns the SMART attributes, thresholds and overall health.
+	SMART() (*SMARTInfo, error)
+
+	// SetPassword sets the user or admin (master) security password.
+	// For the user password, level selects high or maximum security.
+	SetPassword(password string, admin bool, level SecurityLevel) error
+
+	// DisablePassword removes the user password, disabling security.
+	DisablePassword(password string, admin bool) error
+
+	// SecurityErase erases the drive with SECURITY ERASE UNIT. The
+	// user password must have been set. enhanced selects the enhanced
+	// erase mode, which also erases reallocated sectors.
+	SecurityErase(password string, admin bool, enhanced bool) error
+
+	// Sanitize starts a SANITIZE DEVICE operation.
+	Sanitize(SanitizeOptions) error
+
+	// SanitizeStatus returns the progress of a sanitize operation.
+	SanitizeStatus() (*SanitizeStatus, error)
+
+	// Inquiry returns the SCSI INQUIRY data.
+	Inquiry() (*InquiryData, error)
+
+	// ReadCapacity returns the SCSI READ CAPACITY(16) data.
+	ReadCapacity() (*Capacity, error)
 }
 
 // DiskSecurityStatus is information about how the disk is secured.
@@ -87,6 +126,12 @@ func (d DiskSecurityStatus) SecurityFrozen() bool {
 	return (d & securityFrozen) != 0
 }
 
+// EnhancedEraseSupported returns true if the disk supports the enhanced
+// mode of SECURITY ERASE UNIT.
+func (d DiskSecurityStatus) EnhancedEraseSupported() bool {
+	return (d & securityEnhancedErase) != 0
+}
+
 // SecurityCountExpired returns true if all attempts to unlock the disk have
 // been used up.
 func (d DiskSecurityStatus) SecurityCountExpired() bool {
@@ -10,6 +10,8 @@
 //
 // This package only supports post-2003 48-bit lba addressing.
 // Further, we only concern ourselves with ATA_16.
+// The native SCSI INQUIRY and READ CAPACITY(16) commands are also
+// supported, so that SAS disks can be identified.
 // For now it only works on Linux.
 //
 // Other info:
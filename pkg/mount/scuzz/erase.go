@@ -0,0 +1,193 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scuzz
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// SecurityLevel is the security level set along with a user password.
+type SecurityLevel uint8
+
+const (
+	// SecurityHigh allows the master password to unlock the drive.
+	SecurityHigh SecurityLevel = 0
+	// SecurityMaximum only allows the master password to erase the drive.
+	SecurityMaximum SecurityLevel = 1
+)
+
+// maxPasswordLen is the size of an ATA security password.
+const maxPasswordLen = 32
+
+// DefaultEraseTimeout is used for SECURITY ERASE UNIT when the drive does
+// not report how long an erase takes.
+const DefaultEraseTimeout = 12 * time.Hour
+
+// The SANITIZE DEVICE command and its feature register values.
+const (
+	cmdSanitize Cmd = 0xb4
+
+	sanitizeStatusExt     = 0x0000
+	sanitizeCryptoExt     = 0x0011
+	sanitizeBlockEraseExt = 0x0012
+	sanitizeOverwriteExt  = 0x0014
+
+	// Each sanitize command requires a signature in the LBA field.
+	sanitizeCryptoSig    = 0x43727970   // "Cryp"
+	sanitizeBlockSig     = 0x426b4572   // "BkEr"
+	sanitizeOverwriteSig = 0x4f57 << 32 // "OW"
+)
+
+// SanitizeCapabilities are the sanitize operations a drive supports,
+// from IDENTIFY word 59.
+type SanitizeCapabilities uint16
+
+// Supported returns true if the drive implements the Sanitize feature set.
+func (c SanitizeCapabilities) Supported() bool {
+	return c&(1<<12) != 0
+}
+
+// CryptoScramble returns true if CRYPTO SCRAMBLE EXT is supported.
+func (c SanitizeCapabilities) CryptoScramble() bool {
+	return c&(1<<13) != 0
+}
+
+// Overwrite returns true if OVERWRITE EXT is supported.
+func (c SanitizeCapabilities) Overwrite() bool {
+	return c&(1<<14) != 0
+}
+
+// BlockErase returns true if BLOCK ERASE EXT is supported.
+func (c SanitizeCapabilities) BlockErase() bool {
+	return c&(1<<15) != 0
+}
+
+// MarshalText lists the supported sanitize operations.
+func (c SanitizeCapabilities) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+func (c SanitizeCapabilities) String() string {
+	if !c.Supported() {
+		return "not supported"
+	}
+	s := "supported:"
+	if c.CryptoScramble() {
+		s += " crypto"
+	}
+	if c.BlockErase() {
+		s += " block"
+	}
+	if c.Overwrite() {
+		s += " overwrite"
+	}
+	return s
+}
+
+// SanitizeAction is the sanitize operation to perform.
+type SanitizeAction uint8
+
+const (
+	// SanitizeCryptoScramble changes the internal encryption keys.
+	SanitizeCryptoScramble SanitizeAction = iota + 1
+	// SanitizeBlockErase erases all user data blocks.
+	SanitizeBlockErase
+	// SanitizeOverwrite overwrites all user data with a pattern.
+	SanitizeOverwrite
+)
+
+func (a SanitizeAction) String() string {
+	switch a {
+	case SanitizeCryptoScramble:
+		return "crypto"
+	case SanitizeBlockErase:
+		return "block"
+	case SanitizeOverwrite:
+		return "overwrite"
+	}
+	return fmt.Sprintf("SanitizeAction(%d)", uint8(a))
+}
+
+// SanitizeOptions are the parameters of a sanitize operation.
+type SanitizeOptions struct {
+	Action SanitizeAction
+	// AllowUnrestrictedExit sets the FAILURE MODE bit, which allows
+	// the drive to leave a failed sanitize without another sanitize.
+	AllowUnrestrictedExit bool
+	// OverwritePasses is 1 to 16; zero means 16.
+	OverwritePasses uint8
+	// OverwriteInvert inverts the pattern between passes.
+	OverwriteInvert bool
+	// Pattern is the 32-bit overwrite pattern.
+	Pattern uint32
+}
+
+// SanitizeStatus is the status reported by SANITIZE STATUS EXT.
+type SanitizeStatus struct {
+	// Completed is set once a sanitize operation completed successfully.
+	Completed  bool
+	InProgress bool
+	// Frozen means sanitize commands are blocked by SANITIZE FREEZE LOCK.
+	Frozen bool
+	// Progress is the numerator of Progress/65536 of an operation in
+	// progress.
+	Progress uint16
+}
+
+// Percent returns the progress of the sanitize operation in percent.
+func (s *SanitizeStatus) Percent() float64 {
+	if !s.InProgress {
+		return 100
+	}
+	return float64(s.Progress) * 100 / 65536
+}
+
+func (s *SanitizeStatus) String() string {
+	switch {
+	case s.InProgress:
+		return fmt.Sprintf("in progress, %.1f%%", s.Percent())
+	case s.Completed:
+		return "completed"
+	case s.Frozen:
+		return "frozen"
+	}
+	return "idle"
+}
+
+func unpackSanitizeStatus(r *ataRegisters) *SanitizeStatus {
+	return &SanitizeStatus{
+		Completed:  r.count&(1<<15) != 0,
+		InProgress: r.count&(1<<14) != 0,
+		Frozen:     r.count&(1<<13) != 0,
+		Progress:   uint16(r.lba),
+	}
+}
+
+// WaitSanitize polls d every interval until the sanitize operation in
+// progress finishes or ctx is done. If progress is not nil it is called
+// with each status read.
+func WaitSanitize(ctx context.Context, d Disk, interval time.Duration, progress func(*SanitizeStatus)) (*SanitizeStatus, error) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		s, err := d.SanitizeStatus()
+		if err != nil {
+			return nil, err
+		}
+		if progress != nil {
+			progress(s)
+		}
+		if !s.InProgress {
+			return s, nil
+		}
+		select {
+		case <-ctx.Done():
+			return s, ctx.Err()
+		case <-t.C:
+		}
+	}
+}
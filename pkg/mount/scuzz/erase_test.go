@@ -0,0 +1,71 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scuzz
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// sanitizingDisk reports a sanitize in progress for a number of polls.
+type sanitizingDisk struct {
+	Disk
+	polls int
+}
+
+func (d *sanitizingDisk) SanitizeStatus() (*SanitizeStatus, error) {
+	if d.polls == 0 {
+		return &SanitizeStatus{Completed: true}, nil
+	}
+	d.polls--
+	return &SanitizeStatus{InProgress: true, Progress: 0x4000}, nil
+}
+
+func TestWaitSanitize(t *testing.T) {
+	d := &sanitizingDisk{polls: 3}
+	var seen int
+	s, err := WaitSanitize(context.Background(), d, time.Millisecond, func(*SanitizeStatus) { seen++ })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.Completed || seen != 4 {
+		t.Errorf("WaitSanitize: got %v after %d polls, want completed after 4", s, seen)
+	}
+
+	d.polls = 1
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := WaitSanitize(ctx, d, time.Hour, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("WaitSanitize with canceled context: got %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestEraseTime(t *testing.T) {
+	for _, tt := range []struct {
+		w    uint16
+		want time.Duration
+	}{
+		{0, 0},
+		{30, time.Hour},
+		{0x8000 | 300, 10 * time.Hour},
+		{0x0130, 96 * time.Minute}, // bits 14:8 are reserved in the short format
+	} {
+		if got := eraseTime(tt.w); got != tt.want {
+			t.Errorf("eraseTime(%#x): got %v, want %v", tt.w, got, tt.want)
+		}
+	}
+}
+
+func TestSanitizeCapabilities(t *testing.T) {
+	c := SanitizeCapabilities(1<<12 | 1<<13 | 1<<15)
+	if got, want := c.String(), "supported: crypto block"; got != want {
+		t.Errorf("String(): got %q, want %q", got, want)
+	}
+	if SanitizeCapabilities(1 << 13).Supported() {
+		t.Errorf("capabilities without bit 12 are supported")
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scuzz
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// SCSI operation codes.
+const (
+	scsiInquiry         = 0x12
+	scsiServiceActionIn = 0x9e
+	scsiReadCapacity16  = 0x10 // service action of scsiServiceActionIn
+
+	inquiryLen        = 96
+	readCapacity16Len = 32
+
+	vpdUnitSerial = 0x80
+)
+
+// InquiryData is the standard SCSI INQUIRY data, plus the unit serial
+// number if the device provides one.
+type InquiryData struct {
+	PeripheralType uint8
+	Removable      bool
+	Version        uint8
+	Vendor         string
+	Product        string
+	Revision       string
+	Serial         string
+}
+
+// String prints the INQUIRY data as indented JSON.
+func (i *InquiryData) String() string {
+	s, err := json.MarshalIndent(i, "", "\t")
+	if err != nil {
+		return fmt.Sprintf("%v", err)
+	}
+	return string(s)
+}
+
+// Capacity is the data returned by READ CAPACITY(16).
+type Capacity struct {
+	// LastLBA is the address of the last logical block.
+	LastLBA   uint64
+	BlockSize uint32
+}
+
+// Blocks returns the number of logical blocks.
+func (c *Capacity) Blocks() uint64 {
+	return c.LastLBA + 1
+}
+
+// Bytes returns the capacity in bytes.
+func (c *Capacity) Bytes() uint64 {
+	return c.Blocks() * uint64(c.BlockSize)
+}
+
+func (c *Capacity) String() string {
+	return fmt.Sprintf("%d blocks of %d bytes (%d bytes)", c.Blocks(), c.BlockSize, c.Bytes())
+}
+
+func scsiString(b []byte) string {
+	return strings.TrimSpace(strings.TrimRight(string(b), "\x00"))
+}
+
+func unpackInquiry(b dataBlock) *InquiryData {
+	return &InquiryData{
+		PeripheralType: b[0] & 0x1f,
+		Removable:      b[1]&0x80 != 0,
+		Version:        b[2],
+		Vendor:         scsiString(b[8:16]),
+		Product:        scsiString(b[16:32]),
+		Revision:       scsiString(b[32:36]),
+	}
+}
+
+func unpackUnitSerial(b dataBlock) (string, error) {
+	if b[1] != vpdUnitSerial {
+		return "", fmt.Errorf("got VPD page %#02x, want %#02x", b[1], vpdUnitSerial)
+	}
+	n := min(int(b[3]), len(b)-4)
+	return scsiString(b[4 : 4+n]), nil
+}
+
+func unpackReadCapacity16(b dataBlock) *Capacity {
+	return &Capacity{
+		LastLBA:   binary.BigEndian.Uint64(b[0:8]),
+		BlockSize: binary.BigEndian.Uint32(b[8:12]),
+	}
+}
@@ -0,0 +1,42 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scuzz
+
+import (
+	"testing"
+)
+
+func TestUnpackInquiry(t *testing.T) {
+	var b dataBlock
+	b[0] = 0x00
+	b[2] = 0x06
+	copy(b[8:], "SEAGATE ST4000NM0023    0004")
+	i := unpackInquiry(b)
+	if i.Vendor != "SEAGATE" || i.Product != "ST4000NM0023" || i.Revision != "0004" || i.Version != 6 || i.Removable {
+		t.Errorf("unpackInquiry: got %+v", i)
+	}
+
+	var vpd dataBlock
+	vpd[1] = vpdUnitSerial
+	vpd[3] = 12
+	copy(vpd[4:], "  Z1Z0ABCD  ")
+	s, err := unpackUnitSerial(vpd)
+	if err != nil || s != "Z1Z0ABCD" {
+		t.Errorf("unpackUnitSerial: got %q, %v, want Z1Z0ABCD, nil", s, err)
+	}
+	vpd[1] = 0x83
+	if _, err := unpackUnitSerial(vpd); err == nil {
+		t.Errorf("unpackUnitSerial of page 0x83: got nil, want error")
+	}
+}
+
+func TestUnpackReadCapacity16(t *testing.T) {
+	var b dataBlock
+	copy(b[:], []byte{0, 0, 0, 0, 0x1d, 0x1c, 0x59, 0x6f, 0, 0, 0x02, 0x00})
+	c := unpackReadCapacity16(b)
+	if c.Blocks() != 0x1d1c5970 || c.BlockSize != 512 || c.Bytes() != 0x1d1c5970*512 {
+		t.Errorf("unpackReadCapacity16: got %v", c)
+	}
+}
@@ -47,12 +47,12 @@ import (
 // cdb[ 4] = feat
 // cdb[ 5] = hob_nsect
 // cdb[ 6] = nsect
-// cdb[ 7] = hob_lbal
-// cdb[ 8] = lbal
-// cdb[ 9] = hob_lbam
-// cdb[10] = lbam
-// cdb[11] = hob_lbah
-// cdb[12] = lbah
+// cdb[ 7] = hob_lbal (lba 31:24)
+// cdb[ 8] = lbal     (lba 7:0)
+// cdb[ 9] = hob_lbam (lba 39:32)
+// cdb[10] = lbam     (lba 15:8)
+// cdb[11] = hob_lbah (lba 47:40)
+// cdb[12] = lbah     (lba 23:16)
 // cdb[13] = device
 // cdb[14] = command
 // Further, there is a direction which can be to, from, or none.
@@ -109,6 +109,9 @@ type packet struct {
 	nsect    uint16
 	dma      bool
 
+	// regs holds the ATA output registers, if the drive returned them.
+	regs *ataRegisters
+
 	// There are pointers in the packetHeader to this data.
 	//
 	// We maintain them here to ensure they don't
@@ -147,14 +150,35 @@ func NewSGDisk(n string, opt ...SGDiskOpt) (*SGDisk, error) {
 	return NewSGDiskFromFile(f, opt...)
 }
 
+// NewSGDiskFromFile returns a Disk using an already open SCSI Generic
+// capable file. Like NewSGDisk, it verifies the device with an Identify.
 func NewSGDiskFromFile(f diskFile, opt ...SGDiskOpt) (*SGDisk, error) {
 	s := &SGDisk{f: f, Timeout: DefaultTimeout}
+	for _, o := range opt {
+		o(s)
+	}
 	if _, err := s.Identify(); err != nil {
 		return nil, err
 	}
+	return s, nil
+}
+
+// NewSCSIDisk returns a Disk for a device that may not understand ATA
+// commands, such as a SAS disk. Instead of an ATA Identify it verifies
+// the device with a SCSI INQUIRY. ATA operations on such a disk fail.
+func NewSCSIDisk(n string, opt ...SGDiskOpt) (*SGDisk, error) {
+	f, err := os.OpenFile(n, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	s := &SGDisk{f: f, Timeout: DefaultTimeout}
 	for _, o := range opt {
 		o(s)
 	}
+	if _, err := s.Inquiry(); err != nil {
+		f.Close()
+		return nil, err
+	}
 	return s, nil
 }
 
@@ -198,12 +222,12 @@ func (p *packet) genCommandDataBlock() {
 	p.command[4] = uint8(p.features)
 	p.command[5] = uint8(p.nsect >> 8)
 	p.command[6] = uint8(p.nsect)
-	p.command[7] = uint8(p.lba >> 8)
+	p.command[7] = uint8(p.lba >> 24)
 	p.command[8] = uint8(p.lba)
-	p.command[9] = uint8(p.lba >> 24)
-	p.command[10] = uint8(p.lba >> 16)
+	p.command[9] = uint8(p.lba >> 32)
+	p.command[10] = uint8(p.lba >> 8)
 	p.command[11] = uint8(p.lba >> 40)
-	p.command[12] = uint8(p.lba >> 32)
+	p.command[12] = uint8(p.lba >> 16)
 	p.command[13] = p.dev
 	p.command[14] = uint8(p.cmd)
 }
@@ -270,12 +294,297 @@ func (s *SGDisk) Identify() (*Info, error) {
 	return unpackIdentify(p.status, p.block, p.word), nil
 }
 
+// nonDataPacket returns a packet for an ATA command that transfers no data.
+// The drive's output registers are available in p.regs afterwards.
+func (s *SGDisk) nonDataPacket(cmd Cmd, ataType uint8) *packet {
+	p := s.newPacket(cmd, _SG_DXFER_NONE, ataType)
+	p.dataLen = 0
+	p.nsect = 0
+	return p
+}
+
+func (s *SGDisk) smartPacket(feature uint16) *packet {
+	var p *packet
+	if feature == smartReturnStatus {
+		p = s.nonDataPacket(unix.WIN_SMART, 0)
+	} else {
+		p = s.newPacket(unix.WIN_SMART, _SG_DXFER_FROM_DEV, 0)
+	}
+	p.features = feature
+	p.lba = smartLBA
+	p.genCommandDataBlock()
+	return p
+}
+
+// SMART returns the SMART attributes and health of Linux SCSI Generic Disks.
+func (s *SGDisk) SMART() (*SMARTInfo, error) {
+	data := s.smartPacket(smartReadData)
+	if err := s.operate(data); err != nil {
+		return nil, err
+	}
+	thr := s.smartPacket(smartReadThresholds)
+	if err := s.operate(thr); err != nil {
+		// READ THRESHOLDS is obsolete; carry on without thresholds.
+		Debug("SMART READ THRESHOLDS: %v", err)
+	}
+	attrs, err := unpackSMART(data.block, thr.block)
+	if err != nil {
+		return nil, err
+	}
+	st := s.smartPacket(smartReturnStatus)
+	if err := s.operate(st); err != nil {
+		return nil, err
+	}
+	if st.regs == nil {
+		return nil, fmt.Errorf("SMART RETURN STATUS: drive returned no registers")
+	}
+	info := &SMARTInfo{Attributes: attrs}
+	switch st.regs.lba & 0xffff00 {
+	case smartLBA:
+		info.Passed = true
+	case smartThresholdExceeded:
+	default:
+		return nil, fmt.Errorf("SMART RETURN STATUS: unexpected signature %#x", st.regs.lba)
+	}
+	return info, nil
+}
+
+// securityBlock fills in the data block shared by the security commands:
+// word 0 holds the identifier (and other control bits), words 1-16 the
+// password.
+func (p *packet) securityBlock(password string, admin bool) {
+	if admin {
+		p.block[0] |= 1
+	}
+	copy(p.block[2:2+maxPasswordLen], []byte(password))
+}
+
+func (s *SGDisk) setPasswordPacket(password string, admin bool, level SecurityLevel) *packet {
+	p := s.newPacket(unix.WIN_SECURITY_SET_PASS, _SG_DXFER_TO_DEV, 0)
+	p.genCommandDataBlock()
+	p.securityBlock(password, admin)
+	if !admin && level == SecurityMaximum {
+		p.block[1] |= 1
+	}
+	return p
+}
+
+// SetPassword performs SECURITY SET PASSWORD for Linux SCSI Generic Disks.
+func (s *SGDisk) SetPassword(password string, admin bool, level SecurityLevel) error {
+	if len(password) > maxPasswordLen {
+		return fmt.Errorf("password longer than %d bytes", maxPasswordLen)
+	}
+	return s.operate(s.setPasswordPacket(password, admin, level))
+}
+
+func (s *SGDisk) disablePasswordPacket(password string, admin bool) *packet {
+	p := s.newPacket(unix.WIN_SECURITY_DISABLE, _SG_DXFER_TO_DEV, 0)
+	p.genCommandDataBlock()
+	p.securityBlock(password, admin)
+	return p
+}
+
+// DisablePassword performs SECURITY DISABLE PASSWORD for Linux SCSI
+// Generic Disks.
+func (s *SGDisk) DisablePassword(password string, admin bool) error {
+	if len(password) > maxPasswordLen {
+		return fmt.Errorf("password longer than %d bytes", maxPasswordLen)
+	}
+	return s.operate(s.disablePasswordPacket(password, admin))
+}
+
+func (s *SGDisk) erasePreparePacket() *packet {
+	p := s.nonDataPacket(unix.WIN_SECURITY_ERASE_PREPARE, 0)
+	p.genCommandDataBlock()
+	return p
+}
+
+func (s *SGDisk) eraseUnitPacket(password string, admin, enhanced bool, timeout time.Duration) *packet {
+	p := s.newPacket(unix.WIN_SECURITY_ERASE_UNIT, _SG_DXFER_TO_DEV, 0)
+	p.timeout = uint32(timeout.Milliseconds())
+	p.genCommandDataBlock()
+	p.securityBlock(password, admin)
+	if enhanced {
+		p.block[0] |= 2
+	}
+	return p
+}
+
+// SecurityErase performs SECURITY ERASE PREPARE and SECURITY ERASE UNIT
+// for Linux SCSI Generic Disks. It blocks until the erase completes,
+// which may take hours.
+func (s *SGDisk) SecurityErase(password string, admin bool, enhanced bool) error {
+	if len(password) > maxPasswordLen {
+		return fmt.Errorf("password longer than %d bytes", maxPasswordLen)
+	}
+	info, err := s.Identify()
+	if err != nil {
+		return err
+	}
+	if !info.SecurityStatus.SecurityEnabled() {
+		return fmt.Errorf("security is not enabled; set a user password first")
+	}
+	if info.SecurityStatus.SecurityFrozen() {
+		return fmt.Errorf("security is frozen")
+	}
+	if enhanced && !info.SecurityStatus.EnhancedEraseSupported() {
+		return fmt.Errorf("enhanced security erase is not supported")
+	}
+	est := info.SecurityEraseTime
+	if enhanced {
+		est = info.EnhancedSecurityEraseTime
+	}
+	// Allow the drive twice its own estimate.
+	timeout := DefaultEraseTimeout
+	if est != 0 {
+		timeout = 2 * est
+	}
+	Debug("security erase: drive estimate %v, timeout %v", est, timeout)
+	if err := s.operate(s.erasePreparePacket()); err != nil {
+		return err
+	}
+	return s.operate(s.eraseUnitPacket(password, admin, enhanced, timeout))
+}
+
+func (s *SGDisk) sanitizePacket(o SanitizeOptions) (*packet, error) {
+	p := s.nonDataPacket(cmdSanitize, lba48)
+	switch o.Action {
+	case SanitizeCryptoScramble:
+		p.features = sanitizeCryptoExt
+		p.lba = sanitizeCryptoSig
+	case SanitizeBlockErase:
+		p.features = sanitizeBlockEraseExt
+		p.lba = sanitizeBlockSig
+	case SanitizeOverwrite:
+		if o.OverwritePasses > 16 {
+			return nil, fmt.Errorf("%d overwrite passes, at most 16 allowed", o.OverwritePasses)
+		}
+		p.features = sanitizeOverwriteExt
+		p.lba = sanitizeOverwriteSig | uint64(o.Pattern)
+		p.nsect = uint16(o.OverwritePasses & 0xf)
+		if o.OverwriteInvert {
+			p.nsect |= 1 << 7
+		}
+	default:
+		return nil, fmt.Errorf("invalid sanitize action %d", o.Action)
+	}
+	if o.AllowUnrestrictedExit {
+		p.nsect |= 1 << 4
+	}
+	p.genCommandDataBlock()
+	return p, nil
+}
+
+// Sanitize starts a SANITIZE DEVICE operation for Linux SCSI Generic Disks.
+// The operation continues in the background; use SanitizeStatus or
+// WaitSanitize to follow it.
+func (s *SGDisk) Sanitize(o SanitizeOptions) error {
+	p, err := s.sanitizePacket(o)
+	if err != nil {
+		return err
+	}
+	return s.operate(p)
+}
+
+func (s *SGDisk) sanitizeStatusPacket() *packet {
+	p := s.nonDataPacket(cmdSanitize, lba48)
+	p.features = sanitizeStatusExt
+	p.genCommandDataBlock()
+	return p
+}
+
+// SanitizeStatus performs SANITIZE STATUS EXT for Linux SCSI Generic Disks.
+// A sanitize operation that failed is returned as an error.
+func (s *SGDisk) SanitizeStatus() (*SanitizeStatus, error) {
+	p := s.sanitizeStatusPacket()
+	if err := s.operate(p); err != nil {
+		return nil, err
+	}
+	if p.regs == nil {
+		return nil, fmt.Errorf("SANITIZE STATUS EXT: drive returned no registers")
+	}
+	return unpackSanitizeStatus(p.regs), nil
+}
+
+// scsiPacket returns a packet for a native SCSI command which reads
+// dataLen bytes.
+func (s *SGDisk) scsiPacket(cdb []byte, dataLen int) *packet {
+	p := s.newPacket(0, _SG_DXFER_FROM_DEV, 0)
+	p.cmdLen = uint8(len(cdb))
+	copy(p.command[:], cdb)
+	p.dataLen = uint32(dataLen)
+	return p
+}
+
+func (s *SGDisk) inquiryPacket(vpdPage uint8, evpd bool) *packet {
+	cdb := []byte{scsiInquiry, 0, vpdPage, 0, inquiryLen, 0}
+	if evpd {
+		cdb[1] = 1
+	}
+	return s.scsiPacket(cdb, inquiryLen)
+}
+
+// Inquiry performs a SCSI INQUIRY for Linux SCSI Generic Disks.
+func (s *SGDisk) Inquiry() (*InquiryData, error) {
+	p := s.inquiryPacket(0, false)
+	if err := s.operate(p); err != nil {
+		return nil, err
+	}
+	i := unpackInquiry(p.block)
+	// The unit serial number page is optional.
+	vpd := s.inquiryPacket(vpdUnitSerial, true)
+	if err := s.operate(vpd); err != nil {
+		Debug("INQUIRY unit serial number: %v", err)
+		return i, nil
+	}
+	serial, err := unpackUnitSerial(vpd.block)
+	if err != nil {
+		Debug("INQUIRY unit serial number: %v", err)
+		return i, nil
+	}
+	i.Serial = serial
+	return i, nil
+}
+
+func (s *SGDisk) readCapacityPacket() *packet {
+	cdb := make([]byte, 16)
+	cdb[0] = scsiServiceActionIn
+	cdb[1] = scsiReadCapacity16
+	cdb[13] = readCapacity16Len
+	return s.scsiPacket(cdb, readCapacity16Len)
+}
+
+// ReadCapacity performs a SCSI READ CAPACITY(16) for Linux SCSI Generic
+// Disks.
+func (s *SGDisk) ReadCapacity() (*Capacity, error) {
+	p := s.readCapacityPacket()
+	if err := s.operate(p); err != nil {
+		return nil, err
+	}
+	return unpackReadCapacity16(p.block), nil
+}
+
 // _SG_IO is the ioctl request number for SCSI operations.
 const _SG_IO = 0x2285
 
 func (s *SGDisk) operate(p *packet) error {
 	_, _, errno := unix.Syscall(unix.SYS_IOCTL, uintptr(s.f.Fd()), _SG_IO, uintptr(unsafe.Pointer(&p.packetHeader)))
 	sb := p.status[0]
+	if errno == 0 && sb != 0 {
+		// Commands issued with a check condition report their
+		// output registers in the sense data even on success.
+		if r, ok := p.status.ataReturn(); ok {
+			if r.failed() {
+				return &os.PathError{
+					Op:   "ioctl SG_IO",
+					Path: s.f.Name(),
+					Err:  fmt.Errorf("ATA command %#02x failed with status %#02x and error %#02x", uint8(p.cmd), r.status, r.err),
+				}
+			}
+			p.regs = r
+			sb = 0
+		}
+	}
 	if errno != 0 || sb != 0 {
 		return &os.PathError{
 			Op:   "ioctl SG_IO",
@@ -8,6 +8,7 @@ package scuzz
 
 import (
 	"testing"
+	"time"
 	"unsafe"
 )
 
@@ -186,3 +187,141 @@ func TestIdentify(t *testing.T) {
 	p := (&SGDisk{dev: 0x40, Timeout: DefaultTimeout}).identifyPacket()
 	check(t, p, want)
 }
+
+func TestATACommandBlocks(t *testing.T) {
+	d := &SGDisk{dev: 0x40, Timeout: DefaultTimeout}
+	overwrite, err := d.sanitizePacket(SanitizeOptions{
+		Action:                SanitizeOverwrite,
+		OverwritePasses:       3,
+		OverwriteInvert:       true,
+		AllowUnrestrictedExit: true,
+		Pattern:               0xdeadbeef,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	crypto, err := d.sanitizePacket(SanitizeOptions{Action: SanitizeCryptoScramble})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		name      string
+		p         *packet
+		direction direction
+		dataLen   uint32
+		want      commandDataBlock
+	}{
+		{
+			name:      "smart read data",
+			p:         d.smartPacket(smartReadData),
+			direction: _SG_DXFER_FROM_DEV,
+			dataLen:   512,
+			want:      commandDataBlock{0x85, 0x08, 0x0e, 0x00, 0xd0, 0x00, 0x01, 0x00, 0x00, 0x00, 0x4f, 0x00, 0xc2, 0x40, 0xb0, 0x00},
+		},
+		{
+			name:      "smart return status",
+			p:         d.smartPacket(smartReturnStatus),
+			direction: _SG_DXFER_NONE,
+			want:      commandDataBlock{0x85, 0x06, 0x20, 0x00, 0xda, 0x00, 0x00, 0x00, 0x00, 0x00, 0x4f, 0x00, 0xc2, 0x40, 0xb0, 0x00},
+		},
+		{
+			name:      "security erase prepare",
+			p:         d.erasePreparePacket(),
+			direction: _SG_DXFER_NONE,
+			want:      commandDataBlock{0x85, 0x06, 0x20, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x40, 0xf3, 0x00},
+		},
+		{
+			name:      "security erase unit",
+			p:         d.eraseUnitPacket("pw", false, true, time.Hour),
+			direction: _SG_DXFER_TO_DEV,
+			dataLen:   512,
+			want:      commandDataBlock{0x85, 0x0a, 0x06, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x40, 0xf4, 0x00},
+		},
+		{
+			name:      "sanitize crypto scramble",
+			p:         crypto,
+			direction: _SG_DXFER_NONE,
+			want:      commandDataBlock{0x85, 0x07, 0x20, 0x00, 0x11, 0x00, 0x00, 0x43, 0x70, 0x00, 0x79, 0x00, 0x72, 0x40, 0xb4, 0x00},
+		},
+		{
+			name:      "sanitize overwrite",
+			p:         overwrite,
+			direction: _SG_DXFER_NONE,
+			want:      commandDataBlock{0x85, 0x07, 0x20, 0x00, 0x14, 0x00, 0x93, 0xde, 0xef, 0x57, 0xbe, 0x4f, 0xad, 0x40, 0xb4, 0x00},
+		},
+		{
+			name:      "sanitize status",
+			p:         d.sanitizeStatusPacket(),
+			direction: _SG_DXFER_NONE,
+			want:      commandDataBlock{0x85, 0x07, 0x20, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x40, 0xb4, 0x00},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.p.direction != tt.direction {
+				t.Errorf("direction: got %v, want %v", tt.p.direction, tt.direction)
+			}
+			if tt.p.dataLen != tt.dataLen {
+				t.Errorf("dataLen: got %v, want %v", tt.p.dataLen, tt.dataLen)
+			}
+			if tt.p.command != tt.want {
+				t.Errorf("command: got % x, want % x", tt.p.command, tt.want)
+			}
+		})
+	}
+
+	if _, err := d.sanitizePacket(SanitizeOptions{}); err == nil {
+		t.Errorf("sanitizePacket with no action: got nil, want error")
+	}
+	if _, err := d.sanitizePacket(SanitizeOptions{Action: SanitizeOverwrite, OverwritePasses: 17}); err == nil {
+		t.Errorf("sanitizePacket with 17 passes: got nil, want error")
+	}
+}
+
+func TestSecurityBlocks(t *testing.T) {
+	d := &SGDisk{dev: 0x40, Timeout: DefaultTimeout}
+
+	p := d.setPasswordPacket("secret", false, SecurityMaximum)
+	if p.block[0] != 0 || p.block[1] != 1 || string(p.block[2:8]) != "secret" {
+		t.Errorf("set user password block: got % x", p.block[:8])
+	}
+	if p.command[14] != 0xf1 {
+		t.Errorf("set password command: got %#02x, want 0xf1", p.command[14])
+	}
+
+	p = d.setPasswordPacket("secret", true, SecurityMaximum)
+	if p.block[0] != 1 || p.block[1] != 0 {
+		t.Errorf("set master password block: got % x", p.block[:2])
+	}
+
+	p = d.eraseUnitPacket("secret", true, true, 90*time.Minute)
+	if p.block[0] != 3 || string(p.block[2:8]) != "secret" {
+		t.Errorf("erase unit block: got % x", p.block[:8])
+	}
+	if p.timeout != 90*60*1000 {
+		t.Errorf("erase unit timeout: got %d, want %d", p.timeout, 90*60*1000)
+	}
+
+	p = d.disablePasswordPacket("secret", false)
+	if p.block[0] != 0 || p.command[14] != 0xf6 {
+		t.Errorf("disable password: block % x command %#02x", p.block[:2], p.command[14])
+	}
+}
+
+func TestSCSICommandBlocks(t *testing.T) {
+	d := &SGDisk{dev: 0x40, Timeout: DefaultTimeout}
+
+	p := d.inquiryPacket(vpdUnitSerial, true)
+	if p.cmdLen != 6 || p.dataLen != inquiryLen || p.direction != _SG_DXFER_FROM_DEV {
+		t.Errorf("inquiry: cmdLen %d dataLen %d direction %d", p.cmdLen, p.dataLen, p.direction)
+	}
+	if want := [6]byte{0x12, 0x01, 0x80, 0x00, inquiryLen, 0x00}; [6]byte(p.command[:6]) != want {
+		t.Errorf("inquiry: got % x, want % x", p.command[:6], want)
+	}
+
+	p = d.readCapacityPacket()
+	want := commandDataBlock{0x9e, 0x10, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0x20, 0, 0}
+	if p.cmdLen != 16 || p.command != want {
+		t.Errorf("read capacity: got % x (len %d), want % x", p.command, p.cmdLen, want)
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scuzz
+
+import (
+	"testing"
+)
+
+func (b *dataBlock) setChecksum() {
+	var sum uint8
+	for _, v := range b[:len(b)-1] {
+		sum += v
+	}
+	b[len(b)-1] = -sum
+}
+
+func TestUnpackSMART(t *testing.T) {
+	var data, thr dataBlock
+	// Attribute 5, prefailure, current 100, worst 99, raw 0x0102030405.
+	copy(data[2:], []byte{5, 0x03, 0x00, 100, 99, 0x05, 0x04, 0x03, 0x02, 0x01, 0x00})
+	// Attribute 194, current 35, raw 35.
+	copy(data[2+12:], []byte{194, 0x22, 0x00, 35, 20, 35})
+	// An unnamed attribute.
+	copy(data[2+24:], []byte{250, 0x00, 0x00, 10, 10})
+	data.setChecksum()
+	copy(thr[2:], []byte{5, 10})
+	copy(thr[2+12:], []byte{194, 40})
+	thr.setChecksum()
+
+	attrs, err := unpackSMART(data, thr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(attrs) != 3 {
+		t.Fatalf("got %d attributes, want 3", len(attrs))
+	}
+	s := &SMARTInfo{Attributes: attrs}
+	a, ok := s.Attribute(5)
+	if !ok {
+		t.Fatalf("attribute 5 missing")
+	}
+	want := SMARTAttribute{ID: 5, Name: "Reallocated_Sector_Ct", Flags: 3, Current: 100, Worst: 99, Threshold: 10, Raw: 0x0102030405}
+	if a != want {
+		t.Errorf("attribute 5: got %+v, want %+v", a, want)
+	}
+	if !a.Prefailure() || a.Failing() {
+		t.Errorf("attribute 5: Prefailure %v Failing %v", a.Prefailure(), a.Failing())
+	}
+	if a, _ := s.Attribute(194); !a.Failing() || a.Prefailure() {
+		t.Errorf("attribute 194: Prefailure %v Failing %v", a.Prefailure(), a.Failing())
+	}
+	if a, _ := s.Attribute(250); a.Name != "Unknown_Attribute" || a.Failing() {
+		t.Errorf("attribute 250: got %+v", a)
+	}
+
+	// A bad threshold checksum drops the thresholds but not the data.
+	thr[0]++
+	attrs, err = unpackSMART(data, thr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attrs[0].Threshold != 0 {
+		t.Errorf("threshold with bad checksum: got %d, want 0", attrs[0].Threshold)
+	}
+
+	data[0]++
+	if _, err := unpackSMART(data, thr); err == nil {
+		t.Errorf("unpackSMART with bad checksum: got nil, want error")
+	}
+}
+
+func TestATAReturn(t *testing.T) {
+	var sb statusBlock
+	if _, ok := sb.ataReturn(); ok {
+		t.Errorf("ataReturn on empty sense: got ok")
+	}
+
+	// Descriptor format sense with an ATA Status Return descriptor
+	// as returned by SMART RETURN STATUS on a healthy drive.
+	copy(sb[:], []byte{
+		0x72, 0x01, 0x00, 0x1d, 0x00, 0x00, 0x00, 0x0e,
+		0x09, 0x0c, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x4f, 0x00, 0xc2, 0x40, 0x50,
+	})
+	r, ok := sb.ataReturn()
+	if !ok {
+		t.Fatalf("ataReturn: got !ok")
+	}
+	if r.lba != smartLBA || r.status != 0x50 || r.device != 0x40 || r.failed() {
+		t.Errorf("ataReturn: got %+v", r)
+	}
+
+	// SANITIZE STATUS EXT: in progress, half way, 48-bit registers.
+	sb[12], sb[13] = 0x40, 0x00
+	sb[14], sb[15] = 0x01, 0x00
+	sb[16], sb[17] = 0x00, 0x80
+	sb[18], sb[19] = 0x02, 0x00
+	sb[20], sb[21] = 0x40, 0x51
+	r, ok = sb.ataReturn()
+	if !ok {
+		t.Fatalf("ataReturn: got !ok")
+	}
+	if r.count != 0x4000 || r.lba != 0x020001008000 {
+		t.Errorf("ataReturn: count %#x lba %#x", r.count, r.lba)
+	}
+	if !r.failed() {
+		t.Errorf("status %#02x: failed() = false, want true", r.status)
+	}
+	s := unpackSanitizeStatus(r)
+	if !s.InProgress || s.Completed || s.Percent() != 50 {
+		t.Errorf("sanitize status: got %+v", s)
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mount
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// FSOpener is a Mounter whose file system can also be read in userspace,
+// without kernel support.
+type FSOpener interface {
+	Mounter
+	// WithFS calls fn with a read-only view of the file system and its
+	// type. The view is only valid during the call.
+	WithFS(fn func(fsys fs.FS, fsType string) error) error
+}
+
+// UserspaceDirs are the top-level directories Pool copies out of file
+// systems read in userspace, matched case-insensitively. Regular files
+// and symlinks in the root directory are copied as well. Together they
+// hold the boot loader configurations and the kernels they refer to.
+var UserspaceDirs = []string{"boot", "efi", "loader", "grub", "grub2", "syslinux", "isolinux", "extlinux"}
+
+// UserspaceMaxFileSize is the size of the largest file Pool copies out of
+// file systems read in userspace. Larger files, such as disk images, are
+// skipped.
+var UserspaceMaxFileSize int64 = 256 << 20
+
+// UserspaceMaxSize is the number of bytes Pool copies out of each file
+// system read in userspace. The copies live in a temporary directory,
+// which is usually in RAM, so no more than half of its free space is used
+// either. Files that no longer fit are skipped.
+var UserspaceMaxSize int64 = 1 << 30
+
+// copier copies the boot-relevant files of fsys to dir, as long as there
+// are bytes left.
+type copier struct {
+	fsys fs.FS
+	dir  string
+	left int64
+}
+
+// copyOut copies the boot-relevant files of fsys to dir.
+func copyOut(fsys fs.FS, dir string) error {
+	c := &copier{fsys: fsys, dir: dir, left: UserspaceMaxSize}
+	var st unix.Statfs_t
+	if err := unix.Statfs(dir, &st); err == nil {
+		c.left = min(c.left, int64(st.Bavail)*st.Bsize/2)
+	}
+
+	ents, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return err
+	}
+	for _, e := range ents {
+		if e.IsDir() {
+			if slices.ContainsFunc(UserspaceDirs, func(d string) bool { return strings.EqualFold(d, e.Name()) }) {
+				if err := c.copyTree(e.Name()); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if err := c.copyEntry(e.Name(), e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *copier) copyTree(root string) error {
+	return fs.WalkDir(c.fsys, root, func(name string, e fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if e.IsDir() {
+			return os.MkdirAll(filepath.Join(c.dir, filepath.FromSlash(name)), 0o755)
+		}
+		return c.copyEntry(name, e)
+	})
+}
+
+// copyEntry copies a regular file or symlink. Other file types are
+// skipped.
+func (c *copier) copyEntry(name string, e fs.DirEntry) error {
+	fsys := c.fsys
+	dst := filepath.Join(c.dir, filepath.FromSlash(name))
+	switch {
+	case e.Type()&fs.ModeSymlink != 0:
+		target, err := fs.ReadLink(fsys, name)
+		if err != nil {
+			return err
+		}
+		return os.Symlink(target, dst)
+	case !e.Type().IsRegular():
+		return nil
+	}
+	fi, err := e.Info()
+	if err != nil {
+		return err
+	}
+	if fi.Size() > UserspaceMaxFileSize || fi.Size() > c.left {
+		return nil
+	}
+	src, err := fsys.Open(name)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	f, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, fi.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	// Files are not copied beyond the size they said they have.
+	n, err := io.Copy(f, io.LimitReader(src, fi.Size()))
+	c.left -= n
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// mountUserspace reads the file system of o in userspace and copies the
+// boot-relevant files to path.
+func mountUserspace(o FSOpener, path string) (*MountPoint, error) {
+	var fsType string
+	err := o.WithFS(func(fsys fs.FS, t string) error {
+		fsType = t
+		return copyOut(fsys, path)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading %s in userspace: %w", o.DevName(), err)
+	}
+	return &MountPoint{
+		Path:      path,
+		Device:    filepath.Join("/dev", o.DevName()),
+		FSType:    fsType,
+		Flags:     ReadOnly,
+		Userspace: true,
+	}, nil
+}
@@ -0,0 +1,390 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package netconf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/u-root/u-root/pkg/dhclient"
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+)
+
+// resolvConfPath is replaced in tests.
+var resolvConfPath = dhclient.ResolvConfPath
+
+// Apply configures the network as c describes. It waits up to
+// c.WaitOnline for the links that are not optional to have a carrier and
+// their DHCP leases, and returns an error for each that does not.
+func Apply(ctx context.Context, c *Config) error {
+	links, err := c.Ordered()
+	if err != nil {
+		return err
+	}
+	wait := time.Duration(c.WaitOnline)
+	if wait <= 0 {
+		wait = DefaultWaitOnline
+	}
+	ctx, cancel := context.WithTimeout(ctx, wait)
+	defer cancel()
+
+	a := &applier{masters: make(map[string]string)}
+	for _, l := range links {
+		for _, m := range l.Members {
+			a.masters[m] = l.Name
+		}
+	}
+
+	// Links come up in order, each after those it depends on.
+	up := make(map[string][]netlink.Link)
+	for _, l := range links {
+		nl, err := a.setUp(ctx, l)
+		if err != nil {
+			a.fail(l, err)
+			continue
+		}
+		up[l.Name] = nl
+	}
+	for _, l := range links {
+		if nl, ok := up[l.Name]; ok && !a.online(ctx, l, nl) {
+			a.fail(l, errors.New("no carrier"))
+		}
+	}
+
+	for _, l := range links {
+		nl, ok := up[l.Name]
+		if !ok {
+			continue
+		}
+		if l.Name == "" && len(l.Addresses) > 0 {
+			// The static configuration of ip= without a device
+			// is for the first one.
+			nl = nl[:1]
+		}
+		for _, link := range nl {
+			if err := configureStatic(link, l); err != nil {
+				a.fail(l, err)
+			}
+		}
+	}
+	for _, r := range c.Routes {
+		if err := addRoute(nil, r); err != nil {
+			a.errs = append(a.errs, err)
+		}
+	}
+
+	a.dhcp(ctx, links, up)
+
+	if len(c.DNS.Nameservers) > 0 || len(c.DNS.Search) > 0 || c.DNS.Domain != "" {
+		var ns []net.IP
+		for _, s := range c.DNS.Nameservers {
+			ns = append(ns, net.ParseIP(s))
+		}
+		if err := dhclient.WriteDNSSettings(ns, c.DNS.Search, c.DNS.Domain, resolvConfPath); err != nil {
+			a.errs = append(a.errs, err)
+		}
+	}
+	if c.Hostname != "" {
+		if err := unix.Sethostname([]byte(c.Hostname)); err != nil {
+			a.errs = append(a.errs, fmt.Errorf("setting hostname: %w", err))
+		}
+	}
+	return errors.Join(a.errs...)
+}
+
+type applier struct {
+	// masters maps bond and bridge members to their masters.
+	masters map[string]string
+	errs    []error
+}
+
+// fail records an error of l, or logs it if l is optional.
+func (a *applier) fail(l Link, err error) {
+	err = fmt.Errorf("link %s: %w", linkName(l), err)
+	if l.Optional {
+		log.Printf("netconf: %v", err)
+		return
+	}
+	a.errs = append(a.errs, err)
+}
+
+func linkName(l Link) string {
+	if l.Name == "" {
+		return "(any device)"
+	}
+	return l.Name
+}
+
+// devices returns the network devices backed by hardware, for links
+// without a name.
+func devices() ([]netlink.Link, error) {
+	all, err := netlink.LinkList()
+	if err != nil {
+		return nil, err
+	}
+	var ls []netlink.Link
+	for _, l := range all {
+		if _, err := os.Stat(filepath.Join("/sys/class/net", l.Attrs().Name, "device")); err == nil {
+			ls = append(ls, l)
+		}
+	}
+	if len(ls) == 0 {
+		return nil, errors.New("no network devices")
+	}
+	return ls, nil
+}
+
+// find returns the link called name, waiting for it to appear as devices
+// may be probed late.
+func find(ctx context.Context, name string) (netlink.Link, error) {
+	for {
+		l, err := netlink.LinkByName(name)
+		if err == nil {
+			return l, nil
+		}
+		var nf netlink.LinkNotFoundError
+		if !errors.As(err, &nf) {
+			return nil, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("no such link")
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+// create adds the virtual link l.
+func create(l Link) error {
+	attrs := netlink.NewLinkAttrs()
+	attrs.Name = l.Name
+	var nl netlink.Link
+	switch l.Kind {
+	case KindDummy:
+		nl = &netlink.Dummy{LinkAttrs: attrs}
+	case KindBridge:
+		nl = &netlink.Bridge{LinkAttrs: attrs}
+	case KindBond:
+		b := netlink.NewLinkBond(attrs)
+		if l.BondMode != "" {
+			b.Mode = netlink.StringToBondMode(l.BondMode)
+		}
+		if l.BondMiimon != 0 {
+			b.Miimon = l.BondMiimon
+		}
+		nl = b
+	case KindVLAN:
+		parent, err := netlink.LinkByName(l.Parent)
+		if err != nil {
+			return fmt.Errorf("parent %s: %w", l.Parent, err)
+		}
+		attrs.ParentIndex = parent.Attrs().Index
+		nl = &netlink.Vlan{LinkAttrs: attrs, VlanId: l.VLANID}
+	}
+	if err := netlink.LinkAdd(nl); err != nil {
+		return fmt.Errorf("creating %s: %w", l.Kind, err)
+	}
+	return nil
+}
+
+// setUp creates l if it is virtual, enslaves it to its master, sets its
+// MTU and MAC, and brings it up.
+func (a *applier) setUp(ctx context.Context, l Link) ([]netlink.Link, error) {
+	if l.Name == "" {
+		ls, err := devices()
+		if err != nil {
+			return nil, err
+		}
+		for _, nl := range ls {
+			if err := netlink.LinkSetUp(nl); err != nil {
+				return nil, fmt.Errorf("%s: %w", nl.Attrs().Name, err)
+			}
+		}
+		return ls, nil
+	}
+
+	if l.Kind != KindDevice {
+		if _, err := netlink.LinkByName(l.Name); err != nil {
+			if err := create(l); err != nil {
+				return nil, err
+			}
+		}
+	}
+	nl, err := find(ctx, l.Name)
+	if err != nil {
+		return nil, err
+	}
+	if l.Kind != KindDevice && nl.Type() != l.Kind {
+		return nil, fmt.Errorf("exists as a %s, not a %s", nl.Type(), l.Kind)
+	}
+	if m, ok := a.masters[l.Name]; ok {
+		master, err := netlink.LinkByName(m)
+		if err != nil {
+			return nil, fmt.Errorf("master %s: %w", m, err)
+		}
+		if nl.Attrs().MasterIndex != master.Attrs().Index {
+			// Bonds only take members that are down.
+			if err := netlink.LinkSetDown(nl); err != nil {
+				return nil, err
+			}
+			if err := netlink.LinkSetMaster(nl, master); err != nil {
+				return nil, fmt.Errorf("adding to %s: %w", m, err)
+			}
+		}
+	}
+	if l.MTU != 0 && nl.Attrs().MTU != l.MTU {
+		if err := netlink.LinkSetMTU(nl, l.MTU); err != nil {
+			return nil, fmt.Errorf("setting MTU: %w", err)
+		}
+	}
+	if l.MAC != "" {
+		mac, _ := net.ParseMAC(l.MAC)
+		if !slices.Equal(nl.Attrs().HardwareAddr, mac) {
+			if err := netlink.LinkSetHardwareAddr(nl, mac); err != nil {
+				return nil, fmt.Errorf("setting MAC: %w", err)
+			}
+		}
+	}
+	if err := netlink.LinkSetUp(nl); err != nil {
+		return nil, err
+	}
+	return []netlink.Link{nl}, nil
+}
+
+// online waits for a carrier on nl. A link without a name is online when
+// one of its devices is.
+func (a *applier) online(ctx context.Context, l Link, nl []netlink.Link) bool {
+	for {
+		for _, link := range nl {
+			cur, err := netlink.LinkByIndex(link.Attrs().Index)
+			if err != nil {
+				continue
+			}
+			if o := cur.Attrs().OperState; o == netlink.OperUp || o == netlink.OperUnknown {
+				return true
+			}
+		}
+		if l.Optional {
+			return true
+		}
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+// configureStatic adds the static addresses and routes of l to nl.
+func configureStatic(nl netlink.Link, l Link) error {
+	for _, s := range l.Addresses {
+		addr, err := netlink.ParseAddr(s)
+		if err != nil {
+			return err
+		}
+		if err := netlink.AddrReplace(nl, addr); err != nil {
+			return fmt.Errorf("adding %s: %w", s, err)
+		}
+	}
+	for _, r := range l.Routes {
+		if err := addRoute(nl, r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addRoute adds r, through nl if it is not nil.
+func addRoute(nl netlink.Link, r Route) error {
+	dst, _ := r.dst()
+	nr := &netlink.Route{Dst: dst, Priority: r.Metric}
+	if nl != nil {
+		nr.LinkIndex = nl.Attrs().Index
+	}
+	if r.Via != "" {
+		nr.Gw = net.ParseIP(r.Via)
+	} else {
+		nr.Scope = netlink.SCOPE_LINK
+	}
+	if dst == nil && nr.Gw != nil && nr.Gw.To4() == nil {
+		nr.Family = netlink.FAMILY_V6
+	}
+	if err := netlink.RouteReplace(nr); err != nil {
+		return fmt.Errorf("adding route to %s: %w", r.To, err)
+	}
+	return nil
+}
+
+// dhcp obtains and applies leases on the links that use DHCP.
+func (a *applier) dhcp(ctx context.Context, links []Link, up map[string][]netlink.Link) {
+	type req struct {
+		l  Link
+		nl []netlink.Link
+	}
+	var reqs []req
+	for _, l := range links {
+		if nl, ok := up[l.Name]; ok && (l.DHCP4 || l.DHCP6) {
+			reqs = append(reqs, req{l, nl})
+		}
+	}
+	if len(reqs) == 0 {
+		return
+	}
+	deadline, _ := ctx.Deadline()
+	c := dhclient.Config{
+		Timeout: 5 * time.Second,
+		Retries: max(1, int(time.Until(deadline)/(10*time.Second))),
+	}
+	results := make([][]*dhclient.Result, len(reqs))
+	done := make(chan struct{})
+	for i, r := range reqs {
+		go func() {
+			defer func() { done <- struct{}{} }()
+			for res := range dhclient.SendRequests(ctx, r.nl, r.l.DHCP4, r.l.DHCP6, c, time.Until(deadline)) {
+				results[i] = append(results[i], res)
+			}
+		}()
+	}
+	for range reqs {
+		<-done
+	}
+
+	for i, r := range reqs {
+		var errs []error
+		leases := 0
+		for _, res := range results[i] {
+			err := res.Err
+			if err == nil {
+				err = res.Lease.Configure()
+			}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s on %s: %w", res.Protocol, res.Interface.Attrs().Name, err))
+				continue
+			}
+			log.Printf("netconf: configured %s with %s", res.Interface.Attrs().Name, res.Lease)
+			leases++
+		}
+		// A link without a name needs one device with a lease, the
+		// others need every lease they asked for.
+		switch want := len(results[i]); {
+		case r.l.Name == "" && leases > 0:
+			for _, err := range errs {
+				log.Printf("netconf: %v", err)
+			}
+		case leases == 0 && want == 0:
+			a.fail(r.l, errors.New("no DHCP lease"))
+		case len(errs) > 0:
+			a.fail(r.l, errors.Join(errs...))
+		}
+	}
+}
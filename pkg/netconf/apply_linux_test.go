@@ -0,0 +1,135 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package netconf
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hugelgupf/vmtest/guest"
+	"github.com/vishvananda/netlink"
+)
+
+func TestApply(t *testing.T) {
+	guest.SkipIfNotInVM(t)
+
+	resolvConfPath = filepath.Join(t.TempDir(), "resolv.conf")
+	c := &Config{
+		Links: []Link{
+			// Declared before the links it depends on.
+			{Name: "ncbond.7", Kind: KindVLAN, Parent: "ncbond", VLANID: 7, Addresses: []string{"192.0.2.10/24"}, Routes: []Route{{To: "198.51.100.0/24", Via: "192.0.2.1"}}},
+			{Name: "ncbond", Kind: KindBond, Members: []string{"nc0", "nc1"}, BondMode: "active-backup", BondMiimon: 100, MTU: 1400},
+			{Name: "nc0", Kind: KindDummy},
+			{Name: "nc1", Kind: KindDummy},
+			{Name: "ncbr", Kind: KindBridge, Members: []string{"nc2"}, MAC: "02:00:00:00:00:42", Addresses: []string{"2001:db8::10/64"}},
+			{Name: "nc2", Kind: KindDummy},
+		},
+		Routes:     []Route{{To: "203.0.113.0/24", Via: "192.0.2.254", Metric: 5}},
+		DNS:        DNS{Nameservers: []string{"192.0.2.53"}, Search: []string{"example.com"}},
+		WaitOnline: Duration(10 * time.Second),
+	}
+	t.Cleanup(func() {
+		for _, n := range []string{"ncbond.7", "ncbond", "ncbr", "nc0", "nc1", "nc2"} {
+			if l, err := netlink.LinkByName(n); err == nil {
+				netlink.LinkDel(l)
+			}
+		}
+	})
+	if err := Apply(context.Background(), c); err != nil {
+		t.Fatalf("Apply = %v", err)
+	}
+	// Applying again changes nothing.
+	if err := Apply(context.Background(), c); err != nil {
+		t.Fatalf("second Apply = %v", err)
+	}
+
+	link := func(name string) netlink.Link {
+		t.Helper()
+		l, err := netlink.LinkByName(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return l
+	}
+	bond, br, vlan := link("ncbond"), link("ncbr"), link("ncbond.7")
+	for _, m := range []string{"nc0", "nc1"} {
+		if link(m).Attrs().MasterIndex != bond.Attrs().Index {
+			t.Errorf("%s is not in ncbond", m)
+		}
+	}
+	if link("nc2").Attrs().MasterIndex != br.Attrs().Index {
+		t.Errorf("nc2 is not in ncbr")
+	}
+	if b, ok := bond.(*netlink.Bond); !ok || b.Mode != netlink.BOND_MODE_ACTIVE_BACKUP || b.Attrs().MTU != 1400 {
+		t.Errorf("ncbond = %+v, want an active-backup bond with MTU 1400", bond)
+	}
+	if v, ok := vlan.(*netlink.Vlan); !ok || v.VlanId != 7 || v.ParentIndex != bond.Attrs().Index {
+		t.Errorf("ncbond.7 = %+v, want VLAN 7 on ncbond", vlan)
+	}
+	if br.Attrs().HardwareAddr.String() != "02:00:00:00:00:42" {
+		t.Errorf("ncbr has MAC %s", br.Attrs().HardwareAddr)
+	}
+
+	hasAddr := func(l netlink.Link, family int, want string) {
+		t.Helper()
+		addrs, err := netlink.AddrList(l, family)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, a := range addrs {
+			if a.IPNet.String() == want {
+				return
+			}
+		}
+		t.Errorf("%s has addresses %v, want %s", l.Attrs().Name, addrs, want)
+	}
+	hasAddr(vlan, netlink.FAMILY_V4, "192.0.2.10/24")
+	hasAddr(br, netlink.FAMILY_V6, "2001:db8::10/64")
+
+	routes, err := netlink.RouteList(nil, netlink.FAMILY_V4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := 0
+	for _, r := range routes {
+		switch {
+		case r.Dst != nil && r.Dst.String() == "198.51.100.0/24" && r.Gw.Equal(net.ParseIP("192.0.2.1")) && r.LinkIndex == vlan.Attrs().Index:
+			found++
+		case r.Dst != nil && r.Dst.String() == "203.0.113.0/24" && r.Gw.Equal(net.ParseIP("192.0.2.254")) && r.Priority == 5:
+			found++
+		}
+	}
+	if found != 2 {
+		t.Errorf("routes %v lack those to 198.51.100.0/24 and 203.0.113.0/24", routes)
+	}
+
+	b, err := os.ReadFile(resolvConfPath)
+	if err != nil || string(b) != "nameserver 192.0.2.53\nsearch example.com\n" {
+		t.Errorf("resolv.conf = %q, %v", b, err)
+	}
+}
+
+func TestApplyMissingLink(t *testing.T) {
+	guest.SkipIfNotInVM(t)
+
+	c := &Config{
+		Links: []Link{
+			{Name: "ncmissing", Addresses: []string{"192.0.2.20/24"}},
+			{Name: "ncoptional", Optional: true},
+		},
+		WaitOnline: Duration(time.Second),
+	}
+	if err := Apply(context.Background(), c); err == nil {
+		t.Errorf("Apply with a missing link succeeded")
+	}
+	c.Links = c.Links[1:]
+	if err := Apply(context.Background(), c); err != nil {
+		t.Errorf("Apply with a missing optional link = %v", err)
+	}
+}
@@ -0,0 +1,348 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package netconf
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ErrOff is returned by ParseCmdline for ip=off or ip=none, which turn
+// network configuration off.
+var ErrOff = errors.New("network configuration is off")
+
+// ParseCmdline reads the network parameters among the kernel command line
+// arguments args. It returns nil if there are none.
+//
+// The ip= parameter has the forms of the kernel, described in
+// Documentation/admin-guide/nfs/nfsroot.rst,
+//
+//	ip=<client-ip>:<server-ip>:<gw-ip>:<netmask>:<hostname>:<device>:<autoconf>:<dns0-ip>:<dns1-ip>:<ntp0-ip>
+//	ip={off|none|on|any|dhcp|bootp|rarp|both}
+//
+// and those of dracut.cmdline(7),
+//
+//	ip={dhcp|on|any|dhcp6|auto6|either6|link6}
+//	ip=<interface>:{dhcp|on|any|dhcp6|auto6|either6|link6|off|none}[:[<mtu>][:<macaddr>]]
+//	ip=<client-ip>:[<peer>]:<gateway-ip>:<netmask>:<hostname>:<interface>:<autoconf>[:[<mtu>][:<macaddr>]]
+//	ip=<client-ip>:[<peer>]:<gateway-ip>:<netmask>:<hostname>:<interface>:<autoconf>[:[<dns1>][:<dns2>]]
+//
+// with IPv6 addresses in brackets. bootp and rarp mean DHCP, auto6 and
+// link6 only bring the link up for the kernel to autoconfigure, and the
+// server and NTP addresses are ignored. ParseCmdline further understands
+//
+//	vlan=<vlanname>:<phys>
+//	bond=<bondname>[:<members>[:<options>[:<mtu>]]]
+//	bridge=<bridgename>:<members>
+//	nameserver=<ip>
+//	rd.route=<net>/<prefix>:<gateway>[:<interface>]
+func ParseCmdline(args []string) (*Config, error) {
+	var c Config
+	found := false
+	for _, a := range args {
+		key, val, _ := strings.Cut(a, "=")
+		var err error
+		switch key {
+		case "ip":
+			err = c.parseIP(val)
+		case "vlan":
+			err = c.parseVLAN(val)
+		case "bond":
+			err = c.parseBond(val)
+		case "bridge":
+			err = c.parseBridge(val)
+		case "nameserver":
+			ip := strings.Trim(val, "[]")
+			if net.ParseIP(ip) == nil {
+				err = fmt.Errorf("invalid nameserver %q", val)
+			}
+			c.DNS.Nameservers = append(c.DNS.Nameservers, ip)
+		case "rd.route":
+			err = c.parseRoute(val)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", a, err)
+		}
+		found = true
+	}
+	if !found {
+		return nil, nil
+	}
+	if _, err := c.Ordered(); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// splitFields splits s at colons outside brackets and removes the
+// brackets around IPv6 addresses.
+func splitFields(s string) ([]string, error) {
+	var f []string
+	var cur strings.Builder
+	bracket := false
+	for _, r := range s {
+		switch {
+		case r == '[' && !bracket:
+			bracket = true
+		case r == ']' && bracket:
+			bracket = false
+		case r == ':' && !bracket:
+			f = append(f, cur.String())
+			cur.Reset()
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	if bracket {
+		return nil, errors.New("unterminated [")
+	}
+	return append(f, cur.String()), nil
+}
+
+// link returns the link called name, adding it if needed.
+func (c *Config) link(name string) *Link {
+	for i := range c.Links {
+		if c.Links[i].Name == name {
+			return &c.Links[i]
+		}
+	}
+	c.Links = append(c.Links, Link{Name: name})
+	return &c.Links[len(c.Links)-1]
+}
+
+// autoconf applies an autoconfiguration method to l. It reports whether
+// the method was known.
+func autoconf(l *Link, method string) bool {
+	switch method {
+	case "on", "any", "dhcp", "bootp", "rarp", "both":
+		l.DHCP4 = true
+	case "dhcp6", "either6":
+		l.DHCP6 = true
+	case "auto6", "link6", "off", "none", "":
+	default:
+		return false
+	}
+	return true
+}
+
+func (c *Config) parseIP(val string) error {
+	f, err := splitFields(val)
+	if err != nil {
+		return err
+	}
+	if len(f) == 1 {
+		if val == "off" || val == "none" {
+			return ErrOff
+		}
+		if !autoconf(c.link(""), val) {
+			return fmt.Errorf("unknown autoconfiguration method %q", val)
+		}
+		return nil
+	}
+
+	// ip=<interface>:<autoconf>[:[<mtu>][:<macaddr>]]
+	if f[0] != "" && f[1] != "" && net.ParseIP(f[0]) == nil {
+		l := c.link(f[0])
+		if !autoconf(l, f[1]) {
+			return fmt.Errorf("unknown autoconfiguration method %q", f[1])
+		}
+		return l.parseMTUMAC(f[2:])
+	}
+
+	f = append(f, make([]string, max(0, 10-len(f)))...)
+	client, gw, netmask, hostname, device, method := f[0], f[2], f[3], f[4], f[5], f[6]
+	if method == "ibft" {
+		return errors.New("iBFT configuration is not supported")
+	}
+	if method == "" && client == "" {
+		method = "any"
+	}
+	l := c.link(device)
+	if !autoconf(l, method) {
+		return fmt.Errorf("unknown autoconfiguration method %q", method)
+	}
+	if hostname != "" {
+		c.Hostname = hostname
+	}
+	if client != "" && !l.DHCP4 && !l.DHCP6 {
+		addr, err := staticAddress(client, netmask)
+		if err != nil {
+			return err
+		}
+		l.Addresses = append(l.Addresses, addr)
+		if gw != "" {
+			if net.ParseIP(gw) == nil {
+				return fmt.Errorf("invalid gateway %q", gw)
+			}
+			l.Routes = append(l.Routes, Route{To: "default", Via: gw})
+		}
+	}
+
+	// dracut puts an MTU and MAC where the kernel has name servers.
+	if _, err := strconv.Atoi(f[7]); err == nil || (f[7] == "" && f[8] != "" && net.ParseIP(f[8]) == nil) {
+		return l.parseMTUMAC(f[7:])
+	}
+	for _, ns := range f[7:9] {
+		if ns == "" {
+			continue
+		}
+		if net.ParseIP(ns) == nil {
+			return fmt.Errorf("invalid name server %q", ns)
+		}
+		c.DNS.Nameservers = append(c.DNS.Nameservers, ns)
+	}
+	return nil
+}
+
+// parseMTUMAC parses the optional [<mtu>][:<macaddr>] of dracut. MAC
+// addresses are written with colons, so they are split into more fields.
+func (l *Link) parseMTUMAC(f []string) error {
+	if len(f) > 0 && f[0] != "" {
+		mtu, err := strconv.Atoi(f[0])
+		if err != nil || mtu <= 0 {
+			return fmt.Errorf("invalid MTU %q", f[0])
+		}
+		l.MTU = mtu
+	}
+	if len(f) > 1 && f[1] != "" {
+		mac := strings.Join(f[1:], ":")
+		if _, err := net.ParseMAC(mac); err != nil {
+			return err
+		}
+		l.MAC = mac
+	}
+	return nil
+}
+
+// staticAddress returns client with netmask in CIDR notation. An IPv4
+// netmask is a dotted quad and defaults to that of the address class; an
+// IPv6 netmask is a prefix length and defaults to 64.
+func staticAddress(client, netmask string) (string, error) {
+	ip := net.ParseIP(client)
+	if ip == nil {
+		return "", fmt.Errorf("invalid client address %q", client)
+	}
+	ip4 := ip.To4()
+	if ip4 == nil {
+		bits := 64
+		if netmask != "" {
+			var err error
+			if bits, err = strconv.Atoi(netmask); err != nil || bits < 0 || bits > 128 {
+				return "", fmt.Errorf("invalid prefix length %q", netmask)
+			}
+		}
+		return fmt.Sprintf("%s/%d", ip, bits), nil
+	}
+	mask := ip4.DefaultMask()
+	if netmask != "" {
+		m := net.ParseIP(netmask).To4()
+		if m == nil {
+			return "", fmt.Errorf("invalid netmask %q", netmask)
+		}
+		mask = net.IPMask(m)
+	}
+	ones, bits := mask.Size()
+	if bits == 0 {
+		return "", fmt.Errorf("invalid netmask %q", netmask)
+	}
+	return fmt.Sprintf("%s/%d", ip4, ones), nil
+}
+
+// vlanID matches the VLAN names dracut understands: vlan0005, vlan5,
+// eth0.0005 and eth0.5.
+var vlanID = regexp.MustCompile(`(?:^vlan|\.)0*([0-9]+)$`)
+
+func (c *Config) parseVLAN(val string) error {
+	name, parent, ok := strings.Cut(val, ":")
+	if !ok || name == "" || parent == "" {
+		return errors.New("want vlan=<vlanname>:<phys>")
+	}
+	m := vlanID.FindStringSubmatch(name)
+	if m == nil {
+		return fmt.Errorf("no VLAN ID in %q", name)
+	}
+	id, _ := strconv.Atoi(m[1])
+	l := c.link(name)
+	l.Kind, l.Parent, l.VLANID = KindVLAN, parent, id
+	return nil
+}
+
+func (c *Config) parseBond(val string) error {
+	f := strings.Split(val, ":")
+	if val == "" {
+		// dracut's defaults.
+		f = []string{"bond0", "eth0,eth1"}
+	}
+	if len(f) > 4 || f[0] == "" {
+		return errors.New("want bond=<bondname>[:<members>[:<options>[:<mtu>]]]")
+	}
+	l := c.link(f[0])
+	l.Kind = KindBond
+	if len(f) > 1 && f[1] != "" {
+		l.Members = strings.Split(f[1], ",")
+	}
+	if len(f) > 2 {
+		for _, o := range strings.Split(f[2], ",") {
+			k, v, _ := strings.Cut(o, "=")
+			switch k {
+			case "":
+			case "mode":
+				l.BondMode = v
+			case "miimon":
+				n, err := strconv.Atoi(v)
+				if err != nil {
+					return fmt.Errorf("invalid miimon %q", v)
+				}
+				l.BondMiimon = n
+			default:
+				return fmt.Errorf("unsupported bond option %q", k)
+			}
+		}
+	}
+	if len(f) > 3 {
+		return l.parseMTUMAC(f[3:4])
+	}
+	return nil
+}
+
+func (c *Config) parseBridge(val string) error {
+	name, members, ok := strings.Cut(val, ":")
+	if val == "" {
+		name, members, ok = "br0", "eth0", true
+	}
+	if !ok || name == "" || members == "" {
+		return errors.New("want bridge=<bridgename>:<members>")
+	}
+	l := c.link(name)
+	l.Kind, l.Members = KindBridge, strings.Split(members, ",")
+	return nil
+}
+
+func (c *Config) parseRoute(val string) error {
+	f, err := splitFields(val)
+	if err != nil {
+		return err
+	}
+	if len(f) < 2 || len(f) > 3 {
+		return errors.New("want rd.route=<net>/<prefix>:<gateway>[:<interface>]")
+	}
+	r := Route{To: f[0], Via: f[1]}
+	if err := r.check(); err != nil {
+		return err
+	}
+	if len(f) == 3 && f[2] != "" {
+		l := c.link(f[2])
+		l.Routes = append(l.Routes, r)
+	} else {
+		c.Routes = append(c.Routes, r)
+	}
+	return nil
+}
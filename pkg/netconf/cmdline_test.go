@@ -0,0 +1,174 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package netconf
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseCmdline(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		args []string
+		want *Config
+	}{
+		{
+			name: "none",
+			args: []string{"console=ttyS0", "root=/dev/nfs"},
+		},
+		{
+			name: "kernel dhcp",
+			args: []string{"ip=dhcp"},
+			want: &Config{Links: []Link{{DHCP4: true}}},
+		},
+		{
+			name: "kernel bootp",
+			args: []string{"ip=bootp"},
+			want: &Config{Links: []Link{{DHCP4: true}}},
+		},
+		{
+			name: "kernel autoconf on a device",
+			args: []string{"ip=:::::eth1:dhcp"},
+			want: &Config{Links: []Link{{Name: "eth1", DHCP4: true}}},
+		},
+		{
+			name: "kernel autoconf by default",
+			args: []string{"ip=::::box::"},
+			want: &Config{Links: []Link{{DHCP4: true}}, Hostname: "box"},
+		},
+		{
+			name: "kernel static",
+			args: []string{"ip=10.0.2.15:10.0.2.2:10.0.2.2:255.255.255.0:box:eth0:off:10.0.2.3:8.8.8.8:10.0.2.4"},
+			want: &Config{
+				Links: []Link{{
+					Name:      "eth0",
+					Addresses: []string{"10.0.2.15/24"},
+					Routes:    []Route{{To: "default", Via: "10.0.2.2"}},
+				}},
+				DNS:      DNS{Nameservers: []string{"10.0.2.3", "8.8.8.8"}},
+				Hostname: "box",
+			},
+		},
+		{
+			name: "kernel static classful netmask",
+			args: []string{"ip=172.16.1.2::::::none"},
+			want: &Config{Links: []Link{{Addresses: []string{"172.16.1.2/16"}}}},
+		},
+		{
+			name: "kernel static with fewer fields",
+			args: []string{"ip=192.168.1.5::192.168.1.1:255.255.255.0"},
+			want: &Config{Links: []Link{{
+				Addresses: []string{"192.168.1.5/24"},
+				Routes:    []Route{{To: "default", Via: "192.168.1.1"}},
+			}}},
+		},
+		{
+			name: "dracut interface",
+			args: []string{"ip=eth0:dhcp6:9000:52:54:00:12:34:56"},
+			want: &Config{Links: []Link{{Name: "eth0", DHCP6: true, MTU: 9000, MAC: "52:54:00:12:34:56"}}},
+		},
+		{
+			name: "dracut both protocols",
+			args: []string{"ip=eth0:dhcp", "ip=eth0:dhcp6"},
+			want: &Config{Links: []Link{{Name: "eth0", DHCP4: true, DHCP6: true}}},
+		},
+		{
+			name: "dracut link only",
+			args: []string{"ip=eth0:link6"},
+			want: &Config{Links: []Link{{Name: "eth0"}}},
+		},
+		{
+			name: "dracut static IPv6 with MTU",
+			args: []string{"ip=[2001:db8::5]::[2001:db8::1]:64::eth0:none:1400"},
+			want: &Config{Links: []Link{{
+				Name:      "eth0",
+				MTU:       1400,
+				Addresses: []string{"2001:db8::5/64"},
+				Routes:    []Route{{To: "default", Via: "2001:db8::1"}},
+			}}},
+		},
+		{
+			name: "dracut static with IPv6 name server",
+			args: []string{"ip=10.0.0.5::10.0.0.1:255.0.0.0::eth0:off:[2001:db8::53]"},
+			want: &Config{
+				Links: []Link{{
+					Name:      "eth0",
+					Addresses: []string{"10.0.0.5/8"},
+					Routes:    []Route{{To: "default", Via: "10.0.0.1"}},
+				}},
+				DNS: DNS{Nameservers: []string{"2001:db8::53"}},
+			},
+		},
+		{
+			name: "dracut bond with VLAN",
+			args: []string{"bond=bond0:eth0,eth1:mode=active-backup,miimon=100:9000", "vlan=bond0.42:bond0", "ip=bond0.42:dhcp"},
+			want: &Config{Links: []Link{
+				{Name: "bond0", Kind: KindBond, Members: []string{"eth0", "eth1"}, BondMode: "active-backup", BondMiimon: 100, MTU: 9000},
+				{Name: "bond0.42", Kind: KindVLAN, Parent: "bond0", VLANID: 42, DHCP4: true},
+			}},
+		},
+		{
+			name: "dracut defaults",
+			args: []string{"bond", "bridge", "vlan=vlan0007:eth2"},
+			want: &Config{Links: []Link{
+				{Name: "bond0", Kind: KindBond, Members: []string{"eth0", "eth1"}},
+				{Name: "br0", Kind: KindBridge, Members: []string{"eth0"}},
+				{Name: "vlan0007", Kind: KindVLAN, Parent: "eth2", VLANID: 7},
+			}},
+		},
+		{
+			name: "dracut routes and name servers",
+			args: []string{"rd.route=10.1.0.0/16:10.0.0.1", "rd.route=[2001:db8:1::]/48:[2001:db8::1]:eth0", "nameserver=10.0.0.53", "nameserver=[2001:db8::53]"},
+			want: &Config{
+				Links:  []Link{{Name: "eth0", Routes: []Route{{To: "2001:db8:1::/48", Via: "2001:db8::1"}}}},
+				Routes: []Route{{To: "10.1.0.0/16", Via: "10.0.0.1"}},
+				DNS:    DNS{Nameservers: []string{"10.0.0.53", "2001:db8::53"}},
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseCmdline(tt.args)
+			if err != nil {
+				t.Fatalf("ParseCmdline(%q) = %v", tt.args, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseCmdline(%q) =\n%+v, want\n%+v", tt.args, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCmdlineErrors(t *testing.T) {
+	for _, args := range [][]string{
+		{"ip=sometimes"},
+		{"ip=eth0:maybe"},
+		{"ip=10.0.0.300::::::off"},
+		{"ip=10.0.0.3:::255.0.255.0:::off"},
+		{"ip=[2001:db8::5::::eth0:none"},
+		{"ip=10.0.0.5::::::ibft"},
+		{"ip=eth0:dhcp:big"},
+		{"ip=eth0:dhcp::52:54"},
+		{"vlan=eth0"},
+		{"vlan=trunk:eth0"},
+		{"bond=bond0:eth0:lacp_rate=fast"},
+		{"bond=bond0:eth0:mode=fastest"},
+		{"bridge=br0"},
+		{"nameserver=dns.example.com"},
+		{"rd.route=10.0.0.0/8"},
+		{"rd.route=10.0.0.0:10.0.0.1"},
+		{"bridge=br0:eth0", "bridge=br1:br0", "bridge=br0:br1"},
+	} {
+		if c, err := ParseCmdline(args); err == nil {
+			t.Errorf("ParseCmdline(%q) = %+v, want an error", args, c)
+		}
+	}
+	for _, arg := range []string{"ip=off", "ip=none"} {
+		if _, err := ParseCmdline([]string{arg}); !errors.Is(err, ErrOff) {
+			t.Errorf("ParseCmdline(%s) = %v, want %v", arg, err, ErrOff)
+		}
+	}
+}
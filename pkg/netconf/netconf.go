@@ -0,0 +1,408 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package netconf describes a network configuration declaratively and
+// applies it.
+//
+// A Config lists links, including bonds, bridges and VLANs, with their
+// static addresses and routes and whether they use DHCPv4 or DHCPv6, and
+// the DNS settings. It is read from JSON or from the kernel command line,
+// which may hold the ip= parameter of the kernel and the ip=, vlan=, bond=,
+// bridge=, nameserver= and rd.route= parameters of dracut.
+package netconf
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/u-root/u-root/pkg/curl"
+)
+
+// DefaultWaitOnline is how long Apply waits for links to come online by
+// default.
+const DefaultWaitOnline = 30 * time.Second
+
+// Link kinds.
+const (
+	KindDevice = ""
+	KindBond   = "bond"
+	KindBridge = "bridge"
+	KindVLAN   = "vlan"
+	KindDummy  = "dummy"
+)
+
+// Config is a network configuration.
+type Config struct {
+	// Links are configured in dependency order: VLANs after their
+	// parents, and bond and bridge members after their masters.
+	Links []Link `json:"links,omitempty"`
+
+	// Routes are routes that do not belong to one link.
+	Routes []Route `json:"routes,omitempty"`
+
+	// DNS replaces resolv.conf, after DHCP has written it.
+	DNS DNS `json:"dns,omitzero"`
+
+	// Hostname is set if it is not empty.
+	Hostname string `json:"hostname,omitempty"`
+
+	// WaitOnline is how long to wait for links that are not optional to
+	// have a carrier and their DHCP leases. It defaults to
+	// DefaultWaitOnline.
+	WaitOnline Duration `json:"wait_online,omitempty"`
+}
+
+// Link is a network interface.
+type Link struct {
+	// Name is the name of the interface. An empty Name stands for every
+	// network device, as it does in the ip= kernel parameter: all of
+	// them use DHCP, or the first one gets the static addresses.
+	Name string `json:"name"`
+
+	// Kind is KindDevice for an existing device, or the kind of virtual
+	// link to create.
+	Kind string `json:"kind,omitempty"`
+
+	// MAC is the hardware address to set.
+	MAC string `json:"mac,omitempty"`
+
+	// MTU is the MTU to set.
+	MTU int `json:"mtu,omitempty"`
+
+	// Parent is the link a VLAN is on, and VLANID its ID.
+	Parent string `json:"parent,omitempty"`
+	VLANID int    `json:"vlan_id,omitempty"`
+
+	// Members are the links enslaved to a bond or bridge.
+	Members []string `json:"members,omitempty"`
+
+	// BondMode is the mode of a bond, such as active-backup or 802.3ad.
+	// It defaults to balance-rr.
+	BondMode string `json:"bond_mode,omitempty"`
+
+	// BondMiimon is the MII monitoring interval of a bond, in ms.
+	BondMiimon int `json:"bond_miimon,omitempty"`
+
+	// Addresses are static addresses in CIDR notation.
+	Addresses []string `json:"addresses,omitempty"`
+
+	// Routes are static routes through the link.
+	Routes []Route `json:"routes,omitempty"`
+
+	// DHCP4 and DHCP6 request leases on the link.
+	DHCP4 bool `json:"dhcp4,omitempty"`
+	DHCP6 bool `json:"dhcp6,omitempty"`
+
+	// Optional links do not hold up Apply and their failures are only
+	// logged.
+	Optional bool `json:"optional,omitempty"`
+}
+
+// Route is a static route.
+type Route struct {
+	// To is the destination in CIDR notation, or "default".
+	To string `json:"to"`
+
+	// Via is the gateway.
+	Via string `json:"via,omitempty"`
+
+	// Metric is the route priority.
+	Metric int `json:"metric,omitempty"`
+}
+
+// DNS is a resolver configuration.
+type DNS struct {
+	Nameservers []string `json:"nameservers,omitempty"`
+	Search      []string `json:"search,omitempty"`
+	Domain      string   `json:"domain,omitempty"`
+}
+
+// Duration is a time.Duration that is a string such as "30s" in JSON.
+type Duration time.Duration
+
+// MarshalJSON implements json.Marshaler.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"30s\": %w", err)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// Parse reads a JSON Config and checks it.
+func Parse(r io.Reader) (*Config, error) {
+	d := json.NewDecoder(r)
+	d.DisallowUnknownFields()
+	var c Config
+	if err := d.Decode(&c); err != nil {
+		return nil, fmt.Errorf("parsing network configuration: %w", err)
+	}
+	if _, err := c.Ordered(); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// Load reads a JSON Config from a file.
+func Load(path string) (*Config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(bytes.NewReader(b))
+}
+
+// Fetch reads a JSON Config from a URL of a scheme curl supports.
+func Fetch(ctx context.Context, rawURL string) (*Config, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	f, err := curl.FetchWithoutCache(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+	c, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", rawURL, err)
+	}
+	return c, nil
+}
+
+// Merge adds the configuration in o to c. Links of the same name are
+// merged; the settings of o win.
+func (c *Config) Merge(o *Config) {
+	for _, l := range o.Links {
+		i := slices.IndexFunc(c.Links, func(m Link) bool { return m.Name == l.Name })
+		if i < 0 {
+			c.Links = append(c.Links, l)
+			continue
+		}
+		c.Links[i].merge(l)
+	}
+	c.Routes = append(c.Routes, o.Routes...)
+	c.DNS.Nameservers = append(c.DNS.Nameservers, o.DNS.Nameservers...)
+	c.DNS.Search = append(c.DNS.Search, o.DNS.Search...)
+	if o.DNS.Domain != "" {
+		c.DNS.Domain = o.DNS.Domain
+	}
+	if o.Hostname != "" {
+		c.Hostname = o.Hostname
+	}
+	if o.WaitOnline != 0 {
+		c.WaitOnline = o.WaitOnline
+	}
+}
+
+func (l *Link) merge(o Link) {
+	if o.Kind != KindDevice {
+		l.Kind = o.Kind
+	}
+	if o.MAC != "" {
+		l.MAC = o.MAC
+	}
+	if o.MTU != 0 {
+		l.MTU = o.MTU
+	}
+	if o.Parent != "" {
+		l.Parent, l.VLANID = o.Parent, o.VLANID
+	}
+	if o.Members != nil {
+		l.Members = o.Members
+	}
+	if o.BondMode != "" {
+		l.BondMode = o.BondMode
+	}
+	if o.BondMiimon != 0 {
+		l.BondMiimon = o.BondMiimon
+	}
+	l.Addresses = append(l.Addresses, o.Addresses...)
+	l.Routes = append(l.Routes, o.Routes...)
+	l.DHCP4 = l.DHCP4 || o.DHCP4
+	l.DHCP6 = l.DHCP6 || o.DHCP6
+	l.Optional = l.Optional && o.Optional
+}
+
+// Ordered checks c and returns its links in the order they must be set up
+// in. Links that c refers to without declaring them, such as the parent of
+// a VLAN, are optional existing devices that come first.
+func (c *Config) Ordered() ([]Link, error) {
+	byName := make(map[string]*Link)
+	var names []string
+	add := func(l Link) {
+		byName[l.Name] = &l
+		names = append(names, l.Name)
+	}
+	for _, l := range c.Links {
+		if _, ok := byName[l.Name]; ok {
+			return nil, fmt.Errorf("link %q is declared twice", l.Name)
+		}
+		if err := l.check(); err != nil {
+			return nil, err
+		}
+		add(l)
+	}
+	for _, r := range c.Routes {
+		if err := r.check(); err != nil {
+			return nil, err
+		}
+	}
+	for _, ns := range c.DNS.Nameservers {
+		if net.ParseIP(ns) == nil {
+			return nil, fmt.Errorf("invalid nameserver %q", ns)
+		}
+	}
+
+	// deps maps a link to the links that must be set up before it.
+	deps := make(map[string][]string)
+	ensure := func(n string) {
+		if _, ok := byName[n]; !ok {
+			add(Link{Name: n, Optional: true})
+		}
+	}
+	for _, l := range c.Links {
+		if l.Parent != "" {
+			deps[l.Name] = append(deps[l.Name], l.Parent)
+			ensure(l.Parent)
+		}
+		for _, m := range l.Members {
+			deps[m] = append(deps[m], l.Name)
+			ensure(m)
+		}
+	}
+
+	// Depth-first topological sort, keeping the declared order where
+	// dependencies allow.
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int)
+	var ordered []Link
+	var visit func(n string, path []string) error
+	visit = func(n string, path []string) error {
+		switch state[n] {
+		case visiting:
+			return fmt.Errorf("links depend on each other: %s", strings.Join(append(path, n), " -> "))
+		case done:
+			return nil
+		}
+		state[n] = visiting
+		ds := slices.Clone(deps[n])
+		slices.SortStableFunc(ds, func(a, b string) int {
+			return slices.Index(names, a) - slices.Index(names, b)
+		})
+		for _, d := range ds {
+			if err := visit(d, append(path, n)); err != nil {
+				return err
+			}
+		}
+		state[n] = done
+		ordered = append(ordered, *byName[n])
+		return nil
+	}
+	// Undeclared links first, so that devices come before what is
+	// built on them.
+	declared := len(c.Links)
+	for _, n := range append(names[declared:], names[:declared]...) {
+		if err := visit(n, nil); err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
+
+func (l *Link) check() error {
+	if l.Name == "" && (l.Kind != KindDevice || len(l.Members) > 0 || l.Parent != "") {
+		return errors.New("only devices may have no name")
+	}
+	switch l.Kind {
+	case KindDevice, KindDummy, KindBridge:
+	case KindBond:
+		if l.BondMode != "" && !validBondMode(l.BondMode) {
+			return fmt.Errorf("link %q: unknown bond mode %q", l.Name, l.BondMode)
+		}
+	case KindVLAN:
+		if l.Parent == "" || l.VLANID < 1 || l.VLANID > 4094 {
+			return fmt.Errorf("link %q: a VLAN needs a parent and an ID from 1 to 4094", l.Name)
+		}
+	default:
+		return fmt.Errorf("link %q: unknown kind %q", l.Name, l.Kind)
+	}
+	if len(l.Members) > 0 && l.Kind != KindBond && l.Kind != KindBridge {
+		return fmt.Errorf("link %q: only bonds and bridges have members", l.Name)
+	}
+	if l.Kind != KindVLAN && l.Parent != "" {
+		return fmt.Errorf("link %q: only VLANs have a parent", l.Name)
+	}
+	if l.MAC != "" {
+		if _, err := net.ParseMAC(l.MAC); err != nil {
+			return fmt.Errorf("link %q: %w", l.Name, err)
+		}
+	}
+	for _, a := range l.Addresses {
+		if _, _, err := net.ParseCIDR(a); err != nil {
+			return fmt.Errorf("link %q: %w", l.Name, err)
+		}
+	}
+	for _, r := range l.Routes {
+		if err := r.check(); err != nil {
+			return fmt.Errorf("link %q: %w", l.Name, err)
+		}
+	}
+	return nil
+}
+
+func (r *Route) check() error {
+	if _, err := r.dst(); err != nil {
+		return err
+	}
+	if r.Via != "" && net.ParseIP(r.Via) == nil {
+		return fmt.Errorf("route to %s: invalid gateway %q", r.To, r.Via)
+	}
+	return nil
+}
+
+// dst returns the destination of r, which is nil for a default route.
+func (r *Route) dst() (*net.IPNet, error) {
+	if r.To == "default" {
+		return nil, nil
+	}
+	_, n, err := net.ParseCIDR(r.To)
+	if err != nil {
+		return nil, fmt.Errorf("invalid route destination %q", r.To)
+	}
+	return n, nil
+}
+
+var bondModes = []string{"balance-rr", "active-backup", "balance-xor", "broadcast", "802.3ad", "balance-tlb", "balance-alb"}
+
+func validBondMode(m string) bool {
+	if n, err := strconv.Atoi(m); err == nil {
+		return n >= 0 && n < len(bondModes)
+	}
+	return slices.Contains(bondModes, m)
+}
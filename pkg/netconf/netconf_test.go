@@ -0,0 +1,116 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package netconf
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testConfig = `{
+	"links": [
+		{"name": "mgmt", "kind": "vlan", "parent": "bond0", "vlan_id": 10, "addresses": ["192.0.2.10/24"], "routes": [{"to": "default", "via": "192.0.2.1"}]},
+		{"name": "bond0", "kind": "bond", "members": ["eth0", "eth1"], "bond_mode": "802.3ad", "mtu": 9000},
+		{"name": "br0", "kind": "bridge", "members": ["eth2"], "dhcp4": true, "dhcp6": true, "optional": true}
+	],
+	"routes": [{"to": "198.51.100.0/24", "via": "192.0.2.254", "metric": 10}],
+	"dns": {"nameservers": ["192.0.2.53"], "search": ["example.com"]},
+	"hostname": "box",
+	"wait_online": "1m"
+}`
+
+func TestParse(t *testing.T) {
+	c, err := Parse(strings.NewReader(testConfig))
+	if err != nil {
+		t.Fatalf("Parse = %v", err)
+	}
+	if c.WaitOnline != Duration(time.Minute) || c.Hostname != "box" || c.DNS.Search[0] != "example.com" {
+		t.Errorf("Parse = %+v", c)
+	}
+	links, err := c.Ordered()
+	if err != nil {
+		t.Fatalf("Ordered = %v", err)
+	}
+	var names []string
+	for _, l := range links {
+		names = append(names, l.Name)
+	}
+	if want := []string{"bond0", "eth0", "eth1", "br0", "eth2", "mgmt"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("Ordered = %q, want %q", names, want)
+	}
+	for _, l := range links {
+		if strings.HasPrefix(l.Name, "eth") && !l.Optional {
+			t.Errorf("undeclared link %s is not optional", l.Name)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "netconf.json")
+	if err := os.WriteFile(path, []byte(testConfig), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	l, err := Load(path)
+	if err != nil || !reflect.DeepEqual(l, c) {
+		t.Errorf("Load = %+v, %v, want %+v", l, err, c)
+	}
+	f, err := Fetch(context.Background(), "file://"+path)
+	if err != nil || !reflect.DeepEqual(f, c) {
+		t.Errorf("Fetch = %+v, %v, want %+v", f, err, c)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, s := range []string{
+		`{"links": [{"name": "eth0", "speed": 100}]}`,
+		`{"links": [{"name": "eth0"}, {"name": "eth0"}]}`,
+		`{"links": [{"name": "x", "kind": "wireguard"}]}`,
+		`{"links": [{"name": "v", "kind": "vlan", "parent": "eth0"}]}`,
+		`{"links": [{"name": "v", "kind": "vlan", "parent": "eth0", "vlan_id": 4095}]}`,
+		`{"links": [{"name": "eth0", "members": ["eth1"]}]}`,
+		`{"links": [{"name": "eth0", "parent": "eth1"}]}`,
+		`{"links": [{"kind": "bond"}]}`,
+		`{"links": [{"name": "b", "kind": "bond", "bond_mode": "fast"}]}`,
+		`{"links": [{"name": "eth0", "mac": "00:11"}]}`,
+		`{"links": [{"name": "eth0", "addresses": ["10.0.0.1"]}]}`,
+		`{"links": [{"name": "eth0", "routes": [{"to": "10.0.0.0/8", "via": "gateway"}]}]}`,
+		`{"links": [{"name": "a", "kind": "bridge", "members": ["b"]}, {"name": "b", "kind": "bridge", "members": ["a"]}]}`,
+		`{"routes": [{"to": "somewhere"}]}`,
+		`{"dns": {"nameservers": ["ns.example.com"]}}`,
+		`{"wait_online": 30}`,
+	} {
+		if c, err := Parse(strings.NewReader(s)); err == nil {
+			t.Errorf("Parse(%s) = %+v, want an error", s, c)
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	c := &Config{
+		Links: []Link{{Name: "eth0", Addresses: []string{"10.0.0.2/8"}, Optional: true}},
+		DNS:   DNS{Nameservers: []string{"10.0.0.53"}},
+	}
+	c.Merge(&Config{
+		Links:    []Link{{Name: "eth0", DHCP6: true, MTU: 1400}, {Name: "eth1", DHCP4: true}},
+		Routes:   []Route{{To: "default", Via: "10.0.0.1"}},
+		DNS:      DNS{Nameservers: []string{"10.0.0.54"}, Domain: "example.com"},
+		Hostname: "box",
+	})
+	want := &Config{
+		Links: []Link{
+			{Name: "eth0", Addresses: []string{"10.0.0.2/8"}, MTU: 1400, DHCP6: true},
+			{Name: "eth1", DHCP4: true},
+		},
+		Routes:   []Route{{To: "default", Via: "10.0.0.1"}},
+		DNS:      DNS{Nameservers: []string{"10.0.0.53", "10.0.0.54"}, Domain: "example.com"},
+		Hostname: "box",
+	}
+	if !reflect.DeepEqual(c, want) {
+		t.Errorf("Merge =\n%+v, want\n%+v", c, want)
+	}
+}
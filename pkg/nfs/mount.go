@@ -0,0 +1,82 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Programs, versions and procedures of the portmapper (RFC 1833) and of the
+// MOUNT protocol (RFC 1813, appendix I).
+const (
+	pmapProg    = 100000
+	pmapVers    = 2
+	pmapGetport = 3
+
+	ipprotoTCP = 6
+	ipprotoUDP = 17
+
+	mountProg = 100005
+	mountVers = 3
+	mountMnt  = 1
+	mountUmnt = 3
+
+	// maxPath is MNTPATHLEN.
+	maxPath = 1024
+)
+
+var errNotRegistered = errors.New("program not registered with the portmapper")
+
+// getPort asks the portmapper of c for the port of version vers of program
+// prog over proto.
+func getPort(ctx context.Context, c *rpcConn, prog, vers, proto uint32) (int, error) {
+	var e encoder
+	e.uint32(prog)
+	e.uint32(vers)
+	e.uint32(proto)
+	e.uint32(0)
+	d, err := c.call(ctx, pmapProg, pmapVers, pmapGetport, e.b)
+	if err != nil {
+		return 0, err
+	}
+	port := d.uint32()
+	if d.err != nil {
+		return 0, d.err
+	}
+	if port == 0 || port > 65535 {
+		return 0, fmt.Errorf("program %d version %d: %w", prog, vers, errNotRegistered)
+	}
+	return int(port), nil
+}
+
+// mnt mounts export, and returns the file handle of its root.
+func mnt(ctx context.Context, c *rpcConn, export string) ([]byte, error) {
+	var e encoder
+	e.string(export)
+	d, err := c.call(ctx, mountProg, mountVers, mountMnt, e.b)
+	if err != nil {
+		return nil, err
+	}
+	if stat := d.uint32(); d.err == nil && stat != 0 {
+		return nil, fmt.Errorf("mount %s: %w", export, Error(stat))
+	}
+	fh := d.opaque(fhSize)
+	// The auth flavors follow, and AUTH_SYS works with all servers
+	// that are worth it.
+	if d.err != nil {
+		return nil, d.err
+	}
+	return fh, nil
+}
+
+// umnt tells the server that export is no longer mounted.
+func umnt(ctx context.Context, c *rpcConn, export string) error {
+	var e encoder
+	e.string(export)
+	_, err := c.call(ctx, mountProg, mountVers, mountUmnt, e.b)
+	return err
+}
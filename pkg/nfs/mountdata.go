@@ -0,0 +1,258 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nfs
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// Flags of struct nfs_mount_data of Linux (include/uapi/linux/nfs_mount.h).
+const (
+	mountSoft       = 0x0001
+	mountIntr       = 0x0002
+	mountNoCTO      = 0x0010
+	mountNoAC       = 0x0020
+	mountTCP        = 0x0040
+	mountVer3       = 0x0080
+	mountNonLM      = 0x0200
+	mountNoACL      = 0x0800
+	mountNoRdirplus = 0x4000
+
+	// mountDataVersion is NFS_MOUNT_VERSION.
+	mountDataVersion = 6
+	// mountDataSize is the size of struct nfs_mount_data version 6.
+	mountDataSize = 688
+	// maxHostname is NFS_MAXNAMLEN.
+	maxHostname = 255
+)
+
+var (
+	errSource    = errors.New("NFS source must be host:/export")
+	errOption    = errors.New("unknown NFS mount option")
+	errVersion   = errors.New("only NFS version 3 is supported")
+	errNoIPv4    = errors.New("the kernel NFS mount data takes IPv4 addresses only")
+	errBadHandle = errors.New("bad root file handle")
+)
+
+// MountOptions are the options of a Linux kernel NFSv3 mount, as
+// ParseMountOptions takes them from mount -o.
+type MountOptions struct {
+	// Port and MountPort are the NFS and MOUNT ports, from the
+	// portmapper if they are zero.
+	Port      int
+	MountPort int
+
+	Rsize, Wsize int
+	// Timeo is the retransmission timeout in tenths of a second.
+	Timeo   int
+	Retrans int
+	// Acregmin, Acregmax, Acdirmin and Acdirmax are the attribute
+	// cache times in seconds.
+	Acregmin, Acregmax int
+	Acdirmin, Acdirmax int
+
+	Soft, Intr bool
+	// Lock enables NLM locking, which needs rpc.statd.
+	Lock              bool
+	NoAC, NoCTO       bool
+	NoACL, NoRdirplus bool
+	UDP               bool
+}
+
+// DefaultMountOptions returns the defaults of mount.nfs, without locking.
+func DefaultMountOptions() *MountOptions {
+	return &MountOptions{
+		Timeo:    600,
+		Retrans:  2,
+		Acregmin: 3,
+		Acregmax: 60,
+		Acdirmin: 30,
+		Acdirmax: 60,
+	}
+}
+
+// ParseMountOptions parses the NFS options of mount -o over the defaults.
+func ParseMountOptions(opts []string) (*MountOptions, error) {
+	o := DefaultMountOptions()
+	ints := map[string]*int{
+		"port":      &o.Port,
+		"mountport": &o.MountPort,
+		"rsize":     &o.Rsize,
+		"wsize":     &o.Wsize,
+		"timeo":     &o.Timeo,
+		"retrans":   &o.Retrans,
+		"acregmin":  &o.Acregmin,
+		"acregmax":  &o.Acregmax,
+		"acdirmin":  &o.Acdirmin,
+		"acdirmax":  &o.Acdirmax,
+	}
+	bools := map[string]struct {
+		v   *bool
+		set bool
+	}{
+		"soft":       {&o.Soft, true},
+		"hard":       {&o.Soft, false},
+		"intr":       {&o.Intr, true},
+		"nointr":     {&o.Intr, false},
+		"lock":       {&o.Lock, true},
+		"nolock":     {&o.Lock, false},
+		"ac":         {&o.NoAC, false},
+		"noac":       {&o.NoAC, true},
+		"cto":        {&o.NoCTO, false},
+		"nocto":      {&o.NoCTO, true},
+		"acl":        {&o.NoACL, false},
+		"noacl":      {&o.NoACL, true},
+		"rdirplus":   {&o.NoRdirplus, false},
+		"nordirplus": {&o.NoRdirplus, true},
+		"tcp":        {&o.UDP, false},
+		"udp":        {&o.UDP, true},
+	}
+
+	for _, opt := range opts {
+		key, val, hasVal := strings.Cut(opt, "=")
+		if b, ok := bools[key]; ok && !hasVal {
+			*b.v = b.set
+			continue
+		}
+		if p, ok := ints[key]; ok && hasVal {
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("%q: %w", opt, errOption)
+			}
+			*p = n
+			continue
+		}
+		switch {
+		case key == "actimeo" && hasVal:
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("%q: %w", opt, errOption)
+			}
+			o.Acregmin, o.Acregmax, o.Acdirmin, o.Acdirmax = n, n, n, n
+		case (key == "vers" || key == "nfsvers") && hasVal:
+			if val != "3" {
+				return nil, fmt.Errorf("%q: %w", opt, errVersion)
+			}
+		case key == "proto" && (val == "tcp" || val == "udp"):
+			o.UDP = val == "udp"
+		case key == "mountproto" && val == "tcp":
+			// The MOUNT handshake is over TCP anyway.
+		case key == "sec" && val == "sys":
+		default:
+			return nil, fmt.Errorf("%q: %w", opt, errOption)
+		}
+	}
+	return o, nil
+}
+
+// SplitSource splits the host:/export source of an NFS mount.
+func SplitSource(source string) (host, export string, err error) {
+	host, export, ok := strings.Cut(source, ":/")
+	if !ok || host == "" {
+		return "", "", fmt.Errorf("%q: %w", source, errSource)
+	}
+	return host, "/" + export, nil
+}
+
+// KernelMountData performs the MOUNT handshake for export of host, and
+// returns the struct nfs_mount_data that the Linux kernel mounts the export
+// with. Its fields are in the byte order of the host.
+func (d *Dialer) KernelMountData(ctx context.Context, host, export string, o *MountOptions) ([]byte, error) {
+	if len(host) > maxHostname {
+		return nil, fmt.Errorf("%q: %w", host, errSource)
+	}
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip4", host)
+	if err != nil {
+		return nil, err
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("%s: %w", host, errNoIPv4)
+	}
+	ip := addrs[0].Unmap()
+
+	proto := uint32(ipprotoTCP)
+	if o.UDP {
+		proto = ipprotoUDP
+	}
+	dd := *d
+	if o.Port != 0 {
+		dd.Port = o.Port
+	}
+	if o.MountPort != 0 {
+		dd.MountPort = o.MountPort
+	}
+	mountPort, nfsPort, err := dd.ports(ctx, ip.String(), proto)
+	if err != nil {
+		return nil, err
+	}
+	mc, err := dialRPC(ctx, net.JoinHostPort(ip.String(), strconv.Itoa(mountPort)), dd.cred(), dd.timeout())
+	if err != nil {
+		return nil, fmt.Errorf("mountd: %w", err)
+	}
+	defer mc.Close()
+	// The mount stays registered with the server for as long as the
+	// kernel has it mounted.
+	fh, err := mnt(ctx, mc, export)
+	if err != nil {
+		return nil, err
+	}
+	return mountData(host, netip.AddrPortFrom(ip, uint16(nfsPort)), fh, o)
+}
+
+// mountData encodes struct nfs_mount_data version 6.
+func mountData(host string, addr netip.AddrPort, fh []byte, o *MountOptions) ([]byte, error) {
+	if len(fh) == 0 || len(fh) > fhSize {
+		return nil, fmt.Errorf("%w of %d bytes", errBadHandle, len(fh))
+	}
+	if !addr.Addr().Is4() {
+		return nil, errNoIPv4
+	}
+
+	flags := mountVer3
+	for _, f := range []struct {
+		set  bool
+		flag int
+	}{
+		{o.Soft, mountSoft},
+		{o.Intr, mountIntr},
+		{!o.Lock, mountNonLM},
+		{o.NoAC, mountNoAC},
+		{o.NoCTO, mountNoCTO},
+		{o.NoACL, mountNoACL},
+		{o.NoRdirplus, mountNoRdirplus},
+		{!o.UDP, mountTCP},
+	} {
+		if f.set {
+			flags |= f.flag
+		}
+	}
+
+	b := make([]byte, mountDataSize)
+	ne := binary.NativeEndian
+	ne.PutUint32(b[0:], mountDataVersion)
+	// fd is unused, and old_root holds the file handles of NFSv2.
+	for i, v := range []int{flags, o.Rsize, o.Wsize, o.Timeo, o.Retrans, o.Acregmin, o.Acregmax, o.Acdirmin, o.Acdirmax} {
+		ne.PutUint32(b[40+4*i:], uint32(v))
+	}
+	// struct sockaddr_in
+	ne.PutUint16(b[76:], 2) // AF_INET
+	binary.BigEndian.PutUint16(b[78:], addr.Port())
+	ip := addr.Addr().As4()
+	copy(b[80:], ip[:])
+	copy(b[92:92+maxHostname], host)
+	// namlen and bsize are 0, for the server to tell.
+	// struct nfs3_fh
+	ne.PutUint16(b[356:], uint16(len(fh)))
+	copy(b[358:], fh)
+	// pseudoflavor and context are unused without NFS_MOUNT_SECFLAVOUR.
+	return b, nil
+}
@@ -0,0 +1,524 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package nfs implements a read-only NFSv3 client (RFC 1813) in userspace,
+// over ONC RPC on TCP, to fetch files without kernel NFS support. It also
+// performs the MOUNT handshake of kernel NFS mounts.
+package nfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Program, version and procedures of NFSv3.
+const (
+	nfsProg = 100003
+	nfsVers = 3
+
+	procGetattr  = 1
+	procLookup   = 3
+	procReadlink = 5
+	procRead     = 6
+	procFsinfo   = 19
+
+	// fhSize is NFS3_FHSIZE.
+	fhSize = 64
+	// maxName bounds names and symbolic link targets.
+	maxName = 4096
+	// maxRead bounds the size of READ calls. Servers cap it further.
+	maxRead = 1 << 20
+	// maxLinks is how many symbolic links a name may lead through.
+	maxLinks = 40
+
+	// DefaultPort is the NFS port of servers without a portmapper
+	// registration.
+	DefaultPort = 2049
+	// DefaultTimeout is the default timeout of each call.
+	DefaultTimeout = 30 * time.Second
+)
+
+// File types of fattr3.
+const (
+	typeReg = 1
+	typeDir = 2
+	typeLnk = 5
+)
+
+// Error is an NFSv3 or MOUNTv3 status other than OK. It matches the fs
+// errors of the same meaning.
+type Error uint32
+
+var errorNames = map[Error]string{
+	1:     "not owner",
+	2:     "no such file or directory",
+	5:     "I/O error",
+	6:     "no such device or address",
+	13:    "permission denied",
+	17:    "file exists",
+	19:    "no such device",
+	20:    "not a directory",
+	21:    "is a directory",
+	22:    "invalid argument",
+	27:    "file too large",
+	28:    "no space left on device",
+	30:    "read-only file system",
+	63:    "name too long",
+	70:    "stale file handle",
+	10001: "bad file handle",
+	10004: "operation not supported",
+	10006: "server fault",
+	10008: "server busy, try again later",
+}
+
+func (e Error) Error() string {
+	if s, ok := errorNames[e]; ok {
+		return s
+	}
+	return "NFS error " + strconv.FormatUint(uint64(e), 10)
+}
+
+// Is implements errors.Is.
+func (e Error) Is(target error) bool {
+	switch target {
+	case fs.ErrNotExist:
+		return e == 2
+	case fs.ErrPermission:
+		return e == 1 || e == 13
+	case fs.ErrExist:
+		return e == 17
+	}
+	return false
+}
+
+var (
+	errLinks      = errors.New("too many levels of symbolic links")
+	errNotRegular = errors.New("not a regular file")
+)
+
+// attr is the part of fattr3 that the client needs.
+type attr struct {
+	typ  uint32
+	size uint64
+}
+
+// decodeAttr decodes fattr3.
+func decodeAttr(d *decoder) attr {
+	a := attr{typ: d.uint32()}
+	// mode, nlink, uid, gid
+	d.next(16)
+	a.size = d.uint64()
+	// used, rdev, fsid, fileid, atime, mtime, ctime
+	d.next(8 + 8 + 8 + 8 + 3*8)
+	return a
+}
+
+// decodePostOpAttr decodes post_op_attr.
+func decodePostOpAttr(d *decoder) *attr {
+	if !d.bool() {
+		return nil
+	}
+	a := decodeAttr(d)
+	return &a
+}
+
+// status decodes nfsstat3.
+func status(d *decoder) error {
+	stat := d.uint32()
+	if d.err != nil {
+		return d.err
+	}
+	if stat != 0 {
+		return Error(stat)
+	}
+	return nil
+}
+
+// Dialer mounts NFSv3 exports.
+type Dialer struct {
+	// PortmapPort is the port of the portmapper, 111 if it is zero.
+	PortmapPort int
+	// Port is the NFS port. If it is zero, the portmapper tells, or
+	// DefaultPort if it does not know.
+	Port int
+	// MountPort is the port of the MOUNT protocol. If it is zero, the
+	// portmapper tells.
+	MountPort int
+
+	// UID and GID are the AUTH_SYS credentials of the calls. Most
+	// servers map UID 0 to an anonymous user.
+	UID, GID uint32
+
+	// Timeout is the timeout of each call, DefaultTimeout if it is zero.
+	Timeout time.Duration
+}
+
+func (d *Dialer) timeout() time.Duration {
+	if d.Timeout > 0 {
+		return d.Timeout
+	}
+	return DefaultTimeout
+}
+
+func (d *Dialer) cred() []byte {
+	machine, _ := os.Hostname()
+	return authSysCred(machine, d.UID, d.GID)
+}
+
+// ports returns the MOUNT and NFS ports of host for proto, from the
+// portmapper if they are not set.
+func (d *Dialer) ports(ctx context.Context, host string, proto uint32) (mountPort, nfsPort int, err error) {
+	mountPort, nfsPort = d.MountPort, d.Port
+	if mountPort != 0 && nfsPort != 0 {
+		return mountPort, nfsPort, nil
+	}
+	pmapPort := d.PortmapPort
+	if pmapPort == 0 {
+		pmapPort = 111
+	}
+	pc, err := dialRPC(ctx, net.JoinHostPort(host, strconv.Itoa(pmapPort)), nil, d.timeout())
+	if err != nil {
+		return 0, 0, fmt.Errorf("portmapper: %w", err)
+	}
+	defer pc.Close()
+	if mountPort == 0 {
+		if mountPort, err = getPort(ctx, pc, mountProg, mountVers, ipprotoTCP); err != nil {
+			return 0, 0, fmt.Errorf("portmapper: %w", err)
+		}
+	}
+	if nfsPort == 0 {
+		if nfsPort, err = getPort(ctx, pc, nfsProg, nfsVers, proto); errors.Is(err, errNotRegistered) {
+			nfsPort = DefaultPort
+		} else if err != nil {
+			return 0, 0, fmt.Errorf("portmapper: %w", err)
+		}
+	}
+	return mountPort, nfsPort, nil
+}
+
+// Client reads the files of an NFSv3 export.
+type Client struct {
+	mount   *rpcConn
+	nfs     *rpcConn
+	export  string
+	root    []byte
+	rsize   int
+	timeout time.Duration
+}
+
+// Dial mounts export of host with a zero Dialer.
+func Dial(ctx context.Context, host, export string) (*Client, error) {
+	return (&Dialer{}).Dial(ctx, host, export)
+}
+
+// Dial mounts export of host.
+func (d *Dialer) Dial(ctx context.Context, host, export string) (*Client, error) {
+	c, _, err := d.dial(ctx, host, []string{export})
+	return c, err
+}
+
+// DialFile mounts the export of host that holds the file name, and returns
+// the name of the file within it. Like iPXE with nfs:// URLs, it tries the
+// directory of name first, and its parents after it.
+func (d *Dialer) DialFile(ctx context.Context, host, name string) (*Client, string, error) {
+	name = path.Clean("/" + name)
+	var exports []string
+	for dir := path.Dir(name); ; dir = path.Dir(dir) {
+		exports = append(exports, dir)
+		if dir == "/" {
+			break
+		}
+	}
+	c, i, err := d.dial(ctx, host, exports)
+	if err != nil {
+		return nil, "", err
+	}
+	rel := strings.TrimPrefix(strings.TrimPrefix(name, exports[i]), "/")
+	return c, rel, nil
+}
+
+// dial mounts the first of exports that the server lets it, and returns its
+// index.
+func (d *Dialer) dial(ctx context.Context, host string, exports []string) (*Client, int, error) {
+	mountPort, nfsPort, err := d.ports(ctx, host, ipprotoTCP)
+	if err != nil {
+		return nil, 0, err
+	}
+	cred := d.cred()
+	mc, err := dialRPC(ctx, net.JoinHostPort(host, strconv.Itoa(mountPort)), cred, d.timeout())
+	if err != nil {
+		return nil, 0, fmt.Errorf("mountd: %w", err)
+	}
+
+	var (
+		root []byte
+		i    int
+	)
+	for i = range exports {
+		if root, err = mnt(ctx, mc, exports[i]); err == nil {
+			break
+		}
+		var e Error
+		if !errors.As(err, &e) {
+			break
+		}
+	}
+	if err != nil {
+		mc.Close()
+		return nil, 0, err
+	}
+
+	nc, err := dialRPC(ctx, net.JoinHostPort(host, strconv.Itoa(nfsPort)), cred, d.timeout())
+	if err != nil {
+		umnt(ctx, mc, exports[i])
+		mc.Close()
+		return nil, 0, fmt.Errorf("nfs: %w", err)
+	}
+	c := &Client{
+		mount:   mc,
+		nfs:     nc,
+		export:  exports[i],
+		root:    root,
+		rsize:   64 << 10,
+		timeout: d.timeout(),
+	}
+	if rtmax, err := c.fsinfo(ctx); err == nil && rtmax > 0 {
+		c.rsize = min(rtmax, maxRead)
+	}
+	return c, i, nil
+}
+
+// Export is the export that c mounted.
+func (c *Client) Export() string {
+	return c.export
+}
+
+// Close unmounts the export, and closes the connections.
+func (c *Client) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	err := umnt(ctx, c.mount, c.export)
+	c.mount.Close()
+	if cerr := c.nfs.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func (c *Client) call(ctx context.Context, proc uint32, args []byte) (*decoder, error) {
+	return c.nfs.call(ctx, nfsProg, nfsVers, proc, args)
+}
+
+// fsinfo returns the largest READ size of the server.
+func (c *Client) fsinfo(ctx context.Context) (int, error) {
+	var e encoder
+	e.opaque(c.root)
+	d, err := c.call(ctx, procFsinfo, e.b)
+	if err != nil {
+		return 0, err
+	}
+	if err := status(d); err != nil {
+		return 0, err
+	}
+	decodePostOpAttr(d)
+	rtmax := d.uint32()
+	return int(rtmax), d.err
+}
+
+func (c *Client) getattr(ctx context.Context, fh []byte) (attr, error) {
+	var e encoder
+	e.opaque(fh)
+	d, err := c.call(ctx, procGetattr, e.b)
+	if err != nil {
+		return attr{}, err
+	}
+	if err := status(d); err != nil {
+		return attr{}, err
+	}
+	a := decodeAttr(d)
+	return a, d.err
+}
+
+func (c *Client) lookup(ctx context.Context, dir []byte, name string) ([]byte, attr, error) {
+	var e encoder
+	e.opaque(dir)
+	e.string(name)
+	d, err := c.call(ctx, procLookup, e.b)
+	if err != nil {
+		return nil, attr{}, err
+	}
+	if err := status(d); err != nil {
+		return nil, attr{}, err
+	}
+	fh := d.opaque(fhSize)
+	a := decodePostOpAttr(d)
+	if d.err != nil {
+		return nil, attr{}, d.err
+	}
+	if a == nil {
+		at, err := c.getattr(ctx, fh)
+		return fh, at, err
+	}
+	return fh, *a, nil
+}
+
+func (c *Client) readlink(ctx context.Context, fh []byte) (string, error) {
+	var e encoder
+	e.opaque(fh)
+	d, err := c.call(ctx, procReadlink, e.b)
+	if err != nil {
+		return "", err
+	}
+	if err := status(d); err != nil {
+		return "", err
+	}
+	decodePostOpAttr(d)
+	target := d.string(maxName)
+	return target, d.err
+}
+
+// read reads at most count bytes at off.
+func (c *Client) read(ctx context.Context, fh []byte, off uint64, count int) ([]byte, bool, error) {
+	var e encoder
+	e.opaque(fh)
+	e.uint64(off)
+	e.uint32(uint32(count))
+	d, err := c.call(ctx, procRead, e.b)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := status(d); err != nil {
+		return nil, false, err
+	}
+	decodePostOpAttr(d)
+	d.uint32()
+	eof := d.bool()
+	data := d.opaque(count)
+	return data, eof, d.err
+}
+
+// resolve looks name up from the root of the export, which is the root of
+// absolute symbolic links, too.
+func (c *Client) resolve(ctx context.Context, name string) ([]byte, attr, error) {
+	var (
+		fh      = c.root
+		a       = attr{typ: typeDir}
+		parents [][]byte
+		links   int
+		rest    = strings.Split(name, "/")
+	)
+	for len(rest) > 0 {
+		elem := rest[0]
+		rest = rest[1:]
+		switch elem {
+		case "", ".":
+			continue
+		case "..":
+			if len(parents) > 0 {
+				fh, parents = parents[len(parents)-1], parents[:len(parents)-1]
+				a = attr{typ: typeDir}
+			}
+			continue
+		}
+		if a.typ != typeDir {
+			return nil, attr{}, Error(20)
+		}
+		child, ca, err := c.lookup(ctx, fh, elem)
+		if err != nil {
+			return nil, attr{}, err
+		}
+		if ca.typ == typeLnk {
+			if links++; links > maxLinks {
+				return nil, attr{}, errLinks
+			}
+			target, err := c.readlink(ctx, child)
+			if err != nil {
+				return nil, attr{}, err
+			}
+			if strings.HasPrefix(target, "/") {
+				fh, parents = c.root, nil
+			}
+			rest = append(strings.Split(target, "/"), rest...)
+			continue
+		}
+		parents = append(parents, fh)
+		fh, a = child, ca
+	}
+	return fh, a, nil
+}
+
+// Open opens the regular file name of the export, following symbolic links.
+func (c *Client) Open(name string) (*File, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	fh, a, err := c.resolve(ctx, name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	switch a.typ {
+	case typeReg:
+	case typeDir:
+		return nil, &fs.PathError{Op: "open", Path: name, Err: Error(21)}
+	default:
+		return nil, &fs.PathError{Op: "open", Path: name, Err: errNotRegular}
+	}
+	return &File{c: c, name: name, fh: fh, size: int64(a.size)}, nil
+}
+
+// File is a regular file of an export.
+type File struct {
+	c    *Client
+	name string
+	fh   []byte
+	size int64
+	off  int64
+}
+
+// Size is the size of the file when it was opened.
+func (f *File) Size() int64 {
+	return f.size
+}
+
+// ReadAt implements io.ReaderAt.
+func (f *File) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, &fs.PathError{Op: "read", Path: f.name, Err: fs.ErrInvalid}
+	}
+	var n int
+	for n < len(p) {
+		ctx, cancel := context.WithTimeout(context.Background(), f.c.timeout)
+		data, eof, err := f.c.read(ctx, f.fh, uint64(off)+uint64(n), min(len(p)-n, f.c.rsize))
+		cancel()
+		if err != nil {
+			return n, &fs.PathError{Op: "read", Path: f.name, Err: err}
+		}
+		n += copy(p[n:], data)
+		if eof || len(data) == 0 {
+			if n < len(p) {
+				return n, io.EOF
+			}
+			break
+		}
+	}
+	return n, nil
+}
+
+// Read implements io.Reader.
+func (f *File) Read(p []byte) (int, error) {
+	n, err := f.ReadAt(p, f.off)
+	f.off += int64(n)
+	if n > 0 && err == io.EOF {
+		err = nil
+	}
+	return n, err
+}
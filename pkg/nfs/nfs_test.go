@@ -0,0 +1,456 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nfs
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/fs"
+	"net"
+	"net/netip"
+	"path"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// node is a file of the fake server.
+type node struct {
+	typ  uint32
+	data string
+}
+
+// server is a fake portmapper, MOUNT and NFS server on one port. File
+// handles are the paths of the files.
+type server struct {
+	t       *testing.T
+	ln      net.Listener
+	port    int
+	exports []string
+	nodes   map[string]node
+	rsize   int
+
+	mu        sync.Mutex
+	unmounted []string
+}
+
+func newServer(t *testing.T, exports []string, files, links map[string]string) *server {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &server{
+		t:       t,
+		ln:      ln,
+		port:    ln.Addr().(*net.TCPAddr).Port,
+		exports: exports,
+		nodes:   map[string]node{"/": {typ: typeDir}},
+		rsize:   1000,
+	}
+	add := func(name string, n node) {
+		s.nodes[name] = n
+		for dir := path.Dir(name); dir != "/"; dir = path.Dir(dir) {
+			s.nodes[dir] = node{typ: typeDir}
+		}
+	}
+	for name, data := range files {
+		add(name, node{typ: typeReg, data: data})
+	}
+	for name, target := range links {
+		add(name, node{typ: typeLnk, data: target})
+	}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	t.Cleanup(func() { ln.Close() })
+	return s
+}
+
+func (s *server) serve(conn net.Conn) {
+	defer conn.Close()
+	for {
+		rec, err := readRecord(conn)
+		if err != nil {
+			return
+		}
+		d := &decoder{b: rec}
+		xid := d.uint32()
+		d.uint32() // msg type
+		d.uint32() // RPC version
+		prog, vers, proc := d.uint32(), d.uint32(), d.uint32()
+		d.uint32()
+		d.opaque(400)
+		d.uint32()
+		d.opaque(400)
+
+		e := &encoder{b: make([]byte, 4)}
+		e.uint32(xid)
+		e.uint32(msgReply)
+		e.uint32(replyAccepted)
+		e.uint32(authNone)
+		e.opaque(nil)
+		if !s.handle(e, prog, vers, proc, d) {
+			// PROC_UNAVAIL
+			e.b = e.b[:4+4*5]
+			e.uint32(3)
+		}
+		binary.BigEndian.PutUint32(e.b, lastFragment|uint32(len(e.b)-4))
+		if _, err := conn.Write(e.b); err != nil {
+			return
+		}
+	}
+}
+
+func (s *server) attr(e *encoder, n node) {
+	e.uint32(n.typ)
+	for range 4 {
+		e.uint32(0)
+	}
+	e.uint64(uint64(len(n.data)))
+	e.b = append(e.b, make([]byte, 56)...)
+}
+
+func (s *server) handle(e *encoder, prog, vers, proc uint32, d *decoder) bool {
+	const success = 0
+	switch {
+	case prog == pmapProg && proc == pmapGetport:
+		p, v := d.uint32(), d.uint32()
+		e.uint32(success)
+		if (p == mountProg || p == nfsProg) && v == 3 {
+			e.uint32(uint32(s.port))
+		} else {
+			e.uint32(0)
+		}
+	case prog == mountProg && proc == mountMnt:
+		dir := d.string(maxPath)
+		e.uint32(success)
+		switch {
+		case slices.Contains(s.exports, dir):
+			e.uint32(0)
+			e.opaque([]byte(dir))
+			e.uint32(1)
+			e.uint32(authSys)
+		case s.nodes[dir].typ == typeDir:
+			e.uint32(13)
+		default:
+			e.uint32(2)
+		}
+	case prog == mountProg && proc == mountUmnt:
+		dir := d.string(maxPath)
+		s.mu.Lock()
+		s.unmounted = append(s.unmounted, dir)
+		s.mu.Unlock()
+		e.uint32(success)
+	case prog == nfsProg && vers == nfsVers:
+		fh := string(d.opaque(fhSize))
+		n, ok := s.nodes[fh]
+		e.uint32(success)
+		if !ok {
+			e.uint32(10001)
+			e.bool(false)
+			return true
+		}
+		switch proc {
+		case procGetattr:
+			e.uint32(0)
+			s.attr(e, n)
+		case procLookup:
+			child := path.Join(fh, d.string(maxName))
+			cn, ok := s.nodes[child]
+			if !ok {
+				e.uint32(2)
+				e.bool(false)
+				return true
+			}
+			e.uint32(0)
+			e.opaque([]byte(child))
+			// Leave the attributes out of links, for the
+			// client to GETATTR them.
+			e.bool(cn.typ != typeLnk)
+			if cn.typ != typeLnk {
+				s.attr(e, cn)
+			}
+			e.bool(false)
+		case procReadlink:
+			e.uint32(0)
+			e.bool(false)
+			e.string(n.data)
+		case procRead:
+			off, count := d.uint64(), int(d.uint32())
+			data := n.data[min(int(off), len(n.data)):]
+			data = data[:min(len(data), count, s.rsize)]
+			e.uint32(0)
+			e.bool(false)
+			e.uint32(uint32(len(data)))
+			e.bool(int(off)+len(data) >= len(n.data))
+			e.opaque([]byte(data))
+		case procFsinfo:
+			e.uint32(0)
+			e.bool(false)
+			for _, v := range []int{s.rsize, s.rsize, 512, 1 << 20, 1 << 20, 512, 4096} {
+				e.uint32(uint32(v))
+			}
+			e.uint64(1 << 62)
+			e.uint32(0)
+			e.uint32(1)
+			e.uint32(0x1b)
+		default:
+			return false
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+func (s *server) dialer() *Dialer {
+	return &Dialer{PortmapPort: s.port, Timeout: 5 * time.Second}
+}
+
+var testFiles = map[string]string{
+	"/srv/boot/vmlinuz": strings.Repeat("0123456789abcdefghijklmnopqrstuvwxyz", 100),
+	"/srv/boot/initrd":  "",
+	"/srv/etc/hostname": "node1\n",
+}
+
+var testLinks = map[string]string{
+	"/srv/boot/current": "vmlinuz",
+	"/srv/vmlinuz":      "/boot/vmlinuz",
+	"/srv/etc/up":       "../../../boot/current",
+	"/srv/loop":         "loop",
+}
+
+func TestRead(t *testing.T) {
+	s := newServer(t, []string{"/srv"}, testFiles, testLinks)
+	c, err := s.dialer().Dial(context.Background(), "127.0.0.1", "/srv")
+	if err != nil {
+		t.Fatalf("Dial() = %v", err)
+	}
+	if c.rsize != s.rsize {
+		t.Errorf("rsize = %d, want the rtmax of FSINFO %d", c.rsize, s.rsize)
+	}
+
+	kernel := testFiles["/srv/boot/vmlinuz"]
+	for name, want := range map[string]string{
+		"boot/vmlinuz":  kernel,
+		"/boot/vmlinuz": kernel,
+		"boot/initrd":   "",
+		"boot/current":  kernel,
+		"vmlinuz":       kernel,
+		"etc/up":        kernel,
+		"etc/hostname":  "node1\n",
+	} {
+		f, err := c.Open(name)
+		if err != nil {
+			t.Errorf("Open(%q) = %v", name, err)
+			continue
+		}
+		if f.Size() != int64(len(want)) {
+			t.Errorf("Size() of %q = %d, want %d", name, f.Size(), len(want))
+		}
+		b, err := io.ReadAll(f)
+		if err != nil || string(b) != want {
+			t.Errorf("ReadAll(%q) = %d bytes, %v, want %d bytes", name, len(b), err, len(want))
+		}
+	}
+
+	f, err := c.Open("boot/vmlinuz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := make([]byte, 10)
+	if n, err := f.ReadAt(b, 1500); n != 10 || err != nil || string(b) != kernel[1500:1510] {
+		t.Errorf("ReadAt(1500) = %d, %v, %q, want 10, nil, %q", n, err, b, kernel[1500:1510])
+	}
+	if n, err := f.ReadAt(b, int64(len(kernel)-4)); n != 4 || err != io.EOF {
+		t.Errorf("ReadAt() across the end = %d, %v, want 4, EOF", n, err)
+	}
+
+	for _, tt := range []struct {
+		name string
+		err  error
+	}{
+		{name: "nothere", err: fs.ErrNotExist},
+		{name: "boot", err: Error(21)},
+		{name: "etc/hostname/x", err: Error(20)},
+		{name: "loop", err: errLinks},
+	} {
+		if _, err := c.Open(tt.name); !errors.Is(err, tt.err) {
+			t.Errorf("Open(%q) = %v, want %v", tt.name, err, tt.err)
+		}
+	}
+
+	if err := c.Close(); err != nil {
+		t.Errorf("Close() = %v", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !slices.Equal(s.unmounted, []string{"/srv"}) {
+		t.Errorf("unmounted %q, want /srv", s.unmounted)
+	}
+}
+
+func TestDialFile(t *testing.T) {
+	s := newServer(t, []string{"/srv"}, testFiles, testLinks)
+	c, name, err := s.dialer().DialFile(context.Background(), "127.0.0.1", "/srv/boot/vmlinuz")
+	if err != nil {
+		t.Fatalf("DialFile() = %v", err)
+	}
+	defer c.Close()
+	if c.Export() != "/srv" || name != "boot/vmlinuz" {
+		t.Errorf("DialFile() = %q, %q, want /srv, boot/vmlinuz", c.Export(), name)
+	}
+	if _, err := c.Open(name); err != nil {
+		t.Errorf("Open(%q) = %v", name, err)
+	}
+
+	if _, err := s.dialer().Dial(context.Background(), "127.0.0.1", "/srv/boot"); !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("Dial() of a directory that is not exported = %v, want %v", err, fs.ErrPermission)
+	}
+	none := newServer(t, nil, testFiles, nil)
+	if _, _, err := none.dialer().DialFile(context.Background(), "127.0.0.1", "/srv/boot/vmlinuz"); err == nil {
+		t.Errorf("DialFile() without exports = nil, want an error")
+	}
+}
+
+func TestKernelMountData(t *testing.T) {
+	s := newServer(t, []string{"/srv"}, testFiles, nil)
+	o, err := ParseMountOptions([]string{"vers=3", "soft", "rsize=32768"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := s.dialer().KernelMountData(context.Background(), "127.0.0.1", "/srv", o)
+	if err != nil {
+		t.Fatalf("KernelMountData() = %v", err)
+	}
+	if len(b) != mountDataSize {
+		t.Fatalf("len(KernelMountData()) = %d, want %d", len(b), mountDataSize)
+	}
+	ne := binary.NativeEndian
+	for _, f := range []struct {
+		name string
+		off  int
+		want uint32
+	}{
+		{"version", 0, 6},
+		{"flags", 40, mountVer3 | mountSoft | mountTCP | mountNonLM},
+		{"rsize", 44, 32768},
+		{"timeo", 52, 600},
+		{"retrans", 56, 2},
+		{"acregmin", 60, 3},
+		{"acdirmax", 72, 60},
+	} {
+		if got := ne.Uint32(b[f.off:]); got != f.want {
+			t.Errorf("%s = %#x, want %#x", f.name, got, f.want)
+		}
+	}
+	if family, port, ip := ne.Uint16(b[76:]), binary.BigEndian.Uint16(b[78:]), netip.AddrFrom4([4]byte(b[80:84])); family != 2 || int(port) != s.port || ip != netip.MustParseAddr("127.0.0.1") {
+		t.Errorf("addr = %d %v:%d, want 2 127.0.0.1:%d", family, ip, port, s.port)
+	}
+	if host := string(bytes.TrimRight(b[92:348], "\x00")); host != "127.0.0.1" {
+		t.Errorf("hostname = %q, want 127.0.0.1", host)
+	}
+	if n := ne.Uint16(b[356:]); n != 4 || string(b[358:362]) != "/srv" {
+		t.Errorf("root = %q, want /srv", b[358:358+n])
+	}
+
+	if _, err := s.dialer().KernelMountData(context.Background(), "127.0.0.1", "/nothere", o); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("KernelMountData() of a missing export = %v, want %v", err, fs.ErrNotExist)
+	}
+	if _, err := mountData("h", netip.MustParseAddrPort("127.0.0.1:2049"), make([]byte, 65), o); !errors.Is(err, errBadHandle) {
+		t.Errorf("mountData() of a long handle = %v, want %v", err, errBadHandle)
+	}
+}
+
+func TestParseMountOptions(t *testing.T) {
+	o, err := ParseMountOptions([]string{"port=2049", "mountport=635", "wsize=8192", "timeo=100", "retrans=3", "actimeo=10", "intr", "lock", "noac", "nocto", "noacl", "nordirplus", "proto=udp", "sec=sys", "nfsvers=3"})
+	if err != nil {
+		t.Fatalf("ParseMountOptions() = %v", err)
+	}
+	want := &MountOptions{
+		Port: 2049, MountPort: 635, Wsize: 8192, Timeo: 100, Retrans: 3,
+		Acregmin: 10, Acregmax: 10, Acdirmin: 10, Acdirmax: 10,
+		Intr: true, Lock: true, NoAC: true, NoCTO: true, NoACL: true, NoRdirplus: true, UDP: true,
+	}
+	if !reflect.DeepEqual(o, want) {
+		t.Errorf("ParseMountOptions() = %+v, want %+v", o, want)
+	}
+	if o, err := ParseMountOptions(nil); err != nil || !reflect.DeepEqual(o, DefaultMountOptions()) {
+		t.Errorf("ParseMountOptions(nil) = %+v, %v, want the defaults", o, err)
+	}
+
+	for _, tt := range []struct {
+		opt string
+		err error
+	}{
+		{opt: "vers=4", err: errVersion},
+		{opt: "rsize=x", err: errOption},
+		{opt: "rsize", err: errOption},
+		{opt: "soft=1", err: errOption},
+		{opt: "fsc", err: errOption},
+		{opt: "sec=krb5", err: errOption},
+	} {
+		if _, err := ParseMountOptions([]string{tt.opt}); !errors.Is(err, tt.err) {
+			t.Errorf("ParseMountOptions(%q) = %v, want %v", tt.opt, err, tt.err)
+		}
+	}
+}
+
+func TestSplitSource(t *testing.T) {
+	for _, tt := range []struct {
+		source, host, export string
+		err                  error
+	}{
+		{source: "10.0.0.1:/srv/nfs", host: "10.0.0.1", export: "/srv/nfs"},
+		{source: "server:/", host: "server", export: "/"},
+		{source: "/dev/sda", err: errSource},
+		{source: ":/srv", err: errSource},
+		{source: "server:srv", err: errSource},
+	} {
+		host, export, err := SplitSource(tt.source)
+		if host != tt.host || export != tt.export || !errors.Is(err, tt.err) {
+			t.Errorf("SplitSource(%q) = %q, %q, %v, want %q, %q, %v", tt.source, host, export, err, tt.host, tt.export, tt.err)
+		}
+	}
+}
+
+func TestXDR(t *testing.T) {
+	var e encoder
+	e.uint32(7)
+	e.uint64(1 << 40)
+	e.bool(true)
+	e.string("abcde")
+	e.opaque(nil)
+	if len(e.b) != 4+8+4+4+8+4 {
+		t.Fatalf("encoded %d bytes, want 32", len(e.b))
+	}
+
+	d := &decoder{b: e.b}
+	if v, w, b, s, o := d.uint32(), d.uint64(), d.bool(), d.string(5), d.opaque(0); v != 7 || w != 1<<40 || !b || s != "abcde" || len(o) != 0 || d.err != nil || len(d.b) != 0 {
+		t.Errorf("decoded %d, %d, %t, %q, %q, %v", v, w, b, s, o, d.err)
+	}
+
+	d = &decoder{b: e.b[16:]}
+	if d.string(4); !errors.Is(d.err, errTooLong) {
+		t.Errorf("decoding a long string = %v, want %v", d.err, errTooLong)
+	}
+	d = &decoder{b: e.b[:6]}
+	if d.uint64(); !errors.Is(d.err, errShort) || d.uint32() != 0 {
+		t.Errorf("decoding short data = %v, want %v", d.err, errShort)
+	}
+}
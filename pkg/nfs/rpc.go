@@ -0,0 +1,211 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nfs
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// ONC RPC (RFC 5531) constants.
+const (
+	rpcVersion = 2
+
+	msgCall  = 0
+	msgReply = 1
+
+	replyAccepted = 0
+	replyDenied   = 1
+
+	authNone = 0
+	authSys  = 1
+
+	// lastFragment marks the last fragment of a record (RFC 5531,
+	// section 11).
+	lastFragment = 1 << 31
+	// maxRecord bounds replies, which are at most a READ of maxRead
+	// bytes and its header.
+	maxRecord = 2 << 20
+)
+
+var (
+	errProgUnavail  = errors.New("RPC program unavailable")
+	errProgMismatch = errors.New("RPC program version mismatch")
+	errProcUnavail  = errors.New("RPC procedure unavailable")
+	errGarbageArgs  = errors.New("RPC arguments not understood")
+	errSystemErr    = errors.New("RPC system error")
+	errDenied       = errors.New("RPC call denied")
+	errBadReply     = errors.New("malformed RPC reply")
+)
+
+var acceptErrors = map[uint32]error{
+	1: errProgUnavail,
+	2: errProgMismatch,
+	3: errProcUnavail,
+	4: errGarbageArgs,
+	5: errSystemErr,
+}
+
+// authSysCred returns the body of AUTH_SYS credentials (RFC 5531, appendix
+// A).
+func authSysCred(machine string, uid, gid uint32) []byte {
+	var e encoder
+	e.uint32(uint32(time.Now().Unix()))
+	e.string(machine)
+	e.uint32(uid)
+	e.uint32(gid)
+	e.uint32(0)
+	return e.b
+}
+
+// rpcConn makes ONC RPC calls over TCP, one at a time, with AUTH_SYS
+// credentials cred, or without credentials if cred is nil.
+type rpcConn struct {
+	conn    net.Conn
+	cred    []byte
+	timeout time.Duration
+
+	mu  sync.Mutex
+	xid uint32
+}
+
+// dialRPC connects to the RPC server at addr. Servers often insist on
+// a reserved source port, which needs privileges, so dialRPC tries some
+// before it gives up on them.
+func dialRPC(ctx context.Context, addr string, cred []byte, timeout time.Duration) (*rpcConn, error) {
+	var (
+		d    net.Dialer
+		conn net.Conn
+		err  error
+	)
+	for port := 1023; port > 1007; port-- {
+		d.LocalAddr = &net.TCPAddr{Port: port}
+		if conn, err = d.DialContext(ctx, "tcp", addr); err == nil || errors.Is(err, os.ErrPermission) || ctx.Err() != nil {
+			break
+		}
+	}
+	if conn == nil {
+		d.LocalAddr = nil
+		if conn, err = d.DialContext(ctx, "tcp", addr); err != nil {
+			return nil, err
+		}
+	}
+	return &rpcConn{
+		conn:    conn,
+		cred:    cred,
+		timeout: timeout,
+		xid:     uint32(time.Now().UnixNano()),
+	}, nil
+}
+
+func (c *rpcConn) Close() error {
+	return c.conn.Close()
+}
+
+// call calls procedure proc of version vers of program prog, and returns a
+// decoder of the results.
+func (c *rpcConn) call(ctx context.Context, prog, vers, proc uint32, args []byte) (*decoder, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	deadline := time.Now().Add(c.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := c.conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+	if ctx.Done() != nil {
+		stop := context.AfterFunc(ctx, func() { c.conn.SetDeadline(time.Now()) })
+		defer stop()
+	}
+
+	c.xid++
+	e := encoder{b: make([]byte, 4, 64+len(c.cred)+len(args))}
+	e.uint32(c.xid)
+	e.uint32(msgCall)
+	e.uint32(rpcVersion)
+	e.uint32(prog)
+	e.uint32(vers)
+	e.uint32(proc)
+	if c.cred == nil {
+		e.uint32(authNone)
+	} else {
+		e.uint32(authSys)
+	}
+	e.opaque(c.cred)
+	e.uint32(authNone)
+	e.opaque(nil)
+	e.b = append(e.b, args...)
+	binary.BigEndian.PutUint32(e.b, lastFragment|uint32(len(e.b)-4))
+	// A record cut short leaves the stream out of step, so errors
+	// close the connection.
+	if _, err := c.conn.Write(e.b); err != nil {
+		c.conn.Close()
+		return nil, err
+	}
+
+	for {
+		rec, err := readRecord(c.conn)
+		if err != nil {
+			c.conn.Close()
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, err
+		}
+		d := &decoder{b: rec}
+		// Replies to calls that timed out before may still arrive.
+		if d.uint32() != c.xid {
+			continue
+		}
+		if d.uint32() != msgReply {
+			return nil, errBadReply
+		}
+		if d.uint32() != replyAccepted {
+			return nil, errDenied
+		}
+		d.uint32()
+		d.opaque(400)
+		stat := d.uint32()
+		if d.err != nil {
+			return nil, fmt.Errorf("%w: %w", errBadReply, d.err)
+		}
+		if err, ok := acceptErrors[stat]; ok {
+			return nil, fmt.Errorf("program %d version %d procedure %d: %w", prog, vers, proc, err)
+		}
+		return d, nil
+	}
+}
+
+// readRecord reads the fragments of a record.
+func readRecord(r io.Reader) ([]byte, error) {
+	var rec []byte
+	for {
+		var h [4]byte
+		if _, err := io.ReadFull(r, h[:]); err != nil {
+			return nil, err
+		}
+		n := binary.BigEndian.Uint32(h[:])
+		size := int(n &^ lastFragment)
+		if len(rec)+size > maxRecord {
+			return nil, fmt.Errorf("%w: record of more than %d bytes", errBadReply, maxRecord)
+		}
+		rec = append(rec, make([]byte, size)...)
+		if _, err := io.ReadFull(r, rec[len(rec)-size:]); err != nil {
+			return nil, err
+		}
+		if n&lastFragment != 0 {
+			return rec, nil
+		}
+	}
+}
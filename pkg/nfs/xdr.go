@@ -0,0 +1,110 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nfs
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+var (
+	errShort   = errors.New("XDR data too short")
+	errTooLong = errors.New("XDR opaque data too long")
+)
+
+// encoder appends XDR (RFC 4506) values to b.
+type encoder struct {
+	b []byte
+}
+
+func (e *encoder) uint32(v uint32) {
+	e.b = binary.BigEndian.AppendUint32(e.b, v)
+}
+
+func (e *encoder) uint64(v uint64) {
+	e.b = binary.BigEndian.AppendUint64(e.b, v)
+}
+
+func (e *encoder) bool(v bool) {
+	if v {
+		e.uint32(1)
+		return
+	}
+	e.uint32(0)
+}
+
+// opaque appends variable-length opaque data, padded to 4 bytes.
+func (e *encoder) opaque(b []byte) {
+	e.uint32(uint32(len(b)))
+	e.b = append(e.b, b...)
+	e.b = append(e.b, make([]byte, pad(len(b)))...)
+}
+
+func (e *encoder) string(s string) {
+	e.opaque([]byte(s))
+}
+
+func pad(n int) int {
+	return (4 - n%4) % 4
+}
+
+// decoder reads XDR values from b. The first error sticks, and the reads
+// after it return zero values.
+type decoder struct {
+	b   []byte
+	err error
+}
+
+func (d *decoder) next(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if len(d.b) < n {
+		d.err = errShort
+		return nil
+	}
+	b := d.b[:n]
+	d.b = d.b[n:]
+	return b
+}
+
+func (d *decoder) uint32() uint32 {
+	b := d.next(4)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint32(b)
+}
+
+func (d *decoder) uint64() uint64 {
+	b := d.next(8)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint64(b)
+}
+
+func (d *decoder) bool() bool {
+	return d.uint32() != 0
+}
+
+// opaque reads variable-length opaque data of at most max bytes.
+func (d *decoder) opaque(max int) []byte {
+	n := d.uint32()
+	if d.err != nil {
+		return nil
+	}
+	if n > uint32(max) {
+		d.err = errTooLong
+		return nil
+	}
+	b := d.next(int(n))
+	d.next(pad(int(n)))
+	return b
+}
+
+func (d *decoder) string(max int) string {
+	return string(d.opaque(max))
+}
@@ -0,0 +1,64 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ntp
+
+import (
+	"math"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// systemClock is the clock of the kernel.
+type systemClock struct{}
+
+func (systemClock) synchronized() bool {
+	var tx unix.Timex
+	_, err := unix.Adjtimex(&tx)
+	return err == nil && tx.Status&unix.STA_UNSYNC == 0
+}
+
+// step steps the clock by offset at once.
+func (systemClock) step(offset time.Duration) error {
+	tx := unix.Timex{
+		Modes: unix.ADJ_SETOFFSET,
+		Time:  unix.NsecToTimeval(int64(offset)),
+	}
+	_, err := unix.Adjtimex(&tx)
+	return err
+}
+
+// slew hands offset to the PLL of the kernel, which slews the clock and
+// trains its frequency. The time constant of the PLL follows the poll
+// interval, as in RFC 5905. It marks the clock synchronized, with its
+// error bounds and the leap second to come.
+func (systemClock) slew(offset time.Duration, poll int, leap Leap, maxErr, estErr time.Duration) error {
+	status := int32(unix.STA_PLL | unix.STA_NANO)
+	switch leap {
+	case LeapInsert:
+		status |= unix.STA_INS
+	case LeapDelete:
+		status |= unix.STA_DEL
+	}
+	tx := unix.Timex{
+		Modes:  unix.ADJ_OFFSET | unix.ADJ_STATUS | unix.ADJ_NANO | unix.ADJ_TIMECONST | unix.ADJ_MAXERROR | unix.ADJ_ESTERROR,
+		Status: status,
+	}
+	// The kernel takes at most half a second.
+	setLong(&tx.Offset, int64(min(max(offset, -maxSlew), maxSlew)))
+	setLong(&tx.Constant, int64(max(poll-4, 0)))
+	setLong(&tx.Maxerror, min(maxErr.Microseconds(), math.MaxInt32))
+	setLong(&tx.Esterror, min(estErr.Microseconds(), math.MaxInt32))
+	_, err := unix.Adjtimex(&tx)
+	return err
+}
+
+// maxSlew is the largest offset the PLL of the kernel takes.
+const maxSlew = 500 * time.Millisecond
+
+// setLong sets a field of type long, which is 32 or 64 bits.
+func setLong[T int32 | int64](p *T, v int64) {
+	*p = T(v)
+}
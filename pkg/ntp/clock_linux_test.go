@@ -0,0 +1,36 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ntp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hugelgupf/vmtest/guest"
+)
+
+func TestSystemClock(t *testing.T) {
+	guest.SkipIfNotInVM(t)
+
+	var c systemClock
+	before := time.Now()
+	if err := c.step(time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	stepped := time.Now()
+	if err := c.step(-time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if d := stepped.Round(0).Sub(before.Round(0)); d < time.Hour || d > time.Hour+time.Second {
+		t.Errorf("stepping by an hour moved the clock by %v", d)
+	}
+
+	if err := c.slew(time.Millisecond, 6, LeapNone, 10*time.Millisecond, time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if !c.synchronized() {
+		t.Error("the clock is not synchronized after slewing")
+	}
+}
@@ -0,0 +1,28 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !linux
+
+package ntp
+
+import (
+	"errors"
+	"time"
+)
+
+// systemClock is the clock of the kernel, which is only disciplined on
+// Linux.
+type systemClock struct{}
+
+func (systemClock) synchronized() bool {
+	return false
+}
+
+func (systemClock) step(time.Duration) error {
+	return errors.ErrUnsupported
+}
+
+func (systemClock) slew(time.Duration, int, Leap, time.Duration, time.Duration) error {
+	return errors.ErrUnsupported
+}
@@ -0,0 +1,392 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ntp
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"math/bits"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/u-root/u-root/pkg/rtc"
+)
+
+// Defaults of daemons.
+const (
+	DefaultMinPoll       = 64 * time.Second
+	DefaultMaxPoll       = 1024 * time.Second
+	DefaultStepThreshold = 128 * time.Millisecond
+	DefaultRTCInterval   = 11 * time.Minute
+)
+
+// burstLen is how many queries a burst sends.
+const burstLen = 4
+
+// Server is a server that a Daemon polls.
+type Server struct {
+	// Host is the server, as host or host:port. With NTS, the port is
+	// that of the key establishment.
+	Host string
+
+	// NTS authenticates the server with Network Time Security.
+	NTS bool
+
+	// IBurst sends a burst of queries at first, to synchronize sooner.
+	IBurst bool
+}
+
+// ParseConfig returns the servers of an ntp.conf. Lines are "server HOST"
+// or "pool HOST", with the options nts and iburst; other lines and options
+// are ignored.
+func ParseConfig(r io.Reader) ([]Server, error) {
+	var servers []Server
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line, _, _ := strings.Cut(s.Text(), "#")
+		w := strings.Fields(line)
+		if len(w) < 2 || w[0] != "server" && w[0] != "pool" {
+			continue
+		}
+		srv := Server{Host: w[1]}
+		for _, opt := range w[2:] {
+			switch opt {
+			case "nts":
+				srv.NTS = true
+			case "iburst":
+				srv.IBurst = true
+			}
+		}
+		servers = append(servers, srv)
+	}
+	return servers, s.Err()
+}
+
+// clock is the clock that a daemon disciplines.
+type clock interface {
+	// synchronized reports whether the clock has been synchronized
+	// since it was set last.
+	synchronized() bool
+	step(offset time.Duration) error
+	slew(offset time.Duration, poll int, leap Leap, maxErr, estErr time.Duration) error
+}
+
+// Daemon keeps the system clock in time with servers.
+type Daemon struct {
+	// Servers are the servers to poll.
+	Servers []Server
+
+	// Logf logs steps of the clock, and errors. It defaults to
+	// log.Printf.
+	Logf func(format string, v ...any)
+
+	// MinPoll and MaxPoll bound the interval between polls, which
+	// doubles from MinPoll while the clock stays in time.
+	MinPoll, MaxPoll time.Duration
+
+	// StepThreshold is the offset from which the clock is stepped
+	// rather than slewed. The clock is always stepped the first time.
+	StepThreshold time.Duration
+
+	// MinSources is how many servers must agree on the time before the
+	// clock is set. It defaults to 1.
+	MinSources int
+
+	// RTCInterval is how often the RTC is set from the synchronized
+	// clock. The RTC is not set if it is 0.
+	RTCInterval time.Duration
+
+	// TLSConfig configures the TLS of NTS key establishment.
+	TLSConfig *tls.Config
+
+	// IgnoreCertTime verifies the certificates of NTS servers ignoring
+	// their validity period until the clock is synchronized, so that
+	// NTS works from boot with a clock that has not been set.
+	IgnoreCertTime bool
+
+	clock     clock
+	setRTC    func(time.Time) error
+	burstWait time.Duration
+
+	mu     sync.Mutex
+	status Status
+}
+
+// Status is the state of a daemon.
+type Status struct {
+	// Synchronized is set once the clock has been set.
+	Synchronized bool
+
+	// Offset and Jitter are those of the last update of the clock.
+	Offset time.Duration
+	Jitter time.Duration
+
+	// Poll is the interval between polls.
+	Poll time.Duration
+
+	Peers []PeerStatus
+}
+
+// PeerStatus is the state of a server of a daemon.
+type PeerStatus struct {
+	Server Server
+
+	// Reach has a bit for each of the last 8 polls, set if the server
+	// answered.
+	Reach uint8
+
+	// Sample is the best recent sample, if any.
+	Sample *Sample
+	Jitter time.Duration
+
+	// Selected is set if the server was one of the truechimers of the
+	// last update.
+	Selected bool
+
+	// Err is the error of the last poll.
+	Err error
+}
+
+// NewDaemon returns a daemon that polls servers.
+func NewDaemon(servers ...Server) *Daemon {
+	return &Daemon{
+		Servers:       servers,
+		Logf:          log.Printf,
+		MinPoll:       DefaultMinPoll,
+		MaxPoll:       DefaultMaxPoll,
+		StepThreshold: DefaultStepThreshold,
+		MinSources:    1,
+		clock:         systemClock{},
+		setRTC:        setRTC,
+		burstWait:     2 * time.Second,
+	}
+}
+
+func setRTC(t time.Time) error {
+	r, err := rtc.OpenRTC()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return r.Set(t.UTC())
+}
+
+// Status returns the state of the daemon.
+func (d *Daemon) Status() Status {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	s := d.status
+	s.Peers = append([]PeerStatus(nil), s.Peers...)
+	return s
+}
+
+// peer is a server as the daemon polls it.
+type peer struct {
+	server  Server
+	filter  filter
+	reach   uint8
+	nts     *NTS
+	err     error
+	stopped bool
+}
+
+// Run polls the servers and disciplines the clock until ctx is done.
+func (d *Daemon) Run(ctx context.Context) error {
+	if len(d.Servers) == 0 {
+		return errors.New("no servers")
+	}
+	minPoll := max(d.MinPoll, time.Millisecond)
+	maxLevel := max(bits.Len64(uint64(d.MaxPoll/minPoll))-1, 0)
+	peers := make([]*peer, len(d.Servers))
+	for i, s := range d.Servers {
+		peers[i] = &peer{server: s}
+	}
+
+	// The interval between polls is minPoll << level.
+	level := 0
+	first := true
+	var lastRTC time.Time
+	for {
+		d.poll(ctx, peers, first)
+		first = false
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		next, err := d.update(peers, minPoll<<level)
+		switch {
+		case err != nil:
+			d.Logf("ntp: %v", err)
+		case d.RTCInterval > 0 && time.Since(lastRTC) >= d.RTCInterval:
+			if err := d.setRTC(time.Now()); err != nil {
+				d.Logf("ntp: setting the RTC: %v", err)
+			}
+			lastRTC = time.Now()
+		}
+		level = min(max(level+next, 0), maxLevel)
+
+		t := time.NewTimer(minPoll << level)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// pollLog returns the poll exponent of an interval, the log2 of seconds.
+func pollLog(d time.Duration) int {
+	return max(bits.Len64(uint64(d/time.Second))-1, 0)
+}
+
+// poll queries all peers, once or in a burst.
+func (d *Daemon) poll(ctx context.Context, peers []*peer, first bool) {
+	var wg sync.WaitGroup
+	for _, p := range peers {
+		if p.stopped {
+			continue
+		}
+		n := 1
+		if first && p.server.IBurst {
+			n = burstLen
+		}
+		wg.Go(func() {
+			p.reach <<= 1
+			for i := range n {
+				if i > 0 {
+					select {
+					case <-ctx.Done():
+						return
+					case <-time.After(d.burstWait):
+					}
+				}
+				s, err := d.query(ctx, p)
+				if p.err = err; err != nil {
+					var kiss *KissError
+					if errors.As(err, &kiss) {
+						d.kissed(p, kiss)
+					}
+					continue
+				}
+				p.reach |= 1
+				p.filter.add(s)
+			}
+		})
+	}
+	wg.Wait()
+}
+
+// query queries a peer, establishing NTS keys as needed.
+func (d *Daemon) query(ctx context.Context, p *peer) (*Sample, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if !p.server.NTS {
+		return Query(ctx, p.server.Host)
+	}
+	if p.nts == nil || p.nts.Cookies() == 0 {
+		config := d.TLSConfig
+		if d.IgnoreCertTime && !d.Status().Synchronized && !d.clock.synchronized() {
+			config = IgnoreCertTime(config)
+		}
+		n, err := KeyExchange(ctx, p.server.Host, config)
+		if err != nil {
+			return nil, fmt.Errorf("NTS key establishment with %s: %w", p.server.Host, err)
+		}
+		p.nts = n
+	}
+	return p.nts.Query(ctx)
+}
+
+// kissed handles a kiss-o'-death of a peer.
+func (d *Daemon) kissed(p *peer, kiss *KissError) {
+	switch kiss.Code {
+	case "DENY", "RSTR":
+		d.Logf("ntp: %s refuses service, not polling it anymore", p.server.Host)
+		p.stopped = true
+	case "NTSN":
+		// The cookies are stale; establish new keys.
+		p.nts = nil
+	}
+}
+
+// update selects the truechimers, and disciplines the clock with them.
+// It returns whether to poll more (-1) or less (1) often, or -level after
+// stepping the clock.
+func (d *Daemon) update(peers []*peer, poll time.Duration) (int, error) {
+	now := time.Now()
+	var cands []candidate
+	status := make([]PeerStatus, len(peers))
+	for i, p := range peers {
+		best, jitter := p.filter.best()
+		status[i] = PeerStatus{Server: p.server, Reach: p.reach, Sample: best, Jitter: jitter, Err: p.err}
+		if best != nil && p.reach != 0 {
+			cands = append(cands, newCandidate(best, jitter, now))
+		}
+	}
+	trues := intersect(cands)
+	for i := range status {
+		status[i].Selected = slices.ContainsFunc(trues, func(c candidate) bool { return c.sample == status[i].Sample })
+	}
+	d.mu.Lock()
+	d.status.Peers = status
+	d.status.Poll = poll
+	synced := d.status.Synchronized
+	d.mu.Unlock()
+
+	if want := max(d.MinSources, 1); len(trues) < want {
+		return 0, fmt.Errorf("%d of %d servers agree on the time, want %d", len(trues), len(peers), want)
+	}
+	offset, jitter := combine(trues)
+	leaps := map[Leap]int{}
+	dist := time.Duration(math.MaxInt64)
+	for _, c := range trues {
+		leaps[c.sample.Leap]++
+		dist = min(dist, c.distance)
+	}
+	leap := LeapNone
+	for l, n := range leaps {
+		if 2*n > len(trues) {
+			leap = l
+		}
+	}
+
+	var next int
+	slew := offset
+	switch {
+	case !synced || offset.Abs() > d.StepThreshold:
+		if err := d.clock.step(offset); err != nil {
+			return 0, fmt.Errorf("stepping the clock by %v: %w", offset, err)
+		}
+		d.Logf("ntp: stepped the clock by %v", offset)
+		// The samples are of the old time.
+		for _, p := range peers {
+			p.filter.reset()
+		}
+		slew, next = 0, math.MinInt
+	case offset.Abs() < d.StepThreshold/8:
+		next = 1
+	case offset.Abs() > d.StepThreshold/2:
+		next = -1
+	}
+	if err := d.clock.slew(slew, pollLog(poll), leap, dist+offset.Abs(), jitter); err != nil {
+		return 0, fmt.Errorf("slewing the clock by %v: %w", slew, err)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.status.Synchronized = true
+	d.status.Offset = offset
+	d.status.Jitter = jitter
+	return next, nil
+}
@@ -0,0 +1,173 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ntp
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a system clock that is behind the time of servers, and
+// moves them when it is stepped or slewed.
+type fakeClock struct {
+	mu      sync.Mutex
+	servers map[*fakeServer]time.Duration
+	steps   []time.Duration
+	slews   []time.Duration
+	poll    int
+}
+
+func (c *fakeClock) synchronized() bool {
+	return false
+}
+
+func (c *fakeClock) adjust(offset time.Duration) {
+	for s, o := range c.servers {
+		c.servers[s] = o - offset
+		s.set(func() { s.offset = o - offset })
+	}
+}
+
+func (c *fakeClock) step(offset time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.steps = append(c.steps, offset)
+	c.adjust(offset)
+	return nil
+}
+
+func (c *fakeClock) slew(offset time.Duration, poll int, leap Leap, maxErr, estErr time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.slews = append(c.slews, offset)
+	c.poll = poll
+	c.adjust(offset)
+	return nil
+}
+
+func testDaemon(t *testing.T, offsets ...time.Duration) (*Daemon, *fakeClock) {
+	c := &fakeClock{servers: map[*fakeServer]time.Duration{}}
+	d := NewDaemon()
+	for _, o := range offsets {
+		s := newFakeServer(t, o)
+		c.servers[s] = o
+		d.Servers = append(d.Servers, Server{Host: s.addr(), IBurst: true})
+	}
+	d.Logf = t.Logf
+	d.MinPoll = 10 * time.Millisecond
+	d.MaxPoll = 40 * time.Millisecond
+	d.clock = c
+	d.burstWait = time.Millisecond
+	d.setRTC = func(time.Time) error { return errors.ErrUnsupported }
+	return d, c
+}
+
+// runUntil runs d until cond holds.
+func runUntil(t *testing.T, d *Daemon, cond func(Status) bool) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- d.Run(ctx) }()
+	defer func() {
+		cancel()
+		if err := <-done; !errors.Is(err, context.Canceled) {
+			t.Errorf("Run = %v, want %v", err, context.Canceled)
+		}
+	}()
+	for range 500 {
+		if cond(d.Status()) {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("status %+v never met the condition", d.Status())
+}
+
+func TestDaemon(t *testing.T) {
+	d, c := testDaemon(t, 5*time.Second, 5*time.Second+2*time.Millisecond, 40*time.Second)
+	var rtc []time.Time
+	d.RTCInterval = time.Hour
+	d.setRTC = func(t time.Time) error {
+		rtc = append(rtc, t)
+		return nil
+	}
+	runUntil(t, d, func(s Status) bool {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return s.Synchronized && len(c.slews) >= 5 && s.Poll == d.MaxPoll
+	})
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.steps) != 1 || (c.steps[0]-5*time.Second).Abs() > 20*time.Millisecond {
+		t.Errorf("steps = %v, want one of 5s", c.steps)
+	}
+	// After the step, the clock only needs slewing by little.
+	for _, s := range c.slews[1:] {
+		if s.Abs() > 20*time.Millisecond {
+			t.Errorf("slewed by %v", s)
+		}
+	}
+	st := d.Status()
+	if !st.Peers[0].Selected || !st.Peers[1].Selected || st.Peers[2].Selected {
+		t.Errorf("selected %v, %v, %v, want the falseticker out", st.Peers[0].Selected, st.Peers[1].Selected, st.Peers[2].Selected)
+	}
+	if st.Peers[0].Reach == 0 || st.Peers[0].Sample == nil {
+		t.Errorf("peer = %+v", st.Peers[0])
+	}
+	if len(rtc) != 1 {
+		t.Errorf("set the RTC %d times, want once", len(rtc))
+	}
+}
+
+func TestDaemonNoMajority(t *testing.T) {
+	d, c := testDaemon(t, time.Second, 40*time.Second)
+	runUntil(t, d, func(s Status) bool {
+		return len(s.Peers) == 2 && s.Peers[0].Reach&0xf == 0xf
+	})
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if d.Status().Synchronized || len(c.steps) != 0 || len(c.slews) != 0 {
+		t.Errorf("servers that disagree set the clock: steps %v, slews %v", c.steps, c.slews)
+	}
+}
+
+func TestDaemonKiss(t *testing.T) {
+	d, c := testDaemon(t, time.Second, time.Second)
+	for s := range c.servers {
+		if s.addr() == d.Servers[1].Host {
+			s.set(func() { s.kiss = "DENY" })
+		}
+	}
+	d.MinSources = 1
+	runUntil(t, d, func(s Status) bool {
+		return s.Synchronized && len(s.Peers) == 2 && s.Peers[0].Reach&0xf == 0xf
+	})
+	st := d.Status()
+	var kiss *KissError
+	if st.Peers[1].Reach != 0 || !errors.As(st.Peers[1].Err, &kiss) || kiss.Code != "DENY" {
+		t.Errorf("denying peer = %+v", st.Peers[1])
+	}
+}
+
+func TestDaemonNTS(t *testing.T) {
+	d, c := testDaemon(t)
+	s := newFakeServer(t, 2*time.Second)
+	c.servers[s] = 2 * time.Second
+	ke, pool := newKEServer(t, s, time.Now().AddDate(5, 0, 0), time.Now().AddDate(6, 0, 0))
+	d.Servers = []Server{{Host: ke, NTS: true}}
+	d.TLSConfig = &tls.Config{RootCAs: pool}
+	d.IgnoreCertTime = true
+	runUntil(t, d, func(s Status) bool { return s.Synchronized })
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.steps) != 1 || (c.steps[0]-2*time.Second).Abs() > 20*time.Millisecond {
+		t.Errorf("steps = %v, want one of 2s", c.steps)
+	}
+}
@@ -0,0 +1,253 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package ntp implements an NTP client that keeps the system clock in
+// time.
+//
+// Query asks a server for the time once, as SNTP does, and NTS sessions
+// do the same authenticated with Network Time Security (RFC 8915). A
+// Daemon polls several servers, filters their samples and selects the
+// true ones as in RFC 5905, and slews the clock with the kernel's PLL
+// instead of stepping it, unless it is far off.
+package ntp
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"time"
+)
+
+// DefaultPort is the port of NTP servers.
+const DefaultPort = 123
+
+// headerLen is the length of NTP packets without extension fields.
+const headerLen = 48
+
+// Modes of NTP packets.
+const (
+	modeClient = 3
+	modeServer = 4
+)
+
+const version = 4
+
+// Leap is the leap indicator of a server.
+type Leap uint8
+
+// Leap indicators.
+const (
+	LeapNone Leap = iota
+	LeapInsert
+	LeapDelete
+	LeapUnsynchronized
+)
+
+// maxStratum is the largest stratum of a synchronized server.
+const maxStratum = 15
+
+// ntpEpochOffset is the number of seconds from the NTP epoch, 1900, to
+// the Unix epoch.
+const ntpEpochOffset = 2208988800
+
+// header is the fixed part of an NTP packet.
+type header struct {
+	LeapVersionMode uint8
+	Stratum         uint8
+	Poll            int8
+	Precision       int8
+	RootDelay       uint32
+	RootDispersion  uint32
+	ReferenceID     [4]byte
+	ReferenceTime   uint64
+	OriginTime      uint64
+	ReceiveTime     uint64
+	TransmitTime    uint64
+}
+
+func (h *header) leap() Leap { return Leap(h.LeapVersionMode >> 6) }
+func (h *header) mode() int  { return int(h.LeapVersionMode) & 7 }
+
+func (h *header) marshal() []byte {
+	b, _ := binary.Append(make([]byte, 0, headerLen), binary.BigEndian, h)
+	return b
+}
+
+// toTime converts an NTP timestamp to the time nearest to near, so that
+// timestamps of any era convert.
+func toTime(ts uint64, near time.Time) time.Time {
+	secs := int64(ts>>32) - ntpEpochOffset
+	nsecs := int64((ts & 0xffffffff) * 1e9 >> 32)
+	t := time.Unix(secs, nsecs)
+	const era = time.Duration(1<<32) * time.Second
+	// Durations of more than an era overflow; eras are about 136 years.
+	for t.Sub(near) > era/2 {
+		t = t.Add(-era)
+	}
+	for near.Sub(t) > era/2 {
+		t = t.Add(era)
+	}
+	return t
+}
+
+// shortDuration converts an NTP short, a fixed point 16.16 number of
+// seconds.
+func shortDuration(s uint32) time.Duration {
+	return time.Duration(uint64(s) * uint64(time.Second) >> 16)
+}
+
+// Sample is the answer of a server to one query.
+type Sample struct {
+	// Server is the address of the server.
+	Server string
+
+	// Time is when the answer arrived.
+	Time time.Time
+
+	// Offset is how much the server is ahead of the local clock.
+	Offset time.Duration
+
+	// Delay is the round trip time.
+	Delay time.Duration
+
+	Leap           Leap
+	Stratum        int
+	Poll           int
+	Precision      time.Duration
+	RootDelay      time.Duration
+	RootDispersion time.Duration
+
+	// ReferenceID identifies the source of the time of the server.
+	ReferenceID string
+}
+
+// RootDistance is the most the time of the server may be wrong by, from
+// its root delay and dispersion and the delay of the query.
+func (s *Sample) RootDistance() time.Duration {
+	return max(s.RootDelay+s.Delay, minDispersion)/2 + s.RootDispersion + s.Precision
+}
+
+// minDispersion bounds the delay of samples from below, as RFC 5905 does.
+const minDispersion = 5 * time.Millisecond
+
+// KissError is a kiss-o'-death packet of a server, which tells the client
+// to back off (RATE), or to stop (DENY, RSTR), or that its NTS cookie was
+// refused (NTSN).
+type KissError struct {
+	Code string
+}
+
+func (e *KissError) Error() string {
+	return fmt.Sprintf("kiss-o'-death %q", e.Code)
+}
+
+var errUnsynchronized = errors.New("the server is not synchronized")
+
+// Query asks the NTP server at addr, as host or host:port, for the time.
+func Query(ctx context.Context, addr string) (*Sample, error) {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, fmt.Sprint(DefaultPort))
+	}
+	return query(ctx, addr, nil, nil)
+}
+
+// query sends one request, with the extension fields of req, and returns
+// the sample. check, if not nil, checks the whole response.
+func query(ctx context.Context, addr string, req func(h []byte) []byte, check func(resp []byte) error) (*Sample, error) {
+	var d net.Dialer
+	c, err := d.DialContext(ctx, "udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(5 * time.Second)
+	}
+	c.SetDeadline(deadline)
+	defer context.AfterFunc(ctx, func() { c.SetDeadline(time.Now()) })()
+
+	// The transmit time is random, so that requests do not tell the time
+	// of the client and responses are matched to them.
+	var xmt [8]byte
+	rand.Read(xmt[:])
+	h := header{
+		LeapVersionMode: uint8(LeapUnsynchronized)<<6 | version<<3 | modeClient,
+		TransmitTime:    binary.BigEndian.Uint64(xmt[:]),
+	}
+	pkt := h.marshal()
+	if req != nil {
+		pkt = req(pkt)
+	}
+
+	t1 := time.Now()
+	if _, err := c.Write(pkt); err != nil {
+		return nil, err
+	}
+	b := make([]byte, 2048)
+	for {
+		n, err := c.Read(b)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if err != nil {
+			return nil, err
+		}
+		elapsed := time.Since(t1)
+		var r header
+		if _, err := binary.Decode(b[:n], binary.BigEndian, &r); err != nil || r.mode() != modeServer || r.OriginTime != h.TransmitTime {
+			// Not an answer to us.
+			continue
+		}
+		if check != nil {
+			if err := check(b[:n]); err != nil {
+				return nil, err
+			}
+		}
+		return sample(addr, &r, t1.Round(0), elapsed)
+	}
+}
+
+// sample computes the offset and delay of a response to a request sent at
+// t1, which arrived elapsed later.
+func sample(addr string, r *header, t1 time.Time, elapsed time.Duration) (*Sample, error) {
+	if r.Stratum == 0 {
+		return nil, &KissError{Code: string(r.ReferenceID[:])}
+	}
+	if r.leap() == LeapUnsynchronized || r.Stratum > maxStratum || r.TransmitTime == 0 {
+		return nil, errUnsynchronized
+	}
+	t4 := t1.Add(elapsed)
+	t2 := toTime(r.ReceiveTime, t1)
+	t3 := toTime(r.TransmitTime, t1)
+	s := &Sample{
+		Server:         addr,
+		Time:           t4,
+		Offset:         (t2.Sub(t1) + t3.Sub(t4)) / 2,
+		Delay:          max(elapsed-t3.Sub(t2), 0),
+		Leap:           r.leap(),
+		Stratum:        int(r.Stratum),
+		Poll:           int(r.Poll),
+		Precision:      time.Duration(math.Ldexp(float64(time.Second), int(r.Precision))),
+		RootDelay:      shortDuration(r.RootDelay),
+		RootDispersion: shortDuration(r.RootDispersion),
+	}
+	if r.Stratum == 1 {
+		s.ReferenceID = string(trimNUL(r.ReferenceID[:]))
+	} else {
+		s.ReferenceID = net.IP(r.ReferenceID[:]).String()
+	}
+	return s, nil
+}
+
+func trimNUL(b []byte) []byte {
+	for len(b) > 0 && b[len(b)-1] == 0 {
+		b = b[:len(b)-1]
+	}
+	return b
+}
@@ -0,0 +1,268 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ntp
+
+import (
+	"bytes"
+	"context"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// toNTP converts a time to an NTP timestamp.
+func toNTP(t time.Time) uint64 {
+	secs := uint64(t.Unix() + ntpEpochOffset)
+	return secs<<32 | uint64(t.Nanosecond())<<32/1e9
+}
+
+// ntsKeys are the keys of a cookie of a fakeServer.
+type ntsKeys struct {
+	c2s, s2c cipher.AEAD
+}
+
+// fakeServer is an NTP server whose clock is ahead by offset.
+type fakeServer struct {
+	conn *net.UDPConn
+
+	mu      sync.Mutex
+	stratum uint8
+	leap    Leap
+	kiss    string
+	offset  time.Duration
+	cookies map[string]ntsKeys
+	// tamper corrupts the authenticators of responses.
+	tamper bool
+}
+
+func newFakeServer(t *testing.T, offset time.Duration) *fakeServer {
+	t.Helper()
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeServer{conn: conn, stratum: 2, offset: offset, cookies: map[string]ntsKeys{}}
+	t.Cleanup(func() { conn.Close() })
+	go s.serve()
+	return s
+}
+
+func (s *fakeServer) addr() string {
+	return s.conn.LocalAddr().String()
+}
+
+// set changes the server under its lock.
+func (s *fakeServer) set(f func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f()
+}
+
+// newCookie returns a new cookie for keys.
+func (s *fakeServer) newCookie(keys ntsKeys) []byte {
+	c := make([]byte, 64)
+	rand.Read(c)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cookies[string(c)] = keys
+	return c
+}
+
+func (s *fakeServer) serve() {
+	b := make([]byte, 2048)
+	for {
+		n, from, err := s.conn.ReadFromUDP(b)
+		if err != nil {
+			return
+		}
+		if resp := s.respond(b[:n]); resp != nil {
+			s.conn.WriteToUDP(resp, from)
+		}
+	}
+}
+
+func (s *fakeServer) respond(req []byte) []byte {
+	s.mu.Lock()
+	now := time.Now().Add(s.offset)
+	stratum, leap, kiss := s.stratum, s.leap, s.kiss
+	s.mu.Unlock()
+	var q header
+	if _, err := binary.Decode(req, binary.BigEndian, &q); err != nil || q.mode() != modeClient {
+		return nil
+	}
+	r := header{
+		LeapVersionMode: uint8(leap)<<6 | version<<3 | modeServer,
+		Stratum:         stratum,
+		Precision:       -20,
+		RootDelay:       0x100,
+		RootDispersion:  0x100,
+		ReferenceID:     [4]byte{10, 0, 0, 1},
+		OriginTime:      q.TransmitTime,
+		ReceiveTime:     toNTP(now),
+	}
+	if kiss != "" {
+		r.Stratum = 0
+		copy(r.ReferenceID[:], kiss)
+	}
+	r.TransmitTime = toNTP(now.Add(100 * time.Microsecond))
+	resp := r.marshal()
+	if len(req) == headerLen {
+		return resp
+	}
+	return s.respondNTS(req, resp)
+}
+
+// respondNTS authenticates the response to an NTS request.
+func (s *fakeServer) respondNTS(req, resp []byte) []byte {
+	efs, err := parseEFs(req)
+	if err != nil {
+		return nil
+	}
+	var uid []byte
+	var keys ntsKeys
+	var ok bool
+	var cookies []byte
+	for _, f := range efs {
+		switch f.typ {
+		case efUniqueID:
+			uid = f.body
+		case efCookie:
+			s.mu.Lock()
+			keys, ok = s.cookies[string(f.body)]
+			delete(s.cookies, string(f.body))
+			s.mu.Unlock()
+			if !ok {
+				// NAK.
+				resp[1] = 0
+				copy(resp[12:], "NTSN")
+				return appendEF(resp, efUniqueID, uid)
+			}
+			cookies = appendEF(cookies, efCookie, s.newCookie(keys))
+		case efPlaceholder:
+			cookies = appendEF(cookies, efCookie, s.newCookie(keys))
+		case efAuthenticator:
+			nonce, ct, err := parseAuthenticator(f.body)
+			if err != nil || !ok {
+				return nil
+			}
+			if _, err := keys.c2s.Open(nil, nonce, ct, req[:f.off]); err != nil {
+				return nil
+			}
+		}
+	}
+	resp = appendEF(resp, efUniqueID, uid)
+	nonce := make([]byte, sivNonceLen)
+	rand.Read(nonce)
+	ct := keys.s2c.Seal(nil, nonce, cookies, resp)
+	s.mu.Lock()
+	if s.tamper {
+		ct[0] ^= 1
+	}
+	s.mu.Unlock()
+	return appendEF(resp, efAuthenticator, authenticator(nonce, ct))
+}
+
+func TestToTime(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 5e8, time.UTC)
+	if got := toTime(toNTP(now), now); !got.Equal(now) {
+		t.Errorf("toTime(toNTP(%v)) = %v", now, got)
+	}
+	// After 2036, timestamps are of era 1.
+	later := time.Date(2040, 1, 1, 0, 0, 0, 0, time.UTC)
+	if got := toTime(toNTP(later), now); !got.Equal(later) {
+		t.Errorf("toTime(toNTP(%v)) = %v", later, got)
+	}
+	if got, want := shortDuration(0x18000), 1500*time.Millisecond; got != want {
+		t.Errorf("shortDuration(0x18000) = %v, want %v", got, want)
+	}
+}
+
+func TestQuery(t *testing.T) {
+	s := newFakeServer(t, 2*time.Second)
+	got, err := Query(context.Background(), s.addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := got.Offset - 2*time.Second; d.Abs() > 20*time.Millisecond {
+		t.Errorf("offset = %v, want 2s", got.Offset)
+	}
+	if got.Delay < 0 || got.Delay > 20*time.Millisecond || got.Stratum != 2 || got.ReferenceID != "10.0.0.1" {
+		t.Errorf("sample = %+v", got)
+	}
+	if got.RootDelay != time.Second>>8 || got.Precision != time.Duration(1e9>>20) {
+		t.Errorf("root delay %v, precision %v", got.RootDelay, got.Precision)
+	}
+
+	s.set(func() { s.kiss = "RATE" })
+	var kiss *KissError
+	if _, err := Query(context.Background(), s.addr()); !errors.As(err, &kiss) || kiss.Code != "RATE" {
+		t.Errorf("Query = %v, want kiss-o'-death RATE", err)
+	}
+	s.set(func() { s.kiss, s.leap = "", LeapUnsynchronized })
+	if _, err := Query(context.Background(), s.addr()); !errors.Is(err, errUnsynchronized) {
+		t.Errorf("Query = %v, want %v", err, errUnsynchronized)
+	}
+}
+
+func TestQueryTimeout(t *testing.T) {
+	// Nothing answers.
+	c, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := Query(ctx, c.LocalAddr().String()); err == nil {
+		t.Error("Query succeeded")
+	}
+}
+
+func TestParseConfig(t *testing.T) {
+	got, err := ParseConfig(strings.NewReader(`# servers
+driftfile /var/lib/ntp/drift
+server time.cloudflare.com nts iburst
+pool pool.ntp.org iburst # more
+server 10.0.0.1:1123
+server
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Server{
+		{Host: "time.cloudflare.com", NTS: true, IBurst: true},
+		{Host: "pool.ntp.org", IBurst: true},
+		{Host: "10.0.0.1:1123"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("ParseConfig = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("server %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestEFs(t *testing.T) {
+	b := appendEF(make([]byte, headerLen), efCookie, []byte{1, 2, 3, 4, 5})
+	b = appendEF(b, efUniqueID, nil)
+	efs, err := parseEFs(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(efs) != 2 || efs[0].typ != efCookie || !bytes.Equal(efs[0].body, []byte{1, 2, 3, 4, 5, 0, 0, 0}) || efs[1].off != headerLen+12 {
+		t.Errorf("parseEFs = %+v", efs)
+	}
+	if _, err := parseEFs(b[:len(b)-1]); err == nil {
+		t.Error("parseEFs of a truncated packet succeeded")
+	}
+}
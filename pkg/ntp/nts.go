@@ -0,0 +1,389 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ntp
+
+import (
+	"bytes"
+	"context"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultNTSKEPort is the port of NTS key establishment servers.
+const DefaultNTSKEPort = 4460
+
+// ntskeALPN is the ALPN protocol of NTS key establishment.
+const ntskeALPN = "ntske/1"
+
+// Types of NTS-KE records. The high bit of the type marks critical
+// records.
+const (
+	recEnd            = 0
+	recNextProtocol   = 1
+	recError          = 2
+	recWarning        = 3
+	recAEAD           = 4
+	recNewCookie      = 5
+	recServer         = 6
+	recPort           = 7
+	recCritical       = 0x8000
+	protocolNTPv4     = 0
+	aeadAESSIVCMAC256 = 15
+)
+
+// Types of NTP extension fields of NTS.
+const (
+	efUniqueID      = 0x0104
+	efCookie        = 0x0204
+	efPlaceholder   = 0x0304
+	efAuthenticator = 0x0404
+)
+
+// maxCookies is how many cookies a session keeps, as RFC 8915 suggests.
+const maxCookies = 8
+
+// maxKERecords bounds the records of a key establishment.
+const maxKERecords = 64 << 10
+
+// ErrNoCookies is returned by queries of NTS sessions that have used all
+// their cookies. A new key establishment makes a new session.
+var ErrNoCookies = errors.New("no NTS cookies left")
+
+// NTS is a session with an NTS server, from a key establishment.
+type NTS struct {
+	// Server is the address of the NTP server, as host:port.
+	Server string
+
+	c2s, s2c cipher.AEAD
+
+	mu      sync.Mutex
+	cookies [][]byte
+}
+
+// KeyExchange establishes an NTS session with the NTS-KE server at addr,
+// as host or host:port. config, which may be nil, configures TLS.
+func KeyExchange(ctx context.Context, addr string, config *tls.Config) (*NTS, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+		addr = net.JoinHostPort(addr, strconv.Itoa(DefaultNTSKEPort))
+	}
+	if config == nil {
+		config = &tls.Config{}
+	}
+	config = config.Clone()
+	config.MinVersion = tls.VersionTLS13
+	config.NextProtos = []string{ntskeALPN}
+	if config.ServerName == "" {
+		config.ServerName = host
+	}
+
+	d := tls.Dialer{Config: config}
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, 10*time.Second)
+		defer cancel()
+	}
+	c, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	conn := c.(*tls.Conn)
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if p := conn.ConnectionState().NegotiatedProtocol; p != ntskeALPN {
+		return nil, fmt.Errorf("server negotiated %q, want %q", p, ntskeALPN)
+	}
+
+	var req []byte
+	req = appendRecord(req, recCritical|recNextProtocol, binary.BigEndian.AppendUint16(nil, protocolNTPv4))
+	req = appendRecord(req, recCritical|recAEAD, binary.BigEndian.AppendUint16(nil, aeadAESSIVCMAC256))
+	req = appendRecord(req, recCritical|recEnd, nil)
+	if _, err := conn.Write(req); err != nil {
+		return nil, err
+	}
+
+	n := &NTS{Server: net.JoinHostPort(host, strconv.Itoa(DefaultPort))}
+	if err := n.readResponse(io.LimitReader(conn, maxKERecords)); err != nil {
+		return nil, err
+	}
+	if n.c2s, err = exportKey(conn, 0); err != nil {
+		return nil, err
+	}
+	if n.s2c, err = exportKey(conn, 1); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+func appendRecord(b []byte, typ uint16, body []byte) []byte {
+	b = binary.BigEndian.AppendUint16(b, typ)
+	b = binary.BigEndian.AppendUint16(b, uint16(len(body)))
+	return append(b, body...)
+}
+
+// readResponse reads the records of the server, up to the end of
+// message.
+func (n *NTS) readResponse(r io.Reader) error {
+	var protocol, aead bool
+	host, port, _ := net.SplitHostPort(n.Server)
+	for {
+		var hdr [4]byte
+		if _, err := io.ReadFull(r, hdr[:]); err != nil {
+			return fmt.Errorf("reading NTS-KE records: %w", err)
+		}
+		typ := binary.BigEndian.Uint16(hdr[:])
+		body := make([]byte, binary.BigEndian.Uint16(hdr[2:]))
+		if _, err := io.ReadFull(r, body); err != nil {
+			return fmt.Errorf("reading NTS-KE records: %w", err)
+		}
+		switch typ &^ recCritical {
+		case recEnd:
+			if !protocol || !aead {
+				return errors.New("NTS-KE server did not agree on the protocol and AEAD")
+			}
+			if len(n.cookies) == 0 {
+				return errors.New("NTS-KE server sent no cookies")
+			}
+			n.Server = net.JoinHostPort(host, port)
+			return nil
+		case recNextProtocol:
+			if len(body) != 2 || binary.BigEndian.Uint16(body) != protocolNTPv4 {
+				return fmt.Errorf("NTS-KE server chose protocols %x, want NTPv4", body)
+			}
+			protocol = true
+		case recAEAD:
+			if len(body) != 2 || binary.BigEndian.Uint16(body) != aeadAESSIVCMAC256 {
+				return fmt.Errorf("NTS-KE server chose AEAD %x, want AES-SIV-CMAC-256", body)
+			}
+			aead = true
+		case recError:
+			return fmt.Errorf("NTS-KE server error %x", body)
+		case recWarning:
+		case recNewCookie:
+			if len(n.cookies) < maxCookies {
+				n.cookies = append(n.cookies, body)
+			}
+		case recServer:
+			host = string(body)
+		case recPort:
+			if len(body) != 2 {
+				return errors.New("invalid NTS-KE port record")
+			}
+			port = strconv.Itoa(int(binary.BigEndian.Uint16(body)))
+		default:
+			if typ&recCritical != 0 {
+				return fmt.Errorf("unknown critical NTS-KE record %d", typ&^recCritical)
+			}
+		}
+	}
+}
+
+// exportKey exports the key of the AEAD from client to server (dir 0),
+// or from server to client (dir 1).
+func exportKey(conn *tls.Conn, dir byte) (cipher.AEAD, error) {
+	cs := conn.ConnectionState()
+	context := []byte{0, protocolNTPv4, 0, aeadAESSIVCMAC256, dir}
+	key, err := cs.ExportKeyingMaterial("EXPORTER-network-time-security", context, sivKeyLen)
+	if err != nil {
+		return nil, err
+	}
+	return newSIV(key)
+}
+
+// Cookies returns how many cookies the session has left.
+func (n *NTS) Cookies() int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return len(n.cookies)
+}
+
+// Query asks the server of the session for the time, authenticated.
+// Each query uses a cookie, and gets new ones.
+func (n *NTS) Query(ctx context.Context) (*Sample, error) {
+	n.mu.Lock()
+	if len(n.cookies) == 0 {
+		n.mu.Unlock()
+		return nil, ErrNoCookies
+	}
+	cookie := n.cookies[0]
+	n.cookies = n.cookies[1:]
+	// Ask for enough new cookies to fill the jar again.
+	placeholders := maxCookies - len(n.cookies) - 1
+	n.mu.Unlock()
+
+	uid := make([]byte, 32)
+	rand.Read(uid)
+	nonce := make([]byte, sivNonceLen)
+	rand.Read(nonce)
+	req := func(pkt []byte) []byte {
+		pkt = appendEF(pkt, efUniqueID, uid)
+		pkt = appendEF(pkt, efCookie, cookie)
+		for range placeholders {
+			pkt = appendEF(pkt, efPlaceholder, make([]byte, len(cookie)))
+		}
+		return appendEF(pkt, efAuthenticator, authenticator(nonce, n.c2s.Seal(nil, nonce, nil, pkt)))
+	}
+	return query(ctx, n.Server, req, func(resp []byte) error {
+		cookies, err := n.verify(resp, uid)
+		if err != nil {
+			return err
+		}
+		n.mu.Lock()
+		defer n.mu.Unlock()
+		for _, c := range cookies {
+			if len(n.cookies) < maxCookies {
+				n.cookies = append(n.cookies, c)
+			}
+		}
+		return nil
+	})
+}
+
+// appendEF appends an extension field, padded to a multiple of 4 bytes.
+func appendEF(b []byte, typ uint16, body []byte) []byte {
+	l := 4 + (len(body)+3)&^3
+	b = binary.BigEndian.AppendUint16(b, typ)
+	b = binary.BigEndian.AppendUint16(b, uint16(l))
+	b = append(b, body...)
+	return append(b, make([]byte, l-4-len(body))...)
+}
+
+// authenticator returns the body of an authenticator field.
+func authenticator(nonce, ciphertext []byte) []byte {
+	b := binary.BigEndian.AppendUint16(nil, uint16(len(nonce)))
+	b = binary.BigEndian.AppendUint16(b, uint16(len(ciphertext)))
+	b = append(b, nonce...)
+	b = append(b, make([]byte, (4-len(nonce)%4)%4)...)
+	b = append(b, ciphertext...)
+	return append(b, make([]byte, (4-len(ciphertext)%4)%4)...)
+}
+
+// ef is an extension field.
+type ef struct {
+	typ  uint16
+	off  int
+	body []byte
+}
+
+// parseEFs parses the extension fields of a packet, after the header.
+func parseEFs(pkt []byte) ([]ef, error) {
+	var efs []ef
+	for off := headerLen; off < len(pkt); {
+		if len(pkt)-off < 4 {
+			return nil, errors.New("truncated extension field")
+		}
+		typ := binary.BigEndian.Uint16(pkt[off:])
+		l := int(binary.BigEndian.Uint16(pkt[off+2:]))
+		if l < 4 || l%4 != 0 || off+l > len(pkt) {
+			return nil, fmt.Errorf("invalid extension field length %d", l)
+		}
+		efs = append(efs, ef{typ: typ, off: off, body: pkt[off+4 : off+l]})
+		off += l
+	}
+	return efs, nil
+}
+
+// parseAuthenticator returns the nonce and ciphertext of an authenticator.
+func parseAuthenticator(body []byte) (nonce, ciphertext []byte, err error) {
+	if len(body) < 4 {
+		return nil, nil, errors.New("truncated NTS authenticator")
+	}
+	nl := int(binary.BigEndian.Uint16(body))
+	cl := int(binary.BigEndian.Uint16(body[2:]))
+	np := (nl + 3) &^ 3
+	if 4+np+cl > len(body) {
+		return nil, nil, errors.New("truncated NTS authenticator")
+	}
+	return body[4 : 4+nl], body[4+np : 4+np+cl], nil
+}
+
+var errNotAuthentic = errors.New("response is not authentic")
+
+// verify checks that the response is to the request with the unique ID
+// uid and authentic, and returns the new cookies in it.
+func (n *NTS) verify(resp, uid []byte) ([][]byte, error) {
+	efs, err := parseEFs(resp)
+	if err != nil {
+		return nil, err
+	}
+	var ours bool
+	for _, f := range efs {
+		switch f.typ {
+		case efUniqueID:
+			ours = bytes.Equal(f.body, uid)
+		case efAuthenticator:
+			if !ours {
+				return nil, errNotAuthentic
+			}
+			nonce, ct, err := parseAuthenticator(f.body)
+			if err != nil {
+				return nil, err
+			}
+			pt, err := n.s2c.Open(nil, nonce, ct, resp[:f.off])
+			if err != nil {
+				return nil, errNotAuthentic
+			}
+			var cookies [][]byte
+			inner, err := parseEFs(append(make([]byte, headerLen), pt...))
+			if err != nil {
+				return nil, err
+			}
+			for _, e := range inner {
+				if e.typ == efCookie {
+					cookies = append(cookies, bytes.Clone(e.body))
+				}
+			}
+			return cookies, nil
+		}
+	}
+	// A NAK is the one kiss that is not authenticated.
+	if ours && resp[1] == 0 && string(resp[12:16]) == "NTSN" {
+		return nil, &KissError{Code: "NTSN"}
+	}
+	return nil, errNotAuthentic
+}
+
+// IgnoreCertTime returns config, changed so that certificates are verified
+// ignoring their validity period. Before the clock is set, they cannot be
+// checked against it.
+func IgnoreCertTime(config *tls.Config) *tls.Config {
+	if config == nil {
+		config = &tls.Config{}
+	}
+	config = config.Clone()
+	roots := config.RootCAs
+	config.InsecureSkipVerify = true
+	config.VerifyConnection = func(cs tls.ConnectionState) error {
+		if len(cs.PeerCertificates) == 0 {
+			return errors.New("no certificates")
+		}
+		leaf := cs.PeerCertificates[0]
+		opts := x509.VerifyOptions{
+			DNSName:       cs.ServerName,
+			Roots:         roots,
+			Intermediates: x509.NewCertPool(),
+			CurrentTime:   leaf.NotBefore,
+		}
+		for _, c := range cs.PeerCertificates[1:] {
+			opts.Intermediates.AddCert(c)
+		}
+		_, err := leaf.Verify(opts)
+		return err
+	}
+	return config
+}
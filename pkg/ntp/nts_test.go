@@ -0,0 +1,189 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ntp
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// certificate returns a self-signed certificate for localhost, valid
+// from notBefore to notAfter, and a pool with it.
+func certificate(t *testing.T, notBefore, notAfter time.Time) (tls.Certificate, *x509.CertPool) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "localhost"},
+		DNSNames:              []string{"localhost"},
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: cert}, pool
+}
+
+// newKEServer starts an NTS-KE server for the NTP server s, and returns
+// its address and the pool of its certificate.
+func newKEServer(t *testing.T, s *fakeServer, notBefore, notAfter time.Time) (string, *x509.CertPool) {
+	t.Helper()
+	cert, pool := certificate(t, notBefore, notAfter)
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		NextProtos:   []string{ntskeALPN},
+		MinVersion:   tls.VersionTLS13,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+				s.keyExchange(c.(*tls.Conn))
+			}()
+		}
+	}()
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+	return net.JoinHostPort("localhost", port), pool
+}
+
+// keyExchange answers a key establishment with 8 cookies.
+func (s *fakeServer) keyExchange(c *tls.Conn) {
+	for {
+		var hdr [4]byte
+		if _, err := io.ReadFull(c, hdr[:]); err != nil {
+			return
+		}
+		if _, err := io.CopyN(io.Discard, c, int64(binary.BigEndian.Uint16(hdr[2:]))); err != nil {
+			return
+		}
+		if binary.BigEndian.Uint16(hdr[:])&^recCritical == recEnd {
+			break
+		}
+	}
+	var keys ntsKeys
+	var err error
+	if keys.c2s, err = exportKey(c, 0); err != nil {
+		return
+	}
+	if keys.s2c, err = exportKey(c, 1); err != nil {
+		return
+	}
+	_, port, _ := net.SplitHostPort(s.addr())
+	p, _ := strconv.Atoi(port)
+	var resp []byte
+	resp = appendRecord(resp, recCritical|recNextProtocol, []byte{0, protocolNTPv4})
+	resp = appendRecord(resp, recAEAD, []byte{0, aeadAESSIVCMAC256})
+	resp = appendRecord(resp, recServer, []byte("127.0.0.1"))
+	resp = appendRecord(resp, recPort, binary.BigEndian.AppendUint16(nil, uint16(p)))
+	resp = appendRecord(resp, 0x4321, []byte("ignored"))
+	for range maxCookies {
+		resp = appendRecord(resp, recNewCookie, s.newCookie(keys))
+	}
+	resp = appendRecord(resp, recCritical|recEnd, nil)
+	c.Write(resp)
+}
+
+func TestNTS(t *testing.T) {
+	s := newFakeServer(t, -3*time.Second)
+	ke, pool := newKEServer(t, s, time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+	ctx := context.Background()
+
+	if _, err := KeyExchange(ctx, ke, nil); err == nil {
+		t.Fatal("KeyExchange without the certificate succeeded")
+	}
+	n, err := KeyExchange(ctx, ke, &tls.Config{RootCAs: pool})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.Server != s.addr() || n.Cookies() != maxCookies {
+		t.Fatalf("session with %s has %d cookies, want %s and %d", n.Server, n.Cookies(), s.addr(), maxCookies)
+	}
+	// Each query gets a new cookie for the one it used.
+	for range 2 * maxCookies {
+		got, err := n.Query(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d := got.Offset + 3*time.Second; d.Abs() > 20*time.Millisecond {
+			t.Errorf("offset = %v, want -3s", got.Offset)
+		}
+	}
+	if n.Cookies() != maxCookies {
+		t.Errorf("%d cookies left, want %d", n.Cookies(), maxCookies)
+	}
+
+	// Responses that are not authentic are not answers.
+	s.set(func() { s.tamper = true })
+	if _, err := n.Query(ctx); !errors.Is(err, errNotAuthentic) {
+		t.Errorf("Query of a tampered response = %v, want %v", err, errNotAuthentic)
+	}
+	s.set(func() { s.tamper = false })
+
+	// The server forgets its cookies.
+	s.set(func() { clear(s.cookies) })
+	var kiss *KissError
+	if _, err := n.Query(ctx); !errors.As(err, &kiss) || kiss.Code != "NTSN" {
+		t.Errorf("Query with a stale cookie = %v, want NTSN", err)
+	}
+
+	n.cookies = nil
+	if _, err := n.Query(ctx); !errors.Is(err, ErrNoCookies) {
+		t.Errorf("Query without cookies = %v, want %v", err, ErrNoCookies)
+	}
+}
+
+func TestIgnoreCertTime(t *testing.T) {
+	s := newFakeServer(t, 0)
+	// The clock is years ahead of the certificate, as if it had not been
+	// set.
+	ke, pool := newKEServer(t, s, time.Now().AddDate(5, 0, 0), time.Now().AddDate(6, 0, 0))
+	ctx := context.Background()
+	config := &tls.Config{RootCAs: pool}
+	if _, err := KeyExchange(ctx, ke, config); err == nil {
+		t.Fatal("KeyExchange with a certificate of the future succeeded")
+	}
+	if _, err := KeyExchange(ctx, ke, IgnoreCertTime(config)); err != nil {
+		t.Errorf("KeyExchange ignoring the time of the certificate: %v", err)
+	}
+	if _, err := KeyExchange(ctx, ke, IgnoreCertTime(nil)); err == nil {
+		t.Error("KeyExchange ignoring the time of an unknown certificate succeeded")
+	}
+}
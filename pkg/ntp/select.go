@@ -0,0 +1,149 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ntp
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"time"
+)
+
+// filterLen is how many samples of a server the clock filter keeps.
+const filterLen = 8
+
+// dispersionRate is how fast the error of samples grows with their age,
+// 15 ppm as in RFC 5905.
+const dispersionRate = 15e-6
+
+// filter is the clock filter of RFC 5905 for one server. It keeps the
+// last samples, and trusts the one with the lowest delay, which was the
+// least disturbed by queueing.
+type filter struct {
+	samples []*Sample
+}
+
+// add adds a sample, dropping the oldest.
+func (f *filter) add(s *Sample) {
+	if len(f.samples) == filterLen {
+		f.samples = f.samples[1:]
+	}
+	f.samples = append(f.samples, s)
+}
+
+func (f *filter) reset() {
+	f.samples = nil
+}
+
+// best returns the sample with the lowest delay, and the jitter of the
+// offsets of all samples around it.
+func (f *filter) best() (*Sample, time.Duration) {
+	if len(f.samples) == 0 {
+		return nil, 0
+	}
+	best := slices.MinFunc(f.samples, func(a, b *Sample) int {
+		return cmp.Compare(a.Delay, b.Delay)
+	})
+	var sum float64
+	for _, s := range f.samples {
+		d := (s.Offset - best.Offset).Seconds()
+		sum += d * d
+	}
+	jitter := time.Duration(math.Sqrt(sum/float64(max(len(f.samples)-1, 1))) * float64(time.Second))
+	return best, jitter
+}
+
+// candidate is the best sample of a server, as the selection sees it.
+type candidate struct {
+	sample   *Sample
+	jitter   time.Duration
+	distance time.Duration
+}
+
+// newCandidate returns the candidate of a sample at now, with its root
+// distance grown by its age.
+func newCandidate(s *Sample, jitter time.Duration, now time.Time) candidate {
+	age := max(now.Sub(s.Time), 0)
+	return candidate{
+		sample:   s,
+		jitter:   jitter,
+		distance: s.RootDistance() + jitter + time.Duration(dispersionRate*float64(age)),
+	}
+}
+
+// intersect returns the candidates whose intervals of offset plus or minus
+// root distance overlap the intersection of a majority of them, the
+// truechimers, as in the selection algorithm of RFC 5905. The rest are
+// falsetickers.
+func intersect(cands []candidate) []candidate {
+	type endpoint struct {
+		v   time.Duration
+		typ int
+	}
+	var eps []endpoint
+	for _, c := range cands {
+		o, d := c.sample.Offset, c.distance
+		eps = append(eps, endpoint{o - d, -1}, endpoint{o, 0}, endpoint{o + d, 1})
+	}
+	slices.SortFunc(eps, func(a, b endpoint) int {
+		return cmp.Or(cmp.Compare(a.v, b.v), cmp.Compare(a.typ, b.typ))
+	})
+
+	m := len(cands)
+	for f := 0; 2*f < m; f++ {
+		var low, high time.Duration
+		chime, found := 0, 0
+		for _, e := range eps {
+			chime -= e.typ
+			if chime >= m-f {
+				low = e.v
+				break
+			}
+			if e.typ == 0 {
+				found++
+			}
+		}
+		chime = 0
+		for _, e := range slices.Backward(eps) {
+			chime += e.typ
+			if chime >= m-f {
+				high = e.v
+				break
+			}
+			if e.typ == 0 {
+				found++
+			}
+		}
+		if found > f || low > high {
+			continue
+		}
+		var trues []candidate
+		for _, c := range cands {
+			if c.sample.Offset-c.distance <= high && c.sample.Offset+c.distance >= low {
+				trues = append(trues, c)
+			}
+		}
+		return trues
+	}
+	return nil
+}
+
+// combine returns the offset of the truechimers, weighted by their root
+// distances, and their jitter.
+func combine(trues []candidate) (offset, jitter time.Duration) {
+	var sum, weights float64
+	for _, c := range trues {
+		w := 1 / max(c.distance.Seconds(), 1e-6)
+		sum += w * c.sample.Offset.Seconds()
+		weights += w
+	}
+	off := sum / weights
+	var j float64
+	for _, c := range trues {
+		d := c.sample.Offset.Seconds() - off
+		j += d*d + c.jitter.Seconds()*c.jitter.Seconds()
+	}
+	return time.Duration(off * float64(time.Second)), time.Duration(math.Sqrt(j/float64(len(trues))) * float64(time.Second))
+}
@@ -0,0 +1,69 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ntp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFilter(t *testing.T) {
+	var f filter
+	if s, _ := f.best(); s != nil {
+		t.Errorf("best of no samples = %+v", s)
+	}
+	for i := range 10 {
+		f.add(&Sample{Offset: time.Duration(i) * time.Millisecond, Delay: time.Duration(20-i) * time.Millisecond})
+	}
+	if len(f.samples) != filterLen {
+		t.Errorf("filter keeps %d samples, want %d", len(f.samples), filterLen)
+	}
+	best, jitter := f.best()
+	if best.Offset != 9*time.Millisecond || jitter == 0 {
+		t.Errorf("best = %+v with jitter %v, want the offset of the lowest delay", best, jitter)
+	}
+}
+
+func TestIntersect(t *testing.T) {
+	cand := func(offset, distance time.Duration) candidate {
+		return candidate{sample: &Sample{Offset: offset}, distance: distance}
+	}
+	ms := time.Millisecond
+	for _, tt := range []struct {
+		name  string
+		cands []candidate
+		want  int
+	}{
+		{name: "one", cands: []candidate{cand(5*ms, ms)}, want: 1},
+		{name: "agree", cands: []candidate{cand(5*ms, 2*ms), cand(6*ms, 2*ms), cand(4*ms, 2*ms)}, want: 3},
+		{name: "falseticker", cands: []candidate{cand(5*ms, 2*ms), cand(6*ms, 2*ms), cand(900*ms, 2*ms)}, want: 2},
+		{name: "no majority", cands: []candidate{cand(5*ms, ms), cand(900*ms, ms)}, want: 0},
+		{name: "two of four", cands: []candidate{cand(0, ms), cand(0, ms), cand(500*ms, ms), cand(900*ms, ms)}, want: 0},
+		{name: "three of four", cands: []candidate{cand(0, ms), cand(ms, ms), cand(ms/2, 2*ms), cand(900*ms, ms)}, want: 3},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got := intersect(tt.cands)
+			if len(got) != tt.want {
+				t.Fatalf("intersect = %d truechimers, want %d", len(got), tt.want)
+			}
+			for _, c := range got {
+				if c.sample.Offset > 100*ms {
+					t.Errorf("falseticker at %v selected", c.sample.Offset)
+				}
+			}
+		})
+	}
+}
+
+func TestCombine(t *testing.T) {
+	offset, _ := combine([]candidate{
+		{sample: &Sample{Offset: 10 * time.Millisecond}, distance: time.Millisecond},
+		{sample: &Sample{Offset: 20 * time.Millisecond}, distance: 3 * time.Millisecond},
+	})
+	// Weighted 3:1 by the inverse distances.
+	if want := 12500 * time.Microsecond; (offset - want).Abs() > time.Microsecond {
+		t.Errorf("combine = %v, want %v", offset, want)
+	}
+}
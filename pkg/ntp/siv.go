@@ -0,0 +1,169 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ntp
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+)
+
+// siv is AEAD_AES_SIV_CMAC_256 of RFC 5297, the AEAD that all NTS servers
+// implement. The nonce is the last component of the associated data, as
+// in section 6 of the RFC.
+type siv struct {
+	mac, ctr cipher.Block
+}
+
+const (
+	sivKeyLen   = 32
+	sivNonceLen = 16
+)
+
+var errOpen = errors.New("message authentication failed")
+
+// newSIV returns the AEAD for a key of 32 bytes: the CMAC key, then the
+// CTR key.
+func newSIV(key []byte) (cipher.AEAD, error) {
+	if len(key) != sivKeyLen {
+		return nil, fmt.Errorf("AES-SIV-CMAC-256 key of %d bytes, want %d", len(key), sivKeyLen)
+	}
+	mac, err := aes.NewCipher(key[:16])
+	if err != nil {
+		return nil, err
+	}
+	ctr, err := aes.NewCipher(key[16:])
+	if err != nil {
+		return nil, err
+	}
+	return &siv{mac: mac, ctr: ctr}, nil
+}
+
+func (*siv) NonceSize() int { return sivNonceLen }
+func (*siv) Overhead() int  { return aes.BlockSize }
+
+// Seal returns dst with the synthetic IV and the ciphertext appended.
+func (s *siv) Seal(dst, nonce, plaintext, ad []byte) []byte {
+	return s.seal(dst, plaintext, ad, nonce)
+}
+
+// seal seals plaintext with any number of associated data components.
+func (s *siv) seal(dst, plaintext []byte, ad ...[]byte) []byte {
+	v := s.s2v(plaintext, ad...)
+	ret, out := sliceForAppend(dst, len(v)+len(plaintext))
+	copy(out, v[:])
+	s.xorCTR(out[len(v):], plaintext, v)
+	return ret
+}
+
+// Open returns dst with the plaintext appended, if the ciphertext is
+// authentic.
+func (s *siv) Open(dst, nonce, ciphertext, ad []byte) ([]byte, error) {
+	if len(ciphertext) < aes.BlockSize {
+		return nil, errOpen
+	}
+	var v [aes.BlockSize]byte
+	copy(v[:], ciphertext)
+	ciphertext = ciphertext[aes.BlockSize:]
+	ret, out := sliceForAppend(dst, len(ciphertext))
+	s.xorCTR(out, ciphertext, v)
+	if t := s.s2v(out, ad, nonce); subtle.ConstantTimeCompare(t[:], v[:]) != 1 {
+		clear(out)
+		return nil, errOpen
+	}
+	return ret, nil
+}
+
+// xorCTR en- or decrypts src into dst in CTR mode, from the IV v with the
+// 31st bits of its last two words cleared.
+func (s *siv) xorCTR(dst, src []byte, v [aes.BlockSize]byte) {
+	v[8] &= 0x7f
+	v[12] &= 0x7f
+	cipher.NewCTR(s.ctr, v[:]).XORKeyStream(dst, src)
+}
+
+// s2v is the S2V function of the RFC over the associated data components
+// and the plaintext.
+func (s *siv) s2v(plaintext []byte, ad ...[]byte) [aes.BlockSize]byte {
+	var zero [aes.BlockSize]byte
+	d := s.cmac(zero[:])
+	for _, c := range ad {
+		d = dbl(d)
+		m := s.cmac(c)
+		subtle.XORBytes(d[:], d[:], m[:])
+	}
+	var t []byte
+	if len(plaintext) >= aes.BlockSize {
+		t = append([]byte(nil), plaintext...)
+		end := t[len(t)-aes.BlockSize:]
+		subtle.XORBytes(end, end, d[:])
+	} else {
+		d = dbl(d)
+		var p [aes.BlockSize]byte
+		copy(p[:], plaintext)
+		p[len(plaintext)] = 0x80
+		subtle.XORBytes(p[:], p[:], d[:])
+		t = p[:]
+	}
+	return s.cmac(t)
+}
+
+// cmac is AES-CMAC of RFC 4493.
+func (s *siv) cmac(m []byte) [aes.BlockSize]byte {
+	var l [aes.BlockSize]byte
+	s.mac.Encrypt(l[:], l[:])
+	k1 := dbl(l)
+
+	var last [aes.BlockSize]byte
+	n := (len(m) + aes.BlockSize - 1) / aes.BlockSize
+	if n > 0 && len(m)%aes.BlockSize == 0 {
+		copy(last[:], m[(n-1)*aes.BlockSize:])
+		subtle.XORBytes(last[:], last[:], k1[:])
+	} else {
+		n = max(n, 1)
+		k2 := dbl(k1)
+		rest := m[(n-1)*aes.BlockSize:]
+		copy(last[:], rest)
+		last[len(rest)] = 0x80
+		subtle.XORBytes(last[:], last[:], k2[:])
+	}
+
+	var x [aes.BlockSize]byte
+	for i := range n - 1 {
+		subtle.XORBytes(x[:], x[:], m[i*aes.BlockSize:(i+1)*aes.BlockSize])
+		s.mac.Encrypt(x[:], x[:])
+	}
+	subtle.XORBytes(x[:], x[:], last[:])
+	s.mac.Encrypt(x[:], x[:])
+	return x
+}
+
+// dbl multiplies b by x in GF(2^128).
+func dbl(b [aes.BlockSize]byte) [aes.BlockSize]byte {
+	var r [aes.BlockSize]byte
+	for i := range aes.BlockSize - 1 {
+		r[i] = b[i]<<1 | b[i+1]>>7
+	}
+	r[aes.BlockSize-1] = b[aes.BlockSize-1] << 1
+	if b[0]&0x80 != 0 {
+		r[aes.BlockSize-1] ^= 0x87
+	}
+	return r
+}
+
+// sliceForAppend extends in by n bytes, as the AEADs of the standard
+// library do.
+func sliceForAppend(in []byte, n int) (head, tail []byte) {
+	if total := len(in) + n; cap(in) >= total {
+		head = in[:total]
+	} else {
+		head = make([]byte, total)
+		copy(head, in)
+	}
+	tail = head[len(in):]
+	return
+}
@@ -0,0 +1,71 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ntp
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func unhex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(strings.ReplaceAll(s, " ", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestCMAC(t *testing.T) {
+	// RFC 4493, section 4. The CMAC key is the first half of the SIV
+	// key.
+	a, err := newSIV(append(unhex(t, "2b7e1516 28aed2a6 abf71588 09cf4f3c"), make([]byte, 16)...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := a.(*siv)
+	for _, tt := range []struct{ m, want string }{
+		{"", "bb1d6929 e9593728 7fa37d12 9b756746"},
+		{"6bc1bee2 2e409f96 e93d7e11 7393172a", "070a16b4 6b4d4144 f79bdd9d d04a287c"},
+		{"6bc1bee2 2e409f96 e93d7e11 7393172a ae2d8a57 1e03ac9c 9eb76fac 45af8e51 30c81c46 a35ce411", "dfa66747 de9ae630 30ca3261 1497c827"},
+	} {
+		if got := s.cmac(unhex(t, tt.m)); !bytes.Equal(got[:], unhex(t, tt.want)) {
+			t.Errorf("cmac(%s) = %x, want %s", tt.m, got, tt.want)
+		}
+	}
+}
+
+func TestSIV(t *testing.T) {
+	// RFC 5297, appendix A.1.
+	a, err := newSIV(unhex(t, "fffefdfc fbfaf9f8 f7f6f5f4 f3f2f1f0 f0f1f2f3 f4f5f6f7 f8f9fafb fcfdfeff"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := a.(*siv)
+	ad := unhex(t, "10111213 14151617 18191a1b 1c1d1e1f 20212223 24252627")
+	pt := unhex(t, "11223344 55667788 99aabbcc ddee")
+	want := unhex(t, "85632d07 c6e8f37f 950acd32 0a2ecc93 40c02b96 90c4dc04 daef7f6a fe5c")
+	if got := s.seal(nil, pt, ad); !bytes.Equal(got, want) {
+		t.Errorf("seal = %x, want %x", got, want)
+	}
+
+	nonce := bytes.Repeat([]byte{7}, sivNonceLen)
+	for _, pt := range [][]byte{nil, pt, bytes.Repeat(pt, 10)} {
+		ct := a.Seal([]byte("prefix"), nonce, pt, ad)
+		got, err := a.Open(nil, nonce, ct[len("prefix"):], ad)
+		if err != nil || !bytes.Equal(got, pt) {
+			t.Errorf("Open(Seal(%x)) = %x, %v", pt, got, err)
+		}
+		ct[len(ct)-1] ^= 1
+		if _, err := a.Open(nil, nonce, ct[len("prefix"):], ad); err == nil {
+			t.Errorf("Open of a modified ciphertext of %x succeeded", pt)
+		}
+		if _, err := a.Open(nil, nonce[1:], a.Seal(nil, nonce, pt, ad), ad); err == nil {
+			t.Errorf("Open with another nonce of %x succeeded", pt)
+		}
+	}
+}
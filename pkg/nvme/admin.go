@@ -0,0 +1,354 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nvme
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+// DefaultFormatTimeout is used for Format NVM commands when no timeout
+// is given. Erasing a large drive takes far longer than the
+// kernel's default admin timeout.
+const DefaultFormatTimeout = 10 * time.Minute
+
+// SecureErase selects the Secure Erase Settings of a Format NVM command.
+type SecureErase uint8
+
+const (
+	// NoSecureErase requests no secure erase.
+	NoSecureErase SecureErase = 0
+	// UserDataErase erases all user data.
+	UserDataErase SecureErase = 1
+	// CryptoErase erases all user data by deleting the encryption key.
+	CryptoErase SecureErase = 2
+)
+
+// FormatOptions are the parameters of a Format NVM command.
+type FormatOptions struct {
+	// LBAFormat is the index of the LBA format to use.
+	LBAFormat uint8
+	// SecureErase selects the erase performed while formatting.
+	SecureErase SecureErase
+	// PI is the protection information type, 0 to disable.
+	PI uint8
+	// PILFirst places protection information in the first bytes of metadata.
+	PILFirst bool
+	// ExtendedMetadata transfers metadata as part of an extended LBA.
+	ExtendedMetadata bool
+	// Timeout for the command; zero selects DefaultFormatTimeout.
+	Timeout time.Duration
+}
+
+func timeoutMS(d time.Duration) uint32 {
+	if d == 0 {
+		d = DefaultFormatTimeout
+	}
+	return uint32(d.Milliseconds())
+}
+
+// Format issues a Format NVM command for nsid. Use NSIDAll to format all
+// namespaces.
+func (d *Device) Format(nsid uint32, o FormatOptions) error {
+	if o.LBAFormat > 0x3f {
+		return fmt.Errorf("LBA format %d out of range", o.LBAFormat)
+	}
+	if o.SecureErase > CryptoErase {
+		return fmt.Errorf("secure erase setting %d out of range", o.SecureErase)
+	}
+	if o.PI > 7 {
+		return fmt.Errorf("protection information type %d out of range", o.PI)
+	}
+	cdw10 := uint32(o.LBAFormat&0xf) | uint32(o.LBAFormat>>4)<<12
+	if o.ExtendedMetadata {
+		cdw10 |= 1 << 4
+	}
+	cdw10 |= uint32(o.PI) << 5
+	if o.PILFirst {
+		cdw10 |= 1 << 8
+	}
+	cdw10 |= uint32(o.SecureErase) << 9
+	c := &Command{
+		Opcode:    OpFormatNVM,
+		NSID:      nsid,
+		CDW10:     cdw10,
+		TimeoutMS: timeoutMS(o.Timeout),
+	}
+	if _, err := d.AdminCommand(c, nil); err != nil {
+		return fmt.Errorf("format namespace %#x: %w", nsid, err)
+	}
+	return nil
+}
+
+// SanitizeAction is the type of sanitize operation to perform.
+type SanitizeAction uint8
+
+const (
+	// SanitizeExitFailure exits a failed sanitize operation.
+	SanitizeExitFailure SanitizeAction = 1
+	// SanitizeBlockErase performs a low level block erase.
+	SanitizeBlockErase SanitizeAction = 2
+	// SanitizeOverwrite overwrites the media with a pattern.
+	SanitizeOverwrite SanitizeAction = 3
+	// SanitizeCryptoErase changes the media encryption keys.
+	SanitizeCryptoErase SanitizeAction = 4
+)
+
+func (a SanitizeAction) String() string {
+	switch a {
+	case SanitizeExitFailure:
+		return "exit-failure"
+	case SanitizeBlockErase:
+		return "block"
+	case SanitizeOverwrite:
+		return "overwrite"
+	case SanitizeCryptoErase:
+		return "crypto"
+	}
+	return fmt.Sprintf("SanitizeAction(%d)", uint8(a))
+}
+
+// SanitizeOptions are the parameters of a Sanitize command.
+type SanitizeOptions struct {
+	Action SanitizeAction
+	// AllowUnrestrictedExit allows leaving a failed sanitize without
+	// SanitizeExitFailure.
+	AllowUnrestrictedExit bool
+	// OverwritePasses is the number of overwrite passes, 1 to 16.
+	// Zero means 16.
+	OverwritePasses uint8
+	// OverwriteInvert inverts the pattern between passes.
+	OverwriteInvert bool
+	// Pattern is the 32-bit overwrite pattern.
+	Pattern uint32
+	// NoDeallocate leaves the media allocated after the sanitize.
+	NoDeallocate bool
+}
+
+// Sanitize starts a sanitize operation. The operation runs in the
+// background; use SanitizeStatus or WaitSanitize to follow it.
+func (d *Device) Sanitize(o SanitizeOptions) error {
+	if o.Action < SanitizeExitFailure || o.Action > SanitizeCryptoErase {
+		return fmt.Errorf("invalid sanitize action %d", o.Action)
+	}
+	if o.OverwritePasses > 16 {
+		return fmt.Errorf("%d overwrite passes, at most 16 allowed", o.OverwritePasses)
+	}
+	cdw10 := uint32(o.Action)
+	if o.AllowUnrestrictedExit {
+		cdw10 |= 1 << 3
+	}
+	cdw10 |= uint32(o.OverwritePasses&0xf) << 4
+	if o.OverwriteInvert {
+		cdw10 |= 1 << 8
+	}
+	if o.NoDeallocate {
+		cdw10 |= 1 << 9
+	}
+	c := &Command{
+		Opcode: OpSanitize,
+		CDW10:  cdw10,
+		CDW11:  o.Pattern,
+	}
+	if _, err := d.AdminCommand(c, nil); err != nil {
+		return fmt.Errorf("sanitize (%v): %w", o.Action, err)
+	}
+	return nil
+}
+
+// SanitizeState is the status of the most recent sanitize operation.
+type SanitizeState uint8
+
+// Sanitize states, as reported in the Sanitize Status log.
+const (
+	SanitizeNever                 SanitizeState = 0
+	SanitizeCompleted             SanitizeState = 1
+	SanitizeInProgress            SanitizeState = 2
+	SanitizeFailed                SanitizeState = 3
+	SanitizeCompletedNoDeallocate SanitizeState = 4
+)
+
+func (s SanitizeState) String() string {
+	switch s {
+	case SanitizeNever:
+		return "never sanitized"
+	case SanitizeCompleted:
+		return "completed"
+	case SanitizeInProgress:
+		return "in progress"
+	case SanitizeFailed:
+		return "failed"
+	case SanitizeCompletedNoDeallocate:
+		return "completed without deallocation"
+	}
+	return fmt.Sprintf("SanitizeState(%d)", uint8(s))
+}
+
+// SanitizeStatus is the Sanitize Status log page.
+type SanitizeStatus struct {
+	// Progress is the fraction complete, numerator of Progress/65536.
+	Progress uint16
+	State    SanitizeState
+	// PassesCompleted is the number of overwrite passes completed.
+	PassesCompleted uint8
+	// GlobalDataErased is set if no user data has been written since
+	// the last sanitize or format.
+	GlobalDataErased bool
+	CDW10            uint32
+	// Estimated times in seconds, 0xffffffff if unknown.
+	EstimatedOverwrite   uint32
+	EstimatedBlockErase  uint32
+	EstimatedCryptoErase uint32
+}
+
+// Percent returns the progress of the sanitize operation in percent.
+func (s *SanitizeStatus) Percent() float64 {
+	if s.State != SanitizeInProgress {
+		return 100
+	}
+	return float64(s.Progress) * 100 / 65536
+}
+
+// SanitizeStatus returns the Sanitize Status log page.
+func (d *Device) SanitizeStatus() (*SanitizeStatus, error) {
+	b := make([]byte, sanitizeLogSize)
+	if err := d.GetLogPage(LogSanitizeStatus, NSIDAll, b); err != nil {
+		return nil, err
+	}
+	return parseSanitizeStatus(b), nil
+}
+
+func parseSanitizeStatus(b []byte) *SanitizeStatus {
+	le := binary.LittleEndian
+	sstat := le.Uint16(b[2:])
+	return &SanitizeStatus{
+		Progress:             le.Uint16(b[0:]),
+		State:                SanitizeState(sstat & 0x7),
+		PassesCompleted:      uint8(sstat>>3) & 0x1f,
+		GlobalDataErased:     sstat&(1<<8) != 0,
+		CDW10:                le.Uint32(b[4:]),
+		EstimatedOverwrite:   le.Uint32(b[8:]),
+		EstimatedBlockErase:  le.Uint32(b[12:]),
+		EstimatedCryptoErase: le.Uint32(b[16:]),
+	}
+}
+
+// WaitSanitize polls the sanitize status every interval until the
+// operation is no longer in progress or ctx is done. If progress is not
+// nil it is called with each status read. A failed sanitize is an error.
+func (d *Device) WaitSanitize(ctx context.Context, interval time.Duration, progress func(*SanitizeStatus)) (*SanitizeStatus, error) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		s, err := d.SanitizeStatus()
+		if err != nil {
+			return nil, err
+		}
+		if progress != nil {
+			progress(s)
+		}
+		switch s.State {
+		case SanitizeInProgress:
+		case SanitizeFailed:
+			return s, fmt.Errorf("sanitize failed")
+		default:
+			return s, nil
+		}
+		select {
+		case <-ctx.Done():
+			return s, ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// NamespaceSpec describes a namespace to create.
+type NamespaceSpec struct {
+	// Size and Capacity are in logical blocks of the chosen format.
+	// A zero Capacity means Size.
+	Size     uint64
+	Capacity uint64
+	// FLBAS selects the LBA format, see IdentifyNamespace.FLBAS.
+	FLBAS uint8
+	// DPS is the end-to-end data protection setting.
+	DPS uint8
+	// Shared allows the namespace to be attached to multiple controllers.
+	Shared bool
+}
+
+// CreateNamespace creates a namespace and returns its identifier. The
+// namespace is not attached to any controller.
+func (d *Device) CreateNamespace(s NamespaceSpec) (uint32, error) {
+	if s.Capacity == 0 {
+		s.Capacity = s.Size
+	}
+	b := make([]byte, identifySize)
+	binary.LittleEndian.PutUint64(b[0:], s.Size)
+	binary.LittleEndian.PutUint64(b[8:], s.Capacity)
+	b[26] = s.FLBAS
+	b[29] = s.DPS
+	if s.Shared {
+		b[30] = 1
+	}
+	c := &Command{
+		Opcode: OpNSManagement,
+		CDW10:  0, // create
+	}
+	nsid, err := d.AdminCommand(c, b)
+	if err != nil {
+		return 0, fmt.Errorf("create namespace: %w", err)
+	}
+	return nsid, nil
+}
+
+// DeleteNamespace deletes namespace nsid. Use NSIDAll to delete all
+// namespaces.
+func (d *Device) DeleteNamespace(nsid uint32) error {
+	c := &Command{
+		Opcode: OpNSManagement,
+		NSID:   nsid,
+		CDW10:  1, // delete
+	}
+	if _, err := d.AdminCommand(c, nil); err != nil {
+		return fmt.Errorf("delete namespace %#x: %w", nsid, err)
+	}
+	return nil
+}
+
+func (d *Device) nsAttachment(nsid uint32, detach bool, controllers []uint16) error {
+	if len(controllers) > 2047 {
+		return fmt.Errorf("%d controllers, at most 2047 allowed", len(controllers))
+	}
+	b := make([]byte, identifySize)
+	binary.LittleEndian.PutUint16(b, uint16(len(controllers)))
+	for i, id := range controllers {
+		binary.LittleEndian.PutUint16(b[2+2*i:], id)
+	}
+	c := &Command{
+		Opcode: OpNSAttachment,
+		NSID:   nsid,
+	}
+	op := "attach"
+	if detach {
+		c.CDW10 = 1
+		op = "detach"
+	}
+	if _, err := d.AdminCommand(c, b); err != nil {
+		return fmt.Errorf("%s namespace %#x: %w", op, nsid, err)
+	}
+	return nil
+}
+
+// AttachNamespace attaches namespace nsid to the given controllers.
+func (d *Device) AttachNamespace(nsid uint32, controllers ...uint16) error {
+	return d.nsAttachment(nsid, false, controllers)
+}
+
+// DetachNamespace detaches namespace nsid from the given controllers.
+func (d *Device) DetachNamespace(nsid uint32, controllers ...uint16) error {
+	return d.nsAttachment(nsid, true, controllers)
+}
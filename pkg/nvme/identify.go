@@ -0,0 +1,278 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nvme
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Identify CNS values.
+const (
+	cnsNamespace       = 0x00
+	cnsController      = 0x01
+	cnsActiveNamespace = 0x02
+)
+
+// identifySize is the size of every Identify data structure.
+const identifySize = 4096
+
+// Optional Admin Command Support (OACS) bits.
+const (
+	OACSSecurity     = 1 << 0
+	OACSFormat       = 1 << 1
+	OACSFirmware     = 1 << 2
+	OACSNSManagement = 1 << 3
+)
+
+// Sanitize Capabilities (SANICAP) bits.
+const (
+	SANICAPCryptoErase = 1 << 0
+	SANICAPBlockErase  = 1 << 1
+	SANICAPOverwrite   = 1 << 2
+)
+
+// IdentifyController is the decoded Identify Controller data structure.
+// Only the fields commonly needed outside of vendor tooling are decoded.
+type IdentifyController struct {
+	VID      uint16
+	SSVID    uint16
+	Serial   string
+	Model    string
+	Firmware string
+	RAB      uint8
+	IEEE     [3]byte
+	CMIC     uint8
+	MDTS     uint8
+	CNTLID   uint16
+	Version  uint32
+	OACS     uint16
+	ACL      uint8
+	AERL     uint8
+	FRMW     uint8
+	LPA      uint8
+	ELPE     uint8
+	NPSS     uint8
+	WCTEMP   uint16
+	CCTEMP   uint16
+	TNVMCAP  Uint128
+	UNVMCAP  Uint128
+	SANICAP  uint32
+	SQES     uint8
+	CQES     uint8
+	NN       uint32
+	ONCS     uint16
+	FNA      uint8
+	VWC      uint8
+	SubNQN   string
+}
+
+// String prints the controller identity as indented JSON.
+func (c *IdentifyController) String() string {
+	s, err := json.MarshalIndent(c, "", "\t")
+	if err != nil {
+		return fmt.Sprintf("%v", err)
+	}
+	return string(s)
+}
+
+// VersionString returns the NVMe version the controller implements, e.g. "1.4.0".
+func (c *IdentifyController) VersionString() string {
+	return fmt.Sprintf("%d.%d.%d", c.Version>>16, (c.Version>>8)&0xff, c.Version&0xff)
+}
+
+// FirmwareSlots returns the number of firmware slots the controller supports.
+func (c *IdentifyController) FirmwareSlots() int {
+	return int(c.FRMW>>1) & 0x7
+}
+
+// LBAFormat describes one LBA format supported by a namespace.
+type LBAFormat struct {
+	// MetadataSize is the number of metadata bytes per LBA.
+	MetadataSize uint16
+	// DataShift is the LBA data size as a power of two.
+	DataShift uint8
+	// RelativePerformance: 0 is best, 3 is degraded.
+	RelativePerformance uint8
+}
+
+// BlockSize returns the LBA data size in bytes, or 0 if the format is unused.
+func (f LBAFormat) BlockSize() uint64 {
+	if f.DataShift < 9 {
+		return 0
+	}
+	return 1 << f.DataShift
+}
+
+// IdentifyNamespace is the decoded Identify Namespace data structure.
+type IdentifyNamespace struct {
+	NSZE   uint64
+	NCAP   uint64
+	NUSE   uint64
+	NSFEAT uint8
+	NLBAF  uint8
+	FLBAS  uint8
+	MC     uint8
+	DPC    uint8
+	DPS    uint8
+	NMIC   uint8
+	RESCAP uint8
+	FPI    uint8
+	NVMCAP Uint128
+	NGUID  [16]byte
+	EUI64  [8]byte
+	LBAF   []LBAFormat
+}
+
+// String prints the namespace identity as indented JSON.
+func (n *IdentifyNamespace) String() string {
+	s, err := json.MarshalIndent(n, "", "\t")
+	if err != nil {
+		return fmt.Sprintf("%v", err)
+	}
+	return string(s)
+}
+
+// FormattedLBA returns the index of the LBA format in use.
+func (n *IdentifyNamespace) FormattedLBA() int {
+	return int(n.FLBAS & 0xf)
+}
+
+// BlockSize returns the data size of the LBA format in use.
+func (n *IdentifyNamespace) BlockSize() uint64 {
+	i := n.FormattedLBA()
+	if i >= len(n.LBAF) {
+		return 0
+	}
+	return n.LBAF[i].BlockSize()
+}
+
+// Size returns the namespace size in bytes.
+func (n *IdentifyNamespace) Size() uint64 {
+	return n.NSZE * n.BlockSize()
+}
+
+func asciiField(b []byte) string {
+	return strings.TrimRight(string(b), " \x00")
+}
+
+func (d *Device) identify(cns uint8, nsid uint32) ([]byte, error) {
+	data := make([]byte, identifySize)
+	c := &Command{
+		Opcode: OpIdentify,
+		NSID:   nsid,
+		CDW10:  uint32(cns),
+	}
+	if _, err := d.AdminCommand(c, data); err != nil {
+		return nil, fmt.Errorf("identify (cns %#x): %w", cns, err)
+	}
+	return data, nil
+}
+
+// IdentifyController returns the controller's Identify Controller data.
+func (d *Device) IdentifyController() (*IdentifyController, error) {
+	b, err := d.identify(cnsController, 0)
+	if err != nil {
+		return nil, err
+	}
+	return parseIdentifyController(b), nil
+}
+
+func parseIdentifyController(b []byte) *IdentifyController {
+	le := binary.LittleEndian
+	c := &IdentifyController{
+		VID:      le.Uint16(b[0:]),
+		SSVID:    le.Uint16(b[2:]),
+		Serial:   asciiField(b[4:24]),
+		Model:    asciiField(b[24:64]),
+		Firmware: asciiField(b[64:72]),
+		RAB:      b[72],
+		CMIC:     b[76],
+		MDTS:     b[77],
+		CNTLID:   le.Uint16(b[78:]),
+		Version:  le.Uint32(b[80:]),
+		OACS:     le.Uint16(b[256:]),
+		ACL:      b[258],
+		AERL:     b[259],
+		FRMW:     b[260],
+		LPA:      b[261],
+		ELPE:     b[262],
+		NPSS:     b[263],
+		WCTEMP:   le.Uint16(b[266:]),
+		CCTEMP:   le.Uint16(b[268:]),
+		TNVMCAP:  uint128(b[280:]),
+		UNVMCAP:  uint128(b[296:]),
+		SANICAP:  le.Uint32(b[328:]),
+		SQES:     b[512],
+		CQES:     b[513],
+		NN:       le.Uint32(b[516:]),
+		ONCS:     le.Uint16(b[520:]),
+		FNA:      b[524],
+		VWC:      b[525],
+		SubNQN:   asciiField(b[768:1024]),
+	}
+	copy(c.IEEE[:], b[73:76])
+	return c
+}
+
+// IdentifyNamespace returns the Identify Namespace data for nsid.
+func (d *Device) IdentifyNamespace(nsid uint32) (*IdentifyNamespace, error) {
+	b, err := d.identify(cnsNamespace, nsid)
+	if err != nil {
+		return nil, err
+	}
+	return parseIdentifyNamespace(b), nil
+}
+
+func parseIdentifyNamespace(b []byte) *IdentifyNamespace {
+	le := binary.LittleEndian
+	n := &IdentifyNamespace{
+		NSZE:   le.Uint64(b[0:]),
+		NCAP:   le.Uint64(b[8:]),
+		NUSE:   le.Uint64(b[16:]),
+		NSFEAT: b[24],
+		NLBAF:  b[25],
+		FLBAS:  b[26],
+		MC:     b[27],
+		DPC:    b[28],
+		DPS:    b[29],
+		NMIC:   b[30],
+		RESCAP: b[31],
+		FPI:    b[32],
+		NVMCAP: uint128(b[48:]),
+	}
+	copy(n.NGUID[:], b[104:120])
+	copy(n.EUI64[:], b[120:128])
+	// NLBAF is zero-based.
+	for i := 0; i <= int(n.NLBAF) && i < 64; i++ {
+		f := b[128+4*i:]
+		n.LBAF = append(n.LBAF, LBAFormat{
+			MetadataSize:        le.Uint16(f),
+			DataShift:           f[2],
+			RelativePerformance: f[3] & 0x3,
+		})
+	}
+	return n
+}
+
+// ActiveNamespaces returns the identifiers of the active namespaces
+// attached to the controller.
+func (d *Device) ActiveNamespaces() ([]uint32, error) {
+	b, err := d.identify(cnsActiveNamespace, 0)
+	if err != nil {
+		return nil, err
+	}
+	var ids []uint32
+	for i := 0; i < len(b); i += 4 {
+		id := binary.LittleEndian.Uint32(b[i:])
+		if id == 0 {
+			break
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
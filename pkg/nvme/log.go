@@ -0,0 +1,262 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nvme
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Log page identifiers.
+const (
+	LogError          uint8 = 0x01
+	LogSMART          uint8 = 0x02
+	LogFirmwareSlot   uint8 = 0x03
+	LogSanitizeStatus uint8 = 0x81
+)
+
+const (
+	smartLogSize    = 512
+	errorEntrySize  = 64
+	fwSlotLogSize   = 512
+	sanitizeLogSize = 512
+)
+
+// Uint128 is an unsigned 128-bit little-endian counter, as used for
+// capacities and SMART statistics.
+type Uint128 struct {
+	Lo, Hi uint64
+}
+
+func uint128(b []byte) Uint128 {
+	return Uint128{
+		Lo: binary.LittleEndian.Uint64(b[0:]),
+		Hi: binary.LittleEndian.Uint64(b[8:]),
+	}
+}
+
+// Big returns u as a big.Int.
+func (u Uint128) Big() *big.Int {
+	v := new(big.Int).SetUint64(u.Hi)
+	v.Lsh(v, 64)
+	return v.Or(v, new(big.Int).SetUint64(u.Lo))
+}
+
+func (u Uint128) String() string {
+	return u.Big().String()
+}
+
+// MarshalJSON encodes u as a JSON number.
+func (u Uint128) MarshalJSON() ([]byte, error) {
+	return []byte(u.String()), nil
+}
+
+// GetLogPage reads log page lid for nsid into data. The length of data
+// selects how many bytes are read and must be a multiple of 4.
+func (d *Device) GetLogPage(lid uint8, nsid uint32, data []byte) error {
+	if len(data) == 0 || len(data)%4 != 0 {
+		return fmt.Errorf("log page length %d is not a non-zero multiple of 4", len(data))
+	}
+	numd := uint32(len(data)/4 - 1)
+	c := &Command{
+		Opcode: OpGetLogPage,
+		NSID:   nsid,
+		CDW10:  uint32(lid) | (numd&0xffff)<<16,
+		CDW11:  numd >> 16,
+	}
+	if _, err := d.AdminCommand(c, data); err != nil {
+		return fmt.Errorf("get log page %#x: %w", lid, err)
+	}
+	return nil
+}
+
+// Critical warning bits in the SMART / Health Information log.
+const (
+	WarnSpare            = 1 << 0
+	WarnTemperature      = 1 << 1
+	WarnReliability      = 1 << 2
+	WarnReadOnly         = 1 << 3
+	WarnVolatileBackup   = 1 << 4
+	WarnPersistentMemory = 1 << 5
+)
+
+var criticalWarnings = []struct {
+	bit  uint8
+	name string
+}{
+	{WarnSpare, "available spare below threshold"},
+	{WarnTemperature, "temperature threshold exceeded"},
+	{WarnReliability, "reliability degraded"},
+	{WarnReadOnly, "media is read only"},
+	{WarnVolatileBackup, "volatile memory backup failed"},
+	{WarnPersistentMemory, "persistent memory region is read only"},
+}
+
+// SMARTLog is the SMART / Health Information log page.
+type SMARTLog struct {
+	CriticalWarning         uint8
+	Temperature             uint16 // Kelvin
+	AvailableSpare          uint8  // percent
+	AvailableSpareThreshold uint8  // percent
+	PercentageUsed          uint8
+	EnduranceGroupWarning   uint8
+	DataUnitsRead           Uint128 // thousands of 512 byte units
+	DataUnitsWritten        Uint128 // thousands of 512 byte units
+	HostReadCommands        Uint128
+	HostWriteCommands       Uint128
+	ControllerBusyTime      Uint128 // minutes
+	PowerCycles             Uint128
+	PowerOnHours            Uint128
+	UnsafeShutdowns         Uint128
+	MediaErrors             Uint128
+	ErrorLogEntries         Uint128
+	WarningTempTime         uint32 // minutes
+	CriticalTempTime        uint32 // minutes
+	TemperatureSensors      [8]uint16
+}
+
+// Healthy reports whether the controller raised no critical warning.
+func (s *SMARTLog) Healthy() bool {
+	return s.CriticalWarning == 0
+}
+
+// Warnings returns a description of each critical warning bit set.
+func (s *SMARTLog) Warnings() []string {
+	var w []string
+	for _, c := range criticalWarnings {
+		if s.CriticalWarning&c.bit != 0 {
+			w = append(w, c.name)
+		}
+	}
+	return w
+}
+
+// Celsius returns the composite temperature in degrees Celsius.
+func (s *SMARTLog) Celsius() int {
+	return int(s.Temperature) - 273
+}
+
+// SMARTLog returns the SMART / Health Information log. Use NSIDAll for
+// the controller-wide log.
+func (d *Device) SMARTLog(nsid uint32) (*SMARTLog, error) {
+	b := make([]byte, smartLogSize)
+	if err := d.GetLogPage(LogSMART, nsid, b); err != nil {
+		return nil, err
+	}
+	return parseSMARTLog(b), nil
+}
+
+func parseSMARTLog(b []byte) *SMARTLog {
+	le := binary.LittleEndian
+	s := &SMARTLog{
+		CriticalWarning:         b[0],
+		Temperature:             le.Uint16(b[1:]),
+		AvailableSpare:          b[3],
+		AvailableSpareThreshold: b[4],
+		PercentageUsed:          b[5],
+		EnduranceGroupWarning:   b[6],
+		DataUnitsRead:           uint128(b[32:]),
+		DataUnitsWritten:        uint128(b[48:]),
+		HostReadCommands:        uint128(b[64:]),
+		HostWriteCommands:       uint128(b[80:]),
+		ControllerBusyTime:      uint128(b[96:]),
+		PowerCycles:             uint128(b[112:]),
+		PowerOnHours:            uint128(b[128:]),
+		UnsafeShutdowns:         uint128(b[144:]),
+		MediaErrors:             uint128(b[160:]),
+		ErrorLogEntries:         uint128(b[176:]),
+		WarningTempTime:         le.Uint32(b[192:]),
+		CriticalTempTime:        le.Uint32(b[196:]),
+	}
+	for i := range s.TemperatureSensors {
+		s.TemperatureSensors[i] = le.Uint16(b[200+2*i:])
+	}
+	return s
+}
+
+// ErrorLogEntry is one entry of the Error Information log.
+type ErrorLogEntry struct {
+	ErrorCount      uint64
+	SQID            uint16
+	CommandID       uint16
+	Status          uint16
+	ParameterError  uint16
+	LBA             uint64
+	NSID            uint32
+	VendorSpecific  uint8
+	TransportType   uint8
+	CommandSpecific uint64
+}
+
+// ErrorLog returns up to n entries of the Error Information log. Entries
+// with a zero error count are unused and are not returned. n is usually
+// IdentifyController.ELPE + 1.
+func (d *Device) ErrorLog(n int) ([]ErrorLogEntry, error) {
+	if n <= 0 {
+		n = 1
+	}
+	b := make([]byte, n*errorEntrySize)
+	if err := d.GetLogPage(LogError, NSIDAll, b); err != nil {
+		return nil, err
+	}
+	return parseErrorLog(b), nil
+}
+
+func parseErrorLog(b []byte) []ErrorLogEntry {
+	le := binary.LittleEndian
+	var entries []ErrorLogEntry
+	for ; len(b) >= errorEntrySize; b = b[errorEntrySize:] {
+		e := ErrorLogEntry{
+			ErrorCount:      le.Uint64(b[0:]),
+			SQID:            le.Uint16(b[8:]),
+			CommandID:       le.Uint16(b[10:]),
+			Status:          le.Uint16(b[12:]) >> 1,
+			ParameterError:  le.Uint16(b[14:]),
+			LBA:             le.Uint64(b[16:]),
+			NSID:            le.Uint32(b[24:]),
+			VendorSpecific:  b[28],
+			TransportType:   b[29],
+			CommandSpecific: le.Uint64(b[32:]),
+		}
+		if e.ErrorCount == 0 {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+// FirmwareSlotInfo is the Firmware Slot Information log.
+type FirmwareSlotInfo struct {
+	// ActiveSlot is the slot the running firmware was loaded from.
+	ActiveSlot int
+	// NextSlot is the slot activated at the next reset, or 0 if unchanged.
+	NextSlot int
+	// Revisions holds the firmware revision in slots 1 to 7. Empty
+	// slots have an empty revision.
+	Revisions [7]string
+}
+
+// FirmwareSlotInfo returns the Firmware Slot Information log.
+func (d *Device) FirmwareSlotInfo() (*FirmwareSlotInfo, error) {
+	b := make([]byte, fwSlotLogSize)
+	if err := d.GetLogPage(LogFirmwareSlot, NSIDAll, b); err != nil {
+		return nil, err
+	}
+	return parseFirmwareSlotInfo(b), nil
+}
+
+func parseFirmwareSlotInfo(b []byte) *FirmwareSlotInfo {
+	f := &FirmwareSlotInfo{
+		ActiveSlot: int(b[0] & 0x7),
+		NextSlot:   int(b[0]>>4) & 0x7,
+	}
+	for i := range f.Revisions {
+		f.Revisions[i] = strings.TrimRight(string(b[8+8*i:16+8*i]), " \x00")
+	}
+	return f
+}
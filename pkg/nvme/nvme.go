@@ -0,0 +1,170 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package nvme issues NVMe admin commands to a controller.
+//
+// Commands are sent through the Linux admin passthrough ioctl
+// (NVME_IOCTL_ADMIN_CMD), which is accepted by both the controller
+// character device (/dev/nvme0) and its namespace block devices
+// (/dev/nvme0n1).
+//
+// The package decodes the data structures most useful for provisioning:
+// Identify Controller and Namespace, the SMART / Health Information log,
+// the Error Information log and the Firmware Slot Information log. It can
+// also format, sanitize and manage namespaces.
+//
+// References:
+//
+//	NVM Express Base Specification, Revision 2.0
+//	https://nvmexpress.org/specifications/
+package nvme
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Admin command opcodes.
+const (
+	OpGetLogPage     uint8 = 0x02
+	OpIdentify       uint8 = 0x06
+	OpNSManagement   uint8 = 0x0d
+	OpFirmwareCommit uint8 = 0x10
+	OpNSAttachment   uint8 = 0x15
+	OpFormatNVM      uint8 = 0x80
+	OpSecuritySend   uint8 = 0x81
+	OpSecurityRecv   uint8 = 0x82
+	OpSanitize       uint8 = 0x84
+)
+
+// NSIDAll addresses all namespaces of a controller.
+const NSIDAll uint32 = 0xffffffff
+
+// Command is an NVMe admin command. It mirrors the fields of the Linux
+// struct nvme_passthru_cmd that callers are expected to fill in; data
+// buffers are passed separately to Commander.AdminCommand.
+type Command struct {
+	Opcode uint8
+	Flags  uint8
+	NSID   uint32
+	CDW2   uint32
+	CDW3   uint32
+	CDW10  uint32
+	CDW11  uint32
+	CDW12  uint32
+	CDW13  uint32
+	CDW14  uint32
+	CDW15  uint32
+
+	// TimeoutMS is the command timeout in milliseconds. Zero selects the
+	// kernel default.
+	TimeoutMS uint32
+}
+
+// Commander sends admin commands to an NVMe controller.
+//
+// data is transferred to or from the controller, depending on the command.
+// The returned result is Dword 0 of the completion queue entry.
+// A non-zero NVMe completion status is returned as a StatusError.
+type Commander interface {
+	AdminCommand(c *Command, data []byte) (uint32, error)
+}
+
+// Device is an NVMe controller.
+type Device struct {
+	Commander
+}
+
+// NewDevice returns a Device that sends commands using c.
+func NewDevice(c Commander) *Device {
+	return &Device{Commander: c}
+}
+
+// Close closes the underlying Commander, if it can be closed.
+func (d *Device) Close() error {
+	if c, ok := d.Commander.(interface{ Close() error }); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// ErrUnsupported is returned when the controller does not advertise
+// support for an operation.
+var ErrUnsupported = errors.New("operation not supported by controller")
+
+// StatusError is an NVMe completion status as returned by the kernel:
+// bits 7:0 are the Status Code, bits 10:8 the Status Code Type, bit 13
+// More and bit 14 Do Not Retry.
+type StatusError uint16
+
+// Status code types.
+const (
+	SCTGeneric         = 0x0
+	SCTCommandSpecific = 0x1
+	SCTMediaError      = 0x2
+	SCTPath            = 0x3
+	SCTVendor          = 0x7
+)
+
+var genericStatus = map[uint8]string{
+	0x01: "invalid command opcode",
+	0x02: "invalid field in command",
+	0x03: "command id conflict",
+	0x04: "data transfer error",
+	0x05: "aborted due to power loss notification",
+	0x06: "internal error",
+	0x07: "command abort requested",
+	0x0b: "invalid namespace or format",
+	0x0c: "command sequence error",
+	0x15: "operation denied",
+	0x1d: "sanitize failed",
+	0x1e: "sanitize in progress",
+	0x20: "namespace is write protected",
+	0x82: "namespace not ready",
+	0x83: "reservation conflict",
+	0x84: "format in progress",
+}
+
+var commandSpecificStatus = map[uint8]string{
+	0x06: "invalid firmware slot",
+	0x07: "invalid firmware image",
+	0x0a: "invalid format",
+	0x0b: "firmware activation requires conventional reset",
+	0x15: "namespace insufficient capacity",
+	0x16: "namespace identifier unavailable",
+	0x18: "namespace already attached",
+	0x19: "namespace is private",
+	0x1a: "namespace not attached",
+	0x1c: "controller list invalid",
+}
+
+// Type returns the Status Code Type.
+func (s StatusError) Type() uint8 {
+	return uint8(s>>8) & 0x7
+}
+
+// Code returns the Status Code.
+func (s StatusError) Code() uint8 {
+	return uint8(s)
+}
+
+// DoNotRetry reports whether the controller indicated that retrying the
+// command is expected to fail again.
+func (s StatusError) DoNotRetry() bool {
+	return s&(1<<14) != 0
+}
+
+func (s StatusError) Error() string {
+	var m map[uint8]string
+	switch s.Type() {
+	case SCTGeneric:
+		m = genericStatus
+	case SCTCommandSpecific:
+		m = commandSpecificStatus
+	}
+	if msg, ok := m[s.Code()]; ok {
+		return fmt.Sprintf("nvme status %#x: %s", uint16(s), msg)
+	}
+	return fmt.Sprintf("nvme status %#x (type %#x, code %#x)", uint16(s), s.Type(), s.Code())
+}
@@ -0,0 +1,95 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nvme
+
+import (
+	"os"
+	"runtime"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// _NVME_IOCTL_ADMIN_CMD is _IOWR('N', 0x41, struct nvme_admin_cmd).
+const _NVME_IOCTL_ADMIN_CMD = 0xc0484e41
+
+// passthruCmd is the Linux struct nvme_passthru_cmd.
+type passthruCmd struct {
+	opcode      uint8
+	flags       uint8
+	rsvd1       uint16
+	nsid        uint32
+	cdw2        uint32
+	cdw3        uint32
+	metadata    uint64
+	addr        uint64
+	metadataLen uint32
+	dataLen     uint32
+	cdw10       uint32
+	cdw11       uint32
+	cdw12       uint32
+	cdw13       uint32
+	cdw14       uint32
+	cdw15       uint32
+	timeoutMS   uint32
+	result      uint32
+}
+
+// ioctlCommander sends admin commands with the passthrough ioctl.
+type ioctlCommander struct {
+	f *os.File
+}
+
+// Open opens the NVMe controller or namespace device at path.
+func Open(path string) (*Device, error) {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	return NewDevice(&ioctlCommander{f: f}), nil
+}
+
+// Close closes the device file.
+func (c *ioctlCommander) Close() error {
+	return c.f.Close()
+}
+
+// AdminCommand implements Commander.
+func (c *ioctlCommander) AdminCommand(cmd *Command, data []byte) (uint32, error) {
+	p := passthruCmd{
+		opcode:    cmd.Opcode,
+		flags:     cmd.Flags,
+		nsid:      cmd.NSID,
+		cdw2:      cmd.CDW2,
+		cdw3:      cmd.CDW3,
+		cdw10:     cmd.CDW10,
+		cdw11:     cmd.CDW11,
+		cdw12:     cmd.CDW12,
+		cdw13:     cmd.CDW13,
+		cdw14:     cmd.CDW14,
+		cdw15:     cmd.CDW15,
+		timeoutMS: cmd.TimeoutMS,
+	}
+
+	// The kernel reads the data address out of the command structure,
+	// so the buffer must stay put for the duration of the call.
+	var pin runtime.Pinner
+	defer pin.Unpin()
+	if len(data) > 0 {
+		pin.Pin(&data[0])
+		p.addr = uint64(uintptr(unsafe.Pointer(&data[0])))
+		p.dataLen = uint32(len(data))
+	}
+
+	r1, _, errno := unix.Syscall(unix.SYS_IOCTL, c.f.Fd(), _NVME_IOCTL_ADMIN_CMD, uintptr(unsafe.Pointer(&p)))
+	if errno != 0 {
+		return 0, &os.PathError{Op: "ioctl NVME_IOCTL_ADMIN_CMD", Path: c.f.Name(), Err: errno}
+	}
+	// A positive return value is the NVMe status field.
+	if r1 != 0 {
+		return p.result, &os.PathError{Op: "ioctl NVME_IOCTL_ADMIN_CMD", Path: c.f.Name(), Err: StatusError(r1)}
+	}
+	return p.result, nil
+}
@@ -0,0 +1,380 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nvme
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeController answers admin commands from canned data and records
+// every command it receives.
+type fakeController struct {
+	cmds     []Command
+	identify map[uint32][]byte // keyed by CNS
+	logs     map[uint8][]byte
+	result   uint32
+	status   StatusError
+}
+
+func (f *fakeController) AdminCommand(c *Command, data []byte) (uint32, error) {
+	f.cmds = append(f.cmds, *c)
+	if f.status != 0 {
+		return 0, f.status
+	}
+	switch c.Opcode {
+	case OpIdentify:
+		copy(data, f.identify[c.CDW10&0xff])
+	case OpGetLogPage:
+		copy(data, f.logs[uint8(c.CDW10)])
+	}
+	return f.result, nil
+}
+
+func (f *fakeController) last() Command {
+	return f.cmds[len(f.cmds)-1]
+}
+
+func pad(s string, n int) []byte {
+	return []byte(s + strings.Repeat(" ", n-len(s)))
+}
+
+func testController() *fakeController {
+	ctrl := make([]byte, identifySize)
+	le := binary.LittleEndian
+	le.PutUint16(ctrl[0:], 0x144d)
+	le.PutUint16(ctrl[2:], 0x144d)
+	copy(ctrl[4:], pad("S4EWNX0R123456", 20))
+	copy(ctrl[24:], pad("Samsung SSD 980 PRO 1TB", 40))
+	copy(ctrl[64:], pad("5B2QGXA7", 8))
+	le.PutUint32(ctrl[80:], 0x10300)
+	le.PutUint16(ctrl[256:], OACSFormat|OACSNSManagement)
+	ctrl[260] = 3 << 1
+	ctrl[262] = 63
+	le.PutUint64(ctrl[280:], 1000204886016)
+	le.PutUint32(ctrl[328:], SANICAPCryptoErase|SANICAPBlockErase)
+	le.PutUint32(ctrl[516:], 1)
+	copy(ctrl[768:], "nqn.1994-11.com.samsung:nvme:980PRO")
+
+	ns := make([]byte, identifySize)
+	le.PutUint64(ns[0:], 1953525168)
+	le.PutUint64(ns[8:], 1953525168)
+	le.PutUint64(ns[16:], 12345)
+	ns[25] = 1 // two formats
+	ns[26] = 1 // using format 1
+	ns[128+2] = 9
+	ns[132+2] = 12
+
+	active := make([]byte, identifySize)
+	le.PutUint32(active[0:], 1)
+	le.PutUint32(active[4:], 3)
+
+	smart := make([]byte, smartLogSize)
+	smart[0] = WarnSpare | WarnReadOnly
+	le.PutUint16(smart[1:], 310)
+	smart[3] = 5
+	smart[4] = 10
+	smart[5] = 2
+	le.PutUint64(smart[32:], 42)
+	le.PutUint64(smart[48:], 0)
+	le.PutUint64(smart[56:], 1) // 2^64 data units written
+	le.PutUint64(smart[128:], 9000)
+	le.PutUint16(smart[200:], 305)
+
+	errs := make([]byte, 2*errorEntrySize)
+	le.PutUint64(errs[0:], 7)
+	le.PutUint16(errs[12:], 0x0002<<1)
+	le.PutUint64(errs[16:], 0xdead)
+	le.PutUint32(errs[24:], 1)
+
+	fw := make([]byte, fwSlotLogSize)
+	fw[0] = 0x21
+	copy(fw[8:], "1.0.0   ")
+	copy(fw[16:], "1.1.0   ")
+
+	san := make([]byte, sanitizeLogSize)
+	le.PutUint16(san[0:], 0x8000)
+	le.PutUint16(san[2:], uint16(SanitizeInProgress)|2<<3)
+	le.PutUint32(san[12:], 120)
+
+	return &fakeController{
+		identify: map[uint32][]byte{
+			cnsController:      ctrl,
+			cnsNamespace:       ns,
+			cnsActiveNamespace: active,
+		},
+		logs: map[uint8][]byte{
+			LogSMART:          smart,
+			LogError:          errs,
+			LogFirmwareSlot:   fw,
+			LogSanitizeStatus: san,
+		},
+	}
+}
+
+func TestIdentifyController(t *testing.T) {
+	f := testController()
+	d := NewDevice(f)
+	c, err := d.IdentifyController()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := f.last(); got.Opcode != OpIdentify || got.CDW10 != cnsController {
+		t.Errorf("command = %+v, want identify controller", got)
+	}
+	if c.VID != 0x144d || c.Serial != "S4EWNX0R123456" || c.Model != "Samsung SSD 980 PRO 1TB" || c.Firmware != "5B2QGXA7" {
+		t.Errorf("identity = %q %q %q %#x", c.Serial, c.Model, c.Firmware, c.VID)
+	}
+	if v := c.VersionString(); v != "1.3.0" {
+		t.Errorf("VersionString() = %q, want 1.3.0", v)
+	}
+	if n := c.FirmwareSlots(); n != 3 {
+		t.Errorf("FirmwareSlots() = %d, want 3", n)
+	}
+	if c.TNVMCAP.String() != "1000204886016" {
+		t.Errorf("TNVMCAP = %v", c.TNVMCAP)
+	}
+	if c.OACS&OACSNSManagement == 0 || c.SANICAP&SANICAPOverwrite != 0 {
+		t.Errorf("OACS %#x SANICAP %#x", c.OACS, c.SANICAP)
+	}
+	if c.SubNQN != "nqn.1994-11.com.samsung:nvme:980PRO" {
+		t.Errorf("SubNQN = %q", c.SubNQN)
+	}
+	if !strings.Contains(c.String(), `"TNVMCAP": 1000204886016`) {
+		t.Errorf("String() does not encode TNVMCAP as a number:\n%s", c)
+	}
+}
+
+func TestIdentifyNamespace(t *testing.T) {
+	f := testController()
+	d := NewDevice(f)
+	n, err := d.IdentifyNamespace(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := f.last(); got.NSID != 1 || got.CDW10 != cnsNamespace {
+		t.Errorf("command = %+v, want identify namespace 1", got)
+	}
+	if len(n.LBAF) != 2 {
+		t.Fatalf("got %d LBA formats, want 2", len(n.LBAF))
+	}
+	if n.BlockSize() != 4096 {
+		t.Errorf("BlockSize() = %d, want 4096", n.BlockSize())
+	}
+	if n.Size() != 1953525168*4096 {
+		t.Errorf("Size() = %d", n.Size())
+	}
+
+	ids, err := d.ActiveNamespaces()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 2 || ids[0] != 1 || ids[1] != 3 {
+		t.Errorf("ActiveNamespaces() = %v, want [1 3]", ids)
+	}
+}
+
+func TestSMARTLog(t *testing.T) {
+	f := testController()
+	d := NewDevice(f)
+	s, err := d.SMARTLog(NSIDAll)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := f.last()
+	if c.Opcode != OpGetLogPage || c.NSID != NSIDAll {
+		t.Errorf("command = %+v, want get log page", c)
+	}
+	if lid, numdl := uint8(c.CDW10), c.CDW10>>16; lid != LogSMART || numdl != smartLogSize/4-1 {
+		t.Errorf("lid %#x numdl %d", lid, numdl)
+	}
+	if s.Healthy() {
+		t.Errorf("Healthy() = true with critical warning %#x", s.CriticalWarning)
+	}
+	if w := s.Warnings(); len(w) != 2 {
+		t.Errorf("Warnings() = %q, want 2 warnings", w)
+	}
+	if s.Celsius() != 37 {
+		t.Errorf("Celsius() = %d, want 37", s.Celsius())
+	}
+	if s.DataUnitsRead.String() != "42" || s.DataUnitsWritten.String() != "18446744073709551616" {
+		t.Errorf("data units read %v written %v", s.DataUnitsRead, s.DataUnitsWritten)
+	}
+	if s.PowerOnHours.Lo != 9000 || s.TemperatureSensors[0] != 305 {
+		t.Errorf("power on hours %v sensor %d", s.PowerOnHours, s.TemperatureSensors[0])
+	}
+}
+
+func TestErrorAndFirmwareLogs(t *testing.T) {
+	d := NewDevice(testController())
+	errs, err := d.ErrorLog(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(errs) != 1 {
+		t.Fatalf("ErrorLog() returned %d entries, want 1", len(errs))
+	}
+	if e := errs[0]; e.ErrorCount != 7 || e.Status != 2 || e.LBA != 0xdead || e.NSID != 1 {
+		t.Errorf("entry = %+v", e)
+	}
+
+	fw, err := d.FirmwareSlotInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fw.ActiveSlot != 1 || fw.NextSlot != 2 || fw.Revisions[0] != "1.0.0" || fw.Revisions[1] != "1.1.0" || fw.Revisions[2] != "" {
+		t.Errorf("firmware slots = %+v", fw)
+	}
+}
+
+func TestGetLogPageLength(t *testing.T) {
+	d := NewDevice(testController())
+	if err := d.GetLogPage(LogSMART, NSIDAll, make([]byte, 3)); err == nil {
+		t.Errorf("GetLogPage with 3 bytes succeeded, want error")
+	}
+}
+
+func TestFormat(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		o    FormatOptions
+		want uint32
+		err  bool
+	}{
+		{name: "plain", o: FormatOptions{LBAFormat: 1}, want: 0x1},
+		{name: "crypto", o: FormatOptions{SecureErase: CryptoErase}, want: 2 << 9},
+		{name: "user data", o: FormatOptions{LBAFormat: 0x12, SecureErase: UserDataErase, PI: 1, PILFirst: true, ExtendedMetadata: true}, want: 0x2 | 1<<4 | 1<<5 | 1<<8 | 1<<9 | 1<<12},
+		{name: "bad erase", o: FormatOptions{SecureErase: 3}, err: true},
+		{name: "bad pi", o: FormatOptions{PI: 8}, err: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			f := testController()
+			err := NewDevice(f).Format(1, tt.o)
+			if (err != nil) != tt.err {
+				t.Fatalf("Format() = %v, want error %v", err, tt.err)
+			}
+			if tt.err {
+				return
+			}
+			c := f.last()
+			if c.Opcode != OpFormatNVM || c.NSID != 1 || c.CDW10 != tt.want {
+				t.Errorf("command = %+v, want cdw10 %#x", c, tt.want)
+			}
+			if c.TimeoutMS != uint32(DefaultFormatTimeout.Milliseconds()) {
+				t.Errorf("timeout = %d ms", c.TimeoutMS)
+			}
+		})
+	}
+}
+
+func TestSanitize(t *testing.T) {
+	f := testController()
+	d := NewDevice(f)
+	if err := d.Sanitize(SanitizeOptions{Action: SanitizeOverwrite, OverwritePasses: 3, OverwriteInvert: true, Pattern: 0xa5a5a5a5}); err != nil {
+		t.Fatal(err)
+	}
+	c := f.last()
+	if want := uint32(3 | 3<<4 | 1<<8); c.Opcode != OpSanitize || c.CDW10 != want || c.CDW11 != 0xa5a5a5a5 {
+		t.Errorf("command = %+v, want cdw10 %#x", c, want)
+	}
+	if err := d.Sanitize(SanitizeOptions{}); err == nil {
+		t.Errorf("Sanitize with no action succeeded, want error")
+	}
+
+	s, err := d.SanitizeStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.State != SanitizeInProgress || s.PassesCompleted != 2 || s.Percent() != 50 || s.EstimatedBlockErase != 120 {
+		t.Errorf("status = %+v", s)
+	}
+}
+
+func TestWaitSanitize(t *testing.T) {
+	f := testController()
+	d := NewDevice(f)
+	var calls int
+	_, err := d.WaitSanitize(context.Background(), time.Millisecond, func(s *SanitizeStatus) {
+		calls++
+		if calls == 3 {
+			binary.LittleEndian.PutUint16(f.logs[LogSanitizeStatus][2:], uint16(SanitizeCompleted))
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 4 {
+		t.Errorf("progress called %d times, want 4", calls)
+	}
+
+	binary.LittleEndian.PutUint16(f.logs[LogSanitizeStatus][2:], uint16(SanitizeFailed))
+	if _, err := d.WaitSanitize(context.Background(), time.Millisecond, nil); err == nil {
+		t.Errorf("WaitSanitize on failed sanitize succeeded, want error")
+	}
+
+	binary.LittleEndian.PutUint16(f.logs[LogSanitizeStatus][2:], uint16(SanitizeInProgress))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := d.WaitSanitize(ctx, time.Hour, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("WaitSanitize with canceled context = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestNamespaceManagement(t *testing.T) {
+	f := testController()
+	f.result = 5
+	d := NewDevice(f)
+	id, err := d.CreateNamespace(NamespaceSpec{Size: 1000, FLBAS: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != 5 {
+		t.Errorf("CreateNamespace() = %d, want 5", id)
+	}
+	if c := f.last(); c.Opcode != OpNSManagement || c.CDW10 != 0 {
+		t.Errorf("command = %+v, want create", c)
+	}
+	if err := d.AttachNamespace(5, 0, 1); err != nil {
+		t.Fatal(err)
+	}
+	if c := f.last(); c.Opcode != OpNSAttachment || c.NSID != 5 || c.CDW10 != 0 {
+		t.Errorf("command = %+v, want attach", c)
+	}
+	if err := d.DetachNamespace(5, 0); err != nil {
+		t.Fatal(err)
+	}
+	if c := f.last(); c.CDW10 != 1 {
+		t.Errorf("command = %+v, want detach", c)
+	}
+	if err := d.DeleteNamespace(5); err != nil {
+		t.Fatal(err)
+	}
+	if c := f.last(); c.Opcode != OpNSManagement || c.CDW10 != 1 || c.NSID != 5 {
+		t.Errorf("command = %+v, want delete", c)
+	}
+}
+
+func TestStatusError(t *testing.T) {
+	f := testController()
+	f.status = StatusError(1<<14 | SCTCommandSpecific<<8 | 0x0a)
+	_, err := NewDevice(f).IdentifyController()
+	var s StatusError
+	if !errors.As(err, &s) {
+		t.Fatalf("error %v is not a StatusError", err)
+	}
+	if !s.DoNotRetry() || s.Type() != SCTCommandSpecific || s.Code() != 0x0a {
+		t.Errorf("status %#x decoded wrong", uint16(s))
+	}
+	if !strings.Contains(err.Error(), "invalid format") {
+		t.Errorf("error %q does not describe the status", err)
+	}
+	if got := StatusError(0x7ff).Error(); !strings.Contains(got, "type 0x7") {
+		t.Errorf("vendor status = %q", got)
+	}
+}
@@ -0,0 +1,233 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sshclient
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Messages and flags of the SSH agent protocol (draft-miller-ssh-agent).
+const (
+	agentFailure           = 5
+	agentRequestIdentities = 11
+	agentRSASHA256         = 2
+	agentRSASHA512         = 4
+	maxAgentMessage        = 256 << 10
+	authSockEnv            = "SSH_AUTH_SOCK"
+
+	// The OpenSSH extensions of agent forwarding.
+	agentChannel = "auth-agent@openssh.com"
+	agentRequest = "auth-agent-req@openssh.com"
+)
+
+var (
+	errNoAgent      = errors.New(authSockEnv + " is not set")
+	errAgentFailure = errors.New("agent refused the request")
+	errAgentReply   = errors.New("bad agent reply")
+	errForwarded    = errors.New("the agent is already forwarded")
+	errNoForward    = errors.New("the server refused agent forwarding")
+)
+
+// Agent is a client of an SSH agent, such as ssh-agent or one forwarded by
+// ssh -A, which signs with keys it does not give away.
+type Agent struct {
+	mu sync.Mutex
+	rw io.ReadWriter
+}
+
+// NewAgent returns a client of the agent at the other end of rw.
+func NewAgent(rw io.ReadWriter) *Agent {
+	return &Agent{rw: rw}
+}
+
+// DialAgent connects to the agent of SSH_AUTH_SOCK.
+func DialAgent() (*Agent, error) {
+	conn, err := dialAgent()
+	if err != nil {
+		return nil, err
+	}
+	return NewAgent(conn), nil
+}
+
+func dialAgent() (net.Conn, error) {
+	sock := os.Getenv(authSockEnv)
+	if sock == "" {
+		return nil, errNoAgent
+	}
+	return net.Dial("unix", sock)
+}
+
+// ForwardAgent connects the agent channels that the server of c opens to the
+// agent of SSH_AUTH_SOCK, as ssh -A does. Sessions ask the server for them
+// with RequestAgentForwarding.
+func ForwardAgent(c *ssh.Client) error {
+	if os.Getenv(authSockEnv) == "" {
+		return errNoAgent
+	}
+	chans := c.HandleChannelOpen(agentChannel)
+	if chans == nil {
+		return errForwarded
+	}
+	go func() {
+		for nc := range chans {
+			conn, err := dialAgent()
+			if err != nil {
+				nc.Reject(ssh.ConnectionFailed, err.Error())
+				continue
+			}
+			ch, reqs, err := nc.Accept()
+			if err != nil {
+				conn.Close()
+				continue
+			}
+			go ssh.DiscardRequests(reqs)
+			go proxy(ch, conn)
+		}
+	}()
+	return nil
+}
+
+// RequestAgentForwarding asks the server to forward the agent to s.
+func RequestAgentForwarding(s *ssh.Session) error {
+	ok, err := s.SendRequest(agentRequest, true, nil)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errNoForward
+	}
+	return nil
+}
+
+// Close closes the connection to the agent.
+func (a *Agent) Close() error {
+	if c, ok := a.rw.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// call sends the request and returns the reply.
+func (a *Agent) call(req []byte) ([]byte, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	msg := binary.BigEndian.AppendUint32(nil, uint32(len(req)))
+	if _, err := a.rw.Write(append(msg, req...)); err != nil {
+		return nil, err
+	}
+	var n [4]byte
+	if _, err := io.ReadFull(a.rw, n[:]); err != nil {
+		return nil, err
+	}
+	size := binary.BigEndian.Uint32(n[:])
+	if size == 0 || size > maxAgentMessage {
+		return nil, fmt.Errorf("%w: %d bytes", errAgentReply, size)
+	}
+	reply := make([]byte, size)
+	if _, err := io.ReadFull(a.rw, reply); err != nil {
+		return nil, err
+	}
+	if reply[0] == agentFailure {
+		return nil, errAgentFailure
+	}
+	return reply, nil
+}
+
+type identitiesAnswer struct {
+	NumKeys uint32 `sshtype:"12"`
+	Keys    []byte `ssh:"rest"`
+}
+
+type identity struct {
+	Blob    []byte
+	Comment string
+	Rest    []byte `ssh:"rest"`
+}
+
+// Signers returns signers for the keys of the agent.
+func (a *Agent) Signers() ([]ssh.Signer, error) {
+	reply, err := a.call([]byte{agentRequestIdentities})
+	if err != nil {
+		return nil, err
+	}
+	var ans identitiesAnswer
+	if err := ssh.Unmarshal(reply, &ans); err != nil {
+		return nil, fmt.Errorf("%w: %w", errAgentReply, err)
+	}
+	var signers []ssh.Signer
+	rest := ans.Keys
+	for range ans.NumKeys {
+		var id identity
+		if err := ssh.Unmarshal(rest, &id); err != nil {
+			return nil, fmt.Errorf("%w: %w", errAgentReply, err)
+		}
+		rest = id.Rest
+		pub, err := ssh.ParsePublicKey(id.Blob)
+		if err != nil {
+			// Agents may hold keys of types we do not know.
+			continue
+		}
+		signers = append(signers, &agentSigner{a: a, pub: pub})
+	}
+	return signers, nil
+}
+
+type signRequest struct {
+	Blob  []byte `sshtype:"13"`
+	Data  []byte
+	Flags uint32
+}
+
+type signResponse struct {
+	Signature []byte `sshtype:"14"`
+}
+
+// agentSigner signs with a key of the agent.
+type agentSigner struct {
+	a   *Agent
+	pub ssh.PublicKey
+}
+
+var _ ssh.AlgorithmSigner = &agentSigner{}
+
+func (s *agentSigner) PublicKey() ssh.PublicKey {
+	return s.pub
+}
+
+func (s *agentSigner) Sign(rand io.Reader, data []byte) (*ssh.Signature, error) {
+	return s.SignWithAlgorithm(rand, data, "")
+}
+
+func (s *agentSigner) SignWithAlgorithm(_ io.Reader, data []byte, algorithm string) (*ssh.Signature, error) {
+	var flags uint32
+	switch algorithm {
+	case ssh.KeyAlgoRSASHA256:
+		flags = agentRSASHA256
+	case ssh.KeyAlgoRSASHA512:
+		flags = agentRSASHA512
+	}
+	reply, err := s.a.call(ssh.Marshal(&signRequest{Blob: s.pub.Marshal(), Data: data, Flags: flags}))
+	if err != nil {
+		return nil, err
+	}
+	var resp signResponse
+	if err := ssh.Unmarshal(reply, &resp); err != nil {
+		return nil, fmt.Errorf("%w: %w", errAgentReply, err)
+	}
+	var sig ssh.Signature
+	if err := ssh.Unmarshal(resp.Signature, &sig); err != nil {
+		return nil, fmt.Errorf("%w: %w", errAgentReply, err)
+	}
+	return &sig, nil
+}
@@ -0,0 +1,221 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sshclient
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/sftp"
+)
+
+var (
+	// ErrIsDir is returned when copying a directory without Recursive.
+	ErrIsDir = errors.New("is a directory")
+
+	// ErrNotRegular is returned when copying a file that is neither
+	// regular nor a directory.
+	ErrNotRegular = errors.New("not a regular file")
+)
+
+// FS is a file tree that Copy copies between: the local one, or that of an
+// SFTP server.
+type FS interface {
+	Open(name string) (io.ReadCloser, error)
+	Create(name string) (io.WriteCloser, error)
+	Stat(name string) (fs.FileInfo, error)
+	ReadDir(name string) ([]fs.FileInfo, error)
+	Mkdir(name string, perm fs.FileMode) error
+	Chmod(name string, mode fs.FileMode) error
+	Chtimes(name string, atime, mtime time.Time) error
+	Join(elem ...string) string
+}
+
+var (
+	_ FS = LocalFS{}
+	_ FS = SFTPFS{}
+)
+
+// LocalFS is the local file tree.
+type LocalFS struct{}
+
+// Open implements FS.Open.
+func (LocalFS) Open(name string) (io.ReadCloser, error) { return os.Open(name) }
+
+// Create implements FS.Create.
+func (LocalFS) Create(name string) (io.WriteCloser, error) { return os.Create(name) }
+
+// Stat implements FS.Stat.
+func (LocalFS) Stat(name string) (fs.FileInfo, error) { return os.Stat(name) }
+
+// Mkdir implements FS.Mkdir.
+func (LocalFS) Mkdir(name string, perm fs.FileMode) error { return os.Mkdir(name, perm) }
+
+// Chmod implements FS.Chmod.
+func (LocalFS) Chmod(name string, mode fs.FileMode) error { return os.Chmod(name, mode) }
+
+// Chtimes implements FS.Chtimes.
+func (LocalFS) Chtimes(name string, atime, mtime time.Time) error {
+	return os.Chtimes(name, atime, mtime)
+}
+
+// Join implements FS.Join.
+func (LocalFS) Join(elem ...string) string { return filepath.Join(elem...) }
+
+// ReadDir implements FS.ReadDir.
+func (LocalFS) ReadDir(name string) ([]fs.FileInfo, error) {
+	ents, err := os.ReadDir(name)
+	if err != nil {
+		return nil, err
+	}
+	fis := make([]fs.FileInfo, 0, len(ents))
+	for _, e := range ents {
+		fi, err := e.Info()
+		if err != nil {
+			return nil, err
+		}
+		fis = append(fis, fi)
+	}
+	return fis, nil
+}
+
+// SFTPFS is the file tree of an SFTP server.
+type SFTPFS struct {
+	*sftp.Client
+}
+
+// Open implements FS.Open.
+func (s SFTPFS) Open(name string) (io.ReadCloser, error) { return s.Client.Open(name) }
+
+// Create implements FS.Create.
+func (s SFTPFS) Create(name string) (io.WriteCloser, error) { return s.Client.Create(name) }
+
+// Mkdir implements FS.Mkdir.
+func (s SFTPFS) Mkdir(name string, perm fs.FileMode) error {
+	if err := s.Client.Mkdir(name); err != nil {
+		return err
+	}
+	return s.Client.Chmod(name, perm)
+}
+
+// CopyOptions are the options of Copy.
+type CopyOptions struct {
+	// Recursive copies directories.
+	Recursive bool
+
+	// Preserve keeps the modes and times of the files. Otherwise new
+	// files get the mode of their source, and existing files keep
+	// theirs.
+	Preserve bool
+
+	// Logf reports the files copied, if set.
+	Logf func(format string, v ...any)
+}
+
+// Copy copies src of from to dst of to, or into dst if it is a directory, as
+// cp does. It copies as much as it can, and returns all errors.
+func Copy(to FS, dst string, from FS, src string, o *CopyOptions) error {
+	fi, err := from.Stat(src)
+	if err != nil {
+		return err
+	}
+	if di, err := to.Stat(dst); err == nil && di.IsDir() {
+		if b := path.Base(filepath.ToSlash(src)); b != "." && b != "/" {
+			dst = to.Join(dst, b)
+		}
+	}
+	return copyTree(to, dst, from, src, fi, o)
+}
+
+func copyTree(to FS, dst string, from FS, src string, fi fs.FileInfo, o *CopyOptions) error {
+	switch {
+	case fi.Mode().IsRegular():
+		return copyFile(to, dst, from, src, fi, o)
+	case !fi.IsDir():
+		return fmt.Errorf("%s: %w", src, ErrNotRegular)
+	case !o.Recursive:
+		return fmt.Errorf("%s: %w", src, ErrIsDir)
+	}
+
+	// Until its contents are copied, the directory must be writable.
+	if err := to.Mkdir(dst, fi.Mode().Perm()|0o700); err != nil {
+		if di, serr := to.Stat(dst); serr != nil || !di.IsDir() {
+			return err
+		}
+	}
+	if o.Logf != nil {
+		o.Logf("%s -> %s", src, dst)
+	}
+	fis, err := from.ReadDir(src)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, cfi := range fis {
+		name := from.Join(src, cfi.Name())
+		if cfi.Mode()&fs.ModeSymlink != 0 {
+			// Links are followed, as scp does.
+			if cfi, err = from.Stat(name); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+		}
+		errs = append(errs, copyTree(to, to.Join(dst, cfi.Name()), from, name, cfi, o))
+	}
+	errs = append(errs, to.Chmod(dst, fi.Mode().Perm()))
+	if o.Preserve {
+		errs = append(errs, to.Chtimes(dst, atime(fi), fi.ModTime()))
+	}
+	return errors.Join(errs...)
+}
+
+func copyFile(to FS, dst string, from FS, src string, fi fs.FileInfo, o *CopyOptions) error {
+	_, err := to.Stat(dst)
+	existed := err == nil
+
+	r, err := from.Open(src)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	w, err := to.Create(dst)
+	if err != nil {
+		return err
+	}
+	if o.Logf != nil {
+		o.Logf("%s -> %s", src, dst)
+	}
+	_, err = io.Copy(w, r)
+	if cerr := w.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", src, err)
+	}
+	if o.Preserve || !existed {
+		if err := to.Chmod(dst, fi.Mode().Perm()); err != nil {
+			return err
+		}
+	}
+	if o.Preserve {
+		return to.Chtimes(dst, atime(fi), fi.ModTime())
+	}
+	return nil
+}
+
+// atime returns the access time of fi where it is known, and the modification
+// time otherwise.
+func atime(fi fs.FileInfo) time.Time {
+	if st, ok := fi.Sys().(*sftp.FileStat); ok {
+		return time.Unix(int64(st.Atime), 0)
+	}
+	return fi.ModTime()
+}
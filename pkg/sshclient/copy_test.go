@@ -0,0 +1,117 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sshclient
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pkg/sftp"
+)
+
+// sftpFS serves the local file tree over SFTP on pipes.
+func sftpFS(t *testing.T) SFTPFS {
+	t.Helper()
+	cr, sw := io.Pipe()
+	sr, cw := io.Pipe()
+	s, err := sftp.NewServer(struct {
+		io.Reader
+		io.WriteCloser
+	}{sr, sw})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		s.Serve()
+		sw.Close()
+	}()
+	c, err := sftp.NewClientPipe(cr, cw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return SFTPFS{c}
+}
+
+func TestCopy(t *testing.T) {
+	src := t.TempDir()
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	for _, f := range []struct {
+		name string
+		mode os.FileMode
+	}{
+		{"logs/boot.log", 0o644},
+		{"logs/run", 0o755},
+		{"logs/old/dmesg", 0o600},
+	} {
+		p := filepath.Join(src, f.name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(f.name), f.mode); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chmod(p, f.mode); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(p, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	remote := sftpFS(t)
+
+	for _, tt := range []struct {
+		name     string
+		to, from FS
+	}{
+		{"put", remote, LocalFS{}},
+		{"get", LocalFS{}, remote},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			dst := t.TempDir()
+			o := &CopyOptions{}
+			if err := Copy(tt.to, dst, tt.from, filepath.Join(src, "logs"), o); !errors.Is(err, ErrIsDir) {
+				t.Errorf("Copy of a directory = %v, want %v", err, ErrIsDir)
+			}
+
+			o.Recursive, o.Preserve = true, true
+			if err := Copy(tt.to, dst, tt.from, filepath.Join(src, "logs"), o); err != nil {
+				t.Fatalf("Copy = %v", err)
+			}
+			for name, mode := range map[string]os.FileMode{
+				"logs/boot.log":  0o644,
+				"logs/run":       0o755,
+				"logs/old/dmesg": 0o600,
+			} {
+				p := filepath.Join(dst, name)
+				b, err := os.ReadFile(p)
+				if err != nil || string(b) != name {
+					t.Errorf("%s = %q, %v, want %q", name, b, err, name)
+					continue
+				}
+				fi, err := os.Stat(p)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if fi.Mode().Perm() != mode || !fi.ModTime().Equal(mtime) {
+					t.Errorf("%s: mode %v, mtime %v, want %v, %v", name, fi.Mode().Perm(), fi.ModTime(), mode, mtime)
+				}
+			}
+
+			// A file is copied to a new name.
+			p := filepath.Join(dst, "run")
+			if err := Copy(tt.to, p, tt.from, filepath.Join(src, "logs/run"), &CopyOptions{}); err != nil {
+				t.Fatalf("Copy = %v", err)
+			}
+			if fi, err := os.Stat(p); err != nil || fi.Mode().Perm() != 0o755 {
+				t.Errorf("new file: %v, want mode 0755", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,303 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sshclient
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+)
+
+var (
+	errForward = errors.New("forwards are [bind_address:]port:host:hostport")
+	errDynamic = errors.New("dynamic forwards are [bind_address:]port")
+	errSOCKS   = errors.New("bad SOCKS request")
+)
+
+// SOCKS5 protocol values, RFC 1928.
+const (
+	socks5         = 5
+	socksNoAuth    = 0
+	socksNoMethods = 0xff
+	socksConnect   = 1
+	socksIPv4      = 1
+	socksDomain    = 3
+	socksIPv6      = 4
+
+	socksSucceeded        = 0
+	socksRefused          = 5
+	socksCmdNotSupported  = 7
+	socksAddrNotSupported = 8
+)
+
+// Forward is a port forward, as given to ssh -L, -R and -D.
+type Forward struct {
+	// Listen is the host:port to listen on. An empty host is all
+	// addresses.
+	Listen string
+	// Connect is the host:port to connect to, or empty for a dynamic
+	// forward, which connects where its SOCKS clients ask.
+	Connect string
+}
+
+func (f *Forward) String() string {
+	if f.Connect == "" {
+		return f.Listen + " (SOCKS)"
+	}
+	return f.Listen + " -> " + f.Connect
+}
+
+// splitForward splits s at the colons that are not in brackets, and removes
+// the brackets. The ssh_config form of forwards, with spaces between the
+// listening and connecting addresses, is split as well.
+func splitForward(s string) []string {
+	s = strings.Join(strings.Fields(s), ":")
+	var fields []string
+	start, brackets := 0, false
+	for i, c := range s {
+		switch {
+		case c == '[':
+			brackets = true
+		case c == ']':
+			brackets = false
+		case c == ':' && !brackets:
+			fields = append(fields, strings.Trim(s[start:i], "[]"))
+			start = i + 1
+		}
+	}
+	return append(fields, strings.Trim(s[start:], "[]"))
+}
+
+// listenAddr joins the bind address and port of a forward. Without a bind
+// address, forwards listen on the loopback address, as for OpenSSH without
+// GatewayPorts; an empty one or "*" is all addresses.
+func listenAddr(f []string) (string, error) {
+	bind, port := "localhost", f[len(f)-1]
+	if len(f) == 2 {
+		bind = f[0]
+	}
+	if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+		return "", err
+	}
+	if bind == "*" {
+		bind = ""
+	}
+	return net.JoinHostPort(bind, port), nil
+}
+
+// ParseForward parses a forward of ssh -L or -R,
+// [bind_address:]port:host:hostport.
+func ParseForward(s string) (*Forward, error) {
+	f := splitForward(s)
+	if len(f) != 3 && len(f) != 4 {
+		return nil, fmt.Errorf("%q: %w", s, errForward)
+	}
+	n := len(f) - 2
+	listen, err := listenAddr(f[:n])
+	if err != nil {
+		return nil, fmt.Errorf("%q: %w", s, errForward)
+	}
+	if _, err := strconv.ParseUint(f[n+1], 10, 16); err != nil {
+		return nil, fmt.Errorf("%q: %w", s, errForward)
+	}
+	return &Forward{Listen: listen, Connect: net.JoinHostPort(f[n], f[n+1])}, nil
+}
+
+// ParseDynamicForward parses a forward of ssh -D, [bind_address:]port.
+func ParseDynamicForward(s string) (*Forward, error) {
+	f := splitForward(s)
+	if len(f) > 2 {
+		return nil, fmt.Errorf("%q: %w", s, errDynamic)
+	}
+	listen, err := listenAddr(f)
+	if err != nil {
+		return nil, fmt.Errorf("%q: %w", s, errDynamic)
+	}
+	return &Forward{Listen: listen}, nil
+}
+
+// ForwardLocal listens on f.Listen, and forwards the connections to it
+// through c, as ssh -L and -D do. It stops when the listener it returns is
+// closed, or c is. logf, if set, logs the connections that fail.
+func ForwardLocal(c *ssh.Client, f *Forward, logf func(format string, v ...any)) (net.Listener, error) {
+	ln, err := net.Listen("tcp", f.Listen)
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		c.Wait()
+		ln.Close()
+	}()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				var err error
+				if f.Connect == "" {
+					err = serveSOCKS(c, conn)
+				} else {
+					err = forwardConn(conn, func() (net.Conn, error) { return c.Dial("tcp", f.Connect) })
+				}
+				if err != nil && logf != nil {
+					logf("forward %v: %v", f, err)
+				}
+			}()
+		}
+	}()
+	return ln, nil
+}
+
+// ForwardRemote asks the server of c to listen on f.Listen, and connects the
+// connections to it to f.Connect, as ssh -R does. It stops when the listener
+// it returns is closed, or c is.
+func ForwardRemote(c *ssh.Client, f *Forward, logf func(format string, v ...any)) (net.Listener, error) {
+	if f.Connect == "" {
+		return nil, fmt.Errorf("%v: %w", f, errForward)
+	}
+	ln, err := c.Listen("tcp", f.Listen)
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				if err := forwardConn(conn, func() (net.Conn, error) { return net.Dial("tcp", f.Connect) }); err != nil && logf != nil {
+					logf("forward %v: %v", f, err)
+				}
+			}()
+		}
+	}()
+	return ln, nil
+}
+
+// forwardConn connects conn to the connection dial returns.
+func forwardConn(conn net.Conn, dial func() (net.Conn, error)) error {
+	defer conn.Close()
+	to, err := dial()
+	if err != nil {
+		return err
+	}
+	proxy(conn, to)
+	return nil
+}
+
+// closeWriter is implemented by both TCP connections and SSH channels.
+type closeWriter interface {
+	CloseWrite() error
+}
+
+// proxy copies between a and b until both directions are done.
+func proxy(a, b io.ReadWriteCloser) {
+	var wg sync.WaitGroup
+	wg.Add(2)
+	cp := func(dst, src io.ReadWriteCloser) {
+		defer wg.Done()
+		io.Copy(dst, src)
+		if c, ok := dst.(closeWriter); ok {
+			c.CloseWrite()
+		}
+	}
+	go cp(a, b)
+	go cp(b, a)
+	wg.Wait()
+	a.Close()
+	b.Close()
+}
+
+// serveSOCKS connects a SOCKS5 client through c to where it asks. Only
+// CONNECT without authentication is supported, as for ssh -D.
+func serveSOCKS(c *ssh.Client, conn net.Conn) error {
+	defer conn.Close()
+	var hdr [2]byte
+	if _, err := io.ReadFull(conn, hdr[:]); err != nil {
+		return err
+	}
+	if hdr[0] != socks5 {
+		return fmt.Errorf("SOCKS version %d: %w", hdr[0], errSOCKS)
+	}
+	methods := make([]byte, hdr[1])
+	if _, err := io.ReadFull(conn, methods); err != nil {
+		return err
+	}
+	if !strings.ContainsRune(string(methods), socksNoAuth) {
+		conn.Write([]byte{socks5, socksNoMethods})
+		return fmt.Errorf("no supported authentication: %w", errSOCKS)
+	}
+	if _, err := conn.Write([]byte{socks5, socksNoAuth}); err != nil {
+		return err
+	}
+
+	var req [4]byte
+	if _, err := io.ReadFull(conn, req[:]); err != nil {
+		return err
+	}
+	reply := func(code byte) error {
+		_, err := conn.Write([]byte{socks5, code, 0, socksIPv4, 0, 0, 0, 0, 0, 0})
+		return err
+	}
+	if req[0] != socks5 {
+		return fmt.Errorf("SOCKS version %d: %w", req[0], errSOCKS)
+	}
+	var host string
+	switch req[3] {
+	case socksIPv4, socksIPv6:
+		ip := make(net.IP, 4)
+		if req[3] == socksIPv6 {
+			ip = make(net.IP, 16)
+		}
+		if _, err := io.ReadFull(conn, ip); err != nil {
+			return err
+		}
+		host = ip.String()
+	case socksDomain:
+		var n [1]byte
+		if _, err := io.ReadFull(conn, n[:]); err != nil {
+			return err
+		}
+		name := make([]byte, n[0])
+		if _, err := io.ReadFull(conn, name); err != nil {
+			return err
+		}
+		host = string(name)
+	default:
+		reply(socksAddrNotSupported)
+		return fmt.Errorf("address type %d: %w", req[3], errSOCKS)
+	}
+	var port [2]byte
+	if _, err := io.ReadFull(conn, port[:]); err != nil {
+		return err
+	}
+	if req[1] != socksConnect {
+		reply(socksCmdNotSupported)
+		return fmt.Errorf("command %d: %w", req[1], errSOCKS)
+	}
+
+	addr := net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port[:]))))
+	to, err := c.Dial("tcp", addr)
+	if err != nil {
+		reply(socksRefused)
+		return fmt.Errorf("%s: %w", addr, err)
+	}
+	if err := reply(socksSucceeded); err != nil {
+		to.Close()
+		return err
+	}
+	proxy(conn, to)
+	return nil
+}
@@ -0,0 +1,185 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sshclient
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+	netproxy "golang.org/x/net/proxy"
+)
+
+func TestParseForward(t *testing.T) {
+	for _, tt := range []struct {
+		in      string
+		dynamic bool
+		want    *Forward
+		err     error
+	}{
+		{in: "8080:example.org:80", want: &Forward{Listen: "localhost:8080", Connect: "example.org:80"}},
+		{in: "*:8080:example.org:80", want: &Forward{Listen: ":8080", Connect: "example.org:80"}},
+		{in: ":8080:example.org:80", want: &Forward{Listen: ":8080", Connect: "example.org:80"}},
+		{in: "[::1]:8080:[fe80::1]:80", want: &Forward{Listen: "[::1]:8080", Connect: "[fe80::1]:80"}},
+		// The form of LocalForward and RemoteForward in ssh_config.
+		{in: "10.0.0.1:8080 example.org:80", want: &Forward{Listen: "10.0.0.1:8080", Connect: "example.org:80"}},
+		{in: "8080:example.org", err: errForward},
+		{in: "8080:example.org:http", err: errForward},
+		{in: "http:example.org:80", err: errForward},
+		{in: "1080", dynamic: true, want: &Forward{Listen: "localhost:1080"}},
+		{in: "0.0.0.0:1080", dynamic: true, want: &Forward{Listen: "0.0.0.0:1080"}},
+		{in: "a:b:1080", dynamic: true, err: errDynamic},
+		{in: "socks", dynamic: true, err: errDynamic},
+	} {
+		parse := ParseForward
+		if tt.dynamic {
+			parse = ParseDynamicForward
+		}
+		got, err := parse(tt.in)
+		if !errors.Is(err, tt.err) || (tt.want != nil && (got == nil || *got != *tt.want)) {
+			t.Errorf("parse(%q) = %v, %v, want %v, %v", tt.in, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+// echo serves TCP connections that echo a line back.
+func echo(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				line, _ := bufio.NewReader(conn).ReadString('\n')
+				io.WriteString(conn, line)
+			}()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func roundTrip(t *testing.T, conn net.Conn) {
+	t.Helper()
+	defer conn.Close()
+	if _, err := io.WriteString(conn, "hello\n"); err != nil {
+		t.Fatal(err)
+	}
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil || line != "hello\n" {
+		t.Errorf("echo = %q, %v, want hello", line, err)
+	}
+}
+
+// client dials a test server that accepts the password "secret".
+func client(t *testing.T, d *Dialer) *ssh.Client {
+	t.Helper()
+	host := newSigner(t)
+	port := serve(t, host, nil)
+	if err := addKnownHost(filepath.Join(os.Getenv("HOME"), ".ssh", "known_hosts"), "127.0.0.1:"+port, host.PublicKey()); err != nil {
+		t.Fatal(err)
+	}
+	d.Prompt = func(string, bool) (string, error) { return "secret", nil }
+	c, err := d.Dial(context.Background(), "root", "127.0.0.1", port)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func TestForward(t *testing.T) {
+	c := client(t, dialer(t))
+	addr := echo(t)
+
+	t.Run("local", func(t *testing.T) {
+		ln, err := ForwardLocal(c, &Forward{Listen: "127.0.0.1:0", Connect: addr}, t.Logf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer ln.Close()
+		conn, err := net.Dial("tcp", ln.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		roundTrip(t, conn)
+	})
+
+	t.Run("dynamic", func(t *testing.T) {
+		ln, err := ForwardLocal(c, &Forward{Listen: "127.0.0.1:0"}, t.Logf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer ln.Close()
+		socks, err := netproxy.SOCKS5("tcp", ln.Addr().String(), nil, netproxy.Direct)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn, err := socks.Dial("tcp", addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		roundTrip(t, conn)
+		if _, err := socks.Dial("tcp", "127.0.0.1:1"); err == nil {
+			t.Errorf("SOCKS connection to a closed port succeeded")
+		}
+	})
+
+	t.Run("remote", func(t *testing.T) {
+		ln, err := ForwardRemote(c, &Forward{Listen: "127.0.0.1:0", Connect: addr}, t.Logf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer ln.Close()
+		conn, err := net.Dial("tcp", ln.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		roundTrip(t, conn)
+	})
+}
+
+func TestForwardAgent(t *testing.T) {
+	d := dialer(t)
+	serveAgent(t, newSigner(t), make(chan uint32, 10))
+	c := client(t, d)
+
+	if err := ForwardAgent(c); err != nil {
+		t.Fatal(err)
+	}
+	if err := ForwardAgent(c); !errors.Is(err, errForwarded) {
+		t.Errorf("second ForwardAgent = %v, want %v", err, errForwarded)
+	}
+	s, err := c.NewSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	stdout, err := s.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := RequestAgentForwarding(s); err != nil {
+		t.Fatal(err)
+	}
+	// The server lists the keys of the forwarded agent.
+	b, err := io.ReadAll(stdout)
+	if err != nil || string(b) != "1 keys\n" {
+		t.Errorf("server got %q, %v, want 1 keys", b, err)
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sshclient
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+var (
+	// ErrUnknownHost is returned when the host key of a server is in no
+	// known_hosts file, and the user did not accept it.
+	ErrUnknownHost = errors.New("host key is not known")
+
+	// ErrHostKeyChanged is returned when the host key of a server is not
+	// the one in the known_hosts files.
+	ErrHostKeyChanged = errors.New("host key has changed")
+)
+
+// knownHostsFiles returns the user and global known_hosts files for t.
+func (d *Dialer) knownHostsFiles(t *Target) (user, global []string) {
+	for _, f := range strings.Fields(d.Get(t.Alias, "UserKnownHostsFile")) {
+		user = append(user, expand(f, t.Alias, t.User))
+	}
+	global = strings.Fields(d.Get(t.Alias, "GlobalKnownHostsFile"))
+	// Distributions put theirs in their own places.
+	etc, _ := filepath.Glob("/etc/*/ssh_known_hosts")
+	return user, append(global, etc...)
+}
+
+// hostKeyCallback checks host keys against the known_hosts files of t. It
+// also returns the host key algorithms of the known keys, so that the server
+// presents one of those.
+func (d *Dialer) hostKeyCallback(t *Target) (ssh.HostKeyCallback, []string, error) {
+	user, global := d.knownHostsFiles(t)
+	var files []string
+	for _, f := range append(append([]string{}, user...), global...) {
+		if _, err := os.Stat(f); err == nil {
+			files = append(files, f)
+		}
+	}
+	known := func(string, net.Addr, ssh.PublicKey) error { return &knownhosts.KeyError{} }
+	if len(files) > 0 {
+		cb, err := knownhosts.New(files...)
+		if err != nil {
+			return nil, nil, err
+		}
+		known = cb
+	}
+
+	strict := strings.ToLower(d.Get(t.Alias, "StrictHostKeyChecking"))
+	cb := func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		err := known(hostname, remote, key)
+		var ke *knownhosts.KeyError
+		if !errors.As(err, &ke) {
+			return err
+		}
+		if len(ke.Want) > 0 {
+			w := ke.Want[0]
+			return fmt.Errorf("%s presents %s key %s, but %s:%d has %s: %w",
+				hostname, key.Type(), ssh.FingerprintSHA256(key), w.Filename, w.Line, ssh.FingerprintSHA256(w.Key), ErrHostKeyChanged)
+		}
+
+		accept := strict == "no" || strict == "off" || strict == "accept-new"
+		if !accept && strict != "yes" && d.Prompt != nil {
+			a, err := d.Prompt(fmt.Sprintf("The %s key of %s is %s.\nAre you sure you want to continue connecting (yes/no)? ",
+				key.Type(), hostname, ssh.FingerprintSHA256(key)), true)
+			if err != nil {
+				return err
+			}
+			accept = strings.TrimSpace(strings.ToLower(a)) == "yes"
+		}
+		if !accept {
+			return fmt.Errorf("%s key %s of %s: %w", key.Type(), ssh.FingerprintSHA256(key), hostname, ErrUnknownHost)
+		}
+		if len(user) == 0 {
+			return nil
+		}
+		d.logf("adding %s to %s", hostname, user[0])
+		return addKnownHost(user[0], hostname, key)
+	}
+	return cb, knownAlgorithms(known, t.Addr), nil
+}
+
+// addKnownHost adds key of hostname to the known_hosts file.
+func addKnownHost(file, hostname string, key ssh.PublicKey) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0o700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(f, knownhosts.Line([]string{knownhosts.Normalize(hostname)}, key))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// placeholderKey is known to no known_hosts file, so that checking it lists
+// the keys that are known.
+type placeholderKey struct{}
+
+func (placeholderKey) Type() string                                 { return "placeholder" }
+func (placeholderKey) Marshal() []byte                              { return []byte("placeholder") }
+func (placeholderKey) Verify(data []byte, sig *ssh.Signature) error { return ErrUnknownHost }
+
+// knownAlgorithms returns the host key algorithms of the keys of addr in the
+// known_hosts files, or nil for the defaults if there are none.
+func knownAlgorithms(known ssh.HostKeyCallback, addr string) []string {
+	var ke *knownhosts.KeyError
+	if err := known(addr, &net.TCPAddr{IP: net.IPv4zero}, placeholderKey{}); !errors.As(err, &ke) {
+		return nil
+	}
+	var algos []string
+	for _, k := range ke.Want {
+		t := []string{k.Key.Type()}
+		if t[0] == ssh.KeyAlgoRSA {
+			t = []string{ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSASHA256, ssh.KeyAlgoRSA}
+		}
+		for _, a := range t {
+			if !slices.Contains(algos, a) {
+				algos = append(algos, a)
+			}
+		}
+	}
+	return algos
+}
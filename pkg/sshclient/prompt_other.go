@@ -0,0 +1,15 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build plan9 || windows
+
+package sshclient
+
+import "errors"
+
+// TerminalPrompt would ask the user on the controlling terminal, which is
+// only found on Unix.
+func TerminalPrompt(prompt string, echo bool) (string, error) {
+	return "", errors.New("no terminal to prompt on")
+}
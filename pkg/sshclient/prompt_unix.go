@@ -0,0 +1,34 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !plan9 && !windows
+
+package sshclient
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// TerminalPrompt asks the user on the controlling terminal, as a
+// Dialer.Prompt, so that prompts work even when stdin is a pipe.
+func TerminalPrompt(prompt string, echo bool) (string, error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return "", err
+	}
+	defer tty.Close()
+	fmt.Fprint(tty, prompt)
+	if echo {
+		s, err := bufio.NewReader(tty).ReadString('\n')
+		return strings.TrimRight(s, "\r\n"), err
+	}
+	b, err := term.ReadPassword(int(tty.Fd()))
+	fmt.Fprintln(tty)
+	return string(b), err
+}
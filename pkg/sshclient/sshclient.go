@@ -0,0 +1,404 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package sshclient implements what the SSH client commands share: dialing
+// servers the way OpenSSH does, with the settings of ssh_config, jump hosts,
+// the keys of an agent and of identity files, and host keys checked against
+// known_hosts files; forwarding ports and the agent; and copying files over
+// SFTP.
+package sshclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	sshconfig "github.com/kevinburke/ssh_config"
+	"golang.org/x/crypto/ssh"
+)
+
+var (
+	errOption = errors.New("options are Key=Value")
+	errNoAuth = errors.New("no way to authenticate")
+)
+
+// Dialer dials SSH servers.
+type Dialer struct {
+	// Config is the ssh_config of the user, if any.
+	Config *sshconfig.Config
+
+	// Options override Config for all hosts, as ssh -o does. The keys are
+	// in lower case; SetOption adds to them.
+	Options map[string]string
+
+	// IdentityFiles are tried before the identity files of Config.
+	IdentityFiles []string
+
+	// Prompt asks the user for passwords, key passphrases and whether to
+	// trust unknown host keys. Without it, only keys authenticate, and
+	// unknown hosts are refused unless StrictHostKeyChecking says
+	// otherwise.
+	Prompt func(prompt string, echo bool) (string, error)
+
+	// DialContext dials the connection to the server, with a net.Dialer
+	// if nil.
+	DialContext func(ctx context.Context, network, addr string) (net.Conn, error)
+
+	// Logf logs what the Dialer does, if set.
+	Logf func(format string, v ...any)
+}
+
+// DefaultConfigFile returns the path of the ssh_config of the user.
+func DefaultConfigFile() string {
+	return filepath.Join(homeDir(), ".ssh", "config")
+}
+
+// LoadConfig reads the ssh_config at path. A missing file is an empty
+// configuration.
+func LoadConfig(path string) (*sshconfig.Config, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &sshconfig.Config{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return sshconfig.Decode(f)
+}
+
+// SetOption sets an option given as Key=Value or "Key Value", as ssh -o
+// takes them.
+func (d *Dialer) SetOption(opt string) error {
+	k, v, ok := strings.Cut(opt, "=")
+	if !ok {
+		k, v, ok = strings.Cut(strings.TrimSpace(opt), " ")
+	}
+	k, v = strings.TrimSpace(k), strings.TrimSpace(v)
+	if !ok || k == "" {
+		return fmt.Errorf("%q: %w", opt, errOption)
+	}
+	if d.Options == nil {
+		d.Options = map[string]string{}
+	}
+	d.Options[strings.ToLower(k)] = v
+	return nil
+}
+
+func (d *Dialer) logf(format string, v ...any) {
+	if d.Logf != nil {
+		d.Logf(format, v...)
+	}
+}
+
+// Get returns the setting key for host, from Options, Config or the
+// defaults of OpenSSH.
+func (d *Dialer) Get(host, key string) string {
+	if v, ok := d.Options[strings.ToLower(key)]; ok {
+		return v
+	}
+	if d.Config != nil {
+		if v, err := d.Config.Get(host, key); err == nil && v != "" {
+			return v
+		}
+	}
+	return sshconfig.Default(key)
+}
+
+// GetAll returns all the settings key for host, as for IdentityFile and
+// LocalForward, from Options or Config.
+func (d *Dialer) GetAll(host, key string) []string {
+	if v, ok := d.Options[strings.ToLower(key)]; ok {
+		return []string{v}
+	}
+	if d.Config != nil {
+		if v, err := d.Config.GetAll(host, key); err == nil {
+			return v
+		}
+	}
+	return nil
+}
+
+func homeDir() string {
+	if h, err := os.UserHomeDir(); err == nil {
+		return h
+	}
+	return "/"
+}
+
+// expand expands ~ and the %h, %u and %% tokens of ssh_config in s.
+func expand(s, host, user string) string {
+	if s == "~" || strings.HasPrefix(s, "~/") {
+		s = filepath.Join(homeDir(), s[1:])
+	}
+	return strings.NewReplacer("%h", host, "%u", user, "%%", "%").Replace(s)
+}
+
+// Target is where Resolve says to connect to.
+type Target struct {
+	// User is the user to log in as.
+	User string
+	// Addr is the host:port of the server.
+	Addr string
+	// Alias is the host as the user named it, which ssh_config
+	// settings apply to.
+	Alias string
+}
+
+// Resolve applies the settings of host to user and port, either of which may
+// be empty to use the configured or default values.
+func (d *Dialer) Resolve(user, host, port string) (*Target, error) {
+	alias := host
+	if hn := d.Get(alias, "HostName"); hn != "" {
+		host = expand(hn, alias, "")
+	}
+	if port == "" {
+		port = d.Get(alias, "Port")
+	}
+	if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+		return nil, fmt.Errorf("port %q: %w", port, err)
+	}
+	if user == "" {
+		user = d.Get(alias, "User")
+	}
+	if user == "" {
+		u, err := currentUser()
+		if err != nil {
+			return nil, err
+		}
+		user = u
+	}
+	return &Target{User: user, Addr: net.JoinHostPort(host, port), Alias: alias}, nil
+}
+
+func currentUser() (string, error) {
+	u, err := user.Current()
+	if err != nil {
+		return "", err
+	}
+	return u.Username, nil
+}
+
+// Dial connects to host as user on port, either of which may be empty for the
+// configured or default values.
+func (d *Dialer) Dial(ctx context.Context, user, host, port string) (*ssh.Client, error) {
+	t, err := d.Resolve(user, host, port)
+	if err != nil {
+		return nil, err
+	}
+	return d.DialTarget(ctx, t)
+}
+
+// DialTarget connects to t.
+func (d *Dialer) DialTarget(ctx context.Context, t *Target) (*ssh.Client, error) {
+	if s := d.Get(t.Alias, "ConnectTimeout"); s != "" {
+		secs, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, fmt.Errorf("ConnectTimeout %q: %w", s, err)
+		}
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(secs)*time.Second)
+		defer cancel()
+	}
+
+	hostKey, algos, err := d.hostKeyCallback(t)
+	if err != nil {
+		return nil, err
+	}
+	auth, done := d.auth(t)
+	defer done()
+	if len(auth) == 0 {
+		return nil, errNoAuth
+	}
+	config := &ssh.ClientConfig{
+		User:              t.User,
+		Auth:              auth,
+		HostKeyCallback:   hostKey,
+		HostKeyAlgorithms: algos,
+	}
+
+	d.logf("connecting to %s as %s", t.Addr, t.User)
+	conn, err := d.dial(ctx, t)
+	if err != nil {
+		return nil, err
+	}
+	// The handshake has no context of its own.
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	c, chans, reqs, err := ssh.NewClientConn(conn, t.Addr, config)
+	if !stop() {
+		if err == nil {
+			c.Close()
+		}
+		return nil, ctx.Err()
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return ssh.NewClient(c, chans, reqs), nil
+}
+
+// ParseDestination splits a destination of ssh, [user@]host[:port] or
+// ssh://[user@]host[:port]. The user and port are empty if not given.
+func ParseDestination(s string) (user, host, port string) {
+	s = strings.TrimPrefix(s, "ssh://")
+	if i := strings.LastIndex(s, "@"); i >= 0 {
+		user, s = s[:i], s[i+1:]
+	}
+	host, port, err := net.SplitHostPort(s)
+	if err != nil {
+		return user, s, ""
+	}
+	return user, host, port
+}
+
+// jumpConn is a connection through a jump host, which it closes with it.
+type jumpConn struct {
+	net.Conn
+	jump *ssh.Client
+}
+
+func (c *jumpConn) Close() error {
+	err := c.Conn.Close()
+	c.jump.Close()
+	return err
+}
+
+// dial connects to t, through the jump hosts of its ProxyJump setting, if
+// any. As with OpenSSH, jump hosts have their own settings of Config, but
+// not Options and IdentityFiles, and do not jump further themselves.
+func (d *Dialer) dial(ctx context.Context, t *Target) (net.Conn, error) {
+	jumps := d.Get(t.Alias, "ProxyJump")
+	if jumps == "" || jumps == "none" {
+		dial := d.DialContext
+		if dial == nil {
+			dial = (&net.Dialer{}).DialContext
+		}
+		return dial(ctx, "tcp", t.Addr)
+	}
+
+	var jump *ssh.Client
+	for hop := range strings.SplitSeq(jumps, ",") {
+		jd := &Dialer{
+			Config:      d.Config,
+			Options:     map[string]string{"proxyjump": "none"},
+			Prompt:      d.Prompt,
+			DialContext: d.DialContext,
+			Logf:        d.Logf,
+		}
+		if prev := jump; prev != nil {
+			jd.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+				conn, err := prev.DialContext(ctx, network, addr)
+				if err != nil {
+					return nil, err
+				}
+				return &jumpConn{Conn: conn, jump: prev}, nil
+			}
+		}
+		user, host, port := ParseDestination(strings.TrimSpace(hop))
+		d.logf("jumping through %s", hop)
+		next, err := jd.Dial(ctx, user, host, port)
+		if err != nil {
+			if jump != nil {
+				jump.Close()
+			}
+			return nil, fmt.Errorf("jump host %s: %w", hop, err)
+		}
+		jump = next
+	}
+	conn, err := jump.DialContext(ctx, "tcp", t.Addr)
+	if err != nil {
+		jump.Close()
+		return nil, err
+	}
+	return &jumpConn{Conn: conn, jump: jump}, nil
+}
+
+// auth returns the authentication methods for t: the keys of the agent and of
+// the identity files, then passwords. done closes the agent connection, once
+// authentication is over.
+func (d *Dialer) auth(t *Target) (methods []ssh.AuthMethod, done func()) {
+	done = func() {}
+	var signers []ssh.Signer
+	if a, err := DialAgent(); err == nil {
+		done = func() { a.Close() }
+		if s, err := a.Signers(); err == nil {
+			d.logf("%d keys from the agent", len(s))
+			signers = append(signers, s...)
+		} else {
+			d.logf("agent: %v", err)
+		}
+	}
+	signers = append(signers, d.identities(t)...)
+	if len(signers) > 0 {
+		methods = append(methods, ssh.PublicKeys(signers...))
+	}
+
+	if d.Prompt != nil {
+		methods = append(methods,
+			ssh.PasswordCallback(func() (string, error) {
+				return d.Prompt(fmt.Sprintf("%s@%s's password: ", t.User, t.Alias), false)
+			}),
+			ssh.KeyboardInteractive(func(name, instruction string, questions []string, echos []bool) ([]string, error) {
+				answers := make([]string, len(questions))
+				for i, q := range questions {
+					a, err := d.Prompt(q, echos[i])
+					if err != nil {
+						return nil, err
+					}
+					answers[i] = a
+				}
+				return answers, nil
+			}),
+		)
+	}
+	return methods, done
+}
+
+// identities reads the keys of the identity files for t. The default files
+// that are missing are skipped.
+func (d *Dialer) identities(t *Target) []ssh.Signer {
+	files := append([]string{}, d.IdentityFiles...)
+	files = append(files, d.GetAll(t.Alias, "IdentityFile")...)
+	if len(files) == 0 {
+		for _, f := range []string{"id_ed25519", "id_ecdsa", "id_rsa"} {
+			files = append(files, filepath.Join("~", ".ssh", f))
+		}
+	}
+
+	var signers []ssh.Signer
+	for _, f := range files {
+		f = expand(f, t.Alias, t.User)
+		b, err := os.ReadFile(f)
+		if err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				d.logf("identity %s: %v", f, err)
+			}
+			continue
+		}
+		s, err := ssh.ParsePrivateKey(b)
+		var missing *ssh.PassphraseMissingError
+		if errors.As(err, &missing) && d.Prompt != nil {
+			var p string
+			p, err = d.Prompt(fmt.Sprintf("Enter passphrase for key '%s': ", f), false)
+			if err == nil {
+				s, err = ssh.ParsePrivateKeyWithPassphrase(b, []byte(p))
+			}
+		}
+		if err != nil {
+			d.logf("identity %s: %v", f, err)
+			continue
+		}
+		d.logf("identity %s: %s", f, s.PublicKey().Type())
+		signers = append(signers, s)
+	}
+	return signers
+}
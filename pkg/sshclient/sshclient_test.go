@@ -0,0 +1,504 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sshclient
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+func newSigner(t *testing.T) ssh.Signer {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// serve serves SSH with host key, accepting the user key or the password
+// "secret", and an SFTP subsystem. It returns the port.
+func serve(t *testing.T, host ssh.Signer, user ssh.PublicKey) string {
+	t.Helper()
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(_ ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if user != nil && bytes.Equal(key.Marshal(), user.Marshal()) {
+				return nil, nil
+			}
+			return nil, errors.New("unknown key")
+		},
+		PasswordCallback: func(_ ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if string(password) == "secret" {
+				return nil, nil
+			}
+			return nil, errors.New("wrong password")
+		},
+	}
+	config.AddHostKey(host)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serveConn(conn, config)
+		}
+	}()
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+	return port
+}
+
+// tcpipReq is the payload of direct-tcpip and forwarded-tcpip channels.
+type tcpipReq struct {
+	Addr       string
+	Port       uint32
+	OriginAddr string
+	OriginPort uint32
+}
+
+// forwardReq is the payload of tcpip-forward requests.
+type forwardReq struct {
+	Addr string
+	Port uint32
+}
+
+// serveConn serves SFTP sessions, the forwards of ssh -L and -R, and tells
+// the sessions that ask for an agent how many keys it has.
+func serveConn(conn net.Conn, config *ssh.ServerConfig) {
+	sc, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	go serveForwards(sc, reqs)
+	for nc := range chans {
+		switch nc.ChannelType() {
+		case "session":
+			go serveSession(sc, nc)
+		case "direct-tcpip":
+			go func() {
+				var req tcpipReq
+				if err := ssh.Unmarshal(nc.ExtraData(), &req); err != nil {
+					nc.Reject(ssh.ConnectionFailed, err.Error())
+					return
+				}
+				conn, err := net.Dial("tcp", net.JoinHostPort(req.Addr, strconv.Itoa(int(req.Port))))
+				if err != nil {
+					nc.Reject(ssh.ConnectionFailed, err.Error())
+					return
+				}
+				ch, reqs, err := nc.Accept()
+				if err != nil {
+					conn.Close()
+					return
+				}
+				go ssh.DiscardRequests(reqs)
+				proxy(ch, conn)
+			}()
+		default:
+			nc.Reject(ssh.UnknownChannelType, nc.ChannelType())
+		}
+	}
+}
+
+func serveSession(sc *ssh.ServerConn, nc ssh.NewChannel) {
+	ch, reqs, err := nc.Accept()
+	if err != nil {
+		return
+	}
+	for req := range reqs {
+		switch {
+		case req.Type == "subsystem" && string(req.Payload[4:]) == "sftp":
+			req.Reply(true, nil)
+			go func() {
+				if s, err := sftp.NewServer(ch); err == nil {
+					s.Serve()
+				}
+				ch.Close()
+			}()
+		case req.Type == agentRequest:
+			req.Reply(true, nil)
+			go func() {
+				defer ch.Close()
+				agent, reqs, err := sc.OpenChannel(agentChannel, nil)
+				if err != nil {
+					fmt.Fprintln(ch, err)
+					return
+				}
+				go ssh.DiscardRequests(reqs)
+				defer agent.Close()
+				signers, err := NewAgent(agent).Signers()
+				if err != nil {
+					fmt.Fprintln(ch, err)
+					return
+				}
+				fmt.Fprintf(ch, "%d keys\n", len(signers))
+			}()
+		default:
+			req.Reply(false, nil)
+		}
+	}
+}
+
+// serveForwards serves the tcpip-forward requests of ssh -R, listening on
+// the loopback address.
+func serveForwards(sc *ssh.ServerConn, reqs <-chan *ssh.Request) {
+	var lns []net.Listener
+	defer func() {
+		for _, ln := range lns {
+			ln.Close()
+		}
+	}()
+	for req := range reqs {
+		var fwd forwardReq
+		if req.Type != "tcpip-forward" || ssh.Unmarshal(req.Payload, &fwd) != nil {
+			req.Reply(false, nil)
+			continue
+		}
+		ln, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(int(fwd.Port))))
+		if err != nil {
+			req.Reply(false, nil)
+			continue
+		}
+		lns = append(lns, ln)
+		port := uint32(ln.Addr().(*net.TCPAddr).Port)
+		req.Reply(true, ssh.Marshal(struct{ Port uint32 }{port}))
+		go func() {
+			for {
+				conn, err := ln.Accept()
+				if err != nil {
+					return
+				}
+				origin := conn.RemoteAddr().(*net.TCPAddr)
+				ch, reqs, err := sc.OpenChannel("forwarded-tcpip", ssh.Marshal(&tcpipReq{
+					Addr:       fwd.Addr,
+					Port:       port,
+					OriginAddr: origin.IP.String(),
+					OriginPort: uint32(origin.Port),
+				}))
+				if err != nil {
+					conn.Close()
+					continue
+				}
+				go ssh.DiscardRequests(reqs)
+				go proxy(ch, conn)
+			}
+		}()
+	}
+}
+
+// dialer returns a Dialer with no agent, identities or known hosts of the
+// user running the test.
+func dialer(t *testing.T) *Dialer {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv(authSockEnv, "")
+	return &Dialer{
+		Options: map[string]string{
+			"globalknownhostsfile":  filepath.Join(home, "ssh_known_hosts"),
+			"stricthostkeychecking": "yes",
+		},
+	}
+}
+
+func TestKnownHosts(t *testing.T) {
+	host, user := newSigner(t), newSigner(t)
+	port := serve(t, host, nil)
+	d := dialer(t)
+	d.Prompt = func(prompt string, echo bool) (string, error) {
+		if echo {
+			t.Errorf("unexpected prompt %q", prompt)
+		}
+		return "secret", nil
+	}
+	ctx := context.Background()
+
+	if _, err := d.Dial(ctx, "root", "127.0.0.1", port); !errors.Is(err, ErrUnknownHost) {
+		t.Fatalf("Dial to unknown host = %v, want %v", err, ErrUnknownHost)
+	}
+
+	if err := d.SetOption("StrictHostKeyChecking=accept-new"); err != nil {
+		t.Fatal(err)
+	}
+	c, err := d.Dial(ctx, "root", "127.0.0.1", port)
+	if err != nil {
+		t.Fatalf("Dial with accept-new = %v", err)
+	}
+	c.Close()
+	b, err := os.ReadFile(filepath.Join(os.Getenv("HOME"), ".ssh", "known_hosts"))
+	if err != nil || !strings.Contains(string(b), strings.TrimSpace(string(ssh.MarshalAuthorizedKey(host.PublicKey())))) {
+		t.Fatalf("known_hosts = %q, %v, want the host key", b, err)
+	}
+
+	if err := d.SetOption("StrictHostKeyChecking yes"); err != nil {
+		t.Fatal(err)
+	}
+	c, err = d.Dial(ctx, "root", "127.0.0.1", port)
+	if err != nil {
+		t.Fatalf("Dial to known host = %v", err)
+	}
+	c.Close()
+
+	// Another server on the same address has another key.
+	other := serve(t, newSigner(t), user.PublicKey())
+	if err := addKnownHost(filepath.Join(os.Getenv("HOME"), ".ssh", "known_hosts"), "127.0.0.1:"+other, host.PublicKey()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Dial(ctx, "root", "127.0.0.1", other); !errors.Is(err, ErrHostKeyChanged) {
+		t.Errorf("Dial to changed host = %v, want %v", err, ErrHostKeyChanged)
+	}
+}
+
+func TestConfig(t *testing.T) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	host := newSigner(t)
+	port := serve(t, host, user.PublicKey())
+	d := dialer(t)
+	home := os.Getenv("HOME")
+
+	key := filepath.Join(home, "node_key")
+	blk, err := ssh.MarshalPrivateKey(priv, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(key, pem.EncodeToMemory(blk), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	config := filepath.Join(home, "config")
+	if err := os.WriteFile(config, []byte("Host node\n\tHostName 127.0.0.1\n\tPort "+port+"\n\tUser root\n\tIdentityFile "+key+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if d.Config, err = LoadConfig(config); err != nil {
+		t.Fatal(err)
+	}
+	if err := addKnownHost(filepath.Join(home, ".ssh", "known_hosts"), "127.0.0.1:"+port, host.PublicKey()); err != nil {
+		t.Fatal(err)
+	}
+
+	target, err := d.Resolve("", "node", "")
+	if err != nil || target.User != "root" || target.Addr != "127.0.0.1:"+port {
+		t.Fatalf("Resolve(node) = %+v, %v, want root at 127.0.0.1:%s", target, err, port)
+	}
+	c, err := d.Dial(context.Background(), "", "node", "")
+	if err != nil {
+		t.Fatalf("Dial(node) = %v", err)
+	}
+	c.Close()
+}
+
+func TestProxyJump(t *testing.T) {
+	bastionKey, hostKey := newSigner(t), newSigner(t)
+	bastion := serve(t, bastionKey, nil)
+	host := serve(t, hostKey, nil)
+	d := dialer(t)
+	d.Prompt = func(string, bool) (string, error) { return "secret", nil }
+	var dialed []string
+	d.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		dialed = append(dialed, addr)
+		return (&net.Dialer{}).DialContext(ctx, network, addr)
+	}
+	// The options of the Dialer do not apply to jump hosts, so the host
+	// keys are in the known_hosts of the user.
+	kh := filepath.Join(os.Getenv("HOME"), ".ssh", "known_hosts")
+	for port, key := range map[string]ssh.Signer{bastion: bastionKey, host: hostKey} {
+		if err := addKnownHost(kh, "127.0.0.1:"+port, key.PublicKey()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, jump := range []string{
+		"root@127.0.0.1:" + bastion,
+		// A second hop through the same bastion.
+		"127.0.0.1:" + bastion + ",ssh://root@127.0.0.1:" + bastion,
+	} {
+		dialed = nil
+		if err := d.SetOption("ProxyJump=" + jump); err != nil {
+			t.Fatal(err)
+		}
+		c, err := d.Dial(context.Background(), "root", "127.0.0.1", host)
+		if err != nil {
+			t.Fatalf("Dial through %s = %v", jump, err)
+		}
+		c.Close()
+		if want := []string{"127.0.0.1:" + bastion}; !slices.Equal(dialed, want) {
+			t.Errorf("Dial through %s dialed %q, want %q", jump, dialed, want)
+		}
+	}
+
+	if err := d.SetOption("ProxyJump=127.0.0.1:1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Dial(context.Background(), "root", "127.0.0.1", host); err == nil || !strings.Contains(err.Error(), "jump host") {
+		t.Errorf("Dial through a closed port = %v, want a jump host error", err)
+	}
+}
+
+func TestParseDestination(t *testing.T) {
+	for _, tt := range []struct {
+		in, user, host, port string
+	}{
+		{"example.org", "", "example.org", ""},
+		{"foo@example.org", "foo", "example.org", ""},
+		{"ssh://192.168.0.2:23", "", "192.168.0.2", "23"},
+		{"ssh://x@example.org", "x", "example.org", ""},
+		{"a@b@[fe80::1]:2222", "a@b", "fe80::1", "2222"},
+		{"fe80::1", "", "fe80::1", ""},
+	} {
+		user, host, port := ParseDestination(tt.in)
+		if user != tt.user || host != tt.host || port != tt.port {
+			t.Errorf("ParseDestination(%q) = %q, %q, %q, want %q, %q, %q", tt.in, user, host, port, tt.user, tt.host, tt.port)
+		}
+	}
+}
+
+// serveAgent serves an agent of key on a socket in SSH_AUTH_SOCK. It sends
+// the flags of the sign requests on flags.
+func serveAgent(t *testing.T, key ssh.Signer, flags chan<- uint32) {
+	t.Helper()
+	sock := filepath.Join(t.TempDir(), "agent")
+	ln, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Skipf("no Unix sockets: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	t.Setenv(authSockEnv, sock)
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				for {
+					var n [4]byte
+					if _, err := io.ReadFull(conn, n[:]); err != nil {
+						return
+					}
+					req := make([]byte, binary.BigEndian.Uint32(n[:]))
+					if _, err := io.ReadFull(conn, req); err != nil {
+						return
+					}
+					var reply []byte
+					switch req[0] {
+					case agentRequestIdentities:
+						reply = ssh.Marshal(&identitiesAnswer{
+							NumKeys: 1,
+							Keys:    ssh.Marshal(&identity{Blob: key.PublicKey().Marshal(), Comment: "test"}),
+						})
+					case 13:
+						var sr signRequest
+						if err := ssh.Unmarshal(req, &sr); err != nil {
+							return
+						}
+						flags <- sr.Flags
+						algo := ssh.KeyAlgoRSA
+						switch sr.Flags {
+						case agentRSASHA256:
+							algo = ssh.KeyAlgoRSASHA256
+						case agentRSASHA512:
+							algo = ssh.KeyAlgoRSASHA512
+						}
+						sig, err := key.(ssh.AlgorithmSigner).SignWithAlgorithm(rand.Reader, sr.Data, algo)
+						if err != nil {
+							reply = []byte{agentFailure}
+							break
+						}
+						reply = ssh.Marshal(&signResponse{Signature: ssh.Marshal(sig)})
+					default:
+						reply = []byte{agentFailure}
+					}
+					conn.Write(append(binary.BigEndian.AppendUint32(nil, uint32(len(reply))), reply...))
+				}
+			}()
+		}
+	}()
+}
+
+func TestAgent(t *testing.T) {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	host := newSigner(t)
+	port := serve(t, host, user.PublicKey())
+	d := dialer(t)
+	flags := make(chan uint32, 10)
+	serveAgent(t, user, flags)
+	if err := addKnownHost(filepath.Join(os.Getenv("HOME"), ".ssh", "known_hosts"), "127.0.0.1:"+port, host.PublicKey()); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := d.Dial(context.Background(), "root", "127.0.0.1", port)
+	if err != nil {
+		t.Fatalf("Dial with agent = %v", err)
+	}
+	c.Close()
+	// RSA keys sign with SHA-2.
+	if len(flags) != 1 {
+		t.Fatalf("got %d sign requests, want 1", len(flags))
+	}
+	if f := <-flags; f == 0 {
+		t.Errorf("sign request flags = %d, want SHA-2", f)
+	}
+}
+
+func TestSetOption(t *testing.T) {
+	var d Dialer
+	for _, opt := range []string{"Port=2022", "User root", " ConnectTimeout = 5 "} {
+		if err := d.SetOption(opt); err != nil {
+			t.Errorf("SetOption(%q) = %v", opt, err)
+		}
+	}
+	for k, want := range map[string]string{"port": "2022", "User": "root", "CONNECTTIMEOUT": "5", "Compression": "no"} {
+		if got := d.Get("host", k); got != want {
+			t.Errorf("Get(%q) = %q, want %q", k, got, want)
+		}
+	}
+	if err := d.SetOption("Port"); !errors.Is(err, errOption) {
+		t.Errorf("SetOption(Port) = %v, want %v", err, errOption)
+	}
+}
@@ -8,12 +8,17 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"strings"
 
 	"github.com/florianl/go-tc"
 )
 
 var ErrInvalidActionControl = errors.New("invalid action control parameter")
 
+// ErrPoliceRate is returned for police actions with a rate. The kernel takes
+// a police rate only with its rate table, which go-tc does not encode.
+var ErrPoliceRate = fmt.Errorf("%w: police rate, peakrate and burst need a rate table", ErrNotImplemented)
+
 const (
 	GActUnspec  = -1
 	GActOk      = 0
@@ -23,8 +28,37 @@ const (
 	GActTrap    = 8
 	GActJump    = 1 << 28
 	GActGoTo    = 2 << 28
+	GActStolen  = 4
 )
 
+// controls are the names of the control actions.
+var controls = []struct {
+	name string
+	act  int
+}{
+	{"continue", GActUnspec},
+	{"pass", GActOk},
+	{"ok", GActOk},
+	{"reclassify", GActReclass},
+	{"drop", GActShot},
+	{"shot", GActShot},
+	{"pipe", GActPipe},
+	{"stolen", GActStolen},
+	{"trap", GActTrap},
+	{"goto", GActGoTo},
+	{"jump", GActJump},
+}
+
+// parseControl returns the control action of a name.
+func parseControl(name string) (int, bool) {
+	for _, c := range controls {
+		if c.name == name {
+			return c.act, true
+		}
+	}
+	return 0, false
+}
+
 // ParseActionGAT parses options of the filter action category and returns
 // a pointer to a slice of []*tc.Action
 func ParseActionGAT(out io.Writer, args []string) (*[]*tc.Action, error) {
@@ -32,32 +66,12 @@ func ParseActionGAT(out io.Writer, args []string) (*[]*tc.Action, error) {
 		return nil, ErrNotEnoughArgs
 	}
 
-	var act int
-	switch args[0] {
-	case "continue":
-		act = GActUnspec
-	case "drop":
-		act = GActShot
-	case "shot":
-		act = GActShot
-	case "pass":
-		act = GActOk
-	case "ok":
-		act = GActOk
-	case "reclassify":
-		act = GActReclass
-	case "pipe":
-		act = GActPipe
-	case "goto":
-		act = GActGoTo
-	case "jump":
-		act = GActJump
-	case "trap":
-		act = GActTrap
-	case "help":
+	if args[0] == "help" {
 		fmt.Fprintf(out, "%s\n", gactHelp)
 		return nil, nil
-	default:
+	}
+	act, ok := parseControl(args[0])
+	if !ok {
 		fmt.Fprintf(out, "%s\n", gactHelp)
 		return nil, ErrInvalidActionControl
 	}
@@ -92,3 +106,192 @@ tc ... action CONTROL [ RAND ] [ INDEX ]
 	JUMP_COUNT := absolute jump from start of action list
 	INDEX := index value used`
 )
+
+const ActionHelp = `Usage: ... action ACTION_SPEC [ action ACTION_SPEC ... ]
+	ACTION_SPEC := { [ gact ] CONTROL | mirred MIRRED_OPTIONS |
+		police POLICE_OPTIONS }
+	CONTROL := { reclassify | drop | continue | pass | pipe | stolen | trap }
+	MIRRED_OPTIONS := { egress | ingress } { mirror | redirect } dev DEVICE
+		[ CONTROL ]
+	POLICE_OPTIONS := mtu BYTES [ conform-exceed EXCEED[/NOTEXCEED] | CONTROL ]
+`
+
+// ParseActions parses the arguments after `action` of a filter: a list of
+// actions separated by `action`, as in `action mirred egress redirect dev
+// eth1 action drop`. It returns the actions and the number of arguments
+// they took.
+func ParseActions(out io.Writer, args []string) (*[]*tc.Action, int, error) {
+	var acts []*tc.Action
+	i := 0
+	for {
+		if i >= len(args) {
+			return nil, 0, ErrNotEnoughArgs
+		}
+		var act *tc.Action
+		var n int
+		var err error
+		switch args[i] {
+		case "help":
+			fmt.Fprint(out, ActionHelp)
+			return nil, 0, ErrExitAfterHelp
+		case "mirred":
+			act, n, err = parseMirred(args[i+1:])
+			n++
+		case "police":
+			act, n, err = parsePolice(args[i+1:])
+			n++
+		case "gact":
+			i++
+			if i >= len(args) {
+				return nil, 0, ErrNotEnoughArgs
+			}
+			fallthrough
+		default:
+			var gact *[]*tc.Action
+			if gact, err = ParseActionGAT(out, args[i:i+1]); err == nil {
+				act, n = (*gact)[0], 1
+			}
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		acts = append(acts, act)
+		i += n
+		if i < len(args) && args[i] == "action" {
+			i++
+			continue
+		}
+		return &acts, i, nil
+	}
+}
+
+// Mirred actions, from include/uapi/linux/tc_act/tc_mirred.h.
+const (
+	TCAEgressRedir   = 1
+	TCAEgressMirror  = 2
+	TCAIngressRedir  = 3
+	TCAIngressMirror = 4
+)
+
+var mirredActions = map[uint32]string{
+	TCAEgressRedir:   "Egress Redirect",
+	TCAEgressMirror:  "Egress Mirror",
+	TCAIngressRedir:  "Ingress Redirect",
+	TCAIngressMirror: "Ingress Mirror",
+}
+
+// parseMirred parses the options of a mirred action, and returns it and the
+// number of arguments it took.
+func parseMirred(args []string) (*tc.Action, int, error) {
+	if len(args) < 4 {
+		return nil, 0, ErrNotEnoughArgs
+	}
+	p := &tc.MirredParam{}
+	switch args[0] + " " + args[1] {
+	case "egress redirect":
+		p.Eaction = TCAEgressRedir
+	case "egress mirror":
+		p.Eaction = TCAEgressMirror
+	case "ingress redirect":
+		p.Eaction = TCAIngressRedir
+	case "ingress mirror":
+		p.Eaction = TCAIngressMirror
+	default:
+		return nil, 0, fmt.Errorf("%w: mirred: %s %s", ErrInvalidArg, args[0], args[1])
+	}
+	// Redirected packets are gone, mirrored ones go on.
+	p.Action = GActStolen
+	if args[1] == "mirror" {
+		p.Action = GActPipe
+	}
+	if args[2] != "dev" {
+		return nil, 0, fmt.Errorf("%w: mirred needs a dev", ErrInvalidArg)
+	}
+	iface, err := getDevice(args[3])
+	if err != nil {
+		return nil, 0, err
+	}
+	p.IfIndex = uint32(iface.Index)
+	n := 4
+	if len(args) > n {
+		if act, ok := parseControl(args[n]); ok {
+			p.Action = uint32(act)
+			n++
+		}
+	}
+
+	return &tc.Action{
+		Kind:   "mirred",
+		Mirred: &tc.Mirred{Parms: p},
+	}, n, nil
+}
+
+// parsePolice parses the options of a police action, and returns it and the
+// number of arguments it took.
+//
+// Only the mtu of packets is policed: rates are refused with ErrPoliceRate,
+// as go-tc encodes them as a tc_ratespec rather than the rate table that
+// kernels require with it.
+func parsePolice(args []string) (*tc.Action, int, error) {
+	var mtu uint64
+	p := &tc.Policy{Action: tc.PolicyReclassify}
+	police := &tc.Police{Tbf: p}
+
+	i := 0
+options:
+	for i < len(args) {
+		if act, ok := parseControl(args[i]); ok {
+			p.Action = tc.PolicyAction(act)
+			i++
+			break
+		}
+		if i+1 >= len(args) {
+			break
+		}
+		var err error
+		switch args[i] {
+		case "rate", "peakrate", "burst", "buffer", "maxburst", "avrate":
+			return nil, 0, ErrPoliceRate
+		case "mtu", "minburst":
+			// Sizes may have a cell size after a slash, which is
+			// computed here.
+			val, _, _ := strings.Cut(args[i+1], "/")
+			mtu, err = ParseSize(val)
+		case "conform-exceed":
+			exceed, conform, _ := strings.Cut(args[i+1], "/")
+			act, ok := parseControl(exceed)
+			if !ok {
+				return nil, 0, ErrInvalidActionControl
+			}
+			p.Action = tc.PolicyAction(act)
+			if conform != "" {
+				act, ok := parseControl(conform)
+				if !ok {
+					return nil, 0, ErrInvalidActionControl
+				}
+				result := uint32(act)
+				police.Result = &result
+			}
+		default:
+			// The options of the next action or filter.
+			break options
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		i += 2
+	}
+
+	if mtu == 0 {
+		return nil, 0, fmt.Errorf("%w: police needs an mtu", ErrNotEnoughArgs)
+	}
+	if mtu > maxUint32 {
+		return nil, 0, ErrOutOfBounds
+	}
+	p.Mtu = uint32(mtu)
+
+	return &tc.Action{
+		Kind:   "police",
+		Police: police,
+	}, i, nil
+}
@@ -7,6 +7,7 @@ package trafficctl_test
 import (
 	"bytes"
 	"errors"
+	"strings"
 	"testing"
 
 	trafficctl "github.com/u-root/u-root/pkg/tc"
@@ -100,3 +101,39 @@ func TestParseActionGAT(t *testing.T) {
 		})
 	}
 }
+
+func TestParseActions(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		args string
+		n    int
+		err  error
+	}{
+		{name: "gact", args: "drop", n: 1},
+		{name: "gact keyword", args: "gact pass classid 1:1", n: 2},
+		{name: "chain", args: "mirred egress mirror dev lo action drop", n: 7},
+		{name: "mirred control", args: "mirred ingress redirect dev lo pipe flowid 1:1", n: 6},
+		{name: "police", args: "police mtu 1500 drop", n: 4},
+		{name: "police mtu", args: "police mtu 1500 conform-exceed drop/pipe", n: 5},
+		{name: "empty", err: trafficctl.ErrNotEnoughArgs},
+		{name: "mirred without dev", args: "mirred egress redirect", err: trafficctl.ErrNotEnoughArgs},
+		{name: "mirred invalid", args: "mirred sideways redirect dev lo", err: trafficctl.ErrInvalidArg},
+		{name: "mirred unknown dev", args: "mirred egress redirect dev nosuchdevice0", err: trafficctl.ErrNoDevice},
+		{name: "police without mtu", args: "police drop", err: trafficctl.ErrNotEnoughArgs},
+		{name: "police rate", args: "police rate 1mbit burst 10k drop", err: trafficctl.ErrPoliceRate},
+		{name: "police peakrate", args: "police mtu 1500 peakrate 2mbit", err: trafficctl.ErrNotImplemented},
+		{name: "police invalid exceed", args: "police mtu 1500 conform-exceed explode", err: trafficctl.ErrInvalidActionControl},
+		{name: "invalid", args: "explode", err: trafficctl.ErrInvalidActionControl},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var outBuf bytes.Buffer
+			_, n, err := trafficctl.ParseActions(&outBuf, strings.Fields(tt.args))
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("ParseActions(%q) = %v, not %v", tt.args, err, tt.err)
+			}
+			if n != tt.n {
+				t.Errorf("ParseActions(%q) took %d args, not %d", tt.args, n, tt.n)
+			}
+		})
+	}
+}
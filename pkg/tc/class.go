@@ -122,6 +122,9 @@ func (t *Trafficctl) ShowClass(stdout io.Writer, args *Args) error {
 
 				}
 				fmt.Fprintf(stdout, "\n")
+				if t.Stats {
+					fmt.Fprint(stdout, RenderStats(&class.Attribute))
+				}
 			}
 		}
 	}
@@ -17,6 +17,9 @@ import (
 
 var ErrInvalidFilterType = errors.New("invalid filtertype")
 
+// ethPAll is the EtherType of all protocols.
+const ethPAll = 0x0003
+
 // FArgs hold all possible args for qdisc subcommand
 // tc filter [ add | del | change | replace | show ] [ dev STRING ]
 // tc filter [ add | del | change | replace | show ] [ block BLOCK_INDEX ]
@@ -42,7 +45,8 @@ type FArgs struct {
 // and returns an *FArgs structure
 func ParseFilterArgs(stdout io.Writer, args []string) (*FArgs, error) {
 	pref := uint16(0)
-	proto := uint16(0)
+	// Filters match all protocols, unless told otherwise.
+	proto := HToNS(ethPAll)
 	ret := &FArgs{
 		pref:     &pref,
 		protocol: &proto,
@@ -107,27 +111,18 @@ func ParseFilterArgs(stdout io.Writer, args []string) (*FArgs, error) {
 			ret.parent = &indirect
 			// We have a one piece argument. To get to the next arg properly
 			i--
-		case "ingress":
+		case "ingress", "egress":
 			if ret.parent != nil {
 				return nil, ErrInvalidArg
 			}
-			indirectPar := tc.HandleIngress // is the same as clsact handle
-			ret.parent = &indirectPar
-			// We have a one piece argument. To get to the next arg properly
-			indirectHan := core.BuildHandle(tc.HandleIngress, 0)
-			ret.handle = &indirectHan
-
-			i--
-		case "egress":
-			if ret.parent != nil {
-				return nil, ErrInvalidArg
+			// Filters of the ingress and clsact qdiscs hang off their
+			// ingress or egress pseudo classes.
+			indirectPar := core.BuildHandle(tc.HandleIngress>>16, tc.HandleMinIngress)
+			if args[i] == "egress" {
+				indirectPar = core.BuildHandle(tc.HandleIngress>>16, tc.HandleMinEgress)
 			}
-			indirectPar := tc.HandleIngress // is the same as clsact handle
 			ret.parent = &indirectPar
 			// We have a one piece argument. To get to the next arg properly
-			indirectHan := core.BuildHandle(tc.HandleIngress, tc.HandleMinEgress)
-			ret.handle = &indirectHan
-
 			i--
 		case "help":
 			fmt.Fprint(stdout, FilterHelp)
@@ -163,6 +158,9 @@ func (t *Trafficctl) ShowFilter(stdout io.Writer, fArgs *FArgs) error {
 		Family:  0,
 		Ifindex: uint32(iface.Index),
 	}
+	if fArgs.parent != nil {
+		msg.Parent = *fArgs.parent
+	}
 
 	filters, err := t.Tc.Filter().Get(&msg)
 	if err != nil {
@@ -171,34 +169,31 @@ func (t *Trafficctl) ShowFilter(stdout io.Writer, fArgs *FArgs) error {
 
 	for _, f := range filters {
 		var s strings.Builder
-		fmt.Fprintf(&s, "filter parent %d: protocol %s pref %d %s chain %d",
-			f.Parent>>16,
+		fmt.Fprintf(&s, "filter %s protocol %s pref %d %s",
+			RenderClassID(f.Parent, true),
 			RenderProto(GetProtoFromInfo(f.Info)),
 			GetPrefFromInfo(f.Info),
-			f.Kind,
-			*f.Chain)
+			f.Kind)
+		if f.Chain != nil {
+			fmt.Fprintf(&s, " chain %d", *f.Chain)
+		}
 
 		if f.Handle != 0 {
 			fmt.Fprintf(&s, " handle 0x%x", f.Handle)
 		}
 
-		if f.Basic != nil {
-			if f.Basic.Actions != nil {
-				for _, act := range *f.Basic.Actions {
-					fmt.Fprintf(&s, "\n\t\taction order %d: %s action %d",
-						act.Index, act.Kind, act.Gact.Parms.Action)
-				}
-			}
+		s.WriteString(RenderFilterOptions(&f))
+		var actions *[]*tc.Action
+		switch {
+		case f.Basic != nil:
+			actions = f.Basic.Actions
+		case f.U32 != nil:
+			actions = f.U32.Actions
+		case f.Flower != nil:
+			actions = f.Flower.Actions
 		}
-		if f.U32 != nil {
-			if f.U32.ClassID != nil {
-				fmt.Fprintf(&s, " flowid %s", RenderClassID(*f.U32.ClassID, false))
-			}
-			if f.U32.Sel != nil {
-				for _, key := range f.U32.Sel.Keys {
-					fmt.Fprintf(&s, "\n  match %08x/%08x at %d", NToHL(key.Val), NToHL(key.Mask), key.Off)
-				}
-			}
+		if actions != nil {
+			s.WriteString(RenderActions(*actions, t.Stats))
 		}
 		fmt.Fprintf(stdout, "%s\n", s.String())
 	}
@@ -214,9 +209,19 @@ func (t *Trafficctl) AddFilter(stdout io.Writer, fArgs *FArgs) error {
 	}
 
 	q := fArgs.filterObj
+	if q == nil {
+		return fmt.Errorf("%w: no filter", ErrNotEnoughArgs)
+	}
 	q.Ifindex = uint32(iface.Index)
-	q.Parent = *fArgs.parent
+	if fArgs.parent != nil {
+		q.Parent = *fArgs.parent
+	}
 	q.Msg.Info = GetInfoFromPrefAndProto(*fArgs.pref, *fArgs.protocol)
+	// flower matches the protocol of the filter as a key.
+	if q.Flower != nil && q.Flower.KeyEthType == nil && NToHS(*fArgs.protocol) != ethPAll {
+		ethType := NToHS(*fArgs.protocol)
+		q.Flower.KeyEthType = &ethType
+	}
 
 	if err := t.Tc.Filter().Add(q); err != nil {
 		return err
@@ -225,6 +230,7 @@ func (t *Trafficctl) AddFilter(stdout io.Writer, fArgs *FArgs) error {
 }
 
 // DeleteFilter implements the functionality of `tc filter del ... `
+// Without a pref, it deletes the first filter of the parent.
 func (t *Trafficctl) DeleteFilter(stdout io.Writer, fArgs *FArgs) error {
 	iface, err := getDevice(fArgs.dev)
 	if err != nil {
@@ -235,17 +241,35 @@ func (t *Trafficctl) DeleteFilter(stdout io.Writer, fArgs *FArgs) error {
 		Family:  0,
 		Ifindex: uint32(iface.Index),
 	}
+	if fArgs.parent != nil {
+		msg.Parent = *fArgs.parent
+	}
 
 	filters, err := t.Tc.Filter().Get(&msg)
 	if err != nil {
 		return err
 	}
 
-	if err := t.Tc.Filter().Delete(&filters[0]); err != nil {
-		return err
+	for _, f := range filters {
+		if *fArgs.pref != 0 && GetPrefFromInfo(f.Info) != *fArgs.pref {
+			continue
+		}
+		// Deleting the filter without a handle deletes all of its
+		// entries, as u32 and flower have several.
+		del := &tc.Object{
+			Msg: tc.Msg{
+				Ifindex: f.Ifindex,
+				Parent:  f.Parent,
+				Info:    f.Info,
+			},
+			Attribute: tc.Attribute{
+				Kind: f.Kind,
+			},
+		}
+		return t.Tc.Filter().Delete(del)
 	}
 
-	return nil
+	return fmt.Errorf("%w: no filter to delete on %s", ErrInvalidArg, fArgs.dev)
 }
 
 // ReplaceFilter implements the functionality of `tc filter replace ... `
@@ -288,7 +312,7 @@ const FilterHelp = `Usage: tc filter [ add | del | show ] [ dev STRING ]
 	tc filter show [ dev STRING ] [ root | ingress | egress | parent CLASSID ]
 
 Where:
-	FILTER_TYPE := { u32 | basic }
+	FILTER_TYPE := { u32 | flower | basic }
 	OPTIONS := ... try tc filter add <desired FILTER_KIND> help
 `
 
@@ -298,7 +322,7 @@ func supportedFilters(f string) func(io.Writer, []string) (*tc.Object, error) {
 		"bpf":      nil,
 		"cgroup":   nil,
 		"flow":     nil,
-		"flower":   nil,
+		"flower":   ParseFlowerParams,
 		"fw":       nil,
 		"route":    nil,
 		"u32":      ParseU32Params,
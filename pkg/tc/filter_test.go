@@ -7,9 +7,12 @@ package trafficctl_test
 import (
 	"bytes"
 	"errors"
+	"io"
 	"strconv"
+	"strings"
 	"testing"
 
+	"github.com/florianl/go-tc"
 	trafficctl "github.com/u-root/u-root/pkg/tc"
 )
 
@@ -230,3 +233,110 @@ func TestParseBasicParams(t *testing.T) {
 		})
 	}
 }
+
+func TestRenderFilterOptions(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		parse   func(io.Writer, []string) (*tc.Object, error)
+		args    string
+		want    string
+		actions string
+		err     error
+	}{
+		{
+			name:  "u32 ip",
+			parse: trafficctl.ParseU32Params,
+			args:  "match ip src 10.0.0.0/8 match ip dport 80 0xffff classid 1:10",
+			want:  " flowid 1:10\n  match 0a000000/ff000000 at 12\n  match 00000050/0000ffff at 20",
+		},
+		{
+			name:  "u32 merged keys",
+			parse: trafficctl.ParseU32Params,
+			args:  "match ip sport 53 0xffff match ip dport 53 0xffff classid 1:1",
+			want:  " flowid 1:1\n  match 00350035/ffffffff at 20",
+		},
+		{
+			name:    "u32 mirred",
+			parse:   trafficctl.ParseU32Params,
+			args:    "match u32 0 0 action mirred egress redirect dev lo",
+			want:    "\n  match 00000000/00000000 at 0",
+			actions: "\n\taction order 1: mirred (Egress Redirect to device lo) stolen",
+		},
+		{
+			name:  "u32 without match",
+			parse: trafficctl.ParseU32Params,
+			args:  "classid 1:1",
+			err:   trafficctl.ErrInvalidArg,
+		},
+		{
+			name:  "u32 unaligned",
+			parse: trafficctl.ParseU32Params,
+			args:  "match u16 1 0xffff at 3 classid 1:1",
+			err:   trafficctl.ErrInvalidArg,
+		},
+		{
+			name:    "flower",
+			parse:   trafficctl.ParseFlowerParams,
+			args:    "ip_proto tcp dst_ip 192.168.1.0/24 dst_port 443 skip_hw action drop",
+			want:    "\n  ip_proto tcp\n  dst_ip 192.168.1.0/24\n  dst_port 443\n  skip_hw",
+			actions: "\n\taction order 1: gact action drop",
+		},
+		{
+			name:    "flower police",
+			parse:   trafficctl.ParseFlowerParams,
+			args:    "dst_mac aa:bb:cc:dd:ee:ff vlan_id 10 classid 1:1 action police mtu 1500 conform-exceed drop/ok",
+			want:    "\n  classid 1:1\n  vlan_id 10\n  dst_mac aa:bb:cc:dd:ee:ff",
+			actions: "\n\taction order 1: police 0x0 mtu 1500b action drop/pass",
+		},
+		{
+			name:  "flower port without ip_proto",
+			parse: trafficctl.ParseFlowerParams,
+			args:  "dst_port 22",
+			err:   trafficctl.ErrInvalidArg,
+		},
+		{
+			name:  "flower missing value",
+			parse: trafficctl.ParseFlowerParams,
+			args:  "dst_ip",
+			err:   trafficctl.ErrNotEnoughArgs,
+		},
+		{
+			name:    "basic actions",
+			parse:   trafficctl.ParseBasicParams,
+			args:    "action mirred ingress mirror dev lo action pass",
+			actions: "\n\taction order 1: mirred (Ingress Mirror to device lo) pipe\n\taction order 2: gact action pass",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var outBuf bytes.Buffer
+			f, err := tt.parse(&outBuf, strings.Fields(tt.args))
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("parsing %q = %v, not %v", tt.args, err, tt.err)
+			}
+			if err != nil {
+				return
+			}
+
+			if got := trafficctl.RenderFilterOptions(f); got != tt.want {
+				t.Errorf("RenderFilterOptions() = %q, want %q", got, tt.want)
+			}
+
+			var actions *[]*tc.Action
+			switch {
+			case f.Basic != nil:
+				actions = f.Basic.Actions
+			case f.U32 != nil:
+				actions = f.U32.Actions
+			case f.Flower != nil:
+				actions = f.Flower.Actions
+			}
+			var got string
+			if actions != nil {
+				got = trafficctl.RenderActions(*actions, false)
+			}
+			if got != tt.actions {
+				t.Errorf("RenderActions() = %q, want %q", got, tt.actions)
+			}
+		})
+	}
+}
@@ -5,9 +5,13 @@
 package trafficctl
 
 import (
+	"bytes"
+	"encoding/binary"
 	"fmt"
 	"io"
+	"net"
 	"strconv"
+	"strings"
 
 	"github.com/florianl/go-tc"
 )
@@ -29,20 +33,23 @@ const BasicHelp = `Usage: ... basic [ action ACTION_SPEC ] [ classid CLASSID | f
 // and returns a *tc.Object.
 func ParseBasicParams(out io.Writer, params []string) (*tc.Object, error) {
 	b := &tc.Basic{}
-	var err error
 
 	for i := 0; i < len(params); i = i + 2 {
 		switch params[i] {
 		case "match":
 			return nil, ErrNotImplemented
 		case "action":
-			// Only generic actions allowed here
-			b.Actions, err = ParseActionGAT(out, params[1:])
+			acts, n, err := ParseActions(out, params[i+1:])
 			if err != nil {
 				return nil, err
 			}
+			b.Actions = acts
+			i += n - 1
 		case "classid", "flowid":
-			id, err := ParseClassID(params[1])
+			if i+1 >= len(params) {
+				return nil, ErrNotEnoughArgs
+			}
+			id, err := ParseClassID(params[i+1])
 			if err != nil {
 				return nil, err
 			}
@@ -77,7 +84,12 @@ func ParseBasicParams(out io.Writer, params []string) (*tc.Object, error) {
 
 // NOTE: CLASSID is parsed at hexadecimal input.
 
-const U32Help = `Usage ... u32 [ match ...] [ classid CLASSID | flowid CLASSID ]
+const U32Help = `Usage ... u32 [ match SELECTOR ... ] [ classid CLASSID | flowid CLASSID ]
+		[ action ACTION_SPEC ]
+Where:	SELECTOR := { u32 | u16 | u8 } VAL MASK at OFFSET |
+		ip { src | dst } PREFIX |
+		ip { sport | dport } PORT MASK |
+		ip { protocol | tos | dsfield } VAL MASK
 For further information see https://linux-tc-notes.sourceforge.net/tc/doc/cls_u32.txt
 `
 
@@ -107,53 +119,368 @@ func ParseU32Params(out io.Writer, params []string) (*tc.Object, error) {
 			u32.ClassID = &id
 			i = i + 2
 		case "match":
-			if len(params)-i <= 5 || params[i+1] != "u32" || params[i+4] != "at" {
-				return nil, ErrInvalidArg
+			n, err := parseU32Match(u32.Sel, params[i+1:])
+			if err != nil {
+				return nil, err
 			}
-
-			val64, err := strconv.ParseUint(params[i+2], 0, 32)
+			i = i + 1 + n
+		case "action":
+			acts, n, err := ParseActions(out, params[i+1:])
 			if err != nil {
 				return nil, err
 			}
+			u32.Actions = acts
+			i = i + 1 + n
+		case "help":
+			fmt.Fprint(out, U32Help)
+			return nil, ErrExitAfterHelp
+		default:
+			return nil, ErrInvalidArg
+		}
+	}
+
+	if (u32.ClassID == nil && u32.Actions == nil) || u32.Sel.NKeys == 0 {
+		return nil, ErrInvalidArg
+	}
+
+	ret := &tc.Object{}
+	ret.Kind = "u32"
+	ret.U32 = u32
 
-			mask64, err := strconv.ParseUint(params[i+3], 0, 32)
+	return ret, nil
+}
+
+// parseU32Match parses a selector after `match`, adds its key to sel, and
+// returns the number of arguments it took.
+func parseU32Match(sel *tc.U32Sel, params []string) (int, error) {
+	if len(params) < 3 {
+		return 0, ErrInvalidArg
+	}
+
+	switch params[0] {
+	case "u32", "u16", "u8":
+		bits := map[string]int{"u32": 32, "u16": 16, "u8": 8}[params[0]]
+		val, err := strconv.ParseUint(params[1], 0, bits)
+		if err != nil {
+			return 0, err
+		}
+		mask, err := strconv.ParseUint(params[2], 0, bits)
+		if err != nil {
+			return 0, err
+		}
+		// The offset is optional and defaults to the start of the header.
+		if len(params) < 4 || params[3] != "at" {
+			return 3, addU32Key(sel, uint32(val), uint32(mask), 0, bits)
+		}
+		if len(params) < 5 {
+			return 0, ErrNotEnoughArgs
+		}
+		off, err := strconv.ParseUint(params[4], 0, 32)
+		if err != nil {
+			return 0, err
+		}
+		return 5, addU32Key(sel, uint32(val), uint32(mask), uint32(off), bits)
+	case "ip":
+	default:
+		return 0, ErrInvalidArg
+	}
+
+	// IPv4 selectors, of headers without options.
+	switch params[1] {
+	case "src", "dst":
+		_, prefix, err := net.ParseCIDR(params[2])
+		if err != nil {
+			ip := net.ParseIP(params[2]).To4()
+			if ip == nil {
+				return 0, fmt.Errorf("%w: %s", ErrInvalidArg, params[2])
+			}
+			prefix = &net.IPNet{IP: ip, Mask: net.CIDRMask(32, 32)}
+		}
+		if prefix.IP.To4() == nil {
+			return 0, fmt.Errorf("%w: %s is not IPv4", ErrInvalidArg, params[2])
+		}
+		off := uint32(12)
+		if params[1] == "dst" {
+			off = 16
+		}
+		val := binary.BigEndian.Uint32(prefix.IP.To4())
+		mask := binary.BigEndian.Uint32(prefix.Mask)
+		return 3, addU32Key(sel, val, mask, off, 32)
+	}
+
+	if len(params) < 4 {
+		return 0, ErrInvalidArg
+	}
+	var off uint32
+	bits := 8
+	switch params[1] {
+	case "sport":
+		off, bits = 20, 16
+	case "dport":
+		off, bits = 22, 16
+	case "protocol":
+		off = 9
+	case "tos", "dsfield":
+		off = 1
+	default:
+		return 0, fmt.Errorf("%w: ip %s", ErrInvalidArg, params[1])
+	}
+	val, err := strconv.ParseUint(params[2], 0, bits)
+	if err != nil {
+		return 0, err
+	}
+	mask, err := strconv.ParseUint(params[3], 0, bits)
+	if err != nil {
+		return 0, err
+	}
+	return 4, addU32Key(sel, uint32(val), uint32(mask), off, bits)
+}
+
+// addU32Key adds a key of bits at off to sel, as the 32 bit word that holds
+// it. Keys of the same word are merged, as tc does.
+func addU32Key(sel *tc.U32Sel, val, mask, off uint32, bits int) error {
+	if off%uint32(bits/8) != 0 {
+		return fmt.Errorf("%w: offset %d of a u%d", ErrInvalidArg, off, bits)
+	}
+	shift := 32 - bits - int(off%4)*8
+	val, mask = (val&mask)<<shift, mask<<shift
+	off &^= 3
+
+	for i := range sel.Keys {
+		k := &sel.Keys[i]
+		if k.Off != off || k.OffMask != 0 {
+			continue
+		}
+		kval, kmask := NToHL(k.Val), NToHL(k.Mask)
+		if (kval^val)&kmask&mask != 0 {
+			return fmt.Errorf("%w: conflicting matches at %d", ErrInvalidArg, off)
+		}
+		k.Val = HToNL(kval | val)
+		k.Mask = HToNL(kmask | mask)
+		return nil
+	}
+
+	if sel.NKeys == 255 {
+		return ErrOutOfBounds
+	}
+	sel.NKeys++
+	sel.Keys = append(sel.Keys, tc.U32Key{
+		Mask: HToNL(mask),
+		Val:  HToNL(val),
+		Off:  off,
+	})
+	return nil
+}
+
+// Classifier flags, from include/uapi/linux/pkt_cls.h.
+const (
+	tcaClsFlagsSkipHW = 1 << 0
+	tcaClsFlagsSkipSW = 1 << 1
+)
+
+type ipProto struct {
+	name string
+	nr   uint8
+}
+
+var ipProtos = []ipProto{
+	{"icmp", 1},
+	{"tcp", 6},
+	{"udp", 17},
+	{"icmpv6", 58},
+	{"sctp", 132},
+}
+
+// Originally from tc filter flower help
+// Usage: ... flower [ MATCH-LIST ] [ verbose ]
+//                   [ skip_sw | skip_hw ]
+//                   [ action ACTION-SPEC ] [ classid CLASSID ]
+// NOTE: Only IPv4 addresses are supported by go-tc.
+
+const FlowerHelp = `Usage: ... flower [ MATCH-LIST ] [ skip_sw | skip_hw ]
+		[ action ACTION_SPEC ] [ classid CLASSID | flowid CLASSID ]
+Where:	MATCH-LIST := [ MATCH-LIST ] MATCH
+	MATCH := { indev DEVICE |
+		vlan_id VID | vlan_prio PRIORITY |
+		dst_mac MAC | src_mac MAC |
+		ip_proto { tcp | udp | sctp | icmp | NUMBER } |
+		dst_ip PREFIX | src_ip PREFIX |
+		dst_port PORT | src_port PORT |
+		ip_tos TOS[/MASK] | ip_ttl TTL[/MASK] }
+	The protocol of the filter is matched as the ethernet type.
+`
+
+// ParseFlowerParams parses the cmdline arguments for `tc filter ... flower
+// ...` and returns a *tc.Object.
+func ParseFlowerParams(out io.Writer, params []string) (*tc.Object, error) {
+	f := &tc.Flower{}
+	var flags uint32
+
+	for i := 0; i < len(params); i = i + 2 {
+		switch params[i] {
+		case "skip_hw":
+			flags |= tcaClsFlagsSkipHW
+			i--
+			continue
+		case "skip_sw":
+			flags |= tcaClsFlagsSkipSW
+			i--
+			continue
+		case "action":
+			acts, n, err := ParseActions(out, params[i+1:])
 			if err != nil {
 				return nil, err
 			}
+			f.Actions = acts
+			i += n - 1
+			continue
+		case "help":
+			fmt.Fprint(out, FlowerHelp)
+			return nil, ErrExitAfterHelp
+		}
 
-			off64, err := strconv.ParseUint(params[i+5], 0, 32)
+		if i+1 >= len(params) {
+			return nil, fmt.Errorf("%w: flower: %s", ErrNotEnoughArgs, params[i])
+		}
+		val := params[i+1]
+		switch params[i] {
+		case "classid", "flowid":
+			id, err := ParseClassID(val)
 			if err != nil {
 				return nil, err
 			}
-
-			if u32.Sel.NKeys == 255 {
-				return nil, ErrOutOfBounds
+			f.ClassID = &id
+		case "indev":
+			f.Indev = &val
+		case "vlan_id":
+			id, err := strconv.ParseUint(val, 10, 12)
+			if err != nil {
+				return nil, err
 			}
-
-			u32.Sel.NKeys++
-			key := tc.U32Key{
-				Mask: HToNL(uint32(mask64)),
-				Val:  HToNL(uint32(val64)),
-				Off:  uint32(off64),
+			vid := uint16(id)
+			f.KeyVlanID = &vid
+		case "vlan_prio":
+			prio, err := strconv.ParseUint(val, 10, 3)
+			if err != nil {
+				return nil, err
+			}
+			p := uint8(prio)
+			f.KeyVlanPrio = &p
+		case "dst_mac", "src_mac":
+			mac, err := net.ParseMAC(val)
+			if err != nil {
+				return nil, err
+			}
+			mask := net.HardwareAddr(bytes.Repeat([]byte{0xff}, len(mac)))
+			if params[i] == "dst_mac" {
+				f.KeyEthDst, f.KeyEthDstMask = &mac, &mask
+			} else {
+				f.KeyEthSrc, f.KeyEthSrcMask = &mac, &mask
+			}
+		case "ip_proto":
+			proto, err := parseIPProto(val)
+			if err != nil {
+				return nil, err
+			}
+			f.KeyIPProto = &proto
+		case "dst_ip", "src_ip":
+			ip, mask, err := parseIPv4Prefix(val)
+			if err != nil {
+				return nil, err
+			}
+			if params[i] == "dst_ip" {
+				f.KeyIPv4Dst, f.KeyIPv4DstMask = &ip, &mask
+			} else {
+				f.KeyIPv4Src, f.KeyIPv4SrcMask = &ip, &mask
+			}
+		case "dst_port", "src_port":
+			port, err := strconv.ParseUint(val, 10, 16)
+			if err != nil {
+				return nil, err
+			}
+			p := uint16(port)
+			var key **uint16
+			switch {
+			case f.KeyIPProto == nil:
+				return nil, fmt.Errorf("%w: flower: %s needs ip_proto", ErrInvalidArg, params[i])
+			case *f.KeyIPProto == 6 && params[i] == "dst_port":
+				key = &f.KeyTCPDst
+			case *f.KeyIPProto == 6:
+				key = &f.KeyTCPSrc
+			case *f.KeyIPProto == 17 && params[i] == "dst_port":
+				key = &f.KeyUDPDst
+			case *f.KeyIPProto == 17:
+				key = &f.KeyUDPSrc
+			case *f.KeyIPProto == 132 && params[i] == "dst_port":
+				key = &f.KeySctpDst
+			case *f.KeyIPProto == 132:
+				key = &f.KeySctpSrc
+			default:
+				return nil, fmt.Errorf("%w: flower: ports of ip_proto %d", ErrInvalidArg, *f.KeyIPProto)
+			}
+			*key = &p
+		case "ip_tos", "ip_ttl":
+			v, m, ok := strings.Cut(val, "/")
+			key, err := strconv.ParseUint(v, 0, 8)
+			if err != nil {
+				return nil, err
+			}
+			mask := uint64(0xff)
+			if ok {
+				if mask, err = strconv.ParseUint(m, 0, 8); err != nil {
+					return nil, err
+				}
+			}
+			k, km := uint8(key), uint8(mask)
+			if params[i] == "ip_tos" {
+				f.KeyIPTOS, f.KeyIPTOSMask = &k, &km
+			} else {
+				f.KeyIPTTL, f.KeyIPTTLMask = &k, &km
 			}
-			u32.Sel.Keys = append(u32.Sel.Keys, key)
-
-			i = i + 6
-		case "help":
-			fmt.Fprint(out, U32Help)
-			return nil, ErrExitAfterHelp
 		default:
-			return nil, ErrInvalidArg
+			return nil, fmt.Errorf("%w: flower: %s", ErrInvalidArg, params[i])
 		}
 	}
 
-	if u32.ClassID == nil || u32.Sel.NKeys == 0 {
-		return nil, ErrInvalidArg
+	if flags != 0 {
+		f.Flags = &flags
 	}
 
 	ret := &tc.Object{}
-	ret.Kind = "u32"
-	ret.U32 = u32
+	ret.Kind = "flower"
+	ret.Flower = f
 
 	return ret, nil
 }
+
+func parseIPProto(s string) (uint8, error) {
+	for _, p := range ipProtos {
+		if p.name == s {
+			return p.nr, nil
+		}
+	}
+	nr, err := strconv.ParseUint(s, 0, 8)
+	if err != nil {
+		return 0, fmt.Errorf("%w: ip_proto %s", ErrInvalidArg, s)
+	}
+	return uint8(nr), nil
+}
+
+// parseIPv4Prefix parses an IPv4 address with an optional prefix length, and
+// returns the address and the mask as net.IP, as go-tc wants them.
+func parseIPv4Prefix(s string) (net.IP, net.IP, error) {
+	addr, bits, ok := strings.Cut(s, "/")
+	ip := net.ParseIP(addr).To4()
+	if ip == nil {
+		return nil, nil, fmt.Errorf("%w: %s is not an IPv4 address", ErrInvalidArg, addr)
+	}
+	ones := uint64(32)
+	if ok {
+		var err error
+		if ones, err = strconv.ParseUint(bits, 10, 8); err != nil || ones > 32 {
+			return nil, nil, fmt.Errorf("%w: prefix %s", ErrInvalidArg, s)
+		}
+	}
+	mask := net.CIDRMask(int(ones), 32)
+	return ip.Mask(mask), net.IP(mask), nil
+}
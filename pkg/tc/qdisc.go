@@ -70,7 +70,7 @@ func ParseQdiscArgs(stdout io.Writer, args []string) (*Args, error) {
 			indirectPar := tc.HandleIngress // is the same as clsact handle
 			ret.parent = &indirectPar
 			// We have a one piece argument. To get to the next arg properly
-			indirectHan := core.BuildHandle(tc.HandleIngress, 0)
+			indirectHan := core.BuildHandle(tc.HandleIngress>>16, 0)
 			ret.handle = &indirectHan
 
 			i--
@@ -84,7 +84,7 @@ func ParseQdiscArgs(stdout io.Writer, args []string) (*Args, error) {
 			indirectPar := tc.HandleIngress // is the same as clsact handle
 			ret.parent = &indirectPar
 
-			indirectHan := core.BuildHandle(tc.HandleIngress, 0)
+			indirectHan := core.BuildHandle(tc.HandleIngress>>16, 0)
 			ret.handle = &indirectHan
 			i--
 		case "parent":
@@ -145,7 +145,7 @@ const QdiscHelp = `Usage: tc qdisc [ add | del | replace | change | show ] dev S
 	tc qdisc show [ dev STRING ] [ QDISC_ID ]
 
 Where:
-	QDISC_KIND := { codel | qfq | htb | hfsc }
+	QDISC_KIND := { codel | fq_codel | cake | netem | tbf | qfq | htb | hfsc }
 	OPTIONS := ... try tc qdisc add <desired QDISC_KIND> help
 	QDISC_ID := { root | ingress | handle QHANDLE | parent CLASSID }
 `
@@ -169,16 +169,10 @@ func (t *Trafficctl) ShowQdisc(stdout io.Writer, args *Args) error {
 				RenderClassID(qdisc.Handle, false),
 				RenderClassID(qdisc.Parent, true),
 			)
-			if qdisc.Kind == "htb" {
-				htb := qdisc.Htb
-				if htb.Init != nil {
-					fmt.Fprintf(stdout, " r2q %d default 0x%x", htb.Init.Rate2Quantum, htb.Init.Defcls)
-				}
-				if htb.DirectQlen != nil {
-					fmt.Fprintf(stdout, " direct_qlen %d", *htb.DirectQlen)
-				}
+			fmt.Fprintf(stdout, "%s\n", RenderQdiscOptions(&qdisc))
+			if t.Stats {
+				fmt.Fprint(stdout, RenderStats(&qdisc.Attribute))
 			}
-			fmt.Fprintf(stdout, "\n")
 		}
 	}
 	return nil
@@ -191,15 +185,8 @@ func (t *Trafficctl) AddQdisc(stdout io.Writer, args *Args) error {
 		return err
 	}
 
-	msg := tc.Msg{
-		Family:  unix.AF_UNSPEC,
-		Ifindex: uint32(iface.Index),
-		Parent:  *args.parent,
-		Handle:  *args.handle,
-	}
-
 	obj := &tc.Object{
-		Msg:       msg,
+		Msg:       qdiscMsg(iface, args),
 		Attribute: args.obj.Attribute,
 	}
 
@@ -224,10 +211,17 @@ func (t *Trafficctl) DeleteQdisc(stdout io.Writer, args *Args) error {
 	var q tc.Object
 	var found bool
 	for _, qdisc := range qdiscs {
-		if qdisc.Ifindex == uint32(iface.Index) {
-			q = qdisc
-			found = true
+		if qdisc.Ifindex != uint32(iface.Index) {
+			continue
+		}
+		if args.parent != nil && qdisc.Parent != *args.parent {
+			continue
+		}
+		if args.handle != nil && qdisc.Handle != *args.handle {
+			continue
 		}
+		q = qdisc
+		found = true
 	}
 
 	if !found {
@@ -248,16 +242,9 @@ func (t *Trafficctl) ReplaceQdisc(stdout io.Writer, args *Args) error {
 		return err
 	}
 
-	msg := tc.Msg{
-		Family:  unix.AF_UNSPEC,
-		Ifindex: uint32(iface.Index),
-		Parent:  *args.parent,
-	}
 	obj := &tc.Object{
-		Msg: msg,
-		Attribute: tc.Attribute{
-			Kind: args.obj.Kind,
-		},
+		Msg:       qdiscMsg(iface, args),
+		Attribute: args.obj.Attribute,
 	}
 
 	if err := t.Tc.Qdisc().Replace(obj); err != nil {
@@ -273,16 +260,9 @@ func (t *Trafficctl) ChangeQdisc(stdout io.Writer, args *Args) error {
 		return err
 	}
 
-	msg := tc.Msg{
-		Family:  unix.AF_UNSPEC,
-		Ifindex: uint32(iface.Index),
-		Parent:  *args.parent,
-	}
 	obj := &tc.Object{
-		Msg: msg,
-		Attribute: tc.Attribute{
-			Kind: args.obj.Kind,
-		},
+		Msg:       qdiscMsg(iface, args),
+		Attribute: args.obj.Attribute,
 	}
 
 	if err := t.Tc.Qdisc().Change(obj); err != nil {
@@ -292,23 +272,38 @@ func (t *Trafficctl) ChangeQdisc(stdout io.Writer, args *Args) error {
 	return nil
 }
 
+// qdiscMsg returns the message that addresses the qdisc of args on iface.
+func qdiscMsg(iface net.Interface, args *Args) tc.Msg {
+	msg := tc.Msg{
+		Family:  unix.AF_UNSPEC,
+		Ifindex: uint32(iface.Index),
+	}
+	if args.parent != nil {
+		msg.Parent = *args.parent
+	}
+	if args.handle != nil {
+		msg.Handle = *args.handle
+	}
+	return msg
+}
+
 func supportetQdisc(qd string) func(io.Writer, []string) (*tc.Object, error) {
 	supported := map[string]func(io.Writer, []string) (*tc.Object, error){
 		// Classless qdiscs
-		"cake":       nil,
+		"cake":       ParseCakeArgs,
 		"choke":      nil,
 		"codel":      ParseCodelArgs,
 		"pfifo":      nil,
 		"qfifo":      nil,
 		"fq":         nil,
-		"fq_codel":   nil,
+		"fq_codel":   ParseFqCodelArgs,
 		"fq_pie":     nil,
 		"gred":       nil,
 		"hhf":        nil,
 		"ingress":    nil,
 		"mqprio":     nil,
 		"multiq":     nil,
-		"netem":      nil,
+		"netem":      ParseNetemArgs,
 		"pfifo_fast": nil,
 		"pie":        nil,
 		// QFQ is listed as Classfull QDisk in man page of tc, but tc implementation
@@ -317,7 +312,7 @@ func supportetQdisc(qd string) func(io.Writer, []string) (*tc.Object, error) {
 		"red": nil,
 		"sfb": nil,
 		"sfq": nil,
-		"tbf": nil,
+		"tbf": ParseTBFArgs,
 	}
 
 	ret := supported[qd]
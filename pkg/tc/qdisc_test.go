@@ -7,9 +7,12 @@ package trafficctl_test
 import (
 	"bytes"
 	"errors"
+	"io"
 	"strconv"
+	"strings"
 	"testing"
 
+	"github.com/florianl/go-tc"
 	trafficctl "github.com/u-root/u-root/pkg/tc"
 )
 
@@ -523,3 +526,124 @@ func TestParseHFSCQdiscArgs(t *testing.T) {
 		})
 	}
 }
+
+func TestRenderQdiscOptions(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		parse func(io.Writer, []string) (*tc.Object, error)
+		args  string
+		want  string
+		err   error
+	}{
+		{
+			name:  "netem defaults",
+			parse: trafficctl.ParseNetemArgs,
+			want:  " limit 1000",
+		},
+		{
+			name:  "netem",
+			parse: trafficctl.ParseNetemArgs,
+			args:  "limit 100 delay 100ms 10ms 25% loss 0.3% 25% duplicate 1% reorder 25% 50% gap 5 corrupt 0.1% rate 1mbit",
+			want:  " limit 100 delay 100ms 10ms 25% loss 0.3% 25% duplicate 1% reorder 25% 50% gap 5 corrupt 0.1% rate 1mbit",
+		},
+		{
+			name:  "netem reorder without delay",
+			parse: trafficctl.ParseNetemArgs,
+			args:  "reorder 25%",
+			err:   trafficctl.ErrInvalidArg,
+		},
+		{
+			name:  "netem loss model",
+			parse: trafficctl.ParseNetemArgs,
+			args:  "loss state 1%",
+			err:   trafficctl.ErrNotImplemented,
+		},
+		{
+			name:  "netem percent out of bounds",
+			parse: trafficctl.ParseNetemArgs,
+			args:  "duplicate 101%",
+			err:   trafficctl.ErrOutOfBounds,
+		},
+		{
+			name:  "tbf latency",
+			parse: trafficctl.ParseTBFArgs,
+			args:  "rate 1mbit burst 32kb latency 400ms",
+			want:  " rate 1mbit burst 32kb limit 82768b",
+		},
+		{
+			name:  "tbf peakrate",
+			parse: trafficctl.ParseTBFArgs,
+			args:  "rate 1mbit burst 10kb limit 30000 peakrate 2mbit mtu 1540",
+			want:  " rate 1mbit burst 10kb limit 30000b peakrate 2mbit mtu 1540b",
+		},
+		{
+			name:  "tbf without burst",
+			parse: trafficctl.ParseTBFArgs,
+			args:  "rate 1mbit latency 50ms",
+			err:   trafficctl.ErrNotEnoughArgs,
+		},
+		{
+			name:  "tbf peakrate without mtu",
+			parse: trafficctl.ParseTBFArgs,
+			args:  "rate 1mbit burst 10kb limit 30000 peakrate 2mbit",
+			err:   trafficctl.ErrNotEnoughArgs,
+		},
+		{
+			name:  "fq_codel",
+			parse: trafficctl.ParseFqCodelArgs,
+			args:  "limit 1024 flows 2048 target 5ms interval 100ms quantum 1514 memory_limit 32mb ecn",
+			want:  " limit 1024 flows 2048 quantum 1514b target 5ms interval 100ms memory_limit 32mb ecn",
+		},
+		{
+			name:  "fq_codel invalid",
+			parse: trafficctl.ParseFqCodelArgs,
+			args:  "buckets 10",
+			err:   trafficctl.ErrInvalidArg,
+		},
+		{
+			name:  "cake",
+			parse: trafficctl.ParseCakeArgs,
+			args:  "bandwidth 100mbit rtt 20ms diffserv4 dual-dsthost nat ack-filter overhead 18 mpu 64",
+			want:  " bandwidth 100mbit diffserv4 dual-dsthost rtt 20ms nat ack-filter overhead 18 mpu 64",
+		},
+		{
+			name:  "cake unlimited",
+			parse: trafficctl.ParseCakeArgs,
+			args:  "unlimited besteffort triple-isolate",
+			want:  " unlimited besteffort triple-isolate",
+		},
+		{
+			name:  "cake overhead out of bounds",
+			parse: trafficctl.ParseCakeArgs,
+			args:  "overhead 300",
+			err:   trafficctl.ErrOutOfBounds,
+		},
+		{
+			name:  "cake invalid",
+			parse: trafficctl.ParseCakeArgs,
+			args:  "ethernet",
+			err:   trafficctl.ErrInvalidArg,
+		},
+		{
+			name:  "cake missing value",
+			parse: trafficctl.ParseCakeArgs,
+			args:  "bandwidth",
+			err:   trafficctl.ErrNotEnoughArgs,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var outBuf bytes.Buffer
+			q, err := tt.parse(&outBuf, strings.Fields(tt.args))
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("parsing %q = %v, not %v", tt.args, err, tt.err)
+			}
+			if err != nil {
+				return
+			}
+
+			if got := trafficctl.RenderQdiscOptions(q); got != tt.want {
+				t.Errorf("RenderQdiscOptions() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
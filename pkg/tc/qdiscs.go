@@ -7,6 +7,7 @@ package trafficctl
 import (
 	"fmt"
 	"io"
+	"slices"
 	"strconv"
 
 	"github.com/florianl/go-tc"
@@ -204,3 +205,514 @@ func ParseHFSCQDiscArgs(stdout io.Writer, args []string) (*tc.Object, error) {
 
 	return ret, nil
 }
+
+const NetemHelp = `Usage: ... netem [ limit PACKETS ]
+		 [ delay TIME [ JITTER [ CORRELATION ]]]
+		 [ loss [ random ] PERCENT [ CORRELATION ]]
+		 [ corrupt PERCENT [ CORRELATION ]]
+		 [ duplicate PERCENT [ CORRELATION ]]
+		 [ reorder PERCENT [ CORRELATION ] [ gap DISTANCE ]]
+		 [ rate RATE [ PACKETOVERHEAD [ CELLSIZE [ CELLOVERHEAD ]]]]
+		 [ ecn ]
+`
+
+// ParseNetemArgs parses a []string from the commandline for the netem qdisc
+// via `tc qdisc ... netem ...` and returns an *tc.Object accordingly.
+// Times are passed to the kernel in nanoseconds, so the legacy tick based
+// latency and jitter of the options are left at 0.
+func ParseNetemArgs(out io.Writer, args []string) (*tc.Object, error) {
+	netem := &tc.Netem{
+		Qopt: tc.NetemQopt{
+			Limit: 1000,
+		},
+	}
+	corr := &tc.NetemCorr{}
+	// next returns the optional argument after args[i], if there is one.
+	next := func(i int) (string, bool) {
+		if i+1 < len(args) && args[i+1] != "" && args[i+1][0] >= '0' && args[i+1][0] <= '9' {
+			return args[i+1], true
+		}
+		return "", false
+	}
+	// percents parses a PERCENT [ CORRELATION ] argument after args[i]
+	// and returns the index of the last one.
+	percents := func(i int, p, c *uint32) (int, error) {
+		if i+1 >= len(args) {
+			return 0, ErrNotEnoughArgs
+		}
+		i++
+		var err error
+		if *p, err = ParsePercent(args[i]); err != nil {
+			return 0, err
+		}
+		if v, ok := next(i); ok {
+			if *c, err = ParsePercent(v); err != nil {
+				return 0, err
+			}
+			i++
+		}
+		return i, nil
+	}
+
+	for i := 0; i < len(args); i++ {
+		var err error
+		switch args[i] {
+		case "limit":
+			if i+1 >= len(args) {
+				return nil, ErrNotEnoughArgs
+			}
+			val, err := strconv.ParseUint(args[i+1], 10, 32)
+			if err != nil {
+				return nil, err
+			}
+			netem.Qopt.Limit = uint32(val)
+			i++
+		case "delay", "latency":
+			if i+1 >= len(args) {
+				return nil, ErrNotEnoughArgs
+			}
+			i++
+			latency, err := parseTime(args[i])
+			if err != nil {
+				return nil, err
+			}
+			indirect := int64(latency) * 1000
+			netem.Latency64 = &indirect
+			if v, ok := next(i); ok {
+				jitter, err := parseTime(v)
+				if err != nil {
+					return nil, err
+				}
+				indirect := int64(jitter) * 1000
+				netem.Jitter64 = &indirect
+				i++
+			}
+			if v, ok := next(i); ok {
+				if corr.Delay, err = ParsePercent(v); err != nil {
+					return nil, err
+				}
+				i++
+			}
+		case "loss", "drop":
+			if i+1 < len(args) && args[i+1] == "random" {
+				i++
+			}
+			if i+1 < len(args) && (args[i+1] == "state" || args[i+1] == "gemodel") {
+				return nil, ErrNotImplemented
+			}
+			if i, err = percents(i, &netem.Qopt.Loss, &corr.Loss); err != nil {
+				return nil, err
+			}
+		case "duplicate":
+			if i, err = percents(i, &netem.Qopt.Duplicate, &corr.Dup); err != nil {
+				return nil, err
+			}
+		case "corrupt":
+			netem.Corrupt = &tc.NetemCorrupt{}
+			if i, err = percents(i, &netem.Corrupt.Probability, &netem.Corrupt.Correlation); err != nil {
+				return nil, err
+			}
+		case "reorder":
+			netem.Reorder = &tc.NetemReorder{}
+			if i, err = percents(i, &netem.Reorder.Probability, &netem.Reorder.Correlation); err != nil {
+				return nil, err
+			}
+		case "gap":
+			if i+1 >= len(args) {
+				return nil, ErrNotEnoughArgs
+			}
+			val, err := strconv.ParseUint(args[i+1], 10, 32)
+			if err != nil {
+				return nil, err
+			}
+			netem.Qopt.Gap = uint32(val)
+			i++
+		case "rate":
+			if i+1 >= len(args) {
+				return nil, ErrNotEnoughArgs
+			}
+			i++
+			rate, err := ParseRate(args[i])
+			if err != nil {
+				return nil, err
+			}
+			netem.Rate = &tc.NetemRate{Rate: uint32(min(rate, maxUint32))}
+			if rate >= maxUint32 {
+				netem.Rate64 = &rate
+			}
+			// The packet overhead may be negative, as with the
+			// cell overhead.
+			for _, field := range []*int32{&netem.Rate.PacketOverhead, &netem.Rate.CellSize, &netem.Rate.CellOverhead} {
+				if i+1 >= len(args) {
+					break
+				}
+				val, err := strconv.ParseInt(args[i+1], 10, 32)
+				if err != nil {
+					break
+				}
+				*field = int32(val)
+				i++
+			}
+		case "ecn":
+			on := uint32(1)
+			netem.Ecn = &on
+		case "distribution", "slot":
+			return nil, ErrNotImplemented
+		case "help":
+			fmt.Fprint(out, NetemHelp)
+			return nil, ErrExitAfterHelp
+		default:
+			return nil, fmt.Errorf("%w: netem: %s", ErrInvalidArg, args[i])
+		}
+	}
+
+	if netem.Reorder != nil && netem.Reorder.Probability != 0 {
+		if netem.Latency64 == nil || *netem.Latency64 == 0 {
+			return nil, fmt.Errorf("%w: netem: reordering needs a delay", ErrInvalidArg)
+		}
+		if netem.Qopt.Gap == 0 {
+			netem.Qopt.Gap = 1
+		}
+	} else if netem.Qopt.Gap != 0 {
+		return nil, fmt.Errorf("%w: netem: gap needs reordering", ErrInvalidArg)
+	}
+	if *corr != (tc.NetemCorr{}) {
+		netem.Corr = corr
+	}
+
+	ret := &tc.Object{}
+	ret.Kind = "netem"
+	ret.Netem = netem
+	return ret, nil
+}
+
+const TBFHelp = `Usage: ... tbf rate RATE burst BYTES ( latency TIME | limit BYTES )
+		[ mpu BYTES ] [ peakrate RATE mtu BYTES ]
+`
+
+// ParseTBFArgs parses a []string from the commandline for the tbf qdisc
+// via `tc qdisc ... tbf ...` and returns an *tc.Object accordingly.
+func ParseTBFArgs(out io.Writer, args []string) (*tc.Object, error) {
+	var rate, peakRate, burst, mtu, limit uint64
+	var latency uint32
+	parms := &tc.TbfQopt{}
+
+	for i := 0; i < len(args); i = i + 2 {
+		if args[i] == "help" {
+			fmt.Fprint(out, TBFHelp)
+			return nil, ErrExitAfterHelp
+		}
+		if i+1 >= len(args) {
+			return nil, ErrNotEnoughArgs
+		}
+		var err error
+		switch args[i] {
+		case "rate":
+			rate, err = ParseRate(args[i+1])
+		case "peakrate":
+			peakRate, err = ParseRate(args[i+1])
+		case "burst", "buffer", "maxburst":
+			burst, err = ParseSize(args[i+1])
+		case "mtu", "minburst":
+			mtu, err = ParseSize(args[i+1])
+		case "limit":
+			limit, err = ParseSize(args[i+1])
+		case "latency":
+			latency, err = parseTime(args[i+1])
+		case "mpu":
+			var mpu uint64
+			mpu, err = strconv.ParseUint(args[i+1], 10, 16)
+			parms.Rate.Mpu = uint16(mpu)
+		default:
+			return nil, fmt.Errorf("%w: tbf: %s", ErrInvalidArg, args[i])
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if rate == 0 || burst == 0 || (limit == 0) == (latency == 0) {
+		return nil, fmt.Errorf("%w: tbf needs rate, burst and either latency or limit", ErrNotEnoughArgs)
+	}
+	if peakRate != 0 && mtu == 0 {
+		return nil, fmt.Errorf("%w: tbf needs mtu with peakrate", ErrNotEnoughArgs)
+	}
+	if rate > maxUint32 || peakRate > maxUint32 {
+		return nil, ErrOutOfBounds
+	}
+	if limit == 0 {
+		// The queue holds what is sent in latency, and the burst.
+		limit = rate*uint64(latency)/TimeUnitsPerSecs + burst
+		if peakRate != 0 {
+			limit = min(limit, peakRate*uint64(latency)/TimeUnitsPerSecs+mtu)
+		}
+	}
+
+	parms.Rate.Rate = uint32(rate)
+	parms.Rate.Linklayer = 1
+	parms.Limit = uint32(min(limit, maxUint32))
+	buffer, err := CalcXMitTime(rate, uint32(burst))
+	if err != nil {
+		return nil, err
+	}
+	parms.Buffer = buffer
+	tbf := &tc.Tbf{Parms: parms}
+	b := uint32(burst)
+	tbf.Burst = &b
+	if peakRate != 0 {
+		parms.PeakRate.Rate = uint32(peakRate)
+		parms.PeakRate.Linklayer = 1
+		if parms.Mtu, err = CalcXMitTime(peakRate, uint32(mtu)); err != nil {
+			return nil, err
+		}
+		pb := uint32(mtu)
+		tbf.Pburst = &pb
+	}
+
+	ret := &tc.Object{}
+	ret.Kind = "tbf"
+	ret.Tbf = tbf
+	return ret, nil
+}
+
+const FqCodelHelp = `Usage: ... fq_codel [ limit PACKETS ] [ flows NUMBER ]
+		 [ memory_limit BYTES ]
+		 [ target TIME ] [ interval TIME ]
+		 [ quantum BYTES ] [ [no]ecn ]
+		 [ ce_threshold TIME ] [ drop_batch SIZE ]
+`
+
+// ParseFqCodelArgs parses a []string from the commandline for the fq_codel
+// qdisc via `tc qdisc ... fq_codel ...` and returns an *tc.Object
+// accordingly.
+func ParseFqCodelArgs(out io.Writer, args []string) (*tc.Object, error) {
+	fq := &tc.FqCodel{}
+
+	for i := 0; i < len(args); i = i + 2 {
+		var field **uint32
+		switch args[i] {
+		case "ecn", "noecn":
+			ecn := uint32(0)
+			if args[i] == "ecn" {
+				ecn = 1
+			}
+			fq.ECN = &ecn
+			i--
+			continue
+		case "help":
+			fmt.Fprint(out, FqCodelHelp)
+			return nil, ErrExitAfterHelp
+		case "target", "interval", "ce_threshold":
+			if i+1 >= len(args) {
+				return nil, ErrNotEnoughArgs
+			}
+			val, err := parseTime(args[i+1])
+			if err != nil {
+				return nil, err
+			}
+			switch args[i] {
+			case "target":
+				fq.Target = &val
+			case "interval":
+				fq.Interval = &val
+			case "ce_threshold":
+				fq.CEThreshold = &val
+			}
+			continue
+		case "memory_limit", "quantum":
+			if i+1 >= len(args) {
+				return nil, ErrNotEnoughArgs
+			}
+			val, err := ParseSize(args[i+1])
+			if err != nil {
+				return nil, err
+			}
+			indirect := uint32(val)
+			if args[i] == "quantum" {
+				fq.Quantum = &indirect
+			} else {
+				fq.MemoryLimit = &indirect
+			}
+			continue
+		case "limit":
+			field = &fq.Limit
+		case "flows":
+			field = &fq.Flows
+		case "drop_batch":
+			field = &fq.DropBatchSize
+		default:
+			return nil, fmt.Errorf("%w: fq_codel: %s", ErrInvalidArg, args[i])
+		}
+		if i+1 >= len(args) {
+			return nil, ErrNotEnoughArgs
+		}
+		val, err := strconv.ParseUint(args[i+1], 10, 32)
+		if err != nil {
+			return nil, err
+		}
+		indirect := uint32(val)
+		*field = &indirect
+	}
+
+	ret := &tc.Object{}
+	ret.Kind = "fq_codel"
+	ret.FqCodel = fq
+	return ret, nil
+}
+
+const CakeHelp = `Usage: ... cake [ bandwidth RATE | unlimited | autorate-ingress ]
+		[ rtt TIME | datacentre | lan | metro | regional |
+		  internet | oceanic | satellite | interplanetary ]
+		[ besteffort | diffserv8 | diffserv4 | diffserv3 | precedence ]
+		[ flowblind | srchost | dsthost | hosts | flows |
+		  dual-srchost | dual-dsthost | triple-isolate ]
+		[ nat | nonat ] [ wash | nowash ] [ split-gso | no-split-gso ]
+		[ ack-filter | ack-filter-aggressive | no-ack-filter ]
+		[ memlimit LIMIT ] [ fwmark MASK ]
+		[ ptm | atm | noatm ] [ overhead N | conservative | raw ]
+		[ mpu N ] [ ingress | egress ]
+`
+
+// Cake modes, from include/uapi/linux/pkt_sched.h.
+var (
+	cakeRTTs = map[string]uint32{
+		"datacentre":     100,
+		"lan":            1000,
+		"metro":          10000,
+		"regional":       30000,
+		"internet":       100000,
+		"oceanic":        300000,
+		"satellite":      1000000,
+		"interplanetary": 1000000000,
+	}
+	cakeDiffServModes = []string{"diffserv3", "diffserv4", "diffserv8", "besteffort", "precedence"}
+	cakeFlowModes     = []string{"flowblind", "srchost", "dsthost", "hosts", "flows", "dual-srchost", "dual-dsthost", "triple-isolate"}
+	cakeAckFilters    = []string{"no-ack-filter", "ack-filter", "ack-filter-aggressive"}
+	cakeAtmModes      = []string{"noatm", "atm", "ptm"}
+)
+
+// ParseCakeArgs parses a []string from the commandline for the cake qdisc
+// via `tc qdisc ... cake ...` and returns an *tc.Object accordingly.
+func ParseCakeArgs(out io.Writer, args []string) (*tc.Object, error) {
+	cake := &tc.Cake{}
+	u32 := func(v uint32) *uint32 { return &v }
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if rtt, ok := cakeRTTs[arg]; ok {
+			cake.Rtt = u32(rtt)
+			continue
+		}
+		if m := slices.Index(cakeDiffServModes, arg); m >= 0 {
+			cake.DiffServMode = u32(uint32(m))
+			continue
+		}
+		if m := slices.Index(cakeFlowModes, arg); m >= 0 {
+			cake.FlowMode = u32(uint32(m))
+			continue
+		}
+		if m := slices.Index(cakeAckFilters, arg); m >= 0 {
+			cake.AckFilter = u32(uint32(m))
+			continue
+		}
+		if m := slices.Index(cakeAtmModes, arg); m >= 0 {
+			cake.Atm = u32(uint32(m))
+			continue
+		}
+
+		switch arg {
+		case "unlimited":
+			rate := uint64(0)
+			cake.BaseRate = &rate
+			continue
+		case "autorate-ingress":
+			cake.Autorate = u32(1)
+			continue
+		case "nat", "nonat":
+			cake.Nat = u32(b2u(arg == "nat"))
+			continue
+		case "wash", "nowash":
+			cake.Wash = u32(b2u(arg == "wash"))
+			continue
+		case "split-gso", "no-split-gso":
+			cake.SplitGso = u32(b2u(arg == "split-gso"))
+			continue
+		case "ingress", "egress":
+			cake.Ingress = u32(b2u(arg == "ingress"))
+			continue
+		case "conservative":
+			// ATM with the largest overhead of common encapsulations.
+			cake.Atm = u32(1)
+			cake.Overhead = u32(48)
+			continue
+		case "raw":
+			cake.Raw = u32(1)
+			continue
+		case "help":
+			fmt.Fprint(out, CakeHelp)
+			return nil, ErrExitAfterHelp
+		}
+
+		if !slices.Contains([]string{"bandwidth", "rtt", "overhead", "mpu", "memlimit", "fwmark"}, arg) {
+			return nil, fmt.Errorf("%w: cake: %s", ErrInvalidArg, arg)
+		}
+		if i+1 >= len(args) {
+			return nil, fmt.Errorf("%w: cake: %s", ErrNotEnoughArgs, arg)
+		}
+		val := args[i+1]
+		i++
+		switch arg {
+		case "bandwidth":
+			rate, err := ParseRate(val)
+			if err != nil {
+				return nil, err
+			}
+			cake.BaseRate = &rate
+		case "rtt":
+			rtt, err := parseTime(val)
+			if err != nil {
+				return nil, err
+			}
+			cake.Rtt = &rtt
+		case "overhead":
+			overhead, err := strconv.ParseInt(val, 10, 32)
+			if err != nil {
+				return nil, err
+			}
+			if overhead < -64 || overhead > 256 {
+				return nil, ErrOutOfBounds
+			}
+			cake.Overhead = u32(uint32(int32(overhead)))
+		case "mpu":
+			mpu, err := strconv.ParseUint(val, 10, 16)
+			if err != nil {
+				return nil, err
+			}
+			cake.Mpu = u32(uint32(mpu))
+		case "memlimit":
+			limit, err := ParseSize(val)
+			if err != nil {
+				return nil, err
+			}
+			cake.Memory = u32(uint32(limit))
+		case "fwmark":
+			mark, err := strconv.ParseUint(val, 0, 32)
+			if err != nil {
+				return nil, err
+			}
+			cake.FwMark = u32(uint32(mark))
+		}
+	}
+
+	ret := &tc.Object{}
+	ret.Kind = "cake"
+	ret.Cake = cake
+	return ret, nil
+}
+
+func b2u(b bool) uint32 {
+	if b {
+		return 1
+	}
+	return 0
+}
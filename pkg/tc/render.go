@@ -0,0 +1,425 @@
+// Copyright 2012-2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trafficctl
+
+import (
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+
+	"github.com/florianl/go-tc"
+)
+
+// RenderQdiscOptions returns the options of a qdisc in the form they are
+// given to `tc qdisc add`, so that they can be parsed again.
+func RenderQdiscOptions(q *tc.Object) string {
+	var s strings.Builder
+	switch {
+	case q.Htb != nil:
+		if q.Htb.Init != nil {
+			fmt.Fprintf(&s, " r2q %d default 0x%x", q.Htb.Init.Rate2Quantum, q.Htb.Init.Defcls)
+		}
+		if q.Htb.DirectQlen != nil {
+			fmt.Fprintf(&s, " direct_qlen %d", *q.Htb.DirectQlen)
+		}
+	case q.Netem != nil:
+		renderNetem(&s, q.Netem)
+	case q.Tbf != nil:
+		renderTBF(&s, q.Tbf)
+	case q.FqCodel != nil:
+		renderFqCodel(&s, q.FqCodel)
+	case q.Cake != nil:
+		renderCake(&s, q.Cake)
+	}
+	return s.String()
+}
+
+func renderNetem(s *strings.Builder, n *tc.Netem) {
+	var corr tc.NetemCorr
+	if n.Corr != nil {
+		corr = *n.Corr
+	}
+	// optPercent renders a correlation, if there is one.
+	optPercent := func(p uint32) string {
+		if p == 0 {
+			return ""
+		}
+		return " " + RenderPercent(p)
+	}
+
+	fmt.Fprintf(s, " limit %d", n.Qopt.Limit)
+	if n.Latency64 != nil && *n.Latency64 != 0 {
+		fmt.Fprintf(s, " delay %s", renderTime(uint64(*n.Latency64/1000)))
+		var jitter int64
+		if n.Jitter64 != nil {
+			jitter = *n.Jitter64
+		}
+		if jitter != 0 || corr.Delay != 0 {
+			fmt.Fprintf(s, " %s%s", renderTime(uint64(jitter/1000)), optPercent(corr.Delay))
+		}
+	}
+	if n.Qopt.Loss != 0 {
+		fmt.Fprintf(s, " loss %s%s", RenderPercent(n.Qopt.Loss), optPercent(corr.Loss))
+	}
+	if n.Qopt.Duplicate != 0 {
+		fmt.Fprintf(s, " duplicate %s%s", RenderPercent(n.Qopt.Duplicate), optPercent(corr.Dup))
+	}
+	if n.Reorder != nil && n.Reorder.Probability != 0 {
+		fmt.Fprintf(s, " reorder %s%s gap %d", RenderPercent(n.Reorder.Probability), optPercent(n.Reorder.Correlation), n.Qopt.Gap)
+	}
+	if n.Corrupt != nil && n.Corrupt.Probability != 0 {
+		fmt.Fprintf(s, " corrupt %s%s", RenderPercent(n.Corrupt.Probability), optPercent(n.Corrupt.Correlation))
+	}
+	if n.Rate != nil && (n.Rate.Rate != 0 || n.Rate64 != nil) {
+		rate := uint64(n.Rate.Rate)
+		if n.Rate64 != nil {
+			rate = *n.Rate64
+		}
+		fmt.Fprintf(s, " rate %s", RenderRate(rate))
+		// The overheads are positional, so the first ones are rendered
+		// as soon as a later one is set.
+		fields := []int32{n.Rate.PacketOverhead, n.Rate.CellSize, n.Rate.CellOverhead}
+		for len(fields) > 0 && fields[len(fields)-1] == 0 {
+			fields = fields[:len(fields)-1]
+		}
+		for _, f := range fields {
+			fmt.Fprintf(s, " %d", f)
+		}
+	}
+	if n.Ecn != nil && *n.Ecn != 0 {
+		fmt.Fprint(s, " ecn")
+	}
+}
+
+func renderTBF(s *strings.Builder, t *tc.Tbf) {
+	if t.Parms == nil {
+		return
+	}
+	p := t.Parms
+	rate := uint64(p.Rate.Rate)
+	fmt.Fprintf(s, " rate %s", RenderRate(rate))
+	// The kernel does not return the burst, but the time it takes to send
+	// it.
+	burst := p.Buffer
+	if t.Burst != nil {
+		burst = *t.Burst
+	} else if b, err := CalcXMitSize(rate, p.Buffer); err == nil {
+		burst = b
+	}
+	fmt.Fprintf(s, " burst %s limit %s", RenderSize(uint64(burst)), RenderSize(uint64(p.Limit)))
+	if p.Rate.Mpu != 0 {
+		fmt.Fprintf(s, " mpu %d", p.Rate.Mpu)
+	}
+	if p.PeakRate.Rate != 0 {
+		peak := uint64(p.PeakRate.Rate)
+		mtu := p.Mtu
+		if t.Pburst != nil {
+			mtu = *t.Pburst
+		} else if m, err := CalcXMitSize(peak, p.Mtu); err == nil {
+			mtu = m
+		}
+		fmt.Fprintf(s, " peakrate %s mtu %s", RenderRate(peak), RenderSize(uint64(mtu)))
+	}
+}
+
+func renderFqCodel(s *strings.Builder, f *tc.FqCodel) {
+	for _, opt := range []struct {
+		name   string
+		val    *uint32
+		render func(uint32) string
+	}{
+		{"limit", f.Limit, nil},
+		{"flows", f.Flows, nil},
+		{"quantum", f.Quantum, func(v uint32) string { return RenderSize(uint64(v)) }},
+		{"target", f.Target, func(v uint32) string { return renderTime(uint64(v)) }},
+		{"interval", f.Interval, func(v uint32) string { return renderTime(uint64(v)) }},
+		{"memory_limit", f.MemoryLimit, func(v uint32) string { return RenderSize(uint64(v)) }},
+		{"ce_threshold", f.CEThreshold, func(v uint32) string { return renderTime(uint64(v)) }},
+		{"drop_batch", f.DropBatchSize, nil},
+	} {
+		if opt.val == nil {
+			continue
+		}
+		// The kernel returns a threshold of ~0 if there is none.
+		if opt.name == "ce_threshold" && *opt.val == maxUint32 {
+			continue
+		}
+		if opt.render == nil {
+			fmt.Fprintf(s, " %s %d", opt.name, *opt.val)
+		} else {
+			fmt.Fprintf(s, " %s %s", opt.name, opt.render(*opt.val))
+		}
+	}
+	if f.ECN != nil {
+		if *f.ECN != 0 {
+			fmt.Fprint(s, " ecn")
+		} else {
+			fmt.Fprint(s, " noecn")
+		}
+	}
+}
+
+func renderCake(s *strings.Builder, c *tc.Cake) {
+	// mode renders a mode of a list of names.
+	mode := func(v *uint32, names []string) {
+		if v != nil && int(*v) < len(names) {
+			fmt.Fprintf(s, " %s", names[*v])
+		}
+	}
+	// flag renders a boolean option.
+	flag := func(v *uint32, on, off string) {
+		switch {
+		case v == nil:
+		case *v != 0:
+			fmt.Fprintf(s, " %s", on)
+		case off != "":
+			fmt.Fprintf(s, " %s", off)
+		}
+	}
+
+	if c.BaseRate != nil {
+		if *c.BaseRate == 0 {
+			fmt.Fprint(s, " unlimited")
+		} else {
+			fmt.Fprintf(s, " bandwidth %s", RenderRate(*c.BaseRate))
+		}
+	}
+	flag(c.Autorate, "autorate-ingress", "")
+	mode(c.DiffServMode, cakeDiffServModes)
+	mode(c.FlowMode, cakeFlowModes)
+	if c.Rtt != nil {
+		fmt.Fprintf(s, " rtt %s", renderTime(uint64(*c.Rtt)))
+	}
+	flag(c.Nat, "nat", "nonat")
+	flag(c.Wash, "wash", "nowash")
+	flag(c.SplitGso, "split-gso", "no-split-gso")
+	mode(c.AckFilter, cakeAckFilters)
+	flag(c.Ingress, "ingress", "egress")
+	if c.Memory != nil && *c.Memory != 0 {
+		fmt.Fprintf(s, " memlimit %s", RenderSize(uint64(*c.Memory)))
+	}
+	if c.FwMark != nil && *c.FwMark != 0 {
+		fmt.Fprintf(s, " fwmark 0x%x", *c.FwMark)
+	}
+	mode(c.Atm, cakeAtmModes)
+	if c.Raw != nil {
+		fmt.Fprint(s, " raw")
+	} else if c.Overhead != nil {
+		fmt.Fprintf(s, " overhead %d", int32(*c.Overhead))
+	}
+	if c.Mpu != nil && *c.Mpu != 0 {
+		fmt.Fprintf(s, " mpu %d", *c.Mpu)
+	}
+}
+
+// RenderFilterOptions returns the options of a filter, without its actions,
+// as `tc filter show` does.
+func RenderFilterOptions(f *tc.Object) string {
+	var s strings.Builder
+	switch {
+	case f.Basic != nil:
+		if f.Basic.ClassID != nil {
+			fmt.Fprintf(&s, " flowid %s", RenderClassID(*f.Basic.ClassID, false))
+		}
+	case f.U32 != nil:
+		if f.U32.ClassID != nil {
+			fmt.Fprintf(&s, " flowid %s", RenderClassID(*f.U32.ClassID, false))
+		}
+		if f.U32.Sel != nil {
+			for _, key := range f.U32.Sel.Keys {
+				fmt.Fprintf(&s, "\n  match %08x/%08x at %d", NToHL(key.Val), NToHL(key.Mask), key.Off)
+			}
+		}
+	case f.Flower != nil:
+		renderFlower(&s, f.Flower)
+	}
+	return s.String()
+}
+
+func renderFlower(s *strings.Builder, f *tc.Flower) {
+	if f.ClassID != nil {
+		fmt.Fprintf(s, "\n  classid %s", RenderClassID(*f.ClassID, false))
+	}
+	if f.Indev != nil {
+		fmt.Fprintf(s, "\n  indev %s", *f.Indev)
+	}
+	if f.KeyVlanID != nil {
+		fmt.Fprintf(s, "\n  vlan_id %d", *f.KeyVlanID)
+	}
+	if f.KeyVlanPrio != nil {
+		fmt.Fprintf(s, "\n  vlan_prio %d", *f.KeyVlanPrio)
+	}
+	for _, mac := range []struct {
+		name string
+		key  *net.HardwareAddr
+	}{
+		{"dst_mac", f.KeyEthDst},
+		{"src_mac", f.KeyEthSrc},
+	} {
+		if mac.key != nil {
+			fmt.Fprintf(s, "\n  %s %s", mac.name, *mac.key)
+		}
+	}
+	if f.KeyIPProto != nil {
+		fmt.Fprintf(s, "\n  ip_proto %s", renderIPProto(*f.KeyIPProto))
+	}
+	for _, ip := range []struct {
+		name      string
+		key, mask *net.IP
+	}{
+		{"dst_ip", f.KeyIPv4Dst, f.KeyIPv4DstMask},
+		{"src_ip", f.KeyIPv4Src, f.KeyIPv4SrcMask},
+	} {
+		if ip.key == nil {
+			continue
+		}
+		fmt.Fprintf(s, "\n  %s %s", ip.name, *ip.key)
+		if ip.mask != nil {
+			if ones, bits := net.IPMask(ip.mask.To4()).Size(); ones != bits {
+				fmt.Fprintf(s, "/%d", ones)
+			}
+		}
+	}
+	for _, port := range []struct {
+		name string
+		key  *uint16
+	}{
+		{"dst_port", f.KeyTCPDst},
+		{"src_port", f.KeyTCPSrc},
+		{"dst_port", f.KeyUDPDst},
+		{"src_port", f.KeyUDPSrc},
+		{"dst_port", f.KeySctpDst},
+		{"src_port", f.KeySctpSrc},
+	} {
+		if port.key != nil {
+			fmt.Fprintf(s, "\n  %s %d", port.name, *port.key)
+		}
+	}
+	if f.KeyIPTOS != nil {
+		fmt.Fprintf(s, "\n  ip_tos 0x%x", *f.KeyIPTOS)
+		if f.KeyIPTOSMask != nil && *f.KeyIPTOSMask != 0xff {
+			fmt.Fprintf(s, "/0x%x", *f.KeyIPTOSMask)
+		}
+	}
+	if f.KeyIPTTL != nil {
+		fmt.Fprintf(s, "\n  ip_ttl %d", *f.KeyIPTTL)
+		if f.KeyIPTTLMask != nil && *f.KeyIPTTLMask != 0xff {
+			fmt.Fprintf(s, "/0x%x", *f.KeyIPTTLMask)
+		}
+	}
+	if f.Flags != nil {
+		if *f.Flags&tcaClsFlagsSkipHW != 0 {
+			fmt.Fprint(s, "\n  skip_hw")
+		}
+		if *f.Flags&tcaClsFlagsSkipSW != 0 {
+			fmt.Fprint(s, "\n  skip_sw")
+		}
+	}
+}
+
+// RenderActions returns the actions of a filter, with their statistics if
+// stats is set, as `tc filter show` does.
+func RenderActions(actions []*tc.Action, stats bool) string {
+	var s strings.Builder
+	for i, act := range actions {
+		fmt.Fprintf(&s, "\n\taction order %d: ", i+1)
+		switch {
+		case act.Gact != nil && act.Gact.Parms != nil:
+			fmt.Fprintf(&s, "gact action %s", RenderControl(act.Gact.Parms.Action))
+		case act.Mirred != nil && act.Mirred.Parms != nil:
+			p := act.Mirred.Parms
+			dev := fmt.Sprintf("%d", p.IfIndex)
+			if iface, err := net.InterfaceByIndex(int(p.IfIndex)); err == nil {
+				dev = iface.Name
+			}
+			fmt.Fprintf(&s, "mirred (%s to device %s) %s", mirredActions[p.Eaction], dev, RenderControl(p.Action))
+		case act.Police != nil && act.Police.Tbf != nil:
+			p := act.Police.Tbf
+			fmt.Fprintf(&s, "police 0x%x", p.Index)
+			if p.Rate.Rate != 0 {
+				fmt.Fprintf(&s, " rate %s", RenderRate(uint64(p.Rate.Rate)))
+				if burst, err := CalcXMitSize(uint64(p.Rate.Rate), p.Burst); err == nil {
+					fmt.Fprintf(&s, " burst %s", RenderSize(uint64(burst)))
+				}
+			}
+			if p.Mtu != 0 {
+				fmt.Fprintf(&s, " mtu %s", RenderSize(uint64(p.Mtu)))
+			}
+			if p.PeakRate.Rate != 0 {
+				fmt.Fprintf(&s, " peakrate %s", RenderRate(uint64(p.PeakRate.Rate)))
+			}
+			fmt.Fprintf(&s, " action %s", RenderControl(uint32(p.Action)))
+			if act.Police.Result != nil {
+				fmt.Fprintf(&s, "/%s", RenderControl(*act.Police.Result))
+			}
+		default:
+			fmt.Fprint(&s, act.Kind)
+		}
+		if stats && act.Stats != nil {
+			fmt.Fprint(&s, "\n\tAction statistics:")
+			var st stats2
+			if b := act.Stats.Basic; b != nil {
+				st.bytes, st.packets = b.Bytes, b.Packets
+			}
+			if q := act.Stats.Queue; q != nil {
+				st.qlen, st.backlog, st.drops, st.requeues, st.overlimits = q.QueueLen, q.Backlog, q.Drops, q.Requeues, q.Overlimits
+			}
+			for _, line := range strings.Split(strings.TrimSuffix(st.String(), "\n"), "\n") {
+				fmt.Fprintf(&s, "\n\t%s", line)
+			}
+		}
+	}
+	return s.String()
+}
+
+// stats2 holds the counters that `tc -s` shows.
+type stats2 struct {
+	bytes                                               uint64
+	packets, drops, overlimits, requeues, backlog, qlen uint32
+}
+
+func (st stats2) String() string {
+	return fmt.Sprintf(" Sent %d bytes %d pkt (dropped %d, overlimits %d requeues %d)\n backlog %s %dp requeues %d\n",
+		st.bytes, st.packets, st.drops, st.overlimits, st.requeues,
+		RenderSize(uint64(st.backlog)), st.qlen, st.requeues)
+}
+
+// RenderStats returns the statistics of a qdisc or class, as `tc -s` shows
+// them.
+//
+// go-tc decodes TCA_STATS2 as a flat struct, while the kernel sends it as
+// nested attributes, so only the legacy TCA_STATS is used, which the kernel
+// sends alongside it and lacks the requeues.
+func RenderStats(attr *tc.Attribute) string {
+	if attr.Stats == nil {
+		return ""
+	}
+	return stats2{
+		bytes:      attr.Stats.Bytes,
+		packets:    attr.Stats.Packets,
+		drops:      attr.Stats.Drops,
+		overlimits: attr.Stats.Overlimits,
+		backlog:    attr.Stats.Backlog,
+		qlen:       attr.Stats.Qlen,
+	}.String()
+}
+
+// RenderControl returns the name of the control action of an action.
+func RenderControl(act uint32) string {
+	for _, c := range controls {
+		if uint32(c.act) == act {
+			return c.name
+		}
+	}
+	return fmt.Sprintf("0x%x", act)
+}
+
+func renderIPProto(p uint8) string {
+	if i := slices.IndexFunc(ipProtos, func(ip ipProto) bool { return ip.nr == p }); i >= 0 {
+		return ipProtos[i].name
+	}
+	return fmt.Sprintf("%d", p)
+}
@@ -40,4 +40,8 @@ type Tctl interface {
 
 type Trafficctl struct {
 	*tc.Tc
+
+	// Stats shows the statistics of qdiscs, classes and actions, as
+	// `tc -s` does.
+	Stats bool
 }
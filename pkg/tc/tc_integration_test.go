@@ -7,6 +7,7 @@ package trafficctl_test
 import (
 	"bytes"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
@@ -229,3 +230,141 @@ func TestFilter(t *testing.T) {
 		t.Errorf("DeleteFilter() = %v, not nil", err)
 	}
 }
+
+func TestQdiscOptions(t *testing.T) {
+	guest.SkipIfNotInVM(t)
+
+	rtnl, err := tc.Open(&tc.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rtnl.Close()
+
+	tctl := &trafficctl.Trafficctl{Tc: rtnl, Stats: true}
+
+	for _, tt := range []struct {
+		args string
+		want string
+	}{
+		{
+			args: "netem delay 100ms 10ms loss 1% duplicate 2% corrupt 0.5%",
+			want: "netem 1: root limit 1000 delay 100ms 10ms loss 1% duplicate 2% corrupt 0.5%",
+		},
+		{
+			args: "tbf rate 1mbit burst 32kb limit 65536",
+			want: "tbf 1: root rate 1mbit burst 32kb limit 64kb",
+		},
+		{
+			args: "fq_codel limit 1024 target 5ms interval 100ms ecn",
+			want: "fq_codel 1: root limit 1024",
+		},
+		{
+			args: "cake bandwidth 100mbit besteffort",
+			want: "cake 1: root bandwidth 100mbit besteffort",
+		},
+	} {
+		t.Run(tt.args, func(t *testing.T) {
+			var outbuf bytes.Buffer
+			args, err := trafficctl.ParseQdiscArgs(&outbuf,
+				append([]string{"dev", DummyInterface1, "root", "handle", "1:"}, strings.Fields(tt.args)...))
+			if err != nil {
+				t.Fatalf("ParseQdiscArgs() = %v, not nil", err)
+			}
+
+			if err := tctl.AddQdisc(&outbuf, args); err != nil {
+				t.Fatalf("AddQdisc() = %v, not nil", err)
+			}
+			defer func() {
+				if err := tctl.DeleteQdisc(&outbuf, args); err != nil {
+					t.Errorf("DeleteQdisc() = %v, not nil", err)
+				}
+			}()
+
+			outbuf.Reset()
+			showArgs, err := trafficctl.ParseQdiscArgs(&outbuf, []string{"dev", DummyInterface1})
+			if err != nil {
+				t.Fatalf("ParseQdiscArgs() = %v, not nil", err)
+			}
+			if err := tctl.ShowQdisc(&outbuf, showArgs); err != nil {
+				t.Fatalf("ShowQdisc() = %v, not nil", err)
+			}
+			if !strings.Contains(outbuf.String(), tt.want) || !strings.Contains(outbuf.String(), " Sent ") {
+				t.Errorf("ShowQdisc() = %q, want %q and statistics", outbuf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestClsactFilters(t *testing.T) {
+	guest.SkipIfNotInVM(t)
+
+	rtnl, err := tc.Open(&tc.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rtnl.Close()
+
+	tctl := &trafficctl.Trafficctl{Tc: rtnl}
+
+	var outbuf bytes.Buffer
+	qargs, err := trafficctl.ParseQdiscArgs(&outbuf, []string{"dev", DummyInterface0, "clsact"})
+	if err != nil {
+		t.Fatalf("ParseQdiscArgs() = %v, not nil", err)
+	}
+	if err := tctl.AddQdisc(&outbuf, qargs); err != nil {
+		t.Fatalf("AddQdisc() = %v, not nil", err)
+	}
+	defer func() {
+		if err := tctl.DeleteQdisc(&outbuf, qargs); err != nil {
+			t.Errorf("DeleteQdisc() = %v, not nil", err)
+		}
+	}()
+
+	for _, tt := range []struct {
+		args string
+		want []string
+	}{
+		{
+			args: "ingress pref 1 protocol ip u32 match ip dport 22 0xffff action mirred egress mirror dev " + DummyInterface1,
+			want: []string{
+				"filter parent ffff:fff2 protocol ip pref 1 u32",
+				"match 00000016/0000ffff at 20",
+				"mirred (Egress Mirror to device " + DummyInterface1 + ") pipe",
+			},
+		},
+		{
+			args: "egress pref 2 protocol ip flower ip_proto udp dst_port 53 action drop",
+			want: []string{
+				"filter parent ffff:fff3 protocol ip pref 2 flower",
+				"ip_proto udp",
+				"dst_port 53",
+				"gact action drop",
+			},
+		},
+	} {
+		t.Run(tt.args, func(t *testing.T) {
+			fArgs, err := trafficctl.ParseFilterArgs(&outbuf,
+				append([]string{"dev", DummyInterface0}, strings.Fields(tt.args)...))
+			if err != nil {
+				t.Fatalf("ParseFilterArgs() = %v, not nil", err)
+			}
+			if err := tctl.AddFilter(&outbuf, fArgs); err != nil {
+				t.Fatalf("AddFilter() = %v, not nil", err)
+			}
+
+			outbuf.Reset()
+			if err := tctl.ShowFilter(&outbuf, fArgs); err != nil {
+				t.Fatalf("ShowFilter() = %v, not nil", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(outbuf.String(), want) {
+					t.Errorf("ShowFilter() = %q, does not contain %q", outbuf.String(), want)
+				}
+			}
+
+			if err := tctl.DeleteFilter(&outbuf, fArgs); err != nil {
+				t.Errorf("DeleteFilter() = %v, not nil", err)
+			}
+		})
+	}
+}
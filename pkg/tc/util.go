@@ -364,12 +364,13 @@ func ParseProto(prot string) (uint16, error) {
 		prot uint16
 	}{
 		{"802_3", 0x0001},
+		{"all", 0x0003},
 		{"802_2", 0x0004},
 		{"ip", 0x800},
 		{"arp", 0x806},
 		{"aarp", 0x80F3},
 		{"ipx", 0x8137},
-
+		{"802_1q", 0x8100},
 		{"ipv6", 0x86DD},
 	} {
 		if p.name == prot {
@@ -395,11 +396,13 @@ func RenderProto(proto uint16) string {
 		prot uint16
 	}{
 		{"802_3", 0x0001},
+		{"all", 0x0003},
 		{"802_2", 0x0004},
 		{"ip", 0x800},
 		{"arp", 0x806},
 		{"aarp", 0x80F3},
 		{"ipx", 0x8137},
+		{"802_1q", 0x8100},
 		{"ipv6", 0x86DD},
 	} {
 		if p.prot == pNr {
@@ -471,3 +474,60 @@ func NToHL(netLong uint32) uint32 {
 	// parse netBytes into native value (this is where bytes may be swapped)
 	return binary.BigEndian.Uint32(netBytes)
 }
+
+// ParsePercent takes a string of the form `12.5%` or `12.5` and returns the
+// percentage scaled to the range of uint32, as the kernel expects
+// probabilities.
+func ParsePercent(s string) (uint32, error) {
+	val, err := strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64)
+	if err != nil {
+		return 0, err
+	}
+	if val < 0 || val > 100 {
+		return 0, ErrOutOfBounds
+	}
+
+	return uint32(math.Round(val / 100 * maxUint32)), nil
+}
+
+// RenderPercent is the inverse of ParsePercent. Like tc, it prints six
+// significant digits, which hides the rounding of the scaling.
+func RenderPercent(p uint32) string {
+	return strconv.FormatFloat(float64(p)/maxUint32*100, 'g', 6, 64) + "%"
+}
+
+// RenderRate is the inverse of ParseRate. It takes a rate in bytes per second
+// and returns it in the largest unit of bits per second that represents it
+// exactly.
+func RenderRate(rate uint64) string {
+	bits := rate * 8
+	for _, unit := range []string{"bit", "kbit", "mbit"} {
+		if bits < 1000 || bits%1000 != 0 {
+			return fmt.Sprintf("%d%s", bits, unit)
+		}
+		bits /= 1000
+	}
+	return fmt.Sprintf("%dgbit", bits)
+}
+
+// RenderSize is the inverse of ParseSize.
+func RenderSize(size uint64) string {
+	for _, unit := range []string{"b", "kb", "mb"} {
+		if size < 1024 || size%1024 != 0 {
+			return fmt.Sprintf("%d%s", size, unit)
+		}
+		size /= 1024
+	}
+	return fmt.Sprintf("%dgb", size)
+}
+
+// renderTime is the inverse of parseTime.
+func renderTime(usecs uint64) string {
+	switch {
+	case usecs != 0 && usecs%TimeUnitsPerSecs == 0:
+		return fmt.Sprintf("%ds", usecs/TimeUnitsPerSecs)
+	case usecs != 0 && usecs%1000 == 0:
+		return fmt.Sprintf("%dms", usecs/1000)
+	}
+	return fmt.Sprintf("%dus", usecs)
+}
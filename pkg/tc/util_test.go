@@ -238,3 +238,61 @@ func TestRenderProto(t *testing.T) {
 		})
 	}
 }
+
+func TestParsePercent(t *testing.T) {
+	for _, tt := range []struct {
+		arg string
+		exp string
+		err error
+	}{
+		{arg: "25%", exp: "25%"},
+		{arg: "0.1", exp: "0.1%"},
+		{arg: "100%", exp: "100%"},
+		{arg: "0", exp: "0%"},
+		{arg: "101%", err: trafficctl.ErrOutOfBounds},
+		{arg: "-1", err: trafficctl.ErrOutOfBounds},
+		{arg: "many", err: strconv.ErrSyntax},
+	} {
+		t.Run(tt.arg, func(t *testing.T) {
+			ret, err := trafficctl.ParsePercent(tt.arg)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("ParsePercent(%q) = %v, not %v", tt.arg, err, tt.err)
+			}
+			if err != nil {
+				return
+			}
+
+			if got := trafficctl.RenderPercent(ret); got != tt.exp {
+				t.Errorf("RenderPercent(%d) = %q, not %q", ret, got, tt.exp)
+			}
+		})
+	}
+}
+
+func TestRenderRate(t *testing.T) {
+	for _, arg := range []string{"5mbit", "12kbit", "1gbit", "1600bit", "1001kbit"} {
+		t.Run(arg, func(t *testing.T) {
+			rate, err := trafficctl.ParseRate(arg)
+			if err != nil {
+				t.Fatalf("ParseRate(%q) = %v", arg, err)
+			}
+			if got := trafficctl.RenderRate(rate); got != arg {
+				t.Errorf("RenderRate(%d) = %q, not %q", rate, got, arg)
+			}
+		})
+	}
+}
+
+func TestRenderSize(t *testing.T) {
+	for _, arg := range []string{"1514b", "32kb", "4mb", "2gb"} {
+		t.Run(arg, func(t *testing.T) {
+			size, err := trafficctl.ParseSize(arg)
+			if err != nil {
+				t.Fatalf("ParseSize(%q) = %v", arg, err)
+			}
+			if got := trafficctl.RenderSize(size); got != arg {
+				t.Errorf("RenderSize(%d) = %q, not %q", size, got, arg)
+			}
+		})
+	}
+}
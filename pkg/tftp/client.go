@@ -46,7 +46,14 @@ func (r *RealResponse) Size() (int64, error) {
 
 // NewClient sets up a new tftp.Client according to the given ClientCfg struct.
 func NewClient(ccfg *ClientCfg) (*Client, error) {
-	c, err := tftp.NewClient(tftp.ClientMode(ccfg.Mode), ccfg.Rexmt, tftp.ClientTransferSize(false), ccfg.Timeout)
+	opts := []tftp.ClientOpt{tftp.ClientMode(ccfg.Mode), ccfg.Rexmt, tftp.ClientTransferSize(false), ccfg.Timeout}
+	if ccfg.Blksize != 0 {
+		opts = append(opts, tftp.ClientBlocksize(ccfg.Blksize))
+	}
+	if ccfg.Windowsize != 0 {
+		opts = append(opts, tftp.ClientWindowsize(ccfg.Windowsize))
+	}
+	c, err := tftp.NewClient(opts...)
 	return &Client{
 		Client: c,
 	}, err
@@ -19,9 +19,11 @@ import (
 // Flags provides the flags used in ./cmds/core/tftp.
 // For more details, see the main-function in ./cmds/core/tftp/main.go.
 type Flags struct {
-	Cmd       string
-	Mode      string
-	PortRange string
+	Cmd        string
+	Mode       string
+	PortRange  string
+	Blksize    int
+	Windowsize int
 }
 
 // ClientCfg holds all configuration values of a client.
@@ -32,6 +34,10 @@ type ClientCfg struct {
 	Mode    tftp.TransferMode
 	Rexmt   tftp.ClientOpt
 	Timeout tftp.ClientOpt
+	// Blksize and Windowsize are the block size (RFC 2348) and window
+	// size (RFC 7440) to negotiate, unless they are 0.
+	Blksize    int
+	Windowsize int
 	// Trace   bool // not supported by pack.ag/tftp
 	// Literal bool // not implemented
 	// Verbose bool // not implemented
@@ -58,11 +64,13 @@ func RunInteractive(f Flags, ipPort []string, stdin io.Reader, stdout io.Writer)
 	}
 
 	clientcfg := &ClientCfg{
-		Host:    ipHost,
-		Port:    port,
-		Mode:    tftp.ModeNetASCII,
-		Rexmt:   tftp.ClientRetransmit(4),
-		Timeout: tftp.ClientTimeout(10),
+		Host:       ipHost,
+		Port:       port,
+		Mode:       tftp.ModeNetASCII,
+		Rexmt:      tftp.ClientRetransmit(4),
+		Timeout:    tftp.ClientTimeout(10),
+		Blksize:    f.Blksize,
+		Windowsize: f.Windowsize,
 	}
 
 	for {
@@ -129,11 +137,22 @@ func ExecuteOp(input []string, clientcfg *ClientCfg, stdout io.Writer) (bool, er
 		fmt.Fprintf(stdout, "Mode: %s \n",
 			clientcfg.Mode,
 		)
+		fmt.Fprintf(stdout, "Blksize: %d Windowsize: %d\n", clientcfg.Blksize, clientcfg.Windowsize)
 	case "timeout":
 		var val int
 		val, err = strconv.Atoi(input[1])
 
 		clientcfg.Timeout = tftp.ClientTimeout(val)
+	case "blksize":
+		var val int
+		val, err = strconv.Atoi(input[1])
+
+		clientcfg.Blksize = val
+	case "windowsize":
+		var val int
+		val, err = strconv.Atoi(input[1])
+
+		clientcfg.Windowsize = val
 	}
 	if err != nil {
 		fmt.Fprintf(stdout, "%v\n", err)
@@ -173,6 +192,8 @@ func printHelp() string {
 	fmt.Fprintf(&s, "ascii / netascii\tset mode to netascii\n")
 	fmt.Fprintf(&s, "rexmt\tset per-packet transmission timeout in seconds\n")
 	fmt.Fprintf(&s, "timeout\tset total retransmission timeout in seconds\n")
+	fmt.Fprintf(&s, "blksize\tset block size to negotiate, 0 for none\n")
+	fmt.Fprintf(&s, "windowsize\tset window size to negotiate, 0 for none\n")
 	fmt.Fprintf(&s, "?\t\tprint help information\n")
 	fmt.Fprintf(&s, "help\tprint help information\n")
 	return s.String()
@@ -0,0 +1,224 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tftpd
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/netip"
+	"os"
+	"path"
+	"strings"
+	"text/template"
+)
+
+// Request is a read or write request of a client.
+type Request struct {
+	// Name is the file name, as a clean relative slash-separated path.
+	Name string
+	// Addr is the address of the client.
+	Addr netip.AddrPort
+	// Mode is "octet" or "netascii".
+	Mode string
+	// Options are the options that the client asked for, with lower
+	// case names.
+	Options map[string]string
+}
+
+// Handler opens the files of read requests.
+type Handler interface {
+	// ServeTFTP returns the contents of the file of r, and its size, or
+	// -1 if it is not known. Errors of fs.ErrNotExist and
+	// fs.ErrPermission reach the client as such.
+	ServeTFTP(r *Request) (io.ReadCloser, int64, error)
+}
+
+// HandlerFunc is a function that is a Handler.
+type HandlerFunc func(r *Request) (io.ReadCloser, int64, error)
+
+// ServeTFTP implements Handler.
+func (f HandlerFunc) ServeTFTP(r *Request) (io.ReadCloser, int64, error) {
+	return f(r)
+}
+
+// WriteHandler creates the files of write requests.
+type WriteHandler interface {
+	// ReceiveTFTP returns the writer of the file of r. Closing it stores
+	// the file. If the transfer fails and the writer is an Aborter, it is
+	// aborted instead, and closed otherwise.
+	ReceiveTFTP(r *Request) (io.WriteCloser, error)
+}
+
+// Aborter is a writer of WriteHandler that can drop the file of a failed
+// transfer.
+type Aborter interface {
+	// Abort discards what was written, and releases the writer.
+	Abort() error
+}
+
+// cleanName turns a requested name into a relative slash-separated path
+// that cannot leave the root. PXE clients of Windows deployments ask for
+// backslash-separated names.
+func cleanName(name string) (string, error) {
+	name = path.Clean("/" + strings.ReplaceAll(name, `\`, "/"))[1:]
+	if name == "" {
+		return "", errInvalidName
+	}
+	return name, nil
+}
+
+// Dir serves and stores the files of a directory. Neither .. nor symbolic
+// links lead out of it.
+type Dir string
+
+// ServeTFTP implements Handler.
+func (d Dir) ServeTFTP(r *Request) (io.ReadCloser, int64, error) {
+	root, err := os.OpenRoot(string(d))
+	if err != nil {
+		return nil, 0, err
+	}
+	defer root.Close()
+
+	f, err := root.Open(r.Name)
+	if err != nil {
+		return nil, 0, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, 0, err
+	}
+	if !fi.Mode().IsRegular() {
+		f.Close()
+		return nil, 0, &fs.PathError{Op: "open", Path: r.Name, Err: fs.ErrNotExist}
+	}
+	return f, fi.Size(), nil
+}
+
+// ReceiveTFTP implements WriteHandler. It creates the directories of the
+// file, too. The file is received under a temporary name in its directory,
+// and replaces the old one only once the transfer is complete, so failed
+// transfers leave it as it was.
+func (d Dir) ReceiveTFTP(r *Request) (io.WriteCloser, error) {
+	root, err := os.OpenRoot(string(d))
+	if err != nil {
+		return nil, err
+	}
+
+	dir, base := path.Split(r.Name)
+	if dir != "" {
+		if err := root.MkdirAll(dir, 0o755); err != nil {
+			root.Close()
+			return nil, err
+		}
+	}
+	tmp := path.Join(dir, "."+base+".tftp-"+rand.Text())
+	f, err := root.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o666)
+	if err != nil {
+		root.Close()
+		return nil, err
+	}
+	return &pendingFile{f: f, root: root, tmp: tmp, name: r.Name}, nil
+}
+
+// pendingFile is a file being received under the temporary name tmp, which
+// Close renames to name.
+type pendingFile struct {
+	f         *os.File
+	root      *os.Root
+	tmp, name string
+}
+
+func (p *pendingFile) Write(b []byte) (int, error) {
+	return p.f.Write(b)
+}
+
+// Close stores the file as p.name.
+func (p *pendingFile) Close() error {
+	defer p.root.Close()
+	err := p.f.Close()
+	if err == nil {
+		err = p.root.Rename(p.tmp, p.name)
+	}
+	if err != nil {
+		p.root.Remove(p.tmp)
+	}
+	return err
+}
+
+// Abort implements Aborter.
+func (p *pendingFile) Abort() error {
+	defer p.root.Close()
+	p.f.Close()
+	return p.root.Remove(p.tmp)
+}
+
+// Mux serves the files of names that match a pattern with the handler of the
+// pattern, and the others with Default. Without Default, other files are not
+// found.
+type Mux struct {
+	Default Handler
+
+	routes []route
+}
+
+type route struct {
+	pattern string
+	h       Handler
+}
+
+// Handle serves the names that match pattern, a path.Match pattern, with h.
+// The first pattern that matches wins.
+func (m *Mux) Handle(pattern string, h Handler) error {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return fmt.Errorf("%q: %w", pattern, err)
+	}
+	m.routes = append(m.routes, route{pattern: pattern, h: h})
+	return nil
+}
+
+// ServeTFTP implements Handler.
+func (m *Mux) ServeTFTP(r *Request) (io.ReadCloser, int64, error) {
+	for _, rt := range m.routes {
+		if ok, _ := path.Match(rt.pattern, r.Name); ok {
+			return rt.h.ServeTFTP(r)
+		}
+	}
+	if m.Default == nil {
+		return nil, 0, &fs.PathError{Op: "open", Path: r.Name, Err: fs.ErrNotExist}
+	}
+	return m.Default.ServeTFTP(r)
+}
+
+// TemplateData is the data of the templates of Template.
+type TemplateData struct {
+	*Request
+
+	// MAC is the hardware address of a file named as the per-client
+	// configuration files of PXELINUX, pxelinux.cfg/01-aa-bb-cc-dd-ee-ff,
+	// with the ARP hardware type 1 first. It is nil for other names.
+	MAC net.HardwareAddr
+}
+
+// Template returns a handler that renders t with the TemplateData of each
+// request, to synthesise files such as per-client boot configurations.
+func Template(t *template.Template) Handler {
+	return HandlerFunc(func(r *Request) (io.ReadCloser, int64, error) {
+		data := &TemplateData{Request: r}
+		if hw, ok := strings.CutPrefix(path.Base(r.Name), "01-"); ok {
+			data.MAC, _ = net.ParseMAC(strings.ReplaceAll(hw, "-", ":"))
+		}
+
+		var b bytes.Buffer
+		if err := t.Execute(&b, data); err != nil {
+			return nil, 0, err
+		}
+		return io.NopCloser(&b), int64(b.Len()), nil
+	})
+}
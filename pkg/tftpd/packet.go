@@ -0,0 +1,125 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tftpd
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Opcodes, RFC 1350 and RFC 2347.
+const (
+	opRRQ   = 1
+	opWRQ   = 2
+	opDATA  = 3
+	opACK   = 4
+	opERROR = 5
+	opOACK  = 6
+)
+
+// Error codes, RFC 1350 and RFC 2347.
+const (
+	codeNotDefined       = 0
+	codeFileNotFound     = 1
+	codeAccessViolation  = 2
+	codeDiskFull         = 3
+	codeIllegalOperation = 4
+	codeUnknownTID       = 5
+	codeFileExists       = 6
+)
+
+// Options, RFC 2348, RFC 2349 and RFC 7440.
+const (
+	optBlksize    = "blksize"
+	optTimeout    = "timeout"
+	optTsize      = "tsize"
+	optWindowsize = "windowsize"
+)
+
+var (
+	errMalformed     = errors.New("malformed request")
+	errTimeout       = errors.New("transfer timed out")
+	errBusy          = errors.New("server busy, try again later")
+	errNotWritable   = errors.New("writes are not permitted")
+	errInvalidName   = errors.New("invalid file name")
+	errInvalidMode   = errors.New("unsupported transfer mode")
+	errNoReadHandler = errors.New("reads are not permitted")
+)
+
+// peerError is an ERROR packet of the client.
+type peerError struct {
+	code uint16
+	msg  string
+}
+
+func (e *peerError) Error() string {
+	return fmt.Sprintf("client error %d: %s", e.code, e.msg)
+}
+
+// packet builds a packet of op and the uint16 fields.
+func packet(op uint16, fields ...uint16) []byte {
+	b := binary.BigEndian.AppendUint16(nil, op)
+	for _, f := range fields {
+		b = binary.BigEndian.AppendUint16(b, f)
+	}
+	return b
+}
+
+// errorPacket returns the ERROR packet of err.
+func errorPacket(err error) []byte {
+	code := uint16(codeNotDefined)
+	switch {
+	case errors.Is(err, fs.ErrNotExist), errors.Is(err, errNoReadHandler):
+		code = codeFileNotFound
+	case errors.Is(err, fs.ErrPermission), errors.Is(err, errNotWritable), errors.Is(err, errInvalidName):
+		code = codeAccessViolation
+	case errors.Is(err, fs.ErrExist):
+		code = codeFileExists
+	case errors.Is(err, errMalformed), errors.Is(err, errInvalidMode):
+		code = codeIllegalOperation
+	}
+	b := append(packet(opERROR, code), err.Error()...)
+	return append(b, 0)
+}
+
+// oackPacket returns the OACK packet of options, which are sorted so that
+// the packet does not depend on the map order.
+func oackPacket(options map[string]string) []byte {
+	b := packet(opOACK)
+	for _, k := range slices.Sorted(maps.Keys(options)) {
+		b = append(b, k...)
+		b = append(b, 0)
+		b = append(b, options[k]...)
+		b = append(b, 0)
+	}
+	return b
+}
+
+// parseRequest parses the file name, mode and options of an RRQ or WRQ
+// packet. Option names and the mode are case-insensitive, and returned in
+// lower case.
+func parseRequest(b []byte) (name, mode string, options map[string]string, err error) {
+	fields := bytes.Split(b[2:], []byte{0})
+	// The packet ends with a NUL, so the last field is empty.
+	if len(fields) < 3 || len(fields[len(fields)-1]) != 0 {
+		return "", "", nil, errMalformed
+	}
+	fields = fields[:len(fields)-1]
+	name, mode = string(fields[0]), strings.ToLower(string(fields[1]))
+
+	options = map[string]string{}
+	opts := fields[2:]
+	// Ignore an incomplete last option, as some clients pad requests.
+	for i := 0; i+1 < len(opts); i += 2 {
+		options[strings.ToLower(string(opts[i]))] = string(opts[i+1])
+	}
+	return name, mode, options, nil
+}
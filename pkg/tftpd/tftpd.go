@@ -0,0 +1,400 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package tftpd implements a TFTP server (RFC 1350) with the blksize
+// (RFC 2348), timeout and tsize (RFC 2349) and windowsize (RFC 7440)
+// options.
+package tftpd
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"path"
+	"strconv"
+	"sync"
+	"time"
+
+	"pack.ag/tftp/netascii"
+)
+
+// Defaults of the Server fields.
+const (
+	// DefaultMaxBlockSize fills an Ethernet frame over IPv4.
+	DefaultMaxBlockSize  = 1468
+	DefaultMaxWindowSize = 64
+	DefaultTimeout       = time.Second
+	DefaultRetries       = 5
+)
+
+const (
+	defaultBlksize = 512
+	// minBlksize and maxBlksize are the bounds of RFC 2348.
+	minBlksize = 8
+	maxBlksize = 65464
+)
+
+// Server is a TFTP server.
+type Server struct {
+	// Handler serves read requests. Without it, reads fail.
+	Handler Handler
+
+	// WriteHandler serves the write requests of the names that match
+	// one of the Writable path.Match patterns. Without either, the
+	// server is read-only.
+	WriteHandler WriteHandler
+	Writable     []string
+
+	// MaxBlockSize caps the blksize that clients may negotiate. It
+	// defaults to DefaultMaxBlockSize.
+	MaxBlockSize int
+	// MaxWindowSize caps the windowsize that clients may negotiate. It
+	// defaults to DefaultMaxWindowSize.
+	MaxWindowSize int
+	// Timeout is the retransmission timeout of transfers without the
+	// timeout option. It defaults to DefaultTimeout.
+	Timeout time.Duration
+	// Retries is how often a packet is sent again before a transfer
+	// fails. It defaults to DefaultRetries.
+	Retries int
+	// MaxTransfers limits the concurrent transfers, if it is positive.
+	// Clients beyond it get an error.
+	MaxTransfers int
+
+	// Logf logs transfers and errors. It defaults to log.Printf.
+	Logf func(format string, v ...any)
+
+	mu        sync.Mutex
+	conn      *net.UDPConn
+	closed    bool
+	transfers int
+}
+
+// ListenAndServe serves on the UDP address addr, :69 if it is empty.
+func (s *Server) ListenAndServe(addr string) error {
+	if addr == "" {
+		addr = ":69"
+	}
+	a, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return err
+	}
+	conn, err := net.ListenUDP("udp", a)
+	if err != nil {
+		return err
+	}
+	return s.Serve(conn)
+}
+
+// Serve serves the requests of conn until Close. Each transfer gets a port
+// of its own, on the address of conn.
+func (s *Server) Serve(conn *net.UDPConn) error {
+	s.mu.Lock()
+	s.conn = conn
+	s.mu.Unlock()
+	defer conn.Close()
+
+	buf := make([]byte, 65536)
+	for {
+		n, addr, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			s.mu.Unlock()
+			if closed {
+				return nil
+			}
+			return err
+		}
+		if n < 2 {
+			continue
+		}
+		pkt := append([]byte(nil), buf[:n]...)
+		switch binary.BigEndian.Uint16(pkt) {
+		case opRRQ, opWRQ:
+			go s.handle(conn, pkt, addr)
+		default:
+			b := append(packet(opERROR, codeUnknownTID), "unknown transfer ID\x00"...)
+			conn.WriteToUDP(b, addr)
+		}
+	}
+}
+
+// Close stops serving. Transfers in progress go on.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	if s.conn == nil {
+		return nil
+	}
+	return s.conn.Close()
+}
+
+func (s *Server) logf(format string, v ...any) {
+	if s.Logf != nil {
+		s.Logf(format, v...)
+		return
+	}
+	log.Printf(format, v...)
+}
+
+// acquire counts a transfer in, if there is room for it.
+func (s *Server) acquire() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.MaxTransfers > 0 && s.transfers >= s.MaxTransfers {
+		return false
+	}
+	s.transfers++
+	return true
+}
+
+func (s *Server) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.transfers--
+}
+
+// handle serves the RRQ or WRQ pkt of the client at addr.
+func (s *Server) handle(conn *net.UDPConn, pkt []byte, addr *net.UDPAddr) {
+	op := binary.BigEndian.Uint16(pkt)
+	kind := "RRQ"
+	if op == opWRQ {
+		kind = "WRQ"
+	}
+
+	// Errors before the transfer come from the port of the server.
+	reject := func(err error) {
+		conn.WriteToUDP(errorPacket(err), addr)
+		s.logf("%s from %s: %v", kind, addr, err)
+	}
+	if !s.acquire() {
+		reject(errBusy)
+		return
+	}
+	defer s.release()
+
+	name, mode, options, err := parseRequest(pkt)
+	if err != nil {
+		reject(err)
+		return
+	}
+	if mode != "octet" && mode != "netascii" {
+		reject(fmt.Errorf("%w %q", errInvalidMode, mode))
+		return
+	}
+	clean, err := cleanName(name)
+	if err != nil {
+		reject(fmt.Errorf("%q: %w", name, err))
+		return
+	}
+	req := &Request{Name: clean, Addr: addr.AddrPort(), Mode: mode, Options: options}
+
+	// Answer from the address that the client sent to, if there is one.
+	local := conn.LocalAddr().(*net.UDPAddr)
+	tc, err := net.ListenUDP("udp", &net.UDPAddr{IP: local.IP, Zone: local.Zone})
+	if err != nil {
+		reject(err)
+		return
+	}
+	defer tc.Close()
+	t := &transfer{
+		conn:    tc,
+		peer:    addr,
+		blksize: defaultBlksize,
+		window:  1,
+		timeout: s.Timeout,
+		retries: s.Retries,
+	}
+	if t.timeout <= 0 {
+		t.timeout = DefaultTimeout
+	}
+	if t.retries <= 0 {
+		t.retries = DefaultRetries
+	}
+
+	start := time.Now()
+	var n int64
+	if op == opRRQ {
+		n, err = s.read(t, req)
+	} else {
+		n, err = s.write(t, req)
+	}
+	if err != nil {
+		s.logf("%s %q from %s failed after %d bytes: %v", kind, req.Name, addr, n, err)
+		return
+	}
+	s.logf("%s %q from %s: %d bytes in %v (blksize %d, windowsize %d)", kind, req.Name, addr, n, time.Since(start).Round(time.Millisecond), t.blksize, t.window)
+}
+
+// negotiate sets up t with the options of the client, and returns the
+// options to acknowledge, if any. size is the tsize to answer with, if it
+// is not negative.
+func (s *Server) negotiate(t *transfer, options map[string]string, size int64) map[string]string {
+	maxBlock, maxWindow := s.MaxBlockSize, s.MaxWindowSize
+	if maxBlock <= 0 {
+		maxBlock = DefaultMaxBlockSize
+	}
+	if maxWindow <= 0 {
+		maxWindow = DefaultMaxWindowSize
+	}
+
+	// Options of invalid values are ignored, as RFC 2347 allows.
+	ack := map[string]string{}
+	for opt, val := range options {
+		v, err := strconv.ParseInt(val, 10, 64)
+		if err != nil {
+			continue
+		}
+		switch opt {
+		case optBlksize:
+			if v < minBlksize || v > maxBlksize {
+				continue
+			}
+			t.blksize = int(min(v, int64(maxBlock)))
+			ack[opt] = strconv.Itoa(t.blksize)
+		case optTimeout:
+			if v < 1 || v > 255 {
+				continue
+			}
+			t.timeout = time.Duration(v) * time.Second
+			ack[opt] = val
+		case optTsize:
+			// Writes tell the size, reads ask for it.
+			if size < 0 && v == 0 {
+				continue
+			}
+			if size >= 0 {
+				v = size
+			}
+			ack[opt] = strconv.FormatInt(v, 10)
+		case optWindowsize:
+			if v < 1 || v > 65535 {
+				continue
+			}
+			t.window = int(min(v, int64(maxWindow)))
+			ack[opt] = strconv.Itoa(t.window)
+		}
+	}
+	t.buf = make([]byte, 4+max(t.blksize, 512))
+	if len(ack) == 0 {
+		return nil
+	}
+	return ack
+}
+
+// read serves a read request.
+func (s *Server) read(t *transfer, req *Request) (int64, error) {
+	if s.Handler == nil {
+		return 0, t.abort(errNoReadHandler)
+	}
+	rc, size, err := s.Handler.ServeTFTP(req)
+	if err != nil {
+		return 0, t.abort(err)
+	}
+	defer rc.Close()
+
+	var r io.Reader = rc
+	if req.Mode == "netascii" {
+		// The size on the wire is not known before encoding.
+		size = -1
+		pr, pw := io.Pipe()
+		defer pr.Close()
+		go func() {
+			w := netascii.NewWriter(pw)
+			_, err := io.Copy(w, rc)
+			if err == nil {
+				err = w.Flush()
+			}
+			pw.CloseWithError(err)
+		}()
+		r = pr
+	}
+
+	var oack []byte
+	if ack := s.negotiate(t, req.Options, size); ack != nil {
+		oack = oackPacket(ack)
+	}
+	return t.send(r, oack)
+}
+
+// writable is whether clients may write name.
+func (s *Server) writable(name string) bool {
+	if s.WriteHandler == nil {
+		return false
+	}
+	for _, p := range s.Writable {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// write serves a write request.
+func (s *Server) write(t *transfer, req *Request) (int64, error) {
+	if !s.writable(req.Name) {
+		return 0, t.abort(fmt.Errorf("%q: %w", req.Name, errNotWritable))
+	}
+	first := packet(opACK, 0)
+	if ack := s.negotiate(t, req.Options, -1); ack != nil {
+		first = oackPacket(ack)
+	}
+
+	wc, err := s.WriteHandler.ReceiveTFTP(req)
+	if err != nil {
+		return 0, t.abort(err)
+	}
+
+	// Once commit ran, the file is stored or gone. Otherwise it is
+	// dropped, if the handler can.
+	var committed bool
+	store := func() error {
+		committed = true
+		return wc.Close()
+	}
+	drop := wc.Close
+	if a, ok := wc.(Aborter); ok {
+		drop = a.Abort
+	}
+
+	var w io.Writer = wc
+	commit := store
+	// wait waits for the copy of netascii to be done with wc.
+	wait := func() error { return nil }
+	if req.Mode == "netascii" {
+		pr, pw := io.Pipe()
+		done := make(chan error, 1)
+		go func() {
+			_, err := io.Copy(wc, netascii.NewReader(pr))
+			pr.CloseWithError(err)
+			done <- err
+		}()
+		wait = sync.OnceValue(func() error { return <-done })
+		w = pw
+		commit = func() error {
+			pw.Close()
+			if err := wait(); err != nil {
+				return err
+			}
+			return store()
+		}
+	}
+
+	n, err := t.receive(w, first, commit)
+	if err != nil {
+		if !committed {
+			if pw, ok := w.(*io.PipeWriter); ok {
+				pw.CloseWithError(err)
+			}
+			wait()
+			drop()
+		}
+		return n, err
+	}
+	return n, nil
+}
@@ -0,0 +1,547 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tftpd
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"text/template"
+	"time"
+
+	"pack.ag/tftp"
+)
+
+// start serves s on a local port, and returns its address.
+func start(t *testing.T, s *Server) string {
+	t.Helper()
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Transfers may outlive the test, which must not log then.
+	var (
+		mu   sync.Mutex
+		done bool
+	)
+	s.Logf = func(format string, v ...any) {
+		mu.Lock()
+		defer mu.Unlock()
+		if !done {
+			t.Logf(format, v...)
+		}
+	}
+	go s.Serve(conn)
+	t.Cleanup(func() {
+		s.Close()
+		mu.Lock()
+		defer mu.Unlock()
+		done = true
+	})
+	return conn.LocalAddr().String()
+}
+
+func get(addr, name string, opts ...tftp.ClientOpt) ([]byte, error) {
+	c, err := tftp.NewClient(opts...)
+	if err != nil {
+		return nil, err
+	}
+	r, err := c.Get(fmt.Sprintf("tftp://%s/%s", addr, name))
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+func put(addr, name string, b []byte, opts ...tftp.ClientOpt) error {
+	c, err := tftp.NewClient(opts...)
+	if err != nil {
+		return err
+	}
+	return c.Put(fmt.Sprintf("tftp://%s/%s", addr, name), bytes.NewReader(b), int64(len(b)))
+}
+
+// content returns n bytes that differ from block to block.
+func content(n int) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(i % 251)
+	}
+	return b
+}
+
+func TestRead(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string][]byte{
+		"empty":    nil,
+		"small":    content(100),
+		"block":    content(512),
+		"blocks":   content(3 * 1468),
+		"large":    content(1 << 20),
+		"sub/file": content(2000),
+	}
+	for name, b := range files {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), b, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	addr := start(t, &Server{Handler: Dir(dir)})
+
+	for _, tt := range []struct {
+		name string
+		opts []tftp.ClientOpt
+	}{
+		{name: "tsize", opts: []tftp.ClientOpt{tftp.ClientMode(tftp.ModeOctet)}},
+		{name: "blksize", opts: []tftp.ClientOpt{tftp.ClientMode(tftp.ModeOctet), tftp.ClientBlocksize(1468)}},
+		{name: "blksize beyond the maximum", opts: []tftp.ClientOpt{tftp.ClientMode(tftp.ModeOctet), tftp.ClientBlocksize(9000)}},
+		{name: "windowsize", opts: []tftp.ClientOpt{tftp.ClientMode(tftp.ModeOctet), tftp.ClientBlocksize(1468), tftp.ClientWindowsize(16)}},
+		{name: "windowsize beyond the maximum", opts: []tftp.ClientOpt{tftp.ClientMode(tftp.ModeOctet), tftp.ClientWindowsize(1000)}},
+		{name: "timeout", opts: []tftp.ClientOpt{tftp.ClientMode(tftp.ModeOctet), tftp.ClientTimeout(2)}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, want := range files {
+				got, err := get(addr, name, tt.opts...)
+				if err != nil {
+					t.Fatalf("get(%q) = %v", name, err)
+				}
+				if !bytes.Equal(got, want) {
+					t.Errorf("get(%q) = %d bytes, want %d bytes", name, len(got), len(want))
+				}
+			}
+		})
+	}
+
+	for _, name := range []string{"nothere", "sub", "../" + filepath.Base(dir) + "/small"} {
+		if _, err := get(addr, name, tftp.ClientMode(tftp.ModeOctet)); err == nil {
+			t.Errorf("get(%q) = nil, want an error", name)
+		}
+	}
+}
+
+func TestNetASCII(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "text"), []byte("line 1\nline 2\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	addr := start(t, &Server{Handler: Dir(dir), WriteHandler: Dir(dir), Writable: []string{"*"}})
+
+	got, err := get(addr, "text", tftp.ClientMode(tftp.ModeNetASCII))
+	if err != nil {
+		t.Fatalf("get() = %v", err)
+	}
+	if string(got) != "line 1\nline 2\n" {
+		t.Errorf("get() = %q, want %q", got, "line 1\nline 2\n")
+	}
+
+	if err := put(addr, "up", []byte("a\nb\n"), tftp.ClientMode(tftp.ModeNetASCII)); err != nil {
+		t.Fatalf("put() = %v", err)
+	}
+	if got, err := os.ReadFile(filepath.Join(dir, "up")); err != nil || string(got) != "a\nb\n" {
+		t.Errorf("written file = %q, %v, want %q", got, err, "a\nb\n")
+	}
+}
+
+func TestWrite(t *testing.T) {
+	dir := t.TempDir()
+	addr := start(t, &Server{
+		Handler:      Dir(dir),
+		WriteHandler: Dir(dir),
+		Writable:     []string{"upload/*"},
+	})
+
+	for _, tt := range []struct {
+		name string
+		size int
+		opts []tftp.ClientOpt
+		ok   bool
+	}{
+		{name: "upload/small", size: 100, ok: true},
+		{name: "upload/large", size: 1<<20 + 7, opts: []tftp.ClientOpt{tftp.ClientBlocksize(1468), tftp.ClientWindowsize(16)}, ok: true},
+		{name: "upload/empty", ok: true},
+		{name: "upload/block", size: 512, ok: true},
+		{name: "other", size: 100},
+		{name: "upload/../other", size: 100},
+		{name: "upload/sub/file", size: 100},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			want := content(tt.size)
+			err := put(addr, tt.name, want, append([]tftp.ClientOpt{tftp.ClientMode(tftp.ModeOctet)}, tt.opts...)...)
+			if (err == nil) != tt.ok {
+				t.Fatalf("put() = %v, want success %t", err, tt.ok)
+			}
+			got, rerr := os.ReadFile(filepath.Join(dir, tt.name))
+			if !tt.ok {
+				if rerr == nil {
+					t.Errorf("put() of a denied file wrote it")
+				}
+				return
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("written file = %d bytes, %v, want %d bytes", len(got), rerr, len(want))
+			}
+		})
+	}
+
+	// A server without Writable is read-only.
+	ro := start(t, &Server{Handler: Dir(dir), WriteHandler: Dir(dir)})
+	if err := put(ro, "upload/x", content(10), tftp.ClientMode(tftp.ModeOctet)); err == nil {
+		t.Errorf("put() to a read-only server = nil, want an error")
+	}
+}
+
+func TestTemplate(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "static"), []byte("static"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tmpl := template.Must(template.New("pxe").Parse("{{.Name}} {{.MAC}} {{.Addr.Addr}}"))
+	mux := &Mux{Default: Dir(dir)}
+	if err := mux.Handle("pxelinux.cfg/*", Template(tmpl)); err != nil {
+		t.Fatal(err)
+	}
+	if err := mux.Handle("[", Template(tmpl)); err == nil {
+		t.Errorf("Handle() of a bad pattern = nil, want an error")
+	}
+	addr := start(t, &Server{Handler: mux})
+
+	for name, want := range map[string]string{
+		"pxelinux.cfg/01-aa-bb-cc-dd-ee-ff": "pxelinux.cfg/01-aa-bb-cc-dd-ee-ff aa:bb:cc:dd:ee:ff 127.0.0.1",
+		"pxelinux.cfg/default":              "pxelinux.cfg/default  127.0.0.1",
+		"static":                            "static",
+	} {
+		got, err := get(addr, name, tftp.ClientMode(tftp.ModeOctet))
+		if err != nil {
+			t.Fatalf("get(%q) = %v", name, err)
+		}
+		if string(got) != want {
+			t.Errorf("get(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestMaxTransfers(t *testing.T) {
+	release := make(chan struct{})
+	served := make(chan struct{}, 1)
+	addr := start(t, &Server{
+		Handler: HandlerFunc(func(r *Request) (io.ReadCloser, int64, error) {
+			served <- struct{}{}
+			<-release
+			return io.NopCloser(strings.NewReader("slow")), 4, nil
+		}),
+		MaxTransfers: 1,
+	})
+
+	first := make(chan error, 1)
+	go func() {
+		_, err := get(addr, "first", tftp.ClientMode(tftp.ModeOctet))
+		first <- err
+	}()
+	<-served
+
+	if _, err := get(addr, "second", tftp.ClientMode(tftp.ModeOctet)); err == nil || !strings.Contains(err.Error(), errBusy.Error()) {
+		t.Errorf("get() beyond MaxTransfers = %v, want %v", err, errBusy)
+	}
+	close(release)
+	if err := <-first; err != nil {
+		t.Errorf("get() within MaxTransfers = %v", err)
+	}
+}
+
+// rawClient speaks TFTP packet by packet.
+// TestWriteAborted stops writing a file halfway, which leaves the file as it
+// was.
+func TestWriteAborted(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "pxelinux.cfg")
+	if err := os.WriteFile(name, []byte("default linux\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	addr := start(t, &Server{
+		Handler:      Dir(dir),
+		WriteHandler: Dir(dir),
+		Writable:     []string{"*"},
+		Timeout:      10 * time.Millisecond,
+		Retries:      1,
+	})
+
+	c := dialRaw(t, addr)
+	c.send(append(packet(opWRQ), "pxelinux.cfg\x00octet\x00"...))
+	if b := c.recv(); !bytes.Equal(b, packet(opACK, 0)) {
+		t.Fatalf("reply to WRQ = %q, want ACK 0", b)
+	}
+	c.send(append(packet(opDATA, 1), content(512)...))
+	if b := c.recv(); !bytes.Equal(b, packet(opACK, 1)) {
+		t.Fatalf("reply to DATA 1 = %q, want ACK 1", b)
+	}
+
+	// The server gives up on the silent client, and drops what it got.
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("temporary file left behind: %v", entries)
+		}
+	}
+	if b, err := os.ReadFile(name); err != nil || string(b) != "default linux\n" {
+		t.Errorf("after an aborted write, the file is %q, %v, want it unchanged", b, err)
+	}
+}
+
+type rawClient struct {
+	t    *testing.T
+	conn *net.UDPConn
+	peer *net.UDPAddr
+}
+
+func dialRaw(t *testing.T, addr string) *rawClient {
+	t.Helper()
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	peer, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &rawClient{t: t, conn: conn, peer: peer}
+}
+
+func (c *rawClient) send(b []byte) {
+	c.t.Helper()
+	if _, err := c.conn.WriteToUDP(b, c.peer); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+// recv returns the next packet, and sends the next ones to its port.
+func (c *rawClient) recv() []byte {
+	c.t.Helper()
+	c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	b := make([]byte, 65536)
+	n, addr, err := c.conn.ReadFromUDP(b)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	c.peer = addr
+	return b[:n]
+}
+
+// recvData returns the next packet, which has to be a DATA packet.
+func (c *rawClient) recvData() (uint16, []byte) {
+	c.t.Helper()
+	b := c.recv()
+	if op := binary.BigEndian.Uint16(b); op != opDATA {
+		c.t.Fatalf("packet %q is not DATA", b)
+	}
+	return binary.BigEndian.Uint16(b[2:]), b[4:]
+}
+
+func rrq(name string, options ...string) []byte {
+	b := append(packet(opRRQ), name+"\x00octet\x00"...)
+	for _, o := range options {
+		b = append(b, o+"\x00"...)
+	}
+	return b
+}
+
+// TestNoOptions reads a file in lock step as RFC 1350 has it. The client of
+// pack.ag/tftp mistakes the first DATA of an RRQ without options for an
+// OACK, so this one does it by hand.
+func TestNoOptions(t *testing.T) {
+	want := content(3*512 + 10)
+	addr := start(t, &Server{Handler: HandlerFunc(func(r *Request) (io.ReadCloser, int64, error) {
+		return io.NopCloser(bytes.NewReader(want)), int64(len(want)), nil
+	})})
+
+	c := dialRaw(t, addr)
+	c.send(rrq("f"))
+	var got []byte
+	for next := uint16(1); ; next++ {
+		block, data := c.recvData()
+		if block != next {
+			t.Fatalf("block = %d, want %d", block, next)
+		}
+		got = append(got, data...)
+		c.send(packet(opACK, block))
+		if len(data) < 512 {
+			break
+		}
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("received %d bytes, want %d bytes", len(got), len(want))
+	}
+}
+
+func TestWindowLoss(t *testing.T) {
+	want := content(10 * 8)
+	addr := start(t, &Server{Handler: HandlerFunc(func(r *Request) (io.ReadCloser, int64, error) {
+		return io.NopCloser(bytes.NewReader(want)), int64(len(want)), nil
+	})})
+
+	c := dialRaw(t, addr)
+	c.send(rrq("f", "blksize", "8", "windowsize", "4", "tsize", "0"))
+	wantOACK := oackPacket(map[string]string{"blksize": "8", "windowsize": "4", "tsize": "80"})
+	if b := c.recv(); !bytes.Equal(b, wantOACK) {
+		t.Fatalf("reply to the RRQ = %q, want %q", b, wantOACK)
+	}
+	c.send(packet(opACK, 0))
+
+	var got []byte
+	// Take blocks 1 and 2 of the first window, and lose 3 and 4.
+	for i := uint16(1); i <= 4; i++ {
+		block, data := c.recvData()
+		if block != i {
+			t.Fatalf("block = %d, want %d", block, i)
+		}
+		if i <= 2 {
+			got = append(got, data...)
+		}
+	}
+	c.send(packet(opACK, 2))
+
+	// The next window starts at block 3.
+	for next := uint16(3); ; next++ {
+		block, data := c.recvData()
+		if block != next {
+			t.Fatalf("block = %d, want %d", block, next)
+		}
+		got = append(got, data...)
+		if len(data) < 8 {
+			c.send(packet(opACK, block))
+			break
+		}
+		if (block-2)%4 == 0 {
+			c.send(packet(opACK, block))
+		}
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("received %q, want %q", got, want)
+	}
+}
+
+func TestParseRequest(t *testing.T) {
+	for _, tt := range []struct {
+		pkt     string
+		name    string
+		mode    string
+		options map[string]string
+		err     error
+	}{
+		{pkt: "\x00\x01file\x00octet\x00", name: "file", mode: "octet", options: map[string]string{}},
+		{pkt: "\x00\x01file\x00NetASCII\x00BLKSIZE\x001468\x00tsize\x000\x00", name: "file", mode: "netascii", options: map[string]string{"blksize": "1468", "tsize": "0"}},
+		{pkt: "\x00\x01file\x00octet\x00blksize\x00", name: "file", mode: "octet", options: map[string]string{}},
+		{pkt: "\x00\x01file\x00octet", err: errMalformed},
+		{pkt: "\x00\x01file\x00", err: errMalformed},
+	} {
+		name, mode, options, err := parseRequest([]byte(tt.pkt))
+		if !errors.Is(err, tt.err) {
+			t.Errorf("parseRequest(%q) = %v, want %v", tt.pkt, err, tt.err)
+			continue
+		}
+		if name != tt.name || mode != tt.mode || (err == nil && !maps.Equal(options, tt.options)) {
+			t.Errorf("parseRequest(%q) = %q, %q, %v, want %q, %q, %v", tt.pkt, name, mode, options, tt.name, tt.mode, tt.options)
+		}
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	s := &Server{MaxBlockSize: 1024, MaxWindowSize: 8}
+	for _, tt := range []struct {
+		name    string
+		options map[string]string
+		size    int64
+		ack     map[string]string
+		blksize int
+		window  int
+	}{
+		{name: "none", size: 10, blksize: 512, window: 1},
+		{
+			name:    "within limits",
+			options: map[string]string{"blksize": "1000", "windowsize": "4", "tsize": "0", "timeout": "3"},
+			size:    10,
+			ack:     map[string]string{"blksize": "1000", "windowsize": "4", "tsize": "10", "timeout": "3"},
+			blksize: 1000,
+			window:  4,
+		},
+		{
+			name:    "beyond limits",
+			options: map[string]string{"blksize": "65464", "windowsize": "100"},
+			ack:     map[string]string{"blksize": "1024", "windowsize": "8"},
+			blksize: 1024,
+			window:  8,
+		},
+		{
+			name:    "invalid",
+			options: map[string]string{"blksize": "7", "windowsize": "0", "timeout": "256", "tsize": "x", "unknown": "1"},
+			blksize: 512,
+			window:  1,
+		},
+		{
+			name:    "unknown size",
+			options: map[string]string{"tsize": "0"},
+			size:    -1,
+			blksize: 512,
+			window:  1,
+		},
+		{
+			name:    "size of a write",
+			options: map[string]string{"tsize": "1234"},
+			size:    -1,
+			ack:     map[string]string{"tsize": "1234"},
+			blksize: 512,
+			window:  1,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := &transfer{blksize: defaultBlksize, window: 1}
+			ack := s.negotiate(tr, tt.options, tt.size)
+			if !maps.Equal(ack, tt.ack) {
+				t.Errorf("negotiate() = %v, want %v", ack, tt.ack)
+			}
+			if tr.blksize != tt.blksize || tr.window != tt.window {
+				t.Errorf("blksize, windowsize = %d, %d, want %d, %d", tr.blksize, tr.window, tt.blksize, tt.window)
+			}
+		})
+	}
+}
+
+func TestCleanName(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		want string
+		err  error
+	}{
+		{name: "pxelinux.0", want: "pxelinux.0"},
+		{name: "/boot/vmlinuz", want: "boot/vmlinuz"},
+		{name: `boot\x64\wdsnbp.com`, want: "boot/x64/wdsnbp.com"},
+		{name: "../../etc/passwd", want: "etc/passwd"},
+		{name: "a/./b/../c", want: "a/c"},
+		{name: "/", err: errInvalidName},
+		{name: "", err: errInvalidName},
+	} {
+		got, err := cleanName(tt.name)
+		if got != tt.want || !errors.Is(err, tt.err) {
+			t.Errorf("cleanName(%q) = %q, %v, want %q, %v", tt.name, got, err, tt.want, tt.err)
+		}
+	}
+}
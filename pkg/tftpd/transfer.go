@@ -0,0 +1,273 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tftpd
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"time"
+)
+
+// transfer is the exchange of one file with a client, over a port of its own.
+type transfer struct {
+	conn *net.UDPConn
+	peer *net.UDPAddr
+
+	blksize int
+	window  int
+	timeout time.Duration
+	retries int
+
+	buf []byte
+}
+
+func (t *transfer) write(b []byte) error {
+	_, err := t.conn.WriteToUDP(b, t.peer)
+	return err
+}
+
+// abort sends the ERROR packet of err, and returns err.
+func (t *transfer) abort(err error) error {
+	t.write(errorPacket(err))
+	return err
+}
+
+// recv returns the next DATA or ACK packet of the client until deadline.
+// Packets of other ports get an ERROR, as RFC 1350 wants, without
+// disturbing the transfer.
+func (t *transfer) recv(deadline time.Time) (op uint16, block uint16, data []byte, err error) {
+	if err := t.conn.SetReadDeadline(deadline); err != nil {
+		return 0, 0, nil, err
+	}
+	for {
+		n, addr, err := t.conn.ReadFromUDP(t.buf)
+		if err != nil {
+			return 0, 0, nil, err
+		}
+		if !addr.IP.Equal(t.peer.IP) || addr.Port != t.peer.Port {
+			b := append(packet(opERROR, codeUnknownTID), "unknown transfer ID\x00"...)
+			t.conn.WriteToUDP(b, addr)
+			continue
+		}
+		if n < 4 {
+			continue
+		}
+		op, block = binary.BigEndian.Uint16(t.buf), binary.BigEndian.Uint16(t.buf[2:])
+		switch op {
+		case opERROR:
+			msg, _, _ := bytes.Cut(t.buf[4:n], []byte{0})
+			return 0, 0, nil, &peerError{code: block, msg: string(msg)}
+		case opDATA, opACK:
+			return op, block, t.buf[4:n], nil
+		}
+	}
+}
+
+func isTimeout(err error) bool {
+	return errors.Is(err, os.ErrDeadlineExceeded)
+}
+
+// send sends the contents of r, after the OACK packet oack if there is one,
+// and returns how many bytes it sent. It sends windows of t.window DATA
+// packets between ACKs, as RFC 7440 has it, and starts a window anew after
+// the last block that the client acknowledged.
+func (t *transfer) send(r io.Reader, oack []byte) (int64, error) {
+	if oack != nil {
+		if err := t.sendOACK(oack); err != nil {
+			return 0, err
+		}
+	}
+
+	var (
+		// window holds the unacknowledged blocks after acked.
+		window [][]byte
+		acked  uint16
+		last   bool
+		n      int64
+		tries  int
+	)
+	for {
+		for !last && len(window) < t.window {
+			b := make([]byte, 4+t.blksize)
+			m, err := io.ReadFull(r, b[4:])
+			switch {
+			case err == io.EOF || err == io.ErrUnexpectedEOF:
+				last = true
+			case err != nil:
+				return n, t.abort(err)
+			}
+			binary.BigEndian.PutUint16(b, opDATA)
+			binary.BigEndian.PutUint16(b[2:], acked+uint16(len(window))+1)
+			window = append(window, b[:4+m])
+		}
+		for _, b := range window {
+			if err := t.write(b); err != nil {
+				return n, err
+			}
+		}
+
+		deadline := time.Now().Add(t.timeout)
+	wait:
+		for {
+			op, block, _, err := t.recv(deadline)
+			if isTimeout(err) {
+				if tries++; tries > t.retries {
+					return n, t.abort(errTimeout)
+				}
+				break
+			}
+			if err != nil {
+				return n, err
+			}
+			if op != opACK {
+				continue
+			}
+			switch k := block - acked; {
+			case k == 0 && t.window > 1:
+				// The client missed a block of the window, and
+				// acknowledges the one before it.
+				break wait
+			case k == 0 || int(k) > len(window):
+				// A duplicate, which must not double the
+				// packets on the way (the Sorcerer's
+				// Apprentice bug), or a stale ACK.
+				continue
+			default:
+				for _, b := range window[:k] {
+					n += int64(len(b) - 4)
+				}
+				window, acked, tries = window[k:], block, 0
+				if last && len(window) == 0 {
+					return n, nil
+				}
+				break wait
+			}
+		}
+	}
+}
+
+// sendOACK sends oack until the client acknowledges it with block 0.
+func (t *transfer) sendOACK(oack []byte) error {
+	for tries := 0; ; tries++ {
+		if tries > t.retries {
+			return t.abort(errTimeout)
+		}
+		if err := t.write(oack); err != nil {
+			return err
+		}
+		deadline := time.Now().Add(t.timeout)
+		for {
+			op, block, _, err := t.recv(deadline)
+			if isTimeout(err) {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			if op == opACK && block == 0 {
+				return nil
+			}
+		}
+	}
+}
+
+// receive writes the DATA of the client to w, after sending first, which is
+// the OACK or ACK of the request, and returns how many bytes it received. It
+// acknowledges every t.window blocks, and the last in-order block as soon as
+// a block goes missing. The final block is acknowledged only once commit
+// stored the file.
+func (t *transfer) receive(w io.Writer, first []byte, commit func() error) (int64, error) {
+	var (
+		reply    = first
+		block    uint16
+		n        int64
+		received int
+		tries    int
+		// gap is whether the client was told of a missing block
+		// already.
+		gap bool
+	)
+	if err := t.write(reply); err != nil {
+		return 0, err
+	}
+	deadline := time.Now().Add(t.timeout)
+	for {
+		op, b, data, err := t.recv(deadline)
+		if isTimeout(err) {
+			if tries++; tries > t.retries {
+				return n, t.abort(errTimeout)
+			}
+			if n > 0 || block > 0 {
+				reply = packet(opACK, block)
+			}
+			received = 0
+			if err := t.write(reply); err != nil {
+				return n, err
+			}
+			deadline = time.Now().Add(t.timeout)
+			continue
+		}
+		if err != nil {
+			return n, err
+		}
+		if op != opDATA {
+			continue
+		}
+		if b != block+1 {
+			if !gap {
+				gap, received = true, 0
+				reply = packet(opACK, block)
+				if err := t.write(reply); err != nil {
+					return n, err
+				}
+			}
+			continue
+		}
+
+		if _, err := w.Write(data); err != nil {
+			return n, t.abort(err)
+		}
+		block, n, received, tries, gap = b, n+int64(len(data)), received+1, 0, false
+		deadline = time.Now().Add(t.timeout)
+
+		if len(data) < t.blksize {
+			if err := commit(); err != nil {
+				return n, t.abort(err)
+			}
+			reply = packet(opACK, block)
+			if err := t.write(reply); err != nil {
+				return n, err
+			}
+			t.dally(block, reply)
+			return n, nil
+		}
+		if received == t.window {
+			received = 0
+			reply = packet(opACK, block)
+			if err := t.write(reply); err != nil {
+				return n, err
+			}
+		}
+	}
+}
+
+// dally acknowledges the last block again if the client sends it again, in
+// case the final ACK got lost.
+func (t *transfer) dally(block uint16, ack []byte) {
+	deadline := time.Now().Add(t.timeout)
+	for {
+		op, b, _, err := t.recv(deadline)
+		if err != nil {
+			return
+		}
+		if op == opDATA && b == block {
+			t.write(ack)
+		}
+	}
+}
@@ -35,6 +35,39 @@ func ArgsToGoArgs(args []string) []string {
 	return out
 }
 
+// ArgsWithValuesToGoArgs is ArgsToGoArgs for commands with single-letter
+// switches that take a value, listed in values. As with getopt, the value is
+// the rest of the argument or the next argument, and switches after it are
+// still converted, so scp -P2222 -rp turns into scp -P 2222 -r -p.
+// A -- argument stops the process and is kept.
+func ArgsWithValuesToGoArgs(args []string, values string) []string {
+	var out []string
+	for i := 0; i < len(args); i++ {
+		f := args[i]
+		switch {
+		case f == "--" || f == "-" || !strings.HasPrefix(f, "-"):
+			return append(out, args[i:]...)
+		case strings.HasPrefix(f, "--"):
+			out = append(out, f[1:])
+			continue
+		}
+		for j, c := range f[1:] {
+			out = append(out, "-"+string(c))
+			if !strings.ContainsRune(values, c) {
+				continue
+			}
+			if v := f[2+j:]; v != "" {
+				out = append(out, v)
+			} else if i+1 < len(args) {
+				i++
+				out = append(out, args[i])
+			}
+			break
+		}
+	}
+	return out
+}
+
 // OSArgsToGoArgs converts os.Args to Unix-style args.
 // The first argument, i.e. the executable name, is removed.
 // ArgsToGoArgs is called with the rest of the args
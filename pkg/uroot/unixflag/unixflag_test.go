@@ -35,6 +35,30 @@ func TestArgsToGoArgs(t *testing.T) {
 	}
 }
 
+func TestArgsWithValuesToGoArgs(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		args []string
+		out  []string
+	}{
+		{name: "no args", args: []string{}, out: nil},
+		{name: "-rp", args: []string{"-rp", "a", "b"}, out: []string{"-r", "-p", "a", "b"}},
+		{name: "-P 22 -rp", args: []string{"-P", "22", "-rp", "a"}, out: []string{"-P", "22", "-r", "-p", "a"}},
+		{name: "-rP22 -oUser=root", args: []string{"-rP22", "-oUser=root", "a"}, out: []string{"-r", "-P", "22", "-o", "User=root", "a"}},
+		{name: "-P-1", args: []string{"-P", "-1"}, out: []string{"-P", "-1"}},
+		{name: "--verbose", args: []string{"--verbose", "-r"}, out: []string{"-verbose", "-r"}},
+		{name: "--", args: []string{"-r", "--", "-p"}, out: []string{"-r", "--", "-p"}},
+		{name: "-P at the end", args: []string{"-rP"}, out: []string{"-r", "-P"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			out := unixflag.ArgsWithValuesToGoArgs(tt.args, "Po")
+			if !slices.Equal(out, tt.out) {
+				t.Fatalf("%v: got %v, want %v", tt.args, out, tt.out)
+			}
+		})
+	}
+}
+
 func TestOSArgsToGoArgs(t *testing.T) {
 	// because this test has to set os.Args, it is racy, so either only
 	// do one case or don't run the tests concurrently.
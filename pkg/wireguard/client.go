@@ -0,0 +1,62 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package wireguard
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// userspaceNames returns the names of the userspace interfaces with an API
+// socket.
+func userspaceNames() []string {
+	socks, _ := filepath.Glob(filepath.Join(SocketDir, "*.sock"))
+	var names []string
+	for _, s := range socks {
+		names = append(names, strings.TrimSuffix(filepath.Base(s), ".sock"))
+	}
+	return names
+}
+
+// isUserspace reports whether name is a userspace interface.
+func isUserspace(name string) bool {
+	_, err := os.Stat(socketPath(name))
+	return err == nil
+}
+
+// Devices returns the names of the WireGuard interfaces, of the kernel
+// and userspace.
+func Devices() ([]string, error) {
+	names, err := kernelDevices()
+	if err != nil {
+		return nil, err
+	}
+	names = append(names, userspaceNames()...)
+	slices.Sort(names)
+	return slices.Compact(names), nil
+}
+
+// DeviceByName returns the state of the interface name.
+func DeviceByName(name string) (*Device, error) {
+	if isUserspace(name) {
+		d, err := uapiGet(socketPath(name))
+		if err != nil {
+			return nil, err
+		}
+		d.Name = name
+		return d, nil
+	}
+	return kernelDevice(name)
+}
+
+// Configure changes the interface name as c says.
+func Configure(name string, c *Config) error {
+	if isUserspace(name) {
+		return uapiSet(socketPath(name), c)
+	}
+	return kernelConfigure(name, c)
+}
@@ -0,0 +1,223 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package wireguard
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseConfig parses a configuration file of wg(8):
+//
+//	[Interface]
+//	PrivateKey = yAnz5TF+lXXJte14tji3zlMNq+hd2rYUIgJBgB3fBmk=
+//	ListenPort = 51820
+//
+//	[Peer]
+//	PublicKey = xTIBA5rboUvnH4htodjb6e697QjLERt1NAB4mZqp8Dg=
+//	Endpoint = 192.95.5.67:1234
+//	AllowedIPs = 10.192.122.3/32, 10.192.124.1/24
+//	PersistentKeepalive = 25
+//
+// The configuration replaces all peers, and the allowed IPs of each.
+func ParseConfig(r io.Reader) (*Config, error) {
+	c := &Config{ReplacePeers: true}
+	var (
+		section string
+		peer    *PeerConfig
+	)
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		line, _, _ := strings.Cut(s.Text(), "#")
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			section = strings.ToLower(line)
+			switch section {
+			case "[interface]":
+			case "[peer]":
+				c.Peers = append(c.Peers, PeerConfig{ReplaceAllowedIPs: true})
+				peer = &c.Peers[len(c.Peers)-1]
+			default:
+				return nil, fmt.Errorf("line %d: unknown section %s", n, line)
+			}
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: %q is not KEY = VALUE", n, line)
+		}
+		key, value = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value)
+		var err error
+		switch {
+		case section == "[interface]" && key == "privatekey":
+			var k Key
+			if k, err = ParseKey(value); err == nil {
+				c.PrivateKey = &k
+			}
+		case section == "[interface]" && key == "listenport":
+			var port int
+			if port, err = ParsePort(value); err == nil {
+				c.ListenPort = &port
+			}
+		case section == "[interface]" && key == "fwmark":
+			var mark int
+			if mark, err = ParseFirewallMark(value); err == nil {
+				c.FirewallMark = &mark
+			}
+		case section == "[peer]" && key == "publickey":
+			peer.PublicKey, err = ParseKey(value)
+		case section == "[peer]" && key == "presharedkey":
+			var k Key
+			if k, err = ParseKey(value); err == nil {
+				peer.PresharedKey = &k
+			}
+		case section == "[peer]" && key == "allowedips":
+			var ips []netip.Prefix
+			if ips, err = ParseAllowedIPs(value); err == nil {
+				peer.AllowedIPs = append(peer.AllowedIPs, ips...)
+			}
+		case section == "[peer]" && key == "endpoint":
+			var ep netip.AddrPort
+			if ep, err = ParseEndpoint(value); err == nil {
+				peer.Endpoint = &ep
+			}
+		case section == "[peer]" && key == "persistentkeepalive":
+			var d time.Duration
+			if d, err = ParseKeepalive(value); err == nil {
+				peer.PersistentKeepaliveInterval = &d
+			}
+		default:
+			return nil, fmt.Errorf("line %d: unknown key %q", n, key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	for _, p := range c.Peers {
+		if p.PublicKey.IsZero() {
+			return nil, fmt.Errorf("peer without a public key")
+		}
+	}
+	return c, nil
+}
+
+// WriteConfig writes the configuration of d in the format of ParseConfig.
+func WriteConfig(w io.Writer, d *Device) error {
+	b := bufio.NewWriter(w)
+	fmt.Fprintf(b, "[Interface]\n")
+	if d.ListenPort != 0 {
+		fmt.Fprintf(b, "ListenPort = %d\n", d.ListenPort)
+	}
+	if d.FirewallMark != 0 {
+		fmt.Fprintf(b, "FwMark = 0x%x\n", d.FirewallMark)
+	}
+	if !d.PrivateKey.IsZero() {
+		fmt.Fprintf(b, "PrivateKey = %s\n", d.PrivateKey)
+	}
+	for _, p := range d.Peers {
+		fmt.Fprintf(b, "\n[Peer]\nPublicKey = %s\n", p.PublicKey)
+		if !p.PresharedKey.IsZero() {
+			fmt.Fprintf(b, "PresharedKey = %s\n", p.PresharedKey)
+		}
+		if len(p.AllowedIPs) > 0 {
+			fmt.Fprintf(b, "AllowedIPs = %s\n", joinPrefixes(p.AllowedIPs, ", "))
+		}
+		if p.Endpoint.IsValid() {
+			fmt.Fprintf(b, "Endpoint = %s\n", p.Endpoint)
+		}
+		if p.PersistentKeepaliveInterval != 0 {
+			fmt.Fprintf(b, "PersistentKeepalive = %d\n", int(p.PersistentKeepaliveInterval/time.Second))
+		}
+	}
+	return b.Flush()
+}
+
+// ParsePort parses a listen port.
+func ParsePort(s string) (int, error) {
+	p, err := strconv.ParseUint(s, 10, 16)
+	if err != nil {
+		return 0, fmt.Errorf("invalid port %q", s)
+	}
+	return int(p), nil
+}
+
+// ParseFirewallMark parses a firewall mark in decimal or hex, or off.
+func ParseFirewallMark(s string) (int, error) {
+	if s == "off" {
+		return 0, nil
+	}
+	m, err := strconv.ParseUint(s, 0, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid fwmark %q", s)
+	}
+	return int(m), nil
+}
+
+// ParseKeepalive parses a persistent keepalive interval in seconds, or off.
+func ParseKeepalive(s string) (time.Duration, error) {
+	if s == "off" {
+		return 0, nil
+	}
+	n, err := strconv.ParseUint(s, 10, 16)
+	if err != nil {
+		return 0, fmt.Errorf("invalid persistent keepalive interval %q", s)
+	}
+	return time.Duration(n) * time.Second, nil
+}
+
+// ParseAllowedIPs parses a comma-separated list of prefixes. Addresses
+// stand for their host prefixes.
+func ParseAllowedIPs(s string) ([]netip.Prefix, error) {
+	var ips []netip.Prefix
+	for f := range strings.SplitSeq(s, ",") {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			continue
+		}
+		p, err := netip.ParsePrefix(f)
+		if err != nil {
+			a, aerr := netip.ParseAddr(f)
+			if aerr != nil {
+				return nil, fmt.Errorf("invalid allowed IP %q", f)
+			}
+			p = netip.PrefixFrom(a, a.BitLen())
+		}
+		ips = append(ips, p.Masked())
+	}
+	return ips, nil
+}
+
+// ParseEndpoint parses HOST:PORT, looking up HOST if it is a name.
+func ParseEndpoint(s string) (netip.AddrPort, error) {
+	if ap, err := netip.ParseAddrPort(s); err == nil {
+		return ap, nil
+	}
+	a, err := net.ResolveUDPAddr("udp", s)
+	if err != nil {
+		return netip.AddrPort{}, fmt.Errorf("invalid endpoint %q: %w", s, err)
+	}
+	ap := a.AddrPort()
+	return netip.AddrPortFrom(ap.Addr().Unmap(), ap.Port()), nil
+}
+
+func joinPrefixes(ps []netip.Prefix, sep string) string {
+	s := make([]string, len(ps))
+	for i, p := range ps {
+		s[i] = p.String()
+	}
+	return strings.Join(s, sep)
+}
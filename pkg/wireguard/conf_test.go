@@ -0,0 +1,125 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package wireguard
+
+import (
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+const (
+	testPrivateKey = "yAnz5TF+lXXJte14tji3zlMNq+hd2rYUIgJBgB3fBmk="
+	testPublicKey  = "xTIBA5rboUvnH4htodjb6e697QjLERt1NAB4mZqp8Dg="
+	testPSK        = "/UwcSPg38hW/D9Y3tcS1FOV0K1wuURMbS0sesJEP5ak="
+)
+
+// cmpNetip compares the types of net/netip.
+var cmpNetip = cmpopts.EquateComparable(netip.Addr{}, netip.AddrPort{}, netip.Prefix{})
+
+func mustKey(t *testing.T, s string) Key {
+	t.Helper()
+	k, err := ParseKey(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+func TestParseConfig(t *testing.T) {
+	conf := `# A comment.
+[Interface]
+PrivateKey = ` + testPrivateKey + `
+ListenPort = 51820
+FwMark = 0x10
+
+[Peer]
+PublicKey = ` + testPublicKey + `
+PresharedKey = ` + testPSK + `
+Endpoint = 192.95.5.67:1234
+AllowedIPs = 10.192.122.3/32, 10.192.124.1/24
+AllowedIPs = fd00::1
+PersistentKeepalive = 25
+`
+	c, err := ParseConfig(strings.NewReader(conf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	priv, pub, psk := mustKey(t, testPrivateKey), mustKey(t, testPublicKey), mustKey(t, testPSK)
+	port, mark := 51820, 0x10
+	ep := netip.MustParseAddrPort("192.95.5.67:1234")
+	keepalive := 25 * time.Second
+	want := &Config{
+		PrivateKey:   &priv,
+		ListenPort:   &port,
+		FirewallMark: &mark,
+		ReplacePeers: true,
+		Peers: []PeerConfig{{
+			PublicKey:                   pub,
+			PresharedKey:                &psk,
+			Endpoint:                    &ep,
+			PersistentKeepaliveInterval: &keepalive,
+			ReplaceAllowedIPs:           true,
+			AllowedIPs: []netip.Prefix{
+				netip.MustParsePrefix("10.192.122.3/32"),
+				netip.MustParsePrefix("10.192.124.0/24"),
+				netip.MustParsePrefix("fd00::1/128"),
+			},
+		}},
+	}
+	if diff := cmp.Diff(want, c, cmpNetip); diff != "" {
+		t.Errorf("ParseConfig() mismatch (-want +got):\n%s", diff)
+	}
+
+	for _, bad := range []string{
+		"[Interface]\nListenPort = 70000\n",
+		"[Peer]\nAllowedIPs = 10.0.0.1/32\n",
+		"[Peer]\nPublicKey = " + testPublicKey + "\nAllowedIPs = 10.0.0.300\n",
+		"[Interface]\nPublicKey = " + testPublicKey + "\n",
+		"[Wat]\n",
+		"PrivateKey\n",
+	} {
+		if _, err := ParseConfig(strings.NewReader(bad)); err == nil {
+			t.Errorf("ParseConfig(%q) = nil error", bad)
+		}
+	}
+}
+
+func TestWriteConfig(t *testing.T) {
+	d := &Device{
+		PrivateKey: mustKey(t, testPrivateKey),
+		ListenPort: 51820,
+		Peers: []Peer{{
+			PublicKey:                   mustKey(t, testPublicKey),
+			Endpoint:                    netip.MustParseAddrPort("[2001:db8::1]:51820"),
+			AllowedIPs:                  []netip.Prefix{netip.MustParsePrefix("10.0.0.2/32"), netip.MustParsePrefix("fd00::/64")},
+			PersistentKeepaliveInterval: 25 * time.Second,
+		}},
+	}
+	var b strings.Builder
+	if err := WriteConfig(&b, d); err != nil {
+		t.Fatal(err)
+	}
+	want := `[Interface]
+ListenPort = 51820
+PrivateKey = ` + testPrivateKey + `
+
+[Peer]
+PublicKey = ` + testPublicKey + `
+AllowedIPs = 10.0.0.2/32, fd00::/64
+Endpoint = [2001:db8::1]:51820
+PersistentKeepalive = 25
+`
+	if diff := cmp.Diff(want, b.String()); diff != "" {
+		t.Errorf("WriteConfig() mismatch (-want +got):\n%s", diff)
+	}
+	if _, err := ParseConfig(strings.NewReader(b.String())); err != nil {
+		t.Errorf("ParseConfig(WriteConfig()) = %v", err)
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package wireguard
+
+import (
+	"crypto/cipher"
+	"encoding/binary"
+	"time"
+
+	"golang.org/x/crypto/blake2s"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// Protocol limits of the WireGuard whitepaper, section 6.1.
+const (
+	rekeyAfterMessages  = 1 << 60
+	rejectAfterMessages = 1<<64 - 1<<13 - 1
+	rekeyAfterTime      = 120 * time.Second
+	rejectAfterTime     = 180 * time.Second
+	rekeyAttemptTime    = 90 * time.Second
+	rekeyTimeout        = 5 * time.Second
+	keepaliveTimeout    = 10 * time.Second
+	cookieLifetime      = 120 * time.Second
+)
+
+// keypair is the session that a handshake established.
+type keypair struct {
+	send, recv  cipher.AEAD
+	sendNonce   uint64
+	replay      replayFilter
+	created     time.Time
+	initiator   bool
+	localIndex  uint32
+	remoteIndex uint32
+}
+
+func newKeypair(send, recv [blake2s.Size]byte, initiator bool, local, remote uint32, now time.Time) *keypair {
+	s, _ := chacha20poly1305.New(send[:])
+	r, _ := chacha20poly1305.New(recv[:])
+	return &keypair{send: s, recv: r, created: now, initiator: initiator, localIndex: local, remoteIndex: remote}
+}
+
+// expired reports whether the keypair can no longer be used.
+func (kp *keypair) expired(now time.Time) bool {
+	return now.Sub(kp.created) >= rejectAfterTime || kp.sendNonce >= rejectAfterMessages
+}
+
+// seal returns a data message with the padded packet.
+func (kp *keypair) seal(packet []byte) []byte {
+	msg := make([]byte, dataHeaderSize, dataHeaderSize+len(packet)+tagSize)
+	msg[0] = msgData
+	binary.LittleEndian.PutUint32(msg[4:], kp.remoteIndex)
+	binary.LittleEndian.PutUint64(msg[8:], kp.sendNonce)
+	var nonce [chacha20poly1305.NonceSize]byte
+	copy(nonce[4:], msg[8:16])
+	kp.sendNonce++
+	return kp.send.Seal(msg, nonce[:], packet, nil)
+}
+
+// open decrypts a data message, rejecting replays.
+func (kp *keypair) open(msg []byte) ([]byte, bool) {
+	counter := binary.LittleEndian.Uint64(msg[8:])
+	var nonce [chacha20poly1305.NonceSize]byte
+	copy(nonce[4:], msg[8:16])
+	packet, err := kp.recv.Open(nil, nonce[:], msg[dataHeaderSize:], nil)
+	if err != nil || !kp.replay.check(counter) {
+		return nil, false
+	}
+	return packet, true
+}
+
+// replayFilter is the sliding window of RFC 6479 over received counters.
+type replayFilter struct {
+	last   uint64
+	blocks [replayBlocks]uint64
+}
+
+const (
+	replayBlocks = 32
+	replayWindow = (replayBlocks - 1) * 64
+)
+
+// check reports whether counter is new, and records it.
+func (f *replayFilter) check(counter uint64) bool {
+	if counter >= rejectAfterMessages {
+		return false
+	}
+	index := counter / 64
+	if counter > f.last {
+		cur := f.last / 64
+		for i := range min(index-cur, replayBlocks) {
+			f.blocks[(cur+i+1)%replayBlocks] = 0
+		}
+		f.last = counter
+	} else if f.last-counter > replayWindow {
+		return false
+	}
+	bit := uint64(1) << (counter % 64)
+	b := &f.blocks[index%replayBlocks]
+	if *b&bit != 0 {
+		return false
+	}
+	*b |= bit
+	return true
+}
@@ -0,0 +1,35 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package wireguard
+
+import "testing"
+
+func TestReplayFilter(t *testing.T) {
+	var f replayFilter
+	for _, tt := range []struct {
+		counter uint64
+		want    bool
+	}{
+		{0, true},
+		{0, false},
+		{1, true},
+		{5, true},
+		{3, true},
+		{3, false},
+		{5, false},
+		{5000, true},
+		{5000 - replayWindow, true},
+		{5000 - replayWindow - 1, false},
+		{4999, true},
+		{6, false},
+		{rejectAfterMessages, false},
+		{100000, true},
+		{5001, false},
+	} {
+		if got := f.check(tt.counter); got != tt.want {
+			t.Errorf("check(%d) = %t, want %t", tt.counter, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,295 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package wireguard
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net/netip"
+	"syscall"
+	"time"
+
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netlink/nl"
+	"golang.org/x/sys/unix"
+)
+
+// The generic netlink family of the kernel, from linux/wireguard.h.
+const (
+	genlName    = "wireguard"
+	genlVersion = 1
+)
+
+// genlFamily returns the ID of the WireGuard family.
+func genlFamily() (uint16, error) {
+	f, err := netlink.GenlFamilyGet(genlName)
+	if err != nil {
+		return 0, fmt.Errorf("kernel has no WireGuard: %w", err)
+	}
+	return f.ID, nil
+}
+
+// kernelDevices returns the names of the WireGuard interfaces of the
+// kernel.
+func kernelDevices() ([]string, error) {
+	links, err := netlink.LinkList()
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, l := range links {
+		if l.Type() == "wireguard" {
+			names = append(names, l.Attrs().Name)
+		}
+	}
+	return names, nil
+}
+
+// kernelDevice returns the state of the kernel interface name.
+func kernelDevice(name string) (*Device, error) {
+	family, err := genlFamily()
+	if err != nil {
+		return nil, err
+	}
+	req := nl.NewNetlinkRequest(int(family), unix.NLM_F_DUMP)
+	req.AddData(&nl.Genlmsg{Command: unix.WG_CMD_GET_DEVICE, Version: genlVersion})
+	req.AddData(nl.NewRtAttr(unix.WGDEVICE_A_IFNAME, nl.ZeroTerminated(name)))
+	msgs, err := req.Execute(unix.NETLINK_GENERIC, family)
+	if err != nil {
+		return nil, err
+	}
+	return parseDevice(msgs)
+}
+
+// kernelConfigure configures the kernel interface name.
+func kernelConfigure(name string, c *Config) error {
+	family, err := genlFamily()
+	if err != nil {
+		return err
+	}
+	req := nl.NewNetlinkRequest(int(family), unix.NLM_F_ACK)
+	req.AddData(&nl.Genlmsg{Command: unix.WG_CMD_SET_DEVICE, Version: genlVersion})
+	for _, a := range configAttrs(name, c) {
+		req.AddData(a)
+	}
+	_, err = req.Execute(unix.NETLINK_GENERIC, 0)
+	return err
+}
+
+// configAttrs returns the attributes of WG_CMD_SET_DEVICE for c.
+func configAttrs(name string, c *Config) []*nl.RtAttr {
+	attrs := []*nl.RtAttr{nl.NewRtAttr(unix.WGDEVICE_A_IFNAME, nl.ZeroTerminated(name))}
+	if c.PrivateKey != nil {
+		attrs = append(attrs, nl.NewRtAttr(unix.WGDEVICE_A_PRIVATE_KEY, c.PrivateKey[:]))
+	}
+	if c.ListenPort != nil {
+		attrs = append(attrs, nl.NewRtAttr(unix.WGDEVICE_A_LISTEN_PORT, nl.Uint16Attr(uint16(*c.ListenPort))))
+	}
+	if c.FirewallMark != nil {
+		attrs = append(attrs, nl.NewRtAttr(unix.WGDEVICE_A_FWMARK, nl.Uint32Attr(uint32(*c.FirewallMark))))
+	}
+	if c.ReplacePeers {
+		attrs = append(attrs, nl.NewRtAttr(unix.WGDEVICE_A_FLAGS, nl.Uint32Attr(unix.WGDEVICE_F_REPLACE_PEERS)))
+	}
+	if len(c.Peers) == 0 {
+		return attrs
+	}
+	peers := nl.NewRtAttr(unix.WGDEVICE_A_PEERS|unix.NLA_F_NESTED, nil)
+	for i, p := range c.Peers {
+		a := peers.AddRtAttr(i|unix.NLA_F_NESTED, nil)
+		a.AddRtAttr(unix.WGPEER_A_PUBLIC_KEY, p.PublicKey[:])
+		var flags uint32
+		if p.Remove {
+			flags |= unix.WGPEER_F_REMOVE_ME
+		}
+		if p.UpdateOnly {
+			flags |= unix.WGPEER_F_UPDATE_ONLY
+		}
+		if p.ReplaceAllowedIPs {
+			flags |= unix.WGPEER_F_REPLACE_ALLOWEDIPS
+		}
+		if flags != 0 {
+			a.AddRtAttr(unix.WGPEER_A_FLAGS, nl.Uint32Attr(flags))
+		}
+		if p.PresharedKey != nil {
+			a.AddRtAttr(unix.WGPEER_A_PRESHARED_KEY, p.PresharedKey[:])
+		}
+		if p.Endpoint != nil {
+			a.AddRtAttr(unix.WGPEER_A_ENDPOINT, sockaddr(*p.Endpoint))
+		}
+		if p.PersistentKeepaliveInterval != nil {
+			a.AddRtAttr(unix.WGPEER_A_PERSISTENT_KEEPALIVE_INTERVAL, nl.Uint16Attr(uint16(*p.PersistentKeepaliveInterval/time.Second)))
+		}
+		if len(p.AllowedIPs) > 0 {
+			ips := a.AddRtAttr(unix.WGPEER_A_ALLOWEDIPS|unix.NLA_F_NESTED, nil)
+			for j, ip := range p.AllowedIPs {
+				family := uint16(unix.AF_INET)
+				if ip.Addr().Is6() {
+					family = unix.AF_INET6
+				}
+				ipa := ips.AddRtAttr(j|unix.NLA_F_NESTED, nil)
+				ipa.AddRtAttr(unix.WGALLOWEDIP_A_FAMILY, nl.Uint16Attr(family))
+				ipa.AddRtAttr(unix.WGALLOWEDIP_A_IPADDR, ip.Addr().AsSlice())
+				ipa.AddRtAttr(unix.WGALLOWEDIP_A_CIDR_MASK, nl.Uint8Attr(uint8(ip.Bits())))
+			}
+		}
+	}
+	return append(attrs, peers)
+}
+
+// sockaddr returns ep as a struct sockaddr_in or sockaddr_in6.
+func sockaddr(ep netip.AddrPort) []byte {
+	a := ep.Addr().Unmap()
+	if a.Is4() {
+		b := make([]byte, unix.SizeofSockaddrInet4)
+		binary.NativeEndian.PutUint16(b, unix.AF_INET)
+		binary.BigEndian.PutUint16(b[2:], ep.Port())
+		copy(b[4:], a.AsSlice())
+		return b
+	}
+	b := make([]byte, unix.SizeofSockaddrInet6)
+	binary.NativeEndian.PutUint16(b, unix.AF_INET6)
+	binary.BigEndian.PutUint16(b[2:], ep.Port())
+	copy(b[8:], a.AsSlice())
+	return b
+}
+
+// parseSockaddr parses a struct sockaddr_in or sockaddr_in6.
+func parseSockaddr(b []byte) (netip.AddrPort, error) {
+	if len(b) < 4 {
+		return netip.AddrPort{}, fmt.Errorf("invalid endpoint")
+	}
+	port := binary.BigEndian.Uint16(b[2:])
+	switch binary.NativeEndian.Uint16(b) {
+	case unix.AF_INET:
+		if len(b) >= 8 {
+			return netip.AddrPortFrom(netip.AddrFrom4([4]byte(b[4:8])), port), nil
+		}
+	case unix.AF_INET6:
+		if len(b) >= 24 {
+			return netip.AddrPortFrom(netip.AddrFrom16([16]byte(b[8:24])), port), nil
+		}
+	}
+	return netip.AddrPort{}, fmt.Errorf("invalid endpoint")
+}
+
+// attrType returns the type of a, without flags.
+func attrType(a syscall.NetlinkRouteAttr) uint16 {
+	return a.Attr.Type & nl.NLA_TYPE_MASK
+}
+
+// parseDevice parses the replies to WG_CMD_GET_DEVICE. The kernel splits
+// large devices across messages, and a peer's allowed IPs too.
+func parseDevice(msgs [][]byte) (*Device, error) {
+	d := &Device{Type: "kernel"}
+	for _, m := range msgs {
+		if len(m) < nl.SizeofGenlmsg {
+			return nil, fmt.Errorf("short message")
+		}
+		attrs, err := nl.ParseRouteAttr(m[nl.SizeofGenlmsg:])
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range attrs {
+			switch attrType(a) {
+			case unix.WGDEVICE_A_IFNAME:
+				d.Name = nl.BytesToString(a.Value)
+			case unix.WGDEVICE_A_PRIVATE_KEY:
+				d.PrivateKey = Key(a.Value)
+			case unix.WGDEVICE_A_PUBLIC_KEY:
+				d.PublicKey = Key(a.Value)
+			case unix.WGDEVICE_A_LISTEN_PORT:
+				d.ListenPort = int(binary.NativeEndian.Uint16(a.Value))
+			case unix.WGDEVICE_A_FWMARK:
+				d.FirewallMark = int(binary.NativeEndian.Uint32(a.Value))
+			case unix.WGDEVICE_A_PEERS:
+				if err := d.parsePeers(a.Value); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return d, nil
+}
+
+func (d *Device) parsePeers(b []byte) error {
+	peers, err := nl.ParseRouteAttr(b)
+	if err != nil {
+		return err
+	}
+	for _, pa := range peers {
+		attrs, err := nl.ParseRouteAttr(pa.Value)
+		if err != nil {
+			return err
+		}
+		var p Peer
+		for _, a := range attrs {
+			switch attrType(a) {
+			case unix.WGPEER_A_PUBLIC_KEY:
+				p.PublicKey = Key(a.Value)
+			case unix.WGPEER_A_PRESHARED_KEY:
+				p.PresharedKey = Key(a.Value)
+			case unix.WGPEER_A_ENDPOINT:
+				if p.Endpoint, err = parseSockaddr(a.Value); err != nil {
+					return err
+				}
+			case unix.WGPEER_A_PERSISTENT_KEEPALIVE_INTERVAL:
+				p.PersistentKeepaliveInterval = time.Duration(binary.NativeEndian.Uint16(a.Value)) * time.Second
+			case unix.WGPEER_A_LAST_HANDSHAKE_TIME:
+				sec := int64(binary.NativeEndian.Uint64(a.Value))
+				nsec := int64(binary.NativeEndian.Uint64(a.Value[8:]))
+				if sec != 0 || nsec != 0 {
+					p.LastHandshakeTime = time.Unix(sec, nsec)
+				}
+			case unix.WGPEER_A_RX_BYTES:
+				p.ReceiveBytes = int64(binary.NativeEndian.Uint64(a.Value))
+			case unix.WGPEER_A_TX_BYTES:
+				p.TransmitBytes = int64(binary.NativeEndian.Uint64(a.Value))
+			case unix.WGPEER_A_PROTOCOL_VERSION:
+				p.ProtocolVersion = int(binary.NativeEndian.Uint32(a.Value))
+			case unix.WGPEER_A_ALLOWEDIPS:
+				if p.AllowedIPs, err = parseAllowedIPs(a.Value); err != nil {
+					return err
+				}
+			}
+		}
+		if n := len(d.Peers); n > 0 && d.Peers[n-1].PublicKey == p.PublicKey {
+			d.Peers[n-1].AllowedIPs = append(d.Peers[n-1].AllowedIPs, p.AllowedIPs...)
+			continue
+		}
+		d.Peers = append(d.Peers, p)
+	}
+	return nil
+}
+
+func parseAllowedIPs(b []byte) ([]netip.Prefix, error) {
+	list, err := nl.ParseRouteAttr(b)
+	if err != nil {
+		return nil, err
+	}
+	var ips []netip.Prefix
+	for _, ipa := range list {
+		attrs, err := nl.ParseRouteAttr(ipa.Value)
+		if err != nil {
+			return nil, err
+		}
+		var (
+			addr netip.Addr
+			bits int
+		)
+		for _, a := range attrs {
+			switch attrType(a) {
+			case unix.WGALLOWEDIP_A_IPADDR:
+				addr, _ = netip.AddrFromSlice(a.Value)
+			case unix.WGALLOWEDIP_A_CIDR_MASK:
+				bits = int(a.Value[0])
+			}
+		}
+		if p := netip.PrefixFrom(addr, bits); p.IsValid() {
+			ips = append(ips, p)
+		}
+	}
+	return ips, nil
+}
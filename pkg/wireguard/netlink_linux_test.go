@@ -0,0 +1,130 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package wireguard
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/vishvananda/netlink/nl"
+	"golang.org/x/sys/unix"
+)
+
+// genlMessage returns a generic netlink message with attrs, as Execute
+// returns them.
+func genlMessage(attrs ...*nl.RtAttr) []byte {
+	b := (&nl.Genlmsg{Command: unix.WG_CMD_GET_DEVICE, Version: genlVersion}).Serialize()
+	for _, a := range attrs {
+		b = append(b, a.Serialize()...)
+	}
+	return b
+}
+
+func TestConfigAttrs(t *testing.T) {
+	priv, pub, psk := mustKey(t, testPrivateKey), mustKey(t, testPublicKey), mustKey(t, testPSK)
+	port, mark := 51820, 7
+	ep4 := netip.MustParseAddrPort("192.0.2.1:51820")
+	ep6 := netip.MustParseAddrPort("[2001:db8::1]:1234")
+	keepalive := 25 * time.Second
+	c := &Config{
+		PrivateKey:   &priv,
+		ListenPort:   &port,
+		FirewallMark: &mark,
+		ReplacePeers: true,
+		Peers: []PeerConfig{
+			{
+				PublicKey:                   pub,
+				PresharedKey:                &psk,
+				Endpoint:                    &ep4,
+				PersistentKeepaliveInterval: &keepalive,
+				ReplaceAllowedIPs:           true,
+				AllowedIPs:                  []netip.Prefix{netip.MustParsePrefix("10.0.0.2/32"), netip.MustParsePrefix("fd00::/64")},
+			},
+			{PublicKey: psk, Endpoint: &ep6},
+		},
+	}
+	d, err := parseDevice([][]byte{genlMessage(configAttrs("wg0", c)...)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Device{
+		Name:         "wg0",
+		Type:         "kernel",
+		PrivateKey:   priv,
+		ListenPort:   port,
+		FirewallMark: mark,
+		Peers: []Peer{
+			{
+				PublicKey:                   pub,
+				PresharedKey:                psk,
+				Endpoint:                    ep4,
+				PersistentKeepaliveInterval: keepalive,
+				AllowedIPs:                  c.Peers[0].AllowedIPs,
+			},
+			{PublicKey: psk, Endpoint: ep6},
+		},
+	}
+	if diff := cmp.Diff(want, d, cmpNetip); diff != "" {
+		t.Errorf("parseDevice(configAttrs()) mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestParseDeviceSplit(t *testing.T) {
+	pub := mustKey(t, testPublicKey)
+	handshake := time.Unix(1700000000, 5)
+	ts := make([]byte, 16)
+	nl.NativeEndian().PutUint64(ts, uint64(handshake.Unix()))
+	nl.NativeEndian().PutUint64(ts[8:], uint64(handshake.Nanosecond()))
+
+	peer := func(prefix string, first bool) *nl.RtAttr {
+		peers := nl.NewRtAttr(unix.WGDEVICE_A_PEERS|unix.NLA_F_NESTED, nil)
+		p := peers.AddRtAttr(unix.NLA_F_NESTED, nil)
+		p.AddRtAttr(unix.WGPEER_A_PUBLIC_KEY, pub[:])
+		if first {
+			p.AddRtAttr(unix.WGPEER_A_LAST_HANDSHAKE_TIME, ts)
+			p.AddRtAttr(unix.WGPEER_A_RX_BYTES, nl.Uint64Attr(100))
+			p.AddRtAttr(unix.WGPEER_A_TX_BYTES, nl.Uint64Attr(200))
+			p.AddRtAttr(unix.WGPEER_A_PROTOCOL_VERSION, nl.Uint32Attr(1))
+		}
+		ips := p.AddRtAttr(unix.WGPEER_A_ALLOWEDIPS|unix.NLA_F_NESTED, nil)
+		ip := netip.MustParsePrefix(prefix)
+		a := ips.AddRtAttr(unix.NLA_F_NESTED, nil)
+		a.AddRtAttr(unix.WGALLOWEDIP_A_FAMILY, nl.Uint16Attr(unix.AF_INET))
+		a.AddRtAttr(unix.WGALLOWEDIP_A_IPADDR, ip.Addr().AsSlice())
+		a.AddRtAttr(unix.WGALLOWEDIP_A_CIDR_MASK, nl.Uint8Attr(uint8(ip.Bits())))
+		return peers
+	}
+	msgs := [][]byte{
+		genlMessage(
+			nl.NewRtAttr(unix.WGDEVICE_A_IFNAME, nl.ZeroTerminated("wg0")),
+			nl.NewRtAttr(unix.WGDEVICE_A_LISTEN_PORT, nl.Uint16Attr(51820)),
+			peer("10.0.0.0/24", true),
+		),
+		// The kernel continues a peer with its public key.
+		genlMessage(peer("10.0.1.0/24", false)),
+	}
+	d, err := parseDevice(msgs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Device{
+		Name:       "wg0",
+		Type:       "kernel",
+		ListenPort: 51820,
+		Peers: []Peer{{
+			PublicKey:         pub,
+			LastHandshakeTime: handshake,
+			ReceiveBytes:      100,
+			TransmitBytes:     200,
+			ProtocolVersion:   1,
+			AllowedIPs:        []netip.Prefix{netip.MustParsePrefix("10.0.0.0/24"), netip.MustParsePrefix("10.0.1.0/24")},
+		}},
+	}
+	if diff := cmp.Diff(want, d, cmpNetip); diff != "" {
+		t.Errorf("parseDevice() mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,345 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package wireguard
+
+import (
+	"crypto/hmac"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"time"
+
+	"golang.org/x/crypto/blake2s"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/curve25519"
+)
+
+// The Noise_IKpsk2 handshake of the WireGuard whitepaper, section 5.4.
+const (
+	construction = "Noise_IKpsk2_25519_ChaChaPoly_BLAKE2s"
+	identifier   = "WireGuard v1 zx2c4 Jason@zx2c4.com"
+	labelMAC1    = "mac1----"
+	labelCookie  = "cookie--"
+)
+
+// Message types and sizes.
+const (
+	msgInitiation = 1
+	msgResponse   = 2
+	msgCookie     = 3
+	msgData       = 4
+
+	initiationSize = 148
+	responseSize   = 92
+	cookieSize     = 64
+	dataHeaderSize = 16
+	tagSize        = chacha20poly1305.Overhead
+)
+
+var initialChainKey, initialHash [blake2s.Size]byte
+
+func init() {
+	initialChainKey = blake2s.Sum256([]byte(construction))
+	initialHash = mixHash(initialChainKey, []byte(identifier))
+}
+
+var errHandshake = errors.New("invalid handshake message")
+
+func newBlake2s() hash.Hash {
+	h, _ := blake2s.New256(nil)
+	return h
+}
+
+// mixHash returns HASH(h || data).
+func mixHash(h [blake2s.Size]byte, data []byte) [blake2s.Size]byte {
+	d := newBlake2s()
+	d.Write(h[:])
+	d.Write(data)
+	var out [blake2s.Size]byte
+	d.Sum(out[:0])
+	return out
+}
+
+func hmacBlake2s(key, data []byte) [blake2s.Size]byte {
+	m := hmac.New(newBlake2s, key)
+	m.Write(data)
+	var out [blake2s.Size]byte
+	m.Sum(out[:0])
+	return out
+}
+
+// kdf returns n keys derived from the chaining key ck and input.
+func kdf(ck [blake2s.Size]byte, input []byte, n int) [][blake2s.Size]byte {
+	t0 := hmacBlake2s(ck[:], input)
+	out := make([][blake2s.Size]byte, n)
+	prev := []byte{}
+	for i := range n {
+		out[i] = hmacBlake2s(t0[:], append(prev, byte(i+1)))
+		prev = out[i][:]
+	}
+	return out
+}
+
+// mac returns the keyed 16-byte BLAKE2s of data.
+func mac(key, data []byte) [16]byte {
+	h, _ := blake2s.New128(key)
+	h.Write(data)
+	var out [16]byte
+	h.Sum(out[:0])
+	return out
+}
+
+func dh(priv, pub Key) ([]byte, error) {
+	return curve25519.X25519(priv[:], pub[:])
+}
+
+// seal encrypts with a zero counter, as handshake messages do.
+func seal(key [blake2s.Size]byte, plaintext, ad []byte) []byte {
+	aead, _ := chacha20poly1305.New(key[:])
+	return aead.Seal(nil, make([]byte, chacha20poly1305.NonceSize), plaintext, ad)
+}
+
+func open(key [blake2s.Size]byte, ciphertext, ad []byte) ([]byte, error) {
+	aead, _ := chacha20poly1305.New(key[:])
+	return aead.Open(nil, make([]byte, chacha20poly1305.NonceSize), ciphertext, ad)
+}
+
+// tai64n returns t as a TAI64N label.
+func tai64n(t time.Time) [12]byte {
+	var b [12]byte
+	binary.BigEndian.PutUint64(b[:], 0x400000000000000a+uint64(t.Unix()))
+	binary.BigEndian.PutUint32(b[8:], uint32(t.Nanosecond()))
+	return b
+}
+
+// handshake is the state of a handshake with a peer.
+type handshake struct {
+	// initiated is set while our initiation awaits a response.
+	initiated  bool
+	chainKey   [blake2s.Size]byte
+	hash       [blake2s.Size]byte
+	ephemeral  Key
+	localIndex uint32
+
+	// lastTimestamp is of the last initiation of the peer, which
+	// replayed initiations cannot exceed.
+	lastTimestamp [12]byte
+
+	// lastMAC1 is that of our last message, which cookies are bound to.
+	lastMAC1 [16]byte
+	cookie   [16]byte
+	cookieAt time.Time
+}
+
+// macs fills in the MACs at the end of msg, for a peer with public key pub.
+func (hs *handshake) macs(msg []byte, pub Key, now time.Time) {
+	n := len(msg)
+	m1 := mac(macKey(labelMAC1, pub), msg[:n-32])
+	copy(msg[n-32:], m1[:])
+	hs.lastMAC1 = m1
+	clear(msg[n-16:])
+	if !hs.cookieAt.IsZero() && now.Sub(hs.cookieAt) < cookieLifetime {
+		m2 := mac(hs.cookie[:], msg[:n-16])
+		copy(msg[n-16:], m2[:])
+	}
+}
+
+// macKey returns HASH(label || pub).
+func macKey(label string, pub Key) []byte {
+	k := blake2s.Sum256(append([]byte(label), pub[:]...))
+	return k[:]
+}
+
+// checkMAC1 checks the MAC of a handshake message to us.
+func checkMAC1(msg []byte, pub Key) bool {
+	n := len(msg)
+	m1 := mac(macKey(labelMAC1, pub), msg[:n-32])
+	return subtle.ConstantTimeCompare(m1[:], msg[n-32:n-16]) == 1
+}
+
+// createInitiation returns an initiation from the static key priv to the
+// peer pub.
+func (hs *handshake) createInitiation(priv, pub Key, index uint32, now time.Time) ([]byte, error) {
+	eph, err := GeneratePrivateKey()
+	if err != nil {
+		return nil, err
+	}
+	msg := make([]byte, initiationSize)
+	msg[0] = msgInitiation
+	binary.LittleEndian.PutUint32(msg[4:], index)
+
+	ck := initialChainKey
+	h := mixHash(initialHash, pub[:])
+	e := eph.PublicKey()
+	copy(msg[8:40], e[:])
+	ck = kdf(ck, e[:], 1)[0]
+	h = mixHash(h, e[:])
+
+	es, err := dh(eph, pub)
+	if err != nil {
+		return nil, err
+	}
+	k := kdf(ck, es, 2)
+	ck = k[0]
+	static := priv.PublicKey()
+	copy(msg[40:88], seal(k[1], static[:], h[:]))
+	h = mixHash(h, msg[40:88])
+
+	ss, err := dh(priv, pub)
+	if err != nil {
+		return nil, err
+	}
+	k = kdf(ck, ss, 2)
+	ck = k[0]
+	ts := tai64n(now)
+	copy(msg[88:116], seal(k[1], ts[:], h[:]))
+	h = mixHash(h, msg[88:116])
+
+	hs.macs(msg, pub, now)
+	hs.initiated = true
+	hs.chainKey, hs.hash, hs.ephemeral, hs.localIndex = ck, h, eph, index
+	return msg, nil
+}
+
+// initiation is an initiation that consumeInitiation decrypted.
+type initiation struct {
+	sender    uint32
+	static    Key
+	ephemeral Key
+	timestamp [12]byte
+	chainKey  [blake2s.Size]byte
+	hash      [blake2s.Size]byte
+}
+
+// consumeInitiation decrypts an initiation to the static key priv.
+func consumeInitiation(msg []byte, priv Key) (*initiation, error) {
+	if len(msg) != initiationSize || !checkMAC1(msg, priv.PublicKey()) {
+		return nil, errHandshake
+	}
+	in := &initiation{sender: binary.LittleEndian.Uint32(msg[4:])}
+	pub := priv.PublicKey()
+	ck := initialChainKey
+	h := mixHash(initialHash, pub[:])
+	copy(in.ephemeral[:], msg[8:40])
+	ck = kdf(ck, in.ephemeral[:], 1)[0]
+	h = mixHash(h, in.ephemeral[:])
+
+	es, err := dh(priv, in.ephemeral)
+	if err != nil {
+		return nil, errHandshake
+	}
+	k := kdf(ck, es, 2)
+	ck = k[0]
+	static, err := open(k[1], msg[40:88], h[:])
+	if err != nil {
+		return nil, errHandshake
+	}
+	copy(in.static[:], static)
+	h = mixHash(h, msg[40:88])
+
+	ss, err := dh(priv, in.static)
+	if err != nil {
+		return nil, errHandshake
+	}
+	k = kdf(ck, ss, 2)
+	ck = k[0]
+	ts, err := open(k[1], msg[88:116], h[:])
+	if err != nil {
+		return nil, errHandshake
+	}
+	copy(in.timestamp[:], ts)
+	in.hash = mixHash(h, msg[88:116])
+	in.chainKey = ck
+	return in, nil
+}
+
+// createResponse returns the response to in, with psk the preshared key,
+// and the keys to send and receive with.
+func (hs *handshake) createResponse(in *initiation, psk Key, index uint32, now time.Time) (msg []byte, send, recv [blake2s.Size]byte, err error) {
+	eph, err := GeneratePrivateKey()
+	if err != nil {
+		return nil, send, recv, err
+	}
+	msg = make([]byte, responseSize)
+	msg[0] = msgResponse
+	binary.LittleEndian.PutUint32(msg[4:], index)
+	binary.LittleEndian.PutUint32(msg[8:], in.sender)
+
+	ck, h := in.chainKey, in.hash
+	e := eph.PublicKey()
+	copy(msg[12:44], e[:])
+	ck = kdf(ck, e[:], 1)[0]
+	h = mixHash(h, e[:])
+	ee, err := dh(eph, in.ephemeral)
+	if err != nil {
+		return nil, send, recv, err
+	}
+	ck = kdf(ck, ee, 1)[0]
+	se, err := dh(eph, in.static)
+	if err != nil {
+		return nil, send, recv, err
+	}
+	ck = kdf(ck, se, 1)[0]
+	k := kdf(ck, psk[:], 3)
+	ck = k[0]
+	h = mixHash(h, k[1][:])
+	copy(msg[44:60], seal(k[2], nil, h[:]))
+
+	hs.macs(msg, in.static, now)
+	k = kdf(ck, nil, 2)
+	return msg, k[1], k[0], nil
+}
+
+// consumeResponse completes our initiation with its response, returning
+// the keys to send and receive with.
+func (hs *handshake) consumeResponse(msg []byte, priv, psk Key) (send, recv [blake2s.Size]byte, err error) {
+	if !hs.initiated || len(msg) != responseSize || !checkMAC1(msg, priv.PublicKey()) {
+		return send, recv, errHandshake
+	}
+	ck, h := hs.chainKey, hs.hash
+	var e Key
+	copy(e[:], msg[12:44])
+	ck = kdf(ck, e[:], 1)[0]
+	h = mixHash(h, e[:])
+	ee, err := dh(hs.ephemeral, e)
+	if err != nil {
+		return send, recv, errHandshake
+	}
+	ck = kdf(ck, ee, 1)[0]
+	se, err := dh(priv, e)
+	if err != nil {
+		return send, recv, errHandshake
+	}
+	ck = kdf(ck, se, 1)[0]
+	k := kdf(ck, psk[:], 3)
+	ck = k[0]
+	h = mixHash(h, k[1][:])
+	if _, err := open(k[2], msg[44:60], h[:]); err != nil {
+		return send, recv, errHandshake
+	}
+	hs.initiated = false
+	hs.ephemeral = Key{}
+	k = kdf(ck, nil, 2)
+	return k[0], k[1], nil
+}
+
+// consumeCookie decrypts a cookie reply from the peer pub.
+func (hs *handshake) consumeCookie(msg []byte, pub Key, now time.Time) error {
+	if len(msg) != cookieSize {
+		return errHandshake
+	}
+	aead, err := chacha20poly1305.NewX(macKey(labelCookie, pub))
+	if err != nil {
+		return err
+	}
+	cookie, err := aead.Open(nil, msg[8:32], msg[32:64], hs.lastMAC1[:])
+	if err != nil {
+		return errHandshake
+	}
+	copy(hs.cookie[:], cookie)
+	hs.cookieAt = now
+	return nil
+}
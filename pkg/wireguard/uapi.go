@@ -0,0 +1,351 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package wireguard
+
+import (
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// SocketDir is where userspace interfaces have their API sockets, named
+// after the interface with a .sock suffix.
+var SocketDir = "/var/run/wireguard"
+
+// socketPath returns the API socket of the userspace interface name.
+func socketPath(name string) string {
+	return filepath.Join(SocketDir, name+".sock")
+}
+
+// errInvalid is what the userspace API reports for invalid requests.
+var errInvalid = syscall.EINVAL
+
+// uapiGet gets the state of an interface over the userspace API at path.
+func uapiGet(path string) (*Device, error) {
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if _, err := io.WriteString(conn, "get=1\n\n"); err != nil {
+		return nil, err
+	}
+	return parseUAPIDevice(bufio.NewReader(conn))
+}
+
+// uapiSet configures an interface over the userspace API at path.
+func uapiSet(path string, c *Config) error {
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	w := bufio.NewWriter(conn)
+	fmt.Fprintf(w, "set=1\n")
+	writeUAPIConfig(w, c)
+	fmt.Fprintf(w, "\n")
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return readErrno(bufio.NewReader(conn))
+}
+
+// uapiLines calls f with the keys and values of the lines of r up to a
+// blank line.
+func uapiLines(r *bufio.Reader, f func(key, value string) error) error {
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return err
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			return nil
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return fmt.Errorf("invalid line %q", line)
+		}
+		if err := f(key, value); err != nil {
+			return err
+		}
+	}
+}
+
+// readErrno reads the response to a set.
+func readErrno(r *bufio.Reader) error {
+	var errno int
+	err := uapiLines(r, func(key, value string) error {
+		if key == "errno" {
+			n, err := strconv.Atoi(value)
+			errno = n
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if errno != 0 {
+		return syscall.Errno(max(errno, -errno))
+	}
+	return nil
+}
+
+// parseUAPIDevice parses the response to a get.
+func parseUAPIDevice(r *bufio.Reader) (*Device, error) {
+	d := &Device{Type: "userspace"}
+	var (
+		p     *Peer
+		errno int
+	)
+	err := uapiLines(r, func(key, value string) error {
+		var err error
+		switch key {
+		case "private_key":
+			if d.PrivateKey, err = parseHexKey(value); err == nil && !d.PrivateKey.IsZero() {
+				d.PublicKey = d.PrivateKey.PublicKey()
+			}
+		case "listen_port":
+			d.ListenPort, err = strconv.Atoi(value)
+		case "fwmark":
+			d.FirewallMark, err = strconv.Atoi(value)
+		case "public_key":
+			d.Peers = append(d.Peers, Peer{})
+			p = &d.Peers[len(d.Peers)-1]
+			p.PublicKey, err = parseHexKey(value)
+		case "errno":
+			errno, err = strconv.Atoi(value)
+		default:
+			if p == nil {
+				return fmt.Errorf("%s before public_key", key)
+			}
+			err = p.set(key, value)
+		}
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", key, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if errno != 0 {
+		return nil, syscall.Errno(max(errno, -errno))
+	}
+	return d, nil
+}
+
+// set sets a field of p from the response to a get.
+func (p *Peer) set(key, value string) error {
+	var (
+		n   int64
+		err error
+	)
+	switch key {
+	case "preshared_key":
+		p.PresharedKey, err = parseHexKey(value)
+	case "endpoint":
+		p.Endpoint, err = netip.ParseAddrPort(value)
+	case "allowed_ip":
+		var ip netip.Prefix
+		if ip, err = netip.ParsePrefix(value); err == nil {
+			p.AllowedIPs = append(p.AllowedIPs, ip)
+		}
+	case "last_handshake_time_sec":
+		if n, err = strconv.ParseInt(value, 10, 64); err == nil && n != 0 {
+			p.LastHandshakeTime = time.Unix(n, int64(p.LastHandshakeTime.Nanosecond()))
+		}
+	case "last_handshake_time_nsec":
+		if n, err = strconv.ParseInt(value, 10, 64); err == nil && !p.LastHandshakeTime.IsZero() {
+			p.LastHandshakeTime = time.Unix(p.LastHandshakeTime.Unix(), n)
+		}
+	case "rx_bytes":
+		p.ReceiveBytes, err = strconv.ParseInt(value, 10, 64)
+	case "tx_bytes":
+		p.TransmitBytes, err = strconv.ParseInt(value, 10, 64)
+	case "persistent_keepalive_interval":
+		if n, err = strconv.ParseInt(value, 10, 16); err == nil {
+			p.PersistentKeepaliveInterval = time.Duration(n) * time.Second
+		}
+	case "protocol_version":
+		p.ProtocolVersion, err = strconv.Atoi(value)
+	}
+	return err
+}
+
+// writeUAPIDevice writes d as the response to a get, without the errno.
+func writeUAPIDevice(w io.Writer, d *Device) {
+	if !d.PrivateKey.IsZero() {
+		fmt.Fprintf(w, "private_key=%s\n", hex.EncodeToString(d.PrivateKey[:]))
+	}
+	fmt.Fprintf(w, "listen_port=%d\n", d.ListenPort)
+	if d.FirewallMark != 0 {
+		fmt.Fprintf(w, "fwmark=%d\n", d.FirewallMark)
+	}
+	for _, p := range d.Peers {
+		fmt.Fprintf(w, "public_key=%s\n", hex.EncodeToString(p.PublicKey[:]))
+		if !p.PresharedKey.IsZero() {
+			fmt.Fprintf(w, "preshared_key=%s\n", hex.EncodeToString(p.PresharedKey[:]))
+		}
+		fmt.Fprintf(w, "protocol_version=%d\n", p.ProtocolVersion)
+		if p.Endpoint.IsValid() {
+			fmt.Fprintf(w, "endpoint=%s\n", p.Endpoint)
+		}
+		var sec, nsec int64
+		if !p.LastHandshakeTime.IsZero() {
+			sec, nsec = p.LastHandshakeTime.Unix(), int64(p.LastHandshakeTime.Nanosecond())
+		}
+		fmt.Fprintf(w, "last_handshake_time_sec=%d\nlast_handshake_time_nsec=%d\n", sec, nsec)
+		fmt.Fprintf(w, "tx_bytes=%d\nrx_bytes=%d\n", p.TransmitBytes, p.ReceiveBytes)
+		fmt.Fprintf(w, "persistent_keepalive_interval=%d\n", int(p.PersistentKeepaliveInterval/time.Second))
+		for _, ip := range p.AllowedIPs {
+			fmt.Fprintf(w, "allowed_ip=%s\n", ip)
+		}
+	}
+}
+
+// writeUAPIConfig writes c as the body of a set.
+func writeUAPIConfig(w io.Writer, c *Config) {
+	if c.PrivateKey != nil {
+		fmt.Fprintf(w, "private_key=%s\n", hex.EncodeToString(c.PrivateKey[:]))
+	}
+	if c.ListenPort != nil {
+		fmt.Fprintf(w, "listen_port=%d\n", *c.ListenPort)
+	}
+	if c.FirewallMark != nil {
+		fmt.Fprintf(w, "fwmark=%d\n", *c.FirewallMark)
+	}
+	if c.ReplacePeers {
+		fmt.Fprintf(w, "replace_peers=true\n")
+	}
+	for _, p := range c.Peers {
+		fmt.Fprintf(w, "public_key=%s\n", hex.EncodeToString(p.PublicKey[:]))
+		if p.Remove {
+			fmt.Fprintf(w, "remove=true\n")
+			continue
+		}
+		if p.UpdateOnly {
+			fmt.Fprintf(w, "update_only=true\n")
+		}
+		if p.PresharedKey != nil {
+			fmt.Fprintf(w, "preshared_key=%s\n", hex.EncodeToString(p.PresharedKey[:]))
+		}
+		if p.Endpoint != nil {
+			fmt.Fprintf(w, "endpoint=%s\n", *p.Endpoint)
+		}
+		if p.PersistentKeepaliveInterval != nil {
+			fmt.Fprintf(w, "persistent_keepalive_interval=%d\n", int(*p.PersistentKeepaliveInterval/time.Second))
+		}
+		if p.ReplaceAllowedIPs {
+			fmt.Fprintf(w, "replace_allowed_ips=true\n")
+		}
+		for _, ip := range p.AllowedIPs {
+			fmt.Fprintf(w, "allowed_ip=%s\n", ip)
+		}
+	}
+}
+
+// parseUAPIConfig parses the body of a set.
+func parseUAPIConfig(r *bufio.Reader) (*Config, error) {
+	c := &Config{}
+	var p *PeerConfig
+	err := uapiLines(r, func(key, value string) error {
+		var err error
+		switch key {
+		case "private_key":
+			var k Key
+			if k, err = parseHexKey(value); err == nil {
+				c.PrivateKey = &k
+			}
+		case "listen_port":
+			var port int
+			if port, err = ParsePort(value); err == nil {
+				c.ListenPort = &port
+			}
+		case "fwmark":
+			var mark int
+			if mark, err = ParseFirewallMark(value); err == nil {
+				c.FirewallMark = &mark
+			}
+		case "replace_peers":
+			c.ReplacePeers, err = parseTrue(value)
+		case "public_key":
+			c.Peers = append(c.Peers, PeerConfig{})
+			p = &c.Peers[len(c.Peers)-1]
+			p.PublicKey, err = parseHexKey(value)
+		default:
+			if p == nil {
+				return fmt.Errorf("%s before public_key", key)
+			}
+			err = p.set(key, value)
+		}
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", key, err)
+		}
+		return nil
+	})
+	return c, err
+}
+
+// set sets a field of p from the body of a set.
+func (p *PeerConfig) set(key, value string) error {
+	var err error
+	switch key {
+	case "remove":
+		p.Remove, err = parseTrue(value)
+	case "update_only":
+		p.UpdateOnly, err = parseTrue(value)
+	case "preshared_key":
+		var k Key
+		if k, err = parseHexKey(value); err == nil {
+			p.PresharedKey = &k
+		}
+	case "endpoint":
+		var ep netip.AddrPort
+		if ep, err = netip.ParseAddrPort(value); err == nil {
+			p.Endpoint = &ep
+		}
+	case "persistent_keepalive_interval":
+		var d time.Duration
+		if d, err = ParseKeepalive(value); err == nil {
+			p.PersistentKeepaliveInterval = &d
+		}
+	case "replace_allowed_ips":
+		p.ReplaceAllowedIPs, err = parseTrue(value)
+	case "allowed_ip":
+		var ip netip.Prefix
+		if ip, err = netip.ParsePrefix(value); err == nil {
+			p.AllowedIPs = append(p.AllowedIPs, ip.Masked())
+		}
+	case "protocol_version":
+		if value != "1" {
+			err = errors.New("unsupported protocol version")
+		}
+	default:
+		err = errors.New("unknown key")
+	}
+	return err
+}
+
+// parseTrue parses a flag, which can only be true.
+func parseTrue(s string) (bool, error) {
+	if s != "true" {
+		return false, fmt.Errorf("%q is not true", s)
+	}
+	return true, nil
+}
@@ -0,0 +1,100 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package wireguard
+
+import (
+	"bufio"
+	"errors"
+	"net/netip"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestUAPIConfig(t *testing.T) {
+	priv, pub, psk := mustKey(t, testPrivateKey), mustKey(t, testPublicKey), mustKey(t, testPSK)
+	port, mark := 51820, 7
+	ep := netip.MustParseAddrPort("[2001:db8::1]:1234")
+	keepalive := 25 * time.Second
+	c := &Config{
+		PrivateKey:   &priv,
+		ListenPort:   &port,
+		FirewallMark: &mark,
+		ReplacePeers: true,
+		Peers: []PeerConfig{
+			{
+				PublicKey:                   pub,
+				UpdateOnly:                  true,
+				PresharedKey:                &psk,
+				Endpoint:                    &ep,
+				PersistentKeepaliveInterval: &keepalive,
+				ReplaceAllowedIPs:           true,
+				AllowedIPs:                  []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")},
+			},
+			{PublicKey: psk, Remove: true},
+		},
+	}
+	var b strings.Builder
+	writeUAPIConfig(&b, c)
+	b.WriteString("\n")
+	got, err := parseUAPIConfig(bufio.NewReader(strings.NewReader(b.String())))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(c, got, cmpNetip); diff != "" {
+		t.Errorf("parseUAPIConfig(writeUAPIConfig()) mismatch (-want +got):\n%s", diff)
+	}
+
+	for _, bad := range []string{
+		"listen_port=1\nendpoint=1.2.3.4:5\n\n",
+		"public_key=zz\n\n",
+		"replace_peers=false\n\n",
+		"private_key=" + strings.Repeat("00", 32) + "\n",
+	} {
+		if _, err := parseUAPIConfig(bufio.NewReader(strings.NewReader(bad))); err == nil {
+			t.Errorf("parseUAPIConfig(%q) = nil error", bad)
+		}
+	}
+}
+
+func TestUAPIDevice(t *testing.T) {
+	priv, pub, psk := mustKey(t, testPrivateKey), mustKey(t, testPublicKey), mustKey(t, testPSK)
+	d := &Device{
+		Type:         "userspace",
+		PrivateKey:   priv,
+		PublicKey:    priv.PublicKey(),
+		ListenPort:   51820,
+		FirewallMark: 7,
+		Peers: []Peer{{
+			PublicKey:                   pub,
+			PresharedKey:                psk,
+			Endpoint:                    netip.MustParseAddrPort("192.0.2.1:51820"),
+			PersistentKeepaliveInterval: 25 * time.Second,
+			LastHandshakeTime:           time.Unix(1700000000, 5),
+			ReceiveBytes:                100,
+			TransmitBytes:               200,
+			AllowedIPs:                  []netip.Prefix{netip.MustParsePrefix("10.0.0.2/32"), netip.MustParsePrefix("fd00::/64")},
+			ProtocolVersion:             1,
+		}},
+	}
+	var b strings.Builder
+	writeUAPIDevice(&b, d)
+	b.WriteString("errno=0\n\n")
+	got, err := parseUAPIDevice(bufio.NewReader(strings.NewReader(b.String())))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(d, got, cmpNetip); diff != "" {
+		t.Errorf("parseUAPIDevice(writeUAPIDevice()) mismatch (-want +got):\n%s", diff)
+	}
+
+	_, err = parseUAPIDevice(bufio.NewReader(strings.NewReader("errno=-22\n\n")))
+	if !errors.Is(err, syscall.EINVAL) {
+		t.Errorf("parseUAPIDevice(errno=-22) = %v, want %v", err, syscall.EINVAL)
+	}
+}
@@ -0,0 +1,34 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !linux
+
+package wireguard
+
+import (
+	"errors"
+	"net"
+	"os"
+)
+
+func kernelDevices() ([]string, error) {
+	return nil, nil
+}
+
+func kernelDevice(string) (*Device, error) {
+	return nil, errors.ErrUnsupported
+}
+
+func kernelConfigure(string, *Config) error {
+	return errors.ErrUnsupported
+}
+
+// OpenTUN is not supported.
+func OpenTUN(string) (*os.File, error) {
+	return nil, errors.ErrUnsupported
+}
+
+func setMark(*net.UDPConn, int) error {
+	return errors.ErrUnsupported
+}
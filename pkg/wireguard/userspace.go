@@ -0,0 +1,672 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package wireguard
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/netip"
+	"slices"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// DefaultMTU is the MTU of WireGuard interfaces, which leaves room for the
+// headers of IPv6, UDP and WireGuard in an Ethernet frame.
+const DefaultMTU = 1420
+
+const (
+	// tickInterval is how often timers are checked.
+	tickInterval = 250 * time.Millisecond
+
+	// maxQueue is how many packets wait for a handshake per peer.
+	maxQueue = 128
+)
+
+// Userspace is a WireGuard interface implemented in userspace. It moves
+// packets between a TUN device and UDP. It does not send cookie replies,
+// as it does not consider itself under load, but honors those it receives.
+type Userspace struct {
+	// Logf logs handshakes. It defaults to log.Printf.
+	Logf func(format string, v ...any)
+
+	name string
+	tun  io.ReadWriteCloser
+	mtu  int
+
+	// now is replaced in tests.
+	now func() time.Time
+
+	mu         sync.Mutex
+	running    bool
+	privateKey Key
+	listenPort int
+	fwmark     int
+	conn       *net.UDPConn
+	peers      map[Key]*peer
+	indices    map[uint32]*peer
+}
+
+type peer struct {
+	pub        Key
+	psk        Key
+	endpoint   netip.AddrPort
+	keepalive  time.Duration
+	allowedIPs []netip.Prefix
+
+	hs        handshake
+	hsStarted time.Time
+	hsSent    time.Time
+
+	// next is a keypair we responded with, until the initiator uses it.
+	current, previous, next *keypair
+	queue                   [][]byte
+
+	lastSent         time.Time
+	lastReceived     time.Time
+	lastDataReceived time.Time
+	// dataSent is when data was sent that awaits a reply.
+	dataSent      time.Time
+	lastHandshake time.Time
+	rx, tx        int64
+}
+
+// NewUserspace returns an interface called name that reads and writes IP
+// packets on tun, and listens on a random UDP port until configured.
+func NewUserspace(name string, tun io.ReadWriteCloser, mtu int) (*Userspace, error) {
+	u := &Userspace{
+		Logf:    log.Printf,
+		name:    name,
+		tun:     tun,
+		mtu:     mtu,
+		now:     time.Now,
+		peers:   map[Key]*peer{},
+		indices: map[uint32]*peer{},
+	}
+	if err := u.listen(0); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// listen binds the UDP socket to port.
+func (u *Userspace) listen(port int) error {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{Port: port})
+	if err != nil {
+		return err
+	}
+	if u.fwmark != 0 {
+		if err := setMark(conn, u.fwmark); err != nil {
+			conn.Close()
+			return err
+		}
+	}
+	if u.conn != nil {
+		u.conn.Close()
+	}
+	u.conn = conn
+	u.listenPort = conn.LocalAddr().(*net.UDPAddr).Port
+	if u.running {
+		go u.readUDP(conn)
+	}
+	return nil
+}
+
+// Run moves packets until ctx is done or the TUN device fails, as it does
+// when its interface is deleted.
+func (u *Userspace) Run(ctx context.Context) error {
+	u.mu.Lock()
+	u.running = true
+	conn := u.conn
+	u.mu.Unlock()
+	go u.readUDP(conn)
+
+	errc := make(chan error, 1)
+	go func() { errc <- u.readTUN() }()
+
+	t := time.NewTicker(tickInterval)
+	defer t.Stop()
+	defer u.close()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-errc:
+			return err
+		case <-t.C:
+			u.mu.Lock()
+			u.tick(u.now())
+			u.mu.Unlock()
+		}
+	}
+}
+
+func (u *Userspace) close() {
+	u.tun.Close()
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.running = false
+	u.conn.Close()
+}
+
+func (u *Userspace) readTUN() error {
+	buf := make([]byte, 65535)
+	for {
+		n, err := u.tun.Read(buf)
+		if err != nil {
+			return err
+		}
+		dst, ok := packetAddr(buf[:n], false)
+		if !ok {
+			continue
+		}
+		u.mu.Lock()
+		if p := u.lookupPeer(dst); p != nil {
+			u.send(p, bytes.Clone(buf[:n]), u.now())
+		}
+		u.mu.Unlock()
+	}
+}
+
+func (u *Userspace) readUDP(conn *net.UDPConn) {
+	buf := make([]byte, 65535)
+	for {
+		n, src, err := conn.ReadFromUDPAddrPort(buf)
+		if err != nil {
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() {
+				continue
+			}
+			return
+		}
+		src = netip.AddrPortFrom(src.Addr().Unmap(), src.Port())
+		u.mu.Lock()
+		packet := u.receive(buf[:n], src, u.now())
+		u.mu.Unlock()
+		if packet != nil {
+			u.tun.Write(packet)
+		}
+	}
+}
+
+// packetAddr returns the source or destination address of an IP packet.
+func packetAddr(packet []byte, source bool) (netip.Addr, bool) {
+	if len(packet) == 0 {
+		return netip.Addr{}, false
+	}
+	switch packet[0] >> 4 {
+	case 4:
+		if len(packet) < 20 {
+			return netip.Addr{}, false
+		}
+		off := 16
+		if source {
+			off = 12
+		}
+		return netip.AddrFrom4([4]byte(packet[off : off+4])), true
+	case 6:
+		if len(packet) < 40 {
+			return netip.Addr{}, false
+		}
+		off := 24
+		if source {
+			off = 8
+		}
+		return netip.AddrFrom16([16]byte(packet[off : off+16])), true
+	}
+	return netip.Addr{}, false
+}
+
+// packetLen returns the length of an IP packet without padding.
+func packetLen(packet []byte) (int, bool) {
+	var n int
+	switch packet[0] >> 4 {
+	case 4:
+		n = int(binary.BigEndian.Uint16(packet[2:]))
+	case 6:
+		n = 40 + int(binary.BigEndian.Uint16(packet[4:]))
+	}
+	return n, n > 0 && n <= len(packet)
+}
+
+// lookupPeer returns the peer whose allowed IPs best match a.
+func (u *Userspace) lookupPeer(a netip.Addr) *peer {
+	var (
+		best *peer
+		bits = -1
+	)
+	for _, p := range u.peers {
+		for _, ip := range p.allowedIPs {
+			if ip.Bits() > bits && ip.Contains(a) {
+				best, bits = p, ip.Bits()
+			}
+		}
+	}
+	return best
+}
+
+// newIndex returns an unused session index for p.
+func (u *Userspace) newIndex(p *peer) uint32 {
+	for {
+		var b [4]byte
+		rand.Read(b[:])
+		i := binary.LittleEndian.Uint32(b[:])
+		if _, ok := u.indices[i]; !ok {
+			u.indices[i] = p
+			return i
+		}
+	}
+}
+
+// write sends a message to the endpoint of p.
+func (u *Userspace) write(p *peer, msg []byte, now time.Time) {
+	if _, err := u.conn.WriteToUDPAddrPort(msg, p.endpoint); err != nil {
+		return
+	}
+	p.lastSent = now
+	p.tx += int64(len(msg))
+}
+
+// send sends a packet to p, or queues it and starts a handshake if there is
+// no session. An empty packet is a keepalive.
+func (u *Userspace) send(p *peer, packet []byte, now time.Time) {
+	kp := p.current
+	if kp == nil || kp.expired(now) || !p.endpoint.IsValid() {
+		if len(p.queue) < maxQueue {
+			p.queue = append(p.queue, packet)
+		}
+		u.initiate(p, now)
+		return
+	}
+	u.write(p, kp.seal(u.pad(packet)), now)
+	if len(packet) > 0 && p.dataSent.IsZero() {
+		p.dataSent = now
+	}
+	if kp.initiator && (now.Sub(kp.created) >= rekeyAfterTime || kp.sendNonce >= rekeyAfterMessages) {
+		u.initiate(p, now)
+	}
+}
+
+// pad pads a packet to a multiple of 16 bytes, within the MTU.
+func (u *Userspace) pad(packet []byte) []byte {
+	n := (len(packet) + 15) &^ 15
+	if n > u.mtu {
+		n = max(u.mtu, len(packet))
+	}
+	return append(packet, make([]byte, n-len(packet))...)
+}
+
+// initiate sends a handshake initiation to p, unless one was sent within
+// the rekey timeout.
+func (u *Userspace) initiate(p *peer, now time.Time) {
+	if !p.endpoint.IsValid() || u.privateKey.IsZero() {
+		return
+	}
+	if p.hs.initiated {
+		if now.Sub(p.hsSent) < rekeyTimeout {
+			return
+		}
+		delete(u.indices, p.hs.localIndex)
+	} else {
+		p.hsStarted = now
+	}
+	msg, err := p.hs.createInitiation(u.privateKey, p.pub, u.newIndex(p), now)
+	if err != nil {
+		return
+	}
+	p.hsSent = now
+	u.write(p, msg, now)
+}
+
+// receive handles a message from src, returning the packet it carries.
+func (u *Userspace) receive(msg []byte, src netip.AddrPort, now time.Time) []byte {
+	if len(msg) < 4 || msg[1]|msg[2]|msg[3] != 0 || u.privateKey.IsZero() {
+		return nil
+	}
+	switch msg[0] {
+	case msgInitiation:
+		in, err := consumeInitiation(msg, u.privateKey)
+		if err != nil {
+			return nil
+		}
+		p := u.peers[in.static]
+		if p == nil || bytes.Compare(in.timestamp[:], p.hs.lastTimestamp[:]) <= 0 {
+			return nil
+		}
+		p.hs.lastTimestamp = in.timestamp
+		u.authenticated(p, msg, src, now)
+		index := u.newIndex(p)
+		resp, send, recv, err := p.hs.createResponse(in, p.psk, index, now)
+		if err != nil {
+			delete(u.indices, index)
+			return nil
+		}
+		if p.next != nil {
+			delete(u.indices, p.next.localIndex)
+		}
+		p.next = newKeypair(send, recv, false, index, in.sender, now)
+		p.lastHandshake = now
+		u.write(p, resp, now)
+
+	case msgResponse:
+		if len(msg) != responseSize {
+			return nil
+		}
+		index := binary.LittleEndian.Uint32(msg[8:])
+		p := u.indices[index]
+		if p == nil || !p.hs.initiated || p.hs.localIndex != index {
+			return nil
+		}
+		send, recv, err := p.hs.consumeResponse(msg, u.privateKey, p.psk)
+		if err != nil {
+			return nil
+		}
+		u.authenticated(p, msg, src, now)
+		if p.next != nil {
+			delete(u.indices, p.next.localIndex)
+			p.next = nil
+		}
+		u.rotate(p, newKeypair(send, recv, true, index, binary.LittleEndian.Uint32(msg[4:]), now))
+		p.lastHandshake = now
+		u.Logf("wireguard: %s: handshake with %s complete", u.name, src)
+		if len(p.queue) == 0 {
+			// Confirm the session to the responder.
+			u.send(p, nil, now)
+		}
+		u.flush(p, now)
+
+	case msgCookie:
+		if p := u.indices[binary.LittleEndian.Uint32(msg[4:])]; p != nil {
+			p.hs.consumeCookie(msg, p.pub, now)
+		}
+
+	case msgData:
+		if len(msg) < dataHeaderSize+tagSize {
+			return nil
+		}
+		index := binary.LittleEndian.Uint32(msg[4:])
+		p := u.indices[index]
+		if p == nil {
+			return nil
+		}
+		var kp *keypair
+		for _, k := range []*keypair{p.current, p.previous, p.next} {
+			if k != nil && k.localIndex == index {
+				kp = k
+			}
+		}
+		if kp == nil || kp.expired(now) {
+			return nil
+		}
+		packet, ok := kp.open(msg)
+		if !ok {
+			return nil
+		}
+		u.authenticated(p, msg, src, now)
+		if kp == p.next {
+			p.next = nil
+			u.rotate(p, kp)
+			u.Logf("wireguard: %s: handshake with %s complete", u.name, src)
+			u.flush(p, now)
+		}
+		if len(packet) == 0 {
+			return nil
+		}
+		n, ok := packetLen(packet)
+		if !ok {
+			return nil
+		}
+		// The peer may only send from its allowed IPs.
+		if a, ok := packetAddr(packet, true); !ok || u.lookupPeer(a) != p {
+			return nil
+		}
+		p.lastDataReceived = now
+		return packet[:n]
+	}
+	return nil
+}
+
+// authenticated records an authenticated message from p. Peers roam to
+// where their messages come from.
+func (u *Userspace) authenticated(p *peer, msg []byte, src netip.AddrPort, now time.Time) {
+	p.endpoint = src
+	p.lastReceived = now
+	p.rx += int64(len(msg))
+	p.dataSent = time.Time{}
+}
+
+// rotate makes kp the current keypair of p.
+func (u *Userspace) rotate(p *peer, kp *keypair) {
+	if p.previous != nil {
+		delete(u.indices, p.previous.localIndex)
+	}
+	p.previous, p.current = p.current, kp
+}
+
+// flush sends the packets that waited for a session.
+func (u *Userspace) flush(p *peer, now time.Time) {
+	q := p.queue
+	p.queue = nil
+	for _, packet := range q {
+		u.send(p, packet, now)
+	}
+}
+
+// tick runs the timers of the peers.
+func (u *Userspace) tick(now time.Time) {
+	for _, p := range u.peers {
+		if p.hs.initiated && now.Sub(p.hsSent) >= rekeyTimeout {
+			if now.Sub(p.hsStarted) >= rekeyAttemptTime {
+				delete(u.indices, p.hs.localIndex)
+				p.hs.initiated = false
+				p.queue = nil
+			} else {
+				u.initiate(p, now)
+			}
+		}
+		for _, kp := range []**keypair{&p.current, &p.previous, &p.next} {
+			if *kp != nil && now.Sub((*kp).created) >= 3*rejectAfterTime {
+				delete(u.indices, (*kp).localIndex)
+				*kp = nil
+			}
+		}
+		switch {
+		case !p.dataSent.IsZero() && now.Sub(p.dataSent) >= keepaliveTimeout+rekeyTimeout:
+			// Data went unanswered.
+			p.dataSent = time.Time{}
+			u.initiate(p, now)
+		case p.current != nil && p.lastDataReceived.After(p.lastSent) && now.Sub(p.lastDataReceived) >= keepaliveTimeout:
+			u.send(p, nil, now)
+		case p.keepalive > 0 && now.Sub(maxTime(p.lastSent, p.lastReceived)) >= p.keepalive:
+			u.send(p, nil, now)
+		}
+	}
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+// Device returns the state of the interface.
+func (u *Userspace) Device() *Device {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	d := &Device{
+		Name:         u.name,
+		Type:         "userspace",
+		PrivateKey:   u.privateKey,
+		ListenPort:   u.listenPort,
+		FirewallMark: u.fwmark,
+	}
+	if !u.privateKey.IsZero() {
+		d.PublicKey = u.privateKey.PublicKey()
+	}
+	for _, p := range u.peers {
+		d.Peers = append(d.Peers, Peer{
+			PublicKey:                   p.pub,
+			PresharedKey:                p.psk,
+			Endpoint:                    p.endpoint,
+			PersistentKeepaliveInterval: p.keepalive,
+			LastHandshakeTime:           p.lastHandshake,
+			ReceiveBytes:                p.rx,
+			TransmitBytes:               p.tx,
+			AllowedIPs:                  slices.Clone(p.allowedIPs),
+			ProtocolVersion:             1,
+		})
+	}
+	slices.SortFunc(d.Peers, func(a, b Peer) int { return bytes.Compare(a.PublicKey[:], b.PublicKey[:]) })
+	return d
+}
+
+// Configure changes the interface as c says.
+func (u *Userspace) Configure(c *Config) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	now := u.now()
+
+	if c.PrivateKey != nil && *c.PrivateKey != u.privateKey {
+		u.privateKey = *c.PrivateKey
+		// Sessions were made with the old key.
+		for _, p := range u.peers {
+			u.reset(p)
+		}
+	}
+	if c.FirewallMark != nil && *c.FirewallMark != u.fwmark {
+		if err := setMark(u.conn, *c.FirewallMark); err != nil {
+			return err
+		}
+		u.fwmark = *c.FirewallMark
+	}
+	if c.ListenPort != nil && *c.ListenPort != u.listenPort {
+		if err := u.listen(*c.ListenPort); err != nil {
+			return err
+		}
+	}
+	if c.ReplacePeers {
+		for _, p := range u.peers {
+			u.removePeer(p)
+		}
+	}
+	for _, pc := range c.Peers {
+		p := u.peers[pc.PublicKey]
+		switch {
+		case pc.Remove:
+			if p != nil {
+				u.removePeer(p)
+			}
+			continue
+		case p == nil && pc.UpdateOnly:
+			continue
+		case p == nil:
+			p = &peer{pub: pc.PublicKey}
+			u.peers[pc.PublicKey] = p
+		}
+		if pc.PresharedKey != nil {
+			p.psk = *pc.PresharedKey
+		}
+		if pc.Endpoint != nil {
+			p.endpoint = netip.AddrPortFrom(pc.Endpoint.Addr().Unmap(), pc.Endpoint.Port())
+		}
+		if pc.ReplaceAllowedIPs {
+			p.allowedIPs = nil
+		}
+		for _, ip := range pc.AllowedIPs {
+			ip = ip.Masked()
+			// An allowed IP belongs to one peer only.
+			for _, other := range u.peers {
+				other.allowedIPs = slices.DeleteFunc(other.allowedIPs, func(o netip.Prefix) bool { return o == ip })
+			}
+			p.allowedIPs = append(p.allowedIPs, ip)
+		}
+		if pc.PersistentKeepaliveInterval != nil {
+			was := p.keepalive
+			p.keepalive = *pc.PersistentKeepaliveInterval
+			if was == 0 && p.keepalive > 0 {
+				u.send(p, nil, now)
+			}
+		}
+	}
+	return nil
+}
+
+// reset drops the sessions of p.
+func (u *Userspace) reset(p *peer) {
+	for _, kp := range []*keypair{p.current, p.previous, p.next} {
+		if kp != nil {
+			delete(u.indices, kp.localIndex)
+		}
+	}
+	if p.hs.initiated {
+		delete(u.indices, p.hs.localIndex)
+	}
+	p.current, p.previous, p.next = nil, nil, nil
+	p.hs = handshake{}
+	p.queue = nil
+}
+
+func (u *Userspace) removePeer(p *peer) {
+	u.reset(p)
+	delete(u.peers, p.pub)
+}
+
+// ServeUAPI serves the userspace API on ln, which is closed when it
+// returns.
+func (u *Userspace) ServeUAPI(ln net.Listener) error {
+	defer ln.Close()
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return err
+		}
+		go u.serveUAPIConn(conn)
+	}
+}
+
+func (u *Userspace) serveUAPIConn(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		op, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		var errno syscall.Errno
+		switch op {
+		case "get=1\n":
+			if err := uapiLines(r, func(string, string) error { return nil }); err != nil {
+				return
+			}
+			writeUAPIDevice(conn, u.Device())
+		case "set=1\n":
+			c, err := parseUAPIConfig(r)
+			if err == nil {
+				err = u.Configure(c)
+			}
+			if err != nil {
+				u.Logf("wireguard: %s: %v", u.name, err)
+				if !errors.As(err, &errno) {
+					errno = errInvalid
+				}
+			}
+		default:
+			return
+		}
+		if _, err := fmt.Fprintf(conn, "errno=%d\n\n", int(errno)); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,225 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package wireguard
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/netip"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeTUN is a TUN device whose packets go through channels.
+type fakeTUN struct {
+	in, out chan []byte
+	once    sync.Once
+	done    chan struct{}
+}
+
+func newFakeTUN() *fakeTUN {
+	return &fakeTUN{in: make(chan []byte, 16), out: make(chan []byte, 16), done: make(chan struct{})}
+}
+
+func (f *fakeTUN) Read(b []byte) (int, error) {
+	select {
+	case p := <-f.in:
+		return copy(b, p), nil
+	case <-f.done:
+		return 0, io.EOF
+	}
+}
+
+func (f *fakeTUN) Write(b []byte) (int, error) {
+	select {
+	case f.out <- bytes.Clone(b):
+	default:
+	}
+	return len(b), nil
+}
+
+func (f *fakeTUN) Close() error {
+	f.once.Do(func() { close(f.done) })
+	return nil
+}
+
+// ipv4Packet returns a UDP packet from src to dst.
+func ipv4Packet(src, dst string, payload string) []byte {
+	p := make([]byte, 28+len(payload))
+	p[0] = 0x45
+	binary.BigEndian.PutUint16(p[2:], uint16(len(p)))
+	p[8] = 64
+	p[9] = 17
+	s, d := netip.MustParseAddr(src).As4(), netip.MustParseAddr(dst).As4()
+	copy(p[12:], s[:])
+	copy(p[16:], d[:])
+	binary.BigEndian.PutUint16(p[24:], uint16(8+len(payload)))
+	copy(p[28:], payload)
+	return p
+}
+
+func receivePacket(t *testing.T, tun *fakeTUN) []byte {
+	t.Helper()
+	select {
+	case p := <-tun.out:
+		return p
+	case <-time.After(5 * time.Second):
+		t.Fatal("no packet arrived")
+		return nil
+	}
+}
+
+type testInterface struct {
+	u    *Userspace
+	tun  *fakeTUN
+	priv Key
+}
+
+func newTestInterface(t *testing.T, name string) *testInterface {
+	t.Helper()
+	tun := newFakeTUN()
+	u, err := NewUserspace(name, tun, DefaultMTU)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u.Logf = func(string, ...any) {}
+	priv, err := GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Go(func() { u.Run(ctx) })
+	t.Cleanup(func() {
+		cancel()
+		wg.Wait()
+	})
+	return &testInterface{u: u, tun: tun, priv: priv}
+}
+
+func TestUserspace(t *testing.T) {
+	SocketDir = t.TempDir()
+	a, b := newTestInterface(t, "wga"), newTestInterface(t, "wgb")
+
+	// b is configured over its API socket.
+	ln, err := net.Listen("unix", filepath.Join(SocketDir, "wgb.sock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	go b.u.ServeUAPI(ln)
+	t.Cleanup(func() { ln.Close() })
+
+	psk, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bEndpoint := netip.AddrPortFrom(netip.MustParseAddr("127.0.0.1"), uint16(b.u.Device().ListenPort))
+	if err := a.u.Configure(&Config{
+		PrivateKey: &a.priv,
+		Peers: []PeerConfig{{
+			PublicKey:    b.priv.PublicKey(),
+			PresharedKey: &psk,
+			Endpoint:     &bEndpoint,
+			AllowedIPs:   []netip.Prefix{netip.MustParsePrefix("10.0.0.2/32")},
+		}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	// b learns a's endpoint from its handshake.
+	if err := Configure("wgb", &Config{
+		PrivateKey: &b.priv,
+		Peers: []PeerConfig{{
+			PublicKey:    a.priv.PublicKey(),
+			PresharedKey: &psk,
+			AllowedIPs:   []netip.Prefix{netip.MustParsePrefix("10.0.0.0/24")},
+		}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	ping := ipv4Packet("10.0.0.1", "10.0.0.2", "ping")
+	a.tun.in <- ping
+	if got := receivePacket(t, b.tun); !bytes.Equal(got, ping) {
+		t.Errorf("b got %x, want %x", got, ping)
+	}
+	pong := ipv4Packet("10.0.0.2", "10.0.0.1", "pong")
+	b.tun.in <- pong
+	if got := receivePacket(t, a.tun); !bytes.Equal(got, pong) {
+		t.Errorf("a got %x, want %x", got, pong)
+	}
+
+	// b drops packets from outside the allowed IPs of a.
+	a.tun.in <- ipv4Packet("10.0.1.1", "10.0.0.2", "spoof")
+	a.tun.in <- ping
+	if got := receivePacket(t, b.tun); !bytes.Equal(got, ping) {
+		t.Errorf("b got %x, want %x", got, ping)
+	}
+
+	d, err := DeviceByName("wgb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Type != "userspace" || d.PublicKey != b.priv.PublicKey() || len(d.Peers) != 1 {
+		t.Fatalf("DeviceByName(wgb) = %+v", d)
+	}
+	p := d.Peers[0]
+	if p.Endpoint != netip.AddrPortFrom(netip.MustParseAddr("127.0.0.1"), uint16(a.u.Device().ListenPort)) {
+		t.Errorf("peer endpoint = %v, want a's", p.Endpoint)
+	}
+	if p.LastHandshakeTime.IsZero() || p.ReceiveBytes == 0 || p.TransmitBytes == 0 {
+		t.Errorf("peer = %+v, want a handshake and transfer", p)
+	}
+
+	// Removing the peer drops its session.
+	if err := Configure("wgb", &Config{ReplacePeers: true}); err != nil {
+		t.Fatal(err)
+	}
+	if d, err := DeviceByName("wgb"); err != nil || len(d.Peers) != 0 {
+		t.Errorf("DeviceByName(wgb) = %+v, %v, want no peers", d, err)
+	}
+	if err := Configure("wgb", &Config{Peers: []PeerConfig{{PublicKey: a.priv.PublicKey(), Endpoint: &netip.AddrPort{}}}}); err == nil {
+		t.Errorf("Configure(invalid endpoint) = nil error")
+	}
+}
+
+func TestUserspaceKeepalive(t *testing.T) {
+	a, b := newTestInterface(t, "wga"), newTestInterface(t, "wgb")
+	aEndpoint := netip.AddrPortFrom(netip.MustParseAddr("127.0.0.1"), uint16(a.u.Device().ListenPort))
+	bEndpoint := netip.AddrPortFrom(netip.MustParseAddr("127.0.0.1"), uint16(b.u.Device().ListenPort))
+	keepalive := time.Second
+	if err := b.u.Configure(&Config{
+		PrivateKey: &b.priv,
+		Peers:      []PeerConfig{{PublicKey: a.priv.PublicKey(), Endpoint: &aEndpoint}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	// A persistent keepalive makes a handshake without any traffic.
+	if err := a.u.Configure(&Config{
+		PrivateKey: &a.priv,
+		Peers: []PeerConfig{{
+			PublicKey:                   b.priv.PublicKey(),
+			Endpoint:                    &bEndpoint,
+			PersistentKeepaliveInterval: &keepalive,
+		}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		d := b.u.Device()
+		if p := d.Peers[0]; !p.LastHandshakeTime.IsZero() && p.ReceiveBytes > initiationSize {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("no keepalive: %+v", d.Peers[0])
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package wireguard configures WireGuard interfaces.
+//
+// Interfaces of the kernel are configured over its generic netlink family.
+// Where the kernel has no WireGuard, Userspace implements the protocol over
+// a TUN device and serves the cross-platform userspace API on a unix
+// socket, which this package also speaks.
+package wireguard
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/netip"
+	"time"
+
+	"golang.org/x/crypto/curve25519"
+)
+
+// KeyLen is the length of keys.
+const KeyLen = 32
+
+// Key is a Curve25519 private or public key, or a preshared key.
+type Key [KeyLen]byte
+
+// GeneratePrivateKey returns a new private key.
+func GeneratePrivateKey() (Key, error) {
+	k, err := GenerateKey()
+	if err != nil {
+		return k, err
+	}
+	k[0] &= 248
+	k[31] = k[31]&127 | 64
+	return k, nil
+}
+
+// GenerateKey returns a new random key, as used for preshared keys.
+func GenerateKey() (Key, error) {
+	var k Key
+	_, err := rand.Read(k[:])
+	return k, err
+}
+
+// PublicKey returns the public key of the private key k.
+func (k Key) PublicKey() Key {
+	var pub Key
+	b, _ := curve25519.X25519(k[:], curve25519.Basepoint)
+	copy(pub[:], b)
+	return pub
+}
+
+// IsZero reports whether k is all zeros, which stands for no key.
+func (k Key) IsZero() bool {
+	return k == Key{}
+}
+
+// String returns k in base64, as wg(8) prints keys.
+func (k Key) String() string {
+	return base64.StdEncoding.EncodeToString(k[:])
+}
+
+// ParseKey parses a key in base64.
+func ParseKey(s string) (Key, error) {
+	var k Key
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil || len(b) != KeyLen {
+		return k, fmt.Errorf("invalid key %q", s)
+	}
+	copy(k[:], b)
+	return k, nil
+}
+
+// parseHexKey parses a key in hex, as the userspace API has them.
+func parseHexKey(s string) (Key, error) {
+	var k Key
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != KeyLen {
+		return k, fmt.Errorf("invalid key %q", s)
+	}
+	copy(k[:], b)
+	return k, nil
+}
+
+// Device is the state of a WireGuard interface.
+type Device struct {
+	Name string
+
+	// Type is "kernel" or "userspace".
+	Type string
+
+	PrivateKey   Key
+	PublicKey    Key
+	ListenPort   int
+	FirewallMark int
+	Peers        []Peer
+}
+
+// Peer is the state of a peer of an interface.
+type Peer struct {
+	PublicKey                   Key
+	PresharedKey                Key
+	Endpoint                    netip.AddrPort
+	PersistentKeepaliveInterval time.Duration
+	LastHandshakeTime           time.Time
+	ReceiveBytes                int64
+	TransmitBytes               int64
+	AllowedIPs                  []netip.Prefix
+	ProtocolVersion             int
+}
+
+// Config is a change to an interface. Nil fields are left alone.
+type Config struct {
+	PrivateKey   *Key
+	ListenPort   *int
+	FirewallMark *int
+
+	// ReplacePeers removes the peers that are not in Peers.
+	ReplacePeers bool
+	Peers        []PeerConfig
+}
+
+// PeerConfig adds, changes or removes a peer.
+type PeerConfig struct {
+	PublicKey Key
+
+	// Remove removes the peer.
+	Remove bool
+
+	// UpdateOnly changes the peer only if it exists.
+	UpdateOnly bool
+
+	PresharedKey                *Key
+	Endpoint                    *netip.AddrPort
+	PersistentKeepaliveInterval *time.Duration
+
+	// ReplaceAllowedIPs removes the allowed IPs that are not in
+	// AllowedIPs.
+	ReplaceAllowedIPs bool
+	AllowedIPs        []netip.Prefix
+}
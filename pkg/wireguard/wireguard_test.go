@@ -0,0 +1,42 @@
+// Copyright 2026 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package wireguard
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestPublicKey(t *testing.T) {
+	// RFC 7748, section 6.1.
+	priv, err := parseHexKey("77076d0a7318a57d3c16c17251b26645df4c2f87ebc0992ab177fba51db92c2a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub := priv.PublicKey()
+	want := "8520f0098930a754748b7ddcb43ef75a0dbf3a0d26381af4eba4a98eaa9b4e6a"
+	if got := hex.EncodeToString(pub[:]); got != want {
+		t.Errorf("PublicKey() = %s, want %s", got, want)
+	}
+}
+
+func TestParseKey(t *testing.T) {
+	k, err := GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k[0]&7 != 0 || k[31]&0x80 != 0 || k[31]&0x40 == 0 {
+		t.Errorf("GeneratePrivateKey() = %x, not clamped", k)
+	}
+	got, err := ParseKey(k.String())
+	if err != nil || got != k {
+		t.Errorf("ParseKey(%q) = %v, %v, want %v", k.String(), got, err, k)
+	}
+	for _, s := range []string{"", "AAAA", "not base64 at all, not base64 at all=", k.String() + "AAAA"} {
+		if _, err := ParseKey(s); err == nil {
+			t.Errorf("ParseKey(%q) = nil error", s)
+		}
+	}
+}